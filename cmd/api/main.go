@@ -18,6 +18,7 @@ import (
 	"github.com/sinker/ssop/internal/models"
 	"github.com/sinker/ssop/internal/repository"
 	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/dataio"
 	"github.com/sinker/ssop/pkg/logger"
 	"github.com/sinker/ssop/pkg/redis"
 	"github.com/sinker/ssop/pkg/utils"
@@ -59,20 +60,41 @@ func main() {
 
 	// 确保存储目录存在
 	ensureStorageDirs(cfg.StorageConfig)
+	dataio.MaxTableSize = cfg.StorageConfig.MaxTableSize
 
 	// 初始化仓库
 	userRepo := repository.NewUserRepository(db)
 	datasetRepo := repository.NewDatasetRepository(db)
 	analysisRepo := repository.NewAnalysisRepository(db)
 	systemRepo := repository.NewSystemRepository(db)
+	quotaRepo := repository.NewQuotaRepository(db)
+	aliasRepo := repository.NewAliasRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+	climatologyRepo := repository.NewClimatologyRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
 
 	// 初始化服务
 	tokenService := services.NewTokenService()
 	authService := services.NewAuthService(userRepo, cfg.JWTConfig, tokenService)
 	userService := services.NewUserService(userRepo)
-	datasetService := services.NewDatasetService(datasetRepo, cfg.StorageConfig.DatasetDir)
-	analysisService := services.NewAnalysisService(analysisRepo, datasetRepo, cfg.StorageConfig.AnalysisDir)
 	systemService := services.NewSystemService(systemRepo)
+	quotaService := services.NewQuotaService(quotaRepo, userRepo, systemService)
+	standardNameService := services.NewStandardNameService(aliasRepo, datasetRepo, systemService)
+	qcService := services.NewQCService(datasetRepo, systemService)
+	ingestService := services.NewIngestService(datasetRepo, profileRepo, qcService)
+	notificationService := services.NewNotificationService(notificationRepo)
+	datasetService := services.NewDatasetService(datasetRepo, userRepo, quotaService, ingestService, cfg.StorageConfig.DatasetDir, cfg.StorageConfig.StoreDir, cfg.StorageConfig.TrashDir, cfg.BaseURL)
+	analysisService := services.NewAnalysisService(analysisRepo, datasetRepo, profileRepo, climatologyRepo, quotaService, standardNameService, systemService, ingestService, notificationService,
+		cfg.StorageConfig.AnalysisDir, cfg.StorageConfig.DatasetDir, cfg.StorageConfig.TrashDir)
+	oaiService := services.NewOAIService(datasetRepo, userRepo, systemService, cfg.BaseURL)
+	stacService := services.NewSTACService(datasetRepo, cfg.BaseURL)
+	trashService := services.NewTrashService(datasetRepo, analysisRepo, systemService,
+		cfg.StorageConfig.AnalysisDir, cfg.StorageConfig.TrashDir)
+
+	// 启动回收站后台清理
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	trashService.StartPurgeJob(jobCtx)
 
 	// 初始化路由
 	router := gin.Default()
@@ -91,6 +113,14 @@ func main() {
 	handlers.RegisterDatasetRoutes(v1, datasetService, authMiddleware)
 	handlers.RegisterAnalysisRoutes(v1, analysisService, authMiddleware)
 	handlers.RegisterSystemRoutes(v1, systemService, authMiddleware)
+	handlers.RegisterOAIRoutes(v1, oaiService)
+	handlers.RegisterSTACRoutes(v1, stacService)
+	handlers.RegisterTrashRoutes(v1, trashService, authMiddleware)
+	handlers.RegisterQuotaRoutes(v1, quotaService, authMiddleware)
+	handlers.RegisterIngestRoutes(v1, ingestService, authMiddleware)
+	handlers.RegisterQCRoutes(v1, qcService, authMiddleware)
+	handlers.RegisterStandardNameRoutes(v1, standardNameService, authMiddleware)
+	handlers.RegisterNotificationRoutes(v1, notificationService, authMiddleware)
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -113,6 +143,7 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	stopJobs()
 
 	// 设置关闭超时
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -131,6 +162,8 @@ func ensureStorageDirs(cfg config.StorageConfig) {
 		cfg.BaseDir,
 		cfg.DatasetDir,
 		cfg.AnalysisDir,
+		cfg.StoreDir,
+		cfg.TrashDir,
 	}
 
 	for _, dir := range dirs {
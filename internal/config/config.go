@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,7 @@ import (
 type Config struct {
 	Environment string
 	Port        int
+	BaseURL     string // 对外访问地址，用于生成元数据中的链接
 	LogLevel    string
 	DBConfig    DBConfig
 	RedisConfig RedisConfig
@@ -47,7 +49,10 @@ type StorageConfig struct {
 	BaseDir       string
 	DatasetDir    string
 	AnalysisDir   string
+	StoreDir      string // 可登记为数据集的Zarr存储根目录
+	TrashDir      string // 回收站目录，删除的文件在清理前存放于此
 	MaxUploadSize int64
+	MaxTableSize  int64 // 按表格解析的CSV/TSV文件的最大字节数
 }
 
 // LoadConfig 从环境变量加载配置
@@ -58,6 +63,9 @@ func LoadConfig() *Config {
 	// 获取端口
 	port, _ := strconv.Atoi(getEnv("APP_PORT", "8080"))
 	
+	// 获取对外访问地址
+	baseURL := strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:"+strconv.Itoa(port)), "/")
+	
 	// 获取日志级别
 	logLevel := getEnv("LOG_LEVEL", "info")
 	
@@ -93,17 +101,22 @@ func LoadConfig() *Config {
 	// 获取存储配置
 	baseDir := getEnv("STORAGE_BASE_DIR", "./storage")
 	maxUploadSize, _ := strconv.ParseInt(getEnv("MAX_UPLOAD_SIZE", "1073741824"), 10, 64)
+	maxTableSize, _ := strconv.ParseInt(getEnv("MAX_TABLE_SIZE", "268435456"), 10, 64)
 	
 	storageConfig := StorageConfig{
 		BaseDir:       baseDir,
 		DatasetDir:    filepath.Join(baseDir, "datasets"),
 		AnalysisDir:   filepath.Join(baseDir, "analysis"),
+		StoreDir:      getEnv("STORAGE_STORE_DIR", filepath.Join(baseDir, "stores")),
+		TrashDir:      filepath.Join(baseDir, "trash"),
 		MaxUploadSize: maxUploadSize,
+		MaxTableSize:  maxTableSize,
 	}
 	
 	return &Config{
 		Environment:   env,
 		Port:          port,
+		BaseURL:       baseURL,
 		LogLevel:      logLevel,
 		DBConfig:      dbConfig,
 		RedisConfig:   redisConfig,
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +37,7 @@ func RegisterAnalysisRoutes(router *gin.RouterGroup, analysisService services.An
 		results := analysis.Group("/results")
 		{
 			results.GET("/:resultId", analysisHandler.GetResultByID)
+			results.GET("/:resultId/download", analysisHandler.DownloadResult)
 			results.DELETE("/:resultId", analysisHandler.DeleteResult)
 		}
 		
@@ -42,6 +46,37 @@ func RegisterAnalysisRoutes(router *gin.RouterGroup, analysisService services.An
 		{
 			ts.GET("/timeseries", analysisHandler.GetTemperatureSalinityTimeSeries)
 			ts.GET("/spatial", analysisHandler.GetTemperatureSalinitySpatial)
+			ts.GET("/diagram", analysisHandler.GetTSDiagram)
+		}
+		
+		// 垂直剖面
+		analysis.GET("/profiles", analysisHandler.SearchProfiles)
+		
+		// 气候态
+		analysis.GET("/climatologies", analysisHandler.ListClimatologies)
+		
+		// 混合层与温跃层
+		mld := analysis.Group("/mixed-layer")
+		{
+			mld.GET("/timeseries", analysisHandler.GetMixedLayerTimeSeries)
+			mld.GET("/spatial", analysisHandler.GetMixedLayerSpatial)
+		}
+		
+		// 海流分析
+		currents := analysis.Group("/currents")
+		{
+			currents.GET("/field", analysisHandler.GetCurrentField)
+			currents.GET("/rose", analysisHandler.GetCurrentRose)
+		}
+		
+		// 垂直断面
+		analysis.GET("/section", analysisHandler.GetSection)
+		
+		// 趋势分析
+		trend := analysis.Group("/trend")
+		{
+			trend.GET("/timeseries", analysisHandler.GetTrendTimeSeries)
+			trend.GET("/spatial", analysisHandler.GetTrendSpatial)
 		}
 	}
 }
@@ -66,6 +101,10 @@ func (h *AnalysisHandler) CreateTask(c *gin.Context) {
 	
 	// 创建任务
 	taskID, err := h.analysisService.CreateTask(&task)
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		response.Fail(c, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to create analysis task", "error", err)
 		response.Fail(c, http.StatusInternalServerError, "创建分析任务失败")
@@ -259,6 +298,41 @@ func (h *AnalysisHandler) GetResultByID(c *gin.Context) {
 	response.Success(c, result, "获取成功")
 }
 
+// DownloadResult 下载分析结果文件
+func (h *AnalysisHandler) DownloadResult(c *gin.Context) {
+	resultID := c.Param("resultId")
+
+	result, err := h.analysisService.GetResultByID(resultID)
+	if err != nil {
+		logger.Error("Failed to get result", "error", err, "resultId", resultID)
+		response.Fail(c, http.StatusNotFound, "分析结果不存在")
+		return
+	}
+
+	task, err := h.analysisService.GetTaskByID(result.TaskID)
+	if err != nil {
+		logger.Error("Failed to get task for result", "error", err, "taskId", result.TaskID)
+		response.Fail(c, http.StatusInternalServerError, "获取关联任务失败")
+		return
+	}
+
+	// 检查权限(只能下载自己任务的结果)
+	userID, _ := c.Get("userId")
+	if task.CreatedBy != userID.(string) {
+		response.Fail(c, http.StatusForbidden, "无权访问此分析结果")
+		return
+	}
+
+	if _, err := os.Stat(result.FilePath); err != nil {
+		response.Fail(c, http.StatusNotFound, "结果文件不存在")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+result.ID+filepath.Ext(result.FilePath))
+	c.Header("Content-Description", "File Transfer")
+	c.File(result.FilePath)
+}
+
 // DeleteResult 删除分析结果
 func (h *AnalysisHandler) DeleteResult(c *gin.Context) {
 	resultID := c.Param("resultId")
@@ -306,6 +380,7 @@ func (h *AnalysisHandler) GetTemperatureSalinityTimeSeries(c *gin.Context) {
 	startDate := c.Query("startDate")
 	endDate := c.Query("endDate")
 	interval := c.DefaultQuery("interval", "day")
+	variables := c.Query("variables")
 	
 	// 验证必要参数
 	if datasetID == "" || lat == "" || lng == "" || startDate == "" || endDate == "" {
@@ -314,7 +389,15 @@ func (h *AnalysisHandler) GetTemperatureSalinityTimeSeries(c *gin.Context) {
 	}
 	
 	// 执行分析
-	result, err := h.analysisService.GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval)
+	options := anomalyParams(c)
+	for _, key := range resampleParams {
+		options[key] = c.Query(key)
+	}
+	result, err := h.analysisService.GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval, variables, options)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get temperature-salinity timeseries", "error", err)
 		response.Fail(c, http.StatusInternalServerError, "获取温盐时间序列失败: "+err.Error())
@@ -332,6 +415,7 @@ func (h *AnalysisHandler) GetTemperatureSalinitySpatial(c *gin.Context) {
 	depth := c.DefaultQuery("depth", "0")
 	bounds := c.Query("bounds")
 	resolution := c.DefaultQuery("resolution", "medium")
+	variables := c.Query("variables")
 	
 	// 验证必要参数
 	if datasetID == "" || date == "" || bounds == "" {
@@ -340,12 +424,323 @@ func (h *AnalysisHandler) GetTemperatureSalinitySpatial(c *gin.Context) {
 	}
 	
 	// 执行分析
-	result, err := h.analysisService.GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution)
+	result, err := h.analysisService.GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution, variables, anomalyParams(c))
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get temperature-salinity spatial distribution", "error", err)
 		response.Fail(c, http.StatusInternalServerError, "获取温盐空间分布失败: "+err.Error())
 		return
 	}
 	
+	if c.Query("format") == "geotiff" {
+		h.sendGeoTIFF(c, result, datasetID+"_spatial.tif")
+		return
+	}
+	
+	response.Success(c, result, "获取成功")
+} 
+
+// sendGeoTIFF 将规则网格结果导出为GeoTIFF并作为附件下载
+func (h *AnalysisHandler) sendGeoTIFF(c *gin.Context, result map[string]interface{}, filename string) {
+	tmp, err := os.CreateTemp("", "spatial-*.tif")
+	if err != nil {
+		response.Fail(c, http.StatusInternalServerError, "导出GeoTIFF失败")
+		return
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	
+	if err := h.analysisService.ExportGeoTIFF(result, tmp.Name()); err != nil {
+		if errors.Is(err, services.ErrNotGridResult) {
+			response.Fail(c, http.StatusBadRequest, "分析结果不是规则网格，无法导出GeoTIFF")
+			return
+		}
+		logger.Error("Failed to export GeoTIFF", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "导出GeoTIFF失败")
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Description", "File Transfer")
+	c.File(tmp.Name())
+}
+
+// SearchProfiles 按区域、时间窗或浮标查找剖面，返回标准层上的插值结果
+func (h *AnalysisHandler) SearchProfiles(c *gin.Context) {
+	params := map[string]interface{}{}
+	for _, key := range []string{"bounds", "startDate", "endDate", "platform", "cycle", "datasetId", "depths", "variables", "page", "size"} {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.SearchProfiles(params)
+	if errors.Is(err, services.ErrInvalidProfileQuery) {
+		response.Fail(c, http.StatusBadRequest, "无效的查询参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to search profiles", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "查询剖面失败")
+		return
+	}
+
+	response.Success(c, result, "获取成功")
+}
+
+// GetTSDiagram 获取T-S图散点或分箱，叠加σθ等密度线并可按水团分类
+func (h *AnalysisHandler) GetTSDiagram(c *gin.Context) {
+	if c.Query("datasetId") == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{}
+	for _, key := range []string{"datasetId", "bounds", "startDate", "endDate", "minDepth", "maxDepth", "mode", "maxPoints",
+		"tStep", "sStep", "isopycnals", "classify", "waterMasses", "qcFlags"} {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetTSDiagram(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get T-S diagram", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取T-S图失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, result, "获取成功")
+}
+
+// mixedLayerCriteriaParams 混合层判据及距平参数
+var mixedLayerCriteriaParams = []string{"refDepth", "tempThreshold", "densityThreshold", "gradientThreshold", "qcFlags",
+	"anomaly", "climatologyId", "period", "baseline"}
+
+// GetMixedLayerTimeSeries 获取指定位置的混合层深度、温跃层和最大层结时间序列
+func (h *AnalysisHandler) GetMixedLayerTimeSeries(c *gin.Context) {
+	if c.Query("datasetId") == "" || c.Query("lat") == "" || c.Query("lng") == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{"interval": c.DefaultQuery("interval", "day")}
+	for _, key := range append(append([]string{"datasetId", "lat", "lng", "startDate", "endDate"}, mixedLayerCriteriaParams...), resampleParams...) {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetMixedLayerTimeSeries(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get mixed layer timeseries", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取混合层时间序列失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, result, "获取成功")
+}
+
+// GetMixedLayerSpatial 获取混合层深度、温跃层和最大层结的空间分布
+func (h *AnalysisHandler) GetMixedLayerSpatial(c *gin.Context) {
+	datasetID := c.Query("datasetId")
+	if datasetID == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{"resolution": c.DefaultQuery("resolution", "medium")}
+	for _, key := range append([]string{"datasetId", "date", "bounds"}, mixedLayerCriteriaParams...) {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetMixedLayerSpatial(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get mixed layer spatial distribution", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取混合层空间分布失败: "+err.Error())
+		return
+	}
+
+	if c.Query("format") == "geotiff" {
+		h.sendGeoTIFF(c, result, datasetID+"_mixed_layer.tif")
+		return
+	}
+	response.Success(c, result, "获取成功")
+}
+
+// trendParams 趋势分析的公共参数
+var trendParams = []string{"datasetId", "variables", "depth", "startDate", "endDate", "seasonal", "alpha", "qcFlags",
+	"anomaly", "climatologyId", "period", "baseline"}
+
+// GetTrendTimeSeries 获取指定位置时间序列的趋势统计
+func (h *AnalysisHandler) GetTrendTimeSeries(c *gin.Context) {
+	if c.Query("datasetId") == "" || c.Query("lat") == "" || c.Query("lng") == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{"interval": c.DefaultQuery("interval", "month")}
+	for _, key := range append(append([]string{"lat", "lng"}, trendParams...), resampleParams...) {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetTrendTimeSeries(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get trend timeseries", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取趋势时间序列失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, result, "获取成功")
+}
+
+// GetTrendSpatial 获取逐格点趋势的空间分布
+func (h *AnalysisHandler) GetTrendSpatial(c *gin.Context) {
+	datasetID := c.Query("datasetId")
+	if datasetID == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{
+		"interval":   c.DefaultQuery("interval", "month"),
+		"resolution": c.DefaultQuery("resolution", "medium"),
+	}
+	for _, key := range append([]string{"bounds", "minCount", "minCoverage"}, trendParams...) {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetTrendSpatial(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get trend spatial distribution", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取趋势空间分布失败: "+err.Error())
+		return
+	}
+
+	if c.Query("format") == "geotiff" {
+		h.sendGeoTIFF(c, result, datasetID+"_trend.tif")
+		return
+	}
+	response.Success(c, result, "获取成功")
+}
+
+// GetCurrentField 获取流场的流速、流向、涡度、散度、Okubo-Weiss参数和涡旋
+func (h *AnalysisHandler) GetCurrentField(c *gin.Context) {
+	datasetID := c.Query("datasetId")
+	if datasetID == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{"resolution": c.DefaultQuery("resolution", "medium")}
+	for _, key := range []string{"datasetId", "date", "depth", "bounds", "uVariable", "vVariable", "eddyThreshold", "minEddyCells", "qcFlags"} {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetCurrentField(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get current field", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取流场失败: "+err.Error())
+		return
+	}
+
+	if c.Query("format") == "geotiff" {
+		h.sendGeoTIFF(c, result, datasetID+"_currents.tif")
+		return
+	}
+	response.Success(c, result, "获取成功")
+}
+
+// GetCurrentRose 获取指定位置的海流玫瑰图
+func (h *AnalysisHandler) GetCurrentRose(c *gin.Context) {
+	if c.Query("datasetId") == "" || c.Query("lat") == "" || c.Query("lng") == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{}
+	for _, key := range []string{"datasetId", "lat", "lng", "depth", "startDate", "endDate", "uVariable", "vVariable", "sectors", "speedBins", "qcFlags"} {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetCurrentRose(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get current rose", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取海流玫瑰图失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, result, "获取成功")
+}
+
+// GetSection 获取沿折线的垂直断面
+func (h *AnalysisHandler) GetSection(c *gin.Context) {
+	if c.Query("datasetId") == "" || c.Query("path") == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+	params := map[string]interface{}{}
+	for _, key := range []string{"datasetId", "path", "variables", "date", "spacing", "method", "minDepth", "maxDepth", "bathymetryVariable", "qcFlags"} {
+		params[key] = c.Query(key)
+	}
+
+	result, err := h.analysisService.GetSection(params)
+	if errors.Is(err, services.ErrInvalidAnalysisParams) {
+		response.Fail(c, http.StatusBadRequest, "无效的分析参数: "+err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get section", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取垂直断面失败: "+err.Error())
+		return
+	}
+
 	response.Success(c, result, "获取成功")
-} 
\ No newline at end of file
+}
+
+// resampleParams 时间序列重采样的查询参数(周期为interval)
+var resampleParams = []string{"reducer", "percentile", "minCount", "minCoverage"}
+
+// anomalyParams 计算距平的查询参数
+func anomalyParams(c *gin.Context) map[string]interface{} {
+	params := map[string]interface{}{}
+	for _, key := range []string{"anomaly", "climatologyId", "period", "baseline"} {
+		params[key] = c.Query(key)
+	}
+	return params
+}
+
+// ListClimatologies 获取数据集已生成的气候态
+func (h *AnalysisHandler) ListClimatologies(c *gin.Context) {
+	datasetID := c.Query("datasetId")
+	if datasetID == "" {
+		response.Fail(c, http.StatusBadRequest, "缺少必要参数")
+		return
+	}
+
+	climatologies, err := h.analysisService.ListClimatologies(datasetID)
+	if err != nil {
+		logger.Error("Failed to list climatologies", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取气候态列表失败")
+		return
+	}
+
+	response.Success(c, climatologies, "获取成功")
+}
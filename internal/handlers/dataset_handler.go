@@ -2,15 +2,20 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sinker/ssop/internal/models"
 	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/dataio"
 	"github.com/sinker/ssop/pkg/logger"
 	"github.com/sinker/ssop/pkg/response"
+	"github.com/sinker/ssop/pkg/utils"
 )
 
 // RegisterDatasetRoutes 注册数据集相关路由
@@ -22,6 +27,8 @@ func RegisterDatasetRoutes(router *gin.RouterGroup, datasetService services.Data
 		// 公开接口
 		datasets.GET("", datasetHandler.GetDatasets)
 		datasets.GET("/:datasetId", datasetHandler.GetDatasetByID)
+		datasets.GET("/:datasetId/metadata", datasetHandler.ExportMetadata)
+		datasets.GET("/:datasetId/landing", datasetHandler.GetLandingPage)
 		
 		// 需要认证的接口
 		authenticated := datasets.Group("")
@@ -31,6 +38,7 @@ func RegisterDatasetRoutes(router *gin.RouterGroup, datasetService services.Data
 			authenticated.PUT("/:datasetId", datasetHandler.UpdateDataset)
 			authenticated.DELETE("/:datasetId", datasetHandler.DeleteDataset)
 			authenticated.GET("/:datasetId/download", datasetHandler.DownloadDataset)
+			authenticated.GET("/:datasetId/subset", datasetHandler.DownloadSubset)
 		}
 	}
 }
@@ -102,14 +110,20 @@ func (h *DatasetHandler) GetDatasetByID(c *gin.Context) {
 
 // UploadDataset 上传数据集
 func (h *DatasetHandler) UploadDataset(c *gin.Context) {
-	// 获取上传的文件
-	file, fileHeader, err := c.Request.FormFile("file")
-	if err != nil {
-		logger.Error("Failed to get file", "error", err)
-		response.Fail(c, http.StatusBadRequest, "文件上传失败")
-		return
+	// 登记存储后端上的Zarr目录时无需上传文件
+	storePath := c.PostForm("storePath")
+	var file multipart.File
+	var fileHeader *multipart.FileHeader
+	if storePath == "" {
+		var err error
+		file, fileHeader, err = c.Request.FormFile("file")
+		if err != nil {
+			logger.Error("Failed to get file", "error", err)
+			response.Fail(c, http.StatusBadRequest, "文件上传失败")
+			return
+		}
+		defer file.Close()
 	}
-	defer file.Close()
 	
 	// 解析元数据
 	metadataStr := c.PostForm("metadata")
@@ -126,7 +140,21 @@ func (h *DatasetHandler) UploadDataset(c *gin.Context) {
 	dataset.CreatedBy = userID.(string)
 	
 	// 创建数据集
-	datasetID, err := h.datasetService.CreateDataset(&dataset, file, fileHeader.Filename)
+	var datasetID string
+	var err error
+	if storePath != "" {
+		datasetID, err = h.datasetService.CreateDatasetFromStore(&dataset, storePath)
+	} else {
+		datasetID, err = h.datasetService.CreateDataset(&dataset, file, fileHeader.Filename, fileHeader.Size)
+	}
+	if errors.Is(err, services.ErrQuotaExceeded) {
+		response.Fail(c, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStore) {
+		response.Fail(c, http.StatusBadRequest, "无效的Zarr存储: "+err.Error())
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to create dataset", "error", err)
 		response.Fail(c, http.StatusInternalServerError, "创建数据集失败")
@@ -198,8 +226,94 @@ func (h *DatasetHandler) DownloadDataset(c *gin.Context) {
 	parts := strings.Split(filePath, "/")
 	fileName := parts[len(parts)-1]
 	
+	// Zarr等目录存储打包为zip下载
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".zip")
+		c.Header("Content-Description", "File Transfer")
+		c.Header("Content-Type", "application/zip")
+		if err := utils.ZipDir(c.Writer, filePath); err != nil {
+			logger.Error("Failed to stream dataset archive", "error", err, "datasetId", datasetID)
+		}
+		return
+	}
+	
 	// 设置下载头
 	c.Header("Content-Disposition", "attachment; filename="+fileName)
 	c.Header("Content-Description", "File Transfer")
 	c.File(filePath)
-} 
\ No newline at end of file
+} 
+
+// DownloadSubset 按变量、时间和空间范围下载NetCDF子集
+func (h *DatasetHandler) DownloadSubset(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	params := map[string]interface{}{
+		"vars":      c.Query("vars"),
+		"startDate": c.Query("startDate"),
+		"endDate":   c.Query("endDate"),
+		"bounds":    c.Query("bounds"),
+	}
+
+	path, err := h.datasetService.CreateSubset(datasetID, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSubset):
+			response.Fail(c, http.StatusBadRequest, "无效的子集参数: "+err.Error())
+		case errors.Is(err, dataio.ErrVariableNotFound):
+			response.Fail(c, http.StatusBadRequest, "变量不存在: "+err.Error())
+		case errors.Is(err, dataio.ErrEmptySubset):
+			response.Fail(c, http.StatusNotFound, "所选范围内没有数据")
+		case errors.Is(err, dataio.ErrUnsupportedFormat):
+			response.Fail(c, http.StatusBadRequest, "该数据集格式不支持子集下载")
+		default:
+			logger.Error("Failed to create dataset subset", "error", err, "datasetId", datasetID)
+			response.Fail(c, http.StatusNotFound, "数据集不存在或无法读取")
+		}
+		return
+	}
+	defer os.Remove(path)
+
+	c.Header("Content-Disposition", "attachment; filename="+datasetID+"_subset.nc")
+	c.Header("Content-Description", "File Transfer")
+	c.File(path)
+}
+
+// ExportMetadata 导出数据集引用元数据
+func (h *DatasetHandler) ExportMetadata(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	format := c.DefaultQuery("format", services.MetadataFormatDataCite)
+	
+	data, contentType, err := h.datasetService.ExportMetadata(datasetID, format)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedMetadataFormat) {
+			response.Fail(c, http.StatusBadRequest, "不支持的元数据格式: "+format)
+			return
+		}
+		if errors.Is(err, services.ErrDatasetNotFound) {
+			response.Fail(c, http.StatusNotFound, "数据集不存在")
+			return
+		}
+		logger.Error("Failed to export dataset metadata", "error", err, "datasetId", datasetID, "format", format)
+		response.Fail(c, http.StatusInternalServerError, "导出元数据失败")
+		return
+	}
+	
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetLandingPage 获取嵌入JSON-LD的数据集落地页
+func (h *DatasetHandler) GetLandingPage(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	
+	page, err := h.datasetService.RenderLandingPage(datasetID)
+	if err != nil {
+		if errors.Is(err, services.ErrDatasetNotFound) {
+			response.Fail(c, http.StatusNotFound, "数据集不存在")
+			return
+		}
+		logger.Error("Failed to render dataset landing page", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusInternalServerError, "渲染落地页失败")
+		return
+	}
+	
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterIngestRoutes 注册数据集入库处理与变量统计路由
+func RegisterIngestRoutes(router *gin.RouterGroup, ingestService services.IngestService, authMiddleware gin.HandlerFunc) {
+	ingestHandler := &IngestHandler{ingestService: ingestService}
+
+	datasets := router.Group("/datasets")
+	{
+		// 公开接口
+		datasets.GET("/:datasetId/variables", ingestHandler.ListVariableStats)
+		datasets.GET("/:datasetId/variables/:name/stats", ingestHandler.GetVariableStats)
+
+		// 需要认证的接口
+		datasets.POST("/:datasetId/ingest", authMiddleware, ingestHandler.Reingest)
+	}
+}
+
+// IngestHandler 数据集入库处理器
+type IngestHandler struct {
+	ingestService services.IngestService
+}
+
+// ListVariableStats 获取数据集全部变量的统计信息
+func (h *IngestHandler) ListVariableStats(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+
+	stats, err := h.ingestService.ListVariableStats(datasetID)
+	if err != nil {
+		logger.Error("Failed to list variable stats", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusInternalServerError, "获取变量统计失败")
+		return
+	}
+
+	response.Success(c, stats, "获取成功")
+}
+
+// GetVariableStats 获取单个变量的统计信息
+func (h *IngestHandler) GetVariableStats(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	name := c.Param("name")
+
+	stats, err := h.ingestService.GetVariableStats(datasetID, name)
+	if err != nil {
+		if errors.Is(err, services.ErrStatsNotFound) {
+			response.Fail(c, http.StatusNotFound, "变量统计不存在")
+			return
+		}
+		logger.Error("Failed to get variable stats", "error", err, "datasetId", datasetID, "variable", name)
+		response.Fail(c, http.StatusInternalServerError, "获取变量统计失败")
+		return
+	}
+
+	response.Success(c, stats, "获取成功")
+}
+
+// Reingest 重新解析数据集并计算统计信息
+func (h *IngestHandler) Reingest(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	userID, isAdmin := currentUser(c)
+
+	if err := h.ingestService.Reingest(datasetID, userID, isAdmin); err != nil {
+		if errors.Is(err, services.ErrIngestForbidden) {
+			response.Fail(c, http.StatusForbidden, "无权处理此数据集")
+			return
+		}
+		logger.Error("Failed to reingest dataset", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusNotFound, "数据集不存在")
+		return
+	}
+
+	response.Success(c, gin.H{"datasetId": datasetID, "status": "pending"}, "已提交处理")
+}
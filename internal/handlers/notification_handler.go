@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterNotificationRoutes 注册通知相关路由
+func RegisterNotificationRoutes(router *gin.RouterGroup, notificationService services.NotificationService, authMiddleware gin.HandlerFunc) {
+	notificationHandler := &NotificationHandler{notificationService: notificationService}
+
+	notifications := router.Group("/notifications")
+	notifications.Use(authMiddleware)
+	{
+		notifications.GET("", notificationHandler.ListNotifications)
+		notifications.PUT("/:id/read", notificationHandler.MarkRead)
+	}
+}
+
+// NotificationHandler 通知处理器
+type NotificationHandler struct {
+	notificationService services.NotificationService
+}
+
+// ListNotifications 获取当前用户的通知
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	userID, _ := currentUser(c)
+	list, err := h.notificationService.ListNotifications(userID, c.Query("unreadOnly") == "true", page, size)
+	if err != nil {
+		logger.Error("Failed to list notifications", "error", err, "userId", userID)
+		response.Fail(c, http.StatusInternalServerError, "获取通知失败")
+		return
+	}
+
+	response.Success(c, list, "成功")
+}
+
+// MarkRead 标记通知为已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, _ := currentUser(c)
+	notification, err := h.notificationService.MarkRead(c.Param("id"), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			response.NotFound(c, "通知不存在")
+			return
+		}
+		logger.Error("Failed to mark notification as read", "error", err, "notificationId", c.Param("id"))
+		response.Fail(c, http.StatusInternalServerError, "标记通知失败")
+		return
+	}
+
+	response.Success(c, gin.H{"id": notification.ID, "read": notification.Read, "readAt": notification.ReadAt}, "标记成功")
+}
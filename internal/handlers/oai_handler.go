@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+)
+
+// RegisterOAIRoutes 注册OAI-PMH元数据收割路由
+func RegisterOAIRoutes(router *gin.RouterGroup, oaiService services.OAIService) {
+	oaiHandler := &OAIHandler{oaiService: oaiService}
+
+	// 公开接口，协议规定同时支持GET和POST
+	router.GET("/oai", oaiHandler.Handle)
+	router.POST("/oai", oaiHandler.Handle)
+}
+
+// OAIHandler OAI-PMH处理器
+type OAIHandler struct {
+	oaiService services.OAIService
+}
+
+// Handle 处理OAI-PMH请求
+func (h *OAIHandler) Handle(c *gin.Context) {
+	// POST请求参数为application/x-www-form-urlencoded，无法解析时按缺少verb处理
+	var args url.Values
+	if err := c.Request.ParseForm(); err == nil {
+		args = c.Request.Form
+	}
+
+	body, err := h.oaiService.Handle(args)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", body)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterQCRoutes 注册数据质量控制路由
+func RegisterQCRoutes(router *gin.RouterGroup, qcService services.QCService, authMiddleware gin.HandlerFunc) {
+	qcHandler := &QCHandler{qcService: qcService}
+
+	datasets := router.Group("/datasets")
+	{
+		// 公开接口
+		datasets.GET("/:datasetId/qc", qcHandler.GetSummary)
+
+		// 需要认证的接口
+		datasets.GET("/:datasetId/qc/flags", authMiddleware, qcHandler.DownloadFlags)
+		datasets.POST("/:datasetId/qc", authMiddleware, qcHandler.RunQC)
+	}
+}
+
+// QCHandler 质量控制处理器
+type QCHandler struct {
+	qcService services.QCService
+}
+
+// GetSummary 获取数据集的质量控制汇总
+func (h *QCHandler) GetSummary(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+
+	summary, err := h.qcService.GetSummary(datasetID)
+	if err != nil {
+		if errors.Is(err, services.ErrQCNotFound) {
+			response.Fail(c, http.StatusNotFound, "数据集尚未进行质量控制")
+			return
+		}
+		logger.Error("Failed to get QC summary", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusNotFound, "数据集不存在")
+		return
+	}
+
+	response.Success(c, summary, "获取成功")
+}
+
+// DownloadFlags 下载质量控制标志文件
+func (h *QCHandler) DownloadFlags(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+
+	filePath, err := h.qcService.FlagsFile(datasetID)
+	if err != nil {
+		response.Fail(c, http.StatusNotFound, "质量控制标志文件不存在")
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+datasetID+"_qc_flags.nc")
+	c.Header("Content-Description", "File Transfer")
+	c.File(filePath)
+}
+
+// RunQC 按指定配置重新执行质量控制，请求体为空时使用默认配置
+func (h *QCHandler) RunQC(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+	userID, isAdmin := currentUser(c)
+
+	var config *services.QCConfig
+	if c.Request.ContentLength > 0 {
+		config = &services.QCConfig{}
+		if err := c.ShouldBindJSON(config); err != nil {
+			response.Fail(c, http.StatusBadRequest, "无效的质量控制配置")
+			return
+		}
+		if len(config.Variables) == 0 && config.DensityInversion == nil {
+			response.Fail(c, http.StatusBadRequest, "质量控制配置中未指定变量")
+			return
+		}
+	}
+
+	if err := h.qcService.Run(datasetID, userID, isAdmin, config); err != nil {
+		if errors.Is(err, services.ErrQCForbidden) {
+			response.Fail(c, http.StatusForbidden, "无权处理此数据集")
+			return
+		}
+		logger.Error("Failed to run QC", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusNotFound, "数据集不存在或没有数据文件")
+		return
+	}
+
+	response.Success(c, gin.H{"datasetId": datasetID, "status": "running"}, "已提交质量控制")
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterQuotaRoutes 注册存储配额相关路由
+func RegisterQuotaRoutes(router *gin.RouterGroup, quotaService services.QuotaService, authMiddleware gin.HandlerFunc) {
+	quotaHandler := &QuotaHandler{quotaService: quotaService}
+
+	// 当前用户用量
+	router.GET("/users/current/usage", authMiddleware, quotaHandler.GetCurrentUsage)
+
+	// 配额管理(需要管理员权限)
+	quotas := router.Group("/system/quotas")
+	quotas.Use(authMiddleware, AdminRequired())
+	{
+		quotas.GET("", quotaHandler.ListQuotas)
+		quotas.GET("/users/:subject/usage", quotaHandler.GetUserUsage)
+		quotas.PUT("/:scope/:subject", quotaHandler.SetQuota)
+		quotas.DELETE("/:scope/:subject", quotaHandler.DeleteQuota)
+	}
+}
+
+// QuotaHandler 存储配额处理器
+type QuotaHandler struct {
+	quotaService services.QuotaService
+}
+
+// quotaRequest 设置配额请求
+type quotaRequest struct {
+	MaxBytes    int64 `json:"maxBytes"`
+	MaxDatasets int   `json:"maxDatasets"`
+}
+
+// quotaScopes URL中的作用范围与模型常量的对应关系
+var quotaScopes = map[string]string{
+	"users":         models.QuotaScopeUser,
+	"organizations": models.QuotaScopeOrganization,
+}
+
+// GetCurrentUsage 获取当前用户的存储用量与配额
+func (h *QuotaHandler) GetCurrentUsage(c *gin.Context) {
+	userID, _ := c.Get("userId")
+	h.respondUsage(c, userID.(string))
+}
+
+// GetUserUsage 获取指定用户的存储用量与配额
+func (h *QuotaHandler) GetUserUsage(c *gin.Context) {
+	h.respondUsage(c, c.Param("subject"))
+}
+
+// respondUsage 返回用户用量报告
+func (h *QuotaHandler) respondUsage(c *gin.Context, userID string) {
+	report, err := h.quotaService.GetUserUsage(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		logger.Error("Failed to get storage usage", "error", err, "userId", userID)
+		response.Fail(c, http.StatusInternalServerError, "获取存储用量失败")
+		return
+	}
+
+	response.Success(c, report, "获取成功")
+}
+
+// ListQuotas 获取配额列表
+func (h *QuotaHandler) ListQuotas(c *gin.Context) {
+	scope := ""
+	if s := c.Query("scope"); s != "" {
+		var ok bool
+		if scope, ok = quotaScopes[s]; !ok {
+			response.Fail(c, http.StatusBadRequest, "无效的配额范围")
+			return
+		}
+	}
+
+	quotas, err := h.quotaService.ListQuotas(scope)
+	if err != nil {
+		logger.Error("Failed to list quotas", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取配额列表失败")
+		return
+	}
+
+	response.Success(c, quotas, "获取成功")
+}
+
+// SetQuota 设置用户或组织配额
+func (h *QuotaHandler) SetQuota(c *gin.Context) {
+	scope, ok := quotaScopes[c.Param("scope")]
+	if !ok {
+		response.Fail(c, http.StatusBadRequest, "无效的配额范围")
+		return
+	}
+
+	var req quotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, "请求格式错误")
+		return
+	}
+	if req.MaxBytes < 0 || req.MaxDatasets < 0 {
+		response.Fail(c, http.StatusBadRequest, "配额不能为负数")
+		return
+	}
+
+	userID, _ := c.Get("userId")
+	quota, err := h.quotaService.SetQuota(scope, c.Param("subject"), req.MaxBytes, req.MaxDatasets, userID.(string))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		logger.Error("Failed to set quota", "error", err, "scope", scope, "subject", c.Param("subject"))
+		response.Fail(c, http.StatusInternalServerError, "设置配额失败")
+		return
+	}
+
+	response.Success(c, quota, "设置成功")
+}
+
+// DeleteQuota 删除配额，恢复为默认值
+func (h *QuotaHandler) DeleteQuota(c *gin.Context) {
+	scope, ok := quotaScopes[c.Param("scope")]
+	if !ok {
+		response.Fail(c, http.StatusBadRequest, "无效的配额范围")
+		return
+	}
+
+	userID, _ := c.Get("userId")
+	if err := h.quotaService.DeleteQuota(scope, c.Param("subject"), userID.(string)); err != nil {
+		logger.Error("Failed to delete quota", "error", err, "scope", scope, "subject", c.Param("subject"))
+		response.Fail(c, http.StatusInternalServerError, "删除配额失败")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "已恢复默认配额"}, "删除成功")
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+)
+
+// RegisterSTACRoutes 注册STAC目录路由
+func RegisterSTACRoutes(router *gin.RouterGroup, stacService services.STACService) {
+	stacHandler := &STACHandler{stacService: stacService}
+
+	// 公开接口，供pystac、STAC Browser等客户端访问
+	stac := router.Group("/stac")
+	{
+		stac.GET("", stacHandler.GetCatalog)
+		stac.GET("/conformance", stacHandler.GetConformance)
+		stac.GET("/collections", stacHandler.ListCollections)
+		stac.GET("/collections/:collectionId", stacHandler.GetCollection)
+		stac.GET("/collections/:collectionId/items", stacHandler.ListItems)
+		stac.GET("/collections/:collectionId/items/:itemId", stacHandler.GetItem)
+		stac.GET("/search", stacHandler.SearchGet)
+		stac.POST("/search", stacHandler.SearchPost)
+	}
+}
+
+// STACHandler STAC目录处理器
+type STACHandler struct {
+	stacService services.STACService
+}
+
+// GetCatalog 根目录
+func (h *STACHandler) GetCatalog(c *gin.Context) {
+	c.JSON(http.StatusOK, h.stacService.GetCatalog())
+}
+
+// GetConformance 一致性声明
+func (h *STACHandler) GetConformance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.stacService.GetConformance())
+}
+
+// ListCollections 集合列表
+func (h *STACHandler) ListCollections(c *gin.Context) {
+	collections, err := h.stacService.ListCollections()
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, collections)
+}
+
+// GetCollection 集合详情
+func (h *STACHandler) GetCollection(c *gin.Context) {
+	collection, err := h.stacService.GetCollection(c.Param("collectionId"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+// ListItems 集合内条目列表
+func (h *STACHandler) ListItems(c *gin.Context) {
+	search, err := parseSTACQuery(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	items, err := h.stacService.ListItems(c.Param("collectionId"), search)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.geoJSON(c, items)
+}
+
+// GetItem 条目详情
+func (h *STACHandler) GetItem(c *gin.Context) {
+	item, err := h.stacService.GetItem(c.Param("collectionId"), c.Param("itemId"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.geoJSON(c, item)
+}
+
+// SearchGet GET方式检索
+func (h *STACHandler) SearchGet(c *gin.Context) {
+	search, err := parseSTACQuery(c)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	if collections := c.Query("collections"); collections != "" {
+		search.Collections = strings.Split(collections, ",")
+	}
+	if ids := c.Query("ids"); ids != "" {
+		search.IDs = strings.Split(ids, ",")
+	}
+
+	result, err := h.stacService.Search(search, http.MethodGet)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.geoJSON(c, result)
+}
+
+// SearchPost POST方式检索
+func (h *STACHandler) SearchPost(c *gin.Context) {
+	var search services.STACSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		h.fail(c, services.ErrSTACInvalidQuery)
+		return
+	}
+
+	result, err := h.stacService.Search(&search, http.MethodPost)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	h.geoJSON(c, result)
+}
+
+// parseSTACQuery 解析GET请求中的检索参数
+func parseSTACQuery(c *gin.Context) (*services.STACSearch, error) {
+	search := &services.STACSearch{
+		Datetime: c.Query("datetime"),
+	}
+	search.Limit, _ = strconv.Atoi(c.Query("limit"))
+	search.Page, _ = strconv.Atoi(c.Query("page"))
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		for _, part := range strings.Split(bbox, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, services.ErrSTACInvalidQuery
+			}
+			search.BBox = append(search.BBox, value)
+		}
+	}
+	if intersects := c.Query("intersects"); intersects != "" {
+		search.Intersects = &services.GeoJSONGeometry{}
+		if err := json.Unmarshal([]byte(intersects), search.Intersects); err != nil {
+			return nil, services.ErrSTACInvalidQuery
+		}
+	}
+	if query := c.Query("query"); query != "" {
+		if err := json.Unmarshal([]byte(query), &search.Query); err != nil {
+			return nil, services.ErrSTACInvalidQuery
+		}
+	}
+	return search, nil
+}
+
+// geoJSON 返回GeoJSON响应
+func (h *STACHandler) geoJSON(c *gin.Context, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/geo+json", body)
+}
+
+// fail 按OGC API规范返回错误
+func (h *STACHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSTACNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": "NotFound", "description": err.Error()})
+	case errors.Is(err, services.ErrSTACInvalidQuery):
+		c.JSON(http.StatusBadRequest, gin.H{"code": "InvalidParameterValue", "description": err.Error()})
+	default:
+		logger.Error("STAC request failed", "error", err, "uri", c.Request.RequestURI)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "ServerError", "description": "服务器内部错误"})
+	}
+}
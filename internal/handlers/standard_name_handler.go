@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterStandardNameRoutes 注册CF标准名、变量别名与单位换算路由
+func RegisterStandardNameRoutes(router *gin.RouterGroup, standardNameService services.StandardNameService, authMiddleware gin.HandlerFunc) {
+	standardNameHandler := &StandardNameHandler{standardNameService: standardNameService}
+
+	// 公开接口
+	router.GET("/standard-names/aliases", standardNameHandler.ListAliases)
+	router.GET("/datasets/:datasetId/standard-names", standardNameHandler.ResolveDataset)
+	router.GET("/units/convert", standardNameHandler.ConvertUnits)
+
+	// 别名管理(需要管理员权限)
+	aliases := router.Group("/standard-names/aliases")
+	aliases.Use(authMiddleware, AdminRequired())
+	{
+		aliases.PUT("/:name", standardNameHandler.SaveAlias)
+		aliases.DELETE("/:name", standardNameHandler.DeleteAlias)
+	}
+}
+
+// StandardNameHandler CF标准名处理器
+type StandardNameHandler struct {
+	standardNameService services.StandardNameService
+}
+
+// aliasRequest 设置别名请求
+type aliasRequest struct {
+	StandardName string `json:"standardName" binding:"required"`
+	Units        string `json:"units"`
+	Description  string `json:"description"`
+}
+
+// ListAliases 获取变量别名表
+func (h *StandardNameHandler) ListAliases(c *gin.Context) {
+	aliases, err := h.standardNameService.ListAliases()
+	if err != nil {
+		logger.Error("Failed to list variable aliases", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取变量别名失败")
+		return
+	}
+
+	response.Success(c, aliases, "获取成功")
+}
+
+// ResolveDataset 获取数据集各变量对应的标准名和单位
+func (h *StandardNameHandler) ResolveDataset(c *gin.Context) {
+	datasetID := c.Param("datasetId")
+
+	variables, err := h.standardNameService.ResolveDataset(datasetID)
+	if err != nil {
+		logger.Error("Failed to resolve dataset variables", "error", err, "datasetId", datasetID)
+		response.Fail(c, http.StatusNotFound, "数据集不存在或无法解析")
+		return
+	}
+
+	response.Success(c, variables, "获取成功")
+}
+
+// ConvertUnits 单位换算
+func (h *StandardNameHandler) ConvertUnits(c *gin.Context) {
+	from := c.Query("from")
+	to := c.Query("to")
+	value, err := strconv.ParseFloat(c.DefaultQuery("value", "1"), 64)
+	if err != nil || from == "" || to == "" {
+		response.Fail(c, http.StatusBadRequest, "请提供有效的value、from和to参数")
+		return
+	}
+
+	result, err := h.standardNameService.ConvertUnits(value, from, to)
+	if err != nil {
+		response.Fail(c, http.StatusBadRequest, "单位无法换算: "+err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"value": value, "from": from, "to": to, "result": result}, "换算成功")
+}
+
+// SaveAlias 新增或修改变量别名
+func (h *StandardNameHandler) SaveAlias(c *gin.Context) {
+	name := c.Param("name")
+	userID, _ := currentUser(c)
+
+	var req aliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Fail(c, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	alias, err := h.standardNameService.SaveAlias(name, req.StandardName, req.Units, req.Description, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUnits) {
+			response.Fail(c, http.StatusBadRequest, "无法识别的单位: "+req.Units)
+			return
+		}
+		logger.Error("Failed to save variable alias", "error", err, "name", name)
+		response.Fail(c, http.StatusInternalServerError, "保存变量别名失败")
+		return
+	}
+
+	response.Success(c, alias, "保存成功")
+}
+
+// DeleteAlias 删除变量别名
+func (h *StandardNameHandler) DeleteAlias(c *gin.Context) {
+	name := c.Param("name")
+	userID, _ := currentUser(c)
+
+	if err := h.standardNameService.DeleteAlias(name, userID); err != nil {
+		if errors.Is(err, services.ErrAliasNotFound) {
+			response.Fail(c, http.StatusNotFound, "变量别名不存在")
+			return
+		}
+		logger.Error("Failed to delete variable alias", "error", err, "name", name)
+		response.Fail(c, http.StatusInternalServerError, "删除变量别名失败")
+		return
+	}
+
+	response.Success(c, nil, "删除成功")
+}
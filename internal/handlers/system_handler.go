@@ -44,7 +44,7 @@ type SystemHandler struct {
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户角色
-		role, exists := c.Get("userRole")
+		role, exists := c.Get("role")
 		if !exists {
 			response.Fail(c, http.StatusUnauthorized, "未登录")
 			c.Abort()
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinker/ssop/internal/services"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/response"
+)
+
+// RegisterTrashRoutes 注册回收站相关路由
+func RegisterTrashRoutes(router *gin.RouterGroup, trashService services.TrashService, authMiddleware gin.HandlerFunc) {
+	trashHandler := &TrashHandler{trashService: trashService}
+
+	trash := router.Group("/trash")
+	trash.Use(authMiddleware)
+	{
+		trash.GET("", trashHandler.ListTrash)
+
+		// 恢复
+		trash.POST("/datasets/:id/restore", trashHandler.RestoreDataset)
+		trash.POST("/tasks/:id/restore", trashHandler.RestoreTask)
+		trash.POST("/results/:id/restore", trashHandler.RestoreResult)
+
+		// 彻底删除(需要管理员权限)
+		admin := trash.Group("")
+		admin.Use(AdminRequired())
+		{
+			admin.DELETE("/datasets/:id", trashHandler.PurgeDataset)
+			admin.DELETE("/tasks/:id", trashHandler.PurgeTask)
+			admin.DELETE("/results/:id", trashHandler.PurgeResult)
+			admin.POST("/purge", trashHandler.PurgeExpired)
+		}
+	}
+}
+
+// TrashHandler 回收站处理器
+type TrashHandler struct {
+	trashService services.TrashService
+}
+
+// currentUser 获取当前用户ID及是否为管理员
+func currentUser(c *gin.Context) (string, bool) {
+	userID, _ := c.Get("userId")
+	role, _ := c.Get("role")
+	id, _ := userID.(string)
+	return id, role == "admin"
+}
+
+// ListTrash 获取回收站内容
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID, isAdmin := currentUser(c)
+
+	// 管理员可查看所有用户的回收站
+	if isAdmin && c.Query("all") == "true" {
+		userID = ""
+	}
+
+	listing, err := h.trashService.ListTrash(userID)
+	if err != nil {
+		logger.Error("Failed to list trash", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "获取回收站失败")
+		return
+	}
+
+	response.Success(c, listing, "获取成功")
+}
+
+// RestoreDataset 恢复数据集
+func (h *TrashHandler) RestoreDataset(c *gin.Context) {
+	userID, isAdmin := currentUser(c)
+	h.respond(c, h.trashService.RestoreDataset(c.Param("id"), userID, isAdmin), "恢复")
+}
+
+// RestoreTask 恢复分析任务
+func (h *TrashHandler) RestoreTask(c *gin.Context) {
+	userID, isAdmin := currentUser(c)
+	h.respond(c, h.trashService.RestoreTask(c.Param("id"), userID, isAdmin), "恢复")
+}
+
+// RestoreResult 恢复分析结果
+func (h *TrashHandler) RestoreResult(c *gin.Context) {
+	userID, isAdmin := currentUser(c)
+	h.respond(c, h.trashService.RestoreResult(c.Param("id"), userID, isAdmin), "恢复")
+}
+
+// PurgeDataset 彻底删除数据集
+func (h *TrashHandler) PurgeDataset(c *gin.Context) {
+	userID, _ := currentUser(c)
+	h.respond(c, h.trashService.PurgeDataset(c.Param("id"), userID), "彻底删除")
+}
+
+// PurgeTask 彻底删除分析任务
+func (h *TrashHandler) PurgeTask(c *gin.Context) {
+	userID, _ := currentUser(c)
+	h.respond(c, h.trashService.PurgeTask(c.Param("id"), userID), "彻底删除")
+}
+
+// PurgeResult 彻底删除分析结果
+func (h *TrashHandler) PurgeResult(c *gin.Context) {
+	userID, _ := currentUser(c)
+	h.respond(c, h.trashService.PurgeResult(c.Param("id"), userID), "彻底删除")
+}
+
+// PurgeExpired 立即清理超过保留期限的项目
+func (h *TrashHandler) PurgeExpired(c *gin.Context) {
+	count, err := h.trashService.PurgeExpired()
+	if err != nil {
+		logger.Error("Failed to purge expired trash", "error", err)
+		response.Fail(c, http.StatusInternalServerError, "清理回收站失败")
+		return
+	}
+
+	response.Success(c, gin.H{"purged": count}, "清理成功")
+}
+
+// respond 根据操作结果返回响应
+func (h *TrashHandler) respond(c *gin.Context, err error, action string) {
+	switch {
+	case err == nil:
+		response.Success(c, gin.H{"id": c.Param("id")}, action+"成功")
+	case errors.Is(err, services.ErrTrashItemNotFound):
+		response.Fail(c, http.StatusNotFound, "回收站中不存在该项目")
+	case errors.Is(err, services.ErrTrashForbidden):
+		response.Fail(c, http.StatusForbidden, "无权操作此项目")
+	default:
+		logger.Error("Failed to process trash item", "error", err, "id", c.Param("id"), "action", action)
+		response.Fail(c, http.StatusInternalServerError, action+"失败")
+	}
+}
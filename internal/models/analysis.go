@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // AnalysisTask 分析任务模型
@@ -31,6 +33,7 @@ type AnalysisTask struct {
 	StartedAt   *time.Time `json:"startedAt"`
 	CompletedAt *time.Time `json:"completedAt"`
 	UpdatedAt   *time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"` // 软删除时间
 }
 
 // TableName 表名
@@ -49,6 +52,7 @@ type AnalysisResult struct {
 	Type        string     `json:"type" gorm:"type:varchar(20)"` // 如: chart, table, map, file
 	Format      string     `json:"format" gorm:"type:varchar(20)"` // 如: json, csv, netcdf, png
 	FilePath    string     `json:"filePath" gorm:"type:varchar(255)"`
+	Size        int64      `json:"size"` // 结果文件大小(字节)，计入存储配额
 	
 	// 预览数据
 	PreviewData string     `json:"previewData" gorm:"type:text"` // 预览数据的JSON表示
@@ -59,6 +63,7 @@ type AnalysisResult struct {
 	// 创建和更新信息
 	CreatedAt   *time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt   *time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"` // 软删除时间
 }
 
 // TableName 表名
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Climatology 气候态: 源数据集在基准期内的逐月或逐日平均，结果保存为派生数据集
+type Climatology struct {
+	ID               uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DatasetID        string     `json:"datasetId" gorm:"type:varchar(32);index:idx_climatology_source,priority:1"` // 源数据集
+	Period           string     `json:"period" gorm:"type:varchar(10);index:idx_climatology_source,priority:2"`    // monthly, daily
+	BaselineStart    int        `json:"baselineStart"`                                                             // 基准期起始年
+	BaselineEnd      int        `json:"baselineEnd"`                                                               // 基准期结束年(含)
+	Window           int        `json:"window"`                                                                    // 逐日气候态的滑动平均窗口(天)
+	Variables        string     `json:"variables" gorm:"type:text"`                                                // JSON格式存储变量名列表
+	DerivedDatasetID string     `json:"derivedDatasetId" gorm:"type:varchar(32);index"`                            // 保存气候态的派生数据集
+	TaskID           string     `json:"taskId" gorm:"type:varchar(32)"`
+	CreatedBy        string     `json:"createdBy" gorm:"type:varchar(32)"`
+	CreatedAt        *time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName 表名
+func (Climatology) TableName() string {
+	return "climatologies"
+}
+
+// VariableNames 解析变量名列表
+func (c *Climatology) VariableNames() []string {
+	var names []string
+	if c.Variables == "" {
+		return names
+	}
+	if err := json.Unmarshal([]byte(c.Variables), &names); err != nil {
+		return nil
+	}
+	return names
+}
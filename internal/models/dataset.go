@@ -1,7 +1,11 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Dataset 数据集模型
@@ -30,9 +34,22 @@ type Dataset struct {
 	Source      string    `json:"source" gorm:"type:varchar(100)"`
 	Methodology string    `json:"methodology" gorm:"type:varchar(255)"`
 	
+	// 引用信息
+	DOI         string    `json:"doi" gorm:"type:varchar(100);index"`
+	License     string    `json:"license" gorm:"type:varchar(100)"`
+	Version     string    `json:"version" gorm:"type:varchar(20)"`
+	Publisher   string    `json:"publisher" gorm:"type:varchar(100)"`
+	
 	// 文件路径
 	FilePath    string    `json:"filePath" gorm:"type:varchar(255)"`
 	
+	// 入库处理状态
+	IngestStatus  string  `json:"ingestStatus" gorm:"type:varchar(20);default:'pending'"` // pending, processing, ready, stored, failed
+	IngestMessage string  `json:"ingestMessage" gorm:"type:text"`                          // 处理失败原因
+	
+	// 质量控制汇总(JSON)，通过 /datasets/:id/qc 获取
+	QCSummary     string  `json:"-" gorm:"type:longtext"`
+	
 	// 统计信息
 	DownloadCount int       `json:"downloadCount" gorm:"default:0"`
 	
@@ -43,6 +60,7 @@ type Dataset struct {
 	CreatedBy   string    `json:"createdBy" gorm:"type:varchar(32)"`
 	CreatedAt   *time.Time `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt   *time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deletedAt,omitempty" gorm:"index"` // 软删除时间，非空表示在回收站中
 }
 
 // TableName 表名
@@ -50,6 +68,41 @@ func (Dataset) TableName() string {
 	return "datasets"
 }
 
+// Bounds 解析区域边界 [minLat, minLng, maxLat, maxLng]
+func (d *Dataset) Bounds() ([4]float64, bool) {
+	var bounds [4]float64
+	if d.RegionBounds == "" {
+		return bounds, false
+	}
+	if err := json.Unmarshal([]byte(d.RegionBounds), &bounds); err != nil {
+		return bounds, false
+	}
+	return bounds, true
+}
+
+// VariableList 解析变量列表
+func (d *Dataset) VariableList() []VariableInfo {
+	var variables []VariableInfo
+	if d.Variables == "" {
+		return variables
+	}
+	if err := json.Unmarshal([]byte(d.Variables), &variables); err != nil {
+		return nil
+	}
+	return variables
+}
+
+// TagList 解析标签列表
+func (d *Dataset) TagList() []string {
+	var tags []string
+	for _, tag := range strings.Split(d.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // VariableInfo 变量信息
 type VariableInfo struct {
 	Name        string    `json:"name"`
@@ -62,4 +115,36 @@ type VariableInfo struct {
 type Region struct {
 	Name   string    `json:"name"`
 	Bounds [4]float64 `json:"bounds"` // [minLat, minLng, maxLat, maxLng]
-} 
\ No newline at end of file
+}
+
+// 入库处理状态
+const (
+	IngestPending    = "pending"    // 等待处理
+	IngestProcessing = "processing" // 处理中
+	IngestReady      = "ready"      // 已解析，可用于分析
+	IngestStored     = "stored"     // 格式无法解析，仅作为文件存储
+	IngestFailed     = "failed"     // 处理失败
+)
+
+// VariableStats 变量统计信息
+type VariableStats struct {
+	ID          uint       `json:"-" gorm:"primaryKey;autoIncrement"`
+	DatasetID   string     `json:"datasetId" gorm:"type:varchar(32);uniqueIndex:idx_dataset_variable"`
+	Variable    string     `json:"variable" gorm:"type:varchar(100);uniqueIndex:idx_dataset_variable"`
+	Unit        string     `json:"unit" gorm:"type:varchar(50)"`
+	Count       int64      `json:"count"`   // 有效值个数
+	Missing     int64      `json:"missing"` // 缺测值个数
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+	Mean        float64    `json:"mean"`
+	Std         float64    `json:"std"`
+	Percentiles string     `json:"-" gorm:"type:text"` // JSON格式: {"p50": 12.3, ...}
+	Histogram   string     `json:"-" gorm:"type:text"` // JSON格式: {"edges": [...], "counts": [...]}
+	Exact       bool       `json:"exact"`              // 分位数是否为精确值，否则为抽样估计
+	ComputedAt  *time.Time `json:"computedAt"`
+}
+
+// TableName 表名
+func (VariableStats) TableName() string {
+	return "variable_stats"
+}
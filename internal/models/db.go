@@ -34,6 +34,12 @@ func InitDB(config config.DBConfig) (*gorm.DB, error) {
 		&AnalysisResult{},
 		&SystemSetting{},
 		&AuditLog{},
+		&StorageQuota{},
+		&VariableStats{},
+		&VariableAlias{},
+		&Profile{},
+		&Climatology{},
+		&Notification{},
 	)
 	
 	return db, err
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 通知类型
+const (
+	NotificationMarineHeatwave = "marine_heatwave" // 海洋热浪预警
+)
+
+// Notification 用户通知
+type Notification struct {
+	ID        string     `json:"id" gorm:"primaryKey;type:varchar(32)"`
+	UserID    string     `json:"userId" gorm:"type:varchar(32);index:idx_notification_user,priority:1"`
+	Type      string     `json:"type" gorm:"type:varchar(30);index"`
+	Title     string     `json:"title" gorm:"type:varchar(100)"`
+	Content   string     `json:"content" gorm:"type:text"`
+	Data      string     `json:"-" gorm:"type:text"` // JSON格式存储附加数据
+	Read      bool       `json:"read" gorm:"index:idx_notification_user,priority:2"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt *time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// TableName 表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// Payload 解析附加数据
+func (n *Notification) Payload() map[string]interface{} {
+	data := map[string]interface{}{}
+	if n.Data == "" {
+		return data
+	}
+	if err := json.Unmarshal([]byte(n.Data), &data); err != nil {
+		return map[string]interface{}{}
+	}
+	return data
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Profile 垂直剖面(Argo浮标等)，按浮标、循环、位置和时间建立索引，逐层数据以JSON存放在Levels中
+type Profile struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	DatasetID   string     `json:"datasetId" gorm:"type:varchar(32);index"`
+	Platform    string     `json:"platform" gorm:"type:varchar(16);index:idx_profile_float,priority:1"` // WMO浮标编号
+	Cycle       int        `json:"cycle" gorm:"index:idx_profile_float,priority:2"`                     // 循环编号
+	Direction   string     `json:"direction" gorm:"type:varchar(1)"`                                    // A上升，D下降
+	DataMode    string     `json:"dataMode" gorm:"type:varchar(1)"`                                     // R实时，A实时调整，D延时模式
+	Time        *time.Time `json:"time" gorm:"index"`
+	TimeQC      string     `json:"timeQc" gorm:"type:varchar(1)"`
+	Latitude    float64    `json:"latitude" gorm:"index:idx_profile_position,priority:1"`
+	Longitude   float64    `json:"longitude" gorm:"index:idx_profile_position,priority:2"`
+	PositionQC  string     `json:"positionQc" gorm:"type:varchar(1)"`
+	Levels      int        `json:"levels"`                 // 层数
+	MaxPressure float64    `json:"maxPressure"`            // 最大有效压力(dbar)
+	Data        string     `json:"-" gorm:"type:longtext"` // JSON格式: {"pres": [...], "temp": [...], "psal": [...], "presQc": "...", ...}
+	CreatedAt   *time.Time `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+// TableName 表名
+func (Profile) TableName() string {
+	return "profiles"
+}
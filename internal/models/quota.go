@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// 配额作用范围
+const (
+	QuotaScopeUser         = "user"
+	QuotaScopeOrganization = "organization"
+)
+
+// StorageQuota 存储配额模型，未设置时用户使用所属角色的默认配额，组织不受限制
+type StorageQuota struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:varchar(32)"`
+	Scope       string     `json:"scope" gorm:"type:varchar(20);uniqueIndex:idx_quota_subject"`    // user, organization
+	Subject     string     `json:"subject" gorm:"type:varchar(100);uniqueIndex:idx_quota_subject"` // 用户ID或组织名称
+	MaxBytes    int64      `json:"maxBytes"`                                                      // 最大存储字节数，0表示不限
+	MaxDatasets int        `json:"maxDatasets"`                                                   // 最大数据集数量，0表示不限
+	UpdatedBy   string     `json:"updatedBy" gorm:"type:varchar(32)"`
+	CreatedAt   *time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt   *time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName 表名
+func (StorageQuota) TableName() string {
+	return "storage_quotas"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// VariableAlias 变量别名模型，将数据文件中的变量名映射到CF标准名，覆盖同名的内置别名
+type VariableAlias struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name         string     `json:"name" gorm:"type:varchar(100);uniqueIndex"`   // 变量名，匹配时不区分大小写
+	StandardName string     `json:"standardName" gorm:"type:varchar(200);index"` // CF标准名
+	Units        string     `json:"units" gorm:"type:varchar(50)"`               // 变量缺少units属性时假定的单位
+	Description  string     `json:"description" gorm:"type:varchar(255)"`
+	UpdatedBy    string     `json:"updatedBy" gorm:"type:varchar(32)"`
+	CreatedAt    *time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    *time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+// TableName 表名
+func (VariableAlias) TableName() string {
+	return "variable_aliases"
+}
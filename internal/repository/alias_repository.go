@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/sinker/ssop/internal/models"
+	"gorm.io/gorm"
+)
+
+// AliasRepository 变量别名仓库接口
+type AliasRepository interface {
+	List() ([]*models.VariableAlias, error)
+	GetByName(name string) (*models.VariableAlias, error)
+	Save(alias *models.VariableAlias) error
+	Delete(name string) error
+}
+
+// aliasRepository 变量别名仓库实现
+type aliasRepository struct {
+	db *gorm.DB
+}
+
+// NewAliasRepository 创建变量别名仓库
+func NewAliasRepository(db *gorm.DB) AliasRepository {
+	return &aliasRepository{db: db}
+}
+
+// List 获取全部别名
+func (r *aliasRepository) List() ([]*models.VariableAlias, error) {
+	var aliases []*models.VariableAlias
+	err := r.db.Order("standard_name ASC, name ASC").Find(&aliases).Error
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// GetByName 按变量名获取别名(不区分大小写)
+func (r *aliasRepository) GetByName(name string) (*models.VariableAlias, error) {
+	var alias models.VariableAlias
+	err := r.db.Where("LOWER(name) = ?", strings.ToLower(name)).First(&alias).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// Save 保存别名
+func (r *aliasRepository) Save(alias *models.VariableAlias) error {
+	return r.db.Save(alias).Error
+}
+
+// Delete 删除别名
+func (r *aliasRepository) Delete(name string) error {
+	return r.db.Where("LOWER(name) = ?", strings.ToLower(name)).Delete(&models.VariableAlias{}).Error
+}
@@ -1,7 +1,7 @@
 package repository
 
 import (
-	_ "time"
+	"time"
 
 	"github.com/sinker/ssop/internal/models"
 	"gorm.io/gorm"
@@ -21,6 +21,16 @@ type AnalysisRepository interface {
 	GetResultByID(id string) (*models.AnalysisResult, error)
 	ListResultsByTaskID(taskID string) ([]*models.AnalysisResult, error)
 	DeleteResult(id string) error
+
+	// 回收站相关
+	GetDeletedTaskByID(id string) (*models.AnalysisTask, error)
+	ListDeletedTasks(userID string, before *time.Time) ([]*models.AnalysisTask, error)
+	RestoreTask(id string) error
+	PurgeTask(id string) ([]*models.AnalysisResult, error)
+	GetDeletedResultByID(id string) (*models.AnalysisResult, error)
+	ListDeletedResults(userID string, before *time.Time) ([]*models.AnalysisResult, error)
+	RestoreResult(id string) error
+	PurgeResult(id string) error
 }
 
 // analysisRepository 分析功能仓库实现
@@ -89,9 +99,17 @@ func (r *analysisRepository) UpdateTask(task *models.AnalysisTask) error {
 	return r.db.Save(task).Error
 }
 
-// DeleteTask 删除分析任务
+// DeleteTask 删除分析任务(软删除)，其下未删除的结果使用相同的删除时间一并软删除，便于整体恢复
 func (r *analysisRepository) DeleteTask(id string) error {
-	return r.db.Where("id = ?", id).Delete(&models.AnalysisTask{}).Error
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AnalysisResult{}).
+			Where("task_id = ?", id).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.AnalysisTask{}).Where("id = ?", id).Update("deleted_at", now).Error
+	})
 }
 
 // CreateResult 创建分析结果
@@ -119,7 +137,112 @@ func (r *analysisRepository) ListResultsByTaskID(taskID string) ([]*models.Analy
 	return results, nil
 }
 
-// DeleteResult 删除分析结果
+// DeleteResult 删除分析结果(软删除)
 func (r *analysisRepository) DeleteResult(id string) error {
 	return r.db.Where("id = ?", id).Delete(&models.AnalysisResult{}).Error
-} 
\ No newline at end of file
+}
+
+// GetDeletedTaskByID 根据ID获取回收站中的分析任务
+func (r *analysisRepository) GetDeletedTaskByID(id string) (*models.AnalysisTask, error) {
+	var task models.AnalysisTask
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ListDeletedTasks 获取回收站中的分析任务，userID为空时不限用户，before非空时仅返回该时间之前删除的记录
+func (r *analysisRepository) ListDeletedTasks(userID string, before *time.Time) ([]*models.AnalysisTask, error) {
+	var tasks []*models.AnalysisTask
+
+	query := r.db.Unscoped().Where("deleted_at IS NOT NULL")
+	if userID != "" {
+		query = query.Where("created_by = ?", userID)
+	}
+	if before != nil {
+		query = query.Where("deleted_at < ?", *before)
+	}
+
+	err := query.Order("deleted_at DESC").Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// RestoreTask 从回收站恢复分析任务，同时恢复随任务一起删除的结果
+func (r *analysisRepository) RestoreTask(id string) error {
+	task, err := r.GetDeletedTaskByID(id)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.AnalysisResult{}).
+			Where("task_id = ? AND deleted_at = ?", id, task.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.AnalysisTask{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+}
+
+// PurgeTask 彻底删除分析任务及其全部结果记录，返回被删除的结果以便清理文件
+func (r *analysisRepository) PurgeTask(id string) ([]*models.AnalysisResult, error) {
+	var results []*models.AnalysisResult
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("task_id = ?", id).Find(&results).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("task_id = ?", id).Delete(&models.AnalysisResult{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&models.AnalysisTask{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetDeletedResultByID 根据ID获取回收站中的分析结果
+func (r *analysisRepository) GetDeletedResultByID(id string) (*models.AnalysisResult, error) {
+	var result models.AnalysisResult
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListDeletedResults 获取单独删除的分析结果(所属任务仍存在)，userID为空时不限用户
+func (r *analysisRepository) ListDeletedResults(userID string, before *time.Time) ([]*models.AnalysisResult, error) {
+	var results []*models.AnalysisResult
+
+	query := r.db.Unscoped().Model(&models.AnalysisResult{}).
+		Joins("JOIN analysis_tasks ON analysis_tasks.id = analysis_results.task_id").
+		Where("analysis_results.deleted_at IS NOT NULL AND analysis_tasks.deleted_at IS NULL")
+	if userID != "" {
+		query = query.Where("analysis_tasks.created_by = ?", userID)
+	}
+	if before != nil {
+		query = query.Where("analysis_results.deleted_at < ?", *before)
+	}
+
+	err := query.Select("analysis_results.*").Order("analysis_results.deleted_at DESC").Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// RestoreResult 从回收站恢复分析结果
+func (r *analysisRepository) RestoreResult(id string) error {
+	return r.db.Unscoped().Model(&models.AnalysisResult{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+// PurgeResult 彻底删除分析结果记录
+func (r *analysisRepository) PurgeResult(id string) error {
+	return r.db.Unscoped().Where("id = ?", id).Delete(&models.AnalysisResult{}).Error
+}
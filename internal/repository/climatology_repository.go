@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/sinker/ssop/internal/models"
+	"gorm.io/gorm"
+)
+
+// ClimatologyRepository 气候态仓库接口
+type ClimatologyRepository interface {
+	Create(climatology *models.Climatology) error
+	GetByID(id uint) (*models.Climatology, error)
+	// Find 查找源数据集最近生成的气候态，period为空、baselineStart/baselineEnd为0时不限制
+	Find(datasetID, period string, baselineStart, baselineEnd int) (*models.Climatology, error)
+	// ListByDataset 源数据集的全部气候态，按生成时间倒序
+	ListByDataset(datasetID string) ([]*models.Climatology, error)
+}
+
+// climatologyRepository 气候态仓库实现
+type climatologyRepository struct {
+	db *gorm.DB
+}
+
+// NewClimatologyRepository 创建气候态仓库
+func NewClimatologyRepository(db *gorm.DB) ClimatologyRepository {
+	return &climatologyRepository{db: db}
+}
+
+// Create 创建气候态记录
+func (r *climatologyRepository) Create(climatology *models.Climatology) error {
+	return r.db.Create(climatology).Error
+}
+
+// GetByID 根据ID获取气候态
+func (r *climatologyRepository) GetByID(id uint) (*models.Climatology, error) {
+	var climatology models.Climatology
+	if err := r.available().Where("climatologies.id = ?", id).First(&climatology).Error; err != nil {
+		return nil, err
+	}
+	return &climatology, nil
+}
+
+// Find 查找源数据集最近生成的气候态
+func (r *climatologyRepository) Find(datasetID, period string, baselineStart, baselineEnd int) (*models.Climatology, error) {
+	query := r.available().Where("climatologies.dataset_id = ?", datasetID)
+	if period != "" {
+		query = query.Where("climatologies.period = ?", period)
+	}
+	if baselineStart != 0 {
+		query = query.Where("climatologies.baseline_start = ?", baselineStart)
+	}
+	if baselineEnd != 0 {
+		query = query.Where("climatologies.baseline_end = ?", baselineEnd)
+	}
+
+	var climatology models.Climatology
+	if err := query.Order("climatologies.created_at DESC").Order("climatologies.id DESC").First(&climatology).Error; err != nil {
+		return nil, err
+	}
+	return &climatology, nil
+}
+
+// ListByDataset 源数据集的全部气候态
+func (r *climatologyRepository) ListByDataset(datasetID string) ([]*models.Climatology, error) {
+	var climatologies []*models.Climatology
+	err := r.available().Where("climatologies.dataset_id = ?", datasetID).
+		Order("climatologies.created_at DESC").Order("climatologies.id DESC").
+		Find(&climatologies).Error
+	return climatologies, err
+}
+
+// available 派生数据集未删除的气候态
+func (r *climatologyRepository) available() *gorm.DB {
+	return r.db.Model(&models.Climatology{}).Select("climatologies.*").
+		Joins("JOIN datasets ON datasets.id = climatologies.derived_dataset_id AND datasets.deleted_at IS NULL")
+}
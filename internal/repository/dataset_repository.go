@@ -3,6 +3,7 @@ package repository
 import (
 	_ "encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,8 +17,93 @@ type DatasetRepository interface {
 	GetByID(id string) (*models.Dataset, error)
 	List(page, size int, filters map[string]interface{}) ([]*models.Dataset, int64, error)
 	Update(dataset *models.Dataset) error
+	UpdateFields(id string, fields map[string]interface{}) error
 	Delete(id string) error
 	IncrementDownloadCount(id string) error
+	ListTypes() ([]string, error)
+	ListTags() ([]string, error)
+	EarliestUpdatedAt() (*time.Time, error)
+
+	// 回收站相关
+	GetDeletedByID(id string) (*models.Dataset, error)
+	ListDeleted(userID string, before *time.Time) ([]*models.Dataset, error)
+	Restore(id string) error
+	Purge(id string) error
+
+	// 变量统计
+	SaveVariableStats(datasetID string, stats []*models.VariableStats) error
+	ListVariableStats(datasetID string) ([]*models.VariableStats, error)
+	GetVariableStats(datasetID, variable string) (*models.VariableStats, error)
+}
+
+// Condition 数据集字段比较条件
+type Condition struct {
+	Column string      // 列名，仅允许conditionColumns中的列
+	Op     string      // 比较运算符: =, <>, <, <=, >, >=, IN, LIKE，或按字面匹配的 STARTS_WITH, ENDS_WITH, CONTAINS
+	Value  interface{} // 比较值
+}
+
+// conditionColumns 允许用于条件过滤的列
+var conditionColumns = map[string]bool{
+	"name":        true,
+	"type":        true,
+	"format":      true,
+	"region_name": true,
+	"source":      true,
+	"license":     true,
+	"publisher":   true,
+	"version":     true,
+	"doi":         true,
+	"created_by":  true,
+	"size":        true,
+	"start_time":  true,
+	"end_time":    true,
+	"created_at":  true,
+	"updated_at":  true,
+}
+
+// conditionOps 允许的比较运算符
+var conditionOps = map[string]bool{
+	"=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true, "IN": true, "LIKE": true,
+}
+
+// likeOps 按字面匹配的运算符及对应的LIKE模式，比较值中的通配符会被转义
+var likeOps = map[string]string{
+	"STARTS_WITH": "%s%%",
+	"ENDS_WITH":   "%%%s",
+	"CONTAINS":    "%%%s%%",
+}
+
+// clause 生成SQL条件语句及其参数
+func (c Condition) clause() (string, interface{}, error) {
+	if !conditionColumns[c.Column] {
+		return "", nil, fmt.Errorf("unsupported filter column: %s", c.Column)
+	}
+	op := strings.ToUpper(c.Op)
+	if pattern, ok := likeOps[op]; ok {
+		value, ok := c.Value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("filter operator %s requires a string", c.Op)
+		}
+		return c.Column + " LIKE ? ESCAPE '\\\\'", fmt.Sprintf(pattern, escapeLike(value)), nil
+	}
+	if !conditionOps[op] {
+		return "", nil, fmt.Errorf("unsupported filter operator: %s", c.Op)
+	}
+	return c.Column + " " + op + " ?", c.Value, nil
+}
+
+// boundExpr 提取region_bounds中第i个边界值的SQL表达式，非法JSON返回NULL
+func boundExpr(i int) string {
+	return fmt.Sprintf("IF(JSON_VALID(region_bounds), JSON_EXTRACT(region_bounds, '$[%d]'), NULL)", i)
+}
+
+// likeEscaper 转义LIKE模式中的通配符，配合 ESCAPE '\\' 使用
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike 转义字符串使其在LIKE中按字面匹配
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
 
 // datasetRepository 数据集仓库实现
@@ -70,15 +156,65 @@ func (r *datasetRepository) List(page, size int, filters map[string]interface{})
 		// 区域过滤
 		if region, ok := filters["region"]; ok && region != "" {
 			// 区域格式: "minLat,minLng,maxLat,maxLng"
-			parts := strings.Split(region.(string), ",")
-			if len(parts) == 4 {
+			var minLat, minLng, maxLat, maxLng float64
+			if n, _ := fmt.Sscanf(region.(string), "%f,%f,%f,%f", &minLat, &minLng, &maxLat, &maxLng); n == 4 {
 				// 区域搜索的逻辑: 两个区域有重叠
-				// 使用JSON函数提取边界值并比较
-				// 这里简化处理，实际应根据数据库和存储方式具体实现
-				query = query.Where("region_bounds LIKE ?", "%"+region.(string)+"%")
+				// 使用JSON函数提取边界值并比较，region_bounds格式: [minLat, minLng, maxLat, maxLng]
+				query = query.Where(
+					boundExpr(0)+" <= ? AND "+boundExpr(2)+" >= ? AND "+boundExpr(1)+" <= ? AND "+boundExpr(3)+" >= ?",
+					maxLat, minLat, maxLng, minLng)
+			}
+		}
+
+		// 几何相交过滤: WKT几何与数据集范围矩形相交(平面坐标，经度为x)
+		if wkt, ok := filters["intersects"].(string); ok && wkt != "" {
+			query = query.Where("ST_Intersects(ST_GeomFromText(?), ST_MakeEnvelope(POINT("+
+				boundExpr(1)+"+0, "+boundExpr(0)+"+0), POINT("+boundExpr(3)+"+0, "+boundExpr(2)+"+0)))", wkt)
+		}
+
+		// 多个数据类型过滤
+		if types, ok := filters["types"].([]string); ok && len(types) > 0 {
+			query = query.Where("type IN ?", types)
+		}
+
+		// ID过滤
+		if ids, ok := filters["ids"]; ok {
+			if idList, ok := ids.([]string); ok && len(idList) > 0 {
+				query = query.Where("id IN ?", idList)
+			}
+		}
+
+		// 字段条件过滤
+		if conditions, ok := filters["conditions"]; ok {
+			if conditionList, ok := conditions.([]Condition); ok {
+				for _, condition := range conditionList {
+					clause, value, err := condition.clause()
+					if err != nil {
+						return nil, 0, err
+					}
+					query = query.Where(clause, value)
+				}
 			}
 		}
 
+		// 标签过滤
+		if tag, ok := filters["tag"]; ok && tag != "" {
+			query = query.Where("CONCAT(',', REPLACE(tags, ' ', ''), ',') LIKE ? ESCAPE '\\\\'", fmt.Sprintf("%%,%s,%%", escapeLike(fmt.Sprint(tag))))
+		}
+
+		// 包含回收站中的数据集(用于OAI-PMH的删除记录)
+		if includeDeleted, ok := filters["includeDeleted"].(bool); ok && includeDeleted {
+			query = query.Unscoped()
+		}
+
+		// 更新时间过滤(用于增量收割)
+		if updatedFrom, ok := filters["updatedFrom"]; ok && updatedFrom != nil {
+			query = query.Where("updated_at >= ?", updatedFrom)
+		}
+		if updatedUntil, ok := filters["updatedUntil"]; ok && updatedUntil != nil {
+			query = query.Where("updated_at <= ?", updatedUntil)
+		}
+
 		// 关键词搜索
 		if keyword, ok := filters["keyword"]; ok && keyword != "" {
 			query = query.Where("name LIKE ? OR description LIKE ? OR tags LIKE ?", 
@@ -97,8 +233,12 @@ func (r *datasetRepository) List(page, size int, filters map[string]interface{})
 		query = query.Offset(offset).Limit(size)
 	}
 
-	// 排序(默认按创建时间倒序)
-	query = query.Order("created_at DESC")
+	// 排序(默认按创建时间倒序，增量收割按更新时间正序保证分页稳定)
+	if orderBy, ok := filters["orderBy"]; ok && orderBy == "updated" {
+		query = query.Order("updated_at ASC").Order("id ASC")
+	} else {
+		query = query.Order("created_at DESC")
+	}
 
 	// 执行查询
 	err := query.Find(&datasets).Error
@@ -114,9 +254,16 @@ func (r *datasetRepository) Update(dataset *models.Dataset) error {
 	return r.db.Save(dataset).Error
 }
 
-// Delete 删除数据集
+// UpdateFields 只更新指定的列，已删除的数据集不会被更新
+func (r *datasetRepository) UpdateFields(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Dataset{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// Delete 删除数据集(软删除，记录进入回收站)，同时更新修改时间以便增量收割到删除
 func (r *datasetRepository) Delete(id string) error {
-	return r.db.Where("id = ?", id).Delete(&models.Dataset{}).Error
+	now := time.Now()
+	return r.db.Model(&models.Dataset{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"deleted_at": now, "updated_at": now}).Error
 }
 
 // IncrementDownloadCount 增加下载计数
@@ -124,4 +271,124 @@ func (r *datasetRepository) IncrementDownloadCount(id string) error {
 	return r.db.Model(&models.Dataset{}).Where("id = ?", id).
 		UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).
 		UpdateColumn("updated_at", time.Now()).Error
-} 
\ No newline at end of file
+} 
+
+// ListTypes 获取所有数据类型
+func (r *datasetRepository) ListTypes() ([]string, error) {
+	var types []string
+	err := r.db.Model(&models.Dataset{}).Where("type <> ''").Distinct().Order("type").Pluck("type", &types).Error
+	return types, err
+}
+
+// ListTags 获取所有标签(去重)
+func (r *datasetRepository) ListTags() ([]string, error) {
+	var tagColumns []string
+	if err := r.db.Model(&models.Dataset{}).Where("tags <> ''").Distinct().Pluck("tags", &tagColumns).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var tags []string
+	for _, column := range tagColumns {
+		dataset := models.Dataset{Tags: column}
+		for _, tag := range dataset.TagList() {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// EarliestUpdatedAt 获取最早的更新时间(包含回收站中的数据集)
+func (r *datasetRepository) EarliestUpdatedAt() (*time.Time, error) {
+	var dataset models.Dataset
+	err := r.db.Unscoped().Select("updated_at").Order("updated_at ASC").First(&dataset).Error
+	if err != nil {
+		return nil, err
+	}
+	return dataset.UpdatedAt, nil
+}
+
+// GetDeletedByID 根据ID获取回收站中的数据集
+func (r *datasetRepository) GetDeletedByID(id string) (*models.Dataset, error) {
+	var dataset models.Dataset
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&dataset).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+// ListDeleted 获取回收站中的数据集，userID为空时不限用户，before非空时仅返回该时间之前删除的记录
+func (r *datasetRepository) ListDeleted(userID string, before *time.Time) ([]*models.Dataset, error) {
+	var datasets []*models.Dataset
+
+	query := r.db.Unscoped().Where("deleted_at IS NOT NULL")
+	if userID != "" {
+		query = query.Where("created_by = ?", userID)
+	}
+	if before != nil {
+		query = query.Where("deleted_at < ?", *before)
+	}
+
+	err := query.Order("deleted_at DESC").Find(&datasets).Error
+	if err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+// Restore 从回收站恢复数据集，同时更新修改时间
+func (r *datasetRepository) Restore(id string) error {
+	return r.db.Unscoped().Model(&models.Dataset{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now()}).Error
+}
+
+// Purge 彻底删除数据集记录及其统计信息和剖面
+func (r *datasetRepository) Purge(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dataset_id = ?", id).Delete(&models.VariableStats{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("dataset_id = ?", id).Delete(&models.Profile{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&models.Dataset{}).Error
+	})
+}
+
+// SaveVariableStats 保存数据集的变量统计，替换已有记录
+func (r *datasetRepository) SaveVariableStats(datasetID string, stats []*models.VariableStats) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dataset_id = ?", datasetID).Delete(&models.VariableStats{}).Error; err != nil {
+			return err
+		}
+		if len(stats) == 0 {
+			return nil
+		}
+		return tx.Create(&stats).Error
+	})
+}
+
+// ListVariableStats 获取数据集的全部变量统计
+func (r *datasetRepository) ListVariableStats(datasetID string) ([]*models.VariableStats, error) {
+	var stats []*models.VariableStats
+	err := r.db.Where("dataset_id = ?", datasetID).Order("variable ASC").Find(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetVariableStats 获取单个变量的统计
+func (r *datasetRepository) GetVariableStats(datasetID, variable string) (*models.VariableStats, error) {
+	var stats models.VariableStats
+	err := r.db.Where("dataset_id = ? AND variable = ?", datasetID, variable).First(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"gorm.io/gorm"
+)
+
+// NotificationRepository 通知仓库接口
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	GetByID(id string) (*models.Notification, error)
+	// ListByUser 用户的通知，按创建时间倒序，unreadOnly为true时只返回未读通知
+	ListByUser(userID string, unreadOnly bool, page, size int) ([]*models.Notification, int64, error)
+	CountUnread(userID string) (int64, error)
+	MarkRead(id string, readAt time.Time) error
+}
+
+// notificationRepository 通知仓库实现
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository 创建通知仓库
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// Create 创建通知
+func (r *notificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+// GetByID 根据ID获取通知
+func (r *notificationRepository) GetByID(id string) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.Where("id = ?", id).First(&notification).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// ListByUser 获取用户的通知列表
+func (r *notificationRepository) ListByUser(userID string, unreadOnly bool, page, size int) ([]*models.Notification, int64, error) {
+	var notifications []*models.Notification
+	var total int64
+
+	query := r.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("`read` = ?", false)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * size
+	err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(size).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// CountUnread 用户的未读通知数
+func (r *notificationRepository) CountUnread(userID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND `read` = ?", userID, false).Count(&count).Error
+	return count, err
+}
+
+// MarkRead 标记通知为已读
+func (r *notificationRepository) MarkRead(id string, readAt time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"read": true, "read_at": readAt}).Error
+}
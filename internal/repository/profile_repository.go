@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"github.com/sinker/ssop/internal/models"
+	"gorm.io/gorm"
+)
+
+// profileBatchSize 批量写入剖面的每批条数
+const profileBatchSize = 200
+
+// ProfileRepository 剖面仓库接口
+type ProfileRepository interface {
+	// ReplaceForDataset 替换数据集的全部剖面
+	ReplaceForDataset(datasetID string, profiles []*models.Profile) error
+	// Search 按条件查找剖面，只包含未删除数据集中的剖面
+	Search(page, size int, filters map[string]interface{}) ([]*models.Profile, int64, error)
+}
+
+// profileRepository 剖面仓库实现
+type profileRepository struct {
+	db *gorm.DB
+}
+
+// NewProfileRepository 创建剖面仓库
+func NewProfileRepository(db *gorm.DB) ProfileRepository {
+	return &profileRepository{db: db}
+}
+
+// ReplaceForDataset 替换数据集的全部剖面
+func (r *profileRepository) ReplaceForDataset(datasetID string, profiles []*models.Profile) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("dataset_id = ?", datasetID).Delete(&models.Profile{}).Error; err != nil {
+			return err
+		}
+		if len(profiles) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&profiles, profileBatchSize).Error
+	})
+}
+
+// Search 按条件查找剖面，支持的过滤条件:
+// bounds([4]float64: minLat, minLng, maxLat, maxLng)、start/end(time.Time)、platform、cycle(int)、datasetId
+func (r *profileRepository) Search(page, size int, filters map[string]interface{}) ([]*models.Profile, int64, error) {
+	var profiles []*models.Profile
+	var total int64
+
+	query := r.db.Model(&models.Profile{}).
+		Joins("JOIN datasets ON datasets.id = profiles.dataset_id AND datasets.deleted_at IS NULL")
+
+	if bounds, ok := filters["bounds"].([4]float64); ok {
+		query = query.Where("profiles.latitude BETWEEN ? AND ? AND profiles.longitude BETWEEN ? AND ?",
+			bounds[0], bounds[2], bounds[1], bounds[3])
+	}
+	if start, ok := filters["start"]; ok {
+		query = query.Where("profiles.time >= ?", start)
+	}
+	if end, ok := filters["end"]; ok {
+		query = query.Where("profiles.time <= ?", end)
+	}
+	if platform, ok := filters["platform"]; ok && platform != "" {
+		query = query.Where("profiles.platform = ?", platform)
+	}
+	if cycle, ok := filters["cycle"]; ok {
+		query = query.Where("profiles.cycle = ?", cycle)
+	}
+	if datasetID, ok := filters["datasetId"]; ok && datasetID != "" {
+		query = query.Where("profiles.dataset_id = ?", datasetID)
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	if page > 0 && size > 0 {
+		query = query.Offset((page - 1) * size).Limit(size)
+	}
+
+	err := query.Select("profiles.*").
+		Order("profiles.time ASC").Order("profiles.platform ASC").Order("profiles.cycle ASC").
+		Find(&profiles).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return profiles, total, nil
+}
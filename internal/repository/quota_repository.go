@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"github.com/sinker/ssop/internal/models"
+	"gorm.io/gorm"
+)
+
+// StorageUsage 存储用量统计
+type StorageUsage struct {
+	DatasetCount int64 `json:"datasetCount"` // 数据集数量(不含回收站)
+	DatasetBytes int64 `json:"datasetBytes"` // 数据集文件大小(不含回收站)
+	ResultBytes  int64 `json:"resultBytes"`  // 分析结果文件大小(不含回收站)
+	TrashBytes   int64 `json:"trashBytes"`   // 回收站中文件大小
+}
+
+// TotalBytes 计入配额的总字节数，回收站中的文件在清理前仍占用存储
+func (u StorageUsage) TotalBytes() int64 {
+	return u.DatasetBytes + u.ResultBytes + u.TrashBytes
+}
+
+// QuotaRepository 存储配额仓库接口
+type QuotaRepository interface {
+	Get(scope, subject string) (*models.StorageQuota, error)
+	List(scope string) ([]*models.StorageQuota, error)
+	Save(quota *models.StorageQuota) error
+	Delete(scope, subject string) error
+
+	// 用量统计
+	UserUsage(userID string) (*StorageUsage, error)
+	OrganizationUsage(organization string) (*StorageUsage, error)
+}
+
+// quotaRepository 存储配额仓库实现
+type quotaRepository struct {
+	db *gorm.DB
+}
+
+// NewQuotaRepository 创建存储配额仓库
+func NewQuotaRepository(db *gorm.DB) QuotaRepository {
+	return &quotaRepository{db: db}
+}
+
+// Get 获取配额
+func (r *quotaRepository) Get(scope, subject string) (*models.StorageQuota, error) {
+	var quota models.StorageQuota
+	err := r.db.Where("scope = ? AND subject = ?", scope, subject).First(&quota).Error
+	if err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// List 获取配额列表，scope为空时返回全部
+func (r *quotaRepository) List(scope string) ([]*models.StorageQuota, error) {
+	var quotas []*models.StorageQuota
+
+	query := r.db.Model(&models.StorageQuota{})
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	err := query.Order("scope ASC, subject ASC").Find(&quotas).Error
+	if err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// Save 保存配额
+func (r *quotaRepository) Save(quota *models.StorageQuota) error {
+	return r.db.Save(quota).Error
+}
+
+// Delete 删除配额，恢复为默认值
+func (r *quotaRepository) Delete(scope, subject string) error {
+	return r.db.Where("scope = ? AND subject = ?", scope, subject).Delete(&models.StorageQuota{}).Error
+}
+
+// UserUsage 统计用户的存储用量
+func (r *quotaRepository) UserUsage(userID string) (*StorageUsage, error) {
+	return r.usage("= ?", userID)
+}
+
+// OrganizationUsage 统计组织内所有用户的存储用量
+func (r *quotaRepository) OrganizationUsage(organization string) (*StorageUsage, error) {
+	return r.usage("IN (SELECT id FROM users WHERE organization = ?)", organization)
+}
+
+// usage 按创建者条件统计用量，owner为作用于created_by列的条件
+func (r *quotaRepository) usage(owner string, arg interface{}) (*StorageUsage, error) {
+	var usage StorageUsage
+
+	var datasets struct {
+		Count int64
+		Bytes int64
+		Trash int64
+	}
+	err := r.db.Unscoped().Model(&models.Dataset{}).
+		Select("COUNT(CASE WHEN deleted_at IS NULL THEN 1 END) AS count, "+
+			"COALESCE(SUM(CASE WHEN deleted_at IS NULL THEN size ELSE 0 END), 0) AS bytes, "+
+			"COALESCE(SUM(CASE WHEN deleted_at IS NOT NULL THEN size ELSE 0 END), 0) AS trash").
+		Where("created_by "+owner, arg).
+		Scan(&datasets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var results struct {
+		Bytes int64
+		Trash int64
+	}
+	err = r.db.Unscoped().Model(&models.AnalysisResult{}).
+		Joins("JOIN analysis_tasks ON analysis_tasks.id = analysis_results.task_id").
+		Select("COALESCE(SUM(CASE WHEN analysis_results.deleted_at IS NULL THEN analysis_results.size ELSE 0 END), 0) AS bytes, "+
+			"COALESCE(SUM(CASE WHEN analysis_results.deleted_at IS NOT NULL THEN analysis_results.size ELSE 0 END), 0) AS trash").
+		Where("analysis_tasks.created_by "+owner, arg).
+		Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	usage.DatasetCount = datasets.Count
+	usage.DatasetBytes = datasets.Bytes
+	usage.ResultBytes = results.Bytes
+	usage.TrashBytes = datasets.Trash + results.Trash
+	return &usage, nil
+}
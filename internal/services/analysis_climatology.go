@@ -0,0 +1,707 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// 气候态的时间分段
+const (
+	ClimatologyMonthly = "monthly" // 逐月，12段
+	ClimatologyDaily   = "daily"   // 逐日，365段(闰年2月29日并入2月28日)
+)
+
+// maxClimatologyValues 单个变量气候态的最大元素数(分段数×每个时次的元素数)
+const maxClimatologyValues = 20000000
+
+// maxClimatologyWindow 逐日气候态滑动平均窗口的最大天数
+const maxClimatologyWindow = 91
+
+// climatologySlots 各时间分段的段数和分段维度名
+var climatologySlots = map[string]struct {
+	count int
+	dim   string
+}{
+	ClimatologyMonthly: {12, "month"},
+	ClimatologyDaily:   {365, "dayofyear"},
+}
+
+// climatologySlot 时间所在的分段下标(从0开始)
+func climatologySlot(t time.Time, period string) int {
+	if period == ClimatologyMonthly {
+		return int(t.Month()) - 1
+	}
+	day := t.YearDay() - 1
+	if isLeap(t.Year()) && day >= 59 {
+		day-- // 2月29日并入2月28日，其后各日与平年对齐
+	}
+	return day
+}
+
+// isLeap 是否为闰年
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// parseBaseline 读取基准期参数 "1991-2020"，参数不存在时ok为false
+func parseBaseline(params map[string]interface{}) (start, end int, ok bool, err error) {
+	s := paramString(params, "baseline")
+	if s == "" {
+		return 0, 0, false, nil
+	}
+	parts := strings.Split(s, "-")
+	if len(parts) == 2 {
+		start, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err == nil {
+			end, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		}
+		if err == nil && start > 0 && start <= end {
+			return start, end, true, nil
+		}
+	}
+	return 0, 0, false, fmt.Errorf("%w: invalid baseline %q, expected YYYY-YYYY", ErrInvalidAnalysisParams, s)
+}
+
+// parseClimatologyPeriod 读取参数period，未指定时为def
+func parseClimatologyPeriod(params map[string]interface{}, def string) (string, error) {
+	period := paramString(params, "period")
+	if period == "" {
+		return def, nil
+	}
+	if _, ok := climatologySlots[period]; !ok {
+		return "", fmt.Errorf("%w: period must be monthly or daily", ErrInvalidAnalysisParams)
+	}
+	return period, nil
+}
+
+// climatologyVariables 计算气候态的变量: 参数variables中的混合层指标(如mldTemperature)按剖面计算，
+// 其余变量同analysisVariables；结果按变量名排序
+func (s *analysisService) climatologyVariables(src *analysisSource, params map[string]interface{}) ([]*dataio.Variable, error) {
+	var names, others []string
+	for _, name := range strings.Split(paramString(params, "variables"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	var vars []*dataio.Variable
+	var metrics map[string]*dataio.Variable
+	for _, name := range names {
+		if !isMixedLayerMetric(name) {
+			others = append(others, name)
+			continue
+		}
+		if metrics == nil {
+			criteria, err := parseMixedLayerCriteria(params)
+			if err != nil {
+				return nil, err
+			}
+			if metrics, _, _, err = s.mixedLayerVariables(src, criteria); err != nil {
+				return nil, err
+			}
+		}
+		v, ok := metrics[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s requires salinity", ErrInvalidAnalysisParams, name)
+		}
+		vars = append(vars, v)
+	}
+
+	if len(others) > 0 || len(names) == 0 {
+		p := map[string]interface{}{"variables": strings.Join(others, ",")}
+		variables, _, err := s.analysisVariables(src, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variables {
+			vars = append(vars, v)
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars, nil
+}
+
+// isMixedLayerMetric 是否为混合层分析的指标名
+func isMixedLayerMetric(name string) bool {
+	for _, m := range mixedLayerMetrics {
+		if m.key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// climatologyField 单个变量的气候态: 时间维替换为分段维，其余维度不变
+type climatologyField struct {
+	variable *dataio.Variable
+	dims     []string
+	shape    []int
+	mean     []float64
+	samples  []int // 各分段参与平均的时次数
+}
+
+// computeClimatology 逐时次读取基准期内的数据，按分段累加求平均；window大于1时对逐日气候态做循环滑动平均
+func computeClimatology(src *dataio.Source, v *dataio.Variable, period string, startYear, endYear, window int) (*climatologyField, error) {
+	tdim := src.AxisDim(v, dataio.AxisTime)
+	if tdim < 0 {
+		return nil, fmt.Errorf("%w: variable %s has no time dimension", ErrInvalidAnalysisParams, v.Name)
+	}
+	times, err := dataio.Times(src.Coordinate(v, dataio.AxisTime))
+	if err != nil {
+		return nil, err
+	}
+	slots := climatologySlots[period]
+
+	outer, inner := 1, 1
+	for d := 0; d < tdim; d++ {
+		outer *= v.Shape[d]
+	}
+	for d := tdim + 1; d < len(v.Shape); d++ {
+		inner *= v.Shape[d]
+	}
+	if slots.count*outer*inner > maxClimatologyValues {
+		return nil, fmt.Errorf("climatology of %s too large: %d values (max %d)", v.Name, slots.count*outer*inner, maxClimatologyValues)
+	}
+
+	field := &climatologyField{
+		variable: v,
+		dims:     append([]string(nil), v.Dims...),
+		shape:    append([]int(nil), v.Shape...),
+		samples:  make([]int, slots.count),
+	}
+	field.dims[tdim], field.shape[tdim] = slots.dim, slots.count
+	n := slots.count * outer * inner
+	sum := make([]float64, n)
+	count := make([]int32, n)
+
+	start, cnt := dataio.FullSlice(v)
+	cnt[tdim] = 1
+	for ti, t := range times {
+		if t.IsZero() || t.Year() < startYear || t.Year() > endYear {
+			continue
+		}
+		start[tdim] = ti
+		values, err := v.Read(start, cnt)
+		if err != nil {
+			return nil, err
+		}
+		slot := climatologySlot(t, period)
+		field.samples[slot]++
+		for o := 0; o < outer; o++ {
+			for k := 0; k < inner; k++ {
+				x := values[o*inner+k]
+				if math.IsNaN(x) {
+					continue
+				}
+				i := (o*slots.count+slot)*inner + k
+				sum[i] += x
+				count[i]++
+			}
+		}
+	}
+
+	field.mean = make([]float64, n)
+	for i := range sum {
+		field.mean[i] = math.NaN()
+		if count[i] > 0 {
+			field.mean[i] = sum[i] / float64(count[i])
+		}
+	}
+	if window > 1 {
+		field.mean = smoothSlots(field.mean, outer, slots.count, inner, window)
+	}
+	return field, nil
+}
+
+// smoothSlots 沿分段维做循环滑动平均(首尾相接)，窗口内缺测的分段不参与平均
+func smoothSlots(values []float64, outer, slots, inner, window int) []float64 {
+	out := make([]float64, len(values))
+	half := window / 2
+	for o := 0; o < outer; o++ {
+		for k := 0; k < inner; k++ {
+			for s := 0; s < slots; s++ {
+				sum, n := 0.0, 0
+				for d := -half; d <= half; d++ {
+					x := values[(o*slots+(s+d+slots)%slots)*inner+k]
+					if !math.IsNaN(x) {
+						sum += x
+						n++
+					}
+				}
+				out[(o*slots+s)*inner+k] = math.NaN()
+				if n > 0 {
+					out[(o*slots+s)*inner+k] = sum / float64(n)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// climatologySource 由各变量的气候态构建数据源: 包含分段坐标、不随时间变化的坐标变量和气候态变量
+func climatologySource(src *dataio.Source, fields []*climatologyField, period string, attrs dataio.Attributes) *dataio.Source {
+	slots := climatologySlots[period]
+	index := make([]float64, slots.count)
+	for i := range index {
+		index[i] = float64(i + 1)
+	}
+	slotName := "month of year"
+	if period == ClimatologyDaily {
+		slotName = "day of year (365-day calendar)"
+	}
+	vars := []*dataio.Variable{
+		dataio.NewVariable(slots.dim, []string{slots.dim}, []int{slots.count}, "double",
+			dataio.Attributes{"long_name": slotName, "units": "1"}, dataio.MemoryArray(index, []int{slots.count})),
+	}
+	dims := []dataio.Dimension{{Name: slots.dim, Len: slots.count}}
+
+	addDim := func(name string, n int) {
+		for _, d := range dims {
+			if d.Name == name {
+				return
+			}
+		}
+		dims = append(dims, dataio.Dimension{Name: name, Len: n})
+	}
+	hasVar := func(name string) bool {
+		for _, v := range vars {
+			if v.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, f := range fields {
+		for i, d := range f.dims {
+			addDim(d, f.shape[i])
+		}
+		for _, axis := range []string{dataio.AxisLat, dataio.AxisLon, dataio.AxisZ} {
+			c := src.Coordinate(f.variable, axis)
+			if c == nil || hasVar(c.Name) || !subsetOf(c.Dims, f.dims) {
+				continue
+			}
+			vars = append(vars, c)
+		}
+	}
+	for _, f := range fields {
+		v := f.variable
+		a := dataio.Attributes{
+			"units":        v.Units(),
+			"long_name":    "Climatological mean of " + variableLabel(v),
+			"cell_methods": "time: mean over years",
+		}
+		if std := v.Attrs.String("standard_name"); std != "" {
+			a["standard_name"] = std
+		}
+		if c := v.Attrs.String("coordinates"); c != "" {
+			a["coordinates"] = c
+		}
+		vars = append(vars, dataio.NewVariable(v.Name, f.dims, f.shape, "double", a, dataio.MemoryArray(f.mean, f.shape)))
+	}
+	return dataio.NewSource("netCDF", dims, vars, attrs, nil)
+}
+
+// variableLabel 变量描述，没有long_name和standard_name时使用变量名
+func variableLabel(v *dataio.Variable) string {
+	if d := v.Description(); d != "" {
+		return d
+	}
+	return v.Name
+}
+
+// subsetOf 维度列表a是否为b的子集
+func subsetOf(a, b []string) bool {
+	for _, x := range a {
+		if !containsString(b, x) {
+			return false
+		}
+	}
+	return true
+}
+
+// executeClimatology 计算数据集在基准期内的逐月或逐日气候态，保存为派生数据集并登记，供距平计算使用
+func (s *analysisService) executeClimatology(task *models.AnalysisTask, params map[string]interface{}) (map[string]interface{}, error) {
+	period, err := parseClimatologyPeriod(params, ClimatologyMonthly)
+	if err != nil {
+		return nil, err
+	}
+	startYear, endYear, hasBaseline, err := parseBaseline(params)
+	if err != nil {
+		return nil, err
+	}
+	window := 1
+	if w, ok, err := paramFloat(params, "window"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		window = int(w)
+		if float64(window) != w || window < 1 || window%2 == 0 || window > maxClimatologyWindow {
+			return nil, fmt.Errorf("%w: window must be an odd number of days between 1 and %d", ErrInvalidAnalysisParams, maxClimatologyWindow)
+		}
+	}
+	if period == ClimatologyMonthly {
+		window = 1
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	vars, err := s.climatologyVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定基准期时使用数据覆盖的全部年份
+	if !hasBaseline {
+		for _, v := range vars {
+			times, err := dataio.Times(src.Coordinate(v, dataio.AxisTime))
+			if err != nil || src.AxisDim(v, dataio.AxisTime) < 0 {
+				continue
+			}
+			for _, t := range times {
+				if t.IsZero() {
+					continue
+				}
+				if startYear == 0 || t.Year() < startYear {
+					startYear = t.Year()
+				}
+				if t.Year() > endYear {
+					endYear = t.Year()
+				}
+			}
+		}
+		if startYear == 0 {
+			return nil, fmt.Errorf("%w: dataset has no valid time", ErrInvalidAnalysisParams)
+		}
+	}
+
+	// 已有相同条件的气候态时直接复用
+	source := src.dataset
+	baseline := fmt.Sprintf("%d-%d", startYear, endYear)
+	var names []string
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	if existing := s.existingClimatology(source.ID, period, startYear, endYear, window, names); existing != nil {
+		logger.Info("Climatology reused", "datasetId", source.ID, "climatologyId", existing.ID, "period", period, "baseline", baseline)
+		return map[string]interface{}{
+			"climatologyId":    existing.ID,
+			"datasetId":        source.ID,
+			"derivedDatasetId": existing.DerivedDatasetID,
+			"period":           period,
+			"baseline":         baseline,
+			"window":           window,
+			"variables":        existing.VariableNames(),
+			"reused":           true,
+		}, nil
+	}
+
+	var fields []*climatologyField
+	samples := map[string][]int{}
+	for _, v := range vars {
+		field, err := computeClimatology(src.Source, v, period, startYear, endYear, window)
+		if err != nil {
+			return nil, err
+		}
+		total := 0
+		for _, n := range field.samples {
+			total += n
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("%w: no data of %s in baseline %d-%d", ErrInvalidAnalysisParams, v.Name, startYear, endYear)
+		}
+		fields = append(fields, field)
+		samples[v.Name] = field.samples
+	}
+
+	// 写出派生数据集
+	derived := &models.Dataset{
+		ID:                 utils.GenerateID("ds"),
+		Name:               truncateRunes(fmt.Sprintf("%s %s climatology %s", source.Name, period, baseline), 100),
+		Description:        fmt.Sprintf("The %s climatology of dataset %s (%s) over %s", period, source.Name, source.ID, baseline),
+		Type:               "climatology",
+		Format:             "netCDF",
+		RegionName:         source.RegionName,
+		RegionBounds:       source.RegionBounds,
+		SpatialResolution:  source.SpatialResolution,
+		TemporalResolution: period + " climatology",
+		Source:             source.Source,
+		Methodology:        truncateRunes(fmt.Sprintf("Mean over %s per %s, derived from dataset %s", baseline, climatologySlots[period].dim, source.ID), 255),
+		License:            source.License,
+		Publisher:          source.Publisher,
+		Tags:               "climatology",
+		CreatedBy:          task.CreatedBy,
+	}
+	if window > 1 {
+		derived.Methodology = truncateRunes(derived.Methodology+fmt.Sprintf(", smoothed with a %d-day running mean", window), 255)
+	}
+	from := time.Date(startYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(endYear, 12, 31, 23, 59, 59, 0, time.UTC)
+	derived.StartTime, derived.EndTime = &from, &to
+
+	attrs := dataio.Attributes{
+		"title":                derived.Name,
+		"source_dataset":       source.ID,
+		"climatology_period":   period,
+		"climatology_baseline": baseline,
+		"climatology_window":   []float64{float64(window)},
+		"history":              time.Now().UTC().Format(time.RFC3339) + " climatology computed from dataset " + source.ID,
+		"Conventions":          "CF-1.8",
+	}
+	dir := filepath.Join(s.datasetDir, derived.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dataset directory: %w", err)
+	}
+	derived.FilePath = filepath.Join(dir, "climatology.nc")
+	if err := dataio.WriteNetCDF(climatologySource(src.Source, fields, period, attrs), derived.FilePath, nil); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to write climatology: %w", err)
+	}
+	if info, err := os.Stat(derived.FilePath); err == nil {
+		derived.Size = info.Size()
+	}
+	if err := s.quota.CheckUpload(task.CreatedBy, derived.Size); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	derived.IngestStatus = models.IngestPending
+	if err := s.datasetRepo.Create(derived); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to save climatology dataset: %w", err)
+	}
+
+	record := &models.Climatology{
+		DatasetID:        source.ID,
+		Period:           period,
+		BaselineStart:    startYear,
+		BaselineEnd:      endYear,
+		Window:           window,
+		Variables:        mustJSON(names),
+		DerivedDatasetID: derived.ID,
+		TaskID:           task.ID,
+		CreatedBy:        task.CreatedBy,
+	}
+	if err := s.climatologyRepo.Create(record); err != nil {
+		// 未登记的派生数据集不保留
+		if perr := s.datasetRepo.Purge(derived.ID); perr != nil {
+			logger.Error("Failed to purge climatology dataset", "error", perr, "datasetId", derived.ID)
+		}
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to save climatology record: %w", err)
+	}
+	s.ingest.Enqueue(derived.ID)
+	logger.Info("Climatology computed", "datasetId", source.ID, "derivedDatasetId", derived.ID, "period", period, "baseline", baseline)
+
+	return map[string]interface{}{
+		"climatologyId":    record.ID,
+		"datasetId":        source.ID,
+		"derivedDatasetId": derived.ID,
+		"period":           period,
+		"baseline":         baseline,
+		"window":           window,
+		"variables":        names,
+		"samples":          samples,
+	}, nil
+}
+
+// existingClimatology 查找可复用的气候态: 时间分段、基准期和滑动平均窗口相同，且包含全部所请求的变量
+func (s *analysisService) existingClimatology(datasetID, period string, startYear, endYear, window int, names []string) *models.Climatology {
+	climatologies, err := s.climatologyRepo.ListByDataset(datasetID)
+	if err != nil {
+		logger.Warn("Failed to list climatologies", "error", err, "datasetId", datasetID)
+		return nil
+	}
+	for _, c := range climatologies {
+		if c.Period == period && c.BaselineStart == startYear && c.BaselineEnd == endYear && c.Window == window && subsetOf(names, c.VariableNames()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// truncateRunes 截断字符串到最多n个字符
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// ListClimatologies 获取源数据集已生成的气候态
+func (s *analysisService) ListClimatologies(datasetID string) ([]*models.Climatology, error) {
+	return s.climatologyRepo.ListByDataset(datasetID)
+}
+
+// applyAnomaly 参数anomaly为true时将各变量替换为相对气候态的距平，返回所用气候态的说明(未请求距平时为nil)；
+// 参数climatologyId指定气候态，否则取源数据集最近生成的、符合参数period和baseline的气候态
+func (s *analysisService) applyAnomaly(src *analysisSource, variables map[string]*dataio.Variable, params map[string]interface{}) (map[string]interface{}, error) {
+	if paramString(params, "anomaly") != "true" {
+		return nil, nil
+	}
+
+	var record *models.Climatology
+	var err error
+	if id, ok, perr := paramFloat(params, "climatologyId"); perr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, perr)
+	} else if ok {
+		record, err = s.climatologyRepo.GetByID(uint(id))
+		if err == nil && record.DatasetID != src.dataset.ID {
+			return nil, fmt.Errorf("%w: climatology %d does not belong to dataset %s", ErrInvalidAnalysisParams, record.ID, src.dataset.ID)
+		}
+	} else {
+		period, perr := parseClimatologyPeriod(params, "")
+		if perr != nil {
+			return nil, perr
+		}
+		startYear, endYear, _, perr := parseBaseline(params)
+		if perr != nil {
+			return nil, perr
+		}
+		record, err = s.climatologyRepo.Find(src.dataset.ID, period, startYear, endYear)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: no matching climatology for dataset %s, create a climatology task first", ErrInvalidAnalysisParams, src.dataset.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	derived, err := s.datasetRepo.GetByID(record.DerivedDatasetID)
+	if err != nil {
+		return nil, fmt.Errorf("climatology dataset not found: %w", err)
+	}
+	clim, err := dataio.Open(derived.FilePath, derived.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open climatology: %w", err)
+	}
+	src.related = append(src.related, clim)
+
+	resolver := s.standardNames.Resolver()
+	for key, v := range variables {
+		cv := clim.Var(v.Name)
+		if cv == nil {
+			return nil, fmt.Errorf("%w: climatology %d does not contain variable %s", ErrInvalidAnalysisParams, record.ID, v.Name)
+		}
+		if cu, vu := cv.Units(), v.Units(); cu != "" && vu != "" && cu != vu {
+			if cv, err = resolver.Convert(cv, vu); err != nil {
+				return nil, fmt.Errorf("convert climatology of %s: %w", v.Name, err)
+			}
+		}
+		av, err := anomalyVariable(src.Source, v, cv, record.Period)
+		if err != nil {
+			return nil, err
+		}
+		variables[key] = av
+	}
+
+	return map[string]interface{}{
+		"climatologyId":    record.ID,
+		"derivedDatasetId": record.DerivedDatasetID,
+		"period":           record.Period,
+		"baseline":         fmt.Sprintf("%d-%d", record.BaselineStart, record.BaselineEnd),
+	}, nil
+}
+
+// withAnomaly 结果为距平时注明所用的气候态
+func withAnomaly(result map[string]interface{}, climatology map[string]interface{}) {
+	if climatology != nil {
+		result["anomaly"] = true
+		result["climatology"] = climatology
+	}
+}
+
+// anomalyVariable 变量相对气候态的距平: 气候态变量的维度须与变量相同，只是时间维替换为分段维；
+// 读取时按各时次所在的分段减去对应的气候态
+func anomalyVariable(src *dataio.Source, v, clim *dataio.Variable, period string) (*dataio.Variable, error) {
+	tdim := src.AxisDim(v, dataio.AxisTime)
+	if tdim < 0 {
+		return nil, fmt.Errorf("%w: variable %s has no time dimension", ErrInvalidAnalysisParams, v.Name)
+	}
+	slots := climatologySlots[period]
+	match := len(clim.Dims) == len(v.Dims)
+	for d := range v.Dims {
+		if !match {
+			break
+		}
+		if d == tdim {
+			match = clim.Shape[d] == slots.count
+		} else {
+			match = clim.Dims[d] == v.Dims[d] && clim.Shape[d] == v.Shape[d]
+		}
+	}
+	if !match {
+		return nil, fmt.Errorf("%w: climatology grid of %s does not match the dataset", ErrInvalidAnalysisParams, v.Name)
+	}
+	times, err := dataio.Times(src.Coordinate(v, dataio.AxisTime))
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(start, count []int) ([]float64, error) {
+		values, err := v.Read(start, count)
+		if err != nil {
+			return nil, err
+		}
+		outer, inner := 1, 1
+		for d := 0; d < tdim; d++ {
+			outer *= count[d]
+		}
+		for d := tdim + 1; d < len(count); d++ {
+			inner *= count[d]
+		}
+		nt := count[tdim]
+		cs, cc := append([]int(nil), start...), append([]int(nil), count...)
+		cc[tdim] = 1
+		blocks := map[int][]float64{}
+		for it := 0; it < nt; it++ {
+			t := times[start[tdim]+it]
+			var block []float64
+			if !t.IsZero() {
+				slot := climatologySlot(t, period)
+				if block = blocks[slot]; block == nil {
+					cs[tdim] = slot
+					if block, err = clim.Read(cs, cc); err != nil {
+						return nil, err
+					}
+					blocks[slot] = block
+				}
+			}
+			for o := 0; o < outer; o++ {
+				for k := 0; k < inner; k++ {
+					i := (o*nt+it)*inner + k
+					if block == nil {
+						values[i] = math.NaN()
+						continue
+					}
+					values[i] -= block[o*inner+k]
+				}
+			}
+		}
+		return values, nil
+	}
+
+	attrs := dataio.Attributes{}
+	for k, x := range v.Attrs {
+		attrs[k] = x
+	}
+	attrs["long_name"] = variableLabel(v) + " anomaly"
+	delete(attrs, "standard_name")
+	return dataio.NewVariable(v.Name, v.Dims, v.Shape, "double", attrs, read), nil
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sinker/ssop/pkg/argo"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/resample"
+	"github.com/sinker/ssop/pkg/stats"
+)
+
+// maxComparisonValues 读取观测数据的最大元素数
+const maxComparisonValues = 20000000
+
+// maxComparisonObservations 参与配对的最大观测数
+const maxComparisonObservations = 2000000
+
+// maxComparisonRows 结果中配对表的最大行数，超出时等间隔抽稀
+const maxComparisonRows = 100000
+
+// comparisonOptions 模式与观测对比的参数
+type comparisonOptions struct {
+	Method      string  `json:"method"`      // 配对方法: nearest或interpolated
+	MaxTimeDiff float64 `json:"maxTimeDiff"` // 最近时次配对允许的最大时间差(小时)
+	MinDepth    float64 `json:"minDepth"`
+	MaxDepth    float64 `json:"maxDepth"` // 为0时不限制
+}
+
+// parseComparisonOptions 读取参数method(默认interpolated)、maxTimeDiff(小时，默认为模式的典型时间步长)、minDepth和maxDepth
+func parseComparisonOptions(params map[string]interface{}) (comparisonOptions, error) {
+	opts := comparisonOptions{Method: "interpolated"}
+	if method := paramString(params, "method"); method != "" {
+		if method != "nearest" && method != "interpolated" {
+			return opts, fmt.Errorf("%w: method must be nearest or interpolated", ErrInvalidAnalysisParams)
+		}
+		opts.Method = method
+	}
+	var err error
+	if opts.MaxTimeDiff, _, err = paramFloat(params, "maxTimeDiff"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.MinDepth, _, err = paramFloat(params, "minDepth"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.MaxDepth, _, err = paramFloat(params, "maxDepth"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.MaxTimeDiff < 0 || opts.MinDepth < 0 || opts.MaxDepth < 0 || (opts.MaxDepth > 0 && opts.MaxDepth <= opts.MinDepth) {
+		return opts, fmt.Errorf("%w: maxTimeDiff must not be negative and the depth range must be valid", ErrInvalidAnalysisParams)
+	}
+	return opts, nil
+}
+
+// comparisonVariable 对比的变量: 参数key(modelVariable或observationVariable)指定的变量，
+// 未指定时按参数standardName(默认sea_water_temperature)查找；to不为空时换算到该单位
+func (s *analysisService) comparisonVariable(src *analysisSource, params map[string]interface{}, key, to string) (*dataio.Variable, error) {
+	resolver := s.standardNames.Resolver()
+	name := paramString(params, key)
+	if name == "" {
+		standardName := paramString(params, "standardName")
+		if standardName == "" {
+			standardName = "sea_water_temperature"
+		}
+		v, err := resolver.Resolve(src.Source, standardName, to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: dataset %s has no %s variable, specify %s", ErrInvalidAnalysisParams, src.dataset.ID, standardName, key)
+		}
+		return v, nil
+	}
+	v := src.Var(name)
+	if v == nil || v.IsText() {
+		return nil, fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+	}
+	if to == "" {
+		return v, nil
+	}
+	converted, err := resolver.Convert(v, to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	return converted, nil
+}
+
+// matchup 一个观测与模式的配对
+type matchup struct {
+	Time        time.Time
+	Lat, Lng    float64
+	Depth       float64
+	Observation float64
+	Model       float64
+}
+
+// timeWeight 观测在模式第index个时次上的权重
+type timeWeight struct {
+	obs    int
+	weight float64
+}
+
+// modelTimeWeights 各观测在模式时次上的权重: nearest取时间差不超过maxDiff的最近时次，
+// interpolated在相邻两个时次之间线性插值；模式没有时间维时所有观测使用唯一的时次
+func modelTimeWeights(times []time.Time, obsTimes []time.Time, method string, maxDiff time.Duration) map[int][]timeWeight {
+	out := map[int][]timeWeight{}
+	if times == nil {
+		for i := range obsTimes {
+			out[0] = append(out[0], timeWeight{i, 1})
+		}
+		return out
+	}
+	for i, t := range obsTimes {
+		if t.IsZero() || len(times) == 0 {
+			continue
+		}
+		k := sort.Search(len(times), func(j int) bool { return times[j].After(t) }) - 1 // 不晚于t的最后一个时次
+		if method == "nearest" {
+			best := -1
+			var diff time.Duration
+			for _, j := range []int{k, k + 1} {
+				if j < 0 || j >= len(times) {
+					continue
+				}
+				d := t.Sub(times[j])
+				if d < 0 {
+					d = -d
+				}
+				if best < 0 || d < diff {
+					best, diff = j, d
+				}
+			}
+			if best >= 0 && diff <= maxDiff {
+				out[best] = append(out[best], timeWeight{i, 1})
+			}
+			continue
+		}
+		switch {
+		case k < 0:
+		case times[k].Equal(t):
+			out[k] = append(out[k], timeWeight{i, 1})
+		case k+1 < len(times):
+			w := float64(t.Sub(times[k])) / float64(times[k+1].Sub(times[k]))
+			out[k] = append(out[k], timeWeight{i, 1 - w})
+			out[k+1] = append(out[k+1], timeWeight{i, w})
+		}
+	}
+	return out
+}
+
+// levelWeights 深度depth在升序层深depths上的插值权重: nearest取最近层，interpolated在相邻两层之间线性插值；
+// 浅于最浅层时取最浅层，深于最深层时为nil
+func levelWeights(depths []float64, depth float64, method string) []gridWeight {
+	n := len(depths)
+	if n == 1 || depth <= depths[0] {
+		return []gridWeight{{0, 1}}
+	}
+	if depth > depths[n-1] {
+		return nil
+	}
+	k := sort.SearchFloat64s(depths, depth) // depths[k-1] < depth <= depths[k]
+	if depths[k] == depth {
+		return []gridWeight{{k, 1}}
+	}
+	w := (depth - depths[k-1]) / (depths[k] - depths[k-1])
+	if method == "nearest" {
+		if w < 0.5 {
+			return []gridWeight{{k - 1, 1}}
+		}
+		return []gridWeight{{k, 1}}
+	}
+	return []gridWeight{{k - 1, 1 - w}, {k, w}}
+}
+
+// modelLevelRange 覆盖观测深度范围[minDepth, maxDepth]所需的模式层深范围(含两侧相邻的层)，模式没有垂向坐标时为(0, 0)
+func modelLevelRange(src *dataio.Source, v *dataio.Variable, minDepth, maxDepth, reflat float64) (lo, hi float64, err error) {
+	zc := src.Coordinate(v, dataio.AxisZ)
+	if src.AxisDim(v, dataio.AxisZ) < 0 || zc == nil {
+		return 0, 0, nil
+	}
+	z, err := zc.ReadAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	lo, hi = 0, math.Inf(1)
+	for _, x := range z {
+		d := math.Abs(x)
+		if isPressureAxis(zc) {
+			d = argo.PressureToDepth(d, reflat)
+		}
+		if d <= minDepth && d > lo {
+			lo = d
+		}
+		if d >= maxDepth && d < hi {
+			hi = d
+		}
+	}
+	if math.IsInf(hi, 1) {
+		hi = 0
+	}
+	return lo, hi, nil
+}
+
+// colocate 按opts将观测与模式配对，返回有效的配对
+func colocate(model *analysisSource, mv *dataio.Variable, obs *volumeSample, opts comparisonOptions) ([]matchup, error) {
+	var times []time.Time
+	if model.AxisDim(mv, dataio.AxisTime) >= 0 {
+		var err error
+		if times, err = dataio.Times(model.Coordinate(mv, dataio.AxisTime)); err != nil {
+			return nil, err
+		}
+		if times == nil {
+			times = []time.Time{}
+		}
+	}
+	maxDiff := time.Duration(opts.MaxTimeDiff * float64(time.Hour))
+	if opts.MaxTimeDiff == 0 {
+		maxDiff = resample.TypicalStep(times)
+	}
+	byTime := modelTimeWeights(times, obs.Times, opts.Method, maxDiff)
+
+	// 读取范围: 观测的经纬度范围和覆盖观测深度的模式层
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	minDepth, maxDepth := math.Inf(1), 0.0
+	for i := range obs.Values {
+		bbox[0], bbox[2] = math.Min(bbox[0], obs.Lats[i]), math.Max(bbox[2], obs.Lats[i])
+		bbox[1], bbox[3] = math.Min(bbox[1], obs.Lngs[i]), math.Max(bbox[3], obs.Lngs[i])
+		minDepth, maxDepth = math.Min(minDepth, obs.Depths[i]), math.Max(maxDepth, obs.Depths[i])
+	}
+	levelLo, levelHi, err := modelLevelRange(model.Source, mv, minDepth, maxDepth, (bbox[0]+bbox[2])/2)
+	if err != nil {
+		return nil, err
+	}
+	hasZ := model.AxisDim(mv, dataio.AxisZ) >= 0
+
+	indices := make([]int, 0, len(byTime))
+	for k := range byTime {
+		indices = append(indices, k)
+	}
+	sort.Ints(indices)
+
+	sums := make([]float64, len(obs.Values))
+	weights := make([]float64, len(obs.Values))
+	invalid := make([]bool, len(obs.Values))
+	var horizontal [][]gridWeight // 各观测的水平插值权重，各时次网格相同，只计算一次
+	for _, k := range indices {
+		slab, err := readGridSlab(model.Source, mv, k, bbox, levelLo, levelHi, maxComparisonValues)
+		if err != nil {
+			return nil, err
+		}
+		if horizontal == nil {
+			if slab.AxisLats == nil && len(obs.Values)*len(slab.Lats) > maxComparisonValues*10 {
+				return nil, fmt.Errorf("%w: model on an irregular grid with too many observations, narrow the region or time range", ErrInvalidAnalysisParams)
+			}
+			horizontal = make([][]gridWeight, len(obs.Values))
+			for i := range obs.Values {
+				method := "bilinear"
+				if opts.Method == "nearest" {
+					method = "nearest"
+				}
+				horizontal[i] = slab.weights(obs.Lats[i], obs.Lngs[i], method)
+			}
+		}
+		for _, tw := range byTime[k] {
+			i := tw.obs
+			if invalid[i] || horizontal[i] == nil {
+				invalid[i] = true
+				continue
+			}
+			levels := []gridWeight{{0, 1}}
+			if hasZ {
+				if levels = levelWeights(slab.Depths, obs.Depths[i], opts.Method); levels == nil {
+					invalid[i] = true
+					continue
+				}
+			}
+			var x float64
+			for _, l := range levels {
+				y := sampleWeights(slab.Values[l.index], horizontal[i])
+				x += l.weight * y
+			}
+			if math.IsNaN(x) {
+				invalid[i] = true
+				continue
+			}
+			sums[i] += tw.weight * x
+			weights[i] += tw.weight
+		}
+	}
+
+	var matches []matchup
+	for i := range obs.Values {
+		if invalid[i] || weights[i] == 0 {
+			continue
+		}
+		matches = append(matches, matchup{
+			Time:        obs.Times[i],
+			Lat:         obs.Lats[i],
+			Lng:         obs.Lngs[i],
+			Depth:       obs.Depths[i],
+			Observation: obs.Values[i],
+			Model:       sums[i] / weights[i],
+		})
+	}
+	return matches, nil
+}
+
+// skillSummary 对比指标的结果格式
+func skillSummary(s stats.Skill) map[string]interface{} {
+	normalizedStd, correlation, normalizedCRMSE := s.Taylor()
+	return map[string]interface{}{
+		"count":           s.Count,
+		"observationMean": nullable(s.ObsMean),
+		"modelMean":       nullable(s.ModelMean),
+		"bias":            nullable(s.Bias),
+		"rmse":            nullable(s.RMSE),
+		"mae":             nullable(s.MAE),
+		"correlation":     nullable(s.Correlation),
+		"correlationP":    nullable(s.PValue),
+		"willmott":        nullable(s.Willmott),
+		"observationStd":  nullable(s.ObsStd),
+		"modelStd":        nullable(s.ModelStd),
+		"centeredRMSE":    nullable(s.CRMSE),
+		"taylor": map[string]interface{}{
+			"normalizedStd":   nullable(normalizedStd),
+			"correlation":     nullable(correlation),
+			"normalizedCRMSE": nullable(normalizedCRMSE),
+		},
+	}
+}
+
+// executeComparison 模式与观测对比: 将参数observationDatasetId指定的观测与modelDatasetId指定的模式在时间和空间上配对
+// (最近格点或插值)，计算偏差、RMSE、MAE、相关系数、Willmott技巧评分和Taylor图坐标，返回配对表和误差的空间分布
+func (s *analysisService) executeComparison(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseComparisonOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	modelID, obsID := paramString(params, "modelDatasetId"), paramString(params, "observationDatasetId")
+	if modelID == "" || obsID == "" {
+		return nil, fmt.Errorf("%w: modelDatasetId and observationDatasetId are required", ErrInvalidAnalysisParams)
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	bounds, hasBounds, err := paramBounds(params, "bounds")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+
+	model, err := s.openDataset(map[string]interface{}{"datasetId": modelID})
+	if err != nil {
+		return nil, err
+	}
+	defer model.Close()
+	obsSrc, err := s.openDataset(map[string]interface{}{"datasetId": obsID, "qcFlags": params["qcFlags"]})
+	if err != nil {
+		return nil, err
+	}
+	defer obsSrc.Close()
+
+	// 单位: 参数units，未指定时为模式变量的单位，观测换算到该单位
+	mv, err := s.comparisonVariable(model, params, "modelVariable", paramString(params, "units"))
+	if err != nil {
+		return nil, err
+	}
+	units := s.standardNames.Resolver().Units(mv)
+	ov, err := s.comparisonVariable(obsSrc, params, "observationVariable", units)
+	if err != nil {
+		return nil, err
+	}
+
+	// 观测
+	r := volumeRange{From: startDate, To: endDate, MinDepth: opts.MinDepth, MaxDepth: opts.MaxDepth}
+	if opts.MaxDepth == 0 {
+		r.MaxDepth = math.Inf(1)
+	}
+	if hasBounds {
+		r.Bounds = &bounds
+	}
+	obs, err := extractVolume(obsSrc.Source, ov, r, maxComparisonValues)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	// 去掉没有位置的观测
+	located := &volumeSample{}
+	for i := range obs.Values {
+		if math.IsNaN(obs.Lats[i]) || math.IsNaN(obs.Lngs[i]) {
+			continue
+		}
+		located.Values = append(located.Values, obs.Values[i])
+		located.Lats = append(located.Lats, obs.Lats[i])
+		located.Lngs = append(located.Lngs, obs.Lngs[i])
+		located.Depths = append(located.Depths, obs.Depths[i])
+		located.Times = append(located.Times, obs.Times[i])
+	}
+	if len(located.Values) == 0 {
+		return nil, fmt.Errorf("%w: no located observations in the selected range", ErrInvalidAnalysisParams)
+	}
+	if len(located.Values) > maxComparisonObservations {
+		return nil, fmt.Errorf("%w: too many observations (%d, max %d), narrow the region, time or depth range",
+			ErrInvalidAnalysisParams, len(located.Values), maxComparisonObservations)
+	}
+
+	matches, err := colocate(model, mv, located, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: no observations could be matched with the model in space and time", ErrInvalidAnalysisParams)
+	}
+
+	// 总体指标
+	modelValues := make([]float64, len(matches))
+	obsValues := make([]float64, len(matches))
+	field := &horizontalField{Lats: make([]float64, len(matches)), Lngs: make([]float64, len(matches))}
+	first, last := matches[0].Time, matches[0].Time
+	for i, m := range matches {
+		modelValues[i], obsValues[i] = m.Model, m.Observation
+		field.Lats[i], field.Lngs[i] = m.Lat, m.Lng
+		if m.Time.Before(first) {
+			first = m.Time
+		}
+		if m.Time.After(last) {
+			last = m.Time
+		}
+	}
+	skill := stats.NewSkill(modelValues, obsValues)
+
+	// 误差的空间分布
+	gridParams := map[string]interface{}{"bounds": params["bounds"], "resolution": params["resolution"]}
+	grid, err := newRegularGrid(gridParams, field)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	cells := map[int][]int{}
+	for i, m := range matches {
+		gi := int(math.Floor((m.Lat-grid.minLat)/grid.step + 0.5))
+		gj := int(math.Floor(math.Mod(m.Lng-grid.minLng+720, 360)/grid.step + 0.5))
+		if gi < 0 || gi >= grid.latCount || gj < 0 || gj >= grid.lngCount {
+			continue
+		}
+		cells[gi*grid.lngCount+gj] = append(cells[gi*grid.lngCount+gj], i)
+	}
+	keys := []string{"count", "bias", "rmse", "mae", "correlation"}
+	maps := map[string][][]float64{}
+	for _, key := range keys {
+		rows := make([][]float64, grid.latCount)
+		for i := range rows {
+			rows[i] = make([]float64, grid.lngCount)
+			for j := range rows[i] {
+				rows[i][j] = math.NaN()
+			}
+		}
+		maps[key] = rows
+	}
+	for cell, members := range cells {
+		m := make([]float64, len(members))
+		o := make([]float64, len(members))
+		for k, i := range members {
+			m[k], o[k] = modelValues[i], obsValues[i]
+		}
+		cs := stats.NewSkill(m, o)
+		gi, gj := cell/grid.lngCount, cell%grid.lngCount
+		maps["count"][gi][gj] = float64(cs.Count)
+		maps["bias"][gi][gj] = cs.Bias
+		maps["rmse"][gi][gj] = cs.RMSE
+		maps["mae"][gi][gj] = cs.MAE
+		maps["correlation"][gi][gj] = cs.Correlation
+	}
+	data := map[string]interface{}{}
+	for key, values := range maps {
+		rows := make([][]interface{}, len(values))
+		for i, row := range values {
+			rows[i] = nullableSlice(row)
+		}
+		data[key] = rows
+	}
+
+	// 配对表
+	idx := sampleIndices(len(matches), maxComparisonRows)
+	table := map[string]interface{}{}
+	timestamps := make([]interface{}, len(idx))
+	lats := make([]float64, len(idx))
+	lngs := make([]float64, len(idx))
+	depths := make([]float64, len(idx))
+	observations := make([]float64, len(idx))
+	models := make([]float64, len(idx))
+	differences := make([]float64, len(idx))
+	for k, i := range idx {
+		m := matches[i]
+		if !m.Time.IsZero() {
+			timestamps[k] = m.Time.Format(time.RFC3339)
+		}
+		lats[k], lngs[k], depths[k] = m.Lat, m.Lng, roundTo(m.Depth, 2)
+		observations[k], models[k] = m.Observation, m.Model
+		differences[k] = m.Model - m.Observation
+	}
+	table["timestamp"], table["lat"], table["lng"], table["depth"] = timestamps, lats, lngs, depths
+	table["observation"], table["model"], table["difference"] = observations, models, differences
+
+	var timeRange interface{}
+	if !first.IsZero() {
+		timeRange = map[string]interface{}{"start": first.Format(time.RFC3339), "end": last.Format(time.RFC3339)}
+	}
+	return map[string]interface{}{
+		"model":            map[string]interface{}{"datasetId": modelID, "variable": mv.Name},
+		"observation":      map[string]interface{}{"datasetId": obsID, "variable": ov.Name},
+		"variableUnits":    units,
+		"options":          opts,
+		"timeRange":        timeRange,
+		"observations":     len(located.Values),
+		"matched":          len(matches),
+		"statistics":       skillSummary(skill),
+		"matchups":         table,
+		"matchupsReturned": len(idx),
+		"bounds":           grid.bounds(),
+		"resolution":       grid.resolution,
+		"grid":             grid.info(),
+		"units": map[string]string{
+			"count": "count", "bias": units, "rmse": units, "mae": units, "correlation": "1",
+		},
+		"data": data,
+	}, nil
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/currents"
+	"github.com/sinker/ssop/pkg/dataio"
+)
+
+// defaultRoseSpeedBins 海流玫瑰图默认的流速等级分界(m/s)
+var defaultRoseSpeedBins = []float64{0.1, 0.25, 0.5, 1}
+
+// currentVariables 流速的东向和北向分量: 参数uVariable、vVariable指定的变量，未指定时按标准名查找并换算到m/s
+func (s *analysisService) currentVariables(src *analysisSource, params map[string]interface{}) (u, v *dataio.Variable, units string, err error) {
+	uName, vName := paramString(params, "uVariable"), paramString(params, "vVariable")
+	if uName == "" && vName == "" {
+		resolver := s.standardNames.Resolver()
+		if u, err = resolver.Resolve(src.Source, "eastward_sea_water_velocity", "m s-1"); err != nil {
+			return nil, nil, "", fmt.Errorf("%w: dataset has no eastward current variable, specify uVariable and vVariable", ErrInvalidAnalysisParams)
+		}
+		if v, err = resolver.Resolve(src.Source, "northward_sea_water_velocity", "m s-1"); err != nil {
+			return nil, nil, "", fmt.Errorf("%w: dataset has no northward current variable, specify uVariable and vVariable", ErrInvalidAnalysisParams)
+		}
+		return u, v, "m s-1", nil
+	}
+	if uName == "" || vName == "" {
+		return nil, nil, "", fmt.Errorf("%w: uVariable and vVariable must be specified together", ErrInvalidAnalysisParams)
+	}
+	for _, name := range []string{uName, vName} {
+		if x := src.Var(name); x == nil || x.IsText() {
+			return nil, nil, "", fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+		}
+	}
+	return src.Var(uName), src.Var(vName), src.Var(uName).Units(), nil
+}
+
+// currentFieldOptions 流场诊断参数
+type currentFieldOptions struct {
+	EddyThreshold float64 `json:"eddyThreshold"` // 涡旋判据 W < -eddyThreshold×σ_W
+	MinEddyCells  int     `json:"minEddyCells"`  // 涡旋的最少格点数
+}
+
+// parseCurrentFieldOptions 读取参数eddyThreshold(默认0.2)和minEddyCells(默认4)
+func parseCurrentFieldOptions(params map[string]interface{}) (currentFieldOptions, error) {
+	opts := currentFieldOptions{EddyThreshold: 0.2, MinEddyCells: 4}
+	threshold, ok, err := paramFloat(params, "eddyThreshold")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if threshold <= 0 || threshold > 5 {
+			return opts, fmt.Errorf("%w: eddyThreshold must be in (0, 5]", ErrInvalidAnalysisParams)
+		}
+		opts.EddyThreshold = threshold
+	}
+	cells, ok, err := paramFloat(params, "minEddyCells")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if cells < 1 || cells != math.Trunc(cells) {
+			return opts, fmt.Errorf("%w: minEddyCells must be a positive integer", ErrInvalidAnalysisParams)
+		}
+		opts.MinEddyCells = int(cells)
+	}
+	return opts, nil
+}
+
+// executeCurrentField 参数date、depth指定时间和深度的流场: u、v插值到bounds、resolution指定的规则网格，
+// 计算流速、流向、涡度、散度和Okubo-Weiss参数并识别涡旋；vectors为前端绘制箭头和流线用的矢量网格
+func (s *analysisService) executeCurrentField(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseCurrentFieldOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	date, err := paramTime(params, "date")
+	if err != nil {
+		return nil, err
+	}
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	uVar, vVar, units, err := s.currentVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+	uField, err := extractHorizontalField(src.Source, uVar, date, depth)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", uVar.Name, err)
+	}
+	vField, err := extractHorizontalField(src.Source, vVar, date, depth)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", vVar.Name, err)
+	}
+	grid, err := newRegularGrid(params, uField)
+	if err != nil {
+		return nil, err
+	}
+	u, v := grid.regrid(uField), grid.regrid(vField)
+
+	speed := make([][]float64, len(u))
+	direction := make([][]float64, len(u))
+	for i := range u {
+		speed[i] = make([]float64, len(u[i]))
+		direction[i] = make([]float64, len(u[i]))
+		for j := range u[i] {
+			speed[i][j] = currents.Speed(u[i][j], v[i][j])
+			direction[i][j] = currents.Direction(u[i][j], v[i][j])
+		}
+	}
+	g := currents.Grid{MinLat: grid.minLat, MinLng: grid.minLng, Step: grid.step}
+	kinematics := currents.Compute(g, u, v)
+	eddies := currents.DetectEddies(g, kinematics, opts.EddyThreshold, opts.MinEddyCells)
+
+	data := map[string]interface{}{}
+	for key, values := range map[string][][]float64{
+		"u":          u,
+		"v":          v,
+		"speed":      speed,
+		"direction":  direction,
+		"vorticity":  kinematics.Vorticity,
+		"divergence": kinematics.Divergence,
+		"okuboWeiss": kinematics.OkuboWeiss,
+	} {
+		rows := make([][]interface{}, len(values))
+		for i, row := range values {
+			rows[i] = nullableSlice(row)
+		}
+		data[key] = rows
+	}
+
+	eddyList := make([]map[string]interface{}, len(eddies))
+	for i, e := range eddies {
+		kind := "anticyclonic"
+		if e.Cyclonic {
+			kind = "cyclonic"
+		}
+		eddyList[i] = map[string]interface{}{
+			"lat":           e.Lat,
+			"lng":           e.Lng,
+			"type":          kind,
+			"cells":         e.Cells,
+			"area":          e.Area,
+			"radius":        e.Radius,
+			"meanVorticity": e.MeanVorticity,
+			"minOkuboWeiss": e.MinOkuboWeiss,
+		}
+	}
+
+	var actualTime interface{}
+	if !uField.Time.IsZero() {
+		actualTime = uField.Time.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"time":       actualTime,
+		"depth":      nullable(uField.Depth),
+		"bounds":     grid.bounds(),
+		"resolution": grid.resolution,
+		"grid":       grid.info(),
+		"variables":  map[string]string{"u": uVar.Name, "v": vVar.Name},
+		"units": map[string]string{
+			"u":          units,
+			"v":          units,
+			"speed":      units,
+			"direction":  "degree",
+			"vorticity":  "s-1",
+			"divergence": "s-1",
+			"okuboWeiss": "s-2",
+		},
+		"data":    data,
+		"vectors": vectorGrid(grid, u, v, units, uField.Time),
+		"eddies":  eddyList,
+		"options": opts,
+	}, nil
+}
+
+// vectorGrid 矢量网格: u、v两条记录，header描述网格(la1为最北一行的纬度，dx、dy为格距)，
+// data按从北到南、从西到东逐行展开，缺测为null；与 leaflet-velocity 等前端库使用的 grib2json 格式兼容
+func vectorGrid(grid *regularGrid, u, v [][]float64, units string, t time.Time) []map[string]interface{} {
+	var refTime interface{}
+	if !t.IsZero() {
+		refTime = t.Format(time.RFC3339)
+	}
+	records := make([]map[string]interface{}, 0, 2)
+	for k, values := range [][][]float64{u, v} {
+		flat := make([]interface{}, 0, grid.latCount*grid.lngCount)
+		for i := len(values) - 1; i >= 0; i-- {
+			flat = append(flat, nullableSlice(values[i])...)
+		}
+		records = append(records, map[string]interface{}{
+			"header": map[string]interface{}{
+				"parameterCategory": 2,
+				"parameterNumber":   2 + k, // 2为u分量，3为v分量
+				"parameterUnit":     units,
+				"nx":                grid.lngCount,
+				"ny":                grid.latCount,
+				"lo1":               grid.minLng,
+				"la1":               grid.minLat + float64(grid.latCount-1)*grid.step,
+				"lo2":               grid.minLng + float64(grid.lngCount-1)*grid.step,
+				"la2":               grid.minLat,
+				"dx":                grid.step,
+				"dy":                grid.step,
+				"refTime":           refTime,
+			},
+			"data": flat,
+		})
+	}
+	return records
+}
+
+// roseOptions 海流玫瑰图参数
+type roseOptions struct {
+	Sectors   int       `json:"sectors"`   // 流向扇区数
+	SpeedBins []float64 `json:"speedBins"` // 流速等级分界
+}
+
+// parseRoseOptions 读取参数sectors(4~72，默认16)和speedBins(逗号分隔的升序流速分界，默认0.1,0.25,0.5,1)
+func parseRoseOptions(params map[string]interface{}) (roseOptions, error) {
+	opts := roseOptions{Sectors: 16, SpeedBins: defaultRoseSpeedBins}
+	sectors, ok, err := paramFloat(params, "sectors")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if sectors < 4 || sectors > 72 || sectors != math.Trunc(sectors) {
+			return opts, fmt.Errorf("%w: sectors must be an integer between 4 and 72", ErrInvalidAnalysisParams)
+		}
+		opts.Sectors = int(sectors)
+	}
+	if s := paramString(params, "speedBins"); s != "" {
+		var bins []float64
+		for _, part := range strings.Split(s, ",") {
+			x, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || x <= 0 {
+				return opts, fmt.Errorf("%w: invalid speedBins %q", ErrInvalidAnalysisParams, s)
+			}
+			bins = append(bins, x)
+		}
+		if !sort.Float64sAreSorted(bins) {
+			return opts, fmt.Errorf("%w: speedBins must be in ascending order", ErrInvalidAnalysisParams)
+		}
+		opts.SpeedBins = bins
+	}
+	return opts, nil
+}
+
+// executeCurrentRose 参数lat、lng处startDate、endDate内的海流玫瑰图: 按流去的方向分扇区、按流速分等级统计出现频率，
+// 并给出平均流速、最大流速、平均流矢量和稳定度
+func (s *analysisService) executeCurrentRose(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseRoseOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+	lat, _, err := paramFloat(params, "lat")
+	if err != nil {
+		return nil, err
+	}
+	lng, _, err := paramFloat(params, "lng")
+	if err != nil {
+		return nil, err
+	}
+	var depthParam *float64
+	if depth, ok, err := paramFloat(params, "depth"); err != nil {
+		return nil, err
+	} else if ok {
+		depthParam = &depth
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	uVar, vVar, units, err := s.currentVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+	us, err := extractPointSeries(src.Source, uVar, lat, lng, depthParam, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", uVar.Name, err)
+	}
+	vs, err := extractPointSeries(src.Source, vVar, lat, lng, depthParam, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", vVar.Name, err)
+	}
+	if len(us.Values) != len(vs.Values) {
+		return nil, fmt.Errorf("%w: %s and %s have different time axes", ErrInvalidAnalysisParams, uVar.Name, vVar.Name)
+	}
+
+	rose := currents.NewRose(us.Values, vs.Values, opts.Sectors, opts.SpeedBins)
+	width := 360 / float64(opts.Sectors)
+	sectors := make([]map[string]interface{}, opts.Sectors)
+	for k := range sectors {
+		total := 0.0
+		for _, f := range rose.Frequencies[k] {
+			total += f
+		}
+		center := float64(k) * width
+		sectors[k] = map[string]interface{}{
+			"direction":   center,
+			"from":        math.Mod(center-width/2+360, 360),
+			"to":          center + width/2,
+			"counts":      rose.Counts[k],
+			"frequencies": rose.Frequencies[k],
+			"total":       total,
+		}
+	}
+	classes := make([]map[string]interface{}, len(opts.SpeedBins)+1)
+	for c := range classes {
+		lower, upper := 0.0, math.NaN()
+		if c > 0 {
+			lower = opts.SpeedBins[c-1]
+		}
+		if c < len(opts.SpeedBins) {
+			upper = opts.SpeedBins[c]
+		}
+		classes[c] = map[string]interface{}{"min": lower, "max": nullable(upper)}
+	}
+
+	var first, last time.Time
+	for i, t := range us.Times {
+		if t.IsZero() || math.IsNaN(us.Values[i]) || math.IsNaN(vs.Values[i]) {
+			continue
+		}
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	timeRange := map[string]interface{}{"start": nil, "end": nil}
+	if !first.IsZero() {
+		timeRange = map[string]interface{}{"start": first.Format(time.RFC3339), "end": last.Format(time.RFC3339)}
+	}
+
+	return map[string]interface{}{
+		"location":     map[string]interface{}{"lat": nullable(us.Lat), "lng": nullable(us.Lng), "depth": nullable(us.Depth)},
+		"variables":    map[string]string{"u": uVar.Name, "v": vVar.Name},
+		"units":        units,
+		"timeRange":    timeRange,
+		"samples":      rose.Samples,
+		"calm":         rose.Calm,
+		"options":      opts,
+		"speedClasses": classes,
+		"sectors":      sectors,
+		"statistics": map[string]interface{}{
+			"meanSpeed": nullable(rose.MeanSpeed),
+			"maxSpeed":  nullable(rose.MaxSpeed),
+			"meanVector": map[string]interface{}{
+				"u":         nullable(rose.MeanU),
+				"v":         nullable(rose.MeanV),
+				"speed":     nullable(currents.Speed(rose.MeanU, rose.MeanV)),
+				"direction": nullable(currents.Direction(rose.MeanU, rose.MeanV)),
+			},
+			"steadiness": nullable(rose.Steadiness()),
+		},
+	}, nil
+}
+
+// GetCurrentField 获取流场的流速、流向、涡度、散度、Okubo-Weiss参数和涡旋
+func (s *analysisService) GetCurrentField(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeCurrentField(params)
+}
+
+// GetCurrentRose 获取指定位置的海流玫瑰图
+func (s *analysisService) GetCurrentRose(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeCurrentRose(params)
+}
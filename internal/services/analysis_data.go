@@ -0,0 +1,663 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/argo"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/gsw"
+	"github.com/sinker/ssop/pkg/netcdf"
+	"github.com/sinker/ssop/pkg/resample"
+)
+
+// analysisSource 分析任务读取的数据源，按参数qcFlags过滤不可接受的质量标志
+type analysisSource struct {
+	*dataio.Source
+	dataset *models.Dataset
+	flags   *netcdf.File
+	related []*dataio.Source // 随数据源一起关闭的其他数据源(如计算距平用的气候态)
+}
+
+// Close 关闭数据源、标志文件和相关数据源
+func (a *analysisSource) Close() error {
+	if a.flags != nil {
+		a.flags.Close()
+	}
+	for _, r := range a.related {
+		r.Close()
+	}
+	return a.Source.Close()
+}
+
+// openDataset 打开参数datasetId指定的数据集，参数qcFlags(如[1,2])指定可接受的质量标志
+func (s *analysisService) openDataset(params map[string]interface{}) (*analysisSource, error) {
+	datasetID, _ := params["datasetId"].(string)
+	if datasetID == "" {
+		return nil, errors.New("missing required parameter: datasetId")
+	}
+
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return nil, fmt.Errorf("dataset not found: %w", err)
+	}
+	if dataset.FilePath == "" {
+		return nil, fmt.Errorf("dataset %s has no data file", datasetID)
+	}
+
+	source, err := dataio.Open(dataset.FilePath, dataset.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+
+	flags, err := applyQCFilter(source, dataset, parseQCFlags(params))
+	if err != nil {
+		source.Close()
+		return nil, fmt.Errorf("failed to open QC flags: %w", err)
+	}
+	addDerivedVariables(source, s.standardNames.Resolver())
+	return &analysisSource{Source: source, dataset: dataset, flags: flags}, nil
+}
+
+// analysisVariables 分析的变量: 参数variables为逗号分隔的变量名(可为TEOS-10派生变量，如sigma0、sound_speed)，
+// 结果以变量名为键、单位为变量自身的单位；未指定时按标准名查找温度和盐度并换算到℃和PSU
+func (s *analysisService) analysisVariables(src *analysisSource, params map[string]interface{}) (map[string]*dataio.Variable, map[string]string, error) {
+	variables := map[string]*dataio.Variable{}
+	units := map[string]string{}
+	if list := paramString(params, "variables"); list != "" {
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			v := src.Var(name)
+			if v == nil || v.IsText() {
+				return nil, nil, fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+			}
+			variables[name], units[name] = v, v.Units()
+		}
+		if len(variables) == 0 {
+			return nil, nil, fmt.Errorf("%w: no variables specified", ErrInvalidAnalysisParams)
+		}
+		return variables, units, nil
+	}
+
+	resolver := s.standardNames.Resolver()
+	if v, err := resolver.Resolve(src.Source, "sea_water_temperature", "degC"); err == nil {
+		variables["temperature"], units["temperature"] = v, "degC"
+	}
+	if v, err := resolver.Resolve(src.Source, "sea_water_salinity", "psu"); err == nil {
+		variables["salinity"], units["salinity"] = v, "psu"
+	}
+	if len(variables) == 0 {
+		return nil, nil, errors.New("dataset has no temperature or salinity variable")
+	}
+	return variables, units, nil
+}
+
+// paramString 读取字符串参数，数值和布尔参数按默认格式转换
+func paramString(params map[string]interface{}, key string) string {
+	switch v := params[key].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// paramFloat 读取数值参数，支持数值和字符串，参数不存在时ok为false
+func paramFloat(params map[string]interface{}, key string) (value float64, ok bool, err error) {
+	s := paramString(params, key)
+	if s == "" {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid parameter %s: %q", key, s)
+	}
+	return value, true, nil
+}
+
+// paramTime 读取时间参数，支持RFC3339和日期格式，参数不存在时返回零值
+func paramTime(params map[string]interface{}, key string) (time.Time, error) {
+	s := paramString(params, key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return parseTimeValue(key, s)
+}
+
+// parseTimeValue 解析RFC3339或日期格式的时间，key用于错误信息
+func parseTimeValue(key, s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid parameter %s: %q", key, s)
+}
+
+// paramBounds 读取范围参数 "minLat,minLng,maxLat,maxLng"，参数不存在时ok为false
+func paramBounds(params map[string]interface{}, key string) (bounds [4]float64, ok bool, err error) {
+	s := paramString(params, key)
+	if s == "" {
+		return bounds, false, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return bounds, false, fmt.Errorf("invalid parameter %s: %q", key, s)
+	}
+	for i, p := range parts {
+		if bounds[i], err = strconv.ParseFloat(strings.TrimSpace(p), 64); err != nil {
+			return bounds, false, fmt.Errorf("invalid parameter %s: %q", key, s)
+		}
+	}
+	if bounds[0] > bounds[2] || bounds[1] > bounds[3] {
+		return bounds, false, fmt.Errorf("invalid parameter %s: min greater than max", key)
+	}
+	return bounds, true, nil
+}
+
+// nullable 将NaN转换为nil，便于JSON序列化
+func nullable(x float64) interface{} {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil
+	}
+	return x
+}
+
+// nullableSlice 将数组中的NaN转换为nil
+func nullableSlice(values []float64) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, x := range values {
+		out[i] = nullable(x)
+	}
+	return out
+}
+
+// pointSeries 单点时间序列及所选格点的实际位置
+type pointSeries struct {
+	Times  []time.Time
+	Values []float64
+	Lat    float64
+	Lng    float64
+	Depth  float64
+}
+
+// extractPointSeries 提取距离(lat, lng)最近、深度最接近depth的格点在[from, to]内的时间序列，
+// depth为nil时取最接近海面的层；数据没有经纬度坐标时视为单站数据
+func extractPointSeries(src *dataio.Source, v *dataio.Variable, lat, lng float64, depth *float64, from, to time.Time) (*pointSeries, error) {
+	start, count := dataio.FullSlice(v)
+	ps := &pointSeries{Lat: math.NaN(), Lng: math.NaN(), Depth: math.NaN()}
+
+	if fixed, alat, alng, err := src.NearestPoint(v, lat, lng); err == nil {
+		for d, i := range fixed {
+			start[d], count[d] = i, 1
+		}
+		ps.Lat, ps.Lng = alat, alng
+	}
+
+	target := 0.0
+	if depth != nil {
+		target = *depth
+	}
+	if zdim, zi, z := src.Nearest(v, dataio.AxisZ, target); zdim >= 0 {
+		start[zdim], count[zdim] = zi, 1
+		ps.Depth = z
+	}
+
+	tdim, ts, tc, times, err := src.TimeRange(v, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if tc == 0 {
+		return ps, nil
+	}
+	start[tdim], count[tdim] = ts, tc
+
+	// 其余维度取第一个下标
+	for d := range count {
+		if d != tdim && count[d] > 1 {
+			count[d] = 1
+		}
+	}
+
+	values, err := v.Read(start, count)
+	if err != nil {
+		return nil, err
+	}
+	ps.Times, ps.Values = times, values
+	return ps, nil
+}
+
+// horizontalField 水平二维场: 固定时间和深度后剩余的经纬度网格
+type horizontalField struct {
+	Values []float64
+	Lats   []float64 // 与Values等长
+	Lngs   []float64 // 与Values等长
+	// 规则网格的一维坐标，非规则网格时为nil
+	AxisLats []float64
+	AxisLngs []float64
+	latFirst bool // 纬度维在经度维之前
+	Time     time.Time
+	Depth    float64
+}
+
+// extractHorizontalField 提取时间最接近t(零值时取第一个时次)、深度最接近depth的水平场
+func extractHorizontalField(src *dataio.Source, v *dataio.Variable, t time.Time, depth float64) (*horizontalField, error) {
+	cy, cx := src.Coordinate(v, dataio.AxisLat), src.Coordinate(v, dataio.AxisLon)
+	if cy == nil || cx == nil {
+		return nil, fmt.Errorf("variable %s has no latitude/longitude coordinates", v.Name)
+	}
+
+	start, count := dataio.FullSlice(v)
+	field := &horizontalField{Depth: math.NaN()}
+
+	if tdim := src.AxisDim(v, dataio.AxisTime); tdim >= 0 {
+		if t.IsZero() {
+			start[tdim], count[tdim] = 0, 1
+			if times, err := dataio.Times(src.Coordinate(v, dataio.AxisTime)); err == nil && len(times) > 0 {
+				field.Time = times[0]
+			}
+		} else {
+			_, ti, actual, err := src.NearestTime(v, t)
+			if err != nil {
+				return nil, err
+			}
+			start[tdim], count[tdim] = ti, 1
+			field.Time = actual
+		}
+	}
+	if zdim, zi, z := src.Nearest(v, dataio.AxisZ, depth); zdim >= 0 {
+		start[zdim], count[zdim] = zi, 1
+		field.Depth = z
+	}
+
+	// 水平维度为经纬度坐标所在的维度，其余维度取第一个下标
+	var hdims []string
+	var hshape []int
+	for d, name := range v.Dims {
+		if containsString(cy.Dims, name) || containsString(cx.Dims, name) {
+			if count[d] > 1 {
+				hdims = append(hdims, name)
+				hshape = append(hshape, count[d])
+			}
+			continue
+		}
+		count[d] = 1
+	}
+
+	values, err := v.Read(start, count)
+	if err != nil {
+		return nil, err
+	}
+	lats, err := cy.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	lngs, err := cx.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	field.Values = values
+	field.Lats = dataio.Broadcast(lats, cy.Dims, hdims, hshape)
+	field.Lngs = dataio.Broadcast(lngs, cx.Dims, hdims, hshape)
+	if len(cy.Dims) == 1 && len(cx.Dims) == 1 && cy.Dims[0] != cx.Dims[0] && len(hdims) == 2 {
+		field.AxisLats, field.AxisLngs = lats, lngs
+		field.latFirst = hdims[0] == cy.Dims[0]
+	}
+	return field, nil
+}
+
+// regrid 将水平场插值到以(minLat, minLng)为起点、step为间隔的规则网格:
+// 规则网格取最近格点(超出一个格距时为缺测)，非规则网格按网格单元求平均
+func (f *horizontalField) regrid(minLat, minLng float64, latCount, lngCount int, step float64) [][]float64 {
+	grid := make([][]float64, latCount)
+	for i := range grid {
+		grid[i] = make([]float64, lngCount)
+		for j := range grid[i] {
+			grid[i][j] = math.NaN()
+		}
+	}
+
+	if f.AxisLats != nil {
+		latIdx := nearestIndices(f.AxisLats, minLat, step, latCount, false)
+		lngIdx := nearestIndices(f.AxisLngs, minLng, step, lngCount, true)
+		nx := len(f.AxisLngs)
+		ny := len(f.AxisLats)
+		for i, yi := range latIdx {
+			for j, xi := range lngIdx {
+				if yi < 0 || xi < 0 {
+					continue
+				}
+				if f.latFirst {
+					grid[i][j] = f.Values[yi*nx+xi]
+				} else {
+					grid[i][j] = f.Values[xi*ny+yi]
+				}
+			}
+		}
+		return grid
+	}
+
+	sums := make([][]float64, latCount)
+	counts := make([][]int, latCount)
+	for i := range sums {
+		sums[i] = make([]float64, lngCount)
+		counts[i] = make([]int, lngCount)
+	}
+	for k, x := range f.Values {
+		if math.IsNaN(x) {
+			continue
+		}
+		i := int(math.Floor((f.Lats[k]-minLat)/step + 0.5))
+		j := int(math.Floor(math.Mod(f.Lngs[k]-minLng+720, 360)/step + 0.5))
+		if i < 0 || i >= latCount || j < 0 || j >= lngCount {
+			continue
+		}
+		sums[i][j] += x
+		counts[i][j]++
+	}
+	for i := range grid {
+		for j := range grid[i] {
+			if counts[i][j] > 0 {
+				grid[i][j] = sums[i][j] / float64(counts[i][j])
+			}
+		}
+	}
+	return grid
+}
+
+// nearestIndices 目标网格各点在一维坐标上的最近下标，距离超过max(step, 坐标间距)时为-1
+func nearestIndices(coord []float64, origin, step float64, n int, lng bool) []int {
+	spacing := step
+	if len(coord) > 1 {
+		if d := math.Abs(coord[len(coord)-1]-coord[0]) / float64(len(coord)-1); d > spacing {
+			spacing = d
+		}
+	}
+
+	idx := make([]int, n)
+	for i := range idx {
+		target := origin + float64(i)*step
+		best, bi := math.Inf(1), -1
+		for k, c := range coord {
+			d := math.Abs(c - target)
+			if lng {
+				d = dataio.LonDistance(c, target)
+			}
+			if d < best {
+				best, bi = d, k
+			}
+		}
+		if best > spacing {
+			bi = -1
+		}
+		idx[i] = bi
+	}
+	return idx
+}
+
+// resampleOptions 读取时间序列的重采样参数: interval为周期(见resample.ParsePeriod，多倍周期从startDate起算)，
+// reducer为聚合方法(默认mean，percentile由参数percentile指定)，minCount、minCoverage为周期内的最少有效值个数和最低覆盖率
+func resampleOptions(params map[string]interface{}) (resample.Options, error) {
+	var opts resample.Options
+	origin, err := paramTime(params, "startDate")
+	if err != nil {
+		return opts, err
+	}
+	if opts.Period, err = resample.ParsePeriod(paramString(params, "interval"), origin); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	percentile, _, err := paramFloat(params, "percentile")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.Reducer, err = resample.ParseReducer(paramString(params, "reducer"), percentile); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	minCount, _, err := paramFloat(params, "minCount")
+	if err != nil || minCount < 0 {
+		return opts, fmt.Errorf("%w: invalid minCount", ErrInvalidAnalysisParams)
+	}
+	minCoverage, _, err := paramFloat(params, "minCoverage")
+	if err != nil || minCoverage < 0 || minCoverage > 1 {
+		return opts, fmt.Errorf("%w: minCoverage must be between 0 and 1", ErrInvalidAnalysisParams)
+	}
+	opts.MinCount, opts.MinCoverage = int(minCount), minCoverage
+	return opts, nil
+}
+
+// volumeRange 子区域条件，Bounds为nil、From/To为零值时不限制，MaxDepth为+Inf时不限制深度
+type volumeRange struct {
+	Bounds   *[4]float64
+	From, To time.Time
+	MinDepth float64
+	MaxDepth float64
+}
+
+// volumeSample 子区域内逐个有效元素的值及其位置: 缺少经纬度坐标时为NaN，
+// 缺少深度坐标时视为海面，缺少时间坐标时为零值
+type volumeSample struct {
+	Values    []float64
+	Lats      []float64
+	Lngs      []float64
+	Depths    []float64 // 深度(m)，深度坐标为压力时由压力换算
+	Pressures []float64 // 压力(dbar)，深度坐标为深度时由深度换算
+	Times     []time.Time
+	Index     []int // 元素在读取范围内的展开下标，用于对齐同一网格上的其他变量
+}
+
+// isPressureAxis 判断垂向坐标是否为压力(dbar)
+func isPressureAxis(c *dataio.Variable) bool {
+	if strings.EqualFold(c.Attrs.String("standard_name"), "sea_water_pressure") {
+		return true
+	}
+	switch strings.ToLower(c.Units()) {
+	case "dbar", "decibar", "decibars", "dbars":
+		return true
+	}
+	return false
+}
+
+// volumeSlice 子区域在变量各维度上的外包读取范围，范围内没有数据时empty为true
+func volumeSlice(src *dataio.Source, v *dataio.Variable, r volumeRange) (start, count []int, empty bool, err error) {
+	start, count = dataio.FullSlice(v)
+
+	if tdim := src.AxisDim(v, dataio.AxisTime); tdim >= 0 && (!r.From.IsZero() || !r.To.IsZero()) {
+		_, ts, tc, _, err := src.TimeRange(v, r.From, r.To)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if tc == 0 {
+			return nil, nil, true, nil
+		}
+		start[tdim], count[tdim] = ts, tc
+	}
+
+	if zdim := src.AxisDim(v, dataio.AxisZ); zdim >= 0 && (r.MinDepth > 0 || !math.IsInf(r.MaxDepth, 1)) {
+		c := src.Coordinate(v, dataio.AxisZ)
+		z, err := c.ReadAll()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		// 压力(dbar)在数值上略大于深度(m)，按上限放宽3%预选，再逐元素精确筛选
+		maxZ := r.MaxDepth
+		if isPressureAxis(c) {
+			maxZ *= 1.03
+		}
+		lo, hi := indexRange(len(z), func(i int) bool {
+			d := math.Abs(z[i])
+			return d >= r.MinDepth && d <= maxZ
+		})
+		if lo < 0 {
+			return nil, nil, true, nil
+		}
+		start[zdim], count[zdim] = lo, hi-lo+1
+	}
+
+	// 规则网格按经纬度范围预选，其他网格逐元素筛选
+	if r.Bounds != nil {
+		b := r.Bounds
+		cy, cx := src.Coordinate(v, dataio.AxisLat), src.Coordinate(v, dataio.AxisLon)
+		ydim, xdim := src.AxisDim(v, dataio.AxisLat), src.AxisDim(v, dataio.AxisLon)
+		if cy != nil && cx != nil && ydim >= 0 && xdim >= 0 && ydim != xdim {
+			lats, err := cy.ReadAll()
+			if err != nil {
+				return nil, nil, false, err
+			}
+			lngs, err := cx.ReadAll()
+			if err != nil {
+				return nil, nil, false, err
+			}
+			ylo, yhi := indexRange(len(lats), func(i int) bool { return lats[i] >= b[0] && lats[i] <= b[2] })
+			xlo, xhi := indexRange(len(lngs), func(i int) bool { return dataio.InLonRange(lngs[i], b[1], b[3]) })
+			if ylo < 0 || xlo < 0 {
+				return nil, nil, true, nil
+			}
+			start[ydim], count[ydim] = ylo, yhi-ylo+1
+			start[xdim], count[xdim] = xlo, xhi-xlo+1
+		}
+	}
+	return start, count, false, nil
+}
+
+// indexRange 满足条件的第一个和最后一个下标，没有满足条件的下标时返回-1
+func indexRange(n int, ok func(i int) bool) (lo, hi int) {
+	lo, hi = -1, -1
+	for i := 0; i < n; i++ {
+		if ok(i) {
+			if lo < 0 {
+				lo = i
+			}
+			hi = i
+		}
+	}
+	return lo, hi
+}
+
+// readCoordinate 读取坐标变量在变量读取范围内的部分，并广播到读取范围的每个元素
+func readCoordinate(c *dataio.Variable, v *dataio.Variable, start, count []int) ([]float64, error) {
+	cs, cc := make([]int, len(c.Dims)), make([]int, len(c.Dims))
+	for i, name := range c.Dims {
+		for d, vname := range v.Dims {
+			if name == vname {
+				cs[i], cc[i] = start[d], count[d]
+			}
+		}
+	}
+	values, err := c.Read(cs, cc)
+	if err != nil {
+		return nil, err
+	}
+	return dataio.Broadcast(values, c.Dims, v.Dims, count), nil
+}
+
+// extractVolume 提取变量在子区域内的有效元素，读取范围的元素数超过maxValues时返回错误
+func extractVolume(src *dataio.Source, v *dataio.Variable, r volumeRange, maxValues int) (*volumeSample, error) {
+	sample := &volumeSample{}
+	start, count, empty, err := volumeSlice(src, v, r)
+	if err != nil || empty {
+		return sample, err
+	}
+	n := 1
+	for _, c := range count {
+		n *= c
+	}
+	if n > maxValues {
+		return nil, fmt.Errorf("selection of %s too large: %d values (max %d), narrow the region, time or depth range", v.Name, n, maxValues)
+	}
+
+	values, err := v.Read(start, count)
+	if err != nil {
+		return nil, err
+	}
+	coords := map[string][]float64{}
+	for _, axis := range []string{dataio.AxisLat, dataio.AxisLon, dataio.AxisZ} {
+		if c := src.Coordinate(v, axis); c != nil {
+			if coords[axis], err = readCoordinate(c, v, start, count); err != nil {
+				return nil, err
+			}
+		}
+	}
+	pressureAxis := false
+	if c := src.Coordinate(v, dataio.AxisZ); c != nil {
+		pressureAxis = isPressureAxis(c)
+	}
+
+	// 一维时间坐标: 广播时间下标后查表
+	var times []time.Time
+	var timeIndex []float64
+	if tdim := src.AxisDim(v, dataio.AxisTime); tdim >= 0 {
+		c := src.Coordinate(v, dataio.AxisTime)
+		if times, err = dataio.Times(c); err != nil {
+			return nil, err
+		}
+		idx := make([]float64, count[tdim])
+		for i := range idx {
+			idx[i] = float64(start[tdim] + i)
+		}
+		timeIndex = dataio.Broadcast(idx, c.Dims, v.Dims, count)
+	}
+
+	at := func(axis string, i int) float64 {
+		if values, ok := coords[axis]; ok {
+			return values[i]
+		}
+		return math.NaN()
+	}
+	for i, x := range values {
+		if math.IsNaN(x) {
+			continue
+		}
+		lat, lng := at(dataio.AxisLat, i), at(dataio.AxisLon, i)
+		if r.Bounds != nil && !math.IsNaN(lat) && !math.IsNaN(lng) {
+			b := r.Bounds
+			if lat < b[0] || lat > b[2] || !dataio.InLonRange(lng, b[1], b[3]) {
+				continue
+			}
+		}
+
+		z := math.Abs(at(dataio.AxisZ, i))
+		if math.IsNaN(z) {
+			z = 0
+		}
+		reflat := lat
+		if math.IsNaN(reflat) {
+			reflat = 0
+		}
+		depth, pressure := z, gsw.PFromZ(-z, reflat)
+		if pressureAxis {
+			depth, pressure = argo.PressureToDepth(z, reflat), z
+		}
+		if depth < r.MinDepth || depth > r.MaxDepth {
+			continue
+		}
+
+		var t time.Time
+		if timeIndex != nil {
+			t = times[int(timeIndex[i])]
+			if t.IsZero() {
+				continue
+			}
+		}
+
+		sample.Values = append(sample.Values, x)
+		sample.Lats = append(sample.Lats, lat)
+		sample.Lngs = append(sample.Lngs, lng)
+		sample.Depths = append(sample.Depths, depth)
+		sample.Pressures = append(sample.Pressures, pressure)
+		sample.Times = append(sample.Times, t)
+		sample.Index = append(sample.Index, i)
+	}
+	return sample, nil
+}
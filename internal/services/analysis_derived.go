@@ -0,0 +1,258 @@
+package services
+
+import (
+	"math"
+	"strings"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/gsw"
+)
+
+// derivedVariable 由温度、盐度和压力按TEOS-10计算的派生变量
+type derivedVariable struct {
+	name         string
+	standardName string
+	units        string
+	longName     string
+	// compute 由绝对盐度、保守温度和压力计算派生值，为nil时为沿深度差分的量(N2)
+	compute func(sa, ct, p float64) float64
+}
+
+// derivedVariables 分析中可按名称使用的派生变量
+var derivedVariables = []derivedVariable{
+	{"SA", "sea_water_absolute_salinity", "g/kg", "Absolute Salinity (TEOS-10)", func(sa, ct, p float64) float64 { return sa }},
+	{"CT", "sea_water_conservative_temperature", "degC", "Conservative Temperature (TEOS-10)", func(sa, ct, p float64) float64 { return ct }},
+	{"pt", "sea_water_potential_temperature", "degC", "Potential Temperature referenced to 0 dbar (TEOS-10)", func(sa, ct, p float64) float64 { return gsw.PtFromCT(sa, ct) }},
+	{"rho", "sea_water_density", "kg m-3", "In-situ Density (TEOS-10)", gsw.Rho},
+	{"sigma0", "sea_water_sigma_theta", "kg m-3", "Potential Density Anomaly referenced to 0 dbar (TEOS-10)", func(sa, ct, p float64) float64 { return gsw.Sigma0(sa, ct) }},
+	{"sigma2", "", "kg m-3", "Potential Density Anomaly referenced to 2000 dbar (TEOS-10)", func(sa, ct, p float64) float64 { return gsw.Sigma2(sa, ct) }},
+	{"sound_speed", "speed_of_sound_in_sea_water", "m s-1", "Speed of Sound (TEOS-10)", gsw.SoundSpeed},
+	{"N2", "square_of_brunt_vaisala_frequency_in_sea_water", "s-2", "Buoyancy Frequency Squared (TEOS-10)", nil},
+}
+
+// derivedInputs 派生变量的输入: 温度、盐度及其坐标
+type derivedInputs struct {
+	src         *dataio.Source
+	temp, salt  *dataio.Variable
+	tempKind    string // 温度的标准名，决定换算为保守温度的方法
+	saltKind    string // 盐度的标准名
+	absolute    bool   // 盐度为绝对盐度
+	z, lat, lon *dataio.Variable
+	pressure    bool // 垂向坐标为压力
+}
+
+// newDerivedInputs 查找数据源中维度相同的温度和盐度变量，缺少任一变量或无法换算单位时返回nil
+func newDerivedInputs(src *dataio.Source, resolver *dataio.Resolver) *derivedInputs {
+	tv, sv := resolver.Find(src, "sea_water_temperature"), resolver.Find(src, "sea_water_salinity")
+	if tv == nil || sv == nil || strings.Join(tv.Dims, ",") != strings.Join(sv.Dims, ",") {
+		return nil
+	}
+	in := &derivedInputs{src: src, tempKind: resolver.StandardName(tv)}
+	in.saltKind = resolver.StandardName(sv)
+	in.absolute = in.saltKind == "sea_water_absolute_salinity"
+
+	var err error
+	if in.temp, err = resolver.Convert(tv, "degC"); err != nil {
+		return nil
+	}
+	saltUnits := "psu"
+	if in.absolute {
+		saltUnits = "g/kg"
+	}
+	if in.salt, err = resolver.Convert(sv, saltUnits); err != nil {
+		return nil
+	}
+	in.z = src.Coordinate(tv, dataio.AxisZ)
+	in.lat = src.Coordinate(tv, dataio.AxisLat)
+	in.lon = src.Coordinate(tv, dataio.AxisLon)
+	in.pressure = in.z != nil && isPressureAxis(in.z)
+	return in
+}
+
+// addDerivedVariables 数据源同时含温度和盐度时，将TEOS-10派生变量加入数据源，读取时按需计算；
+// 与已有变量同名、或与温度/盐度本身为同一物理量的派生变量不加入
+func addDerivedVariables(src *dataio.Source, resolver *dataio.Resolver) {
+	in := newDerivedInputs(src, resolver)
+	if in == nil {
+		return
+	}
+	for _, d := range derivedVariables {
+		if src.Var(d.name) != nil || (d.standardName != "" && (d.standardName == in.tempKind || d.standardName == in.saltKind)) {
+			continue
+		}
+		attrs := dataio.Attributes{"units": d.units, "long_name": d.longName, "source": "TEOS-10"}
+		if d.standardName != "" {
+			attrs["standard_name"] = d.standardName
+		}
+		d := d
+		read := func(start, count []int) ([]float64, error) { return in.derive(d, start, count) }
+		if d.compute == nil {
+			read = in.buoyancy
+		}
+		src.Vars = append(src.Vars, dataio.NewVariable(d.name, in.temp.Dims, in.temp.Shape, "double", attrs, read))
+	}
+}
+
+// profileDim 变量的垂向维度下标: 一维垂向坐标所在的维度，或多维垂向坐标(如Argo的PRES(N_PROF, N_LEVELS))中
+// 不属于时间和水平坐标的维度；没有垂向坐标时返回-1
+func profileDim(src *dataio.Source, v *dataio.Variable) int {
+	if d := src.AxisDim(v, dataio.AxisZ); d >= 0 {
+		return d
+	}
+	c := src.Coordinate(v, dataio.AxisZ)
+	if c == nil {
+		return -1
+	}
+	for i := len(c.Dims) - 1; i >= 0; i-- {
+		shared := false
+		for _, axis := range []string{dataio.AxisTime, dataio.AxisLat, dataio.AxisLon} {
+			if a := src.Coordinate(v, axis); a != nil && containsString(a.Dims, c.Dims[i]) {
+				shared = true
+			}
+		}
+		if shared {
+			continue
+		}
+		for d, name := range v.Dims {
+			if name == c.Dims[i] {
+				return d
+			}
+		}
+	}
+	return -1
+}
+
+// read 读取切片内的绝对盐度、保守温度、压力和纬度
+func (in *derivedInputs) read(start, count []int) (sa, ct, p, lat []float64, err error) {
+	t, err := in.temp.Read(start, count)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	s, err := in.salt.Read(start, count)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	coord := func(c *dataio.Variable) ([]float64, error) {
+		if c == nil {
+			return make([]float64, len(t)), nil
+		}
+		return readCoordinate(c, in.temp, start, count)
+	}
+	if lat, err = coord(in.lat); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	lon, err := coord(in.lon)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if p, err = coord(in.z); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	sa, ct = s, t
+	for i := range t {
+		if math.IsNaN(lat[i]) {
+			lat[i] = 0
+		}
+		z := math.Abs(p[i])
+		if math.IsNaN(z) {
+			z = 0
+		}
+		if in.pressure {
+			p[i] = z
+		} else {
+			p[i] = gsw.PFromZ(-z, lat[i])
+		}
+		if !in.absolute {
+			sa[i] = gsw.SAFromSP(s[i], p[i], lon[i], lat[i])
+		}
+		switch in.tempKind {
+		case "sea_water_conservative_temperature":
+		case "sea_water_potential_temperature":
+			ct[i] = gsw.CTFromPt(sa[i], t[i])
+		default:
+			ct[i] = gsw.CTFromT(sa[i], t[i], p[i])
+		}
+	}
+	return sa, ct, p, lat, nil
+}
+
+// derive 逐点计算派生变量
+func (in *derivedInputs) derive(d derivedVariable, start, count []int) ([]float64, error) {
+	sa, ct, p, _, err := in.read(start, count)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(sa))
+	for i := range out {
+		if math.IsNaN(sa[i]) || math.IsNaN(ct[i]) {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = d.compute(sa[i], ct[i], p[i])
+	}
+	return out, nil
+}
+
+// buoyancy 计算N²: 读取范围沿深度维各向外扩展一层，先计算相邻层中点的N²，
+// 再取各层上下两个中点的平均值(只有一个有效时取该值)；没有深度维时为NaN
+func (in *derivedInputs) buoyancy(start, count []int) ([]float64, error) {
+	n := 1
+	for _, c := range count {
+		n *= c
+	}
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	zdim := profileDim(in.src, in.temp)
+	if zdim < 0 {
+		return out, nil
+	}
+
+	es, ec := append([]int(nil), start...), append([]int(nil), count...)
+	if es[zdim] > 0 {
+		es[zdim]--
+		ec[zdim]++
+	}
+	if es[zdim]+ec[zdim] < in.temp.Shape[zdim] {
+		ec[zdim]++
+	}
+	sa, ct, p, lat, err := in.read(es, ec)
+	if err != nil {
+		return nil, err
+	}
+
+	outer, inner := 1, 1
+	for d := 0; d < zdim; d++ {
+		outer *= count[d]
+	}
+	for d := zdim + 1; d < len(count); d++ {
+		inner *= count[d]
+	}
+	nz, shift := ec[zdim], start[zdim]-es[zdim]
+	colSA, colCT, colP := make([]float64, nz), make([]float64, nz), make([]float64, nz)
+	for o := 0; o < outer; o++ {
+		for k := 0; k < inner; k++ {
+			for z := 0; z < nz; z++ {
+				i := (o*nz+z)*inner + k
+				colSA[z], colCT[z], colP[z] = sa[i], ct[i], p[i]
+			}
+			mid, _ := gsw.Nsquared(colSA, colCT, colP, lat[o*nz*inner+k])
+			for z := 0; z < count[zdim]; z++ {
+				level := z + shift
+				sum, m := 0.0, 0
+				for _, j := range []int{level - 1, level} {
+					if j >= 0 && j < len(mid) && !math.IsNaN(mid[j]) {
+						sum += mid[j]
+						m++
+					}
+				}
+				if m > 0 {
+					out[(o*count[zdim]+z)*inner+k] = sum / float64(m)
+				}
+			}
+		}
+	}
+	return out, nil
+}
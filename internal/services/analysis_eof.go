@@ -0,0 +1,330 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/linalg"
+)
+
+// maxEOFModes EOF分析返回的最大模态数
+const maxEOFModes = 20
+
+// maxEOFValues EOF数据矩阵的最大元素数(时次数×格点数)
+const maxEOFValues = 10000000
+
+// maxEOFWork SVD的计算量上限(较小维度的平方×较大维度)
+const maxEOFWork = 20000000000
+
+// eofOptions EOF分析参数
+type eofOptions struct {
+	Modes         int     `json:"modes"`         // 返回的模态数
+	AreaWeighting bool    `json:"areaWeighting"` // 按纬度余弦的平方根加权
+	Detrend       bool    `json:"detrend"`       // 逐格点去除线性趋势
+	MinCoverage   float64 `json:"minCoverage"`   // 格点参与分解的最低有效时次比例
+}
+
+// parseEOFOptions 读取参数modes(默认3)、areaWeighting(默认true)、detrend(linear或none，默认none)和minCoverage(默认1)
+func parseEOFOptions(params map[string]interface{}) (eofOptions, error) {
+	opts := eofOptions{Modes: 3, AreaWeighting: paramString(params, "areaWeighting") != "false", MinCoverage: 1}
+	modes, ok, err := paramFloat(params, "modes")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if modes < 1 || modes > maxEOFModes || modes != math.Trunc(modes) {
+			return opts, fmt.Errorf("%w: modes must be an integer between 1 and %d", ErrInvalidAnalysisParams, maxEOFModes)
+		}
+		opts.Modes = int(modes)
+	}
+	switch paramString(params, "detrend") {
+	case "", "none":
+	case "linear":
+		opts.Detrend = true
+	default:
+		return opts, fmt.Errorf("%w: detrend must be linear or none", ErrInvalidAnalysisParams)
+	}
+	coverage, ok, err := paramFloat(params, "minCoverage")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if coverage <= 0 || coverage > 1 {
+			return opts, fmt.Errorf("%w: minCoverage must be in (0, 1]", ErrInvalidAnalysisParams)
+		}
+		opts.MinCoverage = coverage
+	}
+	return opts, nil
+}
+
+// singleVariable 单变量分析(EOF、海洋热浪)的变量: 参数variable指定的变量，未指定时按标准名查找温度并换算到℃；
+// 以变量名为键，便于计算距平
+func (s *analysisService) singleVariable(src *analysisSource, params map[string]interface{}) (map[string]*dataio.Variable, string, string, error) {
+	name := paramString(params, "variable")
+	if name == "" {
+		v, err := s.standardNames.Resolver().Resolve(src.Source, "sea_water_temperature", "degC")
+		if err != nil {
+			return nil, "", "", fmt.Errorf("%w: no variable specified and dataset has no temperature variable", ErrInvalidAnalysisParams)
+		}
+		return map[string]*dataio.Variable{v.Name: v}, v.Name, "degC", nil
+	}
+	v := src.Var(name)
+	if v == nil || v.IsText() {
+		return nil, "", "", fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+	}
+	return map[string]*dataio.Variable{name: v}, name, v.Units(), nil
+}
+
+// executeEOF 对参数startDate、endDate时间范围内的水平场做EOF分解: 逐格点去除时间平均(可选去除线性趋势)，
+// 按纬度加权后对时次×格点的距平矩阵做奇异值分解，返回前modes个空间模态、主成分时间序列和解释方差
+func (s *analysisService) executeEOF(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseEOFOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, key, units, err := s.singleVariable(src, params)
+	if err != nil {
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	v := variables[key]
+
+	// 逐时次读取水平场并插值到规则网格
+	_, _, _, times, err := src.TimeRange(v, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+	}
+	var grid *regularGrid
+	var actualDepth float64
+	var fields [][]float64
+	var fieldTimes []time.Time
+	for _, t := range times {
+		if t.IsZero() {
+			continue
+		}
+		field, err := extractHorizontalField(src.Source, v, t, depth)
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+		}
+		if grid == nil {
+			if grid, err = newRegularGrid(params, field); err != nil {
+				return nil, err
+			}
+			actualDepth = field.Depth
+			if n := len(times) * grid.latCount * grid.lngCount; n > maxEOFValues {
+				return nil, fmt.Errorf("%w: too many values (%d time steps x %d cells, max %d), use a lower resolution, smaller bounds or a shorter time range",
+					ErrInvalidAnalysisParams, len(times), grid.latCount*grid.lngCount, maxEOFValues)
+			}
+		}
+		values := make([]float64, 0, grid.latCount*grid.lngCount)
+		for _, row := range grid.regrid(field) {
+			values = append(values, row...)
+		}
+		fields = append(fields, values)
+		fieldTimes = append(fieldTimes, t)
+	}
+	nt := len(fields)
+	if nt < 3 {
+		return nil, fmt.Errorf("%w: %s has %d time steps in the time range, at least 3 are required", ErrInvalidAnalysisParams, v.Name, nt)
+	}
+
+	// 选取有效时次足够的格点，计算距平并加权
+	years := make([]float64, nt)
+	for i, t := range fieldTimes {
+		years[i] = t.Sub(fieldTimes[0]).Seconds() / secondsPerYear
+	}
+	var cells []int
+	var weights []float64
+	var columns [][]float64
+	for cell := 0; cell < grid.latCount*grid.lngCount; cell++ {
+		column := make([]float64, nt)
+		valid := 0
+		for i := range fields {
+			column[i] = fields[i][cell]
+			if !math.IsNaN(column[i]) {
+				valid++
+			}
+		}
+		if valid < 2 || float64(valid) < opts.MinCoverage*float64(nt) {
+			continue
+		}
+		removeMean(column, years, opts.Detrend)
+		w := 1.0
+		if opts.AreaWeighting {
+			lat := grid.minLat + float64(cell/grid.lngCount)*grid.step
+			w = math.Sqrt(math.Max(math.Cos(lat*math.Pi/180), 0))
+		}
+		for i := range column {
+			if math.IsNaN(column[i]) {
+				column[i] = 0 // 缺测的距平按0(时间平均)处理
+			}
+			column[i] *= w
+		}
+		cells = append(cells, cell)
+		weights = append(weights, w)
+		columns = append(columns, column)
+	}
+	ns := len(cells)
+	if ns < 2 {
+		return nil, fmt.Errorf("%w: only %d grid cells have enough valid time steps", ErrInvalidAnalysisParams, ns)
+	}
+	small, large := nt, ns
+	if small > large {
+		small, large = large, small
+	}
+	if float64(small)*float64(small)*float64(large) > maxEOFWork {
+		return nil, fmt.Errorf("%w: decomposition too large (%d time steps x %d cells), use a lower resolution, smaller bounds or a shorter time range",
+			ErrInvalidAnalysisParams, nt, ns)
+	}
+
+	// 奇异值分解: X(时次×格点) = U·diag(σ)·Vᵀ
+	matrix := make([][]float64, nt)
+	for i := range matrix {
+		matrix[i] = make([]float64, ns)
+		for j, column := range columns {
+			matrix[i][j] = column[i]
+		}
+	}
+	u, sigma, vt := linalg.SVD(matrix)
+	total := 0.0
+	for _, x := range sigma {
+		total += x * x
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: %s has no variability in the selected region and time range", ErrInvalidAnalysisParams, v.Name)
+	}
+	modes := opts.Modes
+	if modes > len(sigma) {
+		modes = len(sigma)
+	}
+
+	// 空间模态为主成分标准化(方差为1)时对应的距平场(原变量单位)，符号取使模态的加权和为正
+	data := map[string]interface{}{}
+	dataUnits := map[string]string{}
+	explained := make([]float64, modes)
+	eigenvalues := make([]float64, modes)
+	northErrors := make([]float64, modes)
+	pcs := make([][]float64, modes)
+	scale := math.Sqrt(float64(nt - 1))
+	for k := 0; k < modes; k++ {
+		sign := 1.0
+		sum := 0.0
+		for j := range cells {
+			sum += vt[k][j] * weights[j]
+		}
+		if sum < 0 {
+			sign = -1
+		}
+
+		pattern := make([]float64, grid.latCount*grid.lngCount)
+		for i := range pattern {
+			pattern[i] = math.NaN()
+		}
+		for j, cell := range cells {
+			if weights[j] > 0 {
+				pattern[cell] = sign * vt[k][j] * sigma[k] / scale / weights[j]
+			}
+		}
+		rows := make([][]interface{}, grid.latCount)
+		for i := range rows {
+			rows[i] = nullableSlice(pattern[i*grid.lngCount : (i+1)*grid.lngCount])
+		}
+		name := "eof" + strconv.Itoa(k+1)
+		data[name] = rows
+		dataUnits[name] = units
+
+		pcs[k] = make([]float64, nt)
+		for i := range pcs[k] {
+			pcs[k][i] = sign * u[k][i] * scale
+		}
+		eigenvalues[k] = sigma[k] * sigma[k] / float64(nt-1)
+		explained[k] = sigma[k] * sigma[k] / total
+		northErrors[k] = explained[k] * math.Sqrt(2/float64(nt))
+	}
+
+	series := make([]map[string]interface{}, nt)
+	for i, t := range fieldTimes {
+		point := map[string]interface{}{"timestamp": t.Format(time.RFC3339)}
+		for k := range pcs {
+			point["pc"+strconv.Itoa(k+1)] = pcs[k][i]
+		}
+		series[i] = point
+	}
+
+	result := map[string]interface{}{
+		"variable": key,
+		"timeRange": map[string]interface{}{
+			"start": fieldTimes[0].Format(time.RFC3339),
+			"end":   fieldTimes[nt-1].Format(time.RFC3339),
+		},
+		"samples":           nt,
+		"cells":             ns,
+		"depth":             nullable(actualDepth),
+		"bounds":            grid.bounds(),
+		"resolution":        grid.resolution,
+		"grid":              grid.info(),
+		"options":           opts,
+		"units":             dataUnits,
+		"data":              data,
+		"pcs":               series,
+		"explainedVariance": explained,
+		"northErrors":       northErrors,
+		"eigenvalues":       eigenvalues,
+	}
+	withAnomaly(result, climatology)
+	return result, nil
+}
+
+// removeMean 去除序列的时间平均，detrend时改为去除线性拟合值；NaN保持不变
+func removeMean(values, x []float64, detrend bool) {
+	var n, sx, sy float64
+	for i, y := range values {
+		if !math.IsNaN(y) {
+			n++
+			sx += x[i]
+			sy += y
+		}
+	}
+	mx, my := sx/n, sy/n
+	slope := 0.0
+	if detrend {
+		var sxx, sxy float64
+		for i, y := range values {
+			if !math.IsNaN(y) {
+				sxx += (x[i] - mx) * (x[i] - mx)
+				sxy += (x[i] - mx) * (y - my)
+			}
+		}
+		if sxx > 0 {
+			slope = sxy / sxx
+		}
+	}
+	for i := range values {
+		values[i] -= my + slope*(x[i]-mx)
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/geotiff"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/utils"
+)
+
+// geotiffNoData 导出GeoTIFF时使用的无效值
+const geotiffNoData = -9999
+
+// ErrNotGridResult 分析结果不是规则网格
+var ErrNotGridResult = errors.New("analysis result is not a regular grid")
+
+// ExportGeoTIFF 将规则网格结果(含grid和data字段)导出为GeoTIFF，每个量对应一个波段
+func (s *analysisService) ExportGeoTIFF(result map[string]interface{}, path string) error {
+	raster, err := gridRaster(result)
+	if err != nil {
+		return err
+	}
+	return geotiff.Write(path, raster)
+}
+
+// exportResultGeoTIFF 任务结果为规则网格时额外生成GeoTIFF结果，失败时仅记录日志
+func (s *analysisService) exportResultGeoTIFF(task *models.AnalysisTask, resultDir string, result map[string]interface{}) {
+	raster, err := gridRaster(result)
+	if err != nil {
+		return
+	}
+	raster.Metadata["analysis_type"] = task.Type
+	raster.Metadata["task_id"] = task.ID
+	path := filepath.Join(resultDir, "result.tif")
+	if err := geotiff.Write(path, raster); err != nil {
+		logger.Error("Failed to export GeoTIFF result", "error", err, "taskId", task.ID)
+		return
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	record := &models.AnalysisResult{
+		ID:          utils.GenerateID("result"),
+		TaskID:      task.ID,
+		Title:       task.Name + " GeoTIFF",
+		Description: "GeoTIFF export for " + task.Name,
+		Type:        "map",
+		Format:      "geotiff",
+		FilePath:    path,
+		Size:        size,
+		Metadata:    mustJSON(map[string]interface{}{"bands": raster.Descriptions, "units": raster.Units, "noData": geotiffNoData}),
+	}
+	if _, err := s.CreateResult(record); err != nil {
+		logger.Error("Failed to create result record", "error", err, "taskId", task.ID)
+	}
+}
+
+// gridRaster 将结果中的规则网格转换为栅格: grid给出起点、格距和格点数(格点为像元中心)，
+// data中每个二维数组(按纬度升序)对应一个波段，写出时翻转为北在上
+func gridRaster(result map[string]interface{}) (*geotiff.Raster, error) {
+	grid, ok := result["grid"].(map[string]interface{})
+	if !ok {
+		return nil, ErrNotGridResult
+	}
+	data, ok := result["data"].(map[string]interface{})
+	if !ok || len(data) == 0 {
+		return nil, ErrNotGridResult
+	}
+
+	latCount, ok1 := number(grid["latCount"])
+	lngCount, ok2 := number(grid["lngCount"])
+	latStep, ok3 := number(grid["latStep"])
+	lngStep, ok4 := number(grid["lngStep"])
+	startLat, ok5 := number(grid["startLat"])
+	startLng, ok6 := number(grid["startLng"])
+	if !(ok1 && ok2 && ok3 && ok4 && ok5 && ok6) || latCount < 1 || lngCount < 1 || latStep <= 0 || lngStep <= 0 {
+		return nil, ErrNotGridResult
+	}
+	ny, nx := int(latCount), int(lngCount)
+
+	units := map[string]string{}
+	switch u := result["units"].(type) {
+	case map[string]string:
+		units = u
+	case map[string]interface{}:
+		for k, v := range u {
+			if s, ok := v.(string); ok {
+				units[k] = s
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	raster := &geotiff.Raster{
+		Width:     nx,
+		Height:    ny,
+		Transform: [6]float64{startLng - lngStep/2, lngStep, 0, startLat + (latCount-0.5)*latStep, 0, -latStep},
+		NoData:    geotiffNoData,
+		Metadata:  map[string]string{},
+	}
+	for _, key := range keys {
+		rows, err := gridRows(data[key], ny, nx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		band := make([]float64, ny*nx)
+		for i := 0; i < ny; i++ {
+			copy(band[(ny-1-i)*nx:(ny-i)*nx], rows[i])
+		}
+		raster.Bands = append(raster.Bands, band)
+		raster.Descriptions = append(raster.Descriptions, key)
+		raster.Units = append(raster.Units, units[key])
+	}
+
+	if t, ok := result["time"].(string); ok && t != "" {
+		raster.Metadata["time"] = t
+	}
+	return raster, nil
+}
+
+// gridRows 解析二维数组，null为缺测
+func gridRows(value interface{}, ny, nx int) ([][]float64, error) {
+	var rows [][]float64
+	switch v := value.(type) {
+	case [][]float64:
+		rows = v
+	case [][]interface{}:
+		for _, r := range v {
+			rows = append(rows, nullableRow(r))
+		}
+	case []interface{}:
+		for _, r := range v {
+			cells, ok := r.([]interface{})
+			if !ok {
+				return nil, ErrNotGridResult
+			}
+			rows = append(rows, nullableRow(cells))
+		}
+	default:
+		return nil, ErrNotGridResult
+	}
+	if len(rows) != ny {
+		return nil, fmt.Errorf("%w: expected %d rows, got %d", ErrNotGridResult, ny, len(rows))
+	}
+	for _, r := range rows {
+		if len(r) != nx {
+			return nil, fmt.Errorf("%w: expected %d columns, got %d", ErrNotGridResult, nx, len(r))
+		}
+	}
+	return rows, nil
+}
+
+// nullableRow nullableSlice的逆操作
+func nullableRow(cells []interface{}) []float64 {
+	out := make([]float64, len(cells))
+	for i, c := range cells {
+		if x, ok := number(c); ok {
+			out[i] = x
+		} else {
+			out[i] = math.NaN()
+		}
+	}
+	return out
+}
+
+// number 将数值(含JSON解码结果)转换为float64
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,545 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/mhw"
+	"github.com/sinker/ssop/pkg/resample"
+)
+
+// maxHeatwaveValues 逐格点热浪识别读取的最大元素数(格点数×天数)
+const maxHeatwaveValues = 20000000
+
+// maxHeatwaveStep 热浪识别要求的最大时间间隔，超过时视为非逐日数据
+const maxHeatwaveStep = 36 * time.Hour
+
+// heatwaveCategoryLabels 各强度类别的中文名称，下标为类别
+var heatwaveCategoryLabels = [5]string{"", "中等(I)", "强(II)", "严重(III)", "极端(IV)"}
+
+// 预警规则
+const (
+	heatwaveAlertOngoing = "ongoing" // 只对持续到数据末尾的事件预警
+	heatwaveAlertAll     = "all"     // 对所有事件预警
+	heatwaveAlertNone    = "none"    // 不预警
+)
+
+// heatwaveOptions 海洋热浪识别参数
+type heatwaveOptions struct {
+	mhw.Options
+	Alert         string // 预警规则
+	AlertCategory int    // 预警的最低强度类别
+}
+
+// parseHeatwaveOptions 读取参数percentile(默认90)、windowHalfWidth(默认5)、smoothWidth(默认31)、minDuration(默认5)、
+// maxGap(默认2)、baseline(气候态基准期，默认为数据覆盖的全部年份)、alert(默认ongoing)和alertCategory(默认moderate)
+func parseHeatwaveOptions(params map[string]interface{}) (heatwaveOptions, error) {
+	opts := heatwaveOptions{Options: mhw.DefaultOptions(), Alert: heatwaveAlertOngoing, AlertCategory: mhw.Moderate}
+	percentile, ok, err := paramFloat(params, "percentile")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if percentile <= 0 || percentile >= 100 {
+			return opts, fmt.Errorf("%w: percentile must be between 0 and 100", ErrInvalidAnalysisParams)
+		}
+		opts.Percentile = percentile
+	}
+	for _, p := range []struct {
+		key      string
+		min, max int
+		value    *int
+	}{
+		{"windowHalfWidth", 0, 45, &opts.WindowHalfWidth},
+		{"smoothWidth", 1, 91, &opts.SmoothWidth},
+		{"minDuration", 1, 366, &opts.MinDuration},
+		{"maxGap", 0, 30, &opts.MaxGap},
+	} {
+		x, ok, err := paramFloat(params, p.key)
+		if err != nil {
+			return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+		}
+		if !ok {
+			continue
+		}
+		if x != math.Trunc(x) || x < float64(p.min) || x > float64(p.max) {
+			return opts, fmt.Errorf("%w: %s must be an integer between %d and %d", ErrInvalidAnalysisParams, p.key, p.min, p.max)
+		}
+		*p.value = int(x)
+	}
+	if opts.SmoothWidth%2 == 0 {
+		return opts, fmt.Errorf("%w: smoothWidth must be odd", ErrInvalidAnalysisParams)
+	}
+	if start, end, ok, err := parseBaseline(params); err != nil {
+		return opts, err
+	} else if ok {
+		opts.BaselineStart, opts.BaselineEnd = start, end
+	}
+
+	switch alert := paramString(params, "alert"); alert {
+	case "":
+	case heatwaveAlertOngoing, heatwaveAlertAll, heatwaveAlertNone:
+		opts.Alert = alert
+	default:
+		return opts, fmt.Errorf("%w: alert must be ongoing, all or none", ErrInvalidAnalysisParams)
+	}
+	if s := paramString(params, "alertCategory"); s != "" {
+		if opts.AlertCategory = mhw.ParseCategory(s); opts.AlertCategory == 0 {
+			return opts, fmt.Errorf("%w: alertCategory must be moderate, strong, severe or extreme", ErrInvalidAnalysisParams)
+		}
+	}
+	return opts, nil
+}
+
+// summary 结果中的options字段
+func (o heatwaveOptions) summary() map[string]interface{} {
+	baseline := interface{}(nil)
+	if o.BaselineStart != 0 {
+		baseline = fmt.Sprintf("%d-%d", o.BaselineStart, o.BaselineEnd)
+	}
+	return map[string]interface{}{
+		"percentile":      o.Percentile,
+		"windowHalfWidth": o.WindowHalfWidth,
+		"smoothWidth":     o.SmoothWidth,
+		"minDuration":     o.MinDuration,
+		"maxGap":          o.MaxGap,
+		"baseline":        baseline,
+		"alert":           o.Alert,
+		"alertCategory":   mhw.CategoryName(o.AlertCategory),
+	}
+}
+
+// alerted 事件是否满足预警规则
+func (o heatwaveOptions) alerted(e mhw.Event) bool {
+	switch o.Alert {
+	case heatwaveAlertAll:
+		return e.Category >= o.AlertCategory
+	case heatwaveAlertOngoing:
+		return e.Ongoing && e.Category >= o.AlertCategory
+	}
+	return false
+}
+
+// readRange 读取数据的时间范围: 检测时段[startDate, endDate]与基准期的并集，零值为不限制
+func (o heatwaveOptions) readRange(startDate, endDate time.Time) (time.Time, time.Time) {
+	if o.BaselineStart == 0 {
+		return startDate, endDate
+	}
+	if first := time.Date(o.BaselineStart, 1, 1, 0, 0, 0, 0, time.UTC); !startDate.IsZero() && first.Before(startDate) {
+		startDate = first
+	}
+	if last := time.Date(o.BaselineEnd, 12, 31, 23, 59, 59, 0, time.UTC); !endDate.IsZero() && last.After(endDate) {
+		endDate = last
+	}
+	return startDate, endDate
+}
+
+// heatwaveAlert 热浪预警: 满足预警规则的事件(单点)或格点(空间分布)
+type heatwaveAlert struct {
+	Rule         string                   `json:"rule"`
+	Category     string                   `json:"category"`           // 预警的最低强度类别
+	Units        string                   `json:"units"`              // 强度的单位
+	Count        int                      `json:"count"`              // 满足规则的事件数(单点)或格点数(空间分布)
+	MaxCategory  string                   `json:"maxCategory"`        // 其中的最高强度类别
+	MaxIntensity interface{}              `json:"maxIntensity"`       // 其中的最大强度
+	Location     map[string]interface{}   `json:"location,omitempty"` // 最大强度所在位置
+	Events       []map[string]interface{} `json:"events,omitempty"`   // 单点时满足规则的事件
+}
+
+// add 计入一个满足规则的事件
+func (a *heatwaveAlert) add(e mhw.Event, lat, lng float64) bool {
+	a.Count++
+	if a.MaxCategory == "" || e.Category > mhw.ParseCategory(a.MaxCategory) {
+		a.MaxCategory = mhw.CategoryName(e.Category)
+	}
+	if max, ok := a.MaxIntensity.(float64); ok && max >= e.IntensityMax {
+		return false
+	}
+	a.MaxIntensity = e.IntensityMax
+	a.Location = map[string]interface{}{"lat": nullable(lat), "lng": nullable(lng)}
+	return true
+}
+
+// heatwaveEvent 结果中的一次事件
+func heatwaveEvent(e mhw.Event) map[string]interface{} {
+	return map[string]interface{}{
+		"start":               e.Start.Format("2006-01-02"),
+		"end":                 e.End.Format("2006-01-02"),
+		"peak":                e.Peak.Format("2006-01-02"),
+		"duration":            e.Duration,
+		"intensityMax":        e.IntensityMax,
+		"intensityMean":       e.IntensityMean,
+		"intensityCumulative": e.IntensityCumulative,
+		"category":            mhw.CategoryName(e.Category),
+		"ongoing":             e.Ongoing,
+	}
+}
+
+// checkDaily 检查数据是否为逐日或更高频率
+func checkDaily(name string, times []time.Time) error {
+	if step := resample.TypicalStep(times); step > maxHeatwaveStep {
+		return fmt.Errorf("%w: %s has a time step of %s, marine heatwave detection requires daily data", ErrInvalidAnalysisParams, name, step)
+	}
+	return nil
+}
+
+// executeMarineHeatwave 按 Hobday 等(2016)的定义识别海洋热浪: 参数lat、lng同时指定时分析单点序列，
+// 返回各次事件和逐年统计；否则对bounds、resolution指定的规则网格逐格点识别，返回逐年统计的空间分布
+func (s *analysisService) executeMarineHeatwave(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseHeatwaveOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, key, units, err := s.singleVariable(src, params)
+	if err != nil {
+		return nil, err
+	}
+	v := variables[key]
+
+	_, hasLat, err := paramFloat(params, "lat")
+	if err != nil {
+		return nil, err
+	}
+	_, hasLng, err := paramFloat(params, "lng")
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if hasLat && hasLng {
+		result, err = heatwavePoint(src, v, units, opts, startDate, endDate, params)
+	} else {
+		result, err = heatwaveSpatial(src, v, units, opts, startDate, endDate, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	result["variable"] = key
+	result["options"] = opts.summary()
+	return result, nil
+}
+
+// heatwavePoint 单点序列的热浪事件、逐年统计及逐日的气候态和阈值
+func heatwavePoint(src *analysisSource, v *dataio.Variable, units string, opts heatwaveOptions, startDate, endDate time.Time, params map[string]interface{}) (map[string]interface{}, error) {
+	lat, _, _ := paramFloat(params, "lat")
+	lng, _, _ := paramFloat(params, "lng")
+	var depthParam *float64
+	if depth, ok, err := paramFloat(params, "depth"); err != nil {
+		return nil, err
+	} else if ok {
+		depthParam = &depth
+	}
+
+	from, to := opts.readRange(startDate, endDate)
+	ps, err := extractPointSeries(src.Source, v, lat, lng, depthParam, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+	}
+	if err := checkDaily(v.Name, ps.Times); err != nil {
+		return nil, err
+	}
+	daily := mhw.Daily(ps.Times, ps.Values)
+	climatology := mhw.ComputeClimatology(daily, opts.Options)
+	if math.IsNaN(climatology.Threshold[0]) {
+		return nil, fmt.Errorf("%w: %s has no data in the baseline period", ErrInvalidAnalysisParams, v.Name)
+	}
+
+	detection := daily.Slice(startDate, endDate)
+	events := mhw.Detect(detection, climatology, opts.Options)
+	alert := &heatwaveAlert{Rule: opts.Alert, Category: mhw.CategoryName(opts.AlertCategory), Units: units}
+	list := make([]map[string]interface{}, len(events))
+	for i, e := range events {
+		list[i] = heatwaveEvent(e)
+		if opts.alerted(e) {
+			alert.add(e, ps.Lat, ps.Lng)
+			alert.Events = append(alert.Events, list[i])
+		}
+	}
+
+	annual := mhw.Annual(detection, events)
+	years := make([]map[string]interface{}, len(annual))
+	for i, y := range annual {
+		years[i] = map[string]interface{}{
+			"year":                y.Year,
+			"validDays":           y.ValidDays,
+			"count":               y.Count,
+			"days":                y.Days,
+			"intensityMax":        nullable(y.IntensityMax),
+			"intensityMean":       nullable(y.IntensityMean),
+			"intensityCumulative": y.IntensityCumulative,
+			"category":            nil,
+		}
+		if y.Category > 0 {
+			years[i]["category"] = mhw.CategoryName(y.Category)
+		}
+	}
+
+	series := make([]map[string]interface{}, len(detection.Values))
+	for i, x := range detection.Values {
+		mean, threshold := climatology.At(detection.Day(i))
+		series[i] = map[string]interface{}{
+			"date":        detection.Day(i).Format("2006-01-02"),
+			"value":       nullable(x),
+			"climatology": nullable(mean),
+			"threshold":   nullable(threshold),
+		}
+	}
+
+	return map[string]interface{}{
+		"location":  map[string]interface{}{"lat": nullable(ps.Lat), "lng": nullable(ps.Lng), "depth": nullable(ps.Depth)},
+		"timeRange": detectionRange(detection),
+		"units":     units,
+		"events":    list,
+		"annual":    years,
+		"series":    series,
+		"alerts":    alert,
+	}, nil
+}
+
+// detectionRange 检测时段的起止日期
+func detectionRange(s mhw.Series) map[string]interface{} {
+	if len(s.Values) == 0 {
+		return map[string]interface{}{"start": nil, "end": nil}
+	}
+	return map[string]interface{}{
+		"start": s.Start.Format("2006-01-02"),
+		"end":   s.Day(len(s.Values) - 1).Format("2006-01-02"),
+	}
+}
+
+// heatwaveMetrics 空间分布的逐年统计量及其单位，units为海温的单位
+func heatwaveMetrics(units string) map[string]string {
+	return map[string]string{
+		"count":               "1",
+		"days":                "day",
+		"intensityMax":        units,
+		"intensityCumulative": units + " day",
+		"category":            "1",
+	}
+}
+
+// heatwaveSpatial 逐格点识别热浪，结果为规则网格上各年的事件次数、热浪天数、最大强度、累积强度和最高类别
+func heatwaveSpatial(src *analysisSource, v *dataio.Variable, units string, opts heatwaveOptions, startDate, endDate time.Time, params map[string]interface{}) (map[string]interface{}, error) {
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+	from, to := opts.readRange(startDate, endDate)
+	_, _, _, times, err := src.TimeRange(v, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+	}
+	if err := checkDaily(v.Name, times); err != nil {
+		return nil, err
+	}
+	var sorted []time.Time
+	for _, t := range times {
+		if !t.IsZero() {
+			sorted = append(sorted, t.UTC())
+		}
+	}
+	if len(sorted) == 0 {
+		return nil, fmt.Errorf("%w: %s has no time steps in the time range", ErrInvalidAnalysisParams, v.Name)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	first := sorted[0].Truncate(24 * time.Hour)
+	days := int(sorted[len(sorted)-1].Truncate(24*time.Hour).Sub(first)/(24*time.Hour)) + 1
+
+	// 逐时次读取水平场并插值到规则网格，同一天的各时次求平均
+	var grid *regularGrid
+	var actualDepth float64
+	var values []float32 // 按天、格点排列
+	var sum []float64
+	var count []int32
+	cells := 0
+	current := -1
+	flush := func() {
+		for cell := range sum {
+			if count[cell] > 0 {
+				values[current*cells+cell] = float32(sum[cell] / float64(count[cell]))
+			}
+			sum[cell], count[cell] = 0, 0
+		}
+	}
+	for _, t := range sorted {
+		field, err := extractHorizontalField(src.Source, v, t, depth)
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+		}
+		if grid == nil {
+			if grid, err = newRegularGrid(params, field); err != nil {
+				return nil, err
+			}
+			actualDepth = field.Depth
+			cells = grid.latCount * grid.lngCount
+			if cells*days > maxHeatwaveValues {
+				return nil, fmt.Errorf("%w: too many values (%d cells x %d days, max %d), use a lower resolution, smaller bounds or a shorter time range",
+					ErrInvalidAnalysisParams, cells, days, maxHeatwaveValues)
+			}
+			values = make([]float32, cells*days)
+			for i := range values {
+				values[i] = float32(math.NaN())
+			}
+			sum, count = make([]float64, cells), make([]int32, cells)
+		}
+		if d := int(t.Truncate(24*time.Hour).Sub(first) / (24 * time.Hour)); d != current {
+			if current >= 0 {
+				flush()
+			}
+			current = d
+		}
+		for i, row := range grid.regrid(field) {
+			for j, x := range row {
+				if !math.IsNaN(x) {
+					sum[i*grid.lngCount+j] += x
+					count[i*grid.lngCount+j]++
+				}
+			}
+		}
+	}
+	flush()
+
+	// 逐格点识别热浪并按年统计
+	daily := mhw.Series{Start: first, Values: make([]float64, days)}
+	detection := daily.Slice(startDate, endDate)
+	if len(detection.Values) == 0 {
+		return nil, fmt.Errorf("%w: %s has no data in the time range", ErrInvalidAnalysisParams, v.Name)
+	}
+	firstYear := detection.Start.Year()
+	yearCount := detection.Day(len(detection.Values)-1).Year() - firstYear + 1
+	metrics := heatwaveMetrics(units)
+	maps := map[string][][][]float64{}
+	for name := range metrics {
+		maps[name] = make([][][]float64, yearCount)
+		for y := range maps[name] {
+			maps[name][y] = make([][]float64, grid.latCount)
+			for i := range maps[name][y] {
+				maps[name][y][i] = make([]float64, grid.lngCount)
+				for j := range maps[name][y][i] {
+					maps[name][y][i][j] = math.NaN()
+				}
+			}
+		}
+	}
+	alert := &heatwaveAlert{Rule: opts.Alert, Category: mhw.CategoryName(opts.AlertCategory), Units: units}
+	events := 0
+	for cell := 0; cell < cells; cell++ {
+		valid := false
+		for d := range daily.Values {
+			daily.Values[d] = float64(values[d*cells+cell])
+			valid = valid || !math.IsNaN(daily.Values[d])
+		}
+		if !valid {
+			continue
+		}
+		climatology := mhw.ComputeClimatology(daily, opts.Options)
+		if math.IsNaN(climatology.Threshold[0]) {
+			continue
+		}
+		i, j := cell/grid.lngCount, cell%grid.lngCount
+		cellEvents := mhw.Detect(daily.Slice(startDate, endDate), climatology, opts.Options)
+		events += len(cellEvents)
+		for _, y := range mhw.Annual(daily.Slice(startDate, endDate), cellEvents) {
+			if y.ValidDays == 0 {
+				continue
+			}
+			k := y.Year - firstYear
+			maps["count"][k][i][j] = float64(y.Count)
+			maps["days"][k][i][j] = float64(y.Days)
+			maps["intensityMax"][k][i][j] = y.IntensityMax
+			maps["intensityCumulative"][k][i][j] = y.IntensityCumulative
+			maps["category"][k][i][j] = float64(y.Category)
+		}
+
+		// 每个格点按其中强度最大的满足规则的事件计入预警
+		strongest := -1
+		for k, e := range cellEvents {
+			if opts.alerted(e) && (strongest < 0 || e.IntensityMax > cellEvents[strongest].IntensityMax) {
+				strongest = k
+			}
+		}
+		if strongest >= 0 {
+			alert.add(cellEvents[strongest], grid.minLat+float64(i)*grid.step, grid.minLng+float64(j)*grid.step)
+		}
+	}
+
+	data := map[string]interface{}{}
+	dataUnits := map[string]string{}
+	years := make([]int, yearCount)
+	for y := range years {
+		years[y] = firstYear + y
+		for name, u := range metrics {
+			rows := make([][]interface{}, grid.latCount)
+			for i, row := range maps[name][y] {
+				rows[i] = nullableSlice(row)
+			}
+			key := name + "_" + strconv.Itoa(years[y])
+			data[key] = rows
+			dataUnits[key] = u
+		}
+	}
+
+	return map[string]interface{}{
+		"timeRange":  detectionRange(detection),
+		"days":       len(detection.Values),
+		"years":      years,
+		"eventCount": events,
+		"depth":      nullable(actualDepth),
+		"bounds":     grid.bounds(),
+		"resolution": grid.resolution,
+		"grid":       grid.info(),
+		"units":      dataUnits,
+		"data":       data,
+		"alerts":     alert,
+	}, nil
+}
+
+// notifyHeatwave 海洋热浪任务的结果中有满足预警规则的事件时通知任务创建者，失败时仅记录日志
+func (s *analysisService) notifyHeatwave(task *models.AnalysisTask, params map[string]interface{}, result map[string]interface{}) {
+	alert, ok := result["alerts"].(*heatwaveAlert)
+	if !ok || alert.Count == 0 || s.notifications == nil {
+		return
+	}
+	ongoing := ""
+	if alert.Rule == heatwaveAlertOngoing {
+		ongoing = "正在持续的"
+	}
+	label := heatwaveCategoryLabels[mhw.ParseCategory(alert.MaxCategory)]
+	var content string
+	if alert.Events != nil {
+		content = fmt.Sprintf("分析任务「%s」在 (%v, %v) 检测到 %d 次%s海洋热浪事件，最高等级为%s，最大强度 %.2f %s",
+			task.Name, alert.Location["lat"], alert.Location["lng"], alert.Count, ongoing, label, alert.MaxIntensity, alert.Units)
+	} else {
+		content = fmt.Sprintf("分析任务「%s」的区域内有 %d 个格点出现%s海洋热浪事件，最高等级为%s，最大强度 %.2f %s，位于 (%v, %v)",
+			task.Name, alert.Count, ongoing, label, alert.MaxIntensity, alert.Units, alert.Location["lat"], alert.Location["lng"])
+	}
+
+	data := map[string]interface{}{
+		"taskId":    task.ID,
+		"datasetId": paramString(params, "datasetId"),
+		"alerts":    alert,
+	}
+	if err := s.notifications.Notify(task.CreatedBy, models.NotificationMarineHeatwave, "海洋热浪预警", content, data); err != nil {
+		logger.Error("Failed to send marine heatwave notification", "error", err, "taskId", task.ID)
+	}
+}
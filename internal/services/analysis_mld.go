@@ -0,0 +1,435 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/sinker/ssop/pkg/argo"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/gsw"
+)
+
+// mixedLayerCriteria 混合层和温跃层的判据
+type mixedLayerCriteria struct {
+	RefDepth          float64 `json:"refDepth"`          // 参考深度(m)
+	TempThreshold     float64 `json:"tempThreshold"`     // 温度判据: 与参考深度处温度之差(℃)
+	DensityThreshold  float64 `json:"densityThreshold"`  // 密度判据: 相对参考深度处位密的增量(kg/m3)
+	GradientThreshold float64 `json:"gradientThreshold"` // 温跃层判据: 温度随深度递减的最小梯度(℃/m)
+}
+
+// defaultMixedLayerCriteria 默认判据(de Boyer Montégut等, 2004)
+var defaultMixedLayerCriteria = mixedLayerCriteria{
+	RefDepth:          10,
+	TempThreshold:     0.2,
+	DensityThreshold:  0.03,
+	GradientThreshold: 0.05,
+}
+
+// mixedLayerMetric 混合层分析输出的指标，density为true时需要盐度
+type mixedLayerMetric struct {
+	key     string
+	units   string
+	density bool
+}
+
+// mixedLayerMetrics 各条剖面计算的指标
+var mixedLayerMetrics = []mixedLayerMetric{
+	{"mldTemperature", "m", false},
+	{"mldDensity", "m", true},
+	{"thermoclineDepth", "m", false},
+	{"thermoclineTop", "m", false},
+	{"thermoclineBottom", "m", false},
+	{"thermoclineStrength", "degC m-1", false},
+	{"maxN2", "s-2", true},
+	{"maxN2Depth", "m", true},
+}
+
+// parseMixedLayerCriteria 读取参数refDepth、tempThreshold、densityThreshold、gradientThreshold，未指定时使用默认值
+func parseMixedLayerCriteria(params map[string]interface{}) (mixedLayerCriteria, error) {
+	c := defaultMixedLayerCriteria
+	fields := []struct {
+		key   string
+		value *float64
+		zero  bool // 是否允许为0
+	}{
+		{"refDepth", &c.RefDepth, true},
+		{"tempThreshold", &c.TempThreshold, false},
+		{"densityThreshold", &c.DensityThreshold, false},
+		{"gradientThreshold", &c.GradientThreshold, false},
+	}
+	for _, f := range fields {
+		x, ok, err := paramFloat(params, f.key)
+		if err != nil {
+			return c, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+		}
+		if !ok {
+			continue
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) || x < 0 || (x == 0 && !f.zero) {
+			return c, fmt.Errorf("%w: %s must be positive", ErrInvalidAnalysisParams, f.key)
+		}
+		*f.value = x
+	}
+	return c, nil
+}
+
+// mixedLayerReader 按剖面计算混合层指标: 读取范围沿垂向维展开为整条剖面，
+// 同一读取范围的各指标只计算一次
+type mixedLayerReader struct {
+	src      *dataio.Source
+	temp     *dataio.Variable // 温度(℃)
+	ts       *derivedInputs   // 温度和盐度，缺少盐度时为nil
+	z, lat   *dataio.Variable
+	zdim     int
+	pressure bool
+	criteria mixedLayerCriteria
+
+	key   string
+	cache map[string][]float64
+}
+
+// mixedLayerVariables 为各指标创建去掉垂向维的虚拟变量，经纬度和时间坐标与温度相同；
+// 缺少盐度时不含密度相关指标
+func (s *analysisService) mixedLayerVariables(src *analysisSource, criteria mixedLayerCriteria) (map[string]*dataio.Variable, map[string]string, map[string]string, error) {
+	resolver := s.standardNames.Resolver()
+	r := &mixedLayerReader{src: src.Source, criteria: criteria, ts: newDerivedInputs(src.Source, resolver)}
+	names := map[string]string{}
+	if r.ts != nil {
+		r.temp = r.ts.temp
+		names["salinity"] = r.ts.salt.Name
+	} else {
+		v, err := resolver.Resolve(src.Source, "sea_water_temperature", "degC")
+		if err != nil {
+			return nil, nil, nil, errors.New("dataset has no temperature variable")
+		}
+		r.temp = v
+	}
+	names["temperature"] = r.temp.Name
+
+	r.zdim = profileDim(src.Source, r.temp)
+	if r.zdim < 0 {
+		return nil, nil, nil, fmt.Errorf("temperature variable %s has no vertical dimension", r.temp.Name)
+	}
+	r.z = src.Coordinate(r.temp, dataio.AxisZ)
+	r.lat = src.Coordinate(r.temp, dataio.AxisLat)
+	r.pressure = isPressureAxis(r.z)
+
+	var dims []string
+	var shape []int
+	for d, name := range r.temp.Dims {
+		if d != r.zdim {
+			dims = append(dims, name)
+			shape = append(shape, r.temp.Shape[d])
+		}
+	}
+	variables := map[string]*dataio.Variable{}
+	units := map[string]string{}
+	for _, m := range mixedLayerMetrics {
+		if m.density && r.ts == nil {
+			continue
+		}
+		key := m.key
+		read := func(start, count []int) ([]float64, error) {
+			values, err := r.columns(start, count)
+			if err != nil {
+				return nil, err
+			}
+			return values[key], nil
+		}
+		variables[key] = dataio.NewVariable(key, dims, shape, "double", dataio.Attributes{"units": m.units}, read)
+		units[key] = m.units
+	}
+	return variables, units, names, nil
+}
+
+// columns 计算读取范围(不含垂向维)内每条剖面的各指标
+func (r *mixedLayerReader) columns(start, count []int) (map[string][]float64, error) {
+	key := fmt.Sprint(start, count)
+	if r.cache != nil && key == r.key {
+		return r.cache, nil
+	}
+
+	// 在垂向维位置插入整条剖面的读取范围
+	fs := make([]int, 0, len(start)+1)
+	fc := make([]int, 0, len(count)+1)
+	fs = append(append(append(fs, start[:r.zdim]...), 0), start[r.zdim:]...)
+	fc = append(append(append(fc, count[:r.zdim]...), r.temp.Shape[r.zdim]), count[r.zdim:]...)
+
+	temp, err := r.temp.Read(fs, fc)
+	if err != nil {
+		return nil, err
+	}
+	z, err := readCoordinate(r.z, r.temp, fs, fc)
+	if err != nil {
+		return nil, err
+	}
+	lat := make([]float64, len(temp))
+	if r.lat != nil {
+		if lat, err = readCoordinate(r.lat, r.temp, fs, fc); err != nil {
+			return nil, err
+		}
+	}
+	var sa, ct, p []float64
+	if r.ts != nil {
+		if sa, ct, p, _, err = r.ts.read(fs, fc); err != nil {
+			return nil, err
+		}
+	}
+
+	outer, inner := 1, 1
+	for d := 0; d < r.zdim; d++ {
+		outer *= fc[d]
+	}
+	for d := r.zdim + 1; d < len(fc); d++ {
+		inner *= fc[d]
+	}
+	nz := fc[r.zdim]
+	out := map[string][]float64{}
+	for _, m := range mixedLayerMetrics {
+		out[m.key] = make([]float64, outer*inner)
+	}
+
+	col := &profileColumn{}
+	for o := 0; o < outer; o++ {
+		for k := 0; k < inner; k++ {
+			col.reset()
+			for iz := 0; iz < nz; iz++ {
+				i := (o*nz+iz)*inner + k
+				d := math.Abs(z[i])
+				if math.IsNaN(d) || math.IsNaN(temp[i]) {
+					continue
+				}
+				if r.pressure {
+					reflat := lat[i]
+					if math.IsNaN(reflat) {
+						reflat = 0
+					}
+					d = argo.PressureToDepth(d, reflat)
+				}
+				col.add(d, temp[i], sa, ct, p, lat, i)
+			}
+			metrics := col.metrics(r.criteria)
+			for key, x := range metrics {
+				out[key][o*inner+k] = x
+			}
+		}
+	}
+	r.key, r.cache = key, out
+	return out, nil
+}
+
+// profileColumn 一条剖面的有效层，按深度递增排列后计算指标
+type profileColumn struct {
+	depth, temp    []float64
+	sa, ct, p, lat []float64 // 缺少盐度时为NaN
+}
+
+// reset 清空剖面，复用已分配的数组
+func (c *profileColumn) reset() {
+	c.depth, c.temp = c.depth[:0], c.temp[:0]
+	c.sa, c.ct, c.p, c.lat = c.sa[:0], c.ct[:0], c.p[:0], c.lat[:0]
+}
+
+// add 加入一层，sa为nil时表示没有盐度
+func (c *profileColumn) add(depth, temp float64, sa, ct, p, lat []float64, i int) {
+	c.depth = append(c.depth, depth)
+	c.temp = append(c.temp, temp)
+	if sa == nil {
+		c.sa, c.ct, c.p, c.lat = append(c.sa, math.NaN()), append(c.ct, math.NaN()), append(c.p, math.NaN()), append(c.lat, math.NaN())
+		return
+	}
+	c.sa, c.ct, c.p, c.lat = append(c.sa, sa[i]), append(c.ct, ct[i]), append(c.p, p[i]), append(c.lat, lat[i])
+}
+
+// Len、Less、Swap 实现sort.Interface，按深度排序各层
+func (c *profileColumn) Len() int           { return len(c.depth) }
+func (c *profileColumn) Less(i, j int) bool { return c.depth[i] < c.depth[j] }
+func (c *profileColumn) Swap(i, j int) {
+	for _, a := range [][]float64{c.depth, c.temp, c.sa, c.ct, c.p, c.lat} {
+		a[i], a[j] = a[j], a[i]
+	}
+}
+
+// metrics 计算剖面的混合层深度、温跃层和最大层结，无法确定的指标为NaN
+func (c *profileColumn) metrics(criteria mixedLayerCriteria) map[string]float64 {
+	out := map[string]float64{}
+	for _, m := range mixedLayerMetrics {
+		out[m.key] = math.NaN()
+	}
+	if len(c.depth) == 0 {
+		return out
+	}
+	if !sort.IsSorted(c) {
+		sort.Stable(c)
+	}
+
+	out["mldTemperature"] = thresholdDepth(c.depth, c.temp, criteria.RefDepth, criteria.TempThreshold, true)
+
+	// 温跃层: 相邻层温度梯度最大处，向上下扩展到梯度低于判据的层
+	grad := make([]float64, len(c.depth)-1)
+	best := -1
+	for k := range grad {
+		grad[k] = math.NaN()
+		if dz := c.depth[k+1] - c.depth[k]; dz > 0 {
+			grad[k] = -(c.temp[k+1] - c.temp[k]) / dz
+			if best < 0 || grad[k] > grad[best] {
+				best = k
+			}
+		}
+	}
+	if best >= 0 && grad[best] >= criteria.GradientThreshold {
+		lo, hi := best, best
+		for lo > 0 && grad[lo-1] >= criteria.GradientThreshold {
+			lo--
+		}
+		for hi < len(grad)-1 && grad[hi+1] >= criteria.GradientThreshold {
+			hi++
+		}
+		out["thermoclineDepth"] = 0.5 * (c.depth[best] + c.depth[best+1])
+		out["thermoclineTop"] = c.depth[lo]
+		out["thermoclineBottom"] = c.depth[hi+1]
+		out["thermoclineStrength"] = grad[best]
+	}
+
+	// 有盐度的层: 位密判据的混合层深度和最大浮力频率
+	var depth, sigma, sa, ct, p []float64
+	lat := math.NaN()
+	for k := range c.depth {
+		if math.IsNaN(c.sa[k]) || math.IsNaN(c.ct[k]) {
+			continue
+		}
+		depth = append(depth, c.depth[k])
+		sigma = append(sigma, gsw.Sigma0(c.sa[k], c.ct[k]))
+		sa, ct, p = append(sa, c.sa[k]), append(ct, c.ct[k]), append(p, c.p[k])
+		if math.IsNaN(lat) {
+			lat = c.lat[k]
+		}
+	}
+	if len(depth) == 0 {
+		return out
+	}
+	out["mldDensity"] = thresholdDepth(depth, sigma, criteria.RefDepth, criteria.DensityThreshold, false)
+	if math.IsNaN(lat) {
+		lat = 0
+	}
+	n2, _ := gsw.Nsquared(sa, ct, p, lat)
+	for k, x := range n2 {
+		if !math.IsNaN(x) && (math.IsNaN(out["maxN2"]) || x > out["maxN2"]) {
+			out["maxN2"], out["maxN2Depth"] = x, 0.5*(depth[k]+depth[k+1])
+		}
+	}
+	return out
+}
+
+// thresholdDepth 值相对参考深度处的值的变化首次超过delta的深度(相邻层间线性插值)：
+// abs为true时按变化的绝对值判断，否则按增量判断；参考深度处的值由相邻层插值，
+// 最浅层深于参考深度时以最浅层为参考；整条剖面都不超过判据时取最深层深度，剖面浅于参考深度时为NaN
+func thresholdDepth(depth, value []float64, refDepth, delta float64, abs bool) float64 {
+	n := len(depth)
+	k := 0
+	for k < n && depth[k] <= refDepth {
+		k++
+	}
+	var zr, vr float64
+	switch {
+	case k == n:
+		return math.NaN()
+	case k == 0:
+		zr, vr, k = depth[0], value[0], 1
+	default:
+		w := (refDepth - depth[k-1]) / (depth[k] - depth[k-1])
+		zr, vr = refDepth, value[k-1]+w*(value[k]-value[k-1])
+	}
+
+	change := func(x float64) float64 {
+		if abs {
+			return math.Abs(x - vr)
+		}
+		return x - vr
+	}
+	zp, dp := zr, 0.0
+	for i := k; i < n; i++ {
+		d := change(value[i])
+		if d > delta {
+			return zp + (delta-dp)/(d-dp)*(depth[i]-zp)
+		}
+		zp, dp = depth[i], d
+	}
+	return depth[n-1]
+}
+
+// executeMixedLayerTimeSeries 参数lat、lng指定位置的混合层深度、温跃层和最大层结时间序列
+func (s *analysisService) executeMixedLayerTimeSeries(params map[string]interface{}) (map[string]interface{}, error) {
+	criteria, err := parseMixedLayerCriteria(params)
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, units, names, err := s.mixedLayerVariables(src, criteria)
+	if err != nil {
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := pointTimeSeries(src, variables, units, params)
+	if err != nil {
+		return nil, err
+	}
+	withAnomaly(result, climatology)
+	if location, ok := result["location"].(map[string]interface{}); ok {
+		delete(location, "depth")
+	}
+	result["variables"] = names
+	result["criteria"] = criteria
+	return result, nil
+}
+
+// executeMixedLayerSpatial 参数date指定时间的混合层深度、温跃层和最大层结空间分布
+func (s *analysisService) executeMixedLayerSpatial(params map[string]interface{}) (map[string]interface{}, error) {
+	criteria, err := parseMixedLayerCriteria(params)
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, units, names, err := s.mixedLayerVariables(src, criteria)
+	if err != nil {
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := spatialGrid(src, variables, units, params)
+	if err != nil {
+		return nil, err
+	}
+	withAnomaly(result, climatology)
+	delete(result, "depth")
+	result["variables"] = names
+	result["criteria"] = criteria
+	return result, nil
+}
+
+// GetMixedLayerTimeSeries 获取指定位置的混合层深度、温跃层和最大层结时间序列
+func (s *analysisService) GetMixedLayerTimeSeries(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeMixedLayerTimeSeries(params)
+}
+
+// GetMixedLayerSpatial 获取混合层深度、温跃层和最大层结的空间分布
+func (s *analysisService) GetMixedLayerSpatial(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeMixedLayerSpatial(params)
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/particle"
+)
+
+// maxParticles 粒子追踪的最大粒子数
+const maxParticles = 10000
+
+// maxParticleOutputPoints 轨迹输出的最大点数(粒子数×输出时次数)
+const maxParticleOutputPoints = 2000000
+
+// maxParticleFieldValues 粒子追踪读取的流场最大元素数(快照数×格点数)
+const maxParticleFieldValues = 50000000
+
+// maxParticleSteps 最大积分步数
+const maxParticleSteps = 100000
+
+// releaseMetersPerDegree 纬度每度对应的距离(m)，用于点释放的半径换算
+const releaseMetersPerDegree = 6371000.0 * math.Pi / 180
+
+// particleRelease 粒子释放源: 点(lat、lng或Point几何，可按radius在圆内随机分布)或多边形(Polygon、MultiPolygon几何，在多边形内随机分布)
+type particleRelease struct {
+	Name     string           `json:"name"`
+	Lat      *float64         `json:"lat"`
+	Lng      *float64         `json:"lng"`
+	Geometry *GeoJSONGeometry `json:"geometry"`
+	Count    int              `json:"count"`  // 粒子数，点默认1、多边形默认100
+	Radius   float64          `json:"radius"` // 点释放的分布半径(m)
+	Time     string           `json:"time"`   // 释放时间，默认为追踪开始时间
+}
+
+// parseParticleReleases 解析参数releases(JSON数组)
+func parseParticleReleases(params map[string]interface{}) ([]particleRelease, error) {
+	var raw []byte
+	switch v := params["releases"].(type) {
+	case nil:
+	case string:
+		if v != "" {
+			raw = []byte(v)
+		}
+	default:
+		raw, _ = json.Marshal(v)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: missing required parameter: releases", ErrInvalidAnalysisParams)
+	}
+	var releases []particleRelease
+	if err := json.Unmarshal(raw, &releases); err != nil {
+		return nil, fmt.Errorf("%w: invalid releases: %v", ErrInvalidAnalysisParams, err)
+	}
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("%w: releases is empty", ErrInvalidAnalysisParams)
+	}
+	return releases, nil
+}
+
+// seed 按释放源生成粒子，time为释放时间
+func (r *particleRelease) seed(index int, t time.Time, rng *rand.Rand) ([]particle.Particle, error) {
+	var rings [][][2]float64
+	if r.Geometry != nil {
+		var err error
+		if rings, err = geometryRings(r.Geometry); err != nil {
+			return nil, fmt.Errorf("%w: release %d: invalid geometry", ErrInvalidAnalysisParams, index)
+		}
+		if r.Geometry.Type == "Point" {
+			lng, lat := rings[0][0][0], rings[0][0][1]
+			r.Lat, r.Lng, rings = &lat, &lng, nil
+		}
+	} else if r.Lat == nil || r.Lng == nil {
+		return nil, fmt.Errorf("%w: release %d: lat and lng or geometry are required", ErrInvalidAnalysisParams, index)
+	}
+	count := r.Count
+	if count == 0 {
+		count = 1
+		if rings != nil {
+			count = 100
+		}
+	}
+	if count < 0 || count > maxParticles || r.Radius < 0 {
+		return nil, fmt.Errorf("%w: release %d: count must be between 1 and %d and radius must not be negative", ErrInvalidAnalysisParams, index, maxParticles)
+	}
+
+	particles := make([]particle.Particle, 0, count)
+	if rings == nil {
+		for k := 0; k < count; k++ {
+			lat, lng := *r.Lat, *r.Lng
+			if r.Radius > 0 {
+				// 圆内均匀分布
+				d := r.Radius * math.Sqrt(rng.Float64()) / releaseMetersPerDegree
+				theta := 2 * math.Pi * rng.Float64()
+				lat += d * math.Cos(theta)
+				lng += d * math.Sin(theta) / math.Cos(lat*math.Pi/180)
+			}
+			particles = append(particles, particle.Particle{Release: index, Lat: lat, Lng: lng, Time: t})
+		}
+		return particles, nil
+	}
+
+	// 在多边形的外包矩形内随机取点，保留落在多边形内的点
+	bbox := ringsBBox(rings)
+	for tries := 0; len(particles) < count; tries++ {
+		if tries > count*1000 {
+			return nil, fmt.Errorf("%w: release %d: polygon is too small to place particles", ErrInvalidAnalysisParams, index)
+		}
+		p := [2]float64{bbox[0] + rng.Float64()*(bbox[2]-bbox[0]), bbox[1] + rng.Float64()*(bbox[3]-bbox[1])}
+		for _, ring := range rings {
+			if pointInRing(p, ring) {
+				particles = append(particles, particle.Particle{Release: index, Lat: p[1], Lng: p[0], Time: t})
+				break
+			}
+		}
+	}
+	return particles, nil
+}
+
+// particleOptions 粒子追踪参数
+type particleOptions struct {
+	Start          time.Time   `json:"start"`
+	End            time.Time   `json:"end"`
+	Step           int         `json:"step"`           // 积分步长(分钟)
+	OutputInterval int         `json:"outputInterval"` // 轨迹输出间隔(分钟)
+	Diffusivity    float64     `json:"diffusivity"`    // 水平扩散系数(m²/s)
+	Windage        float64     `json:"windage"`        // 风致漂移系数
+	Seed           int64       `json:"seed"`
+	DensityTimes   []time.Time `json:"densityTimes"`
+}
+
+// parseParticleOptions 读取参数startDate、endDate(或duration，小时)、step(分钟，默认30)、outputInterval(分钟，默认60)、
+// diffusivity(默认0)、windage(指定风场时默认0.03)、seed(默认1)和densityTimes(逗号分隔，默认为endDate)；
+// startDate未指定时为最早的释放时间
+func parseParticleOptions(params map[string]interface{}, releases []particleRelease) (particleOptions, error) {
+	opts := particleOptions{Step: 30, OutputInterval: 60, Seed: 1}
+	var err error
+	if opts.Start, err = paramTime(params, "startDate"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.Start.IsZero() {
+		for i, r := range releases {
+			if r.Time == "" {
+				return opts, fmt.Errorf("%w: startDate is required when release %d has no time", ErrInvalidAnalysisParams, i)
+			}
+			t, err := parseTimeValue("time", r.Time)
+			if err != nil {
+				return opts, fmt.Errorf("%w: release %d: %v", ErrInvalidAnalysisParams, i, err)
+			}
+			if opts.Start.IsZero() || t.Before(opts.Start) {
+				opts.Start = t
+			}
+		}
+	}
+	if opts.End, err = paramTime(params, "endDate"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	hours, hasDuration, err := paramFloat(params, "duration")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.End.IsZero() && hasDuration {
+		opts.End = opts.Start.Add(time.Duration(hours * float64(time.Hour)))
+	}
+	if opts.End.IsZero() || !opts.End.After(opts.Start) {
+		return opts, fmt.Errorf("%w: endDate or duration is required and must be after startDate", ErrInvalidAnalysisParams)
+	}
+
+	for _, p := range []struct {
+		key   string
+		value *int
+	}{
+		{"step", &opts.Step},
+		{"outputInterval", &opts.OutputInterval},
+	} {
+		x, ok, err := paramFloat(params, p.key)
+		if err != nil {
+			return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+		}
+		if !ok {
+			continue
+		}
+		if x < 1 || x != math.Trunc(x) {
+			return opts, fmt.Errorf("%w: %s must be a positive integer number of minutes", ErrInvalidAnalysisParams, p.key)
+		}
+		*p.value = int(x)
+	}
+	if opts.OutputInterval%opts.Step != 0 {
+		return opts, fmt.Errorf("%w: outputInterval must be a multiple of step", ErrInvalidAnalysisParams)
+	}
+	if steps := opts.End.Sub(opts.Start) / (time.Duration(opts.Step) * time.Minute); steps > maxParticleSteps {
+		return opts, fmt.Errorf("%w: too many integration steps (%d, max %d), use a larger step or a shorter time range", ErrInvalidAnalysisParams, steps, maxParticleSteps)
+	}
+
+	if opts.Diffusivity, _, err = paramFloat(params, "diffusivity"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.Diffusivity < 0 {
+		return opts, fmt.Errorf("%w: diffusivity must not be negative", ErrInvalidAnalysisParams)
+	}
+	if paramString(params, "windDatasetId") != "" {
+		opts.Windage = 0.03
+	}
+	if windage, ok, err := paramFloat(params, "windage"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		if windage < 0 || windage > 0.1 {
+			return opts, fmt.Errorf("%w: windage must be between 0 and 0.1", ErrInvalidAnalysisParams)
+		}
+		opts.Windage = windage
+	}
+	if seed, ok, err := paramFloat(params, "seed"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		opts.Seed = int64(seed)
+	}
+
+	if s := paramString(params, "densityTimes"); s != "" {
+		for _, part := range strings.Split(s, ",") {
+			t, err := parseTimeValue("densityTimes", strings.TrimSpace(part))
+			if err != nil {
+				return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+			}
+			if t.Before(opts.Start) || t.After(opts.End) {
+				return opts, fmt.Errorf("%w: densityTimes must be within the tracking period", ErrInvalidAnalysisParams)
+			}
+			opts.DensityTimes = append(opts.DensityTimes, t)
+		}
+		sort.Slice(opts.DensityTimes, func(i, j int) bool { return opts.DensityTimes[i].Before(opts.DensityTimes[j]) })
+	} else {
+		opts.DensityTimes = []time.Time{opts.End}
+	}
+	return opts, nil
+}
+
+// loadVelocityField 读取u、v在[start, end](含两端外侧最近的时次)内各时次的水平场并插值到规则网格；
+// 变量没有时间维时为定常场
+func loadVelocityField(src *analysisSource, u, v *dataio.Variable, grid *regularGrid, depth float64, start, end time.Time) (*particle.GridField, error) {
+	field := &particle.GridField{MinLat: grid.minLat, MinLng: grid.minLng, Step: grid.step, Rows: grid.latCount, Cols: grid.lngCount}
+	var times []time.Time
+	if tc := src.Coordinate(u, dataio.AxisTime); tc != nil {
+		all, err := dataio.Times(tc)
+		if err != nil {
+			return nil, fmt.Errorf("read time of %s: %w", u.Name, err)
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("%w: %s has no time steps", ErrInvalidAnalysisParams, u.Name)
+		}
+		if len(all) > 1 && (start.Before(all[0]) || end.After(all[len(all)-1])) {
+			return nil, fmt.Errorf("%w: tracking period %s ~ %s is not covered by %s (%s ~ %s)", ErrInvalidAnalysisParams,
+				start.Format(time.RFC3339), end.Format(time.RFC3339), u.Name, all[0].Format(time.RFC3339), all[len(all)-1].Format(time.RFC3339))
+		}
+		lo := sort.Search(len(all), func(i int) bool { return all[i].After(start) }) - 1
+		hi := sort.Search(len(all), func(i int) bool { return !all[i].Before(end) })
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= len(all) {
+			hi = len(all) - 1
+		}
+		times = all[lo : hi+1]
+	} else {
+		times = []time.Time{{}}
+	}
+	if n := len(times) * grid.latCount * grid.lngCount; n > maxParticleFieldValues {
+		return nil, fmt.Errorf("%w: too many values (%d time steps x %d cells, max %d), use a lower resolution, smaller bounds or a shorter time range",
+			ErrInvalidAnalysisParams, len(times), grid.latCount*grid.lngCount, maxParticleFieldValues)
+	}
+
+	for _, t := range times {
+		var snapshot [2][]float32
+		for k, variable := range []*dataio.Variable{u, v} {
+			horizontal, err := extractHorizontalField(src.Source, variable, t, depth)
+			if err != nil {
+				return nil, fmt.Errorf("extract %s: %w", variable.Name, err)
+			}
+			values := make([]float32, 0, grid.latCount*grid.lngCount)
+			for _, row := range grid.regrid(horizontal) {
+				for _, x := range row {
+					values = append(values, float32(x))
+				}
+			}
+			snapshot[k] = values
+		}
+		field.Times = append(field.Times, t)
+		field.U = append(field.U, snapshot[0])
+		field.V = append(field.V, snapshot[1])
+	}
+	return field, nil
+}
+
+// windVariables 风场的东向和北向分量: 参数windUVariable、windVVariable指定的变量，未指定时按标准名查找并换算到m/s
+func (s *analysisService) windVariables(src *analysisSource, params map[string]interface{}) (u, v *dataio.Variable, err error) {
+	uName, vName := paramString(params, "windUVariable"), paramString(params, "windVVariable")
+	if uName == "" && vName == "" {
+		resolver := s.standardNames.Resolver()
+		if u, err = resolver.Resolve(src.Source, "eastward_wind", "m s-1"); err != nil {
+			return nil, nil, fmt.Errorf("%w: wind dataset has no eastward wind variable, specify windUVariable and windVVariable", ErrInvalidAnalysisParams)
+		}
+		if v, err = resolver.Resolve(src.Source, "northward_wind", "m s-1"); err != nil {
+			return nil, nil, fmt.Errorf("%w: wind dataset has no northward wind variable, specify windUVariable and windVVariable", ErrInvalidAnalysisParams)
+		}
+		return u, v, nil
+	}
+	if uName == "" || vName == "" {
+		return nil, nil, fmt.Errorf("%w: windUVariable and windVVariable must be specified together", ErrInvalidAnalysisParams)
+	}
+	u, v = src.Var(uName), src.Var(vName)
+	if u == nil || u.IsText() || v == nil || v.IsText() {
+		return nil, nil, fmt.Errorf("%w: wind variables %q, %q not found", ErrInvalidAnalysisParams, uName, vName)
+	}
+	return u, v, nil
+}
+
+// executeParticleTracking 拉格朗日粒子追踪: 按releases释放粒子，在参数datasetId指定的流场中用RK4平流(可叠加风致漂移和随机游走扩散)，
+// 粒子到达陆地时搁浅；返回GeoJSON LineString轨迹和densityTimes各时刻的粒子密度分布
+func (s *analysisService) executeParticleTracking(params map[string]interface{}) (map[string]interface{}, error) {
+	releases, err := parseParticleReleases(params)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := parseParticleOptions(params, releases)
+	if err != nil {
+		return nil, err
+	}
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成粒子
+	rng := rand.New(rand.NewSource(opts.Seed))
+	var particles []particle.Particle
+	releaseInfo := make([]map[string]interface{}, len(releases))
+	for i := range releases {
+		t := opts.Start
+		if releases[i].Time != "" {
+			if t, err = parseTimeValue("time", releases[i].Time); err != nil {
+				return nil, fmt.Errorf("%w: release %d: %v", ErrInvalidAnalysisParams, i, err)
+			}
+		}
+		if t.Before(opts.Start) || !t.Before(opts.End) {
+			return nil, fmt.Errorf("%w: release %d time must be within the tracking period", ErrInvalidAnalysisParams, i)
+		}
+		seeded, err := releases[i].seed(i, t, rng)
+		if err != nil {
+			return nil, err
+		}
+		if len(particles)+len(seeded) > maxParticles {
+			return nil, fmt.Errorf("%w: too many particles (max %d)", ErrInvalidAnalysisParams, maxParticles)
+		}
+		particles = append(particles, seeded...)
+		releaseInfo[i] = map[string]interface{}{"index": i, "name": releases[i].Name, "time": t.Format(time.RFC3339), "particles": len(seeded)}
+	}
+	outputs := int(opts.End.Sub(opts.Start)/(time.Duration(opts.OutputInterval)*time.Minute)) + 2
+	if len(particles)*outputs > maxParticleOutputPoints {
+		return nil, fmt.Errorf("%w: too many trajectory points (%d particles x %d outputs, max %d), use a larger outputInterval or fewer particles",
+			ErrInvalidAnalysisParams, len(particles), outputs, maxParticleOutputPoints)
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	uVar, vVar, _, err := s.currentVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 流场插值到规则网格，默认使用高分辨率以保留岸线
+	gridParams := map[string]interface{}{"bounds": params["bounds"], "resolution": params["resolution"]}
+	if paramString(gridParams, "resolution") == "" {
+		gridParams["resolution"] = "high"
+	}
+	sample, err := extractHorizontalField(src.Source, uVar, opts.Start, depth)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", uVar.Name, err)
+	}
+	grid, err := newRegularGrid(gridParams, sample)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	current, err := loadVelocityField(src, uVar, vVar, grid, depth, opts.Start, opts.End)
+	if err != nil {
+		return nil, err
+	}
+
+	trackOpts := particle.Options{
+		Start:       opts.Start,
+		End:         opts.End,
+		Step:        time.Duration(opts.Step) * time.Minute,
+		Output:      time.Duration(opts.OutputInterval) * time.Minute,
+		Windage:     opts.Windage,
+		Diffusivity: opts.Diffusivity,
+		Seed:        opts.Seed,
+		Snapshots:   opts.DensityTimes,
+	}
+	var windInfo interface{}
+	if windID := paramString(params, "windDatasetId"); windID != "" && opts.Windage > 0 {
+		windSrc, err := s.openDataset(map[string]interface{}{"datasetId": windID})
+		if err != nil {
+			return nil, err
+		}
+		defer windSrc.Close()
+		wu, wv, err := s.windVariables(windSrc, params)
+		if err != nil {
+			return nil, err
+		}
+		wind, err := loadVelocityField(windSrc, wu, wv, grid, 0, opts.Start, opts.End)
+		if err != nil {
+			return nil, err
+		}
+		trackOpts.Wind = wind
+		windInfo = map[string]interface{}{"datasetId": windID, "u": wu.Name, "v": wv.Name}
+	}
+
+	trajectories, snapshots := particle.Track(particles, current, trackOpts)
+
+	// 轨迹
+	counts := map[string]int{particle.Active: 0, particle.Stranded: 0, particle.Outside: 0}
+	features := make([]map[string]interface{}, len(trajectories))
+	for i, tr := range trajectories {
+		counts[tr.Status]++
+		coordinates := make([][2]float64, len(tr.Times))
+		timestamps := make([]string, len(tr.Times))
+		for k := range tr.Times {
+			coordinates[k] = [2]float64{tr.Lngs[k], tr.Lats[k]}
+			timestamps[k] = tr.Times[k].Format(time.RFC3339)
+		}
+		var stopTime interface{}
+		if !tr.StopTime.IsZero() {
+			stopTime = tr.StopTime.Format(time.RFC3339)
+		}
+		features[i] = map[string]interface{}{
+			"type":     "Feature",
+			"geometry": map[string]interface{}{"type": "LineString", "coordinates": coordinates},
+			"properties": map[string]interface{}{
+				"id":          i,
+				"release":     tr.Particle.Release,
+				"releaseTime": tr.Particle.Time.Format(time.RFC3339),
+				"status":      tr.Status,
+				"stopTime":    stopTime,
+				"timestamps":  timestamps,
+			},
+		}
+	}
+
+	// 各时刻的粒子密度(每个网格单元内的粒子数，含搁浅的粒子)
+	data := map[string]interface{}{}
+	units := map[string]string{}
+	densityInfo := make([]map[string]interface{}, len(snapshots))
+	for k, positions := range snapshots {
+		density := make([][]float64, grid.latCount)
+		for i := range density {
+			density[i] = make([]float64, grid.lngCount)
+		}
+		inGrid := 0
+		for _, p := range positions {
+			if !p.Released || p.Status == particle.Outside {
+				continue
+			}
+			i := int(math.Round((p.Lat - grid.minLat) / grid.step))
+			j := int(math.Round((p.Lng - grid.minLng) / grid.step))
+			if i < 0 || i >= grid.latCount || j < 0 || j >= grid.lngCount {
+				continue
+			}
+			density[i][j]++
+			inGrid++
+		}
+		key := "density_" + opts.DensityTimes[k].Format("20060102T1504")
+		rows := make([][]interface{}, grid.latCount)
+		for i := range rows {
+			rows[i] = nullableSlice(density[i])
+		}
+		data[key] = rows
+		units[key] = "count"
+		densityInfo[k] = map[string]interface{}{"key": key, "time": opts.DensityTimes[k].Format(time.RFC3339), "particles": inGrid}
+	}
+
+	return map[string]interface{}{
+		"variables":    map[string]string{"u": uVar.Name, "v": vVar.Name},
+		"wind":         windInfo,
+		"depth":        nullable(sample.Depth),
+		"timeRange":    map[string]interface{}{"start": opts.Start.Format(time.RFC3339), "end": opts.End.Format(time.RFC3339)},
+		"options":      opts,
+		"releases":     releaseInfo,
+		"particles":    len(particles),
+		"status":       counts,
+		"trajectories": map[string]interface{}{"type": "FeatureCollection", "features": features},
+		"bounds":       grid.bounds(),
+		"resolution":   grid.resolution,
+		"grid":         grid.info(),
+		"units":        units,
+		"data":         data,
+		"densityTimes": densityInfo,
+	}, nil
+}
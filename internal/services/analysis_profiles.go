@@ -0,0 +1,296 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/argo"
+)
+
+// ErrInvalidProfileQuery 剖面查询参数无效
+var ErrInvalidProfileQuery = errors.New("invalid profile query")
+
+const (
+	// defaultProfilePageSize 剖面查询默认每页条数
+	defaultProfilePageSize = 50
+	// maxProfilePageSize 剖面查询每页最大条数
+	maxProfilePageSize = 500
+	// maxProfileDepths 自定义标准层的最大层数
+	maxProfileDepths = 200
+)
+
+// profileVariables 剖面查询可选的变量
+var profileVariables = []string{"temp", "psal"}
+
+// profileData 剖面逐层数据，缺测为null，标志为逐层的QC字符
+type profileData struct {
+	Pressure    []*float64 `json:"pres"`
+	Temperature []*float64 `json:"temp,omitempty"`
+	Salinity    []*float64 `json:"psal,omitempty"`
+	PressureQC  string     `json:"presQc"`
+	TempQC      string     `json:"tempQc,omitempty"`
+	SalinityQC  string     `json:"psalQc,omitempty"`
+}
+
+// newProfileRecord 将Argo剖面转换为剖面记录
+func newProfileRecord(datasetID string, p *argo.Profile) *models.Profile {
+	t := p.Time
+	record := &models.Profile{
+		DatasetID:  datasetID,
+		Platform:   p.Platform,
+		Cycle:      p.Cycle,
+		Direction:  strings.TrimSpace(p.Direction),
+		DataMode:   strings.TrimSpace(p.DataMode),
+		Time:       &t,
+		TimeQC:     strings.TrimSpace(string(p.TimeQC)),
+		Latitude:   p.Latitude,
+		Longitude:  p.Longitude,
+		PositionQC: strings.TrimSpace(string(p.PositionQC)),
+		Levels:     len(p.Pressure),
+	}
+	for _, x := range argo.Filter(p.Pressure, p.PressureQC) {
+		if !math.IsNaN(x) && x > record.MaxPressure {
+			record.MaxPressure = x
+		}
+	}
+	record.Data = mustJSON(profileData{
+		Pressure:    floatPointers(p.Pressure),
+		Temperature: floatPointers(p.Temperature),
+		Salinity:    floatPointers(p.Salinity),
+		PressureQC:  p.PressureQC,
+		TempQC:      p.TempQC,
+		SalinityQC:  p.SalinityQC,
+	})
+	return record
+}
+
+// floatPointers 转换为可序列化的数组，NaN为nil
+func floatPointers(values []float64) []*float64 {
+	if values == nil {
+		return nil
+	}
+	out := make([]*float64, len(values))
+	for i, x := range values {
+		if !math.IsNaN(x) && !math.IsInf(x, 0) {
+			v := x
+			out[i] = &v
+		}
+	}
+	return out
+}
+
+// floatValues floatPointers的逆转换
+func floatValues(values []*float64) []float64 {
+	out := make([]float64, len(values))
+	for i, x := range values {
+		if x == nil {
+			out[i] = math.NaN()
+		} else {
+			out[i] = *x
+		}
+	}
+	return out
+}
+
+// SearchProfiles 按区域、时间窗、浮标查找剖面，并插值到标准层
+func (s *analysisService) SearchProfiles(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeProfileSearch(params)
+}
+
+// executeProfileSearch 剖面查询，参数: bounds、startDate、endDate、platform、cycle、datasetId、
+// depths(逗号分隔的标准层深度，默认WOA标准层)、variables(temp、psal)、page、size
+func (s *analysisService) executeProfileSearch(params map[string]interface{}) (map[string]interface{}, error) {
+	filters := map[string]interface{}{}
+
+	bounds, ok, err := paramBounds(params, "bounds")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfileQuery, err)
+	}
+	if ok {
+		filters["bounds"] = bounds
+	}
+	start, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfileQuery, err)
+	}
+	end, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfileQuery, err)
+	}
+	if len(paramString(params, "endDate")) == len("2006-01-02") {
+		end = end.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		return nil, fmt.Errorf("%w: startDate is after endDate", ErrInvalidProfileQuery)
+	}
+	if !start.IsZero() {
+		filters["start"] = start
+	}
+	if !end.IsZero() {
+		filters["end"] = end
+	}
+	if platform := paramString(params, "platform"); platform != "" {
+		filters["platform"] = platform
+	}
+	if cycle := paramString(params, "cycle"); cycle != "" {
+		n, err := strconv.Atoi(cycle)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid parameter cycle: %q", ErrInvalidProfileQuery, cycle)
+		}
+		filters["cycle"] = n
+	}
+	if datasetID := paramString(params, "datasetId"); datasetID != "" {
+		filters["datasetId"] = datasetID
+	}
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("%w: at least one of bounds, startDate, endDate, platform or datasetId is required", ErrInvalidProfileQuery)
+	}
+
+	depths, err := profileDepths(paramString(params, "depths"))
+	if err != nil {
+		return nil, err
+	}
+	variables, err := profileVariableList(paramString(params, "variables"))
+	if err != nil {
+		return nil, err
+	}
+	page, size, err := profilePage(params)
+	if err != nil {
+		return nil, err
+	}
+
+	records, total, err := s.profileRepo.Search(page, size, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search profiles: %w", err)
+	}
+
+	profiles := make([]map[string]interface{}, 0, len(records))
+	for _, r := range records {
+		item, err := interpolateProfile(r, depths, variables)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, item)
+	}
+
+	units := map[string]string{"depth": "m", "temp": "℃", "psal": "PSU"}
+	return map[string]interface{}{
+		"total":     total,
+		"page":      page,
+		"size":      size,
+		"depths":    depths,
+		"variables": variables,
+		"units":     units,
+		"profiles":  profiles,
+	}, nil
+}
+
+// interpolateProfile 将剖面记录中QC可用的数据插值到标准层
+func interpolateProfile(r *models.Profile, depths []float64, variables []string) (map[string]interface{}, error) {
+	var data profileData
+	if err := json.Unmarshal([]byte(r.Data), &data); err != nil {
+		return nil, fmt.Errorf("invalid data of profile %d: %w", r.ID, err)
+	}
+
+	pressure := argo.Filter(floatValues(data.Pressure), data.PressureQC)
+	depth := make([]float64, len(pressure))
+	for i, p := range pressure {
+		depth[i] = argo.PressureToDepth(p, r.Latitude)
+	}
+
+	item := map[string]interface{}{
+		"id":          r.ID,
+		"datasetId":   r.DatasetID,
+		"platform":    r.Platform,
+		"cycle":       r.Cycle,
+		"direction":   r.Direction,
+		"dataMode":    r.DataMode,
+		"time":        r.Time,
+		"timeQc":      r.TimeQC,
+		"latitude":    r.Latitude,
+		"longitude":   r.Longitude,
+		"positionQc":  r.PositionQC,
+		"levels":      r.Levels,
+		"maxPressure": r.MaxPressure,
+	}
+	for _, name := range variables {
+		var values []float64
+		switch name {
+		case "temp":
+			if data.Temperature != nil {
+				values = argo.Filter(floatValues(data.Temperature), data.TempQC)
+			}
+		case "psal":
+			if data.Salinity != nil {
+				values = argo.Filter(floatValues(data.Salinity), data.SalinityQC)
+			}
+		}
+		if values == nil {
+			item[name] = nil
+			continue
+		}
+		item[name] = nullableSlice(argo.Interpolate(depth, values, depths))
+	}
+	return item, nil
+}
+
+// profileDepths 解析标准层深度，为空时使用默认标准层
+func profileDepths(s string) ([]float64, error) {
+	if s == "" {
+		return argo.StandardDepths, nil
+	}
+	var depths []float64
+	for _, part := range strings.Split(s, ",") {
+		z, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || z < 0 || math.IsNaN(z) || math.IsInf(z, 0) {
+			return nil, fmt.Errorf("%w: invalid depth %q", ErrInvalidProfileQuery, part)
+		}
+		depths = append(depths, z)
+	}
+	if len(depths) > maxProfileDepths {
+		return nil, fmt.Errorf("%w: at most %d depths", ErrInvalidProfileQuery, maxProfileDepths)
+	}
+	sort.Float64s(depths)
+	return depths, nil
+}
+
+// profileVariableList 解析变量列表，为空时返回全部变量
+func profileVariableList(s string) ([]string, error) {
+	if s == "" {
+		return profileVariables, nil
+	}
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !containsString(profileVariables, name) {
+			return nil, fmt.Errorf("%w: unsupported variable %q", ErrInvalidProfileQuery, name)
+		}
+		if !containsString(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// profilePage 解析分页参数
+func profilePage(params map[string]interface{}) (page, size int, err error) {
+	page, size = 1, defaultProfilePageSize
+	if s := paramString(params, "page"); s != "" {
+		if page, err = strconv.Atoi(s); err != nil || page < 1 {
+			return 0, 0, fmt.Errorf("%w: invalid page %q", ErrInvalidProfileQuery, s)
+		}
+	}
+	if s := paramString(params, "size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil || size < 1 || size > maxProfilePageSize {
+			return 0, 0, fmt.Errorf("%w: size must be between 1 and %d", ErrInvalidProfileQuery, maxProfilePageSize)
+		}
+	}
+	return page, size, nil
+}
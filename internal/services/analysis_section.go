@@ -0,0 +1,308 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/transect"
+)
+
+// maxSectionStations 断面的最大站位数
+const maxSectionStations = 2000
+
+// maxSectionValues 断面读取的最大元素数(层数×外包范围内的水平格点数)
+const maxSectionValues = 20000000
+
+// maxSectionSearch 非规则网格最近格点搜索的最大计算量(站位数×水平格点数)
+const maxSectionSearch = 100000000
+
+// parseSectionPath 解析参数path: "lat,lng;lat,lng;..."，或GeoJSON LineString(坐标为[lng, lat])
+func parseSectionPath(params map[string]interface{}) ([][2]float64, error) {
+	var raw []byte
+	switch v := params["path"].(type) {
+	case nil:
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "" {
+			break
+		}
+		if !strings.HasPrefix(s, "{") {
+			var path [][2]float64
+			for _, part := range strings.Split(s, ";") {
+				if part = strings.TrimSpace(part); part == "" {
+					continue
+				}
+				fields := strings.Split(part, ",")
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("%w: invalid path point %q, expected lat,lng", ErrInvalidAnalysisParams, part)
+				}
+				lat, err1 := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+				lng, err2 := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+				if err1 != nil || err2 != nil {
+					return nil, fmt.Errorf("%w: invalid path point %q, expected lat,lng", ErrInvalidAnalysisParams, part)
+				}
+				path = append(path, [2]float64{lat, lng})
+			}
+			return validateSectionPath(path)
+		}
+		raw = []byte(s)
+	default:
+		raw, _ = json.Marshal(v)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: missing required parameter: path", ErrInvalidAnalysisParams)
+	}
+	var line struct {
+		Type        string       `json:"type"`
+		Coordinates [][2]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &line); err != nil || line.Type != "LineString" {
+		return nil, fmt.Errorf("%w: path must be a GeoJSON LineString", ErrInvalidAnalysisParams)
+	}
+	path := make([][2]float64, len(line.Coordinates))
+	for i, c := range line.Coordinates {
+		path[i] = [2]float64{c[1], c[0]}
+	}
+	return validateSectionPath(path)
+}
+
+// validateSectionPath 检查折线的顶点数和坐标范围
+func validateSectionPath(path [][2]float64) ([][2]float64, error) {
+	if len(path) < 2 {
+		return nil, fmt.Errorf("%w: path must have at least 2 points", ErrInvalidAnalysisParams)
+	}
+	for _, p := range path {
+		if p[0] < -90 || p[0] > 90 || p[1] < -180 || p[1] > 360 {
+			return nil, fmt.Errorf("%w: path point (%g, %g) out of range", ErrInvalidAnalysisParams, p[0], p[1])
+		}
+	}
+	return path, nil
+}
+
+// sectionOptions 断面参数
+type sectionOptions struct {
+	Spacing  float64 `json:"spacing"` // 站位间距(km)
+	Method   string  `json:"method"`  // 水平插值方法: bilinear或nearest
+	MinDepth float64 `json:"minDepth"`
+	MaxDepth float64 `json:"maxDepth"` // 为0时不限制
+}
+
+// parseSectionOptions 读取参数spacing(km，默认10)、method(默认bilinear)、minDepth和maxDepth
+func parseSectionOptions(params map[string]interface{}) (sectionOptions, error) {
+	opts := sectionOptions{Spacing: 10, Method: "bilinear"}
+	if spacing, ok, err := paramFloat(params, "spacing"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		if !(spacing > 0) {
+			return opts, fmt.Errorf("%w: spacing must be positive", ErrInvalidAnalysisParams)
+		}
+		opts.Spacing = spacing
+	}
+	if method := paramString(params, "method"); method != "" {
+		if method != "bilinear" && method != "nearest" {
+			return opts, fmt.Errorf("%w: method must be bilinear or nearest", ErrInvalidAnalysisParams)
+		}
+		opts.Method = method
+	}
+	var err error
+	if opts.MinDepth, _, err = paramFloat(params, "minDepth"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.MaxDepth, _, err = paramFloat(params, "maxDepth"); err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.MinDepth < 0 || opts.MaxDepth < 0 || (opts.MaxDepth > 0 && opts.MaxDepth <= opts.MinDepth) {
+		return opts, fmt.Errorf("%w: invalid depth range", ErrInvalidAnalysisParams)
+	}
+	return opts, nil
+}
+
+// readSectionSlab 读取变量在时间最接近t(零值时取第一个时次)、深度在[minDepth, maxDepth]内、
+// 覆盖范围bbox的数据；变量须有一维垂向坐标
+func readSectionSlab(src *dataio.Source, v *dataio.Variable, t time.Time, bbox [4]float64, minDepth, maxDepth float64) (*gridSlab, error) {
+	if zc := src.Coordinate(v, dataio.AxisZ); src.AxisDim(v, dataio.AxisZ) < 0 || zc == nil || len(zc.Dims) != 1 {
+		return nil, fmt.Errorf("%w: variable %s has no vertical coordinate", ErrInvalidAnalysisParams, v.Name)
+	}
+	ti := 0
+	if !t.IsZero() && src.AxisDim(v, dataio.AxisTime) >= 0 {
+		var err error
+		if _, ti, _, err = src.NearestTime(v, t); err != nil {
+			return nil, err
+		}
+	}
+	return readGridSlab(src, v, ti, bbox, minDepth, maxDepth, maxSectionValues)
+}
+
+// sectionBottom 参数bathymetryVariable指定的水深变量在各站位最近格点的值(取绝对值)
+func sectionBottom(src *dataio.Source, v *dataio.Variable, stations []transect.Station) ([]float64, error) {
+	out := make([]float64, len(stations))
+	for k, st := range stations {
+		start, count := dataio.FullSlice(v)
+		fixed, _, _, err := src.NearestPoint(v, st.Lat, st.Lng)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bathymetry variable %s: %v", ErrInvalidAnalysisParams, v.Name, err)
+		}
+		for d := range count {
+			count[d] = 1
+		}
+		for d, i := range fixed {
+			start[d] = i
+		}
+		values, err := v.Read(start, count)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = math.Abs(values[0])
+	}
+	return out, nil
+}
+
+// executeSection 沿参数path指定的折线按spacing布设站位，提取date时刻各变量的垂直断面(深度×距离)，
+// 并给出各站位的海底深度
+func (s *analysisService) executeSection(params map[string]interface{}) (map[string]interface{}, error) {
+	path, err := parseSectionPath(params)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := parseSectionOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	date, err := paramTime(params, "date")
+	if err != nil {
+		return nil, err
+	}
+	stations, vertices, err := transect.Stations(path, opts.Spacing)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if len(stations) > maxSectionStations {
+		return nil, fmt.Errorf("%w: too many stations (%d, max %d), use a larger spacing", ErrInvalidAnalysisParams, len(stations), maxSectionStations)
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	variables, units, err := s.analysisVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+
+	bbox := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, st := range stations {
+		bbox[0], bbox[2] = math.Min(bbox[0], st.Lat), math.Max(bbox[2], st.Lat)
+		bbox[1], bbox[3] = math.Min(bbox[1], st.Lng), math.Max(bbox[3], st.Lng)
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data := map[string]interface{}{}
+	names := map[string]string{}
+	var depths []float64
+	var actual time.Time
+	deepest := make([]float64, len(stations))
+	for k := range deepest {
+		deepest[k] = math.NaN()
+	}
+	for _, key := range keys {
+		v := variables[key]
+		slab, err := readSectionSlab(src.Source, v, date, bbox, opts.MinDepth, opts.MaxDepth)
+		if err != nil {
+			return nil, err
+		}
+		if depths == nil {
+			depths, actual = slab.Depths, slab.Time
+		} else if len(slab.Depths) != len(depths) {
+			return nil, fmt.Errorf("%w: variables must share the same vertical levels", ErrInvalidAnalysisParams)
+		}
+		if slab.AxisLats == nil && len(stations)*len(slab.Lats) > maxSectionSearch {
+			return nil, fmt.Errorf("%w: section on an irregular grid is too large, use a larger spacing or a shorter path", ErrInvalidAnalysisParams)
+		}
+
+		rows := make([][]float64, len(slab.Depths))
+		for l := range rows {
+			rows[l] = make([]float64, len(stations))
+		}
+		for k, st := range stations {
+			w := slab.weights(st.Lat, st.Lng, opts.Method)
+			for l, level := range slab.Values {
+				rows[l][k] = math.NaN()
+				if w != nil {
+					rows[l][k] = sampleWeights(level, w)
+				}
+				if !math.IsNaN(rows[l][k]) && !(slab.Depths[l] <= deepest[k]) {
+					deepest[k] = slab.Depths[l]
+				}
+			}
+		}
+		out := make([][]interface{}, len(rows))
+		for l, row := range rows {
+			out[l] = nullableSlice(row)
+		}
+		data[key] = out
+		names[key] = v.Name
+	}
+
+	// 海底深度: 水深变量的值，未指定时为有数据的最深层
+	bottom, bottomSource := deepest, "deepest-level"
+	if name := paramString(params, "bathymetryVariable"); name != "" {
+		bv := src.Var(name)
+		if bv == nil || bv.IsText() {
+			return nil, fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+		}
+		if bottom, err = sectionBottom(src.Source, bv, stations); err != nil {
+			return nil, err
+		}
+		bottomSource = name
+	}
+
+	stationInfo := make([]map[string]interface{}, len(stations))
+	distances := make([]float64, len(stations))
+	for k, st := range stations {
+		distances[k] = st.Distance
+		stationInfo[k] = map[string]interface{}{
+			"index":       k,
+			"lat":         st.Lat,
+			"lng":         st.Lng,
+			"distance":    st.Distance,
+			"bottomDepth": nullable(bottom[k]),
+		}
+	}
+	var actualTime interface{}
+	if !actual.IsZero() {
+		actualTime = actual.Format(time.RFC3339)
+	}
+
+	return map[string]interface{}{
+		"variables":    names,
+		"units":        units,
+		"time":         actualTime,
+		"path":         path,
+		"vertices":     vertices,
+		"length":       vertices[len(vertices)-1],
+		"options":      opts,
+		"stations":     stationInfo,
+		"distances":    distances,
+		"depths":       depths,
+		"bottomDepth":  nullableSlice(bottom),
+		"bottomSource": bottomSource,
+		"data":         data,
+	}, nil
+}
+
+// GetSection 获取沿折线的垂直断面
+func (s *analysisService) GetSection(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeSection(params)
+}
@@ -4,13 +4,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"time"
 
 	"github.com/sinker/ssop/internal/models"
 	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/dataio"
 	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/resample"
 	"github.com/sinker/ssop/pkg/utils"
 )
 
@@ -23,9 +28,31 @@ type AnalysisService interface {
 	UpdateTask(task *models.AnalysisTask) error
 	DeleteTask(id string) error
 	
-	// 特定分析功能
-	GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval string) (map[string]interface{}, error)
-	GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution string) (map[string]interface{}, error)
+	// 特定分析功能，anomaly为计算距平的参数(anomaly、climatologyId、period、baseline)，可为nil；
+	// 时间序列的options另可包含重采样参数(reducer、percentile、minCount、minCoverage)
+	GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval, variables string, options map[string]interface{}) (map[string]interface{}, error)
+	GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution, variables string, anomaly map[string]interface{}) (map[string]interface{}, error)
+	ExportGeoTIFF(result map[string]interface{}, path string) error
+	// SearchProfiles 按区域、时间窗或浮标查找剖面，返回标准层上的插值结果
+	SearchProfiles(params map[string]interface{}) (map[string]interface{}, error)
+	// GetTSDiagram 区域、时间窗和深度范围内的T-S图散点或分箱，叠加σθ等密度线并可按水团分类
+	GetTSDiagram(params map[string]interface{}) (map[string]interface{}, error)
+	// GetMixedLayerTimeSeries 指定位置的混合层深度、温跃层和最大层结时间序列
+	GetMixedLayerTimeSeries(params map[string]interface{}) (map[string]interface{}, error)
+	// GetMixedLayerSpatial 混合层深度、温跃层和最大层结的空间分布
+	GetMixedLayerSpatial(params map[string]interface{}) (map[string]interface{}, error)
+	// GetTrendTimeSeries 指定位置时间序列的最小二乘趋势、Sen斜率和Mann-Kendall检验
+	GetTrendTimeSeries(params map[string]interface{}) (map[string]interface{}, error)
+	// GetTrendSpatial 逐格点趋势的空间分布，含p值和显著性掩码
+	GetTrendSpatial(params map[string]interface{}) (map[string]interface{}, error)
+	// GetCurrentField 流场的流速、流向、涡度、散度、Okubo-Weiss参数和涡旋，含前端绘制用的矢量网格
+	GetCurrentField(params map[string]interface{}) (map[string]interface{}, error)
+	// GetCurrentRose 指定位置的海流玫瑰图
+	GetCurrentRose(params map[string]interface{}) (map[string]interface{}, error)
+	// GetSection 沿折线(如调查航线)的垂直断面，含各站位的海底深度
+	GetSection(params map[string]interface{}) (map[string]interface{}, error)
+	// ListClimatologies 源数据集已生成的气候态
+	ListClimatologies(datasetID string) ([]*models.Climatology, error)
 	
 	// 结果管理
 	CreateResult(result *models.AnalysisResult) (string, error)
@@ -36,16 +63,32 @@ type AnalysisService interface {
 
 // analysisService 分析功能服务实现
 type analysisService struct {
-	analysisRepo repository.AnalysisRepository
-	datasetRepo  repository.DatasetRepository
-	resultsDir   string // 分析结果存储目录
+	analysisRepo  repository.AnalysisRepository
+	datasetRepo   repository.DatasetRepository
+	profileRepo     repository.ProfileRepository
+	climatologyRepo repository.ClimatologyRepository
+	quota           QuotaService
+	standardNames   StandardNameService
+	systemService   SystemService
+	ingest          IngestService
+	notifications   NotificationService
+	resultsDir      string // 分析结果存储目录
+	datasetDir      string // 派生数据集(如气候态)存储目录
+	bin             trashBin
 }
 
 // NewAnalysisService 创建分析功能服务
 func NewAnalysisService(
 	analysisRepo repository.AnalysisRepository,
 	datasetRepo repository.DatasetRepository,
-	resultsDir string,
+	profileRepo repository.ProfileRepository,
+	climatologyRepo repository.ClimatologyRepository,
+	quota QuotaService,
+	standardNames StandardNameService,
+	systemService SystemService,
+	ingest IngestService,
+	notifications NotificationService,
+	resultsDir, datasetDir, trashDir string,
 ) AnalysisService {
 	// 确保结果目录存在
 	if err := os.MkdirAll(resultsDir, 0755); err != nil {
@@ -53,9 +96,18 @@ func NewAnalysisService(
 	}
 	
 	return &analysisService{
-		analysisRepo: analysisRepo,
-		datasetRepo:  datasetRepo,
-		resultsDir:   resultsDir,
+		analysisRepo:  analysisRepo,
+		datasetRepo:   datasetRepo,
+		profileRepo:     profileRepo,
+		climatologyRepo: climatologyRepo,
+		quota:           quota,
+		standardNames:   standardNames,
+		systemService:   systemService,
+		ingest:          ingest,
+		notifications:   notifications,
+		resultsDir:      resultsDir,
+		datasetDir:      datasetDir,
+		bin:             trashBin{dir: trashDir},
 	}
 }
 
@@ -66,6 +118,11 @@ func (s *analysisService) CreateTask(task *models.AnalysisTask) (string, error)
 		task.ID = utils.GenerateID("task")
 	}
 	
+	// 检查存储配额
+	if err := s.quota.CheckTaskCreation(task.CreatedBy); err != nil {
+		return "", err
+	}
+	
 	// 设置初始状态
 	if task.Status == "" {
 		task.Status = "pending"
@@ -85,6 +142,18 @@ func (s *analysisService) CreateTask(task *models.AnalysisTask) (string, error)
 
 // 处理分析任务
 func (s *analysisService) processTask(task *models.AnalysisTask) {
+	defer func() {
+		// 读取异常文件时的panic只使本任务失败，不影响服务进程
+		if r := recover(); r != nil {
+			logger.Error("Analysis task panicked", "panic", r, "taskId", task.ID, "stack", string(debug.Stack()))
+			task.Status = "failed"
+			task.ErrorMsg = fmt.Sprintf("internal error: %v", r)
+			if err := s.analysisRepo.UpdateTask(task); err != nil {
+				logger.Error("Failed to update task status", "error", err, "taskId", task.ID)
+			}
+		}
+	}()
+
 	// 更新任务状态为运行中
 	task.Status = "running"
 	task.Progress = 10
@@ -131,6 +200,36 @@ func (s *analysisService) processTask(task *models.AnalysisTask) {
 		result, err = s.executeTemperatureSalinityTimeSeries(params)
 	case "temperature-salinity-spatial":
 		result, err = s.executeTemperatureSalinitySpatial(params)
+	case "profiles":
+		result, err = s.executeProfileSearch(params)
+	case "ts-diagram":
+		result, err = s.executeTSDiagram(params)
+	case "mixed-layer-timeseries":
+		result, err = s.executeMixedLayerTimeSeries(params)
+	case "mixed-layer-spatial":
+		result, err = s.executeMixedLayerSpatial(params)
+	case "trend-timeseries":
+		result, err = s.executeTrendTimeSeries(params)
+	case "trend-spatial":
+		result, err = s.executeTrendSpatial(params)
+	case "eof":
+		result, err = s.executeEOF(params)
+	case "marine-heatwave":
+		result, err = s.executeMarineHeatwave(params)
+	case "climatology":
+		result, err = s.executeClimatology(task, params)
+	case "tidal-harmonic":
+		result, err = s.executeTidalHarmonic(params)
+	case "current-field":
+		result, err = s.executeCurrentField(params)
+	case "current-rose":
+		result, err = s.executeCurrentRose(params)
+	case "particle-tracking":
+		result, err = s.executeParticleTracking(params)
+	case "section":
+		result, err = s.executeSection(params)
+	case "comparison":
+		result, err = s.executeComparison(params)
 	default:
 		err = fmt.Errorf("unsupported analysis type: %s", task.Type)
 	}
@@ -178,12 +277,14 @@ func (s *analysisService) processTask(task *models.AnalysisTask) {
 		Type:        "json",
 		Format:      "json",
 		FilePath:    resultFilePath,
+		Size:        int64(len(resultData)),
 		PreviewData: string(resultData[:min(1000, len(resultData))]), // 保存结果预览(最多1000字节)
 	}
 	
 	if _, err := s.CreateResult(analysisResult); err != nil {
 		logger.Error("Failed to create result record", "error", err, "taskId", task.ID)
 	}
+	s.exportResultGeoTIFF(task, resultDir, result)
 	
 	// 完成任务
 	task.Progress = 100
@@ -195,6 +296,7 @@ func (s *analysisService) processTask(task *models.AnalysisTask) {
 	if err := s.analysisRepo.UpdateTask(task); err != nil {
 		logger.Error("Failed to update task status", "error", err, "taskId", task.ID)
 	}
+	s.notifyHeatwave(task, params, result)
 }
 
 // 辅助函数，返回最小值
@@ -207,163 +309,291 @@ func min(a, b int) int {
 
 // 执行温盐时间序列分析
 func (s *analysisService) executeTemperatureSalinityTimeSeries(params map[string]interface{}) (map[string]interface{}, error) {
-	// 在实际应用中，这里应该实现真正的分析逻辑
-	// 这里仅做简单演示，返回模拟数据
-	
-	// 检查必要参数
-	datasetID, _ := params["datasetId"].(string)
-	if datasetID == "" {
-		return nil, errors.New("missing required parameter: datasetId")
+	// 打开数据集
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
 	}
+	defer src.Close()
 	
-	// 获取数据集信息
-	_ , err := s.datasetRepo.GetByID(datasetID)
+	// 按标准名查找温度和盐度并换算到℃和PSU，或使用参数指定的变量
+	variables, units, err := s.analysisVariables(src, params)
 	if err != nil {
-		return nil, fmt.Errorf("dataset not found: %w", err)
-	}
-	
-	// 模拟时间序列数据
-	lat, _ := params["lat"].(string)
-	lng, _ := params["lng"].(string)
-	depth, _ := params["depth"].(string)
-	startDate, _ := params["startDate"].(string)
-	endDate, _ := params["endDate"].(string)
-	interval, _ := params["interval"].(string)
-	
-	// 生成模拟结果
-	series := []map[string]interface{}{}
-	
-	// 解析起始时间和结束时间
-	start, _ := time.Parse(time.RFC3339, startDate)
-	end, _ := time.Parse(time.RFC3339, endDate)
-	
-	// 根据间隔设置步长
-	var step time.Duration
-	switch interval {
-	case "hour":
-		step = time.Hour
-	case "day":
-		step = 24 * time.Hour
-	case "week":
-		step = 7 * 24 * time.Hour
-	case "month":
-		step = 30 * 24 * time.Hour
-	default:
-		step = 24 * time.Hour
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := pointTimeSeries(src, variables, units, params)
+	if err != nil {
+		return nil, err
+	}
+	withAnomaly(result, climatology)
+	return result, nil
+}
+
+// pointTimeSeries 提取各变量在参数lat、lng、depth指定位置的时间序列，并按参数interval、reducer等重采样(见resampleOptions)
+func pointTimeSeries(src *analysisSource, variables map[string]*dataio.Variable, units map[string]string, params map[string]interface{}) (map[string]interface{}, error) {
+	// 提取参数
+	lat, hasLat, err := paramFloat(params, "lat")
+	if err != nil {
+		return nil, err
+	}
+	lng, hasLng, err := paramFloat(params, "lng")
+	if err != nil {
+		return nil, err
+	}
+	depth, hasDepth, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+	interval := paramString(params, "interval")
+	opts, err := resampleOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	
+	var depthParam *float64
+	if hasDepth {
+		depthParam = &depth
+	}
+	if !hasLat || !hasLng {
+		lat, lng = math.NaN(), math.NaN()
+	}
+	
+	// 计数的单位为1
+	if opts.Reducer.Name == "count" {
+		counts := map[string]string{}
+		for key := range units {
+			counts[key] = "1"
+		}
+		units = counts
 	}
 	
-	// 生成数据点
-	for t := start; t.Before(end) || t.Equal(end); t = t.Add(step) {
-		// 模拟温度和盐度数据
-		temp := 20.0 + 5.0*utils.RandomFloat64(-1, 1) // 20±5°C
-		salinity := 35.0 + 2.0*utils.RandomFloat64(-1, 1) // 35±2 PSU
+	// 提取各变量的单点时间序列，并按周期聚合
+	points := map[time.Time]map[string]interface{}{}
+	location := map[string]interface{}{"lat": nullable(lat), "lng": nullable(lng), "depth": nullable(depth)}
+	names := map[string]string{}
+	for key, v := range variables {
+		ps, err := extractPointSeries(src.Source, v, lat, lng, depthParam, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+		}
+		names[key] = v.Name
+		location["lat"], location["lng"], location["depth"] = nullable(ps.Lat), nullable(ps.Lng), nullable(ps.Depth)
 		
-		series = append(series, map[string]interface{}{
-			"timestamp":   t.Format(time.RFC3339),
-			"temperature": temp,
-			"salinity":    salinity,
-		})
+		opts.Calendar = src.Calendar(v)
+		for _, b := range resample.Resample(ps.Times, ps.Values, opts) {
+			point, ok := points[b.Start]
+			if !ok {
+				point = map[string]interface{}{"timestamp": b.Start.Format(time.RFC3339)}
+				if !opts.Period.IsZero() {
+					point["period"] = opts.Period.Label(b.Start)
+				}
+				points[b.Start] = point
+			}
+			point[key] = nullable(b.Value)
+		}
+	}
+	
+	timestamps := make([]time.Time, 0, len(points))
+	for t := range points {
+		timestamps = append(timestamps, t)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i].Before(timestamps[j]) })
+	
+	series := make([]map[string]interface{}, 0, len(timestamps))
+	for _, t := range timestamps {
+		point := points[t]
+		for key := range variables {
+			if _, ok := point[key]; !ok {
+				point[key] = nil
+			}
+		}
+		series = append(series, point)
 	}
 	
 	return map[string]interface{}{
-		"location": map[string]interface{}{
-			"lat":   lat,
-			"lng":   lng,
-			"depth": depth,
-		},
+		"location": location,
 		"timeRange": map[string]interface{}{
-			"start": startDate,
-			"end":   endDate,
+			"start": paramString(params, "startDate"),
+			"end":   paramString(params, "endDate"),
 		},
-		"interval": interval,
-		"series":   series,
+		"interval":  interval,
+		"resample": map[string]interface{}{
+			"reducer":     opts.Reducer.String(),
+			"minCount":    opts.MinCount,
+			"minCoverage": opts.MinCoverage,
+		},
+		"variables": names,
+		"units":     units,
+		"series":    series,
 	}, nil
 }
 
+// spatialResolutions 空间分布的网格间隔(度)
+var spatialResolutions = map[string]float64{
+	"low":    1.0,
+	"medium": 0.5,
+	"high":   0.1,
+}
+
+// maxSpatialCells 空间分布网格的最大格点数
+const maxSpatialCells = 1000000
+
+// regularGrid 空间分布结果的规则经纬度网格
+type regularGrid struct {
+	minLat, minLng     float64
+	maxLat, maxLng     float64
+	latCount, lngCount int
+	step               float64
+	resolution         string
+}
+
+// newRegularGrid 按参数bounds、resolution确定规则网格，未指定范围时使用水平场sample的覆盖范围
+func newRegularGrid(params map[string]interface{}, sample *horizontalField) (*regularGrid, error) {
+	bounds, hasBounds, err := paramBounds(params, "bounds")
+	if err != nil {
+		return nil, err
+	}
+	g := &regularGrid{resolution: paramString(params, "resolution")}
+	var ok bool
+	if g.step, ok = spatialResolutions[g.resolution]; !ok {
+		g.resolution, g.step = "medium", spatialResolutions["medium"]
+	}
+
+	g.minLat, g.minLng, g.maxLat, g.maxLng = bounds[0], bounds[1], bounds[2], bounds[3]
+	if !hasBounds {
+		g.minLat, g.minLng, g.maxLat, g.maxLng = math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for i := range sample.Lats {
+			g.minLat, g.maxLat = math.Min(g.minLat, sample.Lats[i]), math.Max(g.maxLat, sample.Lats[i])
+			g.minLng, g.maxLng = math.Min(g.minLng, sample.Lngs[i]), math.Max(g.maxLng, sample.Lngs[i])
+		}
+		if math.IsInf(g.minLat, 0) {
+			return nil, errors.New("dataset has no valid coordinates")
+		}
+	}
+
+	g.latCount = int(math.Floor((g.maxLat-g.minLat)/g.step+1e-9)) + 1
+	g.lngCount = int(math.Floor((g.maxLng-g.minLng)/g.step+1e-9)) + 1
+	if g.latCount*g.lngCount > maxSpatialCells {
+		return nil, fmt.Errorf("grid too large: %d x %d cells, use a lower resolution or smaller bounds", g.latCount, g.lngCount)
+	}
+	return g, nil
+}
+
+// regrid 将水平场插值到网格，按纬度升序逐行排列
+func (g *regularGrid) regrid(field *horizontalField) [][]float64 {
+	return field.regrid(g.minLat, g.minLng, g.latCount, g.lngCount, g.step)
+}
+
+// bounds 网格范围[minLat, minLng, maxLat, maxLng]
+func (g *regularGrid) bounds() []float64 {
+	return []float64{g.minLat, g.minLng, g.maxLat, g.maxLng}
+}
+
+// info 结果中的grid字段: 起点、格距和格点数(格点为像元中心)
+func (g *regularGrid) info() map[string]interface{} {
+	return map[string]interface{}{
+		"latCount": g.latCount,
+		"lngCount": g.lngCount,
+		"latStep":  g.step,
+		"lngStep":  g.step,
+		"startLat": g.minLat,
+		"startLng": g.minLng,
+	}
+}
+
 // 执行温盐空间分布分析
 func (s *analysisService) executeTemperatureSalinitySpatial(params map[string]interface{}) (map[string]interface{}, error) {
-	// 在实际应用中，这里应该实现真正的分析逻辑
-	// 这里仅做简单演示，返回模拟数据
-	
-	// 检查必要参数
-	datasetID, _ := params["datasetId"].(string)
-	if datasetID == "" {
-		return nil, errors.New("missing required parameter: datasetId")
+	// 打开数据集
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
 	}
+	defer src.Close()
 	
-	// 获取数据集信息
-	_, err := s.datasetRepo.GetByID(datasetID)
+	// 按标准名查找温度和盐度并换算到℃和PSU，或使用参数指定的变量
+	variables, units, err := s.analysisVariables(src, params)
 	if err != nil {
-		return nil, fmt.Errorf("dataset not found: %w", err)
+		return nil, err
 	}
-	
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := spatialGrid(src, variables, units, params)
+	if err != nil {
+		return nil, err
+	}
+	withAnomaly(result, climatology)
+	return result, nil
+}
+
+// spatialGrid 提取各变量在参数date、depth指定时间和深度的水平场，并插值到参数bounds、resolution指定的规则网格
+func spatialGrid(src *analysisSource, variables map[string]*dataio.Variable, units map[string]string, params map[string]interface{}) (map[string]interface{}, error) {
 	// 提取参数
-	date, _ := params["date"].(string)
-	depth, _ := params["depth"].(string)
-	bounds, _ := params["bounds"].(string)
-	resolution, _ := params["resolution"].(string)
-	
-	// 解析边界范围
-	var minLat, minLng, maxLat, maxLng float64 = 20.0, 110.0, 25.0, 120.0
-	fmt.Sscanf(bounds, "%f,%f,%f,%f", &minLat, &minLng, &maxLat, &maxLng)
-	
-	// 计算网格大小
-	var latStep, lngStep float64
-	var latCount, lngCount int
-	
-	switch resolution {
-	case "low":
-		latStep, lngStep = 1.0, 1.0
-	case "medium":
-		latStep, lngStep = 0.5, 0.5
-	case "high":
-		latStep, lngStep = 0.1, 0.1
-	default:
-		latStep, lngStep = 0.5, 0.5
+	date, err := paramTime(params, "date")
+	if err != nil {
+		return nil, err
+	}
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
 	}
 	
-	latCount = int((maxLat - minLat) / latStep) + 1
-	lngCount = int((maxLng - minLng) / lngStep) + 1
+	// 提取水平场
+	fields := map[string]*horizontalField{}
+	names := map[string]string{}
+	var sample *horizontalField
+	for key, v := range variables {
+		field, err := extractHorizontalField(src.Source, v, date, depth)
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+		}
+		fields[key] = field
+		names[key] = v.Name
+		sample = field
+	}
 	
-	// 生成温度和盐度数据
-	temperatureData := make([][]float64, latCount)
-	salinityData := make([][]float64, latCount)
+	grid, err := newRegularGrid(params, sample)
+	if err != nil {
+		return nil, err
+	}
 	
-	for i := 0; i < latCount; i++ {
-		temperatureData[i] = make([]float64, lngCount)
-		salinityData[i] = make([]float64, lngCount)
-		
-		for j := 0; j < lngCount; j++ {
-			// 模拟温度和盐度空间分布
-			lat := minLat + float64(i)*latStep
-			// lng := minLng + float64(j)*lngStep
-			
-			// 简单模拟: 温度随纬度降低，盐度随纬度升高
-			temp := 30.0 - 0.2*(lat-minLat) + 2.0*utils.RandomFloat64(-1, 1)
-			salt := 33.0 + 0.1*(lat-minLat) + 1.0*utils.RandomFloat64(-1, 1)
-			
-			temperatureData[i][j] = temp
-			salinityData[i][j] = salt
+	data := map[string]interface{}{}
+	for key, field := range fields {
+		values := grid.regrid(field)
+		rows := make([][]interface{}, len(values))
+		for i, row := range values {
+			rows[i] = nullableSlice(row)
 		}
+		data[key] = rows
+	}
+	
+	var actualTime interface{}
+	if !sample.Time.IsZero() {
+		actualTime = sample.Time.Format(time.RFC3339)
 	}
 	
 	return map[string]interface{}{
-		"time":   date,
-		"depth":  depth,
-		"bounds": []float64{minLat, minLng, maxLat, maxLng},
-		"resolution": resolution,
-		"grid": map[string]interface{}{
-			"latCount": latCount,
-			"lngCount": lngCount,
-			"latStep":  latStep,
-			"lngStep":  lngStep,
-			"startLat": minLat,
-			"startLng": minLng,
-		},
-		"data": map[string]interface{}{
-			"temperature": temperatureData,
-			"salinity":    salinityData,
-		},
+		"time":       actualTime,
+		"depth":      nullable(sample.Depth),
+		"bounds":     grid.bounds(),
+		"resolution": grid.resolution,
+		"grid":       grid.info(),
+		"variables":  names,
+		"units":      units,
+		"data":       data,
 	}, nil
 }
 
@@ -394,7 +624,7 @@ func (s *analysisService) UpdateTask(task *models.AnalysisTask) error {
 	return s.analysisRepo.UpdateTask(task)
 }
 
-// DeleteTask 删除分析任务
+// DeleteTask 删除分析任务(连同结果移入回收站)
 func (s *analysisService) DeleteTask(id string) error {
 	// 获取任务信息
 	_, err := s.analysisRepo.GetTaskByID(id)
@@ -402,30 +632,18 @@ func (s *analysisService) DeleteTask(id string) error {
 		return fmt.Errorf("task not found: %w", err)
 	}
 	
-	// 删除关联结果
-	results, err := s.analysisRepo.ListResultsByTaskID(id)
-	if err != nil {
-		logger.Error("Failed to get results for task", "error", err, "taskId", id)
-	} else {
-		for _, result := range results {
-			if err := s.DeleteResult(result.ID); err != nil {
-				logger.Error("Failed to delete result", "error", err, "resultId", result.ID)
-			}
-		}
-	}
-	
-	// 删除结果目录
+	// 将结果目录移入回收站
 	resultDir := filepath.Join(s.resultsDir, id)
-	if err := os.RemoveAll(resultDir); err != nil && !os.IsNotExist(err) {
-		logger.Error("Failed to delete result directory", "error", err, "path", resultDir)
+	if err := s.bin.move(resultDir, s.bin.taskDir(id)); err != nil {
+		return fmt.Errorf("failed to move task results to trash: %w", err)
 	}
 	
-	// 从数据库中删除任务
+	// 软删除任务及其结果
 	return s.analysisRepo.DeleteTask(id)
 }
 
 // GetTemperatureSalinityTimeSeries 获取温盐时间序列
-func (s *analysisService) GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval string) (map[string]interface{}, error) {
+func (s *analysisService) GetTemperatureSalinityTimeSeries(datasetID, lat, lng, depth, startDate, endDate, interval, variables string, options map[string]interface{}) (map[string]interface{}, error) {
 	params := map[string]interface{}{
 		"datasetId": datasetID,
 		"lat":       lat,
@@ -434,19 +652,27 @@ func (s *analysisService) GetTemperatureSalinityTimeSeries(datasetID, lat, lng,
 		"startDate": startDate,
 		"endDate":   endDate,
 		"interval":  interval,
+		"variables": variables,
+	}
+	for k, v := range options {
+		params[k] = v
 	}
 	
 	return s.executeTemperatureSalinityTimeSeries(params)
 }
 
 // GetTemperatureSalinitySpatial 获取温盐空间分布
-func (s *analysisService) GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution string) (map[string]interface{}, error) {
+func (s *analysisService) GetTemperatureSalinitySpatial(datasetID, date, depth, bounds, resolution, variables string, anomaly map[string]interface{}) (map[string]interface{}, error) {
 	params := map[string]interface{}{
 		"datasetId":  datasetID,
 		"date":       date,
 		"depth":      depth,
 		"bounds":     bounds,
 		"resolution": resolution,
+		"variables":  variables,
+	}
+	for k, v := range anomaly {
+		params[k] = v
 	}
 	
 	return s.executeTemperatureSalinitySpatial(params)
@@ -459,6 +685,13 @@ func (s *analysisService) CreateResult(result *models.AnalysisResult) (string, e
 		result.ID = utils.GenerateID("result")
 	}
 	
+	// 补充结果文件大小
+	if result.Size == 0 && result.FilePath != "" {
+		if info, err := os.Stat(result.FilePath); err == nil {
+			result.Size = info.Size()
+		}
+	}
+	
 	// 保存结果
 	if err := s.analysisRepo.CreateResult(result); err != nil {
 		return "", fmt.Errorf("failed to create analysis result: %w", err)
@@ -477,7 +710,7 @@ func (s *analysisService) ListResultsByTaskID(taskID string) ([]*models.Analysis
 	return s.analysisRepo.ListResultsByTaskID(taskID)
 }
 
-// DeleteResult 删除分析结果
+// DeleteResult 删除分析结果(移入回收站)
 func (s *analysisService) DeleteResult(id string) error {
 	// 获取结果信息
 	result, err := s.analysisRepo.GetResultByID(id)
@@ -485,13 +718,14 @@ func (s *analysisService) DeleteResult(id string) error {
 		return fmt.Errorf("result not found: %w", err)
 	}
 	
-	// 删除结果文件
+	// 将结果文件移入回收站
 	if result.FilePath != "" {
-		if err := os.Remove(result.FilePath); err != nil && !os.IsNotExist(err) {
-			logger.Error("Failed to delete result file", "error", err, "path", result.FilePath)
+		dst := filepath.Join(s.bin.resultDir(id), filepath.Base(result.FilePath))
+		if err := s.bin.move(result.FilePath, dst); err != nil {
+			return fmt.Errorf("failed to move result to trash: %w", err)
 		}
 	}
 	
-	// 从数据库中删除
+	// 软删除数据库记录
 	return s.analysisRepo.DeleteResult(id)
-}
\ No newline at end of file
+}
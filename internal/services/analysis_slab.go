@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sinker/ssop/pkg/argo"
+	"github.com/sinker/ssop/pkg/dataio"
+)
+
+// 断面提取(analysis_section.go)和模式-观测对比(analysis_comparison.go)共用的网格读取与水平插值
+
+// gridSlab 变量在经纬度范围内某一时次的数据，按(层, 水平格点)排列；变量没有垂向坐标时只有深度为0的一层
+type gridSlab struct {
+	Values [][]float64 // Values[层][水平格点]
+	Depths []float64   // 各层深度(m)，升序
+	Lats   []float64   // 各水平格点的纬度
+	Lngs   []float64
+	Time   time.Time
+	// 规则网格的一维坐标及由(纬度下标, 经度下标)求水平格点下标的步长，非规则网格时为nil
+	AxisLats []float64
+	AxisLngs []float64
+	latStep  int
+	lngStep  int
+}
+
+// readGridSlab 读取变量第ti个时次(变量没有时间维时忽略)、深度在[minDepth, maxDepth](maxDepth为0时不限制)内、
+// 经纬度范围bbox外扩一个格点的数据，读取的元素数超过maxValues时返回错误；垂向坐标须为一维
+func readGridSlab(src *dataio.Source, v *dataio.Variable, ti int, bbox [4]float64, minDepth, maxDepth float64, maxValues int) (*gridSlab, error) {
+	zdim := src.AxisDim(v, dataio.AxisZ)
+	zc := src.Coordinate(v, dataio.AxisZ)
+	if zdim >= 0 && (zc == nil || len(zc.Dims) != 1) {
+		return nil, fmt.Errorf("%w: variable %s has no one-dimensional vertical coordinate", ErrInvalidAnalysisParams, v.Name)
+	}
+	cy, cx := src.Coordinate(v, dataio.AxisLat), src.Coordinate(v, dataio.AxisLon)
+	if cy == nil || cx == nil {
+		return nil, fmt.Errorf("%w: variable %s has no latitude/longitude coordinates", ErrInvalidAnalysisParams, v.Name)
+	}
+
+	r := volumeRange{Bounds: &bbox, MinDepth: minDepth, MaxDepth: maxDepth}
+	if maxDepth == 0 || zdim < 0 {
+		r.MaxDepth = math.Inf(1)
+	}
+	start, count, empty, err := volumeSlice(src, v, r)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return nil, fmt.Errorf("%w: no data of %s in the region and depth range", ErrInvalidAnalysisParams, v.Name)
+	}
+
+	slab := &gridSlab{}
+	ydim, xdim := src.AxisDim(v, dataio.AxisLat), src.AxisDim(v, dataio.AxisLon)
+	rectilinear := len(cy.Dims) == 1 && len(cx.Dims) == 1 && ydim >= 0 && xdim >= 0 && ydim != xdim
+	if rectilinear {
+		// 外扩一个格点，保证范围边缘可以双线性插值
+		for _, d := range []int{ydim, xdim} {
+			lo, hi := start[d]-1, start[d]+count[d]
+			if lo < 0 {
+				lo = 0
+			}
+			if hi > v.Shape[d]-1 {
+				hi = v.Shape[d] - 1
+			}
+			start[d], count[d] = lo, hi-lo+1
+		}
+	}
+	tdim := src.AxisDim(v, dataio.AxisTime)
+	if tdim >= 0 {
+		times, err := dataio.Times(src.Coordinate(v, dataio.AxisTime))
+		if err != nil {
+			return nil, err
+		}
+		if ti < 0 || ti >= len(times) {
+			return nil, fmt.Errorf("time index %d of %s out of range", ti, v.Name)
+		}
+		start[tdim], count[tdim] = ti, 1
+		slab.Time = times[ti]
+	}
+	// 经纬度和垂向以外的维度取第一个下标
+	for d, name := range v.Dims {
+		if d != zdim && d != tdim && !containsString(cy.Dims, name) && !containsString(cx.Dims, name) {
+			start[d], count[d] = 0, 1
+		}
+	}
+	n := 1
+	for _, c := range count {
+		n *= c
+	}
+	if n > maxValues {
+		return nil, fmt.Errorf("%w: selection of %s too large: %d values (max %d), narrow the region or depth range", ErrInvalidAnalysisParams, v.Name, n, maxValues)
+	}
+
+	values, err := v.Read(start, count)
+	if err != nil {
+		return nil, err
+	}
+	lats, err := readCoordinate(cy, v, start, count)
+	if err != nil {
+		return nil, err
+	}
+	lngs, err := readCoordinate(cx, v, start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	// 展开下标 i = 外层×(nz×inner) + 层×inner + 内层，没有垂向维时nz为1
+	inner, nz := 1, 1
+	for d := zdim + 1; d < len(count); d++ {
+		inner *= count[d]
+	}
+	depths := []float64{0}
+	if zdim >= 0 {
+		nz = count[zdim]
+		z, err := zc.Read([]int{start[zdim]}, []int{nz})
+		if err != nil {
+			return nil, err
+		}
+		// 压力坐标按范围中部的纬度换算为深度
+		pressure := isPressureAxis(zc)
+		reflat := (bbox[0] + bbox[2]) / 2
+		depths = make([]float64, nz)
+		for k := range depths {
+			depths[k] = math.Abs(z[k])
+			if pressure {
+				depths[k] = argo.PressureToDepth(depths[k], reflat)
+			}
+		}
+	}
+	nh := n / nz
+	horizontal := func(i int) int { return i/(nz*inner)*inner + i%inner }
+
+	order := make([]int, 0, nz)
+	for k := range depths {
+		if zdim >= 0 && (math.IsNaN(depths[k]) || depths[k] < minDepth || (maxDepth > 0 && depths[k] > maxDepth)) {
+			continue
+		}
+		order = append(order, k)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("%w: no levels of %s in the depth range", ErrInvalidAnalysisParams, v.Name)
+	}
+	sort.SliceStable(order, func(a, b int) bool { return depths[order[a]] < depths[order[b]] })
+	levelOf := make([]int, nz)
+	for k := range levelOf {
+		levelOf[k] = -1
+	}
+	slab.Depths = make([]float64, len(order))
+	slab.Values = make([][]float64, len(order))
+	for l, k := range order {
+		levelOf[k] = l
+		slab.Depths[l] = depths[k]
+		slab.Values[l] = make([]float64, nh)
+	}
+
+	slab.Lats = make([]float64, nh)
+	slab.Lngs = make([]float64, nh)
+	for i, x := range values {
+		k := i / inner % nz
+		h := horizontal(i)
+		if k == 0 {
+			slab.Lats[h], slab.Lngs[h] = lats[i], lngs[i]
+		}
+		if l := levelOf[k]; l >= 0 {
+			slab.Values[l][h] = x
+		}
+	}
+
+	if rectilinear {
+		if slab.AxisLats, err = cy.Read([]int{start[ydim]}, []int{count[ydim]}); err != nil {
+			return nil, err
+		}
+		if slab.AxisLngs, err = cx.Read([]int{start[xdim]}, []int{count[xdim]}); err != nil {
+			return nil, err
+		}
+		stride := func(dim int) int {
+			s := 1
+			for d := dim + 1; d < len(count); d++ {
+				s *= count[d]
+			}
+			return horizontal(s)
+		}
+		slab.latStep, slab.lngStep = stride(ydim), stride(xdim)
+	}
+	return slab, nil
+}
+
+// gridWeight 插值用到的水平格点及其权重
+type gridWeight struct {
+	index  int
+	weight float64
+}
+
+// bracket 坐标轴上包含x的区间[i, i+1]及x在区间内的比例，坐标轴可为降序；x超出范围时ok为false
+func bracket(axis []float64, x float64) (i int, frac float64, ok bool) {
+	if len(axis) == 1 {
+		return 0, 0, axis[0] == x
+	}
+	for i = 0; i < len(axis)-1; i++ {
+		a, b := axis[i], axis[i+1]
+		if (x >= a && x <= b) || (x <= a && x >= b) {
+			if a == b {
+				return i, 0, true
+			}
+			return i, (x - a) / (b - a), true
+		}
+	}
+	return 0, 0, false
+}
+
+// weights 位置(lat, lng)的插值权重: 规则网格按method双线性插值或取最近格点，非规则网格取最近格点；位置不在数据范围内时为nil
+func (s *gridSlab) weights(lat, lng float64, method string) []gridWeight {
+	if s.AxisLats != nil {
+		yi, fy, ok := bracket(s.AxisLats, lat)
+		if !ok {
+			return nil
+		}
+		xi, fx, ok := 0, 0.0, false
+		for _, shift := range []float64{0, 360, -360} {
+			if xi, fx, ok = bracket(s.AxisLngs, lng+shift); ok {
+				break
+			}
+		}
+		if !ok {
+			return nil
+		}
+		index := func(i, j int) int { return i*s.latStep + j*s.lngStep }
+		if method == "nearest" || len(s.AxisLats) == 1 || len(s.AxisLngs) == 1 {
+			if fy > 0.5 {
+				yi++
+			}
+			if fx > 0.5 {
+				xi++
+			}
+			return []gridWeight{{index(yi, xi), 1}}
+		}
+		return []gridWeight{
+			{index(yi, xi), (1 - fy) * (1 - fx)},
+			{index(yi, xi+1), (1 - fy) * fx},
+			{index(yi+1, xi), fy * (1 - fx)},
+			{index(yi+1, xi+1), fy * fx},
+		}
+	}
+
+	best, bi := math.Inf(1), -1
+	coslat := math.Cos(lat * math.Pi / 180)
+	for h := range s.Lats {
+		dy := s.Lats[h] - lat
+		dx := dataio.LonDistance(s.Lngs[h], lng) * coslat
+		if d := dy*dy + dx*dx; d < best {
+			best, bi = d, h
+		}
+	}
+	if bi < 0 {
+		return nil
+	}
+	return []gridWeight{{bi, 1}}
+}
+
+// sampleWeights 按权重插值一层的值: 缺测格点不参与，有效权重之和不足0.5时为缺测
+func sampleWeights(level []float64, weights []gridWeight) float64 {
+	var sum, total float64
+	for _, w := range weights {
+		if x := level[w.index]; !math.IsNaN(x) {
+			sum += w.weight * x
+			total += w.weight
+		}
+	}
+	if total < 0.5 {
+		return math.NaN()
+	}
+	return sum / total
+}
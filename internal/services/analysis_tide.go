@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/resample"
+	"github.com/sinker/ssop/pkg/tide"
+)
+
+// maxTideSeries 调和分析结果中返回的观测序列最大点数
+const maxTideSeries = 200000
+
+// maxTidePrediction 潮位预报的最大点数
+const maxTidePrediction = 100000
+
+// tideOptions 潮汐调和分析参数
+type tideOptions struct {
+	Constituents []string `json:"constituents"` // 指定的分潮，为空时按Rayleigh判据自动选取
+	Rayleigh     float64  `json:"rayleigh"`     // Rayleigh判据系数
+	Trend        bool     `json:"trend"`        // 是否同时拟合线性趋势
+	Confidence   float64  `json:"confidence"`   // 置信水平
+	MinSNR       float64  `json:"minSNR"`       // 参与重构和预报的分潮的最小信噪比
+}
+
+// parseTideOptions 读取参数constituents(逗号分隔的分潮名或auto，默认auto)、rayleigh(默认1)、trend(默认true)、
+// confidence(默认0.95)和minSNR(默认2)
+func parseTideOptions(params map[string]interface{}) (tideOptions, error) {
+	opts := tideOptions{Rayleigh: 1, Trend: paramString(params, "trend") != "false", Confidence: 0.95, MinSNR: 2}
+	if s := paramString(params, "constituents"); s != "" && !strings.EqualFold(s, "auto") {
+		for _, name := range strings.Split(s, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			c, ok := tide.Lookup(name)
+			if !ok {
+				return opts, fmt.Errorf("%w: unknown constituent %q, supported: %s", ErrInvalidAnalysisParams, name, strings.Join(tide.Names(), ", "))
+			}
+			opts.Constituents = append(opts.Constituents, c.Name)
+		}
+	}
+	for _, p := range []struct {
+		key      string
+		min, max float64
+		value    *float64
+	}{
+		{"rayleigh", 0, 10, &opts.Rayleigh},
+		{"confidence", 0.5, 0.999, &opts.Confidence},
+		{"minSNR", 0, 1000, &opts.MinSNR},
+	} {
+		x, ok, err := paramFloat(params, p.key)
+		if err != nil {
+			return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+		}
+		if !ok {
+			continue
+		}
+		if x < p.min || x > p.max {
+			return opts, fmt.Errorf("%w: %s must be between %g and %g", ErrInvalidAnalysisParams, p.key, p.min, p.max)
+		}
+		*p.value = x
+	}
+	return opts, nil
+}
+
+// tidePrediction 预报时段和步长
+type tidePrediction struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// parseTidePrediction 读取参数predictStart、predictEnd(均指定时预报)和predictStep(分钟，默认60)
+func parseTidePrediction(params map[string]interface{}) (*tidePrediction, error) {
+	start, err := paramTime(params, "predictStart")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	end, err := paramTime(params, "predictEnd")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if start.IsZero() && end.IsZero() {
+		return nil, nil
+	}
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return nil, fmt.Errorf("%w: predictStart and predictEnd must both be set and predictEnd must not be before predictStart", ErrInvalidAnalysisParams)
+	}
+	p := &tidePrediction{Start: start, End: end, Step: time.Hour}
+	minutes, ok, err := paramFloat(params, "predictStep")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if minutes < 1 || minutes != math.Trunc(minutes) {
+			return nil, fmt.Errorf("%w: predictStep must be a positive integer number of minutes", ErrInvalidAnalysisParams)
+		}
+		p.Step = time.Duration(minutes) * time.Minute
+	}
+	if n := int64(end.Sub(start)/p.Step) + 1; n > maxTidePrediction {
+		return nil, fmt.Errorf("%w: prediction has %d points (max %d), use a larger predictStep or a shorter window", ErrInvalidAnalysisParams, n, maxTidePrediction)
+	}
+	return p, nil
+}
+
+// tideVariable 调和分析的水位变量: 参数variable指定的变量，未指定时按标准名查找水位并换算到m
+func (s *analysisService) tideVariable(src *analysisSource, params map[string]interface{}) (*dataio.Variable, string, error) {
+	name := paramString(params, "variable")
+	if name == "" {
+		v, err := s.standardNames.Resolver().Resolve(src.Source, "sea_surface_height", "m")
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: no variable specified and dataset has no sea level variable", ErrInvalidAnalysisParams)
+		}
+		return v, "m", nil
+	}
+	v := src.Var(name)
+	if v == nil || v.IsText() {
+		return nil, "", fmt.Errorf("%w: variable %q not found", ErrInvalidAnalysisParams, name)
+	}
+	return v, v.Units(), nil
+}
+
+// executeTidalHarmonic 对参数lat、lng处(单站数据可省略)startDate、endDate内的水位序列做潮汐调和分析:
+// 按交点订正最小二乘拟合各分潮，返回振幅、迟角及置信区间，观测序列分解为潮汐和余水位，可选预报predictStart~predictEnd的潮位
+func (s *analysisService) executeTidalHarmonic(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseTideOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	prediction, err := parseTidePrediction(params)
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+	lat, _, err := paramFloat(params, "lat")
+	if err != nil {
+		return nil, err
+	}
+	lng, _, err := paramFloat(params, "lng")
+	if err != nil {
+		return nil, err
+	}
+	var depthParam *float64
+	if depth, ok, err := paramFloat(params, "depth"); err != nil {
+		return nil, err
+	} else if ok {
+		depthParam = &depth
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	v, units, err := s.tideVariable(src, params)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := extractPointSeries(src.Source, v, lat, lng, depthParam, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+	}
+
+	// 有效数据的时间范围决定Rayleigh判据的记录长度
+	var first, last time.Time
+	var validTimes []time.Time
+	for i, t := range ps.Times {
+		if t.IsZero() || math.IsNaN(ps.Values[i]) {
+			continue
+		}
+		validTimes = append(validTimes, t)
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	if len(validTimes) < 3 {
+		return nil, fmt.Errorf("%w: %s has %d valid values in the time range, at least 3 are required", ErrInvalidAnalysisParams, v.Name, len(validTimes))
+	}
+	step := resample.TypicalStep(validTimes)
+	constituents, excluded, err := tide.Select(opts.Constituents, last.Sub(first), step, opts.Rayleigh)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if len(constituents) == 0 {
+		return nil, fmt.Errorf("%w: no constituent can be resolved from %s of data, use a longer time range", ErrInvalidAnalysisParams, last.Sub(first))
+	}
+
+	fit, err := tide.Analyze(ps.Times, ps.Values, tide.Options{Constituents: constituents, Trend: opts.Trend, Confidence: opts.Confidence})
+	if errors.Is(err, tide.ErrInsufficientData) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	list := make([]map[string]interface{}, len(fit.Results))
+	for i, r := range fit.Results {
+		freq := r.Constituent.Frequency()
+		list[i] = map[string]interface{}{
+			"name":        r.Constituent.Name,
+			"frequency":   freq,
+			"period":      nullable(1 / freq),
+			"amplitude":   r.Amplitude,
+			"amplitudeCI": r.AmplitudeCI,
+			"phase":       r.Phase,
+			"phaseCI":     r.PhaseCI,
+			"snr":         nullable(r.SNR),
+			"significant": r.SNR >= opts.MinSNR,
+		}
+	}
+	exclusions := make([]map[string]interface{}, len(excluded))
+	for i, e := range excluded {
+		exclusions[i] = map[string]interface{}{"name": e.Name, "reason": e.Reason}
+	}
+
+	// 观测序列分解为潮汐(平均值、趋势和显著分潮之和)与余水位
+	stride := 1
+	if n := len(ps.Times); n > maxTideSeries {
+		stride = (n + maxTideSeries - 1) / maxTideSeries
+	}
+	series := make([]map[string]interface{}, 0, len(ps.Times)/stride+1)
+	for i := 0; i < len(ps.Times); i += stride {
+		t := ps.Times[i]
+		if t.IsZero() {
+			continue
+		}
+		predicted := fit.Predict(t, opts.MinSNR, true)
+		series = append(series, map[string]interface{}{
+			"timestamp": t.Format(time.RFC3339),
+			"value":     nullable(ps.Values[i]),
+			"tide":      predicted,
+			"residual":  nullable(ps.Values[i] - predicted),
+		})
+	}
+
+	result := map[string]interface{}{
+		"location": map[string]interface{}{"lat": nullable(ps.Lat), "lng": nullable(ps.Lng), "depth": nullable(ps.Depth)},
+		"variable": v.Name,
+		"units":    units,
+		"timeRange": map[string]interface{}{
+			"start": first.Format(time.RFC3339),
+			"end":   last.Format(time.RFC3339),
+		},
+		"samples":      fit.Samples,
+		"samplingStep": step.String(),
+		"mean":         fit.Mean,
+		"meanCI":       fit.MeanCI(),
+		"epoch":        fit.Epoch.Format(time.RFC3339),
+		"trend":        nil,
+		"constituents": list,
+		"excluded":     exclusions,
+		"fit": map[string]interface{}{
+			"rmsResidual":       fit.Residual,
+			"varianceExplained": fit.Explained,
+		},
+		"series":  series,
+		"options": opts,
+	}
+	if fit.HasTrend {
+		result["trend"] = map[string]interface{}{"value": fit.Trend, "ci": fit.TrendCI(), "units": units + "/year"}
+	}
+	if prediction != nil {
+		points := make([]map[string]interface{}, 0, int(prediction.End.Sub(prediction.Start)/prediction.Step)+1)
+		for t := prediction.Start; !t.After(prediction.End); t = t.Add(prediction.Step) {
+			points = append(points, map[string]interface{}{
+				"timestamp": t.Format(time.RFC3339),
+				"tide":      fit.Predict(t, opts.MinSNR, true),
+			})
+		}
+		result["prediction"] = map[string]interface{}{
+			"start": prediction.Start.Format(time.RFC3339),
+			"end":   prediction.End.Format(time.RFC3339),
+			"step":  prediction.Step.String(),
+			"data":  points,
+		}
+	}
+	return result, nil
+}
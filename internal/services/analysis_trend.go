@@ -0,0 +1,496 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sinker/ssop/pkg/resample"
+	"github.com/sinker/ssop/pkg/stats"
+)
+
+// secondsPerYear 趋势的时间单位(年，按365.2425天计)
+const secondsPerYear = 365.2425 * 86400
+
+// maxTrendSamples 单条序列参与趋势计算的最大时次数(Sen斜率需计算所有点对)
+const maxTrendSamples = 5000
+
+// maxTrendValues 逐格点趋势读取的最大元素数(格点数×时次数)
+const maxTrendValues = 20000000
+
+// maxTrendPairs 逐格点Sen斜率的最大点对总数
+const maxTrendPairs = 2000000000
+
+// trendOptions 趋势分析参数
+type trendOptions struct {
+	Alpha    float64 `json:"alpha"`    // 显著性水平
+	Seasonal bool    `json:"seasonal"` // 计算趋势前去除逐月季节循环
+}
+
+// parseTrendOptions 读取参数alpha(默认0.05)和seasonal，参数interval未指定时设为month
+func parseTrendOptions(params map[string]interface{}) (trendOptions, error) {
+	if paramString(params, "interval") == "" {
+		params["interval"] = "month"
+	}
+	opts := trendOptions{Alpha: 0.05, Seasonal: paramString(params, "seasonal") == "true"}
+	alpha, ok, err := paramFloat(params, "alpha")
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if ok {
+		if alpha <= 0 || alpha >= 1 {
+			return opts, fmt.Errorf("%w: alpha must be between 0 and 1", ErrInvalidAnalysisParams)
+		}
+		opts.Alpha = alpha
+	}
+	period, err := resample.ParsePeriod(paramString(params, "interval"), time.Time{})
+	if err != nil {
+		return opts, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if opts.Seasonal && (period.Unit == resample.Year || (period.Unit == resample.Month && period.N >= 12)) {
+		return opts, fmt.Errorf("%w: seasonal decomposition requires an interval shorter than a year", ErrInvalidAnalysisParams)
+	}
+	return opts, nil
+}
+
+// seriesTrend 单条序列的趋势统计，时间轴为距序列第一个有效时次的年数
+type seriesTrend struct {
+	start    time.Time
+	fit      stats.LinearFit
+	sen      float64
+	senBase  float64
+	mk       stats.MannKendallResult
+	seasonal []float64 // 各月的季节分量(去除季节循环时)，无数据的月份为NaN
+}
+
+// computeTrend 计算按时间升序排列的序列的最小二乘趋势、Sen斜率和Mann-Kendall检验，NaN为缺测；
+// 去除季节循环时先对原序列做线性拟合，以残差的逐月平均(去均值)作为季节分量，再对去除季节分量后的序列计算趋势
+func computeTrend(times []time.Time, values []float64, opts trendOptions) *seriesTrend {
+	t := &seriesTrend{}
+	x := make([]float64, len(values))
+	y := make([]float64, len(values))
+	for i, v := range values {
+		x[i], y[i] = math.NaN(), math.NaN()
+		if math.IsNaN(v) || times[i].IsZero() {
+			continue
+		}
+		if t.start.IsZero() {
+			t.start = times[i]
+		}
+		x[i], y[i] = times[i].Sub(t.start).Seconds()/secondsPerYear, v
+	}
+
+	if opts.Seasonal {
+		fit := stats.LinearRegression(x, y)
+		sum, count := make([]float64, 12), make([]int, 12)
+		for i := range y {
+			if math.IsNaN(y[i]) {
+				continue
+			}
+			r := y[i]
+			if !math.IsNaN(fit.Slope) {
+				r -= fit.Intercept + fit.Slope*x[i]
+			}
+			m := int(times[i].Month()) - 1
+			sum[m] += r
+			count[m]++
+		}
+		t.seasonal = make([]float64, 12)
+		mean, n := 0.0, 0
+		for m := range sum {
+			t.seasonal[m] = math.NaN()
+			if count[m] > 0 {
+				t.seasonal[m] = sum[m] / float64(count[m])
+				mean += t.seasonal[m]
+				n++
+			}
+		}
+		for m := range t.seasonal {
+			t.seasonal[m] -= mean / float64(n)
+		}
+		for i := range y {
+			if !math.IsNaN(y[i]) {
+				y[i] -= t.seasonal[times[i].Month()-1]
+			}
+		}
+	}
+
+	t.fit = stats.LinearRegression(x, y)
+	t.sen, t.senBase = stats.SensSlope(x, y)
+	t.mk = stats.MannKendall(y)
+	return t
+}
+
+// significant Mann-Kendall检验在显著性水平alpha下是否拒绝无趋势假设
+func (t *seriesTrend) significant(alpha float64) bool {
+	return t.mk.PValue < alpha
+}
+
+// summary 趋势统计结果，斜率单位为 units/year
+func (t *seriesTrend) summary(units string, alpha float64) map[string]interface{} {
+	direction := "none"
+	if t.significant(alpha) {
+		direction = "increasing"
+		if t.mk.S < 0 {
+			direction = "decreasing"
+		}
+	}
+	var start interface{}
+	if !t.start.IsZero() {
+		start = t.start.Format(time.RFC3339)
+	}
+	result := map[string]interface{}{
+		"samples":       t.mk.N,
+		"start":         start,
+		"slope":         nullable(t.fit.Slope),
+		"intercept":     nullable(t.fit.Intercept),
+		"slopeStdErr":   nullable(t.fit.SlopeStdErr),
+		"rSquared":      nullable(t.fit.RSquared),
+		"pValue":        nullable(t.fit.PValue),
+		"sensSlope":     nullable(t.sen),
+		"sensIntercept": nullable(t.senBase),
+		"mannKendall": map[string]interface{}{
+			"s":      nullable(t.mk.S),
+			"z":      nullable(t.mk.Z),
+			"tau":    nullable(t.mk.Tau),
+			"pValue": nullable(t.mk.PValue),
+		},
+		"significant": t.significant(alpha),
+		"direction":   direction,
+		"slopeUnits":  trendUnits(units),
+	}
+	if t.seasonal != nil {
+		result["seasonalCycle"] = nullableSlice(t.seasonal)
+	}
+	return result
+}
+
+// components 序列分解为线性趋势、季节分量和残差，与times逐一对应
+func (t *seriesTrend) components(times []time.Time, values []float64) map[string]interface{} {
+	trend := make([]float64, len(values))
+	seasonal := make([]float64, len(values))
+	residual := make([]float64, len(values))
+	for i, v := range values {
+		trend[i], seasonal[i], residual[i] = math.NaN(), math.NaN(), math.NaN()
+		if times[i].IsZero() || t.start.IsZero() {
+			continue
+		}
+		x := times[i].Sub(t.start).Seconds() / secondsPerYear
+		trend[i] = t.fit.Intercept + t.fit.Slope*x
+		seasonal[i] = t.seasonal[times[i].Month()-1]
+		residual[i] = v - trend[i] - seasonal[i]
+	}
+	return map[string]interface{}{
+		"trend":    nullableSlice(trend),
+		"seasonal": nullableSlice(seasonal),
+		"residual": nullableSlice(residual),
+	}
+}
+
+// trendUnits 趋势的单位
+func trendUnits(units string) string {
+	if units == "" || units == "1" {
+		return "1/year"
+	}
+	return units + "/year"
+}
+
+// seriesColumn 从时间序列结果中取出一个量的时间和数值，null为NaN
+func seriesColumn(series []map[string]interface{}, key string) ([]time.Time, []float64) {
+	times := make([]time.Time, len(series))
+	values := make([]float64, len(series))
+	for i, point := range series {
+		if s, ok := point["timestamp"].(string); ok {
+			times[i], _ = time.Parse(time.RFC3339, s)
+		}
+		values[i] = math.NaN()
+		if x, ok := number(point[key]); ok {
+			values[i] = x
+		}
+	}
+	return times, values
+}
+
+// executeTrendTimeSeries 参数lat、lng、depth指定位置的时间序列及各量的趋势统计
+func (s *analysisService) executeTrendTimeSeries(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseTrendOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, units, err := s.analysisVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+	result, err := pointTimeSeries(src, variables, units, params)
+	if err != nil {
+		return nil, err
+	}
+	withAnomaly(result, climatology)
+
+	series, _ := result["series"].([]map[string]interface{})
+	if len(series) > maxTrendSamples {
+		return nil, fmt.Errorf("%w: too many samples (%d, max %d), use a coarser interval or shorter time range",
+			ErrInvalidAnalysisParams, len(series), maxTrendSamples)
+	}
+	trends := map[string]interface{}{}
+	decomposition := map[string]interface{}{}
+	for key := range variables {
+		times, values := seriesColumn(series, key)
+		t := computeTrend(times, values, opts)
+		trends[key] = t.summary(units[key], opts.Alpha)
+		if opts.Seasonal {
+			decomposition[key] = t.components(times, values)
+		}
+	}
+	result["trend"] = opts
+	result["trends"] = trends
+	if opts.Seasonal {
+		result["decomposition"] = decomposition
+	}
+	return result, nil
+}
+
+// trendBin 逐格点趋势的一个重采样周期: 周期内各时次的网格场求平均
+type trendBin struct {
+	time  time.Time
+	sum   []float64
+	count []int32
+}
+
+// checkTrendSize 检查逐格点趋势的数据量和Sen斜率的点对数
+func checkTrendSize(cells, samples int) error {
+	if cells*samples > maxTrendValues {
+		return fmt.Errorf("%w: too many values (%d cells x %d samples, max %d), use a lower resolution, smaller bounds or a coarser interval",
+			ErrInvalidAnalysisParams, cells, samples, maxTrendValues)
+	}
+	if pairs := int64(cells) * int64(samples) * int64(samples-1) / 2; pairs > maxTrendPairs {
+		return fmt.Errorf("%w: too many samples per cell (%d), use a coarser interval or shorter time range",
+			ErrInvalidAnalysisParams, samples)
+	}
+	return nil
+}
+
+// executeTrendSpatial 参数startDate、endDate时间范围内逐格点的趋势，结果为规则网格上的斜率、p值和显著性掩码
+func (s *analysisService) executeTrendSpatial(params map[string]interface{}) (map[string]interface{}, error) {
+	opts, err := parseTrendOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	startDate, err := paramTime(params, "startDate")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := paramTime(params, "endDate")
+	if err != nil {
+		return nil, err
+	}
+	depth, _, err := paramFloat(params, "depth")
+	if err != nil {
+		return nil, err
+	}
+	interval := paramString(params, "interval")
+	resampling, err := resampleOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	if resampling.Reducer.Name != "mean" {
+		return nil, fmt.Errorf("%w: spatial trends only support the mean reducer", ErrInvalidAnalysisParams)
+	}
+	binStart := func(t time.Time) time.Time {
+		if resampling.Period.IsZero() {
+			return t
+		}
+		return resampling.Period.Start(t)
+	}
+
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	variables, units, err := s.analysisVariables(src, params)
+	if err != nil {
+		return nil, err
+	}
+	climatology, err := s.applyAnomaly(src, variables, params)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var grid *regularGrid
+	var actualDepth float64
+	var samples int
+	var first, last time.Time
+	data := map[string]interface{}{}
+	dataUnits := map[string]string{}
+	names := map[string]string{}
+	for _, key := range keys {
+		v := variables[key]
+		_, _, _, times, err := src.TimeRange(v, startDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+		}
+
+		// 按统计间隔分段
+		var bins []*trendBin
+		index := map[time.Time]*trendBin{}
+		for _, t := range times {
+			if t.IsZero() {
+				continue
+			}
+			bt := binStart(t)
+			if index[bt] == nil {
+				index[bt] = &trendBin{time: bt}
+				bins = append(bins, index[bt])
+			}
+		}
+		if len(bins) < 3 {
+			return nil, fmt.Errorf("%w: %s has %d samples in the time range, at least 3 are required", ErrInvalidAnalysisParams, v.Name, len(bins))
+		}
+		sort.Slice(bins, func(i, j int) bool { return bins[i].time.Before(bins[j].time) })
+
+		checked := false
+		for _, t := range times {
+			if t.IsZero() {
+				continue
+			}
+			field, err := extractHorizontalField(src.Source, v, t, depth)
+			if err != nil {
+				return nil, fmt.Errorf("extract %s: %w", v.Name, err)
+			}
+			if grid == nil {
+				if grid, err = newRegularGrid(params, field); err != nil {
+					return nil, err
+				}
+				actualDepth = field.Depth
+			}
+			cells := grid.latCount * grid.lngCount
+			if !checked {
+				if err := checkTrendSize(cells, len(bins)); err != nil {
+					return nil, err
+				}
+				checked = true
+			}
+
+			b := index[binStart(t)]
+			if b.sum == nil {
+				b.sum, b.count = make([]float64, cells), make([]int32, cells)
+			}
+			for i, row := range grid.regrid(field) {
+				for j, x := range row {
+					if !math.IsNaN(x) {
+						b.sum[i*grid.lngCount+j] += x
+						b.count[i*grid.lngCount+j]++
+					}
+				}
+			}
+		}
+
+		// 逐格点计算趋势
+		binTimes := make([]time.Time, len(bins))
+		expected := make([]int, len(bins))
+		step, cal := resample.TypicalStep(times), src.Calendar(v)
+		for i, b := range bins {
+			binTimes[i] = b.time
+			expected[i] = resampling.Period.Expected(b.time, step, cal)
+		}
+		slope := make([][]float64, grid.latCount)
+		sen := make([][]float64, grid.latCount)
+		pValue := make([][]float64, grid.latCount)
+		mask := make([][]float64, grid.latCount)
+		series := make([]float64, len(bins))
+		for i := range slope {
+			slope[i] = make([]float64, grid.lngCount)
+			sen[i] = make([]float64, grid.lngCount)
+			pValue[i] = make([]float64, grid.lngCount)
+			mask[i] = make([]float64, grid.lngCount)
+			for j := range slope[i] {
+				cell := i*grid.lngCount + j
+				for k, b := range bins {
+					series[k] = math.NaN()
+					if b.count != nil && b.count[cell] > 0 && resampling.Accept(int(b.count[cell]), expected[k]) {
+						series[k] = b.sum[cell] / float64(b.count[cell])
+					}
+				}
+				t := computeTrend(binTimes, series, opts)
+				slope[i][j], sen[i][j], pValue[i][j] = t.fit.Slope, t.sen, t.mk.PValue
+				mask[i][j] = math.NaN()
+				if !math.IsNaN(t.mk.PValue) {
+					mask[i][j] = 0
+					if t.significant(opts.Alpha) {
+						mask[i][j] = 1
+					}
+				}
+			}
+		}
+
+		for suffix, values := range map[string][][]float64{"slope": slope, "sensSlope": sen, "pValue": pValue, "significant": mask} {
+			rows := make([][]interface{}, len(values))
+			for i, row := range values {
+				rows[i] = nullableSlice(row)
+			}
+			data[key+"_"+suffix] = rows
+		}
+		dataUnits[key+"_slope"] = trendUnits(units[key])
+		dataUnits[key+"_sensSlope"] = trendUnits(units[key])
+		dataUnits[key+"_pValue"] = "1"
+		dataUnits[key+"_significant"] = "1"
+		names[key] = v.Name
+		if len(bins) > samples {
+			samples = len(bins)
+		}
+		if first.IsZero() || bins[0].time.Before(first) {
+			first = bins[0].time
+		}
+		if last.IsZero() || bins[len(bins)-1].time.After(last) {
+			last = bins[len(bins)-1].time
+		}
+	}
+
+	result := map[string]interface{}{
+		"timeRange": map[string]interface{}{
+			"start": first.Format(time.RFC3339),
+			"end":   last.Format(time.RFC3339),
+		},
+		"interval":   interval,
+		"samples":    samples,
+		"depth":      nullable(actualDepth),
+		"bounds":     grid.bounds(),
+		"resolution": grid.resolution,
+		"grid":       grid.info(),
+		"trend":      opts,
+		"variables":  names,
+		"units":      dataUnits,
+		"data":       data,
+	}
+	withAnomaly(result, climatology)
+	return result, nil
+}
+
+// GetTrendTimeSeries 获取指定位置时间序列的趋势统计
+func (s *analysisService) GetTrendTimeSeries(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeTrendTimeSeries(params)
+}
+
+// GetTrendSpatial 获取逐格点趋势的空间分布
+func (s *analysisService) GetTrendSpatial(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeTrendSpatial(params)
+}
@@ -0,0 +1,572 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/gsw"
+	"github.com/sinker/ssop/pkg/logger"
+)
+
+// ErrInvalidAnalysisParams 分析参数无效
+var ErrInvalidAnalysisParams = errors.New("invalid analysis parameters")
+
+const (
+	// waterMassSetting 水团定义表的系统设置键，值为WaterMass数组的JSON
+	waterMassSetting = "analysis.water_masses"
+	// maxTSValues T-S图单个变量读取的最大元素数
+	maxTSValues = 20000000
+	// defaultTSPoints 散点模式默认返回的最大点数
+	defaultTSPoints = 10000
+	// maxTSPoints 散点模式允许返回的最大点数
+	maxTSPoints = 100000
+	// defaultTSBins 密度模式未指定间隔时每个轴的分箱数
+	defaultTSBins = 100
+	// maxTSBins 密度模式每个轴的最大分箱数
+	maxTSBins = 1000
+	// maxIsopycnals 等密度线的最大条数
+	maxIsopycnals = 100
+	// isopycnalPoints 每条等密度线的采样点数
+	isopycnalPoints = 50
+)
+
+// WaterMass 水团定义，各范围为[min, max]闭区间，未设置的范围不限制
+type WaterMass struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Temperature *[2]float64 `json:"temperature,omitempty"` // 位温(°C)
+	Salinity    *[2]float64 `json:"salinity,omitempty"`    // 实用盐度
+	SigmaTheta  *[2]float64 `json:"sigmaTheta,omitempty"`  // 位密(kg/m3)
+	Depth       *[2]float64 `json:"depth,omitempty"`       // 深度(m)
+}
+
+// defaultWaterMasses 内置的西北太平洋及南海主要水团，范围为文献中的近似值，
+// 实际使用时应通过系统设置按研究海域配置
+var defaultWaterMasses = []WaterMass{
+	{Name: "SW", Description: "表层水", Temperature: &[2]float64{25, 35}, Salinity: &[2]float64{30, 34.4}, Depth: &[2]float64{0, 100}},
+	{Name: "NPTW", Description: "北太平洋热带水(次表层高盐水)", Temperature: &[2]float64{15, 28}, Salinity: &[2]float64{34.6, 35.2}, SigmaTheta: &[2]float64{22.5, 25.5}},
+	{Name: "NPIW", Description: "北太平洋中层水(中层低盐水)", Temperature: &[2]float64{5, 12}, Salinity: &[2]float64{33.8, 34.4}, SigmaTheta: &[2]float64{26.5, 27.0}},
+	{Name: "DW", Description: "深层水", Temperature: &[2]float64{0, 4}, Salinity: &[2]float64{34.5, 34.75}, SigmaTheta: &[2]float64{27.4, 28}},
+}
+
+// match 判断一个点是否属于该水团
+func (w *WaterMass) match(theta, salinity, sigma, depth float64) bool {
+	in := func(r *[2]float64, x float64) bool { return r == nil || (x >= r[0] && x <= r[1]) }
+	return in(w.Temperature, theta) && in(w.Salinity, salinity) && in(w.SigmaTheta, sigma) && in(w.Depth, depth)
+}
+
+// validate 检查水团定义
+func (w *WaterMass) validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return errors.New("water mass name is required")
+	}
+	for key, r := range map[string]*[2]float64{"temperature": w.Temperature, "salinity": w.Salinity, "sigmaTheta": w.SigmaTheta, "depth": w.Depth} {
+		if r != nil && !(r[0] <= r[1]) {
+			return fmt.Errorf("water mass %s: invalid %s range", w.Name, key)
+		}
+	}
+	return nil
+}
+
+// waterMasses 水团定义表: 优先使用参数waterMasses，其次系统设置，否则使用内置定义
+func (s *analysisService) waterMasses(params map[string]interface{}) ([]WaterMass, error) {
+	var raw []byte
+	switch v := params["waterMasses"].(type) {
+	case nil:
+	case string:
+		if v != "" {
+			raw = []byte(v)
+		}
+	default:
+		raw, _ = json.Marshal(v)
+	}
+	if raw != nil {
+		var masses []WaterMass
+		if err := json.Unmarshal(raw, &masses); err != nil {
+			return nil, fmt.Errorf("%w: invalid waterMasses: %v", ErrInvalidAnalysisParams, err)
+		}
+		for i := range masses {
+			if err := masses[i].validate(); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+			}
+		}
+		return masses, nil
+	}
+
+	if s.systemService != nil {
+		if setting, err := s.systemService.GetSetting(waterMassSetting); err == nil && setting != "" {
+			var masses []WaterMass
+			valid := json.Unmarshal([]byte(setting), &masses) == nil
+			for i := 0; valid && i < len(masses); i++ {
+				valid = masses[i].validate() == nil
+			}
+			if valid {
+				return masses, nil
+			}
+			logger.Error("Invalid water mass setting", "key", waterMassSetting)
+		}
+	}
+	return defaultWaterMasses, nil
+}
+
+// tsPoint T-S图上的一个点
+type tsPoint struct {
+	theta, salinity, sigma, depth, lat, lng float64
+}
+
+// GetTSDiagram 获取T-S图数据
+func (s *analysisService) GetTSDiagram(params map[string]interface{}) (map[string]interface{}, error) {
+	return s.executeTSDiagram(params)
+}
+
+// executeTSDiagram T-S图分析，参数: datasetId、bounds、startDate、endDate、minDepth、maxDepth、
+// mode(points散点或density二维分箱)、maxPoints、tStep、sStep、isopycnals(逗号分隔的σθ)、
+// classify、waterMasses(水团定义JSON)、qcFlags
+func (s *analysisService) executeTSDiagram(params map[string]interface{}) (map[string]interface{}, error) {
+	r := volumeRange{MaxDepth: math.Inf(1)}
+	bounds, hasBounds, err := paramBounds(params, "bounds")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if hasBounds {
+		r.Bounds = &bounds
+	}
+	if r.From, err = paramTime(params, "startDate"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if r.To, err = paramTime(params, "endDate"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	}
+	if len(paramString(params, "endDate")) == len("2006-01-02") {
+		r.To = r.To.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return nil, fmt.Errorf("%w: startDate is after endDate", ErrInvalidAnalysisParams)
+	}
+	if v, ok, err := paramFloat(params, "minDepth"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		r.MinDepth = v
+	}
+	if v, ok, err := paramFloat(params, "maxDepth"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAnalysisParams, err)
+	} else if ok {
+		r.MaxDepth = v
+	}
+	if r.MinDepth < 0 || r.MinDepth > r.MaxDepth {
+		return nil, fmt.Errorf("%w: invalid depth range", ErrInvalidAnalysisParams)
+	}
+
+	mode := paramString(params, "mode")
+	if mode == "" {
+		mode = "points"
+	}
+	if mode != "points" && mode != "density" {
+		return nil, fmt.Errorf("%w: unsupported mode %q", ErrInvalidAnalysisParams, mode)
+	}
+	maxPoints := defaultTSPoints
+	if v := paramString(params, "maxPoints"); v != "" {
+		if maxPoints, err = strconv.Atoi(v); err != nil || maxPoints < 1 || maxPoints > maxTSPoints {
+			return nil, fmt.Errorf("%w: maxPoints must be between 1 and %d", ErrInvalidAnalysisParams, maxTSPoints)
+		}
+	}
+	tStep, _, err := paramFloat(params, "tStep")
+	if err != nil || tStep < 0 {
+		return nil, fmt.Errorf("%w: invalid tStep", ErrInvalidAnalysisParams)
+	}
+	sStep, _, err := paramFloat(params, "sStep")
+	if err != nil || sStep < 0 {
+		return nil, fmt.Errorf("%w: invalid sStep", ErrInvalidAnalysisParams)
+	}
+	levels, err := parseIsopycnals(paramString(params, "isopycnals"))
+	if err != nil {
+		return nil, err
+	}
+	classify := paramString(params, "classify") == "true"
+	var masses []WaterMass
+	if classify {
+		if masses, err = s.waterMasses(params); err != nil {
+			return nil, err
+		}
+	}
+
+	// 打开数据集并提取温度、盐度
+	src, err := s.openDataset(params)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	resolver := s.standardNames.Resolver()
+	tv, err := resolver.Resolve(src.Source, "sea_water_temperature", "degC")
+	if err != nil {
+		return nil, fmt.Errorf("dataset has no temperature variable: %w", err)
+	}
+	sv, err := resolver.Resolve(src.Source, "sea_water_salinity", "psu")
+	if err != nil {
+		return nil, fmt.Errorf("dataset has no salinity variable: %w", err)
+	}
+	if strings.Join(tv.Dims, ",") != strings.Join(sv.Dims, ",") {
+		return nil, fmt.Errorf("temperature %s and salinity %s are on different grids", tv.Name, sv.Name)
+	}
+
+	temp, err := extractVolume(src.Source, tv, r, maxTSValues)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", tv.Name, err)
+	}
+	salt, err := extractVolume(src.Source, sv, r, maxTSValues)
+	if err != nil {
+		return nil, fmt.Errorf("extract %s: %w", sv.Name, err)
+	}
+
+	// 按读取范围内的下标配对温度和盐度
+	saltAt := make(map[int]float64, len(salt.Index))
+	for k, i := range salt.Index {
+		saltAt[i] = salt.Values[k]
+	}
+	points := make([]tsPoint, 0, len(temp.Index))
+	for k, i := range temp.Index {
+		sp, ok := saltAt[i]
+		if !ok {
+			continue
+		}
+		sa := gsw.SAFromSP(sp, temp.Pressures[k], temp.Lngs[k], temp.Lats[k])
+		theta := gsw.Pt0FromT(sa, temp.Values[k], temp.Pressures[k])
+		points = append(points, tsPoint{
+			theta:    theta,
+			salinity: sp,
+			sigma:    gsw.Sigma0(sa, gsw.CTFromPt(sa, theta)),
+			depth:    temp.Depths[k],
+			lat:      temp.Lats[k],
+			lng:      temp.Lngs[k],
+		})
+	}
+
+	result := map[string]interface{}{
+		"datasetId":       src.dataset.ID,
+		"variables":       map[string]string{"temperature": tv.Name, "salinity": sv.Name},
+		"mode":            mode,
+		"count":           len(points),
+		"temperatureType": "potential",
+		"equationOfState": "TEOS-10",
+		"units":           map[string]string{"temperature": "degC", "salinity": "psu", "sigmaTheta": "kg/m3", "depth": "m"},
+		"depthRange":      []interface{}{r.MinDepth, nullable(r.MaxDepth)},
+	}
+	if hasBounds {
+		result["bounds"] = bounds
+	}
+	if len(points) == 0 {
+		result["range"] = nil
+		result["isopycnals"] = []interface{}{}
+		if mode == "points" {
+			result["sampled"], result["points"] = false, tsPointColumns(nil, nil, nil)
+		} else {
+			result["bins"] = nil
+		}
+		if classify {
+			result["waterMasses"], result["unclassified"] = waterMassSummary(masses, nil, nil), 0
+		}
+		return result, nil
+	}
+
+	minS, maxS, minT, maxT := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minS, maxS = math.Min(minS, p.salinity), math.Max(maxS, p.salinity)
+		minT, maxT = math.Min(minT, p.theta), math.Max(maxT, p.theta)
+	}
+	result["range"] = map[string]float64{
+		"minSalinity":    roundTo(minS, 4),
+		"maxSalinity":    roundTo(maxS, 4),
+		"minTemperature": roundTo(minT, 4),
+		"maxTemperature": roundTo(maxT, 4),
+	}
+
+	var labels []int
+	if classify {
+		labels = make([]int, len(points))
+		unclassified := 0
+		for i, p := range points {
+			labels[i] = -1
+			for m := range masses {
+				if masses[m].match(p.theta, p.salinity, p.sigma, p.depth) {
+					labels[i] = m
+					break
+				}
+			}
+			if labels[i] < 0 {
+				unclassified++
+			}
+		}
+		result["waterMasses"] = waterMassSummary(masses, points, labels)
+		result["unclassified"] = unclassified
+	}
+
+	if mode == "points" {
+		idx := sampleIndices(len(points), maxPoints)
+		result["sampled"] = len(idx) < len(points)
+		result["points"] = tsPointColumns(points, idx, waterMassNames(masses, labels))
+	} else {
+		bins, err := tsDensityBins(points, minS, maxS, minT, maxT, sStep, tStep)
+		if err != nil {
+			return nil, err
+		}
+		result["bins"] = bins
+	}
+
+	// 等密度线覆盖数据范围并向外扩展一个边距
+	padS, padT := math.Max((maxS-minS)*0.05, 0.05), math.Max((maxT-minT)*0.05, 0.5)
+	result["isopycnals"] = isopycnalLines(levels, minS-padS, maxS+padS, minT-padT, maxT+padT)
+	return result, nil
+}
+
+// parseIsopycnals 解析逗号分隔的σθ等值线，为空时返回nil表示按数据范围自动选取
+func parseIsopycnals(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var levels []float64
+	for _, part := range strings.Split(s, ",") {
+		x, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("%w: invalid isopycnal %q", ErrInvalidAnalysisParams, part)
+		}
+		levels = append(levels, x)
+	}
+	if len(levels) > maxIsopycnals {
+		return nil, fmt.Errorf("%w: at most %d isopycnals", ErrInvalidAnalysisParams, maxIsopycnals)
+	}
+	sort.Float64s(levels)
+	return levels, nil
+}
+
+// isopycnalLines 在盐度[minS, maxS]、位温[minT, maxT]范围内计算σθ等值线；
+// levels为nil时按范围四角的σθ取间隔为0.5的等值线(超过maxIsopycnals条时加倍间隔)
+func isopycnalLines(levels []float64, minS, maxS, minT, maxT float64) []map[string]interface{} {
+	if levels == nil {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, sp := range []float64{minS, maxS} {
+			for _, t := range []float64{minT, maxT} {
+				sigma := sigmaThetaFromPt(sp, t)
+				lo, hi = math.Min(lo, sigma), math.Max(hi, sigma)
+			}
+		}
+		step := 0.5
+		for (hi-lo)/step > maxIsopycnals {
+			step *= 2
+		}
+		for x := math.Ceil(lo/step) * step; x <= hi; x += step {
+			levels = append(levels, roundTo(x, 4))
+		}
+	}
+
+	lines := make([]map[string]interface{}, 0, len(levels))
+	for _, level := range levels {
+		var sal, temp []float64
+		for i := 0; i < isopycnalPoints; i++ {
+			sp := minS + (maxS-minS)*float64(i)/float64(isopycnalPoints-1)
+			t := ptAtSigmaTheta(sp, level, minT, maxT)
+			if math.IsNaN(t) {
+				continue
+			}
+			sal = append(sal, roundTo(sp, 4))
+			temp = append(temp, roundTo(t, 4))
+		}
+		if len(sal) < 2 {
+			continue
+		}
+		lines = append(lines, map[string]interface{}{
+			"sigmaTheta":  level,
+			"salinity":    sal,
+			"temperature": temp,
+		})
+	}
+	return lines
+}
+
+// sigmaThetaFromPt 实用盐度sp、位温pt的海水按TEOS-10计算的位密σθ(即σ0，kg/m3)，绝对盐度不含δSA
+func sigmaThetaFromPt(sp, pt float64) float64 {
+	sa := gsw.SAFromSP(sp, 0, 0, 0)
+	return gsw.Sigma0(sa, gsw.CTFromPt(sa, pt))
+}
+
+// ptAtSigmaTheta 求实用盐度sp下位密为sigma(kg/m3)的位温(°C)，在[tmin, tmax]内二分求解，区间内无解时返回NaN
+func ptAtSigmaTheta(sp, sigma, tmin, tmax float64) float64 {
+	f := func(t float64) float64 { return sigmaThetaFromPt(sp, t) - sigma }
+	lo, hi := tmin, tmax
+	flo, fhi := f(lo), f(hi)
+	if math.IsNaN(flo) || math.IsNaN(fhi) || flo*fhi > 0 {
+		return math.NaN()
+	}
+	for i := 0; i < 60 && hi-lo > 1e-6; i++ {
+		mid := (lo + hi) / 2
+		fm := f(mid)
+		if fm == 0 {
+			return mid
+		}
+		if (fm > 0) == (flo > 0) {
+			lo, flo = mid, fm
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// sampleIndices 从n个点中等间隔抽取不超过max个点的下标
+func sampleIndices(n, max int) []int {
+	if n <= max {
+		idx := make([]int, n)
+		for i := range idx {
+			idx[i] = i
+		}
+		return idx
+	}
+	idx := make([]int, max)
+	for k := range idx {
+		idx[k] = int(int64(k) * int64(n) / int64(max))
+	}
+	return idx
+}
+
+// tsPointColumns 按列输出散点，names为各点的水团名(未分类时为nil)
+func tsPointColumns(points []tsPoint, idx []int, names []interface{}) map[string]interface{} {
+	sal := make([]float64, len(idx))
+	temp := make([]float64, len(idx))
+	sigma := make([]float64, len(idx))
+	depth := make([]float64, len(idx))
+	lat := make([]interface{}, len(idx))
+	lng := make([]interface{}, len(idx))
+	for k, i := range idx {
+		p := points[i]
+		sal[k], temp[k] = roundTo(p.salinity, 4), roundTo(p.theta, 4)
+		sigma[k], depth[k] = roundTo(p.sigma, 4), roundTo(p.depth, 2)
+		lat[k], lng[k] = nullable(p.lat), nullable(p.lng)
+	}
+	out := map[string]interface{}{
+		"salinity":    sal,
+		"temperature": temp,
+		"sigmaTheta":  sigma,
+		"depth":       depth,
+		"lat":         lat,
+		"lng":         lng,
+	}
+	if names != nil {
+		picked := make([]interface{}, len(idx))
+		for k, i := range idx {
+			picked[k] = names[i]
+		}
+		out["waterMass"] = picked
+	}
+	return out
+}
+
+// waterMassNames 各点的水团名，未归类的点为nil
+func waterMassNames(masses []WaterMass, labels []int) []interface{} {
+	if labels == nil {
+		return nil
+	}
+	names := make([]interface{}, len(labels))
+	for i, m := range labels {
+		if m >= 0 {
+			names[i] = masses[m].Name
+		}
+	}
+	return names
+}
+
+// waterMassSummary 各水团的点数、占比及平均位温、盐度和深度
+func waterMassSummary(masses []WaterMass, points []tsPoint, labels []int) []map[string]interface{} {
+	type acc struct{ n, t, s, z float64 }
+	sums := make([]acc, len(masses))
+	for i, m := range labels {
+		if m < 0 {
+			continue
+		}
+		p := points[i]
+		sums[m].n++
+		sums[m].t += p.theta
+		sums[m].s += p.salinity
+		sums[m].z += p.depth
+	}
+
+	out := make([]map[string]interface{}, len(masses))
+	for m, w := range masses {
+		a := sums[m]
+		item := map[string]interface{}{
+			"name":            w.Name,
+			"description":     w.Description,
+			"count":           int(a.n),
+			"fraction":        0.0,
+			"meanTemperature": nil,
+			"meanSalinity":    nil,
+			"meanDepth":       nil,
+		}
+		if a.n > 0 {
+			item["fraction"] = roundTo(a.n/float64(len(points)), 4)
+			item["meanTemperature"] = roundTo(a.t/a.n, 4)
+			item["meanSalinity"] = roundTo(a.s/a.n, 4)
+			item["meanDepth"] = roundTo(a.z/a.n, 2)
+		}
+		out[m] = item
+	}
+	return out
+}
+
+// tsDensityBins 在T-S空间按盐度间隔sStep、位温间隔tStep统计点数，间隔为0时每轴分defaultTSBins箱
+func tsDensityBins(points []tsPoint, minS, maxS, minT, maxT, sStep, tStep float64) (map[string]interface{}, error) {
+	axis := func(lo, hi, step float64) (float64, float64, int) {
+		if step == 0 {
+			step = (hi - lo) / defaultTSBins
+			if step == 0 {
+				step = 1
+			}
+			return lo, step, defaultTSBins
+		}
+		start := math.Floor(lo/step) * step
+		return start, step, int(math.Floor((hi-start)/step)) + 1
+	}
+	s0, sStep, ns := axis(minS, maxS, sStep)
+	t0, tStep, nt := axis(minT, maxT, tStep)
+	if ns > maxTSBins || nt > maxTSBins {
+		return nil, fmt.Errorf("%w: too many bins (%d x %d), at most %d per axis", ErrInvalidAnalysisParams, nt, ns, maxTSBins)
+	}
+
+	counts := make([][]int, nt)
+	for i := range counts {
+		counts[i] = make([]int, ns)
+	}
+	clamp := func(i, n int) int {
+		if i >= n {
+			return n - 1
+		}
+		if i < 0 {
+			return 0
+		}
+		return i
+	}
+	maxCount := 0
+	for _, p := range points {
+		i := clamp(int(math.Floor((p.theta-t0)/tStep)), nt)
+		j := clamp(int(math.Floor((p.salinity-s0)/sStep)), ns)
+		counts[i][j]++
+		if counts[i][j] > maxCount {
+			maxCount = counts[i][j]
+		}
+	}
+	return map[string]interface{}{
+		"startSalinity":    s0,
+		"startTemperature": t0,
+		"salinityStep":     sStep,
+		"temperatureStep":  tStep,
+		"salinityCount":    ns,
+		"temperatureCount": nt,
+		"maxCount":         maxCount,
+		"counts":           counts,
+	}, nil
+}
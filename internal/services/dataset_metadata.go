@@ -0,0 +1,603 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+)
+
+// 元数据导出格式
+const (
+	MetadataFormatDataCite = "datacite"
+	MetadataFormatISO19115 = "iso19115"
+	MetadataFormatBibTeX   = "bibtex"
+	MetadataFormatRIS      = "ris"
+	MetadataFormatJSONLD   = "jsonld"
+)
+
+// 元数据相关错误
+var (
+	ErrUnsupportedMetadataFormat = errors.New("不支持的元数据格式")
+	ErrDatasetNotFound           = errors.New("数据集不存在")
+)
+
+// defaultPublisher 数据集未指定发布者时使用的默认值
+const defaultPublisher = "智慧海洋数据分析平台"
+
+// citationAuthor 引用中的作者信息，元数据公开发布，不包含账户邮箱
+type citationAuthor struct {
+	Name        string
+	Affiliation string
+}
+
+// citationRecord 生成引用元数据所需的信息
+type citationRecord struct {
+	Dataset   *models.Dataset
+	Author    citationAuthor
+	Publisher string
+	Year      int
+	URL       string
+	Bounds    [4]float64
+	HasBounds bool
+	Variables []models.VariableInfo
+	Keywords  []string
+}
+
+// newCitationRecord 根据数据集构造引用信息
+func newCitationRecord(dataset *models.Dataset, author citationAuthor, baseURL string) *citationRecord {
+	record := &citationRecord{
+		Dataset:   dataset,
+		Author:    author,
+		Publisher: dataset.Publisher,
+		Year:      time.Now().Year(),
+		URL:       fmt.Sprintf("%s/api/v1/datasets/%s/landing", baseURL, dataset.ID),
+		Variables: dataset.VariableList(),
+		Keywords:  dataset.TagList(),
+	}
+	if record.Publisher == "" {
+		record.Publisher = defaultPublisher
+	}
+	if record.Author.Name == "" {
+		record.Author.Name = record.Publisher
+	}
+	if dataset.CreatedAt != nil {
+		record.Year = dataset.CreatedAt.Year()
+	}
+	record.Bounds, record.HasBounds = dataset.Bounds()
+	return record
+}
+
+// renderMetadata 将数据集渲染为指定格式，返回内容和Content-Type
+func renderMetadata(record *citationRecord, format string) ([]byte, string, error) {
+	switch strings.ToLower(format) {
+	case MetadataFormatDataCite:
+		data, err := executeTextTemplate(dataciteTemplate, record)
+		return append([]byte(xmlHeader), data...), "application/xml; charset=utf-8", err
+	case MetadataFormatISO19115:
+		data, err := executeTextTemplate(isoTemplate, isoDocument{Root: "gmi:MI_Metadata", Record: record})
+		return append([]byte(xmlHeader), data...), "application/xml; charset=utf-8", err
+	case MetadataFormatBibTeX:
+		data, err := executeTextTemplate(bibtexTemplate, record)
+		return data, "application/x-bibtex; charset=utf-8", err
+	case MetadataFormatRIS:
+		data, err := executeTextTemplate(risTemplate, record)
+		return data, "application/x-research-info-systems; charset=utf-8", err
+	case MetadataFormatJSONLD:
+		data, err := json.MarshalIndent(schemaOrgDataset(record), "", "  ")
+		return data, "application/ld+json; charset=utf-8", err
+	default:
+		return nil, "", ErrUnsupportedMetadataFormat
+	}
+}
+
+// renderLandingPage 渲染嵌入JSON-LD的数据集落地页，供搜索引擎抓取
+func renderLandingPage(record *citationRecord) ([]byte, error) {
+	jsonLD, err := json.Marshal(schemaOrgDataset(record))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = landingTemplate.Execute(&buf, map[string]interface{}{
+		"Record": record,
+		"JSONLD": template.JS(jsonLD),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// schemaOrgDataset 构造schema.org Dataset结构
+func schemaOrgDataset(record *citationRecord) map[string]interface{} {
+	d := record.Dataset
+	doc := map[string]interface{}{
+		"@context":    "https://schema.org/",
+		"@type":       "Dataset",
+		"@id":         record.URL,
+		"name":        d.Name,
+		"description": d.Description,
+		"url":         record.URL,
+		"creator": map[string]interface{}{
+			"@type": "Person",
+			"name":  record.Author.Name,
+			"affiliation": map[string]interface{}{
+				"@type": "Organization",
+				"name":  record.Author.Affiliation,
+			},
+		},
+		"publisher": map[string]interface{}{
+			"@type": "Organization",
+			"name":  record.Publisher,
+		},
+		"keywords":            record.Keywords,
+		"isAccessibleForFree": true,
+	}
+
+	if d.DOI != "" {
+		doc["identifier"] = map[string]interface{}{
+			"@type":      "PropertyValue",
+			"propertyID": "https://registry.identifiers.org/registry/doi",
+			"value":      "doi:" + d.DOI,
+			"url":        "https://doi.org/" + d.DOI,
+		}
+		doc["sameAs"] = "https://doi.org/" + d.DOI
+	}
+	if d.License != "" {
+		doc["license"] = d.License
+	}
+	if d.Version != "" {
+		doc["version"] = d.Version
+	}
+	if d.CreatedAt != nil {
+		doc["dateCreated"] = d.CreatedAt.Format(time.RFC3339)
+		doc["datePublished"] = d.CreatedAt.Format("2006-01-02")
+	}
+	if d.UpdatedAt != nil {
+		doc["dateModified"] = d.UpdatedAt.Format(time.RFC3339)
+	}
+	if d.StartTime != nil && d.EndTime != nil {
+		doc["temporalCoverage"] = d.StartTime.Format(time.RFC3339) + "/" + d.EndTime.Format(time.RFC3339)
+	}
+	if record.HasBounds {
+		b := record.Bounds
+		place := map[string]interface{}{
+			"@type": "Place",
+			"geo": map[string]interface{}{
+				"@type": "GeoShape",
+				// schema.org box格式: "南 西 北 东"
+				"box": fmt.Sprintf("%g %g %g %g", b[0], b[1], b[2], b[3]),
+			},
+		}
+		if d.RegionName != "" {
+			place["name"] = d.RegionName
+		}
+		doc["spatialCoverage"] = place
+	}
+	if d.Source != "" {
+		doc["isBasedOn"] = d.Source
+	}
+	if d.Methodology != "" {
+		doc["measurementTechnique"] = d.Methodology
+	}
+	if len(record.Variables) > 0 {
+		variables := make([]map[string]interface{}, 0, len(record.Variables))
+		for _, v := range record.Variables {
+			variables = append(variables, map[string]interface{}{
+				"@type":       "PropertyValue",
+				"name":        v.Name,
+				"unitText":    v.Unit,
+				"description": v.Description,
+			})
+		}
+		doc["variableMeasured"] = variables
+	}
+	if d.FilePath != "" {
+		doc["distribution"] = map[string]interface{}{
+			"@type":          "DataDownload",
+			"encodingFormat": d.Format,
+			"contentUrl":     strings.TrimSuffix(record.URL, "/landing") + "/download",
+			"contentSize":    fmt.Sprintf("%d B", d.Size),
+		}
+	}
+	return doc
+}
+
+// isoDocument ISO元数据模板参数，Root决定根元素(ISO 19115-2或19139)
+type isoDocument struct {
+	Root   string
+	Record *citationRecord
+}
+
+// executeTextTemplate 执行文本模板
+func executeTextTemplate(tmpl *texttemplate.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlEscape 转义XML特殊字符
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\'':
+			buf.WriteString("&apos;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
+
+// bibtexEscape 转义BibTeX特殊字符
+func bibtexEscape(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\textbackslash{}`,
+		"{", `\{`,
+		"}", `\}`,
+		"&", `\&`,
+		"%", `\%`,
+		"$", `\$`,
+		"#", `\#`,
+		"_", `\_`,
+	)
+	return replacer.Replace(s)
+}
+
+var bibtexKeyPattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// bibtexKey 生成BibTeX引用键
+func bibtexKey(record *citationRecord) string {
+	return bibtexKeyPattern.ReplaceAllString(record.Dataset.ID, "") + fmt.Sprint(record.Year)
+}
+
+// formatDate 格式化时间，空值返回空字符串
+func formatDate(t *time.Time, layout string) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+var metadataFuncs = texttemplate.FuncMap{
+	"xml":     xmlEscape,
+	"bib":     bibtexEscape,
+	"bibkey":  bibtexKey,
+	"date":    formatDate,
+	"oneline": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+}
+
+// xmlHeader XML声明，模板本身不含声明以便嵌入其他文档(如OAI-PMH响应)
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+`
+
+var dataciteTemplate = texttemplate.Must(texttemplate.New("datacite").Funcs(metadataFuncs).Parse(`<resource xmlns="http://datacite.org/schema/kernel-4" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://datacite.org/schema/kernel-4 http://schema.datacite.org/meta/kernel-4.4/metadata.xsd">
+{{- with .Dataset}}
+  {{- if .DOI}}
+  <identifier identifierType="DOI">{{xml .DOI}}</identifier>
+  {{- end}}
+{{- end}}
+  <creators>
+    <creator>
+      <creatorName>{{xml .Author.Name}}</creatorName>
+      {{- if .Author.Affiliation}}
+      <affiliation>{{xml .Author.Affiliation}}</affiliation>
+      {{- end}}
+    </creator>
+  </creators>
+  <titles>
+    <title>{{xml .Dataset.Name}}</title>
+  </titles>
+  <publisher>{{xml .Publisher}}</publisher>
+  <publicationYear>{{.Year}}</publicationYear>
+  <resourceType resourceTypeGeneral="Dataset">{{xml .Dataset.Type}}</resourceType>
+  {{- if .Keywords}}
+  <subjects>
+    {{- range .Keywords}}
+    <subject>{{xml .}}</subject>
+    {{- end}}
+  </subjects>
+  {{- end}}
+  <dates>
+    {{- if and .Dataset.StartTime .Dataset.EndTime}}
+    <date dateType="Collected">{{date .Dataset.StartTime "2006-01-02T15:04:05Z07:00"}}/{{date .Dataset.EndTime "2006-01-02T15:04:05Z07:00"}}</date>
+    {{- end}}
+    {{- if .Dataset.CreatedAt}}
+    <date dateType="Created">{{date .Dataset.CreatedAt "2006-01-02"}}</date>
+    {{- end}}
+    {{- if .Dataset.UpdatedAt}}
+    <date dateType="Updated">{{date .Dataset.UpdatedAt "2006-01-02"}}</date>
+    {{- end}}
+  </dates>
+  <alternateIdentifiers>
+    <alternateIdentifier alternateIdentifierType="URL">{{xml .URL}}</alternateIdentifier>
+  </alternateIdentifiers>
+  {{- if .Dataset.Size}}
+  <sizes>
+    <size>{{.Dataset.Size}} bytes</size>
+  </sizes>
+  {{- end}}
+  {{- if .Dataset.Format}}
+  <formats>
+    <format>{{xml .Dataset.Format}}</format>
+  </formats>
+  {{- end}}
+  {{- if .Dataset.Version}}
+  <version>{{xml .Dataset.Version}}</version>
+  {{- end}}
+  {{- if .Dataset.License}}
+  <rightsList>
+    <rights>{{xml .Dataset.License}}</rights>
+  </rightsList>
+  {{- end}}
+  <descriptions>
+    <description descriptionType="Abstract">{{xml .Dataset.Description}}</description>
+    {{- if .Dataset.Methodology}}
+    <description descriptionType="Methods">{{xml .Dataset.Methodology}}</description>
+    {{- end}}
+    {{- if .Dataset.Source}}
+    <description descriptionType="Other">Source: {{xml .Dataset.Source}}</description>
+    {{- end}}
+  </descriptions>
+  {{- if or .HasBounds .Dataset.RegionName}}
+  <geoLocations>
+    <geoLocation>
+      {{- if .Dataset.RegionName}}
+      <geoLocationPlace>{{xml .Dataset.RegionName}}</geoLocationPlace>
+      {{- end}}
+      {{- if .HasBounds}}
+      <geoLocationBox>
+        <westBoundLongitude>{{index .Bounds 1}}</westBoundLongitude>
+        <eastBoundLongitude>{{index .Bounds 3}}</eastBoundLongitude>
+        <southBoundLatitude>{{index .Bounds 0}}</southBoundLatitude>
+        <northBoundLatitude>{{index .Bounds 2}}</northBoundLatitude>
+      </geoLocationBox>
+      {{- end}}
+    </geoLocation>
+  </geoLocations>
+  {{- end}}
+</resource>
+`))
+
+var isoTemplate = texttemplate.Must(texttemplate.New("iso").Funcs(metadataFuncs).Parse(`<{{.Root}} xmlns:gmi="http://www.isotc211.org/2005/gmi" xmlns:gmd="http://www.isotc211.org/2005/gmd" xmlns:gco="http://www.isotc211.org/2005/gco" xmlns:gml="http://www.opengis.net/gml/3.2" xmlns:xlink="http://www.w3.org/1999/xlink">
+{{- with .Record}}
+  <gmd:fileIdentifier><gco:CharacterString>{{xml .Dataset.ID}}</gco:CharacterString></gmd:fileIdentifier>
+  <gmd:language><gco:CharacterString>zho</gco:CharacterString></gmd:language>
+  <gmd:characterSet><gmd:MD_CharacterSetCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#MD_CharacterSetCode" codeListValue="utf8">utf8</gmd:MD_CharacterSetCode></gmd:characterSet>
+  <gmd:hierarchyLevel><gmd:MD_ScopeCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#MD_ScopeCode" codeListValue="dataset">dataset</gmd:MD_ScopeCode></gmd:hierarchyLevel>
+  <gmd:contact>
+    <gmd:CI_ResponsibleParty>
+      <gmd:individualName><gco:CharacterString>{{xml .Author.Name}}</gco:CharacterString></gmd:individualName>
+      {{- if .Author.Affiliation}}
+      <gmd:organisationName><gco:CharacterString>{{xml .Author.Affiliation}}</gco:CharacterString></gmd:organisationName>
+      {{- end}}
+      <gmd:role><gmd:CI_RoleCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#CI_RoleCode" codeListValue="pointOfContact">pointOfContact</gmd:CI_RoleCode></gmd:role>
+    </gmd:CI_ResponsibleParty>
+  </gmd:contact>
+  <gmd:dateStamp><gco:DateTime>{{if .Dataset.UpdatedAt}}{{date .Dataset.UpdatedAt "2006-01-02T15:04:05Z07:00"}}{{else}}{{date .Dataset.CreatedAt "2006-01-02T15:04:05Z07:00"}}{{end}}</gco:DateTime></gmd:dateStamp>
+  <gmd:metadataStandardName><gco:CharacterString>{{if eq $.Root "gmi:MI_Metadata"}}ISO 19115-2 Geographic Information - Metadata - Part 2: Extensions for Imagery and Gridded Data{{else}}ISO 19115:2003/19139{{end}}</gco:CharacterString></gmd:metadataStandardName>
+  <gmd:identificationInfo>
+    <gmd:MD_DataIdentification>
+      <gmd:citation>
+        <gmd:CI_Citation>
+          <gmd:title><gco:CharacterString>{{xml .Dataset.Name}}</gco:CharacterString></gmd:title>
+          {{- if .Dataset.CreatedAt}}
+          <gmd:date><gmd:CI_Date><gmd:date><gco:Date>{{date .Dataset.CreatedAt "2006-01-02"}}</gco:Date></gmd:date><gmd:dateType><gmd:CI_DateTypeCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#CI_DateTypeCode" codeListValue="publication">publication</gmd:CI_DateTypeCode></gmd:dateType></gmd:CI_Date></gmd:date>
+          {{- end}}
+          {{- if .Dataset.Version}}
+          <gmd:edition><gco:CharacterString>{{xml .Dataset.Version}}</gco:CharacterString></gmd:edition>
+          {{- end}}
+          {{- if .Dataset.DOI}}
+          <gmd:identifier><gmd:MD_Identifier><gmd:code><gco:CharacterString>doi:{{xml .Dataset.DOI}}</gco:CharacterString></gmd:code></gmd:MD_Identifier></gmd:identifier>
+          {{- end}}
+          <gmd:citedResponsibleParty>
+            <gmd:CI_ResponsibleParty>
+              <gmd:individualName><gco:CharacterString>{{xml .Author.Name}}</gco:CharacterString></gmd:individualName>
+              <gmd:role><gmd:CI_RoleCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#CI_RoleCode" codeListValue="originator">originator</gmd:CI_RoleCode></gmd:role>
+            </gmd:CI_ResponsibleParty>
+          </gmd:citedResponsibleParty>
+          <gmd:citedResponsibleParty>
+            <gmd:CI_ResponsibleParty>
+              <gmd:organisationName><gco:CharacterString>{{xml .Publisher}}</gco:CharacterString></gmd:organisationName>
+              <gmd:role><gmd:CI_RoleCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#CI_RoleCode" codeListValue="publisher">publisher</gmd:CI_RoleCode></gmd:role>
+            </gmd:CI_ResponsibleParty>
+          </gmd:citedResponsibleParty>
+        </gmd:CI_Citation>
+      </gmd:citation>
+      <gmd:abstract><gco:CharacterString>{{xml .Dataset.Description}}</gco:CharacterString></gmd:abstract>
+      {{- if .Keywords}}
+      <gmd:descriptiveKeywords>
+        <gmd:MD_Keywords>
+          {{- range .Keywords}}
+          <gmd:keyword><gco:CharacterString>{{xml .}}</gco:CharacterString></gmd:keyword>
+          {{- end}}
+        </gmd:MD_Keywords>
+      </gmd:descriptiveKeywords>
+      {{- end}}
+      {{- if .Dataset.License}}
+      <gmd:resourceConstraints>
+        <gmd:MD_LegalConstraints>
+          <gmd:useLimitation><gco:CharacterString>{{xml .Dataset.License}}</gco:CharacterString></gmd:useLimitation>
+        </gmd:MD_LegalConstraints>
+      </gmd:resourceConstraints>
+      {{- end}}
+      <gmd:language><gco:CharacterString>zho</gco:CharacterString></gmd:language>
+      <gmd:topicCategory><gmd:MD_TopicCategoryCode>oceans</gmd:MD_TopicCategoryCode></gmd:topicCategory>
+      <gmd:extent>
+        <gmd:EX_Extent>
+          {{- if .Dataset.RegionName}}
+          <gmd:description><gco:CharacterString>{{xml .Dataset.RegionName}}</gco:CharacterString></gmd:description>
+          {{- end}}
+          {{- if .HasBounds}}
+          <gmd:geographicElement>
+            <gmd:EX_GeographicBoundingBox>
+              <gmd:westBoundLongitude><gco:Decimal>{{index .Bounds 1}}</gco:Decimal></gmd:westBoundLongitude>
+              <gmd:eastBoundLongitude><gco:Decimal>{{index .Bounds 3}}</gco:Decimal></gmd:eastBoundLongitude>
+              <gmd:southBoundLatitude><gco:Decimal>{{index .Bounds 0}}</gco:Decimal></gmd:southBoundLatitude>
+              <gmd:northBoundLatitude><gco:Decimal>{{index .Bounds 2}}</gco:Decimal></gmd:northBoundLatitude>
+            </gmd:EX_GeographicBoundingBox>
+          </gmd:geographicElement>
+          {{- end}}
+          {{- if and .Dataset.StartTime .Dataset.EndTime}}
+          <gmd:temporalElement>
+            <gmd:EX_TemporalExtent>
+              <gmd:extent>
+                <gml:TimePeriod gml:id="T_{{xml .Dataset.ID}}">
+                  <gml:beginPosition>{{date .Dataset.StartTime "2006-01-02T15:04:05Z07:00"}}</gml:beginPosition>
+                  <gml:endPosition>{{date .Dataset.EndTime "2006-01-02T15:04:05Z07:00"}}</gml:endPosition>
+                </gml:TimePeriod>
+              </gmd:extent>
+            </gmd:EX_TemporalExtent>
+          </gmd:temporalElement>
+          {{- end}}
+        </gmd:EX_Extent>
+      </gmd:extent>
+    </gmd:MD_DataIdentification>
+  </gmd:identificationInfo>
+  {{- if .Variables}}
+  <gmd:contentInfo>
+    <gmd:MD_CoverageDescription>
+      <gmd:attributeDescription><gco:RecordType>{{xml .Dataset.Type}}</gco:RecordType></gmd:attributeDescription>
+      <gmd:contentType><gmd:MD_CoverageContentTypeCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#MD_CoverageContentTypeCode" codeListValue="physicalMeasurement">physicalMeasurement</gmd:MD_CoverageContentTypeCode></gmd:contentType>
+      {{- range .Variables}}
+      <gmd:dimension>
+        <gmd:MD_Band>
+          <gmd:sequenceIdentifier><gco:MemberName><gco:aName><gco:CharacterString>{{xml .Name}}</gco:CharacterString></gco:aName><gco:attributeType><gco:TypeName><gco:aName><gco:CharacterString>float</gco:CharacterString></gco:aName></gco:TypeName></gco:attributeType></gco:MemberName></gmd:sequenceIdentifier>
+          <gmd:descriptor><gco:CharacterString>{{xml .Description}}{{if .Unit}} ({{xml .Unit}}){{end}}</gco:CharacterString></gmd:descriptor>
+        </gmd:MD_Band>
+      </gmd:dimension>
+      {{- end}}
+    </gmd:MD_CoverageDescription>
+  </gmd:contentInfo>
+  {{- end}}
+  <gmd:distributionInfo>
+    <gmd:MD_Distribution>
+      {{- if .Dataset.Format}}
+      <gmd:distributionFormat><gmd:MD_Format><gmd:name><gco:CharacterString>{{xml .Dataset.Format}}</gco:CharacterString></gmd:name><gmd:version gco:nilReason="unknown"/></gmd:MD_Format></gmd:distributionFormat>
+      {{- end}}
+      <gmd:transferOptions>
+        <gmd:MD_DigitalTransferOptions>
+          <gmd:onLine><gmd:CI_OnlineResource><gmd:linkage><gmd:URL>{{xml .URL}}</gmd:URL></gmd:linkage></gmd:CI_OnlineResource></gmd:onLine>
+        </gmd:MD_DigitalTransferOptions>
+      </gmd:transferOptions>
+    </gmd:MD_Distribution>
+  </gmd:distributionInfo>
+  {{- if or .Dataset.Methodology .Dataset.Source}}
+  <gmd:dataQualityInfo>
+    <gmd:DQ_DataQuality>
+      <gmd:scope><gmd:DQ_Scope><gmd:level><gmd:MD_ScopeCode codeList="http://www.isotc211.org/2005/resources/Codelist/gmxCodelists.xml#MD_ScopeCode" codeListValue="dataset">dataset</gmd:MD_ScopeCode></gmd:level></gmd:DQ_Scope></gmd:scope>
+      <gmd:lineage>
+        <gmd:LI_Lineage>
+          {{- if .Dataset.Methodology}}
+          <gmd:statement><gco:CharacterString>{{xml .Dataset.Methodology}}</gco:CharacterString></gmd:statement>
+          {{- end}}
+          {{- if .Dataset.Source}}
+          <gmd:source><gmd:LI_Source><gmd:description><gco:CharacterString>{{xml .Dataset.Source}}</gco:CharacterString></gmd:description></gmd:LI_Source></gmd:source>
+          {{- end}}
+        </gmd:LI_Lineage>
+      </gmd:lineage>
+    </gmd:DQ_DataQuality>
+  </gmd:dataQualityInfo>
+  {{- end}}
+{{- end}}
+</{{.Root}}>
+`))
+
+var bibtexTemplate = texttemplate.Must(texttemplate.New("bibtex").Funcs(metadataFuncs).Parse(`@misc{ {{- bibkey .}},
+  author    = { {{- bib .Author.Name -}} },
+  title     = { {{- bib .Dataset.Name -}} },
+  publisher = { {{- bib .Publisher -}} },
+  year      = { {{- .Year -}} },
+{{- if .Dataset.Version}}
+  version   = { {{- bib .Dataset.Version -}} },
+{{- end}}
+{{- if .Dataset.DOI}}
+  doi       = { {{- bib .Dataset.DOI -}} },
+{{- end}}
+{{- if .Dataset.License}}
+  note      = {License: {{bib .Dataset.License}}},
+{{- end}}
+{{- if .Keywords}}
+  keywords  = { {{- range $i, $k := .Keywords}}{{if $i}}, {{end}}{{bib $k}}{{end -}} },
+{{- end}}
+  url       = { {{- bib .URL -}} },
+  type      = {Dataset}
+}
+`))
+
+var risTemplate = texttemplate.Must(texttemplate.New("ris").Funcs(metadataFuncs).Parse(`TY  - DATA
+AU  - {{oneline .Author.Name}}
+TI  - {{oneline .Dataset.Name}}
+PB  - {{oneline .Publisher}}
+PY  - {{.Year}}
+{{- if .Dataset.Version}}
+ET  - {{oneline .Dataset.Version}}
+{{- end}}
+{{- if .Dataset.DOI}}
+DO  - {{.Dataset.DOI}}
+{{- end}}
+{{- if .Dataset.Description}}
+AB  - {{oneline .Dataset.Description}}
+{{- end}}
+{{- range .Keywords}}
+KW  - {{oneline .}}
+{{- end}}
+{{- if and .Dataset.StartTime .Dataset.EndTime}}
+DA  - {{date .Dataset.StartTime "2006/01/02"}}-{{date .Dataset.EndTime "2006/01/02"}}
+{{- end}}
+{{- if .Dataset.License}}
+N1  - License: {{oneline .Dataset.License}}
+{{- end}}
+{{- if .Dataset.Source}}
+N1  - Source: {{oneline .Dataset.Source}}
+{{- end}}
+UR  - {{.URL}}
+ER  -
+`))
+
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Record.Dataset.Name}}</title>
+<meta name="description" content="{{.Record.Dataset.Description}}">
+<script type="application/ld+json">{{.JSONLD}}</script>
+</head>
+<body>
+<h1>{{.Record.Dataset.Name}}</h1>
+<p>{{.Record.Dataset.Description}}</p>
+<dl>
+<dt>作者</dt><dd>{{.Record.Author.Name}}</dd>
+<dt>发布者</dt><dd>{{.Record.Publisher}}</dd>
+{{- if .Record.Dataset.DOI}}
+<dt>DOI</dt><dd><a href="https://doi.org/{{.Record.Dataset.DOI}}">{{.Record.Dataset.DOI}}</a></dd>
+{{- end}}
+{{- if .Record.Dataset.License}}
+<dt>许可</dt><dd>{{.Record.Dataset.License}}</dd>
+{{- end}}
+{{- if .Record.Dataset.Version}}
+<dt>版本</dt><dd>{{.Record.Dataset.Version}}</dd>
+{{- end}}
+</dl>
+</body>
+</html>
+`))
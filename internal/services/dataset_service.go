@@ -11,26 +11,42 @@ import (
 	"github.com/sinker/ssop/internal/repository"
 	"github.com/sinker/ssop/pkg/logger"
 	"github.com/sinker/ssop/pkg/utils"
+	"gorm.io/gorm"
 )
 
 // DatasetService 数据集服务接口
 type DatasetService interface {
-	CreateDataset(dataset *models.Dataset, file io.Reader, filename string) (string, error)
+	// CreateDataset 保存上传的文件并创建数据集，size为文件大小(未知时为-1)
+	CreateDataset(dataset *models.Dataset, file io.Reader, filename string, size int64) (string, error)
+	// CreateDatasetFromStore 登记存储后端上的Zarr目录为数据集
+	CreateDatasetFromStore(dataset *models.Dataset, storePath string) (string, error)
 	GetDatasetByID(id string) (*models.Dataset, error)
 	GetDatasets(page, size int, filters map[string]interface{}) ([]*models.Dataset, int64, error)
 	UpdateDataset(dataset *models.Dataset) error
 	DeleteDataset(id string) error
 	DownloadDataset(id string) (string, error)
+	// CreateSubset 按变量、时间和空间范围生成NetCDF子集，返回临时文件路径
+	CreateSubset(id string, params map[string]interface{}) (string, error)
+	
+	// 引用元数据
+	ExportMetadata(id, format string) ([]byte, string, error)
+	RenderLandingPage(id string) ([]byte, error)
 }
 
 // datasetService 数据集服务实现
 type datasetService struct {
 	datasetRepo repository.DatasetRepository
+	userRepo    repository.UserRepository
+	quota       QuotaService
+	ingest      IngestService
 	storageDir  string // 数据集文件存储目录
+	storeDir    string // 可登记为数据集的Zarr存储根目录
+	baseURL     string // 对外访问地址
+	bin         trashBin
 }
 
 // NewDatasetService 创建数据集服务
-func NewDatasetService(datasetRepo repository.DatasetRepository, storageDir string) DatasetService {
+func NewDatasetService(datasetRepo repository.DatasetRepository, userRepo repository.UserRepository, quota QuotaService, ingest IngestService, storageDir, storeDir, trashDir, baseURL string) DatasetService {
 	// 确保存储目录存在
 	if err := os.MkdirAll(storageDir, 0755); err != nil {
 		logger.Error("Failed to create dataset storage directory", "error", err)
@@ -38,19 +54,40 @@ func NewDatasetService(datasetRepo repository.DatasetRepository, storageDir stri
 
 	return &datasetService{
 		datasetRepo: datasetRepo,
+		userRepo:    userRepo,
+		quota:       quota,
+		ingest:      ingest,
 		storageDir:  storageDir,
+		storeDir:    storeDir,
+		baseURL:     baseURL,
+		bin:         trashBin{dir: trashDir},
 	}
 }
 
 // CreateDataset 创建数据集
-func (s *datasetService) CreateDataset(dataset *models.Dataset, file io.Reader, filename string) (string, error) {
+func (s *datasetService) CreateDataset(dataset *models.Dataset, file io.Reader, filename string, size int64) (string, error) {
 	// 生成唯一ID
 	if dataset.ID == "" {
 		dataset.ID = utils.GenerateID("ds")
 	}
 
+	// 剩余配额只计算一次，保存记录前按最终大小(如解压后的大小)再比较
+	remaining, err := s.quota.RemainingUpload(dataset.CreatedBy)
+	if err != nil {
+		return "", err
+	}
+
 	// 保存文件
 	if file != nil {
+		// 写入磁盘前检查配额，已知大小时直接拒绝超出配额的上传
+		if size >= 0 && remaining >= 0 && size > remaining {
+			return "", fmt.Errorf("%w: 文件超出剩余配额 %d 字节", ErrQuotaExceeded, remaining)
+		}
+		if remaining >= 0 {
+			// 大小未知或与实际不符时，最多多读一个字节即可判断超出配额
+			file = io.LimitReader(file, remaining+1)
+		}
+
 		// 创建数据集目录
 		datasetDir := filepath.Join(s.storageDir, dataset.ID)
 		if err := os.MkdirAll(datasetDir, 0755); err != nil {
@@ -66,22 +103,54 @@ func (s *datasetService) CreateDataset(dataset *models.Dataset, file io.Reader,
 		defer outFile.Close()
 
 		// 计算文件大小
-		size, err := io.Copy(outFile, file)
+		written, err := io.Copy(outFile, file)
 		if err != nil {
+			os.RemoveAll(datasetDir)
 			return "", fmt.Errorf("failed to save file: %w", err)
 		}
 
+		outFile.Close()
+		if remaining >= 0 && written > remaining {
+			os.RemoveAll(datasetDir)
+			return "", fmt.Errorf("%w: 文件超出剩余配额 %d 字节", ErrQuotaExceeded, remaining)
+		}
+
 		// 更新数据集文件信息
 		dataset.FilePath = filePath
-		dataset.Size = size
+		dataset.Size = written
+
+		// 包含Zarr存储的归档解压为目录
+		if utils.ArchiveExt(filename) != "" {
+			if err := s.unpackZarrArchive(dataset, filename); err != nil {
+				os.RemoveAll(datasetDir)
+				return "", err
+			}
+		}
+	}
+
+	return s.saveDataset(dataset, remaining)
+}
+
+// saveDataset 检查配额后保存数据集记录并排队解析，remaining为RemainingUpload返回的剩余字节数
+func (s *datasetService) saveDataset(dataset *models.Dataset, remaining int64) (string, error) {
+	// 检查存储配额，超出时删除已保存的文件
+	if remaining >= 0 && dataset.Size > remaining {
+		if dataset.FilePath != "" {
+			os.RemoveAll(filepath.Dir(dataset.FilePath))
+		}
+		return "", fmt.Errorf("%w: 数据集大小 %d 字节超出剩余配额 %d 字节", ErrQuotaExceeded, dataset.Size, remaining)
 	}
 
 	// 保存数据集信息到数据库
+	dataset.IngestStatus = models.IngestPending
 	err := s.datasetRepo.Create(dataset)
 	if err != nil {
 		return "", fmt.Errorf("failed to save dataset: %w", err)
 	}
 
+	// 异步解析文件并计算变量统计
+	s.ingest.Enqueue(dataset.ID)
+
 	return dataset.ID, nil
 }
 
@@ -109,11 +178,14 @@ func (s *datasetService) UpdateDataset(dataset *models.Dataset) error {
 	dataset.CreatedAt = existingDataset.CreatedAt
 	dataset.CreatedBy = existingDataset.CreatedBy
 	dataset.DownloadCount = existingDataset.DownloadCount
+	dataset.IngestStatus = existingDataset.IngestStatus
+	dataset.IngestMessage = existingDataset.IngestMessage
+	dataset.QCSummary = existingDataset.QCSummary
 
 	return s.datasetRepo.Update(dataset)
 }
 
-// DeleteDataset 删除数据集
+// DeleteDataset 删除数据集(移入回收站)
 func (s *datasetService) DeleteDataset(id string) error {
 	// 获取数据集信息
 	dataset, err := s.datasetRepo.GetByID(id)
@@ -121,21 +193,15 @@ func (s *datasetService) DeleteDataset(id string) error {
 		return fmt.Errorf("dataset not found: %w", err)
 	}
 
-	// 删除文件
+	// 将数据集目录移入回收站
 	if dataset.FilePath != "" {
-		// 删除文件
-		if err := os.Remove(dataset.FilePath); err != nil && !os.IsNotExist(err) {
-			logger.Error("Failed to delete dataset file", "error", err, "path", dataset.FilePath)
-		}
-
-		// 尝试删除数据集目录
 		datasetDir := filepath.Dir(dataset.FilePath)
-		if err := os.RemoveAll(datasetDir); err != nil {
-			logger.Error("Failed to delete dataset directory", "error", err, "path", datasetDir)
+		if err := s.bin.move(datasetDir, s.bin.datasetDir(id)); err != nil {
+			return fmt.Errorf("failed to move dataset to trash: %w", err)
 		}
 	}
 
-	// 从数据库中删除
+	// 软删除数据库记录
 	return s.datasetRepo.Delete(id)
 }
 
@@ -163,3 +229,53 @@ func (s *datasetService) DownloadDataset(id string) (string, error) {
 
 	return dataset.FilePath, nil
 }
+
+// ExportMetadata 导出数据集引用元数据，返回内容和Content-Type
+func (s *datasetService) ExportMetadata(id, format string) ([]byte, string, error) {
+	record, err := s.citationRecord(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return renderMetadata(record, format)
+}
+
+// RenderLandingPage 渲染数据集落地页
+func (s *datasetService) RenderLandingPage(id string) ([]byte, error) {
+	record, err := s.citationRecord(id)
+	if err != nil {
+		return nil, err
+	}
+	return renderLandingPage(record)
+}
+
+// citationRecord 获取数据集及其创建者信息，构造引用信息
+func (s *datasetService) citationRecord(id string) (*citationRecord, error) {
+	dataset, err := s.datasetRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDatasetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset: %w", err)
+	}
+
+	return newCitationRecord(dataset, datasetAuthor(s.userRepo, dataset), s.baseURL), nil
+}
+
+// datasetAuthor 获取数据集创建者作为作者，未填写姓名时使用用户名
+func datasetAuthor(userRepo repository.UserRepository, dataset *models.Dataset) citationAuthor {
+	var author citationAuthor
+	if dataset.CreatedBy == "" {
+		return author
+	}
+	user, err := userRepo.FindByID(dataset.CreatedBy)
+	if err != nil {
+		logger.Warn("Failed to get dataset creator", "error", err, "userId", dataset.CreatedBy)
+		return author
+	}
+	author.Name = user.FullName
+	if author.Name == "" {
+		author.Name = user.Username
+	}
+	author.Affiliation = user.Organization
+	return author
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+)
+
+// ErrInvalidSubset 子集参数无效
+var ErrInvalidSubset = errors.New("invalid subset parameters")
+
+// parseSubset 解析子集参数: vars(逗号分隔)、startDate、endDate、bounds(minLat,minLng,maxLat,maxLng)；
+// 只有日期的endDate包含当天
+func parseSubset(params map[string]interface{}) (*dataio.Subset, error) {
+	subset := &dataio.Subset{}
+	for _, name := range strings.Split(paramString(params, "vars"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			subset.Variables = append(subset.Variables, name)
+		}
+	}
+
+	var err error
+	if subset.From, err = paramTime(params, "startDate"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubset, err)
+	}
+	if subset.To, err = paramTime(params, "endDate"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubset, err)
+	}
+	if len(paramString(params, "endDate")) == len("2006-01-02") {
+		subset.To = subset.To.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !subset.From.IsZero() && !subset.To.IsZero() && subset.From.After(subset.To) {
+		return nil, fmt.Errorf("%w: startDate is after endDate", ErrInvalidSubset)
+	}
+
+	bounds, ok, err := paramBounds(params, "bounds")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubset, err)
+	}
+	if ok {
+		subset.Bounds = &bounds
+	}
+	return subset, nil
+}
+
+// CreateSubset 按变量、时间和空间范围生成NetCDF子集，返回临时文件路径，调用方使用后删除
+func (s *datasetService) CreateSubset(id string, params map[string]interface{}) (string, error) {
+	subset, err := parseSubset(params)
+	if err != nil {
+		return "", err
+	}
+
+	dataset, err := s.datasetRepo.GetByID(id)
+	if err != nil {
+		return "", fmt.Errorf("dataset not found: %w", err)
+	}
+	if dataset.FilePath == "" {
+		return "", errors.New("dataset has no file")
+	}
+
+	source, err := dataio.Open(dataset.FilePath, dataset.Format)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	tmp, err := os.CreateTemp("", "subset-"+id+"-*.nc")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+
+	if err := dataio.WriteNetCDF(source, tmp.Name(), subset); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := s.datasetRepo.IncrementDownloadCount(id); err != nil {
+		logger.Error("Failed to increment download count", "error", err, "datasetId", id)
+	}
+	return tmp.Name(), nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/pkg/utils"
+	"github.com/sinker/ssop/pkg/zarr"
+)
+
+// ErrInvalidStore Zarr存储路径无效或无法读取
+var ErrInvalidStore = errors.New("invalid zarr store")
+
+// maxArchiveExpansion 归档解压后允许的最大膨胀倍数
+const maxArchiveExpansion = 100
+
+// zarrMarkers 标识Zarr存储根目录的元数据文件
+var zarrMarkers = []string{"zarr.json", ".zgroup", ".zarray", ".zmetadata"}
+
+// CreateDatasetFromStore 登记存储后端上已有的Zarr目录为数据集，storePath相对于存储根目录；
+// 数据集目录中只创建指向该目录的符号链接，删除数据集不会影响原始数据
+func (s *datasetService) CreateDatasetFromStore(dataset *models.Dataset, storePath string) (string, error) {
+	if s.storeDir == "" {
+		return "", fmt.Errorf("%w: store directory is not configured", ErrInvalidStore)
+	}
+	target, err := s.resolveStorePath(storePath)
+	if err != nil {
+		return "", err
+	}
+	if err := checkZarrStore(target); err != nil {
+		return "", err
+	}
+	remaining, err := s.quota.RemainingUpload(dataset.CreatedBy)
+	if err != nil {
+		return "", err
+	}
+
+	if dataset.ID == "" {
+		dataset.ID = utils.GenerateID("ds")
+	}
+	datasetDir := filepath.Join(s.storageDir, dataset.ID)
+	if err := os.MkdirAll(datasetDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create dataset directory: %w", err)
+	}
+	link := filepath.Join(datasetDir, zarrStoreName(filepath.Base(target)))
+	if err := os.Symlink(target, link); err != nil {
+		os.RemoveAll(datasetDir)
+		return "", fmt.Errorf("failed to link zarr store: %w", err)
+	}
+
+	size, err := utils.DirSize(target)
+	if err != nil {
+		os.RemoveAll(datasetDir)
+		return "", fmt.Errorf("failed to stat zarr store: %w", err)
+	}
+	dataset.FilePath = link
+	dataset.Size = size
+	if dataset.Format == "" {
+		dataset.Format = "Zarr"
+	}
+	return s.saveDataset(dataset, remaining)
+}
+
+// resolveStorePath 将相对路径解析为存储根目录下的绝对路径，拒绝越出根目录的路径
+func (s *datasetService) resolveStorePath(storePath string) (string, error) {
+	root, err := filepath.Abs(s.storeDir)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidStore, err)
+	}
+	rel := filepath.FromSlash(path.Clean("/" + strings.ReplaceAll(storePath, "\\", "/")))
+	target, err := filepath.EvalSymlinks(filepath.Join(root, rel))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s not found", ErrInvalidStore, storePath)
+	}
+	if !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: %s is outside the store directory", ErrInvalidStore, storePath)
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%w: %s is not a directory", ErrInvalidStore, storePath)
+	}
+	return target, nil
+}
+
+// unpackZarrArchive 上传的归档包含Zarr存储时解压到数据集目录并删除归档，
+// 数据集文件路径改为存储目录；不含Zarr存储的归档保持原样
+func (s *datasetService) unpackZarrArchive(dataset *models.Dataset, filename string) error {
+	names, err := utils.ListArchive(dataset.FilePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStore, err)
+	}
+	root, ok := archiveStoreRoot(names)
+	if !ok {
+		return nil
+	}
+
+	datasetDir := filepath.Dir(dataset.FilePath)
+	tmp, err := os.MkdirTemp(datasetDir, ".extract-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	size, err := utils.ExtractArchive(dataset.FilePath, tmp, dataset.Size*maxArchiveExpansion)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStore, err)
+	}
+
+	name := filename[:len(filename)-len(utils.ArchiveExt(filename))]
+	if root != "" {
+		name = path.Base(root)
+	}
+	storePath := filepath.Join(datasetDir, zarrStoreName(name))
+	if err := os.Rename(filepath.Join(tmp, filepath.FromSlash(root)), storePath); err != nil {
+		return fmt.Errorf("failed to move zarr store: %w", err)
+	}
+	if err := checkZarrStore(storePath); err != nil {
+		return err
+	}
+	os.Remove(dataset.FilePath)
+
+	dataset.FilePath = storePath
+	dataset.Size = size
+	if dataset.Format == "" {
+		dataset.Format = "Zarr"
+	}
+	return nil
+}
+
+// checkZarrStore 确认目录是至少包含一个可读数组的Zarr存储
+func checkZarrStore(dir string) error {
+	store, err := zarr.Open(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStore, err)
+	}
+	defer store.Close()
+	if len(store.Arrays) == 0 {
+		return fmt.Errorf("%w: no readable arrays", ErrInvalidStore)
+	}
+	return nil
+}
+
+// archiveStoreRoot 查找归档中最浅的Zarr存储根目录，""表示归档根目录本身
+func archiveStoreRoot(names []string) (string, bool) {
+	root, found := "", false
+	for _, name := range names {
+		dir, base := path.Split(path.Clean("/" + name))
+		for _, m := range zarrMarkers {
+			if base != m {
+				continue
+			}
+			dir = strings.Trim(dir, "/")
+			if !found || strings.Count(dir, "/") < strings.Count(root, "/") || (root != "" && dir == "") {
+				root, found = dir, true
+			}
+		}
+	}
+	return root, found
+}
+
+// zarrStoreName 存储目录名，补齐.zarr扩展名以便按扩展名识别格式
+func zarrStoreName(name string) string {
+	if name == "" || name == "." || name == "/" {
+		name = "store"
+	}
+	if strings.EqualFold(filepath.Ext(name), ".zarr") {
+		return name
+	}
+	return name + ".zarr"
+}
@@ -0,0 +1,534 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/argo"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/stats"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxConcurrentIngests 同时进行的入库处理数
+	maxConcurrentIngests = 2
+	// statsHistogramBins 直方图区间数
+	statsHistogramBins = 50
+)
+
+// statsPercentiles 统计的分位数
+var statsPercentiles = []float64{1, 5, 25, 50, 75, 95, 99}
+
+var (
+	// ErrStatsNotFound 变量统计不存在
+	ErrStatsNotFound = errors.New("variable statistics not found")
+	// ErrIngestForbidden 无权重新处理数据集
+	ErrIngestForbidden = errors.New("dataset belongs to another user")
+)
+
+// VariableStatistics 变量统计结果
+type VariableStatistics struct {
+	*models.VariableStats
+	Percentiles map[string]float64 `json:"percentiles"`
+	Histogram   *stats.Histogram   `json:"histogram"`
+}
+
+// IngestService 数据集入库处理服务接口
+type IngestService interface {
+	// Enqueue 异步处理数据集
+	Enqueue(datasetID string)
+	// Ingest 同步处理数据集: 解析文件、提取变量并计算统计信息
+	Ingest(datasetID string) error
+	// Reingest 重新处理数据集，仅所有者或管理员可操作
+	Reingest(datasetID, userID string, isAdmin bool) error
+
+	// 变量统计
+	ListVariableStats(datasetID string) ([]*VariableStatistics, error)
+	GetVariableStats(datasetID, variable string) (*VariableStatistics, error)
+}
+
+// ingestContext 单次入库处理的上下文
+type ingestContext struct {
+	dataset   *models.Dataset
+	source    *dataio.Source
+	variables []models.VariableInfo
+	fields    map[string]interface{} // 处理完成后写回的列
+}
+
+// set 修改数据集字段后记录需要写回的列
+func (c *ingestContext) set(column string, value interface{}) {
+	c.fields[column] = value
+}
+
+// variable 按名称查找变量信息
+func (c *ingestContext) variable(name string) *models.VariableInfo {
+	for i := range c.variables {
+		if c.variables[i].Name == name {
+			return &c.variables[i]
+		}
+	}
+	return nil
+}
+
+// ingestStep 入库处理步骤
+type ingestStep struct {
+	name string
+	run  func(ctx *ingestContext) error
+}
+
+// ingestService 数据集入库处理服务实现
+type ingestService struct {
+	datasetRepo repository.DatasetRepository
+	profileRepo repository.ProfileRepository
+	qc          QCService
+	sem         chan struct{}
+}
+
+// NewIngestService 创建数据集入库处理服务
+func NewIngestService(datasetRepo repository.DatasetRepository, profileRepo repository.ProfileRepository, qc QCService) IngestService {
+	return &ingestService{
+		datasetRepo: datasetRepo,
+		profileRepo: profileRepo,
+		qc:          qc,
+		sem:         make(chan struct{}, maxConcurrentIngests),
+	}
+}
+
+// steps 入库处理步骤，按顺序执行
+func (s *ingestService) steps() []ingestStep {
+	return []ingestStep{
+		{name: "sidecar", run: s.writeSidecar},
+		{name: "variables", run: s.extractVariables},
+		{name: "profiles", run: s.storeProfiles},
+		{name: "extent", run: s.fillExtent},
+		{name: "statistics", run: s.computeStatistics},
+		{name: "qc", run: s.runQC},
+	}
+}
+
+// Enqueue 异步处理数据集
+func (s *ingestService) Enqueue(datasetID string) {
+	go func() {
+		s.sem <- struct{}{}
+		defer func() { <-s.sem }()
+		defer func() {
+			// 读取异常文件时的panic只使本次处理失败，不影响服务进程
+			if r := recover(); r != nil {
+				logger.Error("Dataset ingest panicked", "panic", r, "datasetId", datasetID, "stack", string(debug.Stack()))
+				if err := s.setStatus(datasetID, models.IngestFailed, fmt.Sprintf("internal error: %v", r), nil); err != nil {
+					logger.Error("Failed to update ingest status", "error", err, "datasetId", datasetID)
+				}
+			}
+		}()
+
+		if err := s.Ingest(datasetID); err != nil {
+			logger.Error("Failed to ingest dataset", "error", err, "datasetId", datasetID)
+		}
+	}()
+}
+
+// Reingest 重新处理数据集
+func (s *ingestService) Reingest(datasetID, userID string, isAdmin bool) error {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return fmt.Errorf("dataset not found: %w", err)
+	}
+	if !isAdmin && dataset.CreatedBy != userID {
+		return ErrIngestForbidden
+	}
+
+	if err := s.setStatus(datasetID, models.IngestPending, "", nil); err != nil {
+		return err
+	}
+
+	s.Enqueue(datasetID)
+	return nil
+}
+
+// Ingest 同步处理数据集
+func (s *ingestService) Ingest(datasetID string) error {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return fmt.Errorf("dataset not found: %w", err)
+	}
+	if dataset.FilePath == "" {
+		return s.setStatus(datasetID, models.IngestStored, "", nil)
+	}
+
+	if err := s.setStatus(datasetID, models.IngestProcessing, "", nil); err != nil {
+		return err
+	}
+
+	source, err := dataio.Open(dataset.FilePath, dataset.Format)
+	if errors.Is(err, dataio.ErrUnsupportedFormat) {
+		// 无法解析的格式仅作为文件存储
+		return s.setStatus(datasetID, models.IngestStored, "", nil)
+	}
+	if err != nil {
+		s.setStatus(datasetID, models.IngestFailed, err.Error(), nil)
+		return fmt.Errorf("failed to open dataset file: %w", err)
+	}
+	defer source.Close()
+
+	ctx := &ingestContext{dataset: dataset, source: source, variables: dataset.VariableList(), fields: map[string]interface{}{}}
+	for _, step := range s.steps() {
+		if err := step.run(ctx); err != nil {
+			s.setStatus(datasetID, models.IngestFailed, step.name+": "+err.Error(), nil)
+			return fmt.Errorf("ingest step %s failed: %w", step.name, err)
+		}
+	}
+
+	variables, err := json.Marshal(ctx.variables)
+	if err != nil {
+		return err
+	}
+	ctx.set("variables", string(variables))
+	return s.setStatus(datasetID, models.IngestReady, "", ctx.fields)
+}
+
+// setStatus 更新处理状态，同时写回fields中的列；只更新这些列，避免覆盖处理期间对数据集的其他修改
+func (s *ingestService) setStatus(datasetID, status, message string, fields map[string]interface{}) error {
+	updates := map[string]interface{}{"ingest_status": status, "ingest_message": message}
+	for column, value := range fields {
+		updates[column] = value
+	}
+	return s.datasetRepo.UpdateFields(datasetID, updates)
+}
+
+// runQC 使用默认配置执行质量控制，失败时仅记录日志，不影响入库
+func (s *ingestService) runQC(ctx *ingestContext) error {
+	if _, err := s.qc.Check(ctx.dataset, ctx.source, nil); err != nil {
+		logger.Error("Quality control failed", "error", err, "datasetId", ctx.dataset.ID)
+		return nil
+	}
+	ctx.set("qc_summary", ctx.dataset.QCSummary)
+	return nil
+}
+
+// writeSidecar 为CSV等表格数据生成列式副本，之后的分析和子集下载直接读取副本
+func (s *ingestService) writeSidecar(ctx *ingestContext) error {
+	if ctx.source.Format != "CSV" {
+		return nil
+	}
+	return dataio.WriteSidecar(ctx.source, ctx.dataset.FilePath)
+}
+
+// extractVariables 从文件中提取变量信息，保留用户已填写的单位和描述
+func (s *ingestService) extractVariables(ctx *ingestContext) error {
+	for _, v := range ctx.source.DataVariables() {
+		info := ctx.variable(v.Name)
+		if info == nil {
+			ctx.variables = append(ctx.variables, models.VariableInfo{Name: v.Name})
+			info = &ctx.variables[len(ctx.variables)-1]
+		}
+		if info.Unit == "" {
+			info.Unit = v.Units()
+		}
+		if info.Description == "" {
+			info.Description = v.Description()
+		}
+	}
+	return nil
+}
+
+// storeProfiles 将Argo剖面文件中的剖面写入剖面库，并按剖面位置和时间回填为空的时间和区域范围
+func (s *ingestService) storeProfiles(ctx *ingestContext) error {
+	if !argo.IsArgo(ctx.source) {
+		return nil
+	}
+	profiles, err := argo.Read(ctx.source)
+	if err != nil {
+		return err
+	}
+
+	dataset := ctx.dataset
+	records := make([]*models.Profile, len(profiles))
+	minLat, minLon, maxLat, maxLon := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	var start, end time.Time
+	for i, p := range profiles {
+		records[i] = newProfileRecord(dataset.ID, p)
+		minLat, maxLat = math.Min(minLat, p.Latitude), math.Max(maxLat, p.Latitude)
+		minLon, maxLon = math.Min(minLon, p.Longitude), math.Max(maxLon, p.Longitude)
+		if start.IsZero() || p.Time.Before(start) {
+			start = p.Time
+		}
+		if end.IsZero() || p.Time.After(end) {
+			end = p.Time
+		}
+	}
+	if err := s.profileRepo.ReplaceForDataset(dataset.ID, records); err != nil {
+		return err
+	}
+
+	if len(profiles) == 0 {
+		return nil
+	}
+	if dataset.StartTime == nil {
+		dataset.StartTime = &start
+		ctx.set("start_time", dataset.StartTime)
+	}
+	if dataset.EndTime == nil {
+		dataset.EndTime = &end
+		ctx.set("end_time", dataset.EndTime)
+	}
+	if dataset.RegionBounds == "" {
+		dataset.RegionBounds = mustJSON([4]float64{roundTo(minLat, 6), roundTo(minLon, 6), roundTo(maxLat, 6), roundTo(maxLon, 6)})
+		ctx.set("region_bounds", dataset.RegionBounds)
+	}
+	return nil
+}
+
+// fillExtent 根据坐标回填数据集的时间范围、区域范围和空间分辨率，仅填写为空的字段
+func (s *ingestService) fillExtent(ctx *ingestContext) error {
+	ext := ctx.source.Extent()
+	dataset := ctx.dataset
+
+	if dataset.StartTime == nil && !ext.Start.IsZero() {
+		start := ext.Start
+		dataset.StartTime = &start
+		ctx.set("start_time", dataset.StartTime)
+	}
+	if dataset.EndTime == nil && !ext.End.IsZero() {
+		end := ext.End
+		dataset.EndTime = &end
+		ctx.set("end_time", dataset.EndTime)
+	}
+	if dataset.RegionBounds == "" && ext.HasBounds {
+		bounds := [4]float64{roundTo(ext.MinLat, 6), roundTo(ext.MinLon, 6), roundTo(ext.MaxLat, 6), roundTo(ext.MaxLon, 6)}
+		dataset.RegionBounds = mustJSON(bounds)
+		ctx.set("region_bounds", dataset.RegionBounds)
+	}
+	if dataset.SpatialResolution == "" && ext.LatStep > 0 && ext.LonStep > 0 {
+		lat, lon := roundTo(ext.LatStep, 6), roundTo(ext.LonStep, 6)
+		if lat == lon {
+			dataset.SpatialResolution = fmt.Sprintf("%g度", lat)
+		} else {
+			// 纬向×经向
+			dataset.SpatialResolution = fmt.Sprintf("%g度×%g度", lat, lon)
+		}
+		ctx.set("spatial_resolution", dataset.SpatialResolution)
+	}
+	return nil
+}
+
+// roundTo 保留n位小数
+func roundTo(x float64, n int) float64 {
+	p := math.Pow(10, float64(n))
+	return math.Round(x*p) / p
+}
+
+// computeStatistics 计算各变量的统计量、分位数和直方图
+func (s *ingestService) computeStatistics(ctx *ingestContext) error {
+	var results []*models.VariableStats
+	now := time.Now()
+
+	for _, v := range ctx.source.DataVariables() {
+		acc := stats.NewAccumulator(0)
+		if err := v.Each(0, func(_ int, values []float64) error {
+			acc.Add(values...)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("read %s: %w", v.Name, err)
+		}
+		summary := acc.Summary()
+
+		record := &models.VariableStats{
+			DatasetID:  ctx.dataset.ID,
+			Variable:   v.Name,
+			Unit:       v.Units(),
+			Count:      summary.Count,
+			Missing:    summary.Missing,
+			Exact:      acc.Exact(),
+			ComputedAt: &now,
+		}
+
+		if summary.Count > 0 {
+			record.Min, record.Max = summary.Min, summary.Max
+			record.Mean, record.Std = summary.Mean, finite(summary.Std)
+
+			percentiles := make(map[string]float64, len(statsPercentiles))
+			for i, p := range acc.Percentiles(statsPercentiles...) {
+				percentiles[fmt.Sprintf("p%g", statsPercentiles[i])] = p
+			}
+
+			hist := stats.NewHistogram(summary.Min, summary.Max, statsHistogramBins)
+			if err := v.Each(0, func(_ int, values []float64) error {
+				hist.Add(values...)
+				return nil
+			}); err != nil {
+				return fmt.Errorf("read %s: %w", v.Name, err)
+			}
+
+			record.Percentiles = mustJSON(percentiles)
+			record.Histogram = mustJSON(hist)
+
+			if info := ctx.variable(v.Name); info != nil {
+				info.Range = [2]float64{summary.Min, summary.Max}
+			}
+		}
+
+		results = append(results, record)
+	}
+
+	return s.datasetRepo.SaveVariableStats(ctx.dataset.ID, results)
+}
+
+// ListVariableStats 获取数据集全部变量的统计
+func (s *ingestService) ListVariableStats(datasetID string) ([]*VariableStatistics, error) {
+	records, err := s.datasetRepo.ListVariableStats(datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*VariableStatistics, len(records))
+	for i, r := range records {
+		out[i] = newVariableStatistics(r)
+	}
+	return out, nil
+}
+
+// GetVariableStats 获取单个变量的统计
+func (s *ingestService) GetVariableStats(datasetID, variable string) (*VariableStatistics, error) {
+	record, err := s.datasetRepo.GetVariableStats(datasetID, variable)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrStatsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newVariableStatistics(record), nil
+}
+
+// newVariableStatistics 解析统计记录中的JSON字段
+func newVariableStatistics(record *models.VariableStats) *VariableStatistics {
+	out := &VariableStatistics{VariableStats: record, Percentiles: map[string]float64{}}
+	if record.Percentiles != "" {
+		json.Unmarshal([]byte(record.Percentiles), &out.Percentiles)
+	}
+	if record.Histogram != "" {
+		out.Histogram = &stats.Histogram{}
+		json.Unmarshal([]byte(record.Histogram), out.Histogram)
+	}
+	return out
+}
+
+// finite NaN和Inf转换为0，避免写入数据库失败
+func finite(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0
+	}
+	return x
+}
+
+// mustJSON 序列化为JSON字符串，NaN和Inf序列化为null；仍然失败时记录日志并返回空字符串
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	var unsupported *json.UnsupportedValueError
+	if errors.As(err, &unsupported) {
+		data, err = json.Marshal(finiteJSON(reflect.ValueOf(v)))
+	}
+	if err != nil {
+		logger.Error("Failed to marshal JSON", "error", err, "type", fmt.Sprintf("%T", v))
+		return ""
+	}
+	return string(data)
+}
+
+// finiteJSON 将值转换为可序列化的等价结构，非有限浮点数替换为nil；
+// 结构体按json标签转换为map，实现了json.Marshaler的值保持不变
+func finiteJSON(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if x := v.Float(); math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+		return v.Interface()
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return finiteJSON(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // []byte按base64序列化
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = finiteJSON(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = finiteJSON(iter.Value())
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			fv := v.Field(i)
+			embedded := field.Anonymous && name == "" && fv.Kind() == reflect.Struct
+			if (!field.IsExported() && !embedded) || (name == "-" && opts == "") {
+				continue
+			}
+			if embedded {
+				if fields, ok := finiteJSON(fv).(map[string]interface{}); ok {
+					for k, x := range fields {
+						out[k] = x
+					}
+				}
+				continue
+			}
+			if strings.Contains(opts, "omitempty") && emptyJSON(fv) {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			out[name] = finiteJSON(fv)
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+// emptyJSON 值是否按omitempty省略
+func emptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotificationNotFound 通知不存在或不属于当前用户
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// NotificationList 通知列表
+type NotificationList struct {
+	Total         int64                    `json:"total"`
+	UnreadCount   int64                    `json:"unreadCount"`
+	Page          int                      `json:"page"`
+	Size          int                      `json:"size"`
+	Notifications []map[string]interface{} `json:"notifications"`
+}
+
+// NotificationService 通知服务接口
+type NotificationService interface {
+	// Notify 向用户发送通知，data为附加数据(如任务ID)，可为nil
+	Notify(userID, notificationType, title, content string, data map[string]interface{}) error
+	ListNotifications(userID string, unreadOnly bool, page, size int) (*NotificationList, error)
+	// MarkRead 标记用户的通知为已读
+	MarkRead(id, userID string) (*models.Notification, error)
+}
+
+// notificationService 通知服务实现
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+}
+
+// NewNotificationService 创建通知服务
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	return &notificationService{notificationRepo: notificationRepo}
+}
+
+// Notify 创建通知
+func (s *notificationService) Notify(userID, notificationType, title, content string, data map[string]interface{}) error {
+	notification := &models.Notification{
+		ID:      utils.GenerateID("ntf"),
+		UserID:  userID,
+		Type:    notificationType,
+		Title:   title,
+		Content: content,
+	}
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification data: %w", err)
+		}
+		notification.Data = string(payload)
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications 获取用户的通知列表及未读数
+func (s *notificationService) ListNotifications(userID string, unreadOnly bool, page, size int) (*NotificationList, error) {
+	notifications, total, err := s.notificationRepo.ListByUser(userID, unreadOnly, page, size)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notificationRepo.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &NotificationList{Total: total, UnreadCount: unread, Page: page, Size: size, Notifications: make([]map[string]interface{}, len(notifications))}
+	for i, n := range notifications {
+		list.Notifications[i] = map[string]interface{}{
+			"id":        n.ID,
+			"type":      n.Type,
+			"title":     n.Title,
+			"content":   n.Content,
+			"createdAt": n.CreatedAt,
+			"read":      n.Read,
+			"readAt":    n.ReadAt,
+			"data":      n.Payload(),
+		}
+	}
+	return list, nil
+}
+
+// MarkRead 标记通知为已读，已读的通知保持原来的已读时间
+func (s *notificationService) MarkRead(id, userID string) (*models.Notification, error) {
+	notification, err := s.notificationRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+	if notification.UserID != userID {
+		return nil, ErrNotificationNotFound
+	}
+	if notification.Read {
+		return notification, nil
+	}
+
+	now := time.Now()
+	if err := s.notificationRepo.MarkRead(id, now); err != nil {
+		return nil, err
+	}
+	notification.Read, notification.ReadAt = true, &now
+	return notification, nil
+}
@@ -0,0 +1,629 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// OAI-PMH 元数据前缀
+const (
+	OAIPrefixDC       = "oai_dc"
+	OAIPrefixISO19139 = "iso19139"
+)
+
+// OAI-PMH 集合前缀: 按数据类型和标签划分
+const (
+	oaiSetTypePrefix = "type:"
+	oaiSetTagPrefix  = "tag:"
+)
+
+// oaiPageSize 每次列表请求返回的记录数
+const oaiPageSize = 50
+
+// oaiDatestampLayout OAI-PMH 时间戳格式(秒级粒度)
+const oaiDatestampLayout = "2006-01-02T15:04:05Z"
+
+// OAIService OAI-PMH 元数据收割服务接口
+type OAIService interface {
+	// Handle 处理一次OAI-PMH请求，返回XML响应；协议错误在响应中以error元素返回，
+	// 数据库等内部错误返回error，此时没有响应内容
+	Handle(args url.Values) ([]byte, error)
+}
+
+// oaiService OAI-PMH 服务实现
+type oaiService struct {
+	datasetRepo   repository.DatasetRepository
+	userRepo      repository.UserRepository
+	systemService SystemService
+	baseURL       string
+	repoID        string // oai标识符中的仓库名
+}
+
+// NewOAIService 创建OAI-PMH服务
+func NewOAIService(datasetRepo repository.DatasetRepository, userRepo repository.UserRepository, systemService SystemService, baseURL string) OAIService {
+	repoID := "ssop"
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		repoID = u.Hostname()
+	}
+
+	return &oaiService{
+		datasetRepo:   datasetRepo,
+		userRepo:      userRepo,
+		systemService: systemService,
+		baseURL:       baseURL,
+		repoID:        repoID,
+	}
+}
+
+// oaiError OAI-PMH 协议错误
+type oaiError struct {
+	Code    string
+	Message string
+}
+
+func (e *oaiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// oaiResumption 续传令牌中保存的收割状态
+type oaiResumption struct {
+	Prefix string `json:"p"`
+	From   string `json:"f,omitempty"`
+	Until  string `json:"u,omitempty"`
+	Set    string `json:"s,omitempty"`
+	Offset int    `json:"o"`
+}
+
+// oaiListRequest 解析后的列表请求
+type oaiListRequest struct {
+	oaiResumption
+	from  *time.Time
+	until *time.Time
+}
+
+// 各动词允许的参数
+var oaiVerbArgs = map[string][]string{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier"},
+	"ListSets":            {"resumptionToken"},
+	"GetRecord":           {"identifier", "metadataPrefix"},
+	"ListIdentifiers":     {"from", "until", "set", "metadataPrefix", "resumptionToken"},
+	"ListRecords":         {"from", "until", "set", "metadataPrefix", "resumptionToken"},
+}
+
+// Handle 处理OAI-PMH请求
+func (s *oaiService) Handle(args url.Values) ([]byte, error) {
+	verb := args.Get("verb")
+
+	var body []byte
+	err := s.checkArgs(verb, args)
+	if err == nil {
+		switch verb {
+		case "Identify":
+			body, err = s.identify()
+		case "ListMetadataFormats":
+			body, err = s.listMetadataFormats(args.Get("identifier"))
+		case "ListSets":
+			body, err = s.listSets(args.Get("resumptionToken"))
+		case "GetRecord":
+			body, err = s.getRecord(args.Get("identifier"), args.Get("metadataPrefix"))
+		case "ListIdentifiers", "ListRecords":
+			body, err = s.listRecords(verb, args)
+		}
+	}
+
+	if _, ok := err.(*oaiError); err != nil && !ok {
+		logger.Error("OAI-PMH request failed", "error", err, "verb", verb)
+		return nil, err
+	}
+	return s.envelope(verb, args, body, err), nil
+}
+
+// checkArgs 校验动词及参数
+func (s *oaiService) checkArgs(verb string, args url.Values) error {
+	allowed, ok := oaiVerbArgs[verb]
+	if !ok {
+		return &oaiError{"badVerb", "Illegal OAI verb"}
+	}
+
+	for key, values := range args {
+		if key == "verb" {
+			if len(values) > 1 {
+				return &oaiError{"badVerb", "Verb argument is repeated"}
+			}
+			continue
+		}
+		if !containsString(allowed, key) {
+			return &oaiError{"badArgument", "Illegal argument: " + key}
+		}
+		if len(values) > 1 {
+			return &oaiError{"badArgument", "Repeated argument: " + key}
+		}
+	}
+
+	// resumptionToken 为排他参数
+	if args.Get("resumptionToken") != "" && len(args) > 2 {
+		return &oaiError{"badArgument", "resumptionToken is an exclusive argument"}
+	}
+
+	switch verb {
+	case "GetRecord":
+		if args.Get("identifier") == "" || args.Get("metadataPrefix") == "" {
+			return &oaiError{"badArgument", "identifier and metadataPrefix are required"}
+		}
+	case "ListIdentifiers", "ListRecords":
+		if args.Get("resumptionToken") == "" && args.Get("metadataPrefix") == "" {
+			return &oaiError{"badArgument", "metadataPrefix is required"}
+		}
+	}
+	return nil
+}
+
+// identify 仓库描述
+func (s *oaiService) identify() ([]byte, error) {
+	earliest := time.Unix(0, 0).UTC()
+	if t, err := s.datasetRepo.EarliestUpdatedAt(); err == nil && t != nil {
+		earliest = t.UTC()
+	}
+
+	adminEmail, err := s.systemService.GetSetting("oai.admin_email")
+	if err != nil || adminEmail == "" {
+		adminEmail = "admin@example.com"
+	}
+	repositoryName, err := s.systemService.GetSetting("oai.repository_name")
+	if err != nil || repositoryName == "" {
+		repositoryName = defaultPublisher
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<Identify>\n")
+	fmt.Fprintf(&buf, "  <repositoryName>%s</repositoryName>\n", xmlEscape(repositoryName))
+	fmt.Fprintf(&buf, "  <baseURL>%s</baseURL>\n", xmlEscape(s.endpoint()))
+	fmt.Fprintf(&buf, "  <protocolVersion>2.0</protocolVersion>\n")
+	fmt.Fprintf(&buf, "  <adminEmail>%s</adminEmail>\n", xmlEscape(adminEmail))
+	fmt.Fprintf(&buf, "  <earliestDatestamp>%s</earliestDatestamp>\n", earliest.Format(oaiDatestampLayout))
+	fmt.Fprintf(&buf, "  <deletedRecord>transient</deletedRecord>\n")
+	fmt.Fprintf(&buf, "  <granularity>YYYY-MM-DDThh:mm:ssZ</granularity>\n")
+	fmt.Fprintf(&buf, "  <description>\n")
+	fmt.Fprintf(&buf, "    <oai-identifier xmlns=\"http://www.openarchives.org/OAI/2.0/oai-identifier\" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\" xsi:schemaLocation=\"http://www.openarchives.org/OAI/2.0/oai-identifier http://www.openarchives.org/OAI/2.0/oai-identifier.xsd\">\n")
+	fmt.Fprintf(&buf, "      <scheme>oai</scheme>\n")
+	fmt.Fprintf(&buf, "      <repositoryIdentifier>%s</repositoryIdentifier>\n", xmlEscape(s.repoID))
+	fmt.Fprintf(&buf, "      <delimiter>:</delimiter>\n")
+	fmt.Fprintf(&buf, "      <sampleIdentifier>%s</sampleIdentifier>\n", xmlEscape(s.identifier("ds_1700000000000_abcdef")))
+	fmt.Fprintf(&buf, "    </oai-identifier>\n")
+	fmt.Fprintf(&buf, "  </description>\n")
+	fmt.Fprintf(&buf, "</Identify>")
+	return buf.Bytes(), nil
+}
+
+// listMetadataFormats 支持的元数据格式
+func (s *oaiService) listMetadataFormats(identifier string) ([]byte, error) {
+	if identifier != "" {
+		if _, err := s.datasetByIdentifier(identifier); err != nil {
+			return nil, err
+		}
+	}
+
+	return []byte(`<ListMetadataFormats>
+  <metadataFormat>
+    <metadataPrefix>oai_dc</metadataPrefix>
+    <schema>http://www.openarchives.org/OAI/2.0/oai_dc.xsd</schema>
+    <metadataNamespace>http://www.openarchives.org/OAI/2.0/oai_dc/</metadataNamespace>
+  </metadataFormat>
+  <metadataFormat>
+    <metadataPrefix>iso19139</metadataPrefix>
+    <schema>http://www.isotc211.org/2005/gmd/gmd.xsd</schema>
+    <metadataNamespace>http://www.isotc211.org/2005/gmd</metadataNamespace>
+  </metadataFormat>
+</ListMetadataFormats>`), nil
+}
+
+// listSets 集合列表(数据类型和标签)
+func (s *oaiService) listSets(resumptionToken string) ([]byte, error) {
+	if resumptionToken != "" {
+		// 集合数量有限，一次返回全部，不会产生续传令牌
+		return nil, &oaiError{"badResumptionToken", "Invalid resumption token"}
+	}
+
+	types, err := s.datasetRepo.ListTypes()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.datasetRepo.ListTags()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<ListSets>\n")
+	buf.WriteString("  <set><setSpec>type</setSpec><setName>按数据类型</setName></set>\n")
+	buf.WriteString("  <set><setSpec>tag</setSpec><setName>按标签</setName></set>\n")
+	for _, t := range types {
+		fmt.Fprintf(&buf, "  <set><setSpec>%s</setSpec><setName>数据类型: %s</setName></set>\n",
+			xmlEscape(oaiSetTypePrefix+setSpecEscape(t)), xmlEscape(t))
+	}
+	for _, t := range tags {
+		fmt.Fprintf(&buf, "  <set><setSpec>%s</setSpec><setName>标签: %s</setName></set>\n",
+			xmlEscape(oaiSetTagPrefix+setSpecEscape(t)), xmlEscape(t))
+	}
+	buf.WriteString("</ListSets>")
+	return buf.Bytes(), nil
+}
+
+// getRecord 获取单条记录
+func (s *oaiService) getRecord(identifier, prefix string) ([]byte, error) {
+	if !isOAIPrefix(prefix) {
+		return nil, &oaiError{"cannotDisseminateFormat", "Unsupported metadataPrefix: " + prefix}
+	}
+
+	dataset, err := s.datasetByIdentifier(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.record(dataset, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte("<GetRecord>\n" + record + "</GetRecord>"), nil
+}
+
+// listRecords 处理ListIdentifiers和ListRecords
+func (s *oaiService) listRecords(verb string, args url.Values) ([]byte, error) {
+	req, err := s.parseListRequest(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// 回收站中的数据集以删除记录返回，彻底删除后不再出现
+	filters := map[string]interface{}{
+		"orderBy":        "updated",
+		"includeDeleted": true,
+	}
+	if req.from != nil {
+		filters["updatedFrom"] = *req.from
+	}
+	if req.until != nil {
+		filters["updatedUntil"] = *req.until
+	}
+	// 顶层集合 type 和 tag 包含全部记录
+	if req.Set != "" && req.Set != "type" && req.Set != "tag" {
+		switch {
+		case strings.HasPrefix(req.Set, oaiSetTypePrefix):
+			filters["type"] = setSpecUnescape(strings.TrimPrefix(req.Set, oaiSetTypePrefix))
+		case strings.HasPrefix(req.Set, oaiSetTagPrefix):
+			filters["tag"] = setSpecUnescape(strings.TrimPrefix(req.Set, oaiSetTagPrefix))
+		default:
+			return nil, &oaiError{"noRecordsMatch", "Unknown set: " + req.Set}
+		}
+	}
+
+	// 通过偏移量计算页码
+	page := req.Offset/oaiPageSize + 1
+	datasets, total, err := s.datasetRepo.List(page, oaiPageSize, filters)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 || len(datasets) == 0 {
+		return nil, &oaiError{"noRecordsMatch", "No records match the request"}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%s>\n", verb)
+	for _, dataset := range datasets {
+		if verb == "ListIdentifiers" {
+			buf.WriteString(s.header(dataset))
+			continue
+		}
+		record, err := s.record(dataset, req.Prefix)
+		if err != nil {
+			logger.Error("Failed to render OAI record", "error", err, "datasetId", dataset.ID)
+			continue
+		}
+		buf.WriteString(record)
+	}
+
+	// 续传令牌
+	nextOffset := req.Offset + len(datasets)
+	if int64(nextOffset) < total {
+		next := req.oaiResumption
+		next.Offset = nextOffset
+		fmt.Fprintf(&buf, "  <resumptionToken completeListSize=\"%d\" cursor=\"%d\">%s</resumptionToken>\n",
+			total, req.Offset, encodeResumptionToken(next))
+	} else if args.Get("resumptionToken") != "" {
+		// 最后一页返回空令牌表示列表结束
+		fmt.Fprintf(&buf, "  <resumptionToken completeListSize=\"%d\" cursor=\"%d\"/>\n", total, req.Offset)
+	}
+	fmt.Fprintf(&buf, "</%s>", verb)
+	return buf.Bytes(), nil
+}
+
+// parseListRequest 解析列表参数或续传令牌
+func (s *oaiService) parseListRequest(args url.Values) (*oaiListRequest, error) {
+	req := &oaiListRequest{}
+
+	if token := args.Get("resumptionToken"); token != "" {
+		resumption, err := decodeResumptionToken(token)
+		if err != nil {
+			return nil, &oaiError{"badResumptionToken", "Invalid resumption token"}
+		}
+		req.oaiResumption = *resumption
+	} else {
+		req.Prefix = args.Get("metadataPrefix")
+		req.From = args.Get("from")
+		req.Until = args.Get("until")
+		req.Set = args.Get("set")
+	}
+
+	if !isOAIPrefix(req.Prefix) {
+		return nil, &oaiError{"cannotDisseminateFormat", "Unsupported metadataPrefix: " + req.Prefix}
+	}
+
+	var fromGranularity, untilGranularity int
+	if req.From != "" {
+		from, granularity, err := parseOAIDate(req.From, false)
+		if err != nil {
+			return nil, &oaiError{"badArgument", "Invalid from date: " + req.From}
+		}
+		req.from, fromGranularity = &from, granularity
+	}
+	if req.Until != "" {
+		until, granularity, err := parseOAIDate(req.Until, true)
+		if err != nil {
+			return nil, &oaiError{"badArgument", "Invalid until date: " + req.Until}
+		}
+		req.until, untilGranularity = &until, granularity
+	}
+	if req.from != nil && req.until != nil {
+		if fromGranularity != untilGranularity {
+			return nil, &oaiError{"badArgument", "from and until must have the same granularity"}
+		}
+		if req.from.After(*req.until) {
+			return nil, &oaiError{"badArgument", "from must not be later than until"}
+		}
+	}
+
+	return req, nil
+}
+
+// header 记录头，回收站中的数据集标记为已删除
+func (s *oaiService) header(dataset *models.Dataset) string {
+	var buf bytes.Buffer
+	if dataset.DeletedAt.Valid {
+		buf.WriteString("  <header status=\"deleted\">\n")
+	} else {
+		buf.WriteString("  <header>\n")
+	}
+	fmt.Fprintf(&buf, "    <identifier>%s</identifier>\n", xmlEscape(s.identifier(dataset.ID)))
+	fmt.Fprintf(&buf, "    <datestamp>%s</datestamp>\n", datestamp(dataset))
+	if dataset.Type != "" {
+		fmt.Fprintf(&buf, "    <setSpec>%s</setSpec>\n", xmlEscape(oaiSetTypePrefix+setSpecEscape(dataset.Type)))
+	}
+	for _, tag := range dataset.TagList() {
+		fmt.Fprintf(&buf, "    <setSpec>%s</setSpec>\n", xmlEscape(oaiSetTagPrefix+setSpecEscape(tag)))
+	}
+	buf.WriteString("  </header>\n")
+	return buf.String()
+}
+
+// record 完整记录(头+元数据)，已删除的记录只有记录头
+func (s *oaiService) record(dataset *models.Dataset, prefix string) (string, error) {
+	if dataset.DeletedAt.Valid {
+		return "<record>\n" + s.header(dataset) + "</record>\n", nil
+	}
+	citation := newCitationRecord(dataset, datasetAuthor(s.userRepo, dataset), s.baseURL)
+
+	var metadata []byte
+	var err error
+	switch prefix {
+	case OAIPrefixDC:
+		metadata, err = executeTextTemplate(oaiDCTemplate, citation)
+	case OAIPrefixISO19139:
+		metadata, err = executeTextTemplate(isoTemplate, isoDocument{Root: "gmd:MD_Metadata", Record: citation})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "<record>\n" + s.header(dataset) + "  <metadata>\n" + string(metadata) + "  </metadata>\n</record>\n", nil
+}
+
+// datasetByIdentifier 根据OAI标识符获取数据集
+func (s *oaiService) datasetByIdentifier(identifier string) (*models.Dataset, error) {
+	prefix := "oai:" + s.repoID + ":"
+	if !strings.HasPrefix(identifier, prefix) {
+		return nil, &oaiError{"idDoesNotExist", "Unknown identifier: " + identifier}
+	}
+
+	id := strings.TrimPrefix(identifier, prefix)
+	dataset, err := s.datasetRepo.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		dataset, err = s.datasetRepo.GetDeletedByID(id)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &oaiError{"idDoesNotExist", "Unknown identifier: " + identifier}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// identifier 数据集的OAI标识符
+func (s *oaiService) identifier(datasetID string) string {
+	return "oai:" + s.repoID + ":" + datasetID
+}
+
+// endpoint OAI-PMH 服务地址
+func (s *oaiService) endpoint() string {
+	return s.baseURL + "/api/v1/oai"
+}
+
+// envelope 包装OAI-PMH响应，err为nil或协议错误
+func (s *oaiService) envelope(verb string, args url.Values, body []byte, err error) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	buf.WriteString(`<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd">` + "\n")
+	fmt.Fprintf(&buf, "<responseDate>%s</responseDate>\n", time.Now().UTC().Format(oaiDatestampLayout))
+
+	// badVerb和badArgument错误时request元素不带参数
+	oaiErr, _ := err.(*oaiError)
+	if oaiErr != nil && (oaiErr.Code == "badVerb" || oaiErr.Code == "badArgument") {
+		fmt.Fprintf(&buf, "<request>%s</request>\n", xmlEscape(s.endpoint()))
+	} else {
+		buf.WriteString("<request")
+		fmt.Fprintf(&buf, " verb=\"%s\"", xmlEscape(verb))
+		for _, key := range oaiVerbArgs[verb] {
+			if value := args.Get(key); value != "" {
+				fmt.Fprintf(&buf, " %s=\"%s\"", key, xmlEscape(value))
+			}
+		}
+		fmt.Fprintf(&buf, ">%s</request>\n", xmlEscape(s.endpoint()))
+	}
+
+	if oaiErr != nil {
+		fmt.Fprintf(&buf, "<error code=\"%s\">%s</error>\n", oaiErr.Code, xmlEscape(oaiErr.Message))
+	} else {
+		buf.Write(body)
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("</OAI-PMH>\n")
+	return buf.Bytes()
+}
+
+// isOAIPrefix 检查元数据前缀是否支持
+func isOAIPrefix(prefix string) bool {
+	return prefix == OAIPrefixDC || prefix == OAIPrefixISO19139
+}
+
+// datestamp 记录时间戳
+func datestamp(dataset *models.Dataset) string {
+	if dataset.UpdatedAt != nil {
+		return dataset.UpdatedAt.UTC().Format(oaiDatestampLayout)
+	}
+	if dataset.CreatedAt != nil {
+		return dataset.CreatedAt.UTC().Format(oaiDatestampLayout)
+	}
+	return time.Unix(0, 0).UTC().Format(oaiDatestampLayout)
+}
+
+// parseOAIDate 解析OAI日期参数，返回时间和粒度(1为天，2为秒)
+// until 为天粒度时取当天结束时刻
+func parseOAIDate(value string, endOfDay bool) (time.Time, int, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t, 1, nil
+	}
+	t, err := time.Parse(oaiDatestampLayout, value)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return t, 2, nil
+}
+
+// encodeResumptionToken 编码续传令牌
+func encodeResumptionToken(r oaiResumption) string {
+	data, _ := json.Marshal(r)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeResumptionToken 解码续传令牌
+func decodeResumptionToken(token string) (*oaiResumption, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var r oaiResumption
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d", r.Offset)
+	}
+	return &r, nil
+}
+
+// setSpecEscape 集合名中只允许非保留字符，其余字符进行百分号编码
+func setSpecEscape(s string) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || strings.IndexByte("-_.!~*'()", c) >= 0 {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// setSpecUnescape 还原集合名
+func setSpecUnescape(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// containsString 检查切片中是否包含字符串
+func containsString(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+	return false
+}
+
+var oaiDCTemplate = template.Must(template.New("oai_dc").Funcs(metadataFuncs).Parse(`<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd">
+  <dc:title>{{xml .Dataset.Name}}</dc:title>
+  <dc:creator>{{xml .Author.Name}}</dc:creator>
+  {{- range .Keywords}}
+  <dc:subject>{{xml .}}</dc:subject>
+  {{- end}}
+  <dc:description>{{xml .Dataset.Description}}</dc:description>
+  <dc:publisher>{{xml .Publisher}}</dc:publisher>
+  {{- if .Dataset.CreatedAt}}
+  <dc:date>{{date .Dataset.CreatedAt "2006-01-02"}}</dc:date>
+  {{- end}}
+  <dc:type>Dataset</dc:type>
+  {{- if .Dataset.Format}}
+  <dc:format>{{xml .Dataset.Format}}</dc:format>
+  {{- end}}
+  <dc:identifier>{{xml .URL}}</dc:identifier>
+  {{- if .Dataset.DOI}}
+  <dc:identifier>https://doi.org/{{xml .Dataset.DOI}}</dc:identifier>
+  {{- end}}
+  {{- if .Dataset.Source}}
+  <dc:source>{{xml .Dataset.Source}}</dc:source>
+  {{- end}}
+  {{- if .Dataset.RegionName}}
+  <dc:coverage>{{xml .Dataset.RegionName}}</dc:coverage>
+  {{- end}}
+  {{- if .HasBounds}}
+  <dc:coverage>northlimit={{index .Bounds 2}}; southlimit={{index .Bounds 0}}; westlimit={{index .Bounds 1}}; eastlimit={{index .Bounds 3}}</dc:coverage>
+  {{- end}}
+  {{- if and .Dataset.StartTime .Dataset.EndTime}}
+  <dc:coverage>{{date .Dataset.StartTime "2006-01-02"}}/{{date .Dataset.EndTime "2006-01-02"}}</dc:coverage>
+  {{- end}}
+  {{- if .Dataset.License}}
+  <dc:rights>{{xml .Dataset.License}}</dc:rights>
+  {{- end}}
+</oai_dc:dc>
+`))
@@ -0,0 +1,697 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/netcdf"
+	"github.com/sinker/ssop/pkg/qc"
+	"github.com/sinker/ssop/pkg/units"
+)
+
+const (
+	// qcFlagsFile 质量控制标志文件名，与数据文件存放在同一目录
+	qcFlagsFile = "qc_flags.nc"
+	// qcFlagSuffix 标志变量名后缀
+	qcFlagSuffix = "_qc"
+	// qcMaxValues 单个变量参与质量控制的最大元素数
+	qcMaxValues = 20000000
+	// qcConfigSetting 默认质量控制配置的系统设置键
+	qcConfigSetting = "qc.default_config"
+	// defaultDensityThreshold 密度逆转检验的默认阈值(kg/m3)
+	defaultDensityThreshold = 0.03
+)
+
+var (
+	// ErrQCNotFound 数据集尚未进行质量控制
+	ErrQCNotFound = errors.New("quality control has not been run for this dataset")
+	// ErrQCForbidden 无权对数据集进行质量控制
+	ErrQCForbidden = errors.New("dataset belongs to another user")
+)
+
+// QCConfig 质量控制配置
+type QCConfig struct {
+	Variables        map[string]*QCVariableConfig `json:"variables"`
+	DensityInversion *QCDensityConfig             `json:"densityInversion,omitempty"`
+}
+
+// QCVariableConfig 单个变量的检验配置，未配置的检验不执行
+type QCVariableConfig struct {
+	Axis          string            `json:"axis,omitempty"` // 序列检验的方向: time、depth或维度名，默认有深度维时沿深度，否则沿时间
+	GrossRange    *qc.Range         `json:"grossRange,omitempty"`
+	RegionalRange []QCRegionalRange `json:"regionalRange,omitempty"`
+	Spike         *qc.Threshold     `json:"spike,omitempty"`
+	Gradient      *qc.Threshold     `json:"gradient,omitempty"` // 变化率阈值，单位为变量单位/坐标单位
+	FlatLine      *qc.FlatLine      `json:"flatLine,omitempty"`
+	Climatology   []QCClimatology   `json:"climatology,omitempty"`
+}
+
+// QCRegionalRange 区域范围检验，仅检验落在Bounds内的值
+type QCRegionalRange struct {
+	Bounds [4]float64 `json:"bounds"` // [minLat, minLng, maxLat, maxLng]
+	qc.Range
+}
+
+// QCClimatology 气候态检验，超出[Min, Max]的值标记为可疑
+type QCClimatology struct {
+	Months     []int       `json:"months"`               // 适用月份(1-12)，为空表示全部月份
+	Bounds     *[4]float64 `json:"bounds,omitempty"`     // 适用区域
+	DepthRange *[2]float64 `json:"depthRange,omitempty"` // 适用深度/压力范围
+	Min        float64     `json:"min"`
+	Max        float64     `json:"max"`
+}
+
+// QCDensityConfig 密度逆转检验配置
+type QCDensityConfig struct {
+	Temperature string  `json:"temperature"`
+	Salinity    string  `json:"salinity"`
+	Pressure    string  `json:"pressure,omitempty"` // 为空时使用温度变量的深度坐标
+	Threshold   float64 `json:"threshold,omitempty"`
+}
+
+// QCSummary 质量控制结果汇总
+type QCSummary struct {
+	RunAt     time.Time                     `json:"runAt"`
+	Config    *QCConfig                     `json:"config"`
+	Variables map[string]*QCVariableSummary `json:"variables"`
+	Error     string                        `json:"error,omitempty"` // 后台执行失败的原因
+}
+
+// QCVariableSummary 单个变量的质量控制结果
+type QCVariableSummary struct {
+	Counts  map[string]int64            `json:"counts"`            // 各标志的数量
+	Tests   map[string]map[string]int64 `json:"tests"`             // 各检验标记为可疑/错误的数量
+	Skipped []string                    `json:"skipped,omitempty"` // 未执行的检验及原因
+}
+
+// flagNames 标志名称
+var flagNames = map[byte]string{
+	qc.Pass:         "pass",
+	qc.NotEvaluated: "notEvaluated",
+	qc.Suspect:      "suspect",
+	qc.Fail:         "fail",
+	qc.Missing:      "missing",
+}
+
+// QCService 质量控制服务接口
+type QCService interface {
+	// Check 对已打开的数据源执行质量控制，写入标志文件并更新数据集的QC汇总(不保存数据集)
+	Check(dataset *models.Dataset, source *dataio.Source, config *QCConfig) (*QCSummary, error)
+	// Run 异步执行质量控制，config为nil时使用默认配置
+	Run(datasetID, userID string, isAdmin bool, config *QCConfig) error
+	// GetSummary 获取质量控制汇总
+	GetSummary(datasetID string) (*QCSummary, error)
+	// FlagsFile 获取标志文件路径
+	FlagsFile(datasetID string) (string, error)
+}
+
+// qcService 质量控制服务实现
+type qcService struct {
+	datasetRepo   repository.DatasetRepository
+	systemService SystemService
+}
+
+// NewQCService 创建质量控制服务
+func NewQCService(datasetRepo repository.DatasetRepository, systemService SystemService) QCService {
+	return &qcService{
+		datasetRepo:   datasetRepo,
+		systemService: systemService,
+	}
+}
+
+// Run 异步执行质量控制
+func (s *qcService) Run(datasetID, userID string, isAdmin bool, config *QCConfig) error {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return fmt.Errorf("dataset not found: %w", err)
+	}
+	if !isAdmin && dataset.CreatedBy != userID {
+		return ErrQCForbidden
+	}
+	if dataset.FilePath == "" {
+		return fmt.Errorf("dataset %s has no data file", datasetID)
+	}
+
+	go func() {
+		defer func() {
+			// 读取异常文件时的panic只使本次质量控制失败，不影响服务进程
+			if r := recover(); r != nil {
+				logger.Error("Quality control panicked", "panic", r, "datasetId", datasetID, "stack", string(debug.Stack()))
+				s.saveFailure(datasetID, config, fmt.Sprintf("internal error: %v", r))
+			}
+		}()
+
+		source, err := dataio.Open(dataset.FilePath, dataset.Format)
+		if err != nil {
+			logger.Error("Failed to open dataset for QC", "error", err, "datasetId", datasetID)
+			s.saveFailure(datasetID, config, err.Error())
+			return
+		}
+		defer source.Close()
+
+		if _, err := s.Check(dataset, source, config); err != nil {
+			logger.Error("Quality control failed", "error", err, "datasetId", datasetID)
+			s.saveFailure(datasetID, config, err.Error())
+			return
+		}
+		// 只写回汇总，避免覆盖质量控制期间对数据集的修改
+		if err := s.datasetRepo.UpdateFields(datasetID, map[string]interface{}{"qc_summary": dataset.QCSummary}); err != nil {
+			logger.Error("Failed to save QC summary", "error", err, "datasetId", datasetID)
+		}
+	}()
+	return nil
+}
+
+// saveFailure 后台质量控制失败时写入带失败原因的汇总
+func (s *qcService) saveFailure(datasetID string, config *QCConfig, message string) {
+	summary, err := json.Marshal(&QCSummary{RunAt: time.Now(), Config: config, Error: message})
+	if err == nil {
+		err = s.datasetRepo.UpdateFields(datasetID, map[string]interface{}{"qc_summary": string(summary)})
+	}
+	if err != nil {
+		logger.Error("Failed to save QC summary", "error", err, "datasetId", datasetID)
+	}
+}
+
+// GetSummary 获取质量控制汇总
+func (s *qcService) GetSummary(datasetID string) (*QCSummary, error) {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return nil, fmt.Errorf("dataset not found: %w", err)
+	}
+	if dataset.QCSummary == "" {
+		return nil, ErrQCNotFound
+	}
+
+	var summary QCSummary
+	if err := json.Unmarshal([]byte(dataset.QCSummary), &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// FlagsFile 获取标志文件路径
+func (s *qcService) FlagsFile(datasetID string) (string, error) {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return "", fmt.Errorf("dataset not found: %w", err)
+	}
+	path := qcFlagsPath(dataset)
+	if path == "" {
+		return "", ErrQCNotFound
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrQCNotFound
+	}
+	return path, nil
+}
+
+// Check 执行质量控制
+func (s *qcService) Check(dataset *models.Dataset, source *dataio.Source, config *QCConfig) (*QCSummary, error) {
+	if config == nil {
+		config = s.defaultConfig(source)
+	}
+
+	summary := &QCSummary{RunAt: time.Now(), Config: config, Variables: map[string]*QCVariableSummary{}}
+	flags := map[string][]byte{}
+
+	for name, vc := range config.Variables {
+		v := source.Var(name)
+		if v == nil || v.IsText() {
+			continue
+		}
+
+		vs := &QCVariableSummary{Tests: map[string]map[string]int64{}}
+		summary.Variables[name] = vs
+		if n := v.Size(); n <= 0 || n > qcMaxValues {
+			vs.Skipped = append(vs.Skipped, fmt.Sprintf("all: variable has %d values, limit is %d", n, qcMaxValues))
+			continue
+		}
+
+		values, err := v.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		flags[name] = s.checkVariable(source, v, values, vc, vs)
+	}
+
+	if dc := config.DensityInversion; dc != nil {
+		s.checkDensity(source, dc, flags, summary)
+	}
+
+	for name, f := range flags {
+		counts := qc.Counts(f)
+		summary.Variables[name].Counts = map[string]int64{}
+		for flag, n := range counts {
+			summary.Variables[name].Counts[flagNames[flag]] = n
+		}
+	}
+
+	if len(flags) > 0 {
+		if err := writeQCFlags(qcFlagsPath(dataset), dataset, source, flags); err != nil {
+			return nil, fmt.Errorf("write QC flags: %w", err)
+		}
+	}
+
+	dataset.QCSummary = mustJSON(summary)
+	return summary, nil
+}
+
+// checkVariable 对单个变量执行配置的检验
+func (s *qcService) checkVariable(source *dataio.Source, v *dataio.Variable, values []float64, vc *QCVariableConfig, vs *QCVariableSummary) []byte {
+	flags := qc.NewFlags(values)
+	record := func(test string, result []byte) {
+		counts := qc.Counts(result)
+		vs.Tests[test] = map[string]int64{"suspect": counts[qc.Suspect], "fail": counts[qc.Fail]}
+		qc.Merge(flags, result)
+	}
+	skip := func(test, reason string) {
+		vs.Skipped = append(vs.Skipped, test+": "+reason)
+	}
+
+	if vc.GrossRange != nil {
+		record("grossRange", qc.RangeTest(values, *vc.GrossRange, nil))
+	}
+
+	// 区域范围与气候态检验需要逐点坐标
+	needsPosition := len(vc.RegionalRange) > 0 || len(vc.Climatology) > 0
+	var lat, lon []float64
+	if needsPosition {
+		lat, _ = source.CoordinateValues(v, dataio.AxisLat)
+		lon, _ = source.CoordinateValues(v, dataio.AxisLon)
+	}
+
+	if len(vc.RegionalRange) > 0 {
+		if lat == nil || lon == nil {
+			skip("regionalRange", "latitude/longitude coordinates not found")
+		} else {
+			result := qc.NewFlags(values)
+			for _, rr := range vc.RegionalRange {
+				b := rr.Bounds
+				qc.Merge(result, qc.RangeTest(values, rr.Range, func(i int) bool {
+					return lat[i] >= b[0] && lat[i] <= b[2] && lon[i] >= b[1] && lon[i] <= b[3]
+				}))
+			}
+			record("regionalRange", result)
+		}
+	}
+
+	if len(vc.Climatology) > 0 {
+		months, err := elementMonths(source, v)
+		if err != nil {
+			skip("climatology", err.Error())
+		} else {
+			depth, _ := source.CoordinateValues(v, dataio.AxisZ)
+			result := qc.NewFlags(values)
+			for _, c := range vc.Climatology {
+				c := c
+				r := qc.Range{SuspectMin: c.Min, SuspectMax: c.Max}
+				qc.Merge(result, qc.RangeTest(values, r, func(i int) bool {
+					if len(c.Months) > 0 && !containsInt(c.Months, months[i]) {
+						return false
+					}
+					if c.Bounds != nil {
+						if lat == nil || lon == nil {
+							return false
+						}
+						b := *c.Bounds
+						if lat[i] < b[0] || lat[i] > b[2] || lon[i] < b[1] || lon[i] > b[3] {
+							return false
+						}
+					}
+					if c.DepthRange != nil {
+						if depth == nil || depth[i] < c.DepthRange[0] || depth[i] > c.DepthRange[1] {
+							return false
+						}
+					}
+					return true
+				}))
+			}
+			record("climatology", result)
+		}
+	}
+
+	// 序列检验
+	if vc.Spike != nil || vc.Gradient != nil || vc.FlatLine != nil {
+		axis, coord := seriesAxis(source, v, vc.Axis)
+		if axis < 0 {
+			skip("series", "no axis to run spike/gradient/flat line tests along")
+		} else {
+			spike, gradient, flat := qc.NewFlags(values), qc.NewFlags(values), qc.NewFlags(values)
+			qc.ForEachSeries(v.Shape, axis, func(indices []int) {
+				series := qc.Gather(values, indices)
+				if vc.Spike != nil {
+					qc.Scatter(spike, indices, qc.SpikeTest(series, *vc.Spike))
+				}
+				if vc.Gradient != nil {
+					qc.Scatter(gradient, indices, qc.RateOfChangeTest(series, coord, *vc.Gradient))
+				}
+				if vc.FlatLine != nil {
+					qc.Scatter(flat, indices, qc.FlatLineTest(series, *vc.FlatLine))
+				}
+			})
+			if vc.Spike != nil {
+				record("spike", spike)
+			}
+			if vc.Gradient != nil {
+				record("gradient", gradient)
+			}
+			if vc.FlatLine != nil {
+				record("flatLine", flat)
+			}
+		}
+	}
+
+	return flags
+}
+
+// checkDensity 密度逆转检验，结果同时合并到温度和盐度的标志中
+func (s *qcService) checkDensity(source *dataio.Source, dc *QCDensityConfig, flags map[string][]byte, summary *QCSummary) {
+	tv, sv := source.Var(dc.Temperature), source.Var(dc.Salinity)
+	if tv == nil || sv == nil || tv.Size() != sv.Size() || tv.Size() <= 0 || tv.Size() > qcMaxValues {
+		return
+	}
+
+	temp, err := tv.ReadAll()
+	if err != nil {
+		return
+	}
+	salt, err := sv.ReadAll()
+	if err != nil {
+		return
+	}
+	// 状态方程使用摄氏温度，开尔文等其他温度单位先换算
+	if c, err := units.NewConverter(tv.Units(), "degC"); err == nil {
+		c.ConvertAll(temp)
+	}
+
+	var pressure []float64
+	axis := -1
+	if pv := source.Var(dc.Pressure); pv != nil && pv.Size() == tv.Size() {
+		pressure, _ = pv.ReadAll()
+		axis = source.AxisDim(tv, dataio.AxisZ)
+		if axis < 0 && len(tv.Shape) > 0 {
+			axis = len(tv.Shape) - 1
+		}
+	} else {
+		pressure, _ = source.CoordinateValues(tv, dataio.AxisZ)
+		axis = source.AxisDim(tv, dataio.AxisZ)
+	}
+	if pressure == nil || axis < 0 {
+		return
+	}
+
+	threshold := dc.Threshold
+	if threshold <= 0 {
+		threshold = defaultDensityThreshold
+	}
+
+	density := make([]float64, len(temp))
+	for i := range temp {
+		density[i] = qc.Density0(salt[i], temp[i])
+	}
+
+	result := qc.NewFlags(density)
+	qc.ForEachSeries(tv.Shape, axis, func(indices []int) {
+		qc.Scatter(result, indices, qc.DensityInversionTest(qc.Gather(density, indices), qc.Gather(pressure, indices), threshold))
+	})
+
+	counts := qc.Counts(result)
+	for _, name := range []string{dc.Temperature, dc.Salinity} {
+		if _, ok := flags[name]; !ok {
+			flags[name] = qc.NewFlags(density)
+			summary.Variables[name] = &QCVariableSummary{Tests: map[string]map[string]int64{}}
+		}
+		qc.Merge(flags[name], result)
+		summary.Variables[name].Tests["densityInversion"] = map[string]int64{"suspect": counts[qc.Suspect], "fail": counts[qc.Fail]}
+	}
+}
+
+// seriesAxis 确定序列检验的维度下标及该维度的坐标值
+func seriesAxis(source *dataio.Source, v *dataio.Variable, axis string) (int, []float64) {
+	idx := -1
+	switch strings.ToLower(axis) {
+	case "", "auto":
+		if z := source.AxisDim(v, dataio.AxisZ); z >= 0 && v.Shape[z] > 1 {
+			idx = z
+		} else {
+			idx = source.AxisDim(v, dataio.AxisTime)
+		}
+	case "time":
+		idx = source.AxisDim(v, dataio.AxisTime)
+	case "depth", "pressure":
+		idx = source.AxisDim(v, dataio.AxisZ)
+	default:
+		for i, d := range v.Dims {
+			if d == axis {
+				idx = i
+			}
+		}
+	}
+	if idx < 0 && len(v.Shape) == 1 {
+		idx = 0
+	}
+	if idx < 0 {
+		return -1, nil
+	}
+
+	// 一维坐标变量作为梯度检验的间隔
+	if c := source.Var(v.Dims[idx]); c != nil && len(c.Dims) == 1 && c.Dims[0] == v.Dims[idx] {
+		if values, err := c.ReadAll(); err == nil {
+			return idx, values
+		}
+	}
+	return idx, nil
+}
+
+// elementMonths 每个元素对应的月份
+func elementMonths(source *dataio.Source, v *dataio.Variable) ([]int, error) {
+	c := source.Coordinate(v, dataio.AxisTime)
+	if c == nil {
+		return nil, errors.New("time coordinate not found")
+	}
+	times, err := dataio.Times(c)
+	if err != nil {
+		return nil, err
+	}
+
+	monthValues := make([]float64, len(times))
+	for i, t := range times {
+		monthValues[i] = float64(t.Month())
+	}
+	expanded := dataio.Broadcast(monthValues, c.Dims, v.Dims, v.Shape)
+
+	months := make([]int, len(expanded))
+	for i, m := range expanded {
+		months[i] = int(m)
+	}
+	return months, nil
+}
+
+// defaultConfig 默认配置: 优先使用系统设置，否则按变量识别温度、盐度、压力并使用内置阈值
+func (s *qcService) defaultConfig(source *dataio.Source) *QCConfig {
+	if raw, err := s.systemService.GetSetting(qcConfigSetting); err == nil && raw != "" {
+		var config QCConfig
+		if err := json.Unmarshal([]byte(raw), &config); err == nil {
+			return &config
+		}
+		logger.Error("Invalid QC default config setting", "key", qcConfigSetting)
+	}
+
+	config := &QCConfig{Variables: map[string]*QCVariableConfig{}}
+	var temperature, salinity string
+	for _, v := range source.DataVariables() {
+		switch guessQCQuantity(v) {
+		case "temperature":
+			offset := 0.0
+			if u := strings.ToLower(v.Units()); u == "k" || u == "kelvin" {
+				offset = 273.15
+			}
+			config.Variables[v.Name] = &QCVariableConfig{
+				GrossRange: &qc.Range{FailMin: -2.5 + offset, FailMax: 40 + offset, SuspectMin: -2 + offset, SuspectMax: 35 + offset},
+				Spike:      &qc.Threshold{Suspect: 2, Fail: 6},
+				FlatLine:   &qc.FlatLine{Tolerance: 0.0001, SuspectCount: 5, FailCount: 10},
+			}
+			temperature = v.Name
+		case "salinity":
+			config.Variables[v.Name] = &QCVariableConfig{
+				GrossRange: &qc.Range{FailMin: 0, FailMax: 42, SuspectMin: 2, SuspectMax: 41},
+				Spike:      &qc.Threshold{Suspect: 0.3, Fail: 0.9},
+				FlatLine:   &qc.FlatLine{Tolerance: 0.0001, SuspectCount: 5, FailCount: 10},
+			}
+			salinity = v.Name
+		case "pressure":
+			config.Variables[v.Name] = &QCVariableConfig{
+				GrossRange: &qc.Range{FailMin: -5, FailMax: 12000},
+			}
+		}
+	}
+
+	if temperature != "" && salinity != "" {
+		config.DensityInversion = &QCDensityConfig{Temperature: temperature, Salinity: salinity, Threshold: defaultDensityThreshold}
+	}
+	return config
+}
+
+// guessQCQuantity 按变量名和标准名识别温度、盐度、压力
+func guessQCQuantity(v *dataio.Variable) string {
+	std := strings.ToLower(v.Attrs.String("standard_name"))
+	name := strings.ToLower(v.Name)
+	switch {
+	case strings.Contains(std, "sea_water_temperature") || strings.Contains(std, "sea_surface_temperature") ||
+		strings.Contains(std, "sea_water_potential_temperature") ||
+		containsString([]string{"temp", "temperature", "sst", "thetao", "to", "t", "temp_adjusted", "water_temp"}, name):
+		return "temperature"
+	case strings.Contains(std, "sea_water_salinity") || strings.Contains(std, "sea_water_practical_salinity") ||
+		containsString([]string{"psal", "salt", "salinity", "so", "sss", "psal_adjusted"}, name):
+		return "salinity"
+	case std == "sea_water_pressure" || containsString([]string{"pres", "pressure", "pres_adjusted"}, name):
+		return "pressure"
+	}
+	return ""
+}
+
+// qcFlagsPath 数据集的标志文件路径
+func qcFlagsPath(dataset *models.Dataset) string {
+	if dataset.FilePath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(dataset.FilePath), qcFlagsFile)
+}
+
+// writeQCFlags 将标志写入NetCDF文件，每个变量对应一个<name>_qc字节变量
+func writeQCFlags(path string, dataset *models.Dataset, source *dataio.Source, flags map[string][]byte) error {
+	w := netcdf.NewWriter()
+	w.AddAttr("title", netcdf.Char, "Quality control flags for "+dataset.Name)
+	w.AddAttr("source_dataset", netcdf.Char, dataset.ID)
+	w.AddAttr("Conventions", netcdf.Char, "CF-1.8")
+	w.AddAttr("date_created", netcdf.Char, time.Now().UTC().Format(time.RFC3339))
+	w.AddAttr("qc_convention", netcdf.Char, "IOOS QARTOD")
+
+	flagValues := make([]float64, len(qc.FlagValues))
+	for i, f := range qc.FlagValues {
+		flagValues[i] = float64(f)
+	}
+
+	added := map[string]bool{}
+	for name := range flags {
+		v := source.Var(name)
+		for i, d := range v.Dims {
+			if !added[d] {
+				if err := w.AddDim(d, v.Shape[i]); err != nil {
+					return err
+				}
+				added[d] = true
+			}
+		}
+
+		flagName := name + qcFlagSuffix
+		if err := w.AddVar(flagName, netcdf.Byte, v.Dims); err != nil {
+			return err
+		}
+		w.AddVarAttr(flagName, "long_name", netcdf.Char, "Quality flag for "+name)
+		if std := v.Attrs.String("standard_name"); std != "" {
+			w.AddVarAttr(flagName, "standard_name", netcdf.Char, std+" status_flag")
+		}
+		w.AddVarAttr(flagName, "flag_values", netcdf.Byte, flagValues)
+		w.AddVarAttr(flagName, "flag_meanings", netcdf.Char, qc.FlagMeanings)
+		if err := w.SetData(flagName, flags[name]); err != nil {
+			return err
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := w.WriteFile(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// applyQCFilter 按可接受的标志过滤数据源中的变量，不可接受的值置为NaN；返回需在使用后关闭的标志文件
+func applyQCFilter(source *dataio.Source, dataset *models.Dataset, accepted []byte) (*netcdf.File, error) {
+	path := qcFlagsPath(dataset)
+	if path == "" || len(accepted) == 0 {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+
+	flagsFile, err := netcdf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range source.Vars {
+		fv := flagsFile.Var(v.Name + qcFlagSuffix)
+		if fv == nil {
+			continue
+		}
+		source.Replace(v.WithFilter(func(start, count []int, values []float64) error {
+			flags, err := fv.ReadFloat64(start, count)
+			if err != nil {
+				return err
+			}
+			for i, f := range flags {
+				if i < len(values) && !containsByte(accepted, byte(f)) {
+					values[i] = math.NaN()
+				}
+			}
+			return nil
+		}))
+	}
+	return flagsFile, nil
+}
+
+// parseQCFlags 解析分析参数中的qcFlags，如 [1, 2] 或 "1,2"，未指定时返回nil表示不过滤
+func parseQCFlags(params map[string]interface{}) []byte {
+	var flags []byte
+	switch v := params["qcFlags"].(type) {
+	case []interface{}:
+		for _, x := range v {
+			if f, ok := x.(float64); ok {
+				flags = append(flags, byte(f))
+			}
+		}
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			var f int
+			if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d", &f); err == nil {
+				flags = append(flags, byte(f))
+			}
+		}
+	}
+	return flags
+}
+
+// containsInt 判断切片中是否包含指定整数
+func containsInt(list []int, x int) bool {
+	for _, v := range list {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+// containsByte 判断切片中是否包含指定字节
+func containsByte(list []byte, x byte) bool {
+	for _, v := range list {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded 超出存储配额
+var ErrQuotaExceeded = errors.New("存储配额不足")
+
+const megabyte = int64(1024 * 1024)
+
+// roleQuota 角色默认配额
+type roleQuota struct {
+	maxMB       int // 最大存储(MB)，0表示不限
+	maxDatasets int // 最大数据集数量，0表示不限
+}
+
+// defaultRoleQuotas 各角色的默认配额，可通过系统设置 quota.{role}.max_mb 和 quota.{role}.max_datasets 覆盖
+var defaultRoleQuotas = map[string]roleQuota{
+	"admin":      {maxMB: 0, maxDatasets: 0},
+	"researcher": {maxMB: 100 * 1024, maxDatasets: 1000},
+	"student":    {maxMB: 10 * 1024, maxDatasets: 100},
+	"guest":      {maxMB: 100, maxDatasets: 5},
+}
+
+// QuotaLimit 生效的配额限制
+type QuotaLimit struct {
+	MaxBytes    int64  `json:"maxBytes"`    // 0表示不限
+	MaxDatasets int    `json:"maxDatasets"` // 0表示不限
+	Source      string `json:"source"`      // role, user, organization
+}
+
+// UsageReport 存储用量报告
+type UsageReport struct {
+	Subject    string                   `json:"subject"`
+	Usage      *repository.StorageUsage `json:"usage"`
+	TotalBytes int64                    `json:"totalBytes"`
+	Limit      *QuotaLimit              `json:"limit"`
+}
+
+// UserUsageReport 用户存储用量报告
+type UserUsageReport struct {
+	UserUsage         *UsageReport `json:"user"`
+	OrganizationUsage *UsageReport `json:"organization,omitempty"`
+}
+
+// QuotaService 存储配额服务接口
+type QuotaService interface {
+	// GetUserUsage 获取用户及其所属组织的用量与配额
+	GetUserUsage(userID string) (*UserUsageReport, error)
+	// CheckUpload 检查上传指定大小的数据集是否超出配额
+	CheckUpload(userID string, size int64) error
+	// RemainingUpload 检查数据集数量配额，返回还可上传的字节数，-1表示不限
+	RemainingUpload(userID string) (int64, error)
+	// CheckTaskCreation 检查是否允许创建分析任务(已超出存储配额时拒绝)
+	CheckTaskCreation(userID string) error
+
+	// 管理员配额管理
+	ListQuotas(scope string) ([]*models.StorageQuota, error)
+	SetQuota(scope, subject string, maxBytes int64, maxDatasets int, operatorID string) (*models.StorageQuota, error)
+	DeleteQuota(scope, subject, operatorID string) error
+}
+
+// quotaService 存储配额服务实现
+type quotaService struct {
+	quotaRepo     repository.QuotaRepository
+	userRepo      repository.UserRepository
+	systemService SystemService
+}
+
+// NewQuotaService 创建存储配额服务
+func NewQuotaService(quotaRepo repository.QuotaRepository, userRepo repository.UserRepository, systemService SystemService) QuotaService {
+	return &quotaService{
+		quotaRepo:     quotaRepo,
+		userRepo:      userRepo,
+		systemService: systemService,
+	}
+}
+
+// GetUserUsage 获取用户及其所属组织的用量与配额
+func (s *quotaService) GetUserUsage(userID string) (*UserUsageReport, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	report := &UserUsageReport{}
+
+	usage, err := s.quotaRepo.UserUsage(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute user usage: %w", err)
+	}
+	limit, err := s.userLimit(user)
+	if err != nil {
+		return nil, err
+	}
+	report.UserUsage = &UsageReport{Subject: user.ID, Usage: usage, TotalBytes: usage.TotalBytes(), Limit: limit}
+
+	if user.Organization != "" {
+		usage, err := s.quotaRepo.OrganizationUsage(user.Organization)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute organization usage: %w", err)
+		}
+		limit, err := s.organizationLimit(user.Organization)
+		if err != nil {
+			return nil, err
+		}
+		report.OrganizationUsage = &UsageReport{Subject: user.Organization, Usage: usage, TotalBytes: usage.TotalBytes(), Limit: limit}
+	}
+
+	return report, nil
+}
+
+// CheckUpload 检查上传指定大小的数据集是否超出配额
+func (s *quotaService) CheckUpload(userID string, size int64) error {
+	report, err := s.GetUserUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range []*UsageReport{report.UserUsage, report.OrganizationUsage} {
+		if r == nil {
+			continue
+		}
+		if r.Limit.MaxBytes > 0 && r.TotalBytes+size > r.Limit.MaxBytes {
+			return fmt.Errorf("%w: %s 已使用 %d 字节，上限 %d 字节", ErrQuotaExceeded, r.Subject, r.TotalBytes, r.Limit.MaxBytes)
+		}
+		if r.Limit.MaxDatasets > 0 && r.Usage.DatasetCount+1 > int64(r.Limit.MaxDatasets) {
+			return fmt.Errorf("%w: %s 已有 %d 个数据集，上限 %d 个", ErrQuotaExceeded, r.Subject, r.Usage.DatasetCount, r.Limit.MaxDatasets)
+		}
+	}
+	return nil
+}
+
+// RemainingUpload 检查数据集数量配额，返回用户及其组织还可上传的字节数，-1表示不限
+func (s *quotaService) RemainingUpload(userID string) (int64, error) {
+	report, err := s.GetUserUsage(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := int64(-1)
+	for _, r := range []*UsageReport{report.UserUsage, report.OrganizationUsage} {
+		if r == nil {
+			continue
+		}
+		if r.Limit.MaxDatasets > 0 && r.Usage.DatasetCount+1 > int64(r.Limit.MaxDatasets) {
+			return 0, fmt.Errorf("%w: %s 已有 %d 个数据集，上限 %d 个", ErrQuotaExceeded, r.Subject, r.Usage.DatasetCount, r.Limit.MaxDatasets)
+		}
+		if r.Limit.MaxBytes <= 0 {
+			continue
+		}
+		if left := r.Limit.MaxBytes - r.TotalBytes; remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining, nil
+}
+
+// CheckTaskCreation 检查是否允许创建分析任务
+func (s *quotaService) CheckTaskCreation(userID string) error {
+	report, err := s.GetUserUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range []*UsageReport{report.UserUsage, report.OrganizationUsage} {
+		if r == nil {
+			continue
+		}
+		if r.Limit.MaxBytes > 0 && r.TotalBytes >= r.Limit.MaxBytes {
+			return fmt.Errorf("%w: %s 已使用 %d 字节，上限 %d 字节", ErrQuotaExceeded, r.Subject, r.TotalBytes, r.Limit.MaxBytes)
+		}
+	}
+	return nil
+}
+
+// ListQuotas 获取配额列表
+func (s *quotaService) ListQuotas(scope string) ([]*models.StorageQuota, error) {
+	return s.quotaRepo.List(scope)
+}
+
+// SetQuota 设置配额
+func (s *quotaService) SetQuota(scope, subject string, maxBytes int64, maxDatasets int, operatorID string) (*models.StorageQuota, error) {
+	if scope != models.QuotaScopeUser && scope != models.QuotaScopeOrganization {
+		return nil, fmt.Errorf("invalid quota scope: %s", scope)
+	}
+	if maxBytes < 0 || maxDatasets < 0 {
+		return nil, errors.New("quota limits must not be negative")
+	}
+	if scope == models.QuotaScopeUser {
+		if _, err := s.userRepo.FindByID(subject); err != nil {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	quota, err := s.quotaRepo.Get(scope, subject)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		quota = &models.StorageQuota{
+			ID:      utils.GenerateID("quota"),
+			Scope:   scope,
+			Subject: subject,
+		}
+	}
+
+	quota.MaxBytes = maxBytes
+	quota.MaxDatasets = maxDatasets
+	quota.UpdatedBy = operatorID
+
+	if err := s.quotaRepo.Save(quota); err != nil {
+		return nil, fmt.Errorf("failed to save quota: %w", err)
+	}
+
+	if err := s.audit(operatorID, "update", quota.ID, fmt.Sprintf("设置%s配额 %s: %d 字节, %d 个数据集", scope, subject, maxBytes, maxDatasets)); err != nil {
+		return nil, err
+	}
+
+	return quota, nil
+}
+
+// DeleteQuota 删除配额，恢复为默认值；未单独设置配额时不做任何操作
+func (s *quotaService) DeleteQuota(scope, subject, operatorID string) error {
+	quota, err := s.quotaRepo.Get(scope, subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.quotaRepo.Delete(scope, subject); err != nil {
+		return err
+	}
+	return s.audit(operatorID, "delete", quota.ID, fmt.Sprintf("删除%s配额 %s，恢复默认值", scope, subject))
+}
+
+// audit 记录配额变更的审计日志
+func (s *quotaService) audit(operatorID, action, quotaID, description string) error {
+	err := s.systemService.CreateAuditLog(&models.AuditLog{
+		UserID:      operatorID,
+		Action:      action,
+		Resource:    "storage_quota",
+		ResourceID:  quotaID,
+		Description: description,
+	})
+	if err != nil {
+		logger.Error("Failed to create audit log", "error", err, "action", action, "resourceId", quotaID)
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+	return nil
+}
+
+// userLimit 用户生效的配额，优先使用单独设置的配额，否则使用角色默认配额
+func (s *quotaService) userLimit(user *models.User) (*QuotaLimit, error) {
+	quota, err := s.quotaRepo.Get(models.QuotaScopeUser, user.ID)
+	if err == nil {
+		return &QuotaLimit{MaxBytes: quota.MaxBytes, MaxDatasets: quota.MaxDatasets, Source: models.QuotaScopeUser}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	defaults, ok := defaultRoleQuotas[user.Role]
+	if !ok {
+		defaults = defaultRoleQuotas["guest"]
+	}
+	maxMB := s.systemService.GetSettingInt("quota."+user.Role+".max_mb", defaults.maxMB)
+	maxDatasets := s.systemService.GetSettingInt("quota."+user.Role+".max_datasets", defaults.maxDatasets)
+
+	return &QuotaLimit{MaxBytes: int64(maxMB) * megabyte, MaxDatasets: maxDatasets, Source: "role"}, nil
+}
+
+// organizationLimit 组织生效的配额，未设置时不限
+func (s *quotaService) organizationLimit(organization string) (*QuotaLimit, error) {
+	quota, err := s.quotaRepo.Get(models.QuotaScopeOrganization, organization)
+	if err == nil {
+		return &QuotaLimit{MaxBytes: quota.MaxBytes, MaxDatasets: quota.MaxDatasets, Source: models.QuotaScopeOrganization}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &QuotaLimit{Source: models.QuotaScopeOrganization}, nil
+}
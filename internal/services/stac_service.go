@@ -0,0 +1,732 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+)
+
+// stacVersion 实现的STAC规范版本
+const stacVersion = "1.0.0"
+
+// STAC 分页默认值
+const (
+	stacDefaultLimit = 10
+	stacMaxLimit     = 1000
+)
+
+// STAC 相关错误
+var (
+	ErrSTACNotFound     = errors.New("STAC资源不存在")
+	ErrSTACInvalidQuery = errors.New("无效的STAC查询参数")
+)
+
+// stacConformance 支持的一致性类
+var stacConformance = []string{
+	"https://api.stacspec.org/v1.0.0/core",
+	"https://api.stacspec.org/v1.0.0/collections",
+	"https://api.stacspec.org/v1.0.0/ogcapi-features",
+	"https://api.stacspec.org/v1.0.0/item-search",
+	"https://api.stacspec.org/v1.0.0-rc.1/item-search#query",
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/core",
+	"http://www.opengis.net/spec/ogcapi-features-1/1.0/conf/geojson",
+}
+
+// STACSearch STAC条目检索参数
+type STACSearch struct {
+	Collections []string                          `json:"collections"`
+	IDs         []string                          `json:"ids"`
+	BBox        []float64                         `json:"bbox"`
+	Datetime    string                            `json:"datetime"`
+	Intersects  *GeoJSONGeometry                  `json:"intersects"`
+	Query       map[string]map[string]interface{} `json:"query"`
+	Limit       int                               `json:"limit"`
+	Page        int                               `json:"page"`
+}
+
+// GeoJSONGeometry GeoJSON几何对象
+type GeoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// STACService STAC目录服务接口
+type STACService interface {
+	GetCatalog() map[string]interface{}
+	GetConformance() map[string]interface{}
+	ListCollections() (map[string]interface{}, error)
+	GetCollection(collectionID string) (map[string]interface{}, error)
+	GetItem(collectionID, itemID string) (map[string]interface{}, error)
+	ListItems(collectionID string, search *STACSearch) (map[string]interface{}, error)
+	Search(search *STACSearch, method string) (map[string]interface{}, error)
+}
+
+// stacService STAC目录服务实现
+type stacService struct {
+	datasetRepo repository.DatasetRepository
+	baseURL     string
+}
+
+// NewSTACService 创建STAC目录服务
+func NewSTACService(datasetRepo repository.DatasetRepository, baseURL string) STACService {
+	return &stacService{
+		datasetRepo: datasetRepo,
+		baseURL:     baseURL,
+	}
+}
+
+// GetCatalog 根目录
+func (s *stacService) GetCatalog() map[string]interface{} {
+	links := []map[string]interface{}{
+		s.link("self", "", "application/json"),
+		s.link("root", "", "application/json"),
+		s.link("conformance", "/conformance", "application/json"),
+		s.link("data", "/collections", "application/json"),
+		s.methodLink("search", "/search", "application/geo+json", "GET"),
+		s.methodLink("search", "/search", "application/geo+json", "POST"),
+	}
+
+	// 每个数据类型作为一个子集合
+	if types, err := s.datasetRepo.ListTypes(); err == nil {
+		for _, t := range types {
+			links = append(links, s.link("child", "/collections/"+url.PathEscape(t), "application/json"))
+		}
+	}
+
+	return map[string]interface{}{
+		"type":         "Catalog",
+		"stac_version": stacVersion,
+		"id":           "ssop",
+		"title":        defaultPublisher,
+		"description":  "智慧海洋数据分析平台数据集目录",
+		"conformsTo":   stacConformance,
+		"links":        links,
+	}
+}
+
+// GetConformance 一致性声明
+func (s *stacService) GetConformance() map[string]interface{} {
+	return map[string]interface{}{
+		"conformsTo": stacConformance,
+	}
+}
+
+// ListCollections 集合列表
+func (s *stacService) ListCollections() (map[string]interface{}, error) {
+	types, err := s.datasetRepo.ListTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make([]map[string]interface{}, 0, len(types))
+	for _, t := range types {
+		collection, err := s.GetCollection(t)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	return map[string]interface{}{
+		"collections": collections,
+		"links": []map[string]interface{}{
+			s.link("self", "/collections", "application/json"),
+			s.link("root", "", "application/json"),
+		},
+	}, nil
+}
+
+// GetCollection 集合详情，集合由数据类型派生
+func (s *stacService) GetCollection(collectionID string) (map[string]interface{}, error) {
+	datasets, total, err := s.datasetRepo.List(0, 0, map[string]interface{}{"type": collectionID})
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, ErrSTACNotFound
+	}
+
+	// 合并所有数据集的时空范围
+	bbox := []float64{180, 90, -180, -90}
+	hasBBox := false
+	var start, end *time.Time
+	licenses := make(map[string]bool)
+	keywords := []string{collectionID}
+	for _, dataset := range datasets {
+		if b, ok := dataset.Bounds(); ok {
+			hasBBox = true
+			bbox[0] = math.Min(bbox[0], b[1])
+			bbox[1] = math.Min(bbox[1], b[0])
+			bbox[2] = math.Max(bbox[2], b[3])
+			bbox[3] = math.Max(bbox[3], b[2])
+		}
+		if dataset.StartTime != nil && (start == nil || dataset.StartTime.Before(*start)) {
+			start = dataset.StartTime
+		}
+		if dataset.EndTime != nil && (end == nil || dataset.EndTime.After(*end)) {
+			end = dataset.EndTime
+		}
+		if dataset.License != "" {
+			licenses[dataset.License] = true
+		}
+	}
+	if !hasBBox {
+		bbox = []float64{-180, -90, 180, 90}
+	}
+
+	license := "proprietary"
+	if len(licenses) == 1 {
+		for l := range licenses {
+			license = l
+		}
+	} else if len(licenses) > 1 {
+		license = "various"
+	}
+
+	collectionPath := "/collections/" + url.PathEscape(collectionID)
+	return map[string]interface{}{
+		"type":         "Collection",
+		"stac_version": stacVersion,
+		"id":           collectionID,
+		"title":        collectionID,
+		"description":  fmt.Sprintf("%s 类型的数据集，共 %d 个", collectionID, total),
+		"keywords":     keywords,
+		"license":      license,
+		"extent": map[string]interface{}{
+			"spatial": map[string]interface{}{
+				"bbox": [][]float64{bbox},
+			},
+			"temporal": map[string]interface{}{
+				"interval": [][]interface{}{{stacTime(start), stacTime(end)}},
+			},
+		},
+		"links": []map[string]interface{}{
+			s.link("self", collectionPath, "application/json"),
+			s.link("root", "", "application/json"),
+			s.link("parent", "", "application/json"),
+			s.link("items", collectionPath+"/items", "application/geo+json"),
+		},
+	}, nil
+}
+
+// GetItem 获取单个条目
+func (s *stacService) GetItem(collectionID, itemID string) (map[string]interface{}, error) {
+	dataset, err := s.datasetRepo.GetByID(itemID)
+	if err != nil || dataset.Type != collectionID {
+		return nil, ErrSTACNotFound
+	}
+	return s.item(dataset), nil
+}
+
+// ListItems 集合内条目列表
+func (s *stacService) ListItems(collectionID string, search *STACSearch) (map[string]interface{}, error) {
+	search.Collections = []string{collectionID}
+	search.IDs = nil
+	return s.search(search, "GET", "/collections/"+url.PathEscape(collectionID)+"/items")
+}
+
+// Search 跨集合条目检索
+func (s *stacService) Search(search *STACSearch, method string) (map[string]interface{}, error) {
+	return s.search(search, method, "/search")
+}
+
+// search 条目检索，path为分页链接使用的接口路径
+func (s *stacService) search(search *STACSearch, method, path string) (map[string]interface{}, error) {
+	if search.Limit <= 0 {
+		search.Limit = stacDefaultLimit
+	}
+	if search.Limit > stacMaxLimit {
+		search.Limit = stacMaxLimit
+	}
+	if search.Page <= 0 {
+		search.Page = 1
+	}
+
+	filters, err := s.searchFilters(search)
+	if err != nil {
+		return nil, err
+	}
+
+	datasets, total, err := s.datasetRepo.List(search.Page, search.Limit, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]map[string]interface{}, 0, len(datasets))
+	for _, dataset := range datasets {
+		features = append(features, s.item(dataset))
+	}
+
+	return map[string]interface{}{
+		"type":           "FeatureCollection",
+		"features":       features,
+		"numberMatched":  total,
+		"numberReturned": len(features),
+		"links":          s.searchLinks(search, method, path, total),
+	}, nil
+}
+
+// searchFilters 将检索参数转换为数据集仓库过滤条件
+func (s *stacService) searchFilters(search *STACSearch) (map[string]interface{}, error) {
+	filters := make(map[string]interface{})
+
+	if len(search.IDs) > 0 {
+		filters["ids"] = search.IDs
+	}
+	if len(search.Collections) == 1 {
+		filters["type"] = search.Collections[0]
+	} else if len(search.Collections) > 1 {
+		filters["types"] = search.Collections
+	}
+
+	// bbox: [minLng, minLat, maxLng, maxLat]，或带高程的6元素形式
+	bbox := search.BBox
+	if len(bbox) == 6 {
+		bbox = []float64{bbox[0], bbox[1], bbox[3], bbox[4]}
+	}
+	if len(bbox) != 0 && len(bbox) != 4 {
+		return nil, fmt.Errorf("%w: bbox必须包含4个或6个数值", ErrSTACInvalidQuery)
+	}
+	if search.Intersects != nil && len(bbox) > 0 {
+		return nil, fmt.Errorf("%w: bbox与intersects不能同时使用", ErrSTACInvalidQuery)
+	}
+	if search.Intersects != nil {
+		wkt, err := geometryWKT(search.Intersects)
+		if err != nil {
+			return nil, err
+		}
+		filters["intersects"] = wkt
+	}
+	if len(bbox) == 4 {
+		filters["region"] = fmt.Sprintf("%f,%f,%f,%f", bbox[1], bbox[0], bbox[3], bbox[2])
+	}
+
+	// datetime: 单一时刻或 start/end 区间，".."表示开放端
+	if search.Datetime != "" {
+		parts := strings.Split(search.Datetime, "/")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("%w: datetime格式错误", ErrSTACInvalidQuery)
+		}
+		start, end := parts[0], parts[0]
+		if len(parts) == 2 {
+			start, end = parts[0], parts[1]
+		}
+		if start != "" && start != ".." {
+			t, err := time.Parse(time.RFC3339, start)
+			if err != nil {
+				return nil, fmt.Errorf("%w: datetime格式错误", ErrSTACInvalidQuery)
+			}
+			filters["startDate"] = t
+		}
+		if end != "" && end != ".." {
+			t, err := time.Parse(time.RFC3339, end)
+			if err != nil {
+				return nil, fmt.Errorf("%w: datetime格式错误", ErrSTACInvalidQuery)
+			}
+			filters["endDate"] = t
+		}
+	}
+
+	// query扩展: {"属性": {"运算符": 值}}
+	if len(search.Query) > 0 {
+		var conditions []repository.Condition
+		for property, ops := range search.Query {
+			column, ok := stacQueryColumns[property]
+			if !ok {
+				return nil, fmt.Errorf("%w: 不支持的查询属性 %s", ErrSTACInvalidQuery, property)
+			}
+			for op, value := range ops {
+				condition, err := stacCondition(column, op, value)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, condition)
+			}
+		}
+		filters["conditions"] = conditions
+	}
+
+	return filters, nil
+}
+
+// stacQueryColumns query扩展中可用的属性与数据集列的映射
+var stacQueryColumns = map[string]string{
+	"title":          "name",
+	"license":        "license",
+	"created":        "created_at",
+	"updated":        "updated_at",
+	"start_datetime": "start_time",
+	"end_datetime":   "end_time",
+	"sci:doi":        "doi",
+	"version":        "version",
+	"ssop:type":      "type",
+	"ssop:format":    "format",
+	"ssop:source":    "source",
+	"ssop:region":    "region_name",
+	"ssop:publisher": "publisher",
+	"ssop:size":      "size",
+}
+
+// stacCondition 将query扩展运算符转换为仓库条件
+func stacCondition(column, op string, value interface{}) (repository.Condition, error) {
+	switch op {
+	case "eq":
+		return repository.Condition{Column: column, Op: "=", Value: value}, nil
+	case "neq":
+		return repository.Condition{Column: column, Op: "<>", Value: value}, nil
+	case "lt":
+		return repository.Condition{Column: column, Op: "<", Value: value}, nil
+	case "lte":
+		return repository.Condition{Column: column, Op: "<=", Value: value}, nil
+	case "gt":
+		return repository.Condition{Column: column, Op: ">", Value: value}, nil
+	case "gte":
+		return repository.Condition{Column: column, Op: ">=", Value: value}, nil
+	case "in":
+		values, ok := value.([]interface{})
+		if !ok {
+			return repository.Condition{}, fmt.Errorf("%w: in运算符需要数组", ErrSTACInvalidQuery)
+		}
+		return repository.Condition{Column: column, Op: "IN", Value: values}, nil
+	case "startsWith", "endsWith", "contains":
+		str, ok := value.(string)
+		if !ok {
+			return repository.Condition{}, fmt.Errorf("%w: %s运算符需要字符串", ErrSTACInvalidQuery, op)
+		}
+		likeOp := map[string]string{"startsWith": "STARTS_WITH", "endsWith": "ENDS_WITH", "contains": "CONTAINS"}[op]
+		return repository.Condition{Column: column, Op: likeOp, Value: str}, nil
+	default:
+		return repository.Condition{}, fmt.Errorf("%w: 不支持的运算符 %s", ErrSTACInvalidQuery, op)
+	}
+}
+
+// item 将数据集转换为STAC条目
+func (s *stacService) item(dataset *models.Dataset) map[string]interface{} {
+	var geometry interface{}
+	var bbox []float64
+	if b, ok := dataset.Bounds(); ok {
+		minLat, minLng, maxLat, maxLng := b[0], b[1], b[2], b[3]
+		bbox = []float64{minLng, minLat, maxLng, maxLat}
+		geometry = map[string]interface{}{
+			"type": "Polygon",
+			"coordinates": [][][]float64{{
+				{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+			}},
+		}
+	}
+
+	properties := map[string]interface{}{
+		"title":       dataset.Name,
+		"description": dataset.Description,
+		"datetime":    nil,
+		"ssop:type":   dataset.Type,
+		"ssop:format": dataset.Format,
+		"ssop:size":   dataset.Size,
+	}
+	if dataset.StartTime != nil && dataset.EndTime != nil {
+		properties["start_datetime"] = stacTime(dataset.StartTime)
+		properties["end_datetime"] = stacTime(dataset.EndTime)
+	} else {
+		// STAC要求datetime和区间至少有其一
+		properties["datetime"] = stacTime(firstTime(dataset.StartTime, dataset.CreatedAt))
+	}
+	if dataset.CreatedAt != nil {
+		properties["created"] = stacTime(dataset.CreatedAt)
+	}
+	if dataset.UpdatedAt != nil {
+		properties["updated"] = stacTime(dataset.UpdatedAt)
+	}
+	if dataset.License != "" {
+		properties["license"] = dataset.License
+	}
+	if dataset.Version != "" {
+		properties["version"] = dataset.Version
+	}
+	if dataset.DOI != "" {
+		properties["sci:doi"] = dataset.DOI
+	}
+	if dataset.Source != "" {
+		properties["ssop:source"] = dataset.Source
+	}
+	if dataset.RegionName != "" {
+		properties["ssop:region"] = dataset.RegionName
+	}
+	if dataset.SpatialResolution != "" {
+		properties["ssop:spatial_resolution"] = dataset.SpatialResolution
+	}
+	if dataset.TemporalResolution != "" {
+		properties["ssop:temporal_resolution"] = dataset.TemporalResolution
+	}
+	if tags := dataset.TagList(); len(tags) > 0 {
+		properties["keywords"] = tags
+	}
+	if variables := dataset.VariableList(); len(variables) > 0 {
+		properties["ssop:variables"] = variables
+	}
+
+	datasetURL := s.baseURL + "/api/v1/datasets/" + url.PathEscape(dataset.ID)
+	assets := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"href":  datasetURL + "/metadata?format=" + MetadataFormatISO19115,
+			"type":  "application/xml",
+			"title": "ISO 19115-2 元数据",
+			"roles": []string{"metadata"},
+		},
+	}
+	if dataset.FilePath != "" {
+		assets["data"] = map[string]interface{}{
+			"href":  datasetURL + "/download",
+			"type":  stacMediaType(dataset.Format),
+			"title": dataset.Name,
+			"roles": []string{"data"},
+		}
+	}
+
+	collectionPath := "/collections/" + url.PathEscape(dataset.Type)
+	item := map[string]interface{}{
+		"type":         "Feature",
+		"stac_version": stacVersion,
+		"stac_extensions": []string{
+			"https://stac-extensions.github.io/scientific/v1.0.0/schema.json",
+		},
+		"id":         dataset.ID,
+		"geometry":   geometry,
+		"properties": properties,
+		"assets":     assets,
+		"collection": dataset.Type,
+		"links": []map[string]interface{}{
+			s.link("self", collectionPath+"/items/"+url.PathEscape(dataset.ID), "application/geo+json"),
+			s.link("parent", collectionPath, "application/json"),
+			s.link("collection", collectionPath, "application/json"),
+			s.link("root", "", "application/json"),
+			{"rel": "describedby", "href": datasetURL + "/landing", "type": "text/html"},
+		},
+	}
+	if bbox != nil {
+		item["bbox"] = bbox
+	}
+	return item
+}
+
+// searchLinks 检索结果的分页链接
+func (s *stacService) searchLinks(search *STACSearch, method, path string, total int64) []map[string]interface{} {
+	links := []map[string]interface{}{
+		s.link("root", "", "application/json"),
+	}
+
+	hasNext := int64(search.Page*search.Limit) < total
+	hasPrev := search.Page > 1
+	for _, rel := range []string{"next", "prev"} {
+		page := search.Page + 1
+		if rel == "prev" {
+			if !hasPrev {
+				continue
+			}
+			page = search.Page - 1
+		} else if !hasNext {
+			continue
+		}
+
+		if method == "POST" {
+			// POST检索的分页链接通过body传递页码，merge表示合并原有检索参数
+			link := s.methodLink(rel, path, "application/geo+json", "POST")
+			link["body"] = map[string]interface{}{"page": page}
+			link["merge"] = true
+			links = append(links, link)
+			continue
+		}
+
+		query := url.Values{}
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(search.Limit))
+		if len(search.Collections) > 0 && path == "/search" {
+			query.Set("collections", strings.Join(search.Collections, ","))
+		}
+		if len(search.IDs) > 0 {
+			query.Set("ids", strings.Join(search.IDs, ","))
+		}
+		if len(search.BBox) > 0 {
+			parts := make([]string, len(search.BBox))
+			for i, v := range search.BBox {
+				parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			query.Set("bbox", strings.Join(parts, ","))
+		}
+		if search.Datetime != "" {
+			query.Set("datetime", search.Datetime)
+		}
+		if search.Intersects != nil {
+			if data, err := json.Marshal(search.Intersects); err == nil {
+				query.Set("intersects", string(data))
+			}
+		}
+		if len(search.Query) > 0 {
+			if data, err := json.Marshal(search.Query); err == nil {
+				query.Set("query", string(data))
+			}
+		}
+		links = append(links, s.link(rel, path+"?"+query.Encode(), "application/geo+json"))
+	}
+
+	return links
+}
+
+// link 生成STAC链接
+func (s *stacService) link(rel, path, mediaType string) map[string]interface{} {
+	return map[string]interface{}{
+		"rel":  rel,
+		"href": s.baseURL + "/api/v1/stac" + path,
+		"type": mediaType,
+	}
+}
+
+// methodLink 生成带HTTP方法的STAC链接
+func (s *stacService) methodLink(rel, path, mediaType, method string) map[string]interface{} {
+	link := s.link(rel, path, mediaType)
+	link["method"] = method
+	return link
+}
+
+// stacTime 格式化STAC时间，空值返回nil
+func stacTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// firstTime 返回第一个非空时间
+func firstTime(times ...*time.Time) *time.Time {
+	for _, t := range times {
+		if t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// stacMediaType 根据数据格式推断资产媒体类型
+func stacMediaType(format string) string {
+	switch strings.ToLower(format) {
+	case "netcdf", "nc":
+		return "application/netcdf"
+	case "geotiff", "tiff", "tif", "cog":
+		return "image/tiff; application=geotiff"
+	case "csv":
+		return "text/csv"
+	case "json":
+		return "application/json"
+	case "zarr":
+		return "application/vnd+zarr"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// geometryRings 解析GeoJSON几何对象为多边形环列表(经度, 纬度)
+// 支持Point、Polygon和MultiPolygon，点视为退化的多边形
+func geometryRings(geometry *GeoJSONGeometry) ([][][2]float64, error) {
+	if geometry == nil {
+		return nil, nil
+	}
+
+	switch geometry.Type {
+	case "Point":
+		var point [2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &point); err != nil {
+			return nil, fmt.Errorf("%w: intersects坐标格式错误", ErrSTACInvalidQuery)
+		}
+		return [][][2]float64{{point}}, nil
+	case "Polygon":
+		var polygon [][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil || len(polygon) == 0 {
+			return nil, fmt.Errorf("%w: intersects坐标格式错误", ErrSTACInvalidQuery)
+		}
+		// 只使用外环，忽略内部空洞
+		return polygon[:1], nil
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("%w: intersects坐标格式错误", ErrSTACInvalidQuery)
+		}
+		var rings [][][2]float64
+		for _, polygon := range polygons {
+			if len(polygon) > 0 {
+				rings = append(rings, polygon[0])
+			}
+		}
+		return rings, nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的几何类型 %s", ErrSTACInvalidQuery, geometry.Type)
+	}
+}
+
+// ringsBBox 计算多边形环的外包矩形 [minLng, minLat, maxLng, maxLat]
+func ringsBBox(rings [][][2]float64) []float64 {
+	bbox := []float64{180, 90, -180, -90}
+	for _, ring := range rings {
+		for _, p := range ring {
+			bbox[0] = math.Min(bbox[0], p[0])
+			bbox[1] = math.Min(bbox[1], p[1])
+			bbox[2] = math.Max(bbox[2], p[0])
+			bbox[3] = math.Max(bbox[3], p[1])
+		}
+	}
+	return bbox
+}
+
+// geometryWKT 将GeoJSON几何对象转换为WKT，多边形只使用外环并自动闭合
+func geometryWKT(geometry *GeoJSONGeometry) (string, error) {
+	rings, err := geometryRings(geometry)
+	if err != nil {
+		return "", err
+	}
+
+	coords := func(ring [][2]float64) string {
+		points := make([]string, len(ring))
+		for i, p := range ring {
+			points[i] = strconv.FormatFloat(p[0], 'f', -1, 64) + " " + strconv.FormatFloat(p[1], 'f', -1, 64)
+		}
+		return strings.Join(points, ", ")
+	}
+	var polygons []string
+	for _, ring := range rings {
+		if geometry.Type == "Point" {
+			return "POINT(" + coords(ring) + ")", nil
+		}
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			ring = append(ring, ring[0])
+		}
+		if len(ring) < 4 {
+			return "", fmt.Errorf("%w: 多边形至少需要3个顶点", ErrSTACInvalidQuery)
+		}
+		polygons = append(polygons, "(("+coords(ring)+"))")
+	}
+	if len(polygons) == 0 {
+		return "", fmt.Errorf("%w: intersects坐标格式错误", ErrSTACInvalidQuery)
+	}
+	if geometry.Type == "MultiPolygon" {
+		return "MULTIPOLYGON(" + strings.Join(polygons, ", ") + ")", nil
+	}
+	return "POLYGON" + polygons[0], nil
+}
+
+// pointInRing 射线法判断点是否在多边形环内
+func pointInRing(p [2]float64, ring [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/logger"
+	"github.com/sinker/ssop/pkg/units"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAliasNotFound 别名不存在
+	ErrAliasNotFound = errors.New("variable alias not found")
+	// ErrInvalidUnits 无法识别的单位
+	ErrInvalidUnits = errors.New("invalid units")
+)
+
+// AliasEntry 别名表条目
+type AliasEntry struct {
+	dataio.Alias
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"` // builtin, custom
+}
+
+// ResolvedVariable 数据集变量的标准名解析结果
+type ResolvedVariable struct {
+	Name         string `json:"name"`
+	StandardName string `json:"standardName"`
+	Units        string `json:"units"`
+	Source       string `json:"source"` // attribute(standard_name属性)、alias(别名表)、空(无法识别)
+}
+
+// StandardNameService CF标准名与单位换算服务接口
+type StandardNameService interface {
+	// Resolver 获取当前别名表对应的解析器
+	Resolver() *dataio.Resolver
+	// ResolveDataset 解析数据集各变量的标准名和单位
+	ResolveDataset(datasetID string) ([]*ResolvedVariable, error)
+	// ConvertUnits 单位换算
+	ConvertUnits(value float64, from, to string) (float64, error)
+
+	// 别名管理
+	ListAliases() ([]*AliasEntry, error)
+	SaveAlias(name, standardName, unit, description, operatorID string) (*models.VariableAlias, error)
+	DeleteAlias(name, operatorID string) error
+}
+
+// standardNameService CF标准名与单位换算服务实现
+type standardNameService struct {
+	aliasRepo     repository.AliasRepository
+	datasetRepo   repository.DatasetRepository
+	systemService SystemService
+
+	mu       sync.RWMutex
+	resolver *dataio.Resolver // 缓存，别名变更时重建
+}
+
+// NewStandardNameService 创建CF标准名与单位换算服务
+func NewStandardNameService(aliasRepo repository.AliasRepository, datasetRepo repository.DatasetRepository, systemService SystemService) StandardNameService {
+	return &standardNameService{
+		aliasRepo:     aliasRepo,
+		datasetRepo:   datasetRepo,
+		systemService: systemService,
+	}
+}
+
+// Resolver 获取解析器，自定义别名加载失败时仅使用内置别名
+func (s *standardNameService) Resolver() *dataio.Resolver {
+	s.mu.RLock()
+	r := s.resolver
+	s.mu.RUnlock()
+	if r != nil {
+		return r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resolver != nil {
+		return s.resolver
+	}
+
+	custom, err := s.aliasRepo.List()
+	if err != nil {
+		// 不缓存，下次重试
+		logger.Error("Failed to load variable aliases", "error", err)
+		return dataio.NewResolver(nil)
+	}
+	aliases := make([]dataio.Alias, 0, len(custom))
+	for _, a := range custom {
+		aliases = append(aliases, dataio.Alias{Name: a.Name, StandardName: a.StandardName, Units: a.Units})
+	}
+	s.resolver = dataio.NewResolver(aliases)
+	return s.resolver
+}
+
+// invalidate 清除解析器缓存
+func (s *standardNameService) invalidate() {
+	s.mu.Lock()
+	s.resolver = nil
+	s.mu.Unlock()
+}
+
+// ResolveDataset 解析数据集各变量的标准名和单位
+func (s *standardNameService) ResolveDataset(datasetID string) ([]*ResolvedVariable, error) {
+	dataset, err := s.datasetRepo.GetByID(datasetID)
+	if err != nil {
+		return nil, fmt.Errorf("dataset not found: %w", err)
+	}
+	if dataset.FilePath == "" {
+		return []*ResolvedVariable{}, nil
+	}
+
+	source, err := dataio.Open(dataset.FilePath, dataset.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	resolver := s.Resolver()
+	result := make([]*ResolvedVariable, 0, len(source.Vars))
+	for _, v := range source.Vars {
+		if v.IsText() {
+			continue
+		}
+		rv := &ResolvedVariable{Name: v.Name, StandardName: resolver.StandardName(v), Units: resolver.Units(v)}
+		switch {
+		case v.Attrs.String("standard_name") != "":
+			rv.Source = "attribute"
+		case rv.StandardName != "":
+			rv.Source = "alias"
+		}
+		result = append(result, rv)
+	}
+	return result, nil
+}
+
+// ConvertUnits 单位换算
+func (s *standardNameService) ConvertUnits(value float64, from, to string) (float64, error) {
+	result, err := units.Convert(value, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidUnits, err)
+	}
+	return result, nil
+}
+
+// ListAliases 获取别名表，自定义别名覆盖同名的内置别名
+func (s *standardNameService) ListAliases() ([]*AliasEntry, error) {
+	custom, err := s.aliasRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*AliasEntry{}
+	for _, a := range dataio.DefaultAliases {
+		entries[strings.ToLower(a.Name)] = &AliasEntry{Alias: a, Source: "builtin"}
+	}
+	for _, a := range custom {
+		entries[strings.ToLower(a.Name)] = &AliasEntry{
+			Alias:       dataio.Alias{Name: a.Name, StandardName: a.StandardName, Units: a.Units},
+			Description: a.Description,
+			Source:      "custom",
+		}
+	}
+
+	result := make([]*AliasEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].StandardName != result[j].StandardName {
+			return result[i].StandardName < result[j].StandardName
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// SaveAlias 新增或修改自定义别名
+func (s *standardNameService) SaveAlias(name, standardName, unit, description, operatorID string) (*models.VariableAlias, error) {
+	name = strings.TrimSpace(name)
+	standardName = strings.TrimSpace(standardName)
+	if name == "" || standardName == "" {
+		return nil, errors.New("name and standard name are required")
+	}
+	if unit != "" {
+		if _, err := units.Parse(unit); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUnits, err)
+		}
+	}
+
+	alias, err := s.aliasRepo.GetByName(name)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		alias = &models.VariableAlias{Name: name}
+	}
+	alias.StandardName = standardName
+	alias.Units = unit
+	alias.Description = description
+	alias.UpdatedBy = operatorID
+
+	if err := s.aliasRepo.Save(alias); err != nil {
+		return nil, fmt.Errorf("failed to save alias: %w", err)
+	}
+	s.invalidate()
+
+	s.systemService.CreateAuditLog(&models.AuditLog{
+		UserID:      operatorID,
+		Action:      "update",
+		Resource:    "variable_alias",
+		ResourceID:  name,
+		Description: fmt.Sprintf("设置变量别名 %s -> %s (%s)", name, standardName, unit),
+	})
+	return alias, nil
+}
+
+// DeleteAlias 删除自定义别名，同名的内置别名重新生效
+func (s *standardNameService) DeleteAlias(name, operatorID string) error {
+	if _, err := s.aliasRepo.GetByName(name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAliasNotFound
+		}
+		return err
+	}
+	if err := s.aliasRepo.Delete(name); err != nil {
+		return err
+	}
+	s.invalidate()
+
+	s.systemService.CreateAuditLog(&models.AuditLog{
+		UserID:      operatorID,
+		Action:      "delete",
+		Resource:    "variable_alias",
+		ResourceID:  name,
+		Description: "删除变量别名 " + name,
+	})
+	return nil
+}
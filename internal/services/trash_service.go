@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sinker/ssop/internal/models"
+	"github.com/sinker/ssop/internal/repository"
+	"github.com/sinker/ssop/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// trashRetentionSetting 回收站保留天数的系统设置键
+	trashRetentionSetting = "storage.trash_retention_days"
+	// defaultTrashRetentionDays 默认保留天数
+	defaultTrashRetentionDays = 30
+	// trashPurgeInterval 后台清理任务的执行间隔
+	trashPurgeInterval = time.Hour
+)
+
+var (
+	// ErrTrashItemNotFound 回收站中不存在该项目
+	ErrTrashItemNotFound = errors.New("trash item not found")
+	// ErrTrashForbidden 无权操作该回收站项目
+	ErrTrashForbidden = errors.New("trash item belongs to another user")
+)
+
+// TrashListing 回收站内容
+type TrashListing struct {
+	Datasets      []*models.Dataset        `json:"datasets"`
+	Tasks         []*models.AnalysisTask   `json:"tasks"`
+	Results       []*models.AnalysisResult `json:"results"`
+	RetentionDays int                      `json:"retentionDays"`
+}
+
+// TrashService 回收站服务接口
+type TrashService interface {
+	// ListTrash 获取回收站内容，userID为空时返回所有用户的内容
+	ListTrash(userID string) (*TrashListing, error)
+
+	// 恢复，管理员可恢复任意用户的项目
+	RestoreDataset(id, userID string, isAdmin bool) error
+	RestoreTask(id, userID string, isAdmin bool) error
+	RestoreResult(id, userID string, isAdmin bool) error
+
+	// 彻底删除(管理员)
+	PurgeDataset(id, operatorID string) error
+	PurgeTask(id, operatorID string) error
+	PurgeResult(id, operatorID string) error
+
+	// PurgeExpired 清理超过保留期限的项目，返回清理数量
+	PurgeExpired() (int, error)
+	// StartPurgeJob 启动后台定时清理，ctx取消时退出
+	StartPurgeJob(ctx context.Context)
+}
+
+// trashBin 回收站文件目录布局
+//
+//	{dir}/datasets/{datasetId}/  数据集目录
+//	{dir}/tasks/{taskId}/        分析任务结果目录
+//	{dir}/results/{resultId}/    单独删除的分析结果文件
+type trashBin struct {
+	dir string
+}
+
+// datasetDir 数据集在回收站中的目录
+func (b trashBin) datasetDir(id string) string {
+	return filepath.Join(b.dir, "datasets", id)
+}
+
+// taskDir 分析任务在回收站中的目录
+func (b trashBin) taskDir(id string) string {
+	return filepath.Join(b.dir, "tasks", id)
+}
+
+// resultDir 分析结果在回收站中的目录
+func (b trashBin) resultDir(id string) string {
+	return filepath.Join(b.dir, "results", id)
+}
+
+// move 移动文件或目录，源不存在时忽略
+func (b trashBin) move(src, dst string) error {
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("destination already exists: %s", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// trashService 回收站服务实现
+type trashService struct {
+	datasetRepo   repository.DatasetRepository
+	analysisRepo  repository.AnalysisRepository
+	systemService SystemService
+	resultsDir    string // 分析结果存储目录
+	bin           trashBin
+}
+
+// NewTrashService 创建回收站服务
+func NewTrashService(
+	datasetRepo repository.DatasetRepository,
+	analysisRepo repository.AnalysisRepository,
+	systemService SystemService,
+	resultsDir, trashDir string,
+) TrashService {
+	return &trashService{
+		datasetRepo:   datasetRepo,
+		analysisRepo:  analysisRepo,
+		systemService: systemService,
+		resultsDir:    resultsDir,
+		bin:           trashBin{dir: trashDir},
+	}
+}
+
+// ListTrash 获取回收站内容
+func (s *trashService) ListTrash(userID string) (*TrashListing, error) {
+	datasets, err := s.datasetRepo.ListDeleted(userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted datasets: %w", err)
+	}
+	tasks, err := s.analysisRepo.ListDeletedTasks(userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted tasks: %w", err)
+	}
+	results, err := s.analysisRepo.ListDeletedResults(userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted results: %w", err)
+	}
+
+	return &TrashListing{
+		Datasets:      datasets,
+		Tasks:         tasks,
+		Results:       results,
+		RetentionDays: s.retentionDays(),
+	}, nil
+}
+
+// RestoreDataset 恢复数据集
+func (s *trashService) RestoreDataset(id, userID string, isAdmin bool) error {
+	dataset, err := s.datasetRepo.GetDeletedByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+	if !isAdmin && dataset.CreatedBy != userID {
+		return ErrTrashForbidden
+	}
+
+	// 删除时移走的是数据文件所在的目录，恢复到原位置
+	if dataset.FilePath != "" {
+		if err := s.bin.move(s.bin.datasetDir(id), filepath.Dir(dataset.FilePath)); err != nil {
+			return fmt.Errorf("failed to restore dataset files: %w", err)
+		}
+	}
+	if err := s.datasetRepo.Restore(id); err != nil {
+		return fmt.Errorf("failed to restore dataset: %w", err)
+	}
+
+	s.audit(userID, "restore", "dataset", id, "从回收站恢复数据集: "+dataset.Name)
+	return nil
+}
+
+// RestoreTask 恢复分析任务
+func (s *trashService) RestoreTask(id, userID string, isAdmin bool) error {
+	task, err := s.analysisRepo.GetDeletedTaskByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+	if !isAdmin && task.CreatedBy != userID {
+		return ErrTrashForbidden
+	}
+
+	if err := s.bin.move(s.bin.taskDir(id), filepath.Join(s.resultsDir, id)); err != nil {
+		return fmt.Errorf("failed to restore task files: %w", err)
+	}
+	if err := s.analysisRepo.RestoreTask(id); err != nil {
+		return fmt.Errorf("failed to restore task: %w", err)
+	}
+
+	s.audit(userID, "restore", "analysis_task", id, "从回收站恢复分析任务: "+task.Name)
+	return nil
+}
+
+// RestoreResult 恢复分析结果，所属任务已删除时需先恢复任务
+func (s *trashService) RestoreResult(id, userID string, isAdmin bool) error {
+	result, err := s.analysisRepo.GetDeletedResultByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+	task, err := s.analysisRepo.GetTaskByID(result.TaskID)
+	if err != nil {
+		return notFoundOr(err)
+	}
+	if !isAdmin && task.CreatedBy != userID {
+		return ErrTrashForbidden
+	}
+
+	if result.FilePath != "" {
+		src := filepath.Join(s.bin.resultDir(id), filepath.Base(result.FilePath))
+		if err := s.bin.move(src, result.FilePath); err != nil {
+			return fmt.Errorf("failed to restore result file: %w", err)
+		}
+		os.Remove(s.bin.resultDir(id))
+	}
+	if err := s.analysisRepo.RestoreResult(id); err != nil {
+		return fmt.Errorf("failed to restore result: %w", err)
+	}
+
+	s.audit(userID, "restore", "analysis_result", id, "从回收站恢复分析结果: "+result.Title)
+	return nil
+}
+
+// PurgeDataset 彻底删除数据集
+func (s *trashService) PurgeDataset(id, operatorID string) error {
+	dataset, err := s.datasetRepo.GetDeletedByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+
+	if err := os.RemoveAll(s.bin.datasetDir(id)); err != nil {
+		logger.Error("Failed to remove trashed dataset directory", "error", err, "datasetId", id)
+	}
+	if err := s.datasetRepo.Purge(id); err != nil {
+		return fmt.Errorf("failed to purge dataset: %w", err)
+	}
+
+	s.audit(operatorID, "purge", "dataset", id, "彻底删除数据集: "+dataset.Name)
+	return nil
+}
+
+// PurgeTask 彻底删除分析任务及其结果
+func (s *trashService) PurgeTask(id, operatorID string) error {
+	task, err := s.analysisRepo.GetDeletedTaskByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+
+	results, err := s.analysisRepo.PurgeTask(id)
+	if err != nil {
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
+	if err := os.RemoveAll(s.bin.taskDir(id)); err != nil {
+		logger.Error("Failed to remove trashed task directory", "error", err, "taskId", id)
+	}
+	for _, result := range results {
+		if err := os.RemoveAll(s.bin.resultDir(result.ID)); err != nil {
+			logger.Error("Failed to remove trashed result directory", "error", err, "resultId", result.ID)
+		}
+	}
+
+	s.audit(operatorID, "purge", "analysis_task", id, "彻底删除分析任务: "+task.Name)
+	return nil
+}
+
+// PurgeResult 彻底删除分析结果
+func (s *trashService) PurgeResult(id, operatorID string) error {
+	result, err := s.analysisRepo.GetDeletedResultByID(id)
+	if err != nil {
+		return notFoundOr(err)
+	}
+
+	if err := os.RemoveAll(s.bin.resultDir(id)); err != nil {
+		logger.Error("Failed to remove trashed result directory", "error", err, "resultId", id)
+	}
+	if err := s.analysisRepo.PurgeResult(id); err != nil {
+		return fmt.Errorf("failed to purge result: %w", err)
+	}
+
+	s.audit(operatorID, "purge", "analysis_result", id, "彻底删除分析结果: "+result.Title)
+	return nil
+}
+
+// PurgeExpired 清理超过保留期限的项目
+func (s *trashService) PurgeExpired() (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -s.retentionDays())
+	purged := 0
+
+	datasets, err := s.datasetRepo.ListDeleted("", &cutoff)
+	if err != nil {
+		return purged, fmt.Errorf("failed to list expired datasets: %w", err)
+	}
+	for _, dataset := range datasets {
+		if err := s.PurgeDataset(dataset.ID, ""); err != nil {
+			logger.Error("Failed to purge expired dataset", "error", err, "datasetId", dataset.ID)
+			continue
+		}
+		purged++
+	}
+
+	tasks, err := s.analysisRepo.ListDeletedTasks("", &cutoff)
+	if err != nil {
+		return purged, fmt.Errorf("failed to list expired tasks: %w", err)
+	}
+	for _, task := range tasks {
+		if err := s.PurgeTask(task.ID, ""); err != nil {
+			logger.Error("Failed to purge expired task", "error", err, "taskId", task.ID)
+			continue
+		}
+		purged++
+	}
+
+	results, err := s.analysisRepo.ListDeletedResults("", &cutoff)
+	if err != nil {
+		return purged, fmt.Errorf("failed to list expired results: %w", err)
+	}
+	for _, result := range results {
+		if err := s.PurgeResult(result.ID, ""); err != nil {
+			logger.Error("Failed to purge expired result", "error", err, "resultId", result.ID)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// StartPurgeJob 启动后台定时清理
+func (s *trashService) StartPurgeJob(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			if n, err := s.PurgeExpired(); err != nil {
+				logger.Error("Failed to purge expired trash", "error", err)
+			} else if n > 0 {
+				logger.Info("Purged expired trash items", "count", n)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// retentionDays 回收站保留天数
+func (s *trashService) retentionDays() int {
+	days := s.systemService.GetSettingInt(trashRetentionSetting, defaultTrashRetentionDays)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// audit 记录审计日志，operatorID为空表示系统操作
+func (s *trashService) audit(operatorID, action, resource, resourceID, description string) {
+	log := &models.AuditLog{
+		UserID:      operatorID,
+		Action:      action,
+		Resource:    resource,
+		ResourceID:  resourceID,
+		Description: description,
+	}
+	if operatorID == "" {
+		log.UserName = "system"
+	}
+	if err := s.systemService.CreateAuditLog(log); err != nil {
+		logger.Error("Failed to create audit log", "error", err, "action", action, "resourceId", resourceID)
+	}
+}
+
+// notFoundOr 将记录不存在错误转换为ErrTrashItemNotFound
+func notFoundOr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTrashItemNotFound
+	}
+	return err
+}
@@ -0,0 +1,285 @@
+// Package argo 解析Argo浮标剖面NetCDF文件(单剖面R/D文件及多剖面_prof文件)，
+// 并提供压力-深度换算和标准层插值
+package argo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+)
+
+// ErrNotArgo 数据源不是Argo剖面文件
+var ErrNotArgo = errors.New("argo: not an Argo profile file")
+
+// Profile 单个剖面，各层按文件中的顺序存放，标志为逐层的QC字符
+type Profile struct {
+	Platform    string    // WMO浮标编号
+	Cycle       int       // 循环编号
+	Direction   string    // A上升剖面，D下降剖面
+	DataMode    string    // R实时，A实时调整，D延时模式
+	Time        time.Time // 观测时间(JULD)
+	TimeQC      byte
+	Latitude    float64
+	Longitude   float64
+	PositionQC  byte
+	Pressure    []float64 // 压力(dbar)
+	Temperature []float64 // 现场温度(°C)，文件中没有时为nil
+	Salinity    []float64 // 实用盐度，文件中没有时为nil
+	PressureQC  string
+	TempQC      string
+	SalinityQC  string
+}
+
+// IsArgo 判断数据源是否为Argo剖面文件
+func IsArgo(src *dataio.Source) bool {
+	if _, ok := src.Dim("N_PROF"); !ok {
+		return false
+	}
+	return src.Var("PRES") != nil && src.Var("JULD") != nil && src.Var("PLATFORM_NUMBER") != nil
+}
+
+// Read 读取文件中的全部剖面；调整模式(A/D)的剖面使用*_ADJUSTED变量及其标志，
+// 缺少时间或位置的剖面跳过
+func Read(src *dataio.Source) ([]*Profile, error) {
+	if !IsArgo(src) {
+		return nil, ErrNotArgo
+	}
+	n, _ := src.Dim("N_PROF")
+	if n == 0 {
+		return nil, nil
+	}
+
+	platforms, err := src.Var("PLATFORM_NUMBER").ReadText(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("argo: read PLATFORM_NUMBER: %w", err)
+	}
+	times, err := dataio.Times(src.Var("JULD"))
+	if err != nil {
+		return nil, fmt.Errorf("argo: read JULD: %w", err)
+	}
+	cycles := readValues(src, "CYCLE_NUMBER", n)
+	lats := readValues(src, "LATITUDE", n)
+	lons := readValues(src, "LONGITUDE", n)
+	directions := readFlags(src, "DIRECTION", n)
+	modes := readFlags(src, "DATA_MODE", n)
+	timeQC := readFlags(src, "JULD_QC", n)
+	positionQC := readFlags(src, "POSITION_QC", n)
+
+	params := map[string]*parameter{}
+	for _, name := range []string{"PRES", "TEMP", "PSAL"} {
+		if p, err := readParameter(src, name); err != nil {
+			return nil, err
+		} else if p != nil {
+			params[name] = p
+		}
+	}
+
+	var out []*Profile
+	for i := 0; i < n; i++ {
+		if i >= len(times) || times[i].IsZero() || math.IsNaN(lats[i]) || math.IsNaN(lons[i]) {
+			continue
+		}
+		p := &Profile{
+			Cycle:      int(cycles[i]),
+			Direction:  string(directions[i]),
+			DataMode:   string(modes[i]),
+			Time:       times[i],
+			TimeQC:     timeQC[i],
+			Latitude:   lats[i],
+			Longitude:  lons[i],
+			PositionQC: positionQC[i],
+		}
+		if i < len(platforms) {
+			p.Platform = platforms[i]
+		}
+		if math.IsNaN(cycles[i]) {
+			p.Cycle = -1
+		}
+		adjusted := p.DataMode == "A" || p.DataMode == "D"
+		p.Pressure, p.PressureQC = params["PRES"].profile(i, adjusted)
+		if t := params["TEMP"]; t != nil {
+			p.Temperature, p.TempQC = t.profile(i, adjusted)
+		}
+		if s := params["PSAL"]; s != nil {
+			p.Salinity, p.SalinityQC = s.profile(i, adjusted)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// parameter 剖面参数的原始值和调整值
+type parameter struct {
+	levels       int
+	raw, adj     []float64
+	rawQC, adjQC []byte
+}
+
+// readParameter 读取N_PROF×N_LEVELS的参数及其标志，变量不存在时返回nil
+func readParameter(src *dataio.Source, name string) (*parameter, error) {
+	v := src.Var(name)
+	if v == nil {
+		return nil, nil
+	}
+	if len(v.Shape) != 2 {
+		return nil, fmt.Errorf("argo: %s is not a N_PROF x N_LEVELS variable", name)
+	}
+	p := &parameter{levels: v.Shape[1]}
+	var err error
+	if p.raw, err = v.ReadAll(); err != nil {
+		return nil, fmt.Errorf("argo: read %s: %w", name, err)
+	}
+	p.rawQC = readFlags(src, name+"_QC", len(p.raw))
+	if adj := src.Var(name + "_ADJUSTED"); adj != nil {
+		if p.adj, err = adj.ReadAll(); err != nil {
+			return nil, fmt.Errorf("argo: read %s_ADJUSTED: %w", name, err)
+		}
+		p.adjQC = readFlags(src, name+"_ADJUSTED_QC", len(p.adj))
+	}
+	return p, nil
+}
+
+// profile 第i个剖面的值和标志，调整模式且调整值非全部缺测时使用调整值
+func (p *parameter) profile(i int, adjusted bool) ([]float64, string) {
+	if p == nil {
+		return nil, ""
+	}
+	from, to := i*p.levels, (i+1)*p.levels
+	if adjusted && p.adj != nil && hasValue(p.adj[from:to]) {
+		return append([]float64(nil), p.adj[from:to]...), string(p.adjQC[from:to])
+	}
+	return append([]float64(nil), p.raw[from:to]...), string(p.rawQC[from:to])
+}
+
+// readValues 读取N_PROF维的数值变量，变量不存在或读取失败时为NaN
+func readValues(src *dataio.Source, name string, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	if v := src.Var(name); v != nil {
+		if values, err := v.ReadAll(); err == nil {
+			copy(out, values)
+		}
+	}
+	return out
+}
+
+// readFlags 读取逐元素的字符标志，长度不足的部分补空格
+func readFlags(src *dataio.Source, name string, n int) []byte {
+	out := []byte(strings.Repeat(" ", n))
+	if v := src.Var(name); v != nil && v.IsText() {
+		if chars, err := v.ReadChars(nil, nil); err == nil {
+			copy(out, chars)
+		}
+	}
+	for i, c := range out {
+		if c == 0 {
+			out[i] = ' '
+		}
+	}
+	return out
+}
+
+func hasValue(values []float64) bool {
+	for _, x := range values {
+		if !math.IsNaN(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// GoodQC 可用于分析的标志: 1正确、2可能正确、5已修改、8插值/估计值
+func GoodQC(flag byte) bool {
+	return flag == '1' || flag == '2' || flag == '5' || flag == '8'
+}
+
+// Filter 标志不可用的值置为NaN，返回新数组
+func Filter(values []float64, flags string) []float64 {
+	out := make([]float64, len(values))
+	for i, x := range values {
+		if i < len(flags) && GoodQC(flags[i]) {
+			out[i] = x
+		} else {
+			out[i] = math.NaN()
+		}
+	}
+	return out
+}
+
+// PressureToDepth 由压力(dbar)和纬度计算深度(m)，UNESCO 1983(Saunders & Fofonoff)公式
+func PressureToDepth(p, lat float64) float64 {
+	x := math.Sin(lat * math.Pi / 180)
+	x *= x
+	g := 9.780318*(1+(5.2788e-3+2.36e-5*x)*x) + 1.092e-6*p
+	return ((((-1.82e-15*p+2.279e-10)*p-2.2512e-5)*p + 9.72659) * p) / g
+}
+
+// StandardDepths 默认标准层深度(m)，与WOA标准层一致
+var StandardDepths = []float64{
+	0, 5, 10, 20, 30, 50, 75, 100, 125, 150, 200, 250, 300, 400, 500,
+	600, 700, 800, 900, 1000, 1100, 1200, 1300, 1400, 1500, 1750, 2000,
+}
+
+// surfaceTolerance 最浅观测在此深度(m)以内时，其值用于更浅的标准层
+const surfaceTolerance = 5
+
+// maxGap 线性插值允许的上下相邻观测最大间距(m)，随深度放宽
+func maxGap(z float64) float64 {
+	switch {
+	case z <= 200:
+		return 50
+	case z <= 1000:
+		return 200
+	default:
+		return 500
+	}
+}
+
+// Interpolate 将剖面插值到标准层: 取depth与value均有效的层按深度排序后线性插值，
+// 不外推(最浅观测以上surfaceTolerance内除外)，上下观测间距超过maxGap时为NaN
+func Interpolate(depth, values []float64, levels []float64) []float64 {
+	type sample struct{ z, v float64 }
+	var samples []sample
+	for i := range depth {
+		if i < len(values) && !math.IsNaN(depth[i]) && !math.IsNaN(values[i]) {
+			samples = append(samples, sample{depth[i], values[i]})
+		}
+	}
+	sort.SliceStable(samples, func(a, b int) bool { return samples[a].z < samples[b].z })
+
+	out := make([]float64, len(levels))
+	for k, z := range levels {
+		out[k] = math.NaN()
+		if len(samples) == 0 {
+			continue
+		}
+		first, last := samples[0], samples[len(samples)-1]
+		switch {
+		case z < first.z:
+			if first.z-z <= surfaceTolerance {
+				out[k] = first.v
+			}
+			continue
+		case z > last.z:
+			continue
+		}
+		j := sort.Search(len(samples), func(i int) bool { return samples[i].z >= z })
+		if samples[j].z == z {
+			out[k] = samples[j].v
+			continue
+		}
+		lo, hi := samples[j-1], samples[j]
+		if hi.z-lo.z > maxGap(z) {
+			continue
+		}
+		out[k] = lo.v + (hi.v-lo.v)*(z-lo.z)/(hi.z-lo.z)
+	}
+	return out
+}
@@ -0,0 +1,285 @@
+// Package currents 海流矢量场的诊断量: 流速、流向、涡度、散度、Okubo-Weiss参数、涡旋识别和海流玫瑰图
+package currents
+
+import (
+	"math"
+	"sort"
+)
+
+// earthRadius 地球平均半径(m)
+const earthRadius = 6371000.0
+
+// Speed 流速 √(u²+v²)，任一分量缺测时为NaN
+func Speed(u, v float64) float64 {
+	return math.Hypot(u, v)
+}
+
+// Direction 流向: 海流流去的方向，从正北顺时针的角度(0~360)；任一分量缺测或流速为0时为NaN
+func Direction(u, v float64) float64 {
+	if math.IsNaN(u) || math.IsNaN(v) || (u == 0 && v == 0) {
+		return math.NaN()
+	}
+	return math.Mod(math.Atan2(u, v)*180/math.Pi+360, 360)
+}
+
+// Grid 规则经纬度网格: 第i行为纬度 MinLat + i×Step，第j列为经度 MinLng + j×Step
+type Grid struct {
+	MinLat float64
+	MinLng float64
+	Step   float64 // 格距(度)
+}
+
+// Lat 第i行的纬度
+func (g Grid) Lat(i int) float64 {
+	return g.MinLat + float64(i)*g.Step
+}
+
+// Lng 第j列的经度
+func (g Grid) Lng(j int) float64 {
+	return g.MinLng + float64(j)*g.Step
+}
+
+// spacing 第i行的东西向和南北向格距(m)
+func (g Grid) spacing(i int) (dx, dy float64) {
+	dy = earthRadius * g.Step * math.Pi / 180
+	return dy * math.Cos(g.Lat(i)*math.Pi/180), dy
+}
+
+// Kinematics 流场的运动学诊断量(单位 s⁻¹，Okubo-Weiss参数为 s⁻²)
+type Kinematics struct {
+	Vorticity    [][]float64 // 相对涡度 ζ = ∂v/∂x - ∂u/∂y
+	Divergence   [][]float64 // 水平散度 ∂u/∂x + ∂v/∂y
+	NormalStrain [][]float64 // 法向应变 ∂u/∂x - ∂v/∂y
+	ShearStrain  [][]float64 // 切向应变 ∂v/∂x + ∂u/∂y
+	OkuboWeiss   [][]float64 // W = sn² + ss² - ζ²，负值为涡度主导区
+}
+
+// Compute 由规则网格上的u、v(按纬度升序逐行排列)计算运动学诊断量: 内部用中心差分，
+// 邻点缺测或位于边界时用单侧差分；本格点缺测或两侧均缺测时为NaN
+func Compute(g Grid, u, v [][]float64) *Kinematics {
+	rows := len(u)
+	k := &Kinematics{
+		Vorticity:    nanGrid(rows, u),
+		Divergence:   nanGrid(rows, u),
+		NormalStrain: nanGrid(rows, u),
+		ShearStrain:  nanGrid(rows, u),
+		OkuboWeiss:   nanGrid(rows, u),
+	}
+	for i := 0; i < rows; i++ {
+		dx, dy := g.spacing(i)
+		if dx <= 0 {
+			continue // 极点
+		}
+		for j := range u[i] {
+			if math.IsNaN(u[i][j]) || math.IsNaN(v[i][j]) {
+				continue
+			}
+			dudx := derivative(at(u, i, j-1), u[i][j], at(u, i, j+1), dx)
+			dvdx := derivative(at(v, i, j-1), v[i][j], at(v, i, j+1), dx)
+			dudy := derivative(at(u, i-1, j), u[i][j], at(u, i+1, j), dy)
+			dvdy := derivative(at(v, i-1, j), v[i][j], at(v, i+1, j), dy)
+			zeta := dvdx - dudy
+			sn := dudx - dvdy
+			ss := dvdx + dudy
+			k.Vorticity[i][j] = zeta
+			k.Divergence[i][j] = dudx + dvdy
+			k.NormalStrain[i][j] = sn
+			k.ShearStrain[i][j] = ss
+			k.OkuboWeiss[i][j] = sn*sn + ss*ss - zeta*zeta
+		}
+	}
+	return k
+}
+
+// nanGrid 与like同形状、以NaN填充的网格
+func nanGrid(rows int, like [][]float64) [][]float64 {
+	out := make([][]float64, rows)
+	for i := range out {
+		out[i] = make([]float64, len(like[i]))
+		for j := range out[i] {
+			out[i][j] = math.NaN()
+		}
+	}
+	return out
+}
+
+// at 网格值，越界时为NaN
+func at(a [][]float64, i, j int) float64 {
+	if i < 0 || i >= len(a) || j < 0 || j >= len(a[i]) {
+		return math.NaN()
+	}
+	return a[i][j]
+}
+
+// derivative 间距为h的三点差分: 两侧都有效时用中心差分，否则用有效一侧的单侧差分
+func derivative(prev, center, next, h float64) float64 {
+	switch {
+	case !math.IsNaN(prev) && !math.IsNaN(next):
+		return (next - prev) / (2 * h)
+	case !math.IsNaN(next):
+		return (next - center) / h
+	case !math.IsNaN(prev):
+		return (center - prev) / h
+	}
+	return math.NaN()
+}
+
+// Eddy Okubo-Weiss方法识别的涡旋
+type Eddy struct {
+	Lat           float64 // 中心(按-W加权的质心)
+	Lng           float64
+	Cells         int
+	Area          float64 // 面积(km²)
+	Radius        float64 // 等面积圆半径(km)
+	MeanVorticity float64 // 平均相对涡度(s⁻¹)
+	MinOkuboWeiss float64
+	Cyclonic      bool // 涡度与当地科氏参数同号
+}
+
+// DetectEddies 按 Isern-Fontanet 等(2003)的方法识别涡旋: W < -threshold×σ_W(σ_W为全场W的标准差)的格点
+// 按四邻域连通成区域，格点数不少于minCells的区域为一个涡旋；结果按面积降序
+func DetectEddies(g Grid, k *Kinematics, threshold float64, minCells int) []Eddy {
+	w := k.OkuboWeiss
+	var sum, sumSq, n float64
+	for _, row := range w {
+		for _, x := range row {
+			if !math.IsNaN(x) {
+				sum += x
+				sumSq += x * x
+				n++
+			}
+		}
+	}
+	if n < 2 {
+		return nil
+	}
+	mean := sum / n
+	sigma := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+	if sigma == 0 {
+		return nil
+	}
+	limit := -threshold * sigma
+
+	visited := make([][]bool, len(w))
+	for i := range visited {
+		visited[i] = make([]bool, len(w[i]))
+	}
+	var eddies []Eddy
+	for i := range w {
+		for j := range w[i] {
+			if visited[i][j] || !(w[i][j] < limit) {
+				continue
+			}
+			// 广度优先搜索连通区域
+			queue := [][2]int{{i, j}}
+			visited[i][j] = true
+			var cells [][2]int
+			for len(queue) > 0 {
+				c := queue[0]
+				queue = queue[1:]
+				cells = append(cells, c)
+				for _, d := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+					ni, nj := c[0]+d[0], c[1]+d[1]
+					if ni < 0 || ni >= len(w) || nj < 0 || nj >= len(w[ni]) || visited[ni][nj] || !(w[ni][nj] < limit) {
+						continue
+					}
+					visited[ni][nj] = true
+					queue = append(queue, [2]int{ni, nj})
+				}
+			}
+			if len(cells) < minCells {
+				continue
+			}
+
+			e := Eddy{Cells: len(cells), MinOkuboWeiss: math.Inf(1)}
+			var weight, zeta float64
+			for _, c := range cells {
+				dx, dy := g.spacing(c[0])
+				e.Area += dx * dy / 1e6
+				x := w[c[0]][c[1]]
+				weight -= x
+				e.Lat -= x * g.Lat(c[0])
+				e.Lng -= x * g.Lng(c[1])
+				zeta += k.Vorticity[c[0]][c[1]]
+				e.MinOkuboWeiss = math.Min(e.MinOkuboWeiss, x)
+			}
+			e.Lat /= weight
+			e.Lng /= weight
+			e.Radius = math.Sqrt(e.Area / math.Pi)
+			e.MeanVorticity = zeta / float64(len(cells))
+			e.Cyclonic = e.MeanVorticity*e.Lat > 0
+			eddies = append(eddies, e)
+		}
+	}
+	sort.SliceStable(eddies, func(a, b int) bool { return eddies[a].Area > eddies[b].Area })
+	return eddies
+}
+
+// Rose 海流玫瑰图: 各流向扇区内各流速等级的出现频率
+type Rose struct {
+	Sectors     int         // 扇区数，第k个扇区以 k×360/Sectors 度为中心
+	SpeedBins   []float64   // 流速等级的分界(升序)，共 len(SpeedBins)+1 个等级
+	Counts      [][]int     // Counts[扇区][等级]
+	Frequencies [][]float64 // 占有效样本的比例
+	Samples     int         // 有效样本数
+	Calm        int         // 流速为0(流向无定义)的样本数，计入第一个扇区
+	MeanSpeed   float64     // 平均流速(标量平均)
+	MaxSpeed    float64
+	MeanU       float64 // 平均流矢量
+	MeanV       float64
+}
+
+// NewRose 统计u、v序列的海流玫瑰图，任一分量缺测的样本忽略
+func NewRose(u, v []float64, sectors int, speedBins []float64) *Rose {
+	r := &Rose{Sectors: sectors, SpeedBins: speedBins, Counts: make([][]int, sectors), Frequencies: make([][]float64, sectors),
+		MeanSpeed: math.NaN(), MaxSpeed: math.NaN(), MeanU: math.NaN(), MeanV: math.NaN()}
+	for k := range r.Counts {
+		r.Counts[k] = make([]int, len(speedBins)+1)
+		r.Frequencies[k] = make([]float64, len(speedBins)+1)
+	}
+	width := 360 / float64(sectors)
+	var speedSum, uSum, vSum float64
+	for i := range u {
+		if math.IsNaN(u[i]) || math.IsNaN(v[i]) {
+			continue
+		}
+		speed := Speed(u[i], v[i])
+		sector := 0
+		if dir := Direction(u[i], v[i]); math.IsNaN(dir) {
+			r.Calm++
+		} else {
+			sector = int(math.Floor((dir+width/2)/width)) % sectors
+		}
+		class := sort.SearchFloat64s(speedBins, speed)
+		if class < len(speedBins) && speedBins[class] == speed {
+			class++ // 分界值计入上一等级
+		}
+		r.Counts[sector][class]++
+		r.Samples++
+		speedSum += speed
+		uSum += u[i]
+		vSum += v[i]
+		if math.IsNaN(r.MaxSpeed) || speed > r.MaxSpeed {
+			r.MaxSpeed = speed
+		}
+	}
+	if r.Samples == 0 {
+		return r
+	}
+	n := float64(r.Samples)
+	r.MeanSpeed, r.MeanU, r.MeanV = speedSum/n, uSum/n, vSum/n
+	for k := range r.Counts {
+		for c, count := range r.Counts[k] {
+			r.Frequencies[k][c] = float64(count) / n
+		}
+	}
+	return r
+}
+
+// Steadiness 海流稳定度: 平均流矢量的大小与平均流速之比(0~1)
+func (r *Rose) Steadiness() float64 {
+	if r.Samples == 0 || r.MeanSpeed == 0 {
+		return math.NaN()
+	}
+	return Speed(r.MeanU, r.MeanV) / r.MeanSpeed
+}
@@ -0,0 +1,200 @@
+package currents
+
+import (
+	"math"
+	"testing"
+)
+
+func closeTo(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol
+}
+
+func TestSpeedDirection(t *testing.T) {
+	tests := []struct {
+		u, v       float64
+		speed, dir float64
+	}{
+		{0, 1, 1, 0},
+		{1, 0, 1, 90},
+		{0, -2, 2, 180},
+		{-1, 0, 1, 270},
+		{1, 1, math.Sqrt2, 45},
+		{-3, 4, 5, 360 - math.Atan(0.75)*180/math.Pi},
+		{0, 0, 0, math.NaN()},
+		{math.NaN(), 1, math.NaN(), math.NaN()},
+	}
+	for _, tt := range tests {
+		speed, dir := Speed(tt.u, tt.v), Direction(tt.u, tt.v)
+		if !(closeTo(speed, tt.speed, 1e-12) || math.IsNaN(speed) && math.IsNaN(tt.speed)) ||
+			!(closeTo(dir, tt.dir, 1e-9) || math.IsNaN(dir) && math.IsNaN(tt.dir)) {
+			t.Errorf("(%g, %g): speed %g, direction %g, want %g, %g", tt.u, tt.v, speed, dir, tt.speed, tt.dir)
+		}
+	}
+}
+
+func TestGridSpacing(t *testing.T) {
+	// 一个纬度约111.195 km，纬度60°处的经向格距减半
+	g := Grid{MinLat: 0, MinLng: 100, Step: 0.25}
+	for _, tt := range []struct {
+		i      int
+		dx, dy float64
+	}{
+		{0, 27798.73, 27798.73},
+		{240, 13899.37, 27798.73},
+		{360, 0, 27798.73},
+	} {
+		dx, dy := g.spacing(tt.i)
+		if !closeTo(dx, tt.dx, 0.01) || !closeTo(dy, tt.dy, 0.01) {
+			t.Errorf("row %d (lat %g): spacing %g, %g, want %g, %g", tt.i, g.Lat(tt.i), dx, dy, tt.dx, tt.dy)
+		}
+	}
+	if g.Lng(4) != 101 {
+		t.Errorf("Lng(4) = %g, want 101", g.Lng(4))
+	}
+}
+
+// linearField 只随纬度线性变化的流场 u = a·y、v = b·y，其差分在内部和边界上都是精确的
+func linearField(g Grid, rows, cols int, a, b float64) (u, v [][]float64) {
+	_, dy := g.spacing(0)
+	for i := 0; i < rows; i++ {
+		u = append(u, make([]float64, cols))
+		v = append(v, make([]float64, cols))
+		for j := 0; j < cols; j++ {
+			u[i][j], v[i][j] = a*dy*float64(i), b*dy*float64(i)
+		}
+	}
+	return u, v
+}
+
+func TestCompute(t *testing.T) {
+	g := Grid{MinLat: 20, MinLng: 110, Step: 0.1}
+	tests := []struct {
+		name                 string
+		a, b                 float64
+		zeta, div, sn, ss, w float64
+	}{
+		// 纯剪切: 涡度与应变相抵，W = 0
+		{"shear", 1e-5, 0, -1e-5, 0, 0, 1e-5, 0},
+		// 南北向辐散
+		{"stretch", 0, 2e-5, 0, 2e-5, -2e-5, 0, 4e-10},
+		{"both", 1e-5, 2e-5, -1e-5, 2e-5, -2e-5, 1e-5, 4e-10},
+	}
+	for _, tt := range tests {
+		u, v := linearField(g, 5, 5, tt.a, tt.b)
+		u[2][2] = math.NaN()
+		k := Compute(g, u, v)
+		for i := range u {
+			for j := range u[i] {
+				if i == 2 && j == 2 {
+					if !math.IsNaN(k.Vorticity[i][j]) || !math.IsNaN(k.OkuboWeiss[i][j]) {
+						t.Errorf("%s: missing cell = %g", tt.name, k.Vorticity[i][j])
+					}
+					continue
+				}
+				if !closeTo(k.Vorticity[i][j], tt.zeta, 1e-15) || !closeTo(k.Divergence[i][j], tt.div, 1e-15) ||
+					!closeTo(k.NormalStrain[i][j], tt.sn, 1e-15) || !closeTo(k.ShearStrain[i][j], tt.ss, 1e-15) ||
+					!closeTo(k.OkuboWeiss[i][j], tt.w, 1e-20) {
+					t.Errorf("%s: cell (%d, %d) = %g, %g, %g, %g, %g", tt.name, i, j, k.Vorticity[i][j], k.Divergence[i][j],
+						k.NormalStrain[i][j], k.ShearStrain[i][j], k.OkuboWeiss[i][j])
+				}
+			}
+		}
+	}
+
+	// 东西两侧都缺测时该格点无法求x方向导数
+	u, v := linearField(g, 3, 3, 1e-5, 0)
+	u[1][0], u[1][2] = math.NaN(), math.NaN()
+	if k := Compute(g, u, v); !math.IsNaN(k.Divergence[1][1]) {
+		t.Errorf("divergence without x neighbours = %g, want NaN", k.Divergence[1][1])
+	}
+}
+
+// gaussianEddies 高斯流函数 ψ = A·exp(-r²/L²) 叠加的地转流场，中心涡度为 -4A/L²
+func gaussianEddies(g Grid, rows, cols int, eddies [][4]float64) (u, v [][]float64) {
+	for i := 0; i < rows; i++ {
+		u = append(u, make([]float64, cols))
+		v = append(v, make([]float64, cols))
+		for j := 0; j < cols; j++ {
+			for _, e := range eddies {
+				lat, lng, a, l := e[0], e[1], e[2], e[3]
+				y := earthRadius * (g.Lat(i) - lat) * math.Pi / 180
+				x := earthRadius * math.Cos(g.Lat(i)*math.Pi/180) * (g.Lng(j) - lng) * math.Pi / 180
+				psi := a * math.Exp(-(x*x+y*y)/(l*l))
+				u[i][j] += 2 * y / (l * l) * psi
+				v[i][j] -= 2 * x / (l * l) * psi
+			}
+		}
+	}
+	return u, v
+}
+
+func TestDetectEddies(t *testing.T) {
+	g := Grid{MinLat: 29, MinLng: 118.5, Step: 0.05}
+	// 北半球: ψ < 0为气旋式(逆时针)涡旋
+	eddies := [][4]float64{{30, 119.5, -3000, 30e3}, {30.2, 120.6, 1500, 15e3}}
+	u, v := gaussianEddies(g, 41, 61, eddies)
+	k := Compute(g, u, v)
+
+	if zeta, want := k.Vorticity[20][20], 4*3000/(30e3*30e3); !closeTo(zeta, want, 0.05*want) {
+		t.Errorf("center vorticity = %g, want %g", zeta, want)
+	}
+	found := DetectEddies(g, k, 0.2, 4)
+	if len(found) != 2 {
+		t.Fatalf("%d eddies, want 2: %+v", len(found), found)
+	}
+	for n, e := range found {
+		want := eddies[n]
+		if !closeTo(e.Lat, want[0], g.Step) || !closeTo(e.Lng, want[1], g.Step) {
+			t.Errorf("eddy %d center = %g, %g, want %g, %g", n, e.Lat, e.Lng, want[0], want[1])
+		}
+		if e.Cyclonic != (want[2] < 0) || (e.MeanVorticity > 0) != (want[2] < 0) || e.MinOkuboWeiss >= 0 {
+			t.Errorf("eddy %d = %+v", n, e)
+		}
+		if !closeTo(e.Radius*e.Radius*math.Pi, e.Area, 1e-6) {
+			t.Errorf("eddy %d radius %g for area %g", n, e.Radius, e.Area)
+		}
+	}
+	if found[0].Area <= found[1].Area {
+		t.Errorf("eddies not sorted by area: %g, %g", found[0].Area, found[1].Area)
+	}
+	if found := DetectEddies(g, k, 0.2, 100000); len(found) != 0 {
+		t.Errorf("minCells filter kept %d eddies", len(found))
+	}
+	u, v = linearField(g, 5, 5, 1e-5, 0)
+	if found := DetectEddies(g, Compute(g, u, v), 0.2, 1); found != nil {
+		t.Errorf("uniform W gave %+v", found)
+	}
+}
+
+func TestRose(t *testing.T) {
+	u := []float64{0, 0.6, 0, -2, 0, math.NaN(), 0.3, -0.1}
+	v := []float64{0.3, 0, -1, 0, 0, 1, 0.4, 1}
+	r := NewRose(u, v, 4, []float64{0.5, 1})
+
+	// 流速恰为分界值时计入上一等级，静流计入第一个扇区
+	want := [][]int{{2, 1, 1}, {0, 1, 0}, {0, 0, 1}, {0, 0, 1}}
+	for k := range want {
+		for c := range want[k] {
+			if r.Counts[k][c] != want[k][c] || !closeTo(r.Frequencies[k][c], float64(want[k][c])/7, 1e-12) {
+				t.Errorf("sector %d class %d: %d (%g), want %d", k, c, r.Counts[k][c], r.Frequencies[k][c], want[k][c])
+			}
+		}
+	}
+	meanSpeed := (0.3 + 0.6 + 1 + 2 + 0 + 0.5 + math.Hypot(0.1, 1)) / 7
+	if r.Samples != 7 || r.Calm != 1 || r.MaxSpeed != 2 || !closeTo(r.MeanSpeed, meanSpeed, 1e-12) ||
+		!closeTo(r.MeanU, -1.2/7, 1e-12) || !closeTo(r.MeanV, 0.7/7, 1e-12) {
+		t.Errorf("rose = %+v", r)
+	}
+	if got, want := r.Steadiness(), math.Hypot(1.2, 0.7)/7/meanSpeed; !closeTo(got, want, 1e-12) {
+		t.Errorf("Steadiness = %g, want %g", got, want)
+	}
+
+	// 完全定常的海流稳定度为1
+	if got := NewRose([]float64{1, 2}, []float64{1, 2}, 8, nil).Steadiness(); !closeTo(got, 1, 1e-12) {
+		t.Errorf("steady current = %g, want 1", got)
+	}
+	empty := NewRose([]float64{math.NaN()}, []float64{0}, 8, nil)
+	if empty.Samples != 0 || !math.IsNaN(empty.MeanSpeed) || !math.IsNaN(empty.Steadiness()) {
+		t.Errorf("empty rose = %+v", empty)
+	}
+}
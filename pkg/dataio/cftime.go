@@ -0,0 +1,196 @@
+package dataio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// TimeUnits CF时间单位，形如 "days since 1950-01-01 00:00:00"
+type TimeUnits struct {
+	Step     time.Duration
+	Epoch    time.Time
+	Calendar *Calendar // 非公历的模式日历，nil为公历
+}
+
+// Calendar 每年月长固定的CF模式日历(noleap、all_leap、360_day)。转换为time.Time时，
+// 日期在公历中存在(月长不超过公历月长)则按原日期表示，否则(如360_day的2月30日)按在月内的比例映射到公历的同一个月，
+// 年、月始终不变
+type Calendar struct {
+	Name      string
+	MonthDays [12]int
+}
+
+// calendars 支持的模式日历，键为CF calendar属性值
+var calendars = map[string]*Calendar{
+	"noleap":   {Name: "noleap", MonthDays: [12]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+	"365_day":  {Name: "noleap", MonthDays: [12]int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+	"all_leap": {Name: "all_leap", MonthDays: [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+	"366_day":  {Name: "all_leap", MonthDays: [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+	"360_day":  {Name: "360_day", MonthDays: [12]int{30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30}},
+}
+
+// ParseCalendar 解析CF calendar属性，公历(含空值、standard、gregorian、proleptic_gregorian)返回nil
+func ParseCalendar(name string) (*Calendar, error) {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "", "standard", "gregorian", "proleptic_gregorian":
+		return nil, nil
+	}
+	if c, ok := calendars[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("dataio: unsupported calendar %q", name)
+}
+
+// yearDays 一年的天数
+func (c *Calendar) yearDays() int {
+	n := 0
+	for _, d := range c.MonthDays {
+		n += d
+	}
+	return n
+}
+
+// seconds 日期距0年1月1日的秒数，day为月内的天数(从0开始，可含小数)
+func (c *Calendar) seconds(year, month int, day float64) float64 {
+	days := float64(year * c.yearDays())
+	for m := 0; m < month-1; m++ {
+		days += float64(c.MonthDays[m])
+	}
+	return (days + day) * 86400
+}
+
+// gregorianDays 公历某月的天数
+func gregorianDays(year, month int) int {
+	return time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// toTime 将距0年1月1日的秒数转换为time.Time
+func (c *Calendar) toTime(seconds float64) time.Time {
+	days := seconds / 86400
+	year := int(math.Floor(days / float64(c.yearDays())))
+	day := days - float64(year*c.yearDays())
+	month := 1
+	for month < 12 && day >= float64(c.MonthDays[month-1]) {
+		day -= float64(c.MonthDays[month-1])
+		month++
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	if n := c.MonthDays[month-1]; n > gregorianDays(year, month) {
+		day *= float64(gregorianDays(year, month)) / float64(n)
+	}
+	return start.Add(time.Duration(math.Round(day * 86400 * float64(time.Second))))
+}
+
+// fromTime toTime的逆变换
+func (c *Calendar) fromTime(t time.Time) float64 {
+	t = t.UTC()
+	year, month := t.Year(), int(t.Month())
+	start := time.Date(year, t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	day := t.Sub(start).Hours() / 24
+	if n := c.MonthDays[month-1]; n > gregorianDays(year, month) {
+		day *= float64(n) / float64(gregorianDays(year, month))
+	}
+	return c.seconds(year, month, day)
+}
+
+// timeSteps 时间单位名称与时长
+var timeSteps = map[string]time.Duration{
+	"second": time.Second, "seconds": time.Second, "sec": time.Second, "secs": time.Second, "s": time.Second,
+	"minute": time.Minute, "minutes": time.Minute, "min": time.Minute, "mins": time.Minute,
+	"hour": time.Hour, "hours": time.Hour, "hr": time.Hour, "hrs": time.Hour, "h": time.Hour,
+	"day": 24 * time.Hour, "days": 24 * time.Hour, "d": 24 * time.Hour,
+}
+
+// epochLayouts 起始时间的可接受格式
+var epochLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.0",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04Z",
+	"2006-01-02",
+	"2006-1-2 15:04:05",
+	"2006-1-2",
+}
+
+// ParseTimeUnits 解析CF时间单位，日历为公历；模式日历由Times按变量的calendar属性设置
+func ParseTimeUnits(units string) (*TimeUnits, error) {
+	parts := strings.SplitN(strings.TrimSpace(units), " since ", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("dataio: invalid time units %q", units)
+	}
+
+	step, ok := timeSteps[strings.ToLower(strings.TrimSpace(parts[0]))]
+	if !ok {
+		return nil, fmt.Errorf("dataio: unsupported time step %q", parts[0])
+	}
+
+	ref := strings.TrimSpace(parts[1])
+	ref = strings.TrimSuffix(ref, " UTC")
+	ref = strings.TrimSuffix(ref, " utc")
+	ref = strings.TrimSpace(ref)
+	for _, layout := range epochLayouts {
+		if t, err := time.Parse(layout, ref); err == nil {
+			return &TimeUnits{Step: step, Epoch: t.UTC()}, nil
+		}
+	}
+	return nil, fmt.Errorf("dataio: invalid time reference %q", parts[1])
+}
+
+// maxTimeSeconds 可转换的时间偏移上限(秒，约3万年)，超出时按缺测处理
+const maxTimeSeconds = 1e12
+
+// Time 将数值转换为时间，非有限值或偏移超过maxTimeSeconds时返回零值。
+// time.Duration只能表示约292年，按整天(AddDate)加日内秒数计算，远离起始时间的数值(如 days since 0001-01-01)不会溢出
+func (u *TimeUnits) Time(v float64) time.Time {
+	seconds := v * u.Step.Seconds()
+	if math.IsNaN(seconds) || math.Abs(seconds) > maxTimeSeconds {
+		return time.Time{}
+	}
+	if u.Calendar != nil {
+		return u.Calendar.toTime(u.Calendar.fromTime(u.Epoch) + seconds)
+	}
+	days := math.Floor(seconds / 86400)
+	rest := seconds - days*86400
+	return u.Epoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round(rest * float64(time.Second))))
+}
+
+// Value 将时间转换为数值
+func (u *TimeUnits) Value(t time.Time) float64 {
+	if u.Calendar != nil {
+		return (u.Calendar.fromTime(t) - u.Calendar.fromTime(u.Epoch)) / u.Step.Seconds()
+	}
+	// 按Unix秒相减，避免Sub在相差约292年以上时饱和
+	seconds := float64(t.Unix()-u.Epoch.Unix()) + float64(t.Nanosecond()-u.Epoch.Nanosecond())/1e9
+	return seconds / u.Step.Seconds()
+}
+
+// Times 读取时间变量并按calendar属性转换为时间，缺测值为零值
+func Times(v *Variable) ([]time.Time, error) {
+	units, err := ParseTimeUnits(v.Units())
+	if err != nil {
+		return nil, err
+	}
+	if units.Calendar, err = ParseCalendar(v.Attrs.String("calendar")); err != nil {
+		return nil, err
+	}
+
+	values, err := v.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]time.Time, len(values))
+	for i, x := range values {
+		if !math.IsNaN(x) {
+			out[i] = units.Time(x)
+		}
+	}
+	return out, nil
+}
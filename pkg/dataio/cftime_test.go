@@ -0,0 +1,71 @@
+package dataio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimeUnits(t *testing.T) {
+	tests := []struct {
+		units    string
+		calendar string
+		value    float64
+		want     time.Time
+	}{
+		// 远离起始时间的数值超出time.Duration的范围
+		{"days since 0001-01-01", "", 737425, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"days since 0001-01-01 00:00:00", "proleptic_gregorian", 737424.25, time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)},
+		{"hours since 1800-01-01", "", 1927680.5, time.Date(2019, 11, 29, 0, 30, 0, 0, time.UTC)},
+		{"seconds since 1900-01-01", "standard", 3786825600, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 1950-01-01", "", -36524.25, time.Date(1849, 12, 31, 18, 0, 0, 0, time.UTC)},
+		{"days since 1970-01-01T00:00:00Z", "", 0.5, time.Date(1970, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"minutes since 2000-01-01 12:00", "", -90, time.Date(2000, 1, 1, 10, 30, 0, 0, time.UTC)},
+		// 模式日历
+		{"days since 2000-01-01", "noleap", 365, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 2000-01-01", "noleap", 59, time.Date(2000, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 2000-01-01", "360_day", 30, time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 2000-01-01", "360_day", 360, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 0001-01-01", "360_day", 719280, time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"days since 2000-01-01", "all_leap", 366, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		u, err := ParseTimeUnits(tt.units)
+		if err != nil {
+			t.Fatalf("ParseTimeUnits(%q): %v", tt.units, err)
+		}
+		if u.Calendar, err = ParseCalendar(tt.calendar); err != nil {
+			t.Fatalf("ParseCalendar(%q): %v", tt.calendar, err)
+		}
+		got := u.Time(tt.value)
+		if !got.Equal(tt.want) {
+			t.Errorf("%s (%s) %g = %v, want %v", tt.units, tt.calendar, tt.value, got, tt.want)
+		}
+		if back := u.Value(got); math.Abs(back-tt.value) > 1e-6 {
+			t.Errorf("%s (%s) Value(%v) = %.9g, want %g", tt.units, tt.calendar, got, back, tt.value)
+		}
+	}
+}
+
+func TestTimeUnitsOutOfRange(t *testing.T) {
+	u, err := ParseTimeUnits("days since 1950-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e20} {
+		if got := u.Time(v); !got.IsZero() {
+			t.Errorf("Time(%g) = %v, want zero time", v, got)
+		}
+	}
+}
+
+func TestParseTimeUnitsInvalid(t *testing.T) {
+	for _, units := range []string{"days", "fortnights since 2000-01-01", "days since yesterday"} {
+		if _, err := ParseTimeUnits(units); err == nil {
+			t.Errorf("ParseTimeUnits(%q) should fail", units)
+		}
+	}
+	if _, err := ParseCalendar("julian"); err == nil {
+		t.Error("ParseCalendar(julian) should fail")
+	}
+}
@@ -0,0 +1,187 @@
+package dataio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 坐标轴
+const (
+	AxisTime = "T"
+	AxisLat  = "Y"
+	AxisLon  = "X"
+	AxisZ    = "Z"
+)
+
+// axisNames 各坐标轴的常见变量名(小写)
+var axisNames = map[string][]string{
+	AxisTime: {"time", "t", "juld", "date_time", "datetime", "ocean_time"},
+	AxisLat:  {"lat", "latitude", "nav_lat", "y_lat", "lat_rho"},
+	AxisLon:  {"lon", "long", "longitude", "nav_lon", "x_lon", "lon_rho"},
+	AxisZ:    {"depth", "deptht", "lev", "level", "z", "pres", "pressure", "altitude", "height", "s_rho"},
+}
+
+// axisStandardNames 各坐标轴的CF标准名
+var axisStandardNames = map[string][]string{
+	AxisTime: {"time"},
+	AxisLat:  {"latitude", "grid_latitude"},
+	AxisLon:  {"longitude", "grid_longitude"},
+	AxisZ:    {"depth", "sea_water_pressure", "altitude", "height", "ocean_s_coordinate_g1", "ocean_s_coordinate_g2", "ocean_sigma_coordinate"},
+}
+
+// AxisOf 按CF约定识别变量代表的坐标轴，依次检查axis属性、standard_name、units和变量名，无法识别时返回空
+func AxisOf(v *Variable) string {
+	if v.IsText() {
+		return ""
+	}
+
+	if axis := strings.ToUpper(v.Attrs.String("axis")); axis == AxisTime || axis == AxisLat || axis == AxisLon || axis == AxisZ {
+		return axis
+	}
+
+	std := strings.ToLower(v.Attrs.String("standard_name"))
+	for axis, names := range axisStandardNames {
+		for _, n := range names {
+			if std == n {
+				return axis
+			}
+		}
+	}
+
+	units := strings.ToLower(v.Units())
+	switch {
+	case strings.Contains(units, " since "):
+		return AxisTime
+	case units == "degrees_north" || units == "degree_north" || units == "degree_n" || units == "degrees_n":
+		return AxisLat
+	case units == "degrees_east" || units == "degree_east" || units == "degree_e" || units == "degrees_e":
+		return AxisLon
+	case v.Attrs.String("positive") != "":
+		return AxisZ
+	}
+
+	name := strings.ToLower(v.Name)
+	for axis, names := range axisNames {
+		for _, n := range names {
+			if name == n {
+				return axis
+			}
+		}
+	}
+	return ""
+}
+
+// Coordinate 查找变量在指定坐标轴上的坐标变量: 坐标变量的维度须为目标变量维度的子集，
+// 优先选择与维度同名的一维坐标变量，其次选择维度最多的辅助坐标变量
+func (s *Source) Coordinate(v *Variable, axis string) *Variable {
+	var best *Variable
+	for _, c := range s.Vars {
+		if c == v || c.read == nil || AxisOf(c) != axis || !subset(c.Dims, v.Dims) {
+			continue
+		}
+		if len(c.Dims) == 1 && c.Dims[0] == c.Name {
+			return c
+		}
+		if best == nil || len(c.Dims) > len(best.Dims) {
+			best = c
+		}
+	}
+	return best
+}
+
+// AxisDim 变量在指定坐标轴上的维度下标，坐标变量为多维或不存在时返回-1
+func (s *Source) AxisDim(v *Variable, axis string) int {
+	c := s.Coordinate(v, axis)
+	if c == nil || len(c.Dims) != 1 {
+		return -1
+	}
+	for i, d := range v.Dims {
+		if d == c.Dims[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+// Calendar 变量时间坐标的模式日历，公历、没有时间坐标或日历无法识别时为nil
+func (s *Source) Calendar(v *Variable) *Calendar {
+	c := s.Coordinate(v, AxisTime)
+	if c == nil {
+		return nil
+	}
+	cal, err := ParseCalendar(c.Attrs.String("calendar"))
+	if err != nil {
+		return nil
+	}
+	return cal
+}
+
+// CoordinateValues 读取坐标值并广播到变量的每个元素，返回长度与变量元素数相同的数组
+func (s *Source) CoordinateValues(v *Variable, axis string) ([]float64, error) {
+	c := s.Coordinate(v, axis)
+	if c == nil {
+		return nil, fmt.Errorf("dataio: variable %s has no %s coordinate", v.Name, axis)
+	}
+	values, err := c.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return Broadcast(values, c.Dims, v.Dims, v.Shape), nil
+}
+
+// Broadcast 将定义在from维度上的数组展开到to维度上，from须为to的子集
+func Broadcast(values []float64, from []string, to []string, shape []int) []float64 {
+	n := 1
+	for _, s := range shape {
+		n *= s
+	}
+
+	// 每个目标维度在源数组中的步长，不在源数组中的维度步长为0
+	strides := make([]int, len(to))
+	stride := 1
+	for i := len(from) - 1; i >= 0; i-- {
+		for j, d := range to {
+			if d == from[i] {
+				strides[j] = stride
+				stride *= shape[j]
+			}
+		}
+	}
+
+	out := make([]float64, n)
+	idx := make([]int, len(shape))
+	for i := 0; i < n; i++ {
+		src := 0
+		for j := range idx {
+			src += idx[j] * strides[j]
+		}
+		if src < len(values) {
+			out[i] = values[src]
+		}
+		for j := len(idx) - 1; j >= 0; j-- {
+			idx[j]++
+			if idx[j] < shape[j] {
+				break
+			}
+			idx[j] = 0
+		}
+	}
+	return out
+}
+
+// subset 判断a中的维度是否均在b中
+func subset(a, b []string) bool {
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,609 @@
+package dataio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+// CSV相关常量
+const (
+	// csvSniffLines 识别分隔符时采样的行数
+	csvSniffLines = 20
+	// csvTimeUnits 时间列转换后的单位
+	csvTimeUnits = "seconds since 1970-01-01 00:00:00"
+	// sidecarSuffix 列式副本文件后缀
+	sidecarSuffix = ".columns.nc"
+)
+
+func init() {
+	Register("CSV", openCSV, []string{".csv", ".tsv", ".tab"})
+}
+
+// MaxTableSize 按表格解析的文件的最大字节数，解析时整个文件读入内存，超过时拒绝解析；不大于0表示不限制
+var MaxTableSize int64 = 256 << 20
+
+// csvDelimiters 候选分隔符，' '表示连续空白
+var csvDelimiters = []rune{',', '\t', ';', '|', ' '}
+
+// csvMissing 表示缺测的字符串(小写)
+var csvMissing = map[string]bool{
+	"": true, "na": true, "n/a": true, "nan": true, "null": true, "none": true, "-": true, "--": true, "missing": true,
+}
+
+// csvMissingValues 常见的缺测数值
+var csvMissingValues = []float64{-999, -9999, -99999, 99999}
+
+// csvTimeLayouts 时间列可接受的格式，未带时区时按UTC处理
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-1-2 15:04:05",
+	"2006-1-2 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02",
+	"2006-1-2",
+	"2006/01/02",
+	"2006/1/2",
+	"20060102150405",
+	"200601021504",
+	"20060102",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日 15:04",
+	"2006年1月2日",
+}
+
+// csvClockLayouts 仅含时刻的格式，与日期列合并使用
+var csvClockLayouts = []string{"15:04:05", "15:04"}
+
+// csvRoleNames 各坐标轴的常见列名(小写)
+var csvRoleNames = map[string][]string{
+	AxisTime: {"time", "date", "datetime", "date_time", "timestamp", "obs_time", "time_utc", "utc", "时间", "日期", "观测时间", "日期时间"},
+	AxisLat:  {"lat", "latitude", "lat_deg", "纬度"},
+	AxisLon:  {"lon", "lng", "long", "longitude", "lon_deg", "经度"},
+	AxisZ:    {"depth", "depth_m", "dep", "pres", "pressure", "深度"},
+}
+
+// headerUnits 匹配列名中的单位，如 temp(°C)、TEMP [degC]、温度（℃）
+var headerUnits = regexp.MustCompile(`^\s*(.*?)\s*[(\[（【]\s*(.*?)\s*[)\]）】]\s*$`)
+
+// SidecarPath 表格数据的列式副本路径，入库时生成，之后读取时替代原始文件
+func SidecarPath(path string) string {
+	return path + sidecarSuffix
+}
+
+// csvColumn 解析后的列
+type csvColumn struct {
+	header string // 原始列名
+	label  string // 去除单位后的列名
+	name   string // 变量名
+	units  string
+	role   string // 坐标轴，普通列为空
+	merged bool   // 已合并到时间列的时刻列
+	raw    []string
+	values []float64 // 数值列和时间列
+	text   bool
+}
+
+// openCSV 打开CSV/TSV文件: 存在不早于原文件的列式副本时直接读取副本，否则解析原文件
+func openCSV(path string) (*Source, error) {
+	if src, err := openSidecar(path); err == nil && src != nil {
+		return src, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if MaxTableSize > 0 && info.Size() > MaxTableSize {
+		return nil, fmt.Errorf("dataio: %s is too large to parse as a table (%d bytes, limit %d)", path, info.Size(), MaxTableSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text, encoding, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	delimiter := sniffDelimiter(text)
+	rows, err := readRows(text, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("dataio: %s has no data rows", path)
+	}
+
+	columns := buildColumns(rows[0], rows[1:])
+	src := buildTableSource(columns, len(rows)-1)
+	src.Attrs["encoding"] = encoding
+	src.Attrs["delimiter"] = delimiterName(delimiter)
+	return src, nil
+}
+
+// openSidecar 读取列式副本，副本不存在或早于原文件时返回nil
+func openSidecar(path string) (*Source, error) {
+	orig, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	side, err := os.Stat(SidecarPath(path))
+	if err != nil || side.ModTime().Before(orig.ModTime()) {
+		return nil, err
+	}
+	src, err := openNetCDF(SidecarPath(path))
+	if err != nil {
+		return nil, err
+	}
+	src.Format = "CSV"
+	return src, nil
+}
+
+// WriteSidecar 为表格数据生成列式副本，副本不早于原文件时不重复生成
+func WriteSidecar(src *Source, path string) error {
+	orig, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if side, err := os.Stat(SidecarPath(path)); err == nil && !side.ModTime().Before(orig.ModTime()) {
+		return nil
+	}
+	return WriteNetCDF(src, SidecarPath(path), nil)
+}
+
+// decodeText 识别编码并转换为UTF-8: 去除BOM，非法UTF-8按GBK解码
+func decodeText(data []byte) (string, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), "UTF-8", nil
+	}
+	decoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewDecoder(), data)
+	if err != nil {
+		return "", "", fmt.Errorf("dataio: unsupported text encoding: %w", err)
+	}
+	return string(decoded), "GBK", nil
+}
+
+// sniffDelimiter 按前若干行中各候选分隔符出现次数的一致性选择分隔符
+func sniffDelimiter(text string) rune {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() && len(lines) < csvSniffLines {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	best, bestScore := ',', 0
+	for _, d := range csvDelimiters {
+		counts := map[int]int{}
+		for _, line := range lines {
+			var n int
+			if d == ' ' {
+				n = len(strings.Fields(line)) - 1
+			} else {
+				n = strings.Count(line, string(d))
+			}
+			if n > 0 {
+				counts[n]++
+			}
+		}
+		// 得分为最常见的字段数出现的行数，字段数相同时优先排在前面的分隔符
+		for n, c := range counts {
+			if score := c*1000 + n; c > 0 && score > bestScore {
+				best, bestScore = d, score
+			}
+		}
+	}
+	return best
+}
+
+// delimiterName 分隔符名称
+func delimiterName(d rune) string {
+	switch d {
+	case '\t':
+		return "tab"
+	case ' ':
+		return "whitespace"
+	}
+	return string(d)
+}
+
+// readRows 读取全部行，跳过空行和以#开头的注释行
+func readRows(text string, delimiter rune) ([][]string, error) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, "\r"))
+	}
+
+	if delimiter == ' ' {
+		rows := make([][]string, len(lines))
+		for i, line := range lines {
+			rows[i] = strings.Fields(line)
+		}
+		return rows, nil
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("dataio: parse CSV: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// buildColumns 推断各列的名称、单位、类型和坐标轴
+func buildColumns(header []string, rows [][]string) []*csvColumn {
+	columns := make([]*csvColumn, len(header))
+	used := map[string]bool{}
+	for i, h := range header {
+		c := &csvColumn{header: strings.TrimSpace(h), raw: make([]string, len(rows))}
+		c.label = c.header
+		if m := headerUnits.FindStringSubmatch(c.header); m != nil {
+			c.label, c.units = m[1], m[2]
+		}
+		c.name = uniqueName(sanitizeName(c.label, i), used)
+		for j, row := range rows {
+			if i < len(row) {
+				c.raw[j] = strings.TrimSpace(row[i])
+			}
+		}
+		columns[i] = c
+	}
+
+	for _, c := range columns {
+		c.values, c.text = parseNumbers(c.raw)
+		c.role = columnRole(c)
+	}
+	mergeDateTime(columns)
+
+	// 时间列: 名称匹配或数值解析失败但能按时间解析的列
+	for _, c := range columns {
+		if c.role == AxisTime || (c.text && c.role == "") {
+			if times, ok := parseTimes(c.raw); ok {
+				c.values, c.text, c.role = times, false, AxisTime
+				c.units = csvTimeUnits
+			} else if c.role == AxisTime {
+				c.role = ""
+			}
+		}
+	}
+
+	// 只保留每个坐标轴的第一列
+	seen := map[string]bool{}
+	for _, c := range columns {
+		if c.role == "" {
+			continue
+		}
+		if seen[c.role] || (c.role != AxisTime && c.text) {
+			c.role = ""
+			continue
+		}
+		seen[c.role] = true
+	}
+	return columns
+}
+
+// sanitizeName 将列名转换为变量名: 保留字母、数字(含中文)，其余字符替换为下划线
+func sanitizeName(name string, index int) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	s := strings.Trim(b.String(), "_")
+	if s == "" {
+		return fmt.Sprintf("column_%d", index+1)
+	}
+	if unicode.IsDigit([]rune(s)[0]) {
+		s = "v_" + s
+	}
+	return s
+}
+
+// uniqueName 重名时追加序号
+func uniqueName(name string, used map[string]bool) string {
+	out := name
+	for i := 2; used[out]; i++ {
+		out = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[out] = true
+	return out
+}
+
+// parseNumbers 按数值解析列，存在无法解析的非缺测值时判定为文本列
+func parseNumbers(raw []string) ([]float64, bool) {
+	values := make([]float64, len(raw))
+	valid := 0
+	for i, s := range raw {
+		if csvMissing[strings.ToLower(s)] {
+			values[i] = math.NaN()
+			continue
+		}
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, true
+		}
+		for _, m := range csvMissingValues {
+			if x == m {
+				x = math.NaN()
+			}
+		}
+		values[i] = x
+		valid++
+	}
+	return values, valid == 0
+}
+
+// columnRole 按列名识别坐标轴
+func columnRole(c *csvColumn) string {
+	name := strings.ToLower(c.label)
+	for _, axis := range []string{AxisTime, AxisLat, AxisLon, AxisZ} {
+		for _, n := range csvRoleNames[axis] {
+			if name == n {
+				return axis
+			}
+		}
+	}
+	return ""
+}
+
+// mergeDateTime 日期列与时刻列分开存放时合并为一个时间列
+func mergeDateTime(columns []*csvColumn) {
+	var date, clock *csvColumn
+	for _, c := range columns {
+		if !c.text || len(c.raw) == 0 {
+			continue
+		}
+		sample := firstValue(c.raw)
+		if clock == nil && matchesLayout(sample, csvClockLayouts) {
+			clock = c
+		} else if date == nil && matchesLayout(sample, []string{"2006-01-02", "2006-1-2", "2006/01/02", "2006/1/2"}) {
+			date = c
+		}
+	}
+	if date == nil || clock == nil {
+		return
+	}
+
+	for i := range date.raw {
+		if date.raw[i] != "" && clock.raw[i] != "" {
+			date.raw[i] = date.raw[i] + " " + clock.raw[i]
+		}
+	}
+	date.role = AxisTime
+	clock.role = ""
+	clock.merged = true
+}
+
+// firstValue 第一个非空值
+func firstValue(raw []string) string {
+	for _, s := range raw {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// matchesLayout 是否符合任一时间格式
+func matchesLayout(s string, layouts []string) bool {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTimes 按时间解析列，转换为自1970年起的秒数；使用第一个可解析的格式，所有非空值都须可解析
+func parseTimes(raw []string) ([]float64, bool) {
+	sample := firstValue(raw)
+	if sample == "" {
+		return nil, false
+	}
+	layout := ""
+	for _, l := range csvTimeLayouts {
+		if _, err := time.Parse(l, sample); err == nil {
+			layout = l
+			break
+		}
+	}
+	if layout == "" {
+		return nil, false
+	}
+
+	values := make([]float64, len(raw))
+	for i, s := range raw {
+		if csvMissing[strings.ToLower(s)] {
+			values[i] = math.NaN()
+			continue
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = float64(t.Unix())
+	}
+	return values, true
+}
+
+// buildTableSource 由列构建数据源: 有时间列时以时间为维度并按时间排序，否则以行号为维度
+func buildTableSource(columns []*csvColumn, n int) *Source {
+	dim := "row"
+	var timeCol *csvColumn
+	for _, c := range columns {
+		if c.role == AxisTime {
+			timeCol = c
+			dim = "time"
+		}
+	}
+	if timeCol != nil {
+		// 时间坐标变量与维度同名，其他同名列改名
+		for _, c := range columns {
+			if c != timeCol && c.name == "time" {
+				c.name = "time_" + strconv.Itoa(len(columns))
+			}
+		}
+		timeCol.name = "time"
+	}
+
+	// 按时间排序，缺测时间排在最后
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if timeCol != nil {
+		t := timeCol.values
+		sort.SliceStable(order, func(a, b int) bool {
+			ta, tb := t[order[a]], t[order[b]]
+			if math.IsNaN(tb) {
+				return !math.IsNaN(ta)
+			}
+			return ta < tb
+		})
+	}
+
+	var coords []string
+	for _, c := range columns {
+		if c.role != "" && c.role != AxisTime {
+			coords = append(coords, c.name)
+		}
+	}
+	if timeCol != nil {
+		coords = append([]string{"time"}, coords...)
+	}
+
+	dims := []Dimension{{Name: dim, Len: n}}
+	vars := make([]*Variable, 0, len(columns))
+	for _, c := range columns {
+		if c.merged {
+			continue
+		}
+		attrs := Attributes{"long_name": c.label}
+		if c.units != "" {
+			attrs["units"] = c.units
+		}
+
+		if c.text {
+			width := 1
+			for _, s := range c.raw {
+				if len(s) > width {
+					width = len(s)
+				}
+			}
+			strDim := c.name + "_strlen"
+			dims = append(dims, Dimension{Name: strDim, Len: width})
+			vars = append(vars, NewTextVariable(c.name, []string{dim, strDim}, []int{n, width}, attrs, textColumn(c.raw, order, width)))
+			continue
+		}
+
+		switch c.role {
+		case AxisTime:
+			attrs["standard_name"], attrs["axis"] = "time", AxisTime
+		case AxisLat:
+			attrs["standard_name"], attrs["units"] = "latitude", "degrees_north"
+		case AxisLon:
+			attrs["standard_name"], attrs["units"] = "longitude", "degrees_east"
+		case AxisZ:
+			attrs["axis"] = AxisZ
+			if _, ok := attrs["units"]; !ok {
+				attrs["units"] = "m"
+			}
+			if strings.Contains(strings.ToLower(c.header), "pres") {
+				attrs["standard_name"] = "sea_water_pressure"
+			} else {
+				attrs["standard_name"], attrs["positive"] = "depth", "down"
+			}
+		default:
+			if len(coords) > 0 {
+				attrs["coordinates"] = strings.Join(coords, " ")
+			}
+		}
+
+		values := make([]float64, n)
+		for i, idx := range order {
+			values[i] = c.values[idx]
+		}
+		vars = append(vars, NewVariable(c.name, []string{dim}, []int{n}, "double", attrs, memoryColumn(values)))
+	}
+
+	attrs := Attributes{}
+	if timeCol != nil {
+		attrs["featureType"] = "timeSeries"
+	} else {
+		attrs["featureType"] = "point"
+	}
+	return NewSource("CSV", dims, vars, attrs, nil)
+}
+
+// memoryColumn 读取内存中的一维数值列
+func memoryColumn(values []float64) ReadFunc {
+	return func(start, count []int) ([]float64, error) {
+		if len(start) != 1 || start[0] < 0 || start[0]+count[0] > len(values) {
+			return nil, fmt.Errorf("dataio: invalid slice")
+		}
+		return append([]float64(nil), values[start[0]:start[0]+count[0]]...), nil
+	}
+}
+
+// textColumn 读取内存中的文本列，每个字符串按width补齐
+func textColumn(raw []string, order []int, width int) TextFunc {
+	return func(start, count []int) ([]byte, error) {
+		if len(start) != 2 || start[0] < 0 || start[0]+count[0] > len(order) || start[1]+count[1] > width {
+			return nil, fmt.Errorf("dataio: invalid slice")
+		}
+		out := make([]byte, 0, count[0]*count[1])
+		for i := start[0]; i < start[0]+count[0]; i++ {
+			padded := make([]byte, width)
+			copy(padded, raw[order[i]])
+			out = append(out, padded[start[1]:start[1]+count[1]]...)
+		}
+		return out, nil
+	}
+}
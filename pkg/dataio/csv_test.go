@@ -0,0 +1,139 @@
+package dataio
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTemp 将内容写入临时目录下的文件
+func writeTemp(t testing.TB, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenCSV(t *testing.T) {
+	unix := func(s string) float64 {
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return float64(tm.Unix())
+	}
+	tests := []struct {
+		name      string
+		data      string
+		delimiter string
+		encoding  string
+		dim       string
+		vars      map[string][]float64
+		units     map[string]string
+	}{
+		{"comma with units", "# 站点观测\ntime,lat,lon,temp(°C)\n2020-01-02 00:00,30.5,120,15.2\n2020-01-01 00:00,30.5,120,-999\n",
+			",", "UTF-8", "time",
+			map[string][]float64{
+				"time": {unix("2020-01-01 00:00"), unix("2020-01-02 00:00")},
+				"lat":  {30.5, 30.5},
+				"temp": {math.NaN(), 15.2},
+			},
+			map[string]string{"temp": "°C", "lat": "degrees_north", "time": csvTimeUnits}},
+		{"separate date and clock", "date\tclock\tdepth [m]\n2020/1/1\t06:00\t5\n2020/1/1\t18:30\tNA\n",
+			"tab", "UTF-8", "time",
+			map[string][]float64{
+				"time":  {unix("2020-01-01 06:00"), unix("2020-01-01 18:30")},
+				"depth": {5, math.NaN()},
+			},
+			map[string]string{"depth": "m"}},
+		{"whitespace without time", "x y 1st\n1 2 3\n4  5 6\n",
+			"whitespace", "UTF-8", "row",
+			map[string][]float64{"x": {1, 4}, "y": {2, 5}, "v_1st": {3, 6}},
+			nil},
+		// GBK编码的"温度;盐度"
+		{"gbk", "\xce\xc2\xb6\xc8;\xd1\xce\xb6\xc8\n1;2\n3;4\n",
+			";", "GBK", "row",
+			map[string][]float64{"温度": {1, 3}, "盐度": {2, 4}},
+			nil},
+	}
+	for _, tt := range tests {
+		src, err := openCSV(writeTemp(t, "data.csv", []byte(tt.data)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if src.Attrs.String("delimiter") != tt.delimiter || src.Attrs.String("encoding") != tt.encoding {
+			t.Errorf("%s: delimiter %q, encoding %q", tt.name, src.Attrs.String("delimiter"), src.Attrs.String("encoding"))
+		}
+		if _, ok := src.Dim(tt.dim); !ok {
+			t.Errorf("%s: missing dimension %s in %v", tt.name, tt.dim, src.Dims)
+		}
+		for name, want := range tt.vars {
+			v := src.Var(name)
+			if v == nil {
+				t.Errorf("%s: missing variable %s", tt.name, name)
+				continue
+			}
+			got, err := v.ReadAll()
+			if err != nil || !equalFloats(got, want) {
+				t.Errorf("%s: %s = %v (%v), want %v", tt.name, name, got, err, want)
+			}
+		}
+		for name, want := range tt.units {
+			if v := src.Var(name); v == nil || v.Units() != want {
+				t.Errorf("%s: units of %s = %v, want %q", tt.name, name, v, want)
+			}
+		}
+	}
+
+	if _, err := openCSV(writeTemp(t, "empty.csv", []byte("a,b\n"))); err == nil {
+		t.Error("openCSV without data rows should fail")
+	}
+}
+
+func TestOpenCSVText(t *testing.T) {
+	src, err := openCSV(writeTemp(t, "station.csv", []byte("station,value\nA1,1\nB22,2\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := src.Var("station")
+	if v == nil || !v.IsText() {
+		t.Fatalf("station = %+v, want text variable", v)
+	}
+	got, err := v.ReadText(nil, nil)
+	if err != nil || len(got) != 2 || got[0] != "A1" || got[1] != "B22" {
+		t.Errorf("ReadText = %q (%v)", got, err)
+	}
+}
+
+func FuzzOpenCSV(f *testing.F) {
+	f.Add([]byte("time,lat,lon,temp\n2020-01-01,30,120,15\n"))
+	f.Add([]byte("date;clock;v\n2020/1/1;06:00;NA\n2020/1/2;07:00;1e3\n"))
+	f.Add([]byte("a b\n\"1 2\n"))
+	f.Add([]byte("\xef\xbb\xbfx\ty\n\xff\xfe\t1\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		src, err := openCSV(writeTemp(t, "fuzz.csv", data))
+		if err != nil {
+			return
+		}
+		for _, v := range src.Vars {
+			if v.IsText() {
+				if _, err := v.ReadText(nil, nil); err != nil {
+					t.Fatalf("ReadText(%s): %v", v.Name, err)
+				}
+				continue
+			}
+			values, err := v.ReadAll()
+			if err != nil {
+				t.Fatalf("ReadAll(%s): %v", v.Name, err)
+			}
+			if len(values) != v.Size() {
+				t.Fatalf("%s: %d values, size %d", v.Name, len(values), v.Size())
+			}
+		}
+	})
+}
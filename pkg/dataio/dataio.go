@@ -0,0 +1,507 @@
+// Package dataio 提供与文件格式无关的数据集读取抽象，各格式通过Register注册打开函数
+package dataio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedFormat 不支持的数据格式
+var ErrUnsupportedFormat = errors.New("dataio: unsupported format")
+
+// DefaultChunkSize 分块读取时每块的最大元素数
+const DefaultChunkSize = 1 << 20
+
+// defaultFloatFill NetCDF浮点型的默认填充值
+const defaultFloatFill = 9.969209968386869e36
+
+// Opener 打开指定路径的数据源
+type Opener func(path string) (*Source, error)
+
+// format 已注册的格式
+type format struct {
+	name       string
+	opener     Opener
+	extensions []string
+	magic      []string
+}
+
+var (
+	mu      sync.RWMutex
+	formats = map[string]*format{}
+)
+
+// Register 注册数据格式，extensions为小写文件扩展名(含点)，magic为文件头特征字节
+func Register(name string, opener Opener, extensions []string, magic ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	formats[strings.ToLower(name)] = &format{name: name, opener: opener, extensions: extensions, magic: magic}
+}
+
+// Formats 已注册的格式名称
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for _, f := range formats {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open 打开数据源，declared为数据集声明的格式(可为空)，无法匹配时依次按扩展名和文件头识别
+func Open(path, declared string) (*Source, error) {
+	f := detect(path, declared)
+	if f == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, filepath.Base(path))
+	}
+	src, err := f.opener(path)
+	if err != nil {
+		return nil, err
+	}
+	if src.Format == "" {
+		src.Format = f.name
+	}
+	return src, nil
+}
+
+// detect 识别文件格式
+func detect(path, declared string) *format {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if f, ok := formats[strings.ToLower(declared)]; ok {
+		return f
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range formats {
+		for _, e := range f.extensions {
+			if e == ext {
+				return f
+			}
+		}
+	}
+
+	head := readHead(path, 8)
+	for _, f := range formats {
+		for _, m := range f.magic {
+			if m != "" && strings.HasPrefix(head, m) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Dimension 维度
+type Dimension struct {
+	Name string `json:"name"`
+	Len  int    `json:"len"`
+}
+
+// Attributes 属性集合，值为string或[]float64
+type Attributes map[string]interface{}
+
+// String 字符串属性
+func (a Attributes) String(key string) string {
+	if s, ok := a[key].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// Floats 数值属性
+func (a Attributes) Floats(key string) []float64 {
+	switch v := a[key].(type) {
+	case []float64:
+		return v
+	case float64:
+		return []float64{v}
+	}
+	return nil
+}
+
+// Float 数值属性的第一个值
+func (a Attributes) Float(key string) (float64, bool) {
+	v := a.Floats(key)
+	if len(v) == 0 {
+		return 0, false
+	}
+	return v[0], true
+}
+
+// ReadFunc 读取变量的超立方体切片
+type ReadFunc func(start, count []int) ([]float64, error)
+
+// TextFunc 读取字符变量的切片，最后一维为字符串长度
+type TextFunc func(start, count []int) ([]byte, error)
+
+// FilterFunc 对读取到的物理值做后处理(如质量控制掩码、单位换算)，可原地修改values
+type FilterFunc func(start, count []int, values []float64) error
+
+// Variable 数据变量
+type Variable struct {
+	Name  string     `json:"name"`
+	Dims  []string   `json:"dims"`
+	Shape []int      `json:"shape"`
+	Type  string     `json:"type"`
+	Attrs Attributes `json:"attributes"`
+
+	read    ReadFunc
+	text    TextFunc
+	filters []FilterFunc
+}
+
+// NewVariable 创建数值变量
+func NewVariable(name string, dims []string, shape []int, typ string, attrs Attributes, read ReadFunc) *Variable {
+	if attrs == nil {
+		attrs = Attributes{}
+	}
+	return &Variable{Name: name, Dims: dims, Shape: shape, Type: typ, Attrs: attrs, read: read}
+}
+
+// NewTextVariable 创建字符变量
+func NewTextVariable(name string, dims []string, shape []int, attrs Attributes, text TextFunc) *Variable {
+	if attrs == nil {
+		attrs = Attributes{}
+	}
+	return &Variable{Name: name, Dims: dims, Shape: shape, Type: "char", Attrs: attrs, text: text}
+}
+
+// MemoryArray 读取内存中按行优先存放、形状为shape的多维数组
+func MemoryArray(values []float64, shape []int) ReadFunc {
+	return func(start, count []int) ([]float64, error) {
+		if len(start) != len(shape) || len(count) != len(shape) {
+			return nil, fmt.Errorf("dataio: invalid slice")
+		}
+		n := 1
+		for i := range shape {
+			if start[i] < 0 || count[i] < 0 || start[i]+count[i] > shape[i] {
+				return nil, fmt.Errorf("dataio: invalid slice")
+			}
+			n *= count[i]
+		}
+		out := make([]float64, 0, n)
+		if n == 0 {
+			return out, nil
+		}
+		idx := make([]int, len(shape))
+		for {
+			off := 0
+			for i := range shape {
+				off = off*shape[i] + start[i] + idx[i]
+			}
+			out = append(out, values[off])
+			i := len(shape) - 1
+			for ; i >= 0; i-- {
+				if idx[i]++; idx[i] < count[i] {
+					break
+				}
+				idx[i] = 0
+			}
+			if i < 0 {
+				return out, nil
+			}
+		}
+	}
+}
+
+// Size 元素总数，超出int范围时返回math.MaxInt
+func (v *Variable) Size() int {
+	for _, s := range v.Shape {
+		if s == 0 {
+			return 0
+		}
+	}
+	n := 1
+	for _, s := range v.Shape {
+		if s < 0 || n > math.MaxInt/s {
+			return math.MaxInt
+		}
+		n *= s
+	}
+	return n
+}
+
+// IsText 是否为字符变量
+func (v *Variable) IsText() bool {
+	return v.text != nil
+}
+
+// Units 单位
+func (v *Variable) Units() string {
+	return v.Attrs.String("units")
+}
+
+// Description 变量描述，依次取long_name、standard_name
+func (v *Variable) Description() string {
+	if s := v.Attrs.String("long_name"); s != "" {
+		return s
+	}
+	return v.Attrs.String("standard_name")
+}
+
+// ReadRaw 读取原始值，不做缺测与缩放处理
+func (v *Variable) ReadRaw(start, count []int) ([]float64, error) {
+	if v.read == nil {
+		return nil, fmt.Errorf("dataio: variable %s is not numeric", v.Name)
+	}
+	start, count = fullSlice(v.Shape, start, count)
+	return v.read(start, count)
+}
+
+// Read 读取物理值: 缺测值、填充值及有效范围外的值置为NaN，并应用scale_factor/add_offset和过滤器
+func (v *Variable) Read(start, count []int) ([]float64, error) {
+	start, count = fullSlice(v.Shape, start, count)
+	values, err := v.ReadRaw(start, count)
+	if err != nil {
+		return nil, err
+	}
+	v.unpack(values)
+	for _, f := range v.filters {
+		if err := f(start, count, values); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// WithFilter 返回附加了过滤器的变量副本，原变量不受影响
+func (v *Variable) WithFilter(f FilterFunc) *Variable {
+	c := *v
+	c.filters = append(append([]FilterFunc(nil), v.filters...), f)
+	return &c
+}
+
+// ReadAll 读取全部物理值
+func (v *Variable) ReadAll() ([]float64, error) {
+	return v.Read(nil, nil)
+}
+
+// ReadText 读取字符变量，按最后一维切分为字符串并去除空白
+func (v *Variable) ReadText(start, count []int) ([]string, error) {
+	if v.text == nil {
+		return nil, fmt.Errorf("dataio: variable %s is not a text variable", v.Name)
+	}
+	start, count = fullSlice(v.Shape, start, count)
+	raw, err := v.text(start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	width := 1
+	if len(count) > 0 {
+		width = count[len(count)-1]
+	}
+	if width == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(raw)/width)
+	for i := 0; i+width <= len(raw); i += width {
+		out = append(out, strings.TrimRight(strings.TrimSpace(string(raw[i:i+width])), "\x00"))
+	}
+	return out, nil
+}
+
+// ReadChars 读取字符变量的原始字节，不切分也不去除空白，用于逐字符存放的标志
+func (v *Variable) ReadChars(start, count []int) ([]byte, error) {
+	if v.text == nil {
+		return nil, fmt.Errorf("dataio: variable %s is not a text variable", v.Name)
+	}
+	start, count = fullSlice(v.Shape, start, count)
+	return v.text(start, count)
+}
+
+// Each 沿第一维分块读取物理值，fn接收块在展开数组中的起始位置和数据
+func (v *Variable) Each(chunkSize int, fn func(offset int, values []float64) error) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if len(v.Shape) == 0 {
+		values, err := v.Read(nil, nil)
+		if err != nil {
+			return err
+		}
+		return fn(0, values)
+	}
+
+	inner := 1
+	for _, s := range v.Shape[1:] {
+		inner *= s
+	}
+	if inner == 0 {
+		return nil
+	}
+	rows := chunkSize / inner
+	if rows < 1 {
+		rows = 1
+	}
+
+	start := make([]int, len(v.Shape))
+	count := append([]int(nil), v.Shape...)
+	for i := 0; i < v.Shape[0]; i += rows {
+		start[0] = i
+		count[0] = rows
+		if i+rows > v.Shape[0] {
+			count[0] = v.Shape[0] - i
+		}
+		values, err := v.Read(start, count)
+		if err != nil {
+			return err
+		}
+		if err := fn(i*inner, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpack 按CF约定处理缺测与缩放
+func (v *Variable) unpack(values []float64) {
+	var missing []float64
+	missing = append(missing, v.Attrs.Floats("_FillValue")...)
+	missing = append(missing, v.Attrs.Floats("missing_value")...)
+	if _, ok := v.Attrs["_FillValue"]; !ok && (v.Type == "float" || v.Type == "double") {
+		missing = append(missing, defaultFloatFill)
+	}
+
+	validMin, validMax := math.Inf(-1), math.Inf(1)
+	if r := v.Attrs.Floats("valid_range"); len(r) == 2 {
+		validMin, validMax = r[0], r[1]
+	}
+	if m, ok := v.Attrs.Float("valid_min"); ok {
+		validMin = m
+	}
+	if m, ok := v.Attrs.Float("valid_max"); ok {
+		validMax = m
+	}
+
+	scale, hasScale := v.Attrs.Float("scale_factor")
+	offset, hasOffset := v.Attrs.Float("add_offset")
+	if !hasScale {
+		scale = 1
+	}
+	if !hasOffset {
+		offset = 0
+	}
+
+	for i, x := range values {
+		if math.IsNaN(x) || x < validMin || x > validMax {
+			values[i] = math.NaN()
+			continue
+		}
+		for _, m := range missing {
+			if x == m || (math.Abs(m) >= 1e30 && math.Abs(x-m) <= math.Abs(m)*1e-6) {
+				x = math.NaN()
+				break
+			}
+		}
+		if !math.IsNaN(x) && (hasScale || hasOffset) {
+			x = x*scale + offset
+		}
+		values[i] = x
+	}
+}
+
+// Source 已打开的数据源
+type Source struct {
+	Format string      `json:"format"`
+	Dims   []Dimension `json:"dimensions"`
+	Vars   []*Variable `json:"variables"`
+	Attrs  Attributes  `json:"attributes"`
+	closer io.Closer
+}
+
+// NewSource 创建数据源，closer可为nil
+func NewSource(format string, dims []Dimension, vars []*Variable, attrs Attributes, closer io.Closer) *Source {
+	if attrs == nil {
+		attrs = Attributes{}
+	}
+	return &Source{Format: format, Dims: dims, Vars: vars, Attrs: attrs, closer: closer}
+}
+
+// Replace 替换同名变量(如附加过滤器后的副本)
+func (s *Source) Replace(v *Variable) {
+	for i, old := range s.Vars {
+		if old.Name == v.Name {
+			s.Vars[i] = v
+			return
+		}
+	}
+}
+
+// Var 按名称查找变量
+func (s *Source) Var(name string) *Variable {
+	for _, v := range s.Vars {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Dim 按名称查找维度长度
+func (s *Source) Dim(name string) (int, bool) {
+	for _, d := range s.Dims {
+		if d.Name == name {
+			return d.Len, true
+		}
+	}
+	return 0, false
+}
+
+// DataVariables 数据变量，不含坐标变量(与维度同名的一维变量)、其他变量coordinates属性引用的辅助坐标变量和字符变量
+func (s *Source) DataVariables() []*Variable {
+	var out []*Variable
+	for _, v := range s.Vars {
+		if v.IsText() || (len(v.Dims) == 1 && v.Dims[0] == v.Name) || referenced(s.Vars, v.Name) {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// Close 关闭数据源
+func (s *Source) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// fullSlice 补全start/count，nil表示整个维度
+func fullSlice(shape, start, count []int) ([]int, []int) {
+	if start == nil {
+		start = make([]int, len(shape))
+	}
+	if count == nil {
+		count = make([]int, len(shape))
+		for i := range shape {
+			count[i] = shape[i] - start[i]
+		}
+	}
+	return start, count
+}
+
+// readHead 读取文件开头的若干字节
+func readHead(path string, n int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	m, _ := f.Read(buf)
+	return string(buf[:m])
+}
@@ -0,0 +1,128 @@
+package dataio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMemoryArray(t *testing.T) {
+	// 2x3x4数组，值为行优先下标
+	values := make([]float64, 24)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	read := MemoryArray(values, []int{2, 3, 4})
+	tests := []struct {
+		start, count []int
+		want         []float64
+	}{
+		{[]int{0, 0, 0}, []int{1, 1, 4}, []float64{0, 1, 2, 3}},
+		{[]int{1, 2, 3}, []int{1, 1, 1}, []float64{23}},
+		{[]int{0, 1, 1}, []int{2, 2, 2}, []float64{5, 6, 9, 10, 17, 18, 21, 22}},
+		{[]int{0, 0, 2}, []int{2, 3, 1}, []float64{2, 6, 10, 14, 18, 22}},
+		{[]int{1, 0, 0}, []int{0, 3, 4}, []float64{}},
+	}
+	for _, tt := range tests {
+		got, err := read(tt.start, tt.count)
+		if err != nil {
+			t.Errorf("read(%v, %v): %v", tt.start, tt.count, err)
+			continue
+		}
+		if !equalFloats(got, tt.want) {
+			t.Errorf("read(%v, %v) = %v, want %v", tt.start, tt.count, got, tt.want)
+		}
+	}
+	for _, s := range [][2][]int{
+		{{0, 0, 0}, {3, 1, 1}},
+		{{1, 2, 3}, {1, 1, 2}},
+		{{-1, 0, 0}, {1, 1, 1}},
+		{{0, 0}, {1, 1}},
+	} {
+		if _, err := read(s[0], s[1]); err == nil {
+			t.Errorf("read(%v, %v) should fail", s[0], s[1])
+		}
+	}
+}
+
+func TestVariableSize(t *testing.T) {
+	tests := []struct {
+		shape []int
+		want  int
+	}{
+		{nil, 1},
+		{[]int{3, 4}, 12},
+		{[]int{1 << 40, 0}, 0},
+		{[]int{1 << 40, 1 << 40}, math.MaxInt},
+	}
+	for _, tt := range tests {
+		v := NewVariable("v", nil, tt.shape, "double", nil, nil)
+		if got := v.Size(); got != tt.want {
+			t.Errorf("Size(%v) = %d, want %d", tt.shape, got, tt.want)
+		}
+	}
+}
+
+func TestVariableRead(t *testing.T) {
+	raw := []float64{-32767, 0, 100, 200, 30000, math.NaN(), 9.96921e36}
+	tests := []struct {
+		name  string
+		typ   string
+		attrs Attributes
+		want  []float64
+	}{
+		{"packed", "short", Attributes{"_FillValue": -32767.0, "scale_factor": 0.01, "add_offset": 20.0},
+			[]float64{math.NaN(), 20, 21, 22, 320, math.NaN(), 9.96921e34 + 20}},
+		{"valid range", "short", Attributes{"valid_range": []float64{0, 1000}},
+			[]float64{math.NaN(), 0, 100, 200, math.NaN(), math.NaN(), math.NaN()}},
+		{"valid min", "short", Attributes{"valid_min": 100.0, "missing_value": 200.0},
+			[]float64{math.NaN(), math.NaN(), 100, math.NaN(), 30000, math.NaN(), 9.96921e36}},
+		// 浮点变量未声明_FillValue时使用netCDF默认填充值
+		{"default fill", "float", nil,
+			[]float64{-32767, 0, 100, 200, 30000, math.NaN(), math.NaN()}},
+	}
+	for _, tt := range tests {
+		v := NewVariable("v", []string{"n"}, []int{len(raw)}, tt.typ, tt.attrs, MemoryArray(raw, []int{len(raw)}))
+		got, err := v.ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalFloats(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	tests := []struct {
+		values   []float64
+		from, to []string
+		shape    []int
+		want     []float64
+	}{
+		{[]float64{1, 2}, []string{"lat"}, []string{"lat", "lon"}, []int{2, 3}, []float64{1, 1, 1, 2, 2, 2}},
+		{[]float64{1, 2, 3}, []string{"lon"}, []string{"lat", "lon"}, []int{2, 3}, []float64{1, 2, 3, 1, 2, 3}},
+		{[]float64{1, 2, 3, 4}, []string{"t", "lat"}, []string{"t", "z", "lat"}, []int{2, 2, 2},
+			[]float64{1, 2, 1, 2, 3, 4, 3, 4}},
+		{[]float64{7}, nil, []string{"n"}, []int{3}, []float64{7, 7, 7}},
+	}
+	for _, tt := range tests {
+		if got := Broadcast(tt.values, tt.from, tt.to, tt.shape); !equalFloats(got, tt.want) {
+			t.Errorf("Broadcast(%v, %v -> %v) = %v, want %v", tt.values, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// equalFloats 逐元素比较，NaN与NaN视为相等
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] && !(math.IsNaN(a[i]) && math.IsNaN(b[i])) {
+			if math.Abs(a[i]-b[i]) > 1e-9*math.Max(1, math.Abs(b[i])) {
+				return false
+			}
+		}
+	}
+	return true
+}
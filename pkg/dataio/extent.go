@@ -0,0 +1,95 @@
+package dataio
+
+import (
+	"math"
+	"time"
+)
+
+// Extent 数据覆盖的时空范围
+type Extent struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+	HasBounds                      bool
+	Start, End                     time.Time // 无时间坐标时为零值
+	// LatStep、LonStep 规则网格的格距(度)，非规则网格或站点数据时为0
+	LatStep, LonStep float64
+}
+
+// Extent 根据数据变量的经纬度和时间坐标计算覆盖范围
+func (s *Source) Extent() *Extent {
+	ext := &Extent{
+		MinLat: math.Inf(1), MinLon: math.Inf(1),
+		MaxLat: math.Inf(-1), MaxLon: math.Inf(-1),
+	}
+
+	done := map[*Variable]bool{}
+	for _, v := range s.DataVariables() {
+		for _, axis := range []string{AxisLat, AxisLon, AxisTime} {
+			c := s.Coordinate(v, axis)
+			if c == nil || done[c] {
+				continue
+			}
+			done[c] = true
+
+			if axis == AxisTime {
+				times, err := Times(c)
+				if err != nil {
+					continue
+				}
+				for _, t := range times {
+					if t.IsZero() {
+						continue
+					}
+					if ext.Start.IsZero() || t.Before(ext.Start) {
+						ext.Start = t
+					}
+					if ext.End.IsZero() || t.After(ext.End) {
+						ext.End = t
+					}
+				}
+				continue
+			}
+
+			values, err := c.ReadAll()
+			if err != nil {
+				continue
+			}
+			lo, hi := &ext.MinLat, &ext.MaxLat
+			step := &ext.LatStep
+			if axis == AxisLon {
+				lo, hi, step = &ext.MinLon, &ext.MaxLon, &ext.LonStep
+			}
+			for _, x := range values {
+				if !math.IsNaN(x) {
+					*lo, *hi = math.Min(*lo, x), math.Max(*hi, x)
+				}
+			}
+			if len(c.Dims) == 1 && c.Dims[0] == c.Name {
+				*step = uniformStep(values)
+			}
+		}
+	}
+
+	ext.HasBounds = !math.IsInf(ext.MinLat, 0) && !math.IsInf(ext.MinLon, 0)
+	if !ext.HasBounds {
+		ext.MinLat, ext.MinLon, ext.MaxLat, ext.MaxLon = 0, 0, 0, 0
+		ext.LatStep, ext.LonStep = 0, 0
+	}
+	return ext
+}
+
+// uniformStep 等间距坐标的间距绝对值，不等间距时返回0
+func uniformStep(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	step := (values[len(values)-1] - values[0]) / float64(len(values)-1)
+	if step == 0 || math.IsNaN(step) {
+		return 0
+	}
+	for i := 1; i < len(values); i++ {
+		if math.Abs(values[i]-values[i-1]-step) > math.Abs(step)*1e-3 {
+			return 0
+		}
+	}
+	return math.Abs(step)
+}
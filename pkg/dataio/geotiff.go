@@ -0,0 +1,189 @@
+package dataio
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/sinker/ssop/pkg/geotiff"
+)
+
+func init() {
+	Register("GeoTIFF", openGeoTIFF, []string{".tif", ".tiff", ".gtiff"}, "II*\x00", "MM\x00*", "II+\x00", "MM\x00+")
+}
+
+// earthRadius Web墨卡托投影使用的地球半径(米)
+const earthRadius = 6378137.0
+
+// openGeoTIFF 打开GeoTIFF: 每个波段对应一个二维变量；经纬度和Web墨卡托坐标系生成一维lat/lon坐标变量，
+// 含旋转项时生成二维经纬度辅助坐标，其他投影坐标系生成x/y投影坐标变量
+func openGeoTIFF(path string) (*Source, error) {
+	f, err := geotiff.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	geographic := f.Georeferenced && (f.Geographic() || f.WebMercator())
+	ydim, xdim := "y", "x"
+	if geographic && !f.Rotated() {
+		ydim, xdim = "lat", "lon"
+	}
+	dims := []Dimension{{Name: ydim, Len: f.Height}, {Name: xdim, Len: f.Width}}
+
+	var vars []*Variable
+	var coords string
+	switch {
+	case !f.Georeferenced:
+	case geographic && !f.Rotated():
+		lats, lons := make([]float64, f.Height), make([]float64, f.Width)
+		for i := range lats {
+			_, y := f.PixelCenter(i, 0)
+			lats[i] = y
+		}
+		for j := range lons {
+			x, _ := f.PixelCenter(0, j)
+			lons[j] = x
+		}
+		if f.WebMercator() {
+			for i := range lats {
+				_, lats[i] = inverseMercator(0, lats[i])
+			}
+			for j := range lons {
+				lons[j], _ = inverseMercator(lons[j], 0)
+			}
+		}
+		vars = append(vars,
+			NewVariable("lat", []string{ydim}, []int{f.Height}, "double",
+				Attributes{"standard_name": "latitude", "units": "degrees_north", "axis": AxisLat}, memoryColumn(lats)),
+			NewVariable("lon", []string{xdim}, []int{f.Width}, "double",
+				Attributes{"standard_name": "longitude", "units": "degrees_east", "axis": AxisLon}, memoryColumn(lons)))
+	case geographic:
+		vars = append(vars,
+			NewVariable("lat", []string{ydim, xdim}, []int{f.Height, f.Width}, "double",
+				Attributes{"standard_name": "latitude", "units": "degrees_north"}, pixelCoordinate(f, false)),
+			NewVariable("lon", []string{ydim, xdim}, []int{f.Height, f.Width}, "double",
+				Attributes{"standard_name": "longitude", "units": "degrees_east"}, pixelCoordinate(f, true)))
+		coords = "lat lon"
+	case !f.Rotated():
+		ys, xs := make([]float64, f.Height), make([]float64, f.Width)
+		for i := range ys {
+			_, ys[i] = f.PixelCenter(i, 0)
+		}
+		for j := range xs {
+			xs[j], _ = f.PixelCenter(0, j)
+		}
+		vars = append(vars,
+			NewVariable("y", []string{ydim}, []int{f.Height}, "double",
+				Attributes{"standard_name": "projection_y_coordinate", "units": "m"}, memoryColumn(ys)),
+			NewVariable("x", []string{xdim}, []int{f.Width}, "double",
+				Attributes{"standard_name": "projection_x_coordinate", "units": "m"}, memoryColumn(xs)))
+	}
+
+	used := map[string]bool{"lat": true, "lon": true, "x": true, "y": true}
+	for b := 0; b < f.Bands; b++ {
+		b := b
+		md := f.BandMetadata[b]
+		attrs := Attributes{}
+		name := md["NETCDF_VARNAME"]
+		if desc := md["description"]; desc != "" {
+			attrs["long_name"] = desc
+			if name == "" {
+				name = desc
+			}
+		}
+		if name == "" {
+			name = "band_" + strconv.Itoa(b+1)
+		}
+		name = uniqueName(sanitizeName(name, b), used)
+
+		if u := md["unittype"]; u != "" {
+			attrs["units"] = u
+		}
+		if s, err := strconv.ParseFloat(md["scale"], 64); err == nil && s != 1 {
+			attrs["scale_factor"] = []float64{s}
+		}
+		if o, err := strconv.ParseFloat(md["offset"], 64); err == nil && o != 0 {
+			attrs["add_offset"] = []float64{o}
+		}
+		// GDAL由NetCDF转换时保留的变量属性，形如"sst#standard_name"
+		for key, value := range f.Metadata {
+			if v, attr, ok := strings.Cut(key, "#"); ok && md["NETCDF_VARNAME"] != "" && v == md["NETCDF_VARNAME"] && !packingAttrs[attr] {
+				if _, exists := attrs[attr]; !exists {
+					attrs[attr] = value
+				}
+			}
+		}
+		for key, value := range md {
+			if key == strings.ToLower(key) && key != "description" && key != "unittype" && key != "scale" && key != "offset" {
+				attrs[key] = value
+			}
+		}
+		if f.HasNoData && !math.IsNaN(f.NoData) {
+			nodata := f.NoData
+			if f.SampleFormat == geotiff.SampleFloat && f.BitsPerSample == 32 {
+				nodata = float64(float32(nodata))
+			}
+			attrs["_FillValue"] = []float64{nodata}
+		}
+		if coords != "" {
+			attrs["coordinates"] = coords
+		}
+
+		vars = append(vars, NewVariable(name, []string{ydim, xdim}, []int{f.Height, f.Width}, f.TypeName(), attrs,
+			func(start, count []int) ([]float64, error) {
+				if len(start) != 2 {
+					return nil, fmt.Errorf("dataio: invalid slice")
+				}
+				return f.ReadBand(b, start[0], start[1], count[0], count[1])
+			}))
+	}
+
+	attrs := Attributes{}
+	for key, value := range f.Metadata {
+		if !strings.Contains(key, "#") {
+			attrs[key] = value
+		} else if global := strings.TrimPrefix(key, "NC_GLOBAL#"); global != key {
+			attrs[global] = value
+		}
+	}
+	if f.EPSG != 0 {
+		attrs["crs"] = "EPSG:" + strconv.Itoa(f.EPSG)
+	}
+	if f.Georeferenced {
+		t := f.Transform
+		attrs["geotransform"] = fmt.Sprintf("%g %g %g %g %g %g", t[0], t[1], t[2], t[3], t[4], t[5])
+	}
+	return NewSource("GeoTIFF", dims, vars, attrs, f), nil
+}
+
+// pixelCoordinate 含旋转项时按像元计算的二维经纬度坐标
+func pixelCoordinate(f *geotiff.File, lon bool) ReadFunc {
+	return func(start, count []int) ([]float64, error) {
+		if len(start) != 2 {
+			return nil, fmt.Errorf("dataio: invalid slice")
+		}
+		out := make([]float64, 0, count[0]*count[1])
+		for i := start[0]; i < start[0]+count[0]; i++ {
+			for j := start[1]; j < start[1]+count[1]; j++ {
+				x, y := f.PixelCenter(i, j)
+				if f.WebMercator() {
+					x, y = inverseMercator(x, y)
+				}
+				if lon {
+					out = append(out, x)
+				} else {
+					out = append(out, y)
+				}
+			}
+		}
+		return out, nil
+	}
+}
+
+// inverseMercator Web墨卡托坐标(米)转换为经纬度
+func inverseMercator(x, y float64) (lon, lat float64) {
+	lon = x / earthRadius * 180 / math.Pi
+	lat = (2*math.Atan(math.Exp(y/earthRadius)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat
+}
@@ -0,0 +1,49 @@
+package dataio
+
+import (
+	"github.com/sinker/ssop/pkg/netcdf"
+)
+
+func init() {
+	Register("netCDF", openNetCDF, []string{".nc", ".nc4", ".cdf", ".netcdf"}, "CDF\x01", "CDF\x02", "CDF\x05")
+}
+
+// openNetCDF 打开NetCDF经典格式文件
+func openNetCDF(path string) (*Source, error) {
+	nc, err := netcdf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dims := make([]Dimension, len(nc.Dims))
+	for i, d := range nc.Dims {
+		dims[i] = Dimension{Name: d.Name, Len: d.Len}
+	}
+
+	vars := make([]*Variable, 0, len(nc.Vars))
+	for _, v := range nc.Vars {
+		v := v
+		names := make([]string, len(v.Dims))
+		for i, d := range v.Dims {
+			names[i] = d.Name
+		}
+		attrs := netcdfAttrs(v.Attrs)
+
+		if v.Type == netcdf.Char {
+			vars = append(vars, NewTextVariable(v.Name, names, v.Shape(), attrs, v.ReadBytes))
+			continue
+		}
+		vars = append(vars, NewVariable(v.Name, names, v.Shape(), v.Type.String(), attrs, v.ReadFloat64))
+	}
+
+	return NewSource("netCDF", dims, vars, netcdfAttrs(nc.Attrs), nc), nil
+}
+
+// netcdfAttrs 转换属性
+func netcdfAttrs(attrs []netcdf.Attribute) Attributes {
+	out := make(Attributes, len(attrs))
+	for _, a := range attrs {
+		out[a.Name] = a.Value
+	}
+	return out
+}
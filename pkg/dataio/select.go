@@ -0,0 +1,186 @@
+package dataio
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Nearest 在变量的一维坐标上查找最接近value的下标，返回维度下标、坐标下标和实际坐标值；
+// 经度按360度周期比较，深度按绝对值比较；没有一维坐标时dim为-1
+func (s *Source) Nearest(v *Variable, axis string, value float64) (dim, index int, actual float64) {
+	dim = s.AxisDim(v, axis)
+	if dim < 0 {
+		return -1, 0, math.NaN()
+	}
+	values, err := s.Coordinate(v, axis).ReadAll()
+	if err != nil || len(values) == 0 {
+		return -1, 0, math.NaN()
+	}
+
+	best := math.Inf(1)
+	for i, x := range values {
+		var d float64
+		switch axis {
+		case AxisLon:
+			d = LonDistance(x, value)
+		case AxisZ:
+			d = math.Abs(math.Abs(x) - math.Abs(value))
+		default:
+			d = math.Abs(x - value)
+		}
+		if d < best {
+			best, index = d, i
+		}
+	}
+	if math.IsInf(best, 1) {
+		return -1, 0, math.NaN()
+	}
+	return dim, index, values[index]
+}
+
+// NearestTime 在变量的时间坐标上查找最接近t的下标
+func (s *Source) NearestTime(v *Variable, t time.Time) (dim, index int, actual time.Time, err error) {
+	dim = s.AxisDim(v, AxisTime)
+	if dim < 0 {
+		return -1, 0, time.Time{}, fmt.Errorf("dataio: variable %s has no time dimension", v.Name)
+	}
+	times, err := Times(s.Coordinate(v, AxisTime))
+	if err != nil {
+		return -1, 0, time.Time{}, err
+	}
+
+	best := time.Duration(math.MaxInt64)
+	for i, x := range times {
+		if x.IsZero() {
+			continue
+		}
+		d := x.Sub(t)
+		if d < 0 {
+			d = -d
+		}
+		if d < best {
+			best, index = d, i
+		}
+	}
+	if best == time.Duration(math.MaxInt64) {
+		return -1, 0, time.Time{}, fmt.Errorf("dataio: variable %s has no valid time", v.Name)
+	}
+	return dim, index, times[index], nil
+}
+
+// NearestPoint 查找最接近(lat, lon)的水平位置，返回需固定的维度及其下标和实际经纬度；
+// 支持一维经纬度坐标和二维(曲线网格)辅助坐标，经纬度坐标依赖时间维(走航数据)时不固定
+func (s *Source) NearestPoint(v *Variable, lat, lon float64) (fixed map[int]int, actualLat, actualLon float64, err error) {
+	cy, cx := s.Coordinate(v, AxisLat), s.Coordinate(v, AxisLon)
+	if cy == nil || cx == nil {
+		return nil, math.NaN(), math.NaN(), fmt.Errorf("dataio: variable %s has no latitude/longitude coordinates", v.Name)
+	}
+
+	fixed = map[int]int{}
+	timeDim := s.AxisDim(v, AxisTime)
+	dimIndex := func(name string) int {
+		for i, d := range v.Dims {
+			if d == name {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// 规则网格: 经纬度分别为不同维度上的一维坐标
+	if len(cy.Dims) == 1 && len(cx.Dims) == 1 && cy.Dims[0] != cx.Dims[0] {
+		ydim, yi, y := s.Nearest(v, AxisLat, lat)
+		xdim, xi, x := s.Nearest(v, AxisLon, lon)
+		if ydim >= 0 {
+			fixed[ydim] = yi
+		}
+		if xdim >= 0 {
+			fixed[xdim] = xi
+		}
+		return fixed, y, x, nil
+	}
+
+	// 曲线网格或站点维: 经纬度定义在相同维度上
+	lats, err := cy.ReadAll()
+	if err != nil {
+		return nil, math.NaN(), math.NaN(), err
+	}
+	lons, err := cx.ReadAll()
+	if err != nil {
+		return nil, math.NaN(), math.NaN(), err
+	}
+	if len(lats) != len(lons) {
+		return nil, math.NaN(), math.NaN(), fmt.Errorf("dataio: latitude and longitude of %s have different shapes", v.Name)
+	}
+
+	best, bi := math.Inf(1), -1
+	coslat := math.Cos(lat * math.Pi / 180)
+	for i := range lats {
+		if math.IsNaN(lats[i]) || math.IsNaN(lons[i]) {
+			continue
+		}
+		dy, dx := lats[i]-lat, LonDistance(lons[i], lon)*coslat
+		if d := dy*dy + dx*dx; d < best {
+			best, bi = d, i
+		}
+	}
+	if bi < 0 {
+		return nil, math.NaN(), math.NaN(), fmt.Errorf("dataio: variable %s has no valid positions", v.Name)
+	}
+
+	// 将展开下标还原到坐标变量的各维度
+	rem := bi
+	for i := len(cy.Dims) - 1; i >= 0; i-- {
+		size := cy.Shape[i]
+		idx := rem % size
+		rem /= size
+		if d := dimIndex(cy.Dims[i]); d >= 0 && d != timeDim {
+			fixed[d] = idx
+		}
+	}
+	return fixed, lats[bi], lons[bi], nil
+}
+
+// TimeRange 变量时间坐标落在[from, to]内的下标范围，from或to为零值时不限制；要求时间坐标单调递增
+func (s *Source) TimeRange(v *Variable, from, to time.Time) (dim, start, count int, times []time.Time, err error) {
+	dim = s.AxisDim(v, AxisTime)
+	if dim < 0 {
+		return -1, 0, 0, nil, fmt.Errorf("dataio: variable %s has no time dimension", v.Name)
+	}
+	all, err := Times(s.Coordinate(v, AxisTime))
+	if err != nil {
+		return -1, 0, 0, nil, err
+	}
+
+	start, end := -1, -1
+	for i, t := range all {
+		if t.IsZero() || (!from.IsZero() && t.Before(from)) || (!to.IsZero() && t.After(to)) {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		end = i
+	}
+	if start < 0 {
+		return dim, 0, 0, nil, nil
+	}
+	return dim, start, end - start + 1, all[start : end+1], nil
+}
+
+// LonDistance 两个经度之间的最小角距离(度)
+func LonDistance(a, b float64) float64 {
+	d := math.Mod(math.Abs(a-b), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
+
+// FullSlice 变量的完整读取范围，可在此基础上固定部分维度
+func FullSlice(v *Variable) (start, count []int) {
+	start = make([]int, len(v.Shape))
+	count = append([]int(nil), v.Shape...)
+	return start, count
+}
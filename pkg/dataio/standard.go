@@ -0,0 +1,236 @@
+package dataio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sinker/ssop/pkg/units"
+)
+
+// ErrVariableNotFound 数据源中找不到对应标准名的变量
+var ErrVariableNotFound = errors.New("dataio: variable not found")
+
+// Alias 变量别名: 将变量名(不区分大小写)映射到CF标准名，Units为变量缺少units属性时假定的单位
+type Alias struct {
+	Name         string `json:"name"`
+	StandardName string `json:"standardName"`
+	Units        string `json:"units"`
+}
+
+// DefaultAliases 内置别名表
+var DefaultAliases = []Alias{
+	{"temp", "sea_water_temperature", "degC"},
+	{"temperature", "sea_water_temperature", "degC"},
+	{"water_temp", "sea_water_temperature", "degC"},
+	{"to", "sea_water_temperature", "degC"},
+	{"temp_adjusted", "sea_water_temperature", "degC"},
+	{"thetao", "sea_water_potential_temperature", "degC"},
+	{"potemp", "sea_water_potential_temperature", "degC"},
+	{"theta", "sea_water_potential_temperature", "degC"},
+	{"sst", "sea_surface_temperature", "degC"},
+	{"analysed_sst", "sea_surface_temperature", "K"},
+	{"tos", "sea_surface_temperature", "degC"},
+	{"psal", "sea_water_practical_salinity", "psu"},
+	{"psal_adjusted", "sea_water_practical_salinity", "psu"},
+	{"salinity", "sea_water_salinity", "psu"},
+	{"salt", "sea_water_salinity", "psu"},
+	{"so", "sea_water_salinity", "psu"},
+	{"sss", "sea_surface_salinity", "psu"},
+	{"sos", "sea_surface_salinity", "psu"},
+	{"pres", "sea_water_pressure", "dbar"},
+	{"pressure", "sea_water_pressure", "dbar"},
+	{"pres_adjusted", "sea_water_pressure", "dbar"},
+	{"depth", "depth", "m"},
+	{"zos", "sea_surface_height_above_geoid", "m"},
+	{"ssh", "sea_surface_height_above_geoid", "m"},
+	{"zeta", "sea_surface_height_above_geoid", "m"},
+	{"adt", "sea_surface_height_above_geoid", "m"},
+	{"sla", "sea_surface_height_above_sea_level", "m"},
+	{"u", "eastward_sea_water_velocity", "m s-1"},
+	{"uo", "eastward_sea_water_velocity", "m s-1"},
+	{"water_u", "eastward_sea_water_velocity", "m s-1"},
+	{"v", "northward_sea_water_velocity", "m s-1"},
+	{"vo", "northward_sea_water_velocity", "m s-1"},
+	{"water_v", "northward_sea_water_velocity", "m s-1"},
+	{"u10", "eastward_wind", "m s-1"},
+	{"uwnd", "eastward_wind", "m s-1"},
+	{"v10", "northward_wind", "m s-1"},
+	{"vwnd", "northward_wind", "m s-1"},
+	{"doxy", "moles_of_oxygen_per_unit_mass_in_sea_water", "umol/kg"},
+	{"chla", "mass_concentration_of_chlorophyll_a_in_sea_water", "mg m-3"},
+	{"chl", "mass_concentration_of_chlorophyll_a_in_sea_water", "mg m-3"},
+	{"swh", "sea_surface_wave_significant_height", "m"},
+	{"hs", "sea_surface_wave_significant_height", "m"},
+	{"vhm0", "sea_surface_wave_significant_height", "m"},
+	{"mld", "ocean_mixed_layer_thickness", "m"},
+	// 浮标、潮位站CSV导出的常见列名
+	{"wtmp", "sea_water_temperature", "degC"},
+	{"wvht", "sea_surface_wave_significant_height", "m"},
+	{"water_level", "sea_surface_height", "m"},
+	{"水温", "sea_water_temperature", "degC"},
+	{"温度", "sea_water_temperature", "degC"},
+	{"海温", "sea_water_temperature", "degC"},
+	{"表层水温", "sea_surface_temperature", "degC"},
+	{"盐度", "sea_water_salinity", "psu"},
+	{"表层盐度", "sea_surface_salinity", "psu"},
+	{"潮位", "sea_surface_height", "cm"},
+	{"水位", "sea_surface_height", "cm"},
+	{"有效波高", "sea_surface_wave_significant_height", "m"},
+	{"波高", "sea_surface_wave_significant_height", "m"},
+	{"深度", "depth", "m"},
+	{"压力", "sea_water_pressure", "dbar"},
+}
+
+// equivalentNames 可互相替代的标准名，按优先级排列；海面高度异常(SLA)与绝对动力地形(ADT)的基准不同，
+// 不互相替代，只有不区分基准的水位(sea_surface_height)可使用两者
+var equivalentNames = map[string][]string{
+	"sea_water_temperature":           {"sea_water_temperature", "sea_water_potential_temperature", "sea_water_conservative_temperature", "sea_surface_temperature"},
+	"sea_water_potential_temperature": {"sea_water_potential_temperature", "sea_water_temperature"},
+	"sea_surface_temperature":         {"sea_surface_temperature", "sea_water_temperature", "sea_water_potential_temperature"},
+	"sea_water_salinity":              {"sea_water_salinity", "sea_water_practical_salinity", "sea_water_absolute_salinity", "sea_surface_salinity"},
+	"sea_water_practical_salinity":    {"sea_water_practical_salinity", "sea_water_salinity"},
+	"sea_surface_salinity":            {"sea_surface_salinity", "sea_water_salinity", "sea_water_practical_salinity"},
+	"sea_surface_height":              {"sea_surface_height", "sea_surface_height_above_sea_level", "sea_surface_height_above_geoid"},
+}
+
+// salinityNames 盐度标准名，units为"1"时按实用盐度处理
+var salinityNames = map[string]bool{
+	"sea_water_salinity": true, "sea_water_practical_salinity": true, "sea_surface_salinity": true,
+}
+
+// Resolver 按CF标准名查找变量并换算单位
+type Resolver struct {
+	aliases map[string]Alias // 键为小写变量名
+}
+
+// NewResolver 创建解析器，aliases中的条目覆盖同名的内置别名
+func NewResolver(aliases []Alias) *Resolver {
+	r := &Resolver{aliases: make(map[string]Alias, len(DefaultAliases)+len(aliases))}
+	for _, list := range [][]Alias{DefaultAliases, aliases} {
+		for _, a := range list {
+			r.aliases[strings.ToLower(a.Name)] = a
+		}
+	}
+	return r
+}
+
+// StandardName 变量的CF标准名: 优先使用standard_name属性，其次查别名表
+func (r *Resolver) StandardName(v *Variable) string {
+	if std := v.Attrs.String("standard_name"); std != "" {
+		return std
+	}
+	if a, ok := r.aliases[strings.ToLower(v.Name)]; ok {
+		return a.StandardName
+	}
+	return ""
+}
+
+// Units 变量的单位，缺少units属性时使用别名表中假定的单位
+func (r *Resolver) Units(v *Variable) string {
+	u := strings.TrimSpace(v.Units())
+	std := r.StandardName(v)
+	if u == "" {
+		if a, ok := r.aliases[strings.ToLower(v.Name)]; ok {
+			u = a.Units
+		}
+	}
+	// CF规定实用盐度的单位为"1"(旧版为"1e-3")，数值与psu相同；早期资料以千分比(‰)记录盐度
+	if salinityNames[std] && practicalSalinityUnits[u] {
+		u = "psu"
+	}
+	return u
+}
+
+// practicalSalinityUnits 盐度标准名下按实用盐度处理的单位
+var practicalSalinityUnits = map[string]bool{
+	"": true, "1": true, "1e-3": true, "0.001": true, "ppt": true, "ppth": true, "‰": true, "permil": true,
+}
+
+// Find 查找标准名对应的变量，依次尝试等价标准名；同一标准名下优先选择standard_name属性匹配的变量，
+// 存在同名的*_ADJUSTED变量(如Argo的TEMP_ADJUSTED)时使用调整后的变量
+func (r *Resolver) Find(s *Source, standardName string) *Variable {
+	if v := r.find(s, standardName); v != nil {
+		return adjusted(s, v)
+	}
+	return nil
+}
+
+// adjusted 变量v对应的*_ADJUSTED变量，不存在时为v本身
+func adjusted(s *Source, v *Variable) *Variable {
+	for _, suffix := range []string{"_ADJUSTED", "_adjusted"} {
+		if a := s.Var(v.Name + suffix); a != nil && !a.IsText() {
+			return a
+		}
+	}
+	return v
+}
+
+func (r *Resolver) find(s *Source, standardName string) *Variable {
+	candidates := equivalentNames[standardName]
+	if len(candidates) == 0 {
+		candidates = []string{standardName}
+	}
+
+	for _, name := range candidates {
+		var byAlias *Variable
+		for _, v := range s.Vars {
+			if v.IsText() {
+				continue
+			}
+			if v.Attrs.String("standard_name") == name {
+				return v
+			}
+			if byAlias == nil && v.Attrs.String("standard_name") == "" {
+				if a, ok := r.aliases[strings.ToLower(v.Name)]; ok && a.StandardName == name {
+					byAlias = v
+				}
+			}
+		}
+		if byAlias != nil {
+			return byAlias
+		}
+	}
+	return nil
+}
+
+// Resolve 查找标准名对应的变量并换算到指定单位，to为空时不换算；
+// 返回的变量为原变量的副本，读取时自动换算，units属性为目标单位
+func (r *Resolver) Resolve(s *Source, standardName, to string) (*Variable, error) {
+	v := r.Find(s, standardName)
+	if v == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVariableNotFound, standardName)
+	}
+	if to == "" {
+		return v, nil
+	}
+	return r.Convert(v, to)
+}
+
+// Convert 返回读取时换算到指定单位的变量副本
+func (r *Resolver) Convert(v *Variable, to string) (*Variable, error) {
+	from := r.Units(v)
+	conv, err := units.NewConverter(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("dataio: convert %s from %q to %q: %w", v.Name, from, to, err)
+	}
+
+	var out *Variable
+	if conv.Identity() {
+		copied := *v
+		out = &copied
+	} else {
+		out = v.WithFilter(func(start, count []int, values []float64) error {
+			conv.ConvertAll(values)
+			return nil
+		})
+	}
+
+	attrs := make(Attributes, len(v.Attrs)+1)
+	for k, val := range v.Attrs {
+		attrs[k] = val
+	}
+	attrs["units"] = to
+	out.Attrs = attrs
+	return out, nil
+}
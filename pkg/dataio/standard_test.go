@@ -0,0 +1,104 @@
+package dataio
+
+import (
+	"errors"
+	"testing"
+)
+
+// testSource 由变量名和属性构造数据源，变量均为长度为2的一维数值变量
+func testSource(vars ...*Variable) *Source {
+	return NewSource("test", []Dimension{{Name: "n", Len: 2}}, vars, nil, nil)
+}
+
+func testVar(name string, attrs Attributes) *Variable {
+	return NewVariable(name, []string{"n"}, []int{2}, "double", attrs, MemoryArray([]float64{10, 20}, []int{2}))
+}
+
+func TestResolverFind(t *testing.T) {
+	std := func(name string) Attributes { return Attributes{"standard_name": name} }
+	tests := []struct {
+		name         string
+		source       *Source
+		standardName string
+		want         string
+	}{
+		{"standard_name before alias",
+			testSource(testVar("temp", nil), testVar("t", std("sea_water_temperature"))),
+			"sea_water_temperature", "t"},
+		{"alias",
+			testSource(testVar("TEMP", nil)),
+			"sea_water_temperature", "TEMP"},
+		{"equivalent name",
+			testSource(testVar("thetao", nil)),
+			"sea_water_temperature", "thetao"},
+		{"adjusted after raw",
+			testSource(testVar("TEMP", std("sea_water_temperature")), testVar("TEMP_ADJUSTED", std("sea_water_temperature"))),
+			"sea_water_temperature", "TEMP_ADJUSTED"},
+		{"adjusted before raw",
+			testSource(testVar("PSAL_ADJUSTED", nil), testVar("PSAL", nil)),
+			"sea_water_practical_salinity", "PSAL_ADJUSTED"},
+		{"lower-case adjusted",
+			testSource(testVar("pres", nil), testVar("pres_adjusted", nil)),
+			"sea_water_pressure", "pres_adjusted"},
+		// 绝对动力地形和海面高度异常不互相替代
+		{"adt is not sla",
+			testSource(testVar("sla", nil)),
+			"sea_surface_height_above_geoid", ""},
+		{"sla is not adt",
+			testSource(testVar("adt", nil)),
+			"sea_surface_height_above_sea_level", ""},
+		{"water level accepts adt",
+			testSource(testVar("zos", nil)),
+			"sea_surface_height", "zos"},
+	}
+	r := NewResolver(nil)
+	for _, tt := range tests {
+		got := ""
+		if v := r.Find(tt.source, tt.standardName); v != nil {
+			got = v.Name
+		}
+		if got != tt.want {
+			t.Errorf("%s: Find(%s) = %q, want %q", tt.name, tt.standardName, got, tt.want)
+		}
+	}
+}
+
+func TestResolverUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs Attributes
+		want  string
+	}{
+		{"psal", Attributes{"units": "1"}, "psu"},
+		{"psal", Attributes{"units": "‰"}, "psu"},
+		{"psal", nil, "psu"},
+		{"so", Attributes{"units": "1e-3"}, "psu"},
+		{"sa", Attributes{"standard_name": "sea_water_absolute_salinity", "units": "g/kg"}, "g/kg"},
+		{"analysed_sst", nil, "K"},
+		{"temp", Attributes{"units": "degrees Celsius"}, "degrees Celsius"},
+	}
+	r := NewResolver(nil)
+	for _, tt := range tests {
+		if got := r.Units(testVar(tt.name, tt.attrs)); got != tt.want {
+			t.Errorf("Units(%s %v) = %q, want %q", tt.name, tt.attrs, got, tt.want)
+		}
+	}
+}
+
+func TestResolverResolve(t *testing.T) {
+	r := NewResolver([]Alias{{"水温", "sea_water_temperature", "K"}})
+	v, err := r.Resolve(testSource(testVar("水温", nil)), "sea_water_temperature", "degC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := v.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0] != 10-273.15 || v.Units() != "degC" {
+		t.Errorf("values = %v %s, want %g degC", values, v.Units(), 10-273.15)
+	}
+	if _, err := r.Resolve(testSource(), "sea_water_temperature", ""); !errors.Is(err, ErrVariableNotFound) {
+		t.Errorf("missing variable error = %v", err)
+	}
+}
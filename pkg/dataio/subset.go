@@ -0,0 +1,417 @@
+package dataio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/netcdf"
+)
+
+// ErrEmptySubset 子集不含任何数据
+var ErrEmptySubset = errors.New("dataio: subset is empty")
+
+// packingAttrs 写出解包后的物理值时需去除的属性
+var packingAttrs = map[string]bool{
+	"_FillValue": true, "missing_value": true, "scale_factor": true, "add_offset": true,
+	"valid_min": true, "valid_max": true, "valid_range": true,
+}
+
+// Subset 子集条件，零值表示不限制
+type Subset struct {
+	Variables []string    // 数据变量，为空时包含全部
+	From, To  time.Time   // 时间范围
+	Bounds    *[4]float64 // 空间范围: [minLat, minLng, maxLat, maxLng]
+}
+
+// WriteNetCDF 按子集条件将数据源写出为NetCDF文件: 包含所选数据变量及其坐标变量，
+// 按时间和经纬度坐标在各维度上筛选下标，数值按解包后的物理值写出；subset为nil时写出全部数据
+func WriteNetCDF(src *Source, path string, subset *Subset) error {
+	full := subset == nil
+	if full {
+		subset = &Subset{}
+	}
+
+	selected, err := subsetVariables(src, subset.Variables)
+	if err != nil {
+		return err
+	}
+	indices, err := subsetIndices(src, selected, subset)
+	if err != nil {
+		return err
+	}
+
+	// 所选变量用到的维度，以及定义在这些维度上的坐标变量和字符变量
+	used := map[string]bool{}
+	for _, v := range selected {
+		for _, d := range v.Dims {
+			used[d] = true
+		}
+	}
+	vars := append([]*Variable(nil), selected...)
+	for _, v := range src.Vars {
+		if containsVar(vars, v) || len(v.Dims) == 0 {
+			continue
+		}
+		inside := true
+		for _, d := range v.Dims {
+			if !used[d] && !(v.IsText() && d == v.Dims[len(v.Dims)-1]) {
+				inside = false
+			}
+		}
+		if inside && (v.IsText() || AxisOf(v) != "" || referenced(selected, v.Name)) {
+			vars = append(vars, v)
+		}
+	}
+
+	w := netcdf.NewWriter()
+	for _, name := range src.Attrs.names() {
+		if name == "history" && !full {
+			continue
+		}
+		writeAttr(func(typ netcdf.Type, value interface{}) error {
+			w.AddAttr(name, typ, value)
+			return nil
+		}, netcdf.Double, src.Attrs[name])
+	}
+	if !full {
+		w.AddAttr("history", netcdf.Char, strings.TrimSpace(src.Attrs.String("history")+"\n"+
+			time.Now().UTC().Format(time.RFC3339)+" subset"))
+	}
+
+	added := map[string]bool{}
+	for _, v := range vars {
+		for i, d := range v.Dims {
+			if added[d] {
+				continue
+			}
+			n := v.Shape[i]
+			if idx, ok := indices[d]; ok {
+				n = len(idx)
+			}
+			if n == 0 {
+				return ErrEmptySubset
+			}
+			if err := w.AddDim(d, n); err != nil {
+				return err
+			}
+			added[d] = true
+		}
+	}
+
+	for _, v := range vars {
+		if err := writeSubsetVar(w, v, indices); err != nil {
+			return fmt.Errorf("dataio: write %s: %w", v.Name, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := w.WriteFile(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// subsetVariables 查找所选数据变量
+func subsetVariables(src *Source, names []string) ([]*Variable, error) {
+	if len(names) == 0 {
+		vars := src.DataVariables()
+		if len(vars) == 0 {
+			return nil, ErrEmptySubset
+		}
+		return vars, nil
+	}
+	vars := make([]*Variable, 0, len(names))
+	for _, name := range names {
+		v := src.Var(name)
+		if v == nil || v.IsText() {
+			return nil, fmt.Errorf("%w: %s", ErrVariableNotFound, name)
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// subsetIndices 按时间和经纬度条件计算各维度保留的下标，未出现的维度保留全部下标
+func subsetIndices(src *Source, vars []*Variable, subset *Subset) (map[string][]int, error) {
+	keep := map[string][]bool{}
+	restrict := func(dim string, n int, ok func(i int) bool) {
+		mask, exists := keep[dim]
+		if !exists {
+			mask = make([]bool, n)
+			for i := range mask {
+				mask[i] = true
+			}
+			keep[dim] = mask
+		}
+		for i := range mask {
+			if mask[i] && !ok(i) {
+				mask[i] = false
+			}
+		}
+	}
+
+	done := map[*Variable]bool{}
+	for _, v := range vars {
+		if !subset.From.IsZero() || !subset.To.IsZero() {
+			if c := src.Coordinate(v, AxisTime); c != nil && len(c.Dims) == 1 && !done[c] {
+				done[c] = true
+				times, err := Times(c)
+				if err != nil {
+					return nil, err
+				}
+				restrict(c.Dims[0], len(times), func(i int) bool {
+					t := times[i]
+					return !t.IsZero() && (subset.From.IsZero() || !t.Before(subset.From)) && (subset.To.IsZero() || !t.After(subset.To))
+				})
+			}
+		}
+		if subset.Bounds == nil {
+			continue
+		}
+
+		b := subset.Bounds
+		cy, cx := src.Coordinate(v, AxisLat), src.Coordinate(v, AxisLon)
+		if cy == nil || cx == nil || done[cy] {
+			continue
+		}
+		done[cy] = true
+		lats, err := cy.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		lons, err := cx.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		inLat := func(x float64) bool { return x >= b[0] && x <= b[2] }
+		inLon := func(x float64) bool { return InLonRange(x, b[1], b[3]) }
+
+		// 规则网格: 经纬度各自为一维坐标
+		if len(cy.Dims) == 1 && len(cx.Dims) == 1 && cy.Dims[0] != cx.Dims[0] {
+			restrict(cy.Dims[0], len(lats), func(i int) bool { return inLat(lats[i]) })
+			restrict(cx.Dims[0], len(lons), func(i int) bool { return inLon(lons[i]) })
+			continue
+		}
+		if len(lats) != len(lons) || strings.Join(cy.Dims, ",") != strings.Join(cx.Dims, ",") {
+			continue
+		}
+
+		// 站点维: 逐点筛选
+		if len(cy.Dims) == 1 {
+			restrict(cy.Dims[0], len(lats), func(i int) bool { return inLat(lats[i]) && inLon(lons[i]) })
+			continue
+		}
+
+		// 曲线网格: 取包含全部范围内格点的最小矩形
+		lo := make([]int, len(cy.Dims))
+		hi := make([]int, len(cy.Dims))
+		for i := range lo {
+			lo[i], hi[i] = math.MaxInt32, -1
+		}
+		for i := range lats {
+			if !inLat(lats[i]) || !inLon(lons[i]) {
+				continue
+			}
+			rem := i
+			for d := len(cy.Dims) - 1; d >= 0; d-- {
+				idx := rem % cy.Shape[d]
+				rem /= cy.Shape[d]
+				if idx < lo[d] {
+					lo[d] = idx
+				}
+				if idx > hi[d] {
+					hi[d] = idx
+				}
+			}
+		}
+		for d, name := range cy.Dims {
+			d := d
+			restrict(name, cy.Shape[d], func(i int) bool { return i >= lo[d] && i <= hi[d] })
+		}
+	}
+
+	indices := make(map[string][]int, len(keep))
+	for dim, mask := range keep {
+		idx := []int{}
+		for i, ok := range mask {
+			if ok {
+				idx = append(idx, i)
+			}
+		}
+		indices[dim] = idx
+	}
+	return indices, nil
+}
+
+// InLonRange 经度是否在[min, max]内，按360度周期比较
+func InLonRange(x, min, max float64) bool {
+	if math.IsNaN(x) {
+		return false
+	}
+	if max-min >= 360 {
+		return true
+	}
+	d := math.Mod(x-min, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d <= max-min
+}
+
+// writeSubsetVar 读取变量在各维度保留下标的外包范围，抽取保留的元素后写入
+func writeSubsetVar(w *netcdf.Writer, v *Variable, indices map[string][]int) error {
+	start, count := FullSlice(v)
+	picks := make([][]int, len(v.Dims))
+	for i, d := range v.Dims {
+		idx, ok := indices[d]
+		if !ok {
+			continue
+		}
+		start[i], count[i] = idx[0], idx[len(idx)-1]-idx[0]+1
+		picks[i] = make([]int, len(idx))
+		for j, x := range idx {
+			picks[i][j] = x - idx[0]
+		}
+	}
+
+	typ := netcdf.Double
+	if v.Type == "float" {
+		typ = netcdf.Float
+	}
+	if v.IsText() {
+		typ = netcdf.Char
+	}
+	if err := w.AddVar(v.Name, typ, v.Dims); err != nil {
+		return err
+	}
+	for _, name := range v.Attrs.names() {
+		if packingAttrs[name] && !v.IsText() {
+			continue
+		}
+		if err := writeAttr(func(t netcdf.Type, value interface{}) error {
+			return w.AddVarAttr(v.Name, name, t, value)
+		}, typ, v.Attrs[name]); err != nil {
+			return err
+		}
+	}
+
+	if v.IsText() {
+		raw, err := v.text(start, count)
+		if err != nil {
+			return err
+		}
+		return w.SetData(v.Name, gather(raw, count, picks))
+	}
+
+	values, err := v.Read(start, count)
+	if err != nil {
+		return err
+	}
+	if AxisOf(v) == "" {
+		w.AddVarAttr(v.Name, "_FillValue", typ, []float64{defaultFloatFill})
+	}
+	return w.SetData(v.Name, gather(values, count, picks))
+}
+
+// writeAttr 按值的类型写入属性，数值属性使用typ类型(字符变量使用双精度)
+func writeAttr(add func(typ netcdf.Type, value interface{}) error, typ netcdf.Type, value interface{}) error {
+	if typ == netcdf.Char {
+		typ = netcdf.Double
+	}
+	switch x := value.(type) {
+	case string:
+		return add(netcdf.Char, x)
+	case []float64:
+		return add(typ, x)
+	}
+	return nil
+}
+
+// gather 从按count读取的数组中抽取各维度picks指定的元素，picks[i]为nil表示保留该维度全部元素
+func gather[T any](values []T, count []int, picks [][]int) []T {
+	all := true
+	for _, p := range picks {
+		if p != nil {
+			all = false
+		}
+	}
+	if all {
+		return values
+	}
+
+	shape := make([]int, len(count))
+	total := 1
+	for i := range count {
+		shape[i] = count[i]
+		if picks[i] != nil {
+			shape[i] = len(picks[i])
+		}
+		total *= shape[i]
+	}
+	strides := make([]int, len(count))
+	stride := 1
+	for i := len(count) - 1; i >= 0; i-- {
+		strides[i] = stride
+		stride *= count[i]
+	}
+
+	out := make([]T, 0, total)
+	pos := make([]int, len(count))
+	for n := 0; n < total; n++ {
+		offset := 0
+		for i, p := range pos {
+			if picks[i] != nil {
+				p = picks[i][p]
+			}
+			offset += p * strides[i]
+		}
+		out = append(out, values[offset])
+
+		for i := len(pos) - 1; i >= 0; i-- {
+			pos[i]++
+			if pos[i] < shape[i] {
+				break
+			}
+			pos[i] = 0
+		}
+	}
+	return out
+}
+
+// names 按名称排序的属性名
+func (a Attributes) names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containsVar 变量是否在列表中
+func containsVar(vars []*Variable, v *Variable) bool {
+	for _, x := range vars {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// referenced 变量是否出现在任一变量的coordinates属性中
+func referenced(vars []*Variable, name string) bool {
+	for _, v := range vars {
+		for _, c := range strings.Fields(v.Attrs.String("coordinates")) {
+			if c == name {
+				return true
+			}
+		}
+	}
+	return false
+}
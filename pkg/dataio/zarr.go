@@ -0,0 +1,184 @@
+package dataio
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/sinker/ssop/pkg/zarr"
+)
+
+func init() {
+	Register("Zarr", openZarr, []string{".zarr"})
+}
+
+// openZarr 打开Zarr v2/v3目录存储: 根组下的每个数组对应一个变量，维度名取自dimension_names或
+// xarray的_ARRAY_DIMENSIONS属性；读取时只解码与切片相交的块
+func openZarr(path string) (*Source, error) {
+	store, err := zarr.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dims []Dimension
+	seen := map[string]bool{}
+	vars := make([]*Variable, 0, len(store.Arrays))
+	for _, a := range store.Arrays {
+		names := a.Dims
+		if names == nil {
+			names = make([]string, len(a.Shape))
+			for i := range names {
+				names[i] = fmt.Sprintf("%s_dim%d", a.Name, i)
+			}
+		}
+		for i, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				dims = append(dims, Dimension{Name: name, Len: a.Shape[i]})
+			}
+		}
+
+		attrs := zarrAttrs(a.Attrs)
+		if fill, ok := a.Attrs["_FillValue"].(string); ok {
+			// xarray写入v3时以base64编码浮点填充值
+			if v, ok := decodeFillBytes(fill, a.TypeName()); ok {
+				attrs["_FillValue"] = []float64{v}
+			}
+		}
+		if _, ok := attrs["_FillValue"]; !ok && a.Version == 2 && a.HasFill && a.Dims != nil {
+			// xarray写入v2时以fill_value保存_FillValue
+			attrs["_FillValue"] = []float64{a.FillValue}
+		}
+		vars = append(vars, NewVariable(a.Name, names, a.Shape, a.TypeName(), attrs, a.Read))
+	}
+
+	attrs := zarrAttrs(store.Attrs)
+	if len(store.Skipped) > 0 {
+		attrs["zarr_skipped_arrays"] = strings.Join(store.Skipped, " ")
+	}
+	return NewSource("Zarr", dims, vars, attrs, store), nil
+}
+
+// zarrAttrs 转换JSON属性: 数值和数值数组转为[]float64，字符串数组以空格连接，其他值保留JSON文本
+func zarrAttrs(raw map[string]interface{}) Attributes {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(Attributes, len(raw))
+	for _, k := range keys {
+		switch v := raw[k].(type) {
+		case string:
+			out[k] = v
+		case float64:
+			out[k] = []float64{v}
+		case bool:
+			if v {
+				out[k] = []float64{1}
+			} else {
+				out[k] = []float64{0}
+			}
+		case []interface{}:
+			if nums, ok := jsonFloats(v); ok {
+				out[k] = nums
+			} else if strs, ok := jsonStrings(v); ok {
+				out[k] = strings.Join(strs, " ")
+			} else if data, err := json.Marshal(v); err == nil {
+				out[k] = string(data)
+			}
+		case nil:
+		default:
+			if data, err := json.Marshal(v); err == nil {
+				out[k] = string(data)
+			}
+		}
+	}
+	return out
+}
+
+func jsonFloats(list []interface{}) ([]float64, bool) {
+	out := make([]float64, len(list))
+	for i, x := range list {
+		switch v := x.(type) {
+		case float64:
+			out[i] = v
+		case string:
+			// 非有限值以字符串表示
+			switch v {
+			case "NaN":
+				out[i] = math.NaN()
+			case "Infinity":
+				out[i] = math.Inf(1)
+			case "-Infinity":
+				out[i] = math.Inf(-1)
+			default:
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return out, len(out) > 0
+}
+
+func jsonStrings(list []interface{}) ([]string, bool) {
+	out := make([]string, len(list))
+	for i, x := range list {
+		s, ok := x.(string)
+		if !ok {
+			return nil, false
+		}
+		out[i] = s
+	}
+	return out, true
+}
+
+// decodeFillBytes 解析base64编码的小端填充值
+func decodeFillBytes(s, typ string) (float64, bool) {
+	switch s {
+	case "NaN":
+		return math.NaN(), true
+	case "Infinity":
+		return math.Inf(1), true
+	case "-Infinity":
+		return math.Inf(-1), true
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, false
+	}
+	le := binary.LittleEndian
+	switch {
+	case typ == "float" && len(b) == 4:
+		return float64(math.Float32frombits(le.Uint32(b))), true
+	case typ == "double" && len(b) == 8:
+		return math.Float64frombits(le.Uint64(b)), true
+	case len(b) == 1:
+		if typ == "byte" {
+			return float64(int8(b[0])), true
+		}
+		return float64(b[0]), true
+	case len(b) == 2:
+		if typ == "short" {
+			return float64(int16(le.Uint16(b))), true
+		}
+		return float64(le.Uint16(b)), true
+	case len(b) == 4:
+		if typ == "int" {
+			return float64(int32(le.Uint32(b))), true
+		}
+		return float64(le.Uint32(b)), true
+	case len(b) == 8:
+		if typ == "int64" {
+			return float64(int64(le.Uint64(b))), true
+		}
+		return float64(le.Uint64(b)), true
+	}
+	return 0, false
+}
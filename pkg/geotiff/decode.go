@@ -0,0 +1,239 @@
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// chunk 读取并解压数据块(条带或瓦片)，结果按字节数上限缓存
+func (f *File) chunk(index, chunkRow int) ([]byte, error) {
+	f.mu.Lock()
+	if data, ok := f.cache[index]; ok {
+		f.mu.Unlock()
+		return data, nil
+	}
+	f.mu.Unlock()
+
+	samples := f.Bands
+	if f.planar == 2 {
+		samples = 1
+	}
+	rows := f.chunkHeight
+	if !f.tiled && (chunkRow+1)*f.chunkHeight > f.Height {
+		// 最后一个条带可能不足RowsPerStrip行
+		rows = f.Height - chunkRow*f.chunkHeight
+	}
+	rowBytes := f.chunkWidth * samples * f.BitsPerSample / 8
+	size := rows * rowBytes
+
+	raw := make([]byte, f.counts[index])
+	if len(raw) > 0 {
+		if _, err := f.r.ReadAt(raw, int64(f.offsets[index])); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("geotiff: read block %d: %w", index, err)
+		}
+	}
+
+	var data []byte
+	var err error
+	switch f.compression {
+	case compressionNone:
+		data = raw
+	case compressionLZW:
+		data, err = lzwDecode(raw, size)
+	case compressionDeflate, compressionDeflate2:
+		data, err = inflate(raw, size)
+	case compressionPackBits:
+		data, err = unpackBits(raw, size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("geotiff: decompress block %d: %w", index, err)
+	}
+	if len(data) < size {
+		// 稀疏或截断的数据块补零
+		data = append(data, make([]byte, size-len(data))...)
+	}
+	data = data[:size]
+
+	switch f.predictor {
+	case 2:
+		undoHorizontal(data, rowBytes, samples, f.BitsPerSample/8, f.order)
+	case 3:
+		data = undoFloatingPoint(data, rowBytes, samples, f.BitsPerSample/8, f.order)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.cacheBytes+len(data) > maxCacheBytes && len(f.cacheOrder) > 0 {
+		oldest := f.cacheOrder[0]
+		f.cacheOrder = f.cacheOrder[1:]
+		f.cacheBytes -= len(f.cache[oldest])
+		delete(f.cache, oldest)
+	}
+	f.cache[index] = data
+	f.cacheOrder = append(f.cacheOrder, index)
+	f.cacheBytes += len(data)
+	return data, nil
+}
+
+// inflate Deflate解压
+func inflate(raw []byte, size int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	out := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := io.Copy(out, io.LimitReader(zr, int64(size))); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// unpackBits PackBits解压
+func unpackBits(raw []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for i := 0; i < len(raw) && len(out) < size; {
+		n := int(int8(raw[i]))
+		i++
+		switch {
+		case n >= 0:
+			if i+n+1 > len(raw) {
+				return nil, fmt.Errorf("packbits: truncated literal run")
+			}
+			out = append(out, raw[i:i+n+1]...)
+			i += n + 1
+		case n != -128:
+			if i >= len(raw) {
+				return nil, fmt.Errorf("packbits: truncated repeat run")
+			}
+			for k := 0; k < 1-n; k++ {
+				out = append(out, raw[i])
+			}
+			i++
+		}
+	}
+	return out, nil
+}
+
+// lzwEntry LZW码表条目，指向输出中已解码的字符串
+type lzwEntry struct {
+	pos, len int
+}
+
+// lzwDecode TIFF风格的LZW解压: 高位在前，码宽提前一个码切换("early change")
+func lzwDecode(raw []byte, size int) ([]byte, error) {
+	const (
+		clearCode = 256
+		eoiCode   = 257
+	)
+
+	out := make([]byte, 0, size)
+	table := make([]lzwEntry, 4096)
+	next, width := 258, 9
+	oldCode, oldPos, oldLen := -1, 0, 0
+
+	var bits uint32
+	var nbits uint
+	pos := 0
+	for {
+		for nbits < uint(width) && pos < len(raw) {
+			bits = bits<<8 | uint32(raw[pos])
+			nbits += 8
+			pos++
+		}
+		if nbits < uint(width) {
+			break
+		}
+		code := int(bits>>(nbits-uint(width))) & (1<<width - 1)
+		nbits -= uint(width)
+
+		if code == eoiCode {
+			break
+		}
+		if code == clearCode {
+			next, width, oldCode = 258, 9, -1
+			continue
+		}
+
+		start := len(out)
+		switch {
+		case oldCode < 0:
+			if code > 255 {
+				return nil, fmt.Errorf("lzw: invalid first code %d", code)
+			}
+			out = append(out, byte(code))
+		case code < 256:
+			out = append(out, byte(code))
+		case code < next:
+			e := table[code]
+			out = append(out, out[e.pos:e.pos+e.len]...)
+		case code == next:
+			out = append(out, out[oldPos:oldPos+oldLen]...)
+			out = append(out, out[oldPos])
+		default:
+			return nil, fmt.Errorf("lzw: invalid code %d", code)
+		}
+
+		if oldCode >= 0 && next < len(table) {
+			// 新条目为上一个字符串加当前字符串的首字符，在输出中恰好连续
+			table[next] = lzwEntry{pos: oldPos, len: oldLen + 1}
+			next++
+			if next >= 1<<width-1 && width < 12 {
+				width++
+			}
+		}
+		oldCode, oldPos, oldLen = code, start, len(out)-start
+		if len(out) >= size {
+			break
+		}
+	}
+	return out, nil
+}
+
+// undoHorizontal 还原整数采样的水平差分预测
+func undoHorizontal(data []byte, rowBytes, samples, bytesPerSample int, order binary.ByteOrder) {
+	stride := samples * bytesPerSample
+	for row := 0; row+rowBytes <= len(data); row += rowBytes {
+		line := data[row : row+rowBytes]
+		for i := stride; i+bytesPerSample <= len(line); i += bytesPerSample {
+			prev := i - stride
+			switch bytesPerSample {
+			case 1:
+				line[i] += line[prev]
+			case 2:
+				order.PutUint16(line[i:], order.Uint16(line[i:])+order.Uint16(line[prev:]))
+			case 4:
+				order.PutUint32(line[i:], order.Uint32(line[i:])+order.Uint32(line[prev:]))
+			case 8:
+				order.PutUint64(line[i:], order.Uint64(line[i:])+order.Uint64(line[prev:]))
+			}
+		}
+	}
+}
+
+// undoFloatingPoint 还原浮点预测: 逐字节差分后各采样的字节按高位在前分平面存放
+func undoFloatingPoint(data []byte, rowBytes, samples, bytesPerSample int, order binary.ByteOrder) []byte {
+	out := make([]byte, len(data))
+	n := rowBytes / bytesPerSample // 每行的采样数
+	for row := 0; row+rowBytes <= len(data); row += rowBytes {
+		line := data[row : row+rowBytes]
+		for i := samples; i < len(line); i++ {
+			line[i] += line[i-samples]
+		}
+		dst := out[row : row+rowBytes]
+		for k := 0; k < n; k++ {
+			for b := 0; b < bytesPerSample; b++ {
+				plane := b
+				if order == binary.LittleEndian {
+					plane = bytesPerSample - 1 - b
+				}
+				dst[k*bytesPerSample+b] = line[plane*n+k]
+			}
+		}
+	}
+	return out
+}
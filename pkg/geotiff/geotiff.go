@@ -0,0 +1,753 @@
+// Package geotiff 纯Go实现的GeoTIFF读写: 读取支持经典TIFF和BigTIFF(含COG)、条带和瓦片组织、
+// 无压缩/LZW/Deflate/PackBits压缩及差分预测；写入单精度浮点的多波段GeoTIFF
+package geotiff
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TIFF标签
+const (
+	tagNewSubfileType  = 254
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagPhotometric     = 262
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagExtraSamples    = 338
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagModelTransform  = 34264
+	tagGeoKeyDirectory = 34735
+	tagGDALMetadata    = 42112
+	tagGDALNoData      = 42113
+)
+
+// TIFF字段类型
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeSByte     = 6
+	typeUndefined = 7
+	typeSShort    = 8
+	typeSLong     = 9
+	typeSRational = 10
+	typeFloat     = 11
+	typeDouble    = 12
+	typeLong8     = 16
+	typeSLong8    = 17
+	typeIFD8      = 18
+)
+
+// typeSizes 各字段类型的字节数
+var typeSizes = map[uint16]int{
+	typeByte: 1, typeASCII: 1, typeShort: 2, typeLong: 4, typeRational: 8, typeSByte: 1, typeUndefined: 1,
+	typeSShort: 2, typeSLong: 4, typeSRational: 8, typeFloat: 4, typeDouble: 8, typeLong8: 8, typeSLong8: 8, typeIFD8: 8,
+}
+
+// 压缩方式
+const (
+	compressionNone     = 1
+	compressionLZW      = 5
+	compressionDeflate  = 8
+	compressionPackBits = 32773
+	compressionDeflate2 = 32946
+)
+
+// 地理键
+const (
+	keyModelType      = 1024
+	keyRasterType     = 1025
+	keyGeographicType = 2048
+	keyAngularUnits   = 2054
+	keyProjectedType  = 3072
+)
+
+// 模型类型
+const (
+	ModelProjected  = 1
+	ModelGeographic = 2
+)
+
+// 采样格式
+const (
+	SampleUint  = 1
+	SampleInt   = 2
+	SampleFloat = 3
+)
+
+// rasterPixelIsPoint 栅格类型: 坐标指向像元中心
+const rasterPixelIsPoint = 2
+
+// maxIFDs 查找主图像时最多遍历的IFD数
+const maxIFDs = 64
+
+// maxCacheBytes 解压后数据块的缓存上限
+const maxCacheBytes = 64 << 20
+
+// maxBlockBytes 单个数据块解压后的字节数上限
+const maxBlockBytes = 256 << 20
+
+// blockOverhead 压缩数据块允许超出解压大小的字节数(最坏情况下LZW约膨胀1.5倍，另加压缩头)
+const blockOverhead = 1024
+
+var (
+	// ErrNotTIFF 不是TIFF文件
+	ErrNotTIFF = errors.New("geotiff: not a TIFF file")
+	// ErrUnsupported 不支持的TIFF特性
+	ErrUnsupported = errors.New("geotiff: unsupported TIFF feature")
+	// ErrInvalidWindow 读取范围无效
+	ErrInvalidWindow = errors.New("geotiff: invalid window")
+)
+
+// File 已打开的GeoTIFF文件，只读取第一个全分辨率图像(忽略COG的概览和掩膜)
+type File struct {
+	Width         int
+	Height        int
+	Bands         int
+	BitsPerSample int
+	SampleFormat  int
+	// Transform 仿射变换(GDAL约定，像元角点): X = T[0] + col*T[1] + row*T[2]，Y = T[3] + col*T[4] + row*T[5]
+	Transform     [6]float64
+	Georeferenced bool
+	ModelType     int // ModelProjected、ModelGeographic，未知时为0
+	EPSG          int // 坐标参考系的EPSG代码，未知时为0
+	NoData        float64
+	HasNoData     bool
+	Metadata      map[string]string   // 数据集级GDAL元数据
+	BandMetadata  []map[string]string // 各波段GDAL元数据，description、unittype、scale、offset为GDAL的标准角色
+
+	order       binary.ByteOrder
+	bigTIFF     bool
+	compression int
+	predictor   int
+	planar      int
+	tiled       bool
+	chunkWidth  int // 瓦片宽度，条带时为图像宽度
+	chunkHeight int // 瓦片高度或每条带行数
+	offsets     []uint64
+	counts      []uint64
+	size        int64 // 文件大小，未知时为-1
+
+	r      io.ReaderAt
+	closer io.Closer
+
+	mu         sync.Mutex
+	cache      map[int][]byte
+	cacheOrder []int
+	cacheBytes int
+}
+
+// entry IFD条目
+type entry struct {
+	typ   uint16
+	count uint64
+	data  []byte
+}
+
+// Open 打开GeoTIFF文件
+func Open(path string) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := NewReader(fh)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	f.closer = fh
+	return f, nil
+}
+
+// NewReader 从ReaderAt解析GeoTIFF
+func NewReader(r io.ReaderAt) (*File, error) {
+	head := make([]byte, 16)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, ErrNotTIFF
+	}
+
+	f := &File{r: r, size: readerSize(r), cache: map[int][]byte{}}
+	switch string(head[:2]) {
+	case "II":
+		f.order = binary.LittleEndian
+	case "MM":
+		f.order = binary.BigEndian
+	default:
+		return nil, ErrNotTIFF
+	}
+
+	var offset uint64
+	switch f.order.Uint16(head[2:]) {
+	case 42:
+		offset = uint64(f.order.Uint32(head[4:]))
+	case 43:
+		f.bigTIFF = true
+		if f.order.Uint16(head[4:]) != 8 {
+			return nil, ErrNotTIFF
+		}
+		offset = f.order.Uint64(head[8:])
+	default:
+		return nil, ErrNotTIFF
+	}
+
+	// 跳过缩略图、概览和掩膜
+	var ifd map[uint16]*entry
+	for i := 0; offset != 0 && i < maxIFDs; i++ {
+		entries, next, err := f.readIFD(offset)
+		if err != nil {
+			return nil, err
+		}
+		subfile, _ := f.uint(entries, tagNewSubfileType)
+		if subfile&(1|4) == 0 {
+			ifd = entries
+			break
+		}
+		offset = next
+	}
+	if ifd == nil {
+		return nil, fmt.Errorf("geotiff: no full-resolution image found")
+	}
+
+	if err := f.parseImage(ifd); err != nil {
+		return nil, err
+	}
+	f.parseGeoKeys(ifd)
+	f.parseMetadata(ifd)
+	return f, nil
+}
+
+// readerSize 数据源的大小，无法获取时为-1
+func readerSize(r io.ReaderAt) int64 {
+	switch v := r.(type) {
+	case interface{ Size() int64 }:
+		return v.Size()
+	case interface{ Stat() (os.FileInfo, error) }:
+		if info, err := v.Stat(); err == nil {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+// Close 关闭文件
+func (f *File) Close() error {
+	if f.closer != nil {
+		return f.closer.Close()
+	}
+	return nil
+}
+
+// readIFD 读取IFD，返回条目和下一个IFD的偏移
+func (f *File) readIFD(offset uint64) (map[uint16]*entry, uint64, error) {
+	countSize, entrySize, valueSize := 2, 12, 4
+	if f.bigTIFF {
+		countSize, entrySize, valueSize = 8, 20, 8
+	}
+
+	buf := make([]byte, countSize)
+	if _, err := f.r.ReadAt(buf, int64(offset)); err != nil {
+		return nil, 0, fmt.Errorf("geotiff: read IFD: %w", err)
+	}
+	n := uint64(f.order.Uint16(buf))
+	if f.bigTIFF {
+		n = f.order.Uint64(buf)
+	}
+	if n > 4096 {
+		return nil, 0, fmt.Errorf("geotiff: IFD has too many entries (%d)", n)
+	}
+
+	buf = make([]byte, int(n)*entrySize+valueSize)
+	if _, err := f.r.ReadAt(buf, int64(offset)+int64(countSize)); err != nil {
+		return nil, 0, fmt.Errorf("geotiff: read IFD: %w", err)
+	}
+
+	entries := make(map[uint16]*entry, n)
+	for i := 0; i < int(n); i++ {
+		b := buf[i*entrySize:]
+		e := &entry{typ: f.order.Uint16(b[2:])}
+		var value []byte
+		if f.bigTIFF {
+			e.count, value = f.order.Uint64(b[4:]), b[12:20]
+		} else {
+			e.count, value = uint64(f.order.Uint32(b[4:])), b[8:12]
+		}
+
+		size, ok := typeSizes[e.typ]
+		if !ok {
+			continue
+		}
+		total := uint64(size) * e.count
+		if total > 1<<30 {
+			return nil, 0, fmt.Errorf("geotiff: tag %d is too large", f.order.Uint16(b))
+		}
+		if total <= uint64(valueSize) {
+			e.data = append([]byte(nil), value[:total]...)
+		} else {
+			pos := uint64(f.order.Uint32(value))
+			if f.bigTIFF {
+				pos = f.order.Uint64(value)
+			}
+			e.data = make([]byte, total)
+			if _, err := f.r.ReadAt(e.data, int64(pos)); err != nil {
+				return nil, 0, fmt.Errorf("geotiff: read tag %d: %w", f.order.Uint16(b), err)
+			}
+		}
+		entries[f.order.Uint16(b)] = e
+	}
+
+	tail := buf[int(n)*entrySize:]
+	next := uint64(f.order.Uint32(tail))
+	if f.bigTIFF {
+		next = f.order.Uint64(tail)
+	}
+	return entries, next, nil
+}
+
+// values 将条目解析为float64数组
+func (f *File) values(entries map[uint16]*entry, tag uint16) []float64 {
+	e, ok := entries[tag]
+	if !ok {
+		return nil
+	}
+	out := make([]float64, 0, e.count)
+	d := e.data
+	for i := 0; i < int(e.count); i++ {
+		switch e.typ {
+		case typeByte, typeUndefined:
+			out = append(out, float64(d[i]))
+		case typeSByte:
+			out = append(out, float64(int8(d[i])))
+		case typeShort:
+			out = append(out, float64(f.order.Uint16(d[i*2:])))
+		case typeSShort:
+			out = append(out, float64(int16(f.order.Uint16(d[i*2:]))))
+		case typeLong:
+			out = append(out, float64(f.order.Uint32(d[i*4:])))
+		case typeSLong:
+			out = append(out, float64(int32(f.order.Uint32(d[i*4:]))))
+		case typeRational:
+			out = append(out, float64(f.order.Uint32(d[i*8:]))/float64(f.order.Uint32(d[i*8+4:])))
+		case typeSRational:
+			out = append(out, float64(int32(f.order.Uint32(d[i*8:])))/float64(int32(f.order.Uint32(d[i*8+4:]))))
+		case typeFloat:
+			out = append(out, float64(math.Float32frombits(f.order.Uint32(d[i*4:]))))
+		case typeDouble:
+			out = append(out, math.Float64frombits(f.order.Uint64(d[i*8:])))
+		case typeLong8, typeIFD8:
+			out = append(out, float64(f.order.Uint64(d[i*8:])))
+		case typeSLong8:
+			out = append(out, float64(int64(f.order.Uint64(d[i*8:]))))
+		}
+	}
+	return out
+}
+
+// uints 将整数条目解析为uint64数组(用于偏移，避免float64精度损失)
+func (f *File) uints(entries map[uint16]*entry, tag uint16) []uint64 {
+	e, ok := entries[tag]
+	if !ok {
+		return nil
+	}
+	out := make([]uint64, e.count)
+	for i := range out {
+		switch e.typ {
+		case typeShort:
+			out[i] = uint64(f.order.Uint16(e.data[i*2:]))
+		case typeLong:
+			out[i] = uint64(f.order.Uint32(e.data[i*4:]))
+		case typeLong8, typeIFD8:
+			out[i] = f.order.Uint64(e.data[i*8:])
+		default:
+			return nil
+		}
+	}
+	return out
+}
+
+// uint 读取单值整数条目
+func (f *File) uint(entries map[uint16]*entry, tag uint16) (int, bool) {
+	v := f.values(entries, tag)
+	if len(v) == 0 {
+		return 0, false
+	}
+	return int(v[0]), true
+}
+
+// ascii 读取字符串条目
+func ascii(entries map[uint16]*entry, tag uint16) string {
+	e, ok := entries[tag]
+	if !ok || e.typ != typeASCII {
+		return ""
+	}
+	return strings.TrimRight(string(e.data), "\x00 ")
+}
+
+// parseImage 解析图像结构
+func (f *File) parseImage(ifd map[uint16]*entry) error {
+	var ok bool
+	if f.Width, ok = f.uint(ifd, tagImageWidth); !ok {
+		return fmt.Errorf("geotiff: missing ImageWidth")
+	}
+	if f.Height, ok = f.uint(ifd, tagImageLength); !ok {
+		return fmt.Errorf("geotiff: missing ImageLength")
+	}
+	if f.Bands, ok = f.uint(ifd, tagSamplesPerPixel); !ok {
+		f.Bands = 1
+	}
+	if f.Width <= 0 || f.Height <= 0 || f.Bands <= 0 {
+		return fmt.Errorf("geotiff: invalid image size %dx%dx%d", f.Width, f.Height, f.Bands)
+	}
+
+	f.BitsPerSample = 1
+	if bits := f.values(ifd, tagBitsPerSample); len(bits) > 0 {
+		f.BitsPerSample = int(bits[0])
+		for _, b := range bits {
+			if int(b) != f.BitsPerSample {
+				return fmt.Errorf("%w: mixed bits per sample", ErrUnsupported)
+			}
+		}
+	}
+	f.SampleFormat = SampleUint
+	if formats := f.values(ifd, tagSampleFormat); len(formats) > 0 {
+		f.SampleFormat = int(formats[0])
+	}
+	switch {
+	case f.SampleFormat == SampleFloat && (f.BitsPerSample == 32 || f.BitsPerSample == 64):
+	case (f.SampleFormat == SampleUint || f.SampleFormat == SampleInt) &&
+		(f.BitsPerSample == 8 || f.BitsPerSample == 16 || f.BitsPerSample == 32 || f.BitsPerSample == 64):
+	default:
+		return fmt.Errorf("%w: %d-bit samples of format %d", ErrUnsupported, f.BitsPerSample, f.SampleFormat)
+	}
+
+	if f.compression, ok = f.uint(ifd, tagCompression); !ok {
+		f.compression = compressionNone
+	}
+	switch f.compression {
+	case compressionNone, compressionLZW, compressionDeflate, compressionDeflate2, compressionPackBits:
+	default:
+		return fmt.Errorf("%w: compression %d", ErrUnsupported, f.compression)
+	}
+	if f.predictor, ok = f.uint(ifd, tagPredictor); !ok {
+		f.predictor = 1
+	}
+	if f.predictor < 1 || f.predictor > 3 {
+		return fmt.Errorf("%w: predictor %d", ErrUnsupported, f.predictor)
+	}
+	if f.planar, ok = f.uint(ifd, tagPlanarConfig); !ok {
+		f.planar = 1
+	}
+
+	if _, f.tiled = ifd[tagTileWidth]; f.tiled {
+		f.chunkWidth, _ = f.uint(ifd, tagTileWidth)
+		f.chunkHeight, _ = f.uint(ifd, tagTileLength)
+		f.offsets = f.uints(ifd, tagTileOffsets)
+		f.counts = f.uints(ifd, tagTileByteCounts)
+	} else {
+		f.chunkWidth = f.Width
+		if f.chunkHeight, ok = f.uint(ifd, tagRowsPerStrip); !ok || f.chunkHeight > f.Height {
+			f.chunkHeight = f.Height
+		}
+		f.offsets = f.uints(ifd, tagStripOffsets)
+		f.counts = f.uints(ifd, tagStripByteCounts)
+	}
+	if f.chunkWidth <= 0 || f.chunkHeight <= 0 {
+		return fmt.Errorf("geotiff: invalid tile size %dx%d", f.chunkWidth, f.chunkHeight)
+	}
+
+	planes := 1
+	if f.planar == 2 {
+		planes = f.Bands
+	}
+	// 按浮点计算块数和块大小，避免异常的尺寸使整数溢出
+	if blocks := float64(f.chunksAcross()) * float64(f.chunksDown()) * float64(planes); blocks > float64(len(f.offsets)) || blocks > float64(len(f.counts)) {
+		return fmt.Errorf("geotiff: expected %.0f data blocks, found %d", blocks, len(f.offsets))
+	}
+	want := f.chunksAcross() * f.chunksDown() * planes
+
+	// 数据块的字节数和位置来自文件本身，须在读取前检查，避免按异常值分配内存
+	samples := f.Bands
+	if f.planar == 2 {
+		samples = 1
+	}
+	if float64(f.chunkWidth)*float64(f.chunkHeight)*float64(samples)*float64(f.BitsPerSample/8) > maxBlockBytes {
+		return fmt.Errorf("%w: data block of %dx%d pixels is too large", ErrUnsupported, f.chunkWidth, f.chunkHeight)
+	}
+	block := uint64(f.chunkWidth * f.chunkHeight * samples * f.BitsPerSample / 8)
+	limit := block + block/2 + blockOverhead
+	for i := 0; i < want; i++ {
+		if f.counts[i] > limit {
+			return fmt.Errorf("geotiff: data block %d has invalid byte count %d", i, f.counts[i])
+		}
+		if f.size >= 0 && f.counts[i] > 0 && (f.offsets[i] > uint64(f.size) || f.counts[i] > uint64(f.size)-f.offsets[i]) {
+			return fmt.Errorf("geotiff: data block %d at offset %d exceeds file size %d", i, f.offsets[i], f.size)
+		}
+	}
+	return nil
+}
+
+// parseGeoKeys 解析地理参考: 仿射变换、模型类型和EPSG代码
+func (f *File) parseGeoKeys(ifd map[uint16]*entry) {
+	rasterType := 1
+	if keys := f.values(ifd, tagGeoKeyDirectory); len(keys) >= 4 {
+		n := int(keys[3])
+		for i := 0; i < n && 4+i*4+3 < len(keys); i++ {
+			id, location, value := int(keys[4+i*4]), int(keys[4+i*4+1]), int(keys[4+i*4+3])
+			if location != 0 {
+				// 仅需要直接存储的SHORT值
+				continue
+			}
+			switch id {
+			case keyModelType:
+				f.ModelType = value
+			case keyRasterType:
+				rasterType = value
+			case keyGeographicType:
+				if f.EPSG == 0 && value > 0 && value < 32767 {
+					f.EPSG = value
+				}
+			case keyProjectedType:
+				if value > 0 && value < 32767 {
+					f.EPSG = value
+				}
+			}
+		}
+	}
+
+	if m := f.values(ifd, tagModelTransform); len(m) >= 16 {
+		f.Transform = [6]float64{m[3], m[0], m[1], m[7], m[4], m[5]}
+		f.Georeferenced = true
+	} else if tie, scale := f.values(ifd, tagModelTiepoint), f.values(ifd, tagModelPixelScale); len(tie) >= 6 && len(scale) >= 2 {
+		f.Transform = [6]float64{tie[3] - tie[0]*scale[0], scale[0], 0, tie[4] + tie[1]*scale[1], 0, -scale[1]}
+		f.Georeferenced = true
+	}
+
+	// 坐标指向像元中心时平移半个像元，统一为角点约定
+	if f.Georeferenced && rasterType == rasterPixelIsPoint {
+		t := &f.Transform
+		t[0] -= (t[1] + t[2]) / 2
+		t[3] -= (t[4] + t[5]) / 2
+	}
+}
+
+// gdalMetadata GDAL_METADATA标签的XML结构
+type gdalMetadata struct {
+	Items []struct {
+		Name   string `xml:"name,attr"`
+		Sample string `xml:"sample,attr"`
+		Role   string `xml:"role,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"Item"`
+}
+
+// parseMetadata 解析GDAL元数据和无效值
+func (f *File) parseMetadata(ifd map[uint16]*entry) {
+	f.Metadata = map[string]string{}
+	f.BandMetadata = make([]map[string]string, f.Bands)
+	for i := range f.BandMetadata {
+		f.BandMetadata[i] = map[string]string{}
+	}
+
+	var md gdalMetadata
+	if s := ascii(ifd, tagGDALMetadata); s != "" && xml.Unmarshal([]byte(s), &md) == nil {
+		for _, item := range md.Items {
+			key := item.Name
+			if item.Role != "" {
+				key = item.Role
+			}
+			value := strings.TrimSpace(item.Value)
+			if item.Sample == "" {
+				f.Metadata[key] = value
+				continue
+			}
+			if band, err := strconv.Atoi(item.Sample); err == nil && band >= 0 && band < f.Bands {
+				f.BandMetadata[band][key] = value
+			}
+		}
+	}
+
+	if s := ascii(ifd, tagGDALNoData); s != "" {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			f.NoData, f.HasNoData = v, true
+		}
+	}
+}
+
+// Geographic 是否为经纬度坐标系
+func (f *File) Geographic() bool {
+	if f.ModelType != 0 {
+		return f.ModelType == ModelGeographic
+	}
+	if f.EPSG != 0 {
+		return f.EPSG == 4326 || f.EPSG == 4490 || f.EPSG == 4269 || f.EPSG == 4258
+	}
+	// 缺少地理键时按坐标范围判断
+	t := f.Transform
+	x0, x1 := t[0], t[0]+float64(f.Width)*t[1]+float64(f.Height)*t[2]
+	y0, y1 := t[3], t[3]+float64(f.Width)*t[4]+float64(f.Height)*t[5]
+	return f.Georeferenced && math.Abs(x0) <= 360 && math.Abs(x1) <= 360 && math.Abs(y0) <= 90.001 && math.Abs(y1) <= 90.001
+}
+
+// WebMercator 是否为Web墨卡托投影
+func (f *File) WebMercator() bool {
+	switch f.EPSG {
+	case 3857, 3785, 900913, 102100, 102113:
+		return true
+	}
+	return false
+}
+
+// Rotated 仿射变换是否含旋转项
+func (f *File) Rotated() bool {
+	return f.Transform[2] != 0 || f.Transform[4] != 0
+}
+
+// PixelCenter 像元中心的模型坐标
+func (f *File) PixelCenter(row, col int) (x, y float64) {
+	c, r := float64(col)+0.5, float64(row)+0.5
+	t := f.Transform
+	return t[0] + c*t[1] + r*t[2], t[3] + c*t[4] + r*t[5]
+}
+
+// ReadBand 读取波段(从0开始)在窗口内的值，按行存放；不做无效值处理
+func (f *File) ReadBand(band, row, col, rows, cols int) ([]float64, error) {
+	if band < 0 || band >= f.Bands || row < 0 || col < 0 || rows < 0 || cols < 0 ||
+		row+rows > f.Height || col+cols > f.Width {
+		return nil, ErrInvalidWindow
+	}
+
+	out := make([]float64, rows*cols)
+	bytesPerSample := f.BitsPerSample / 8
+	samples := f.Bands
+	if f.planar == 2 {
+		samples = 1
+	}
+
+	across := f.chunksAcross()
+	for cy := row / f.chunkHeight; cy*f.chunkHeight < row+rows; cy++ {
+		for cx := col / f.chunkWidth; cx*f.chunkWidth < col+cols; cx++ {
+			index := cy*across + cx
+			sample := band
+			if f.planar == 2 {
+				index += band * across * f.chunksDown()
+				sample = 0
+			}
+			data, err := f.chunk(index, cy)
+			if err != nil {
+				return nil, err
+			}
+
+			// 数据块与窗口的交集
+			r0, r1 := maxInt(row, cy*f.chunkHeight), minInt(row+rows, (cy+1)*f.chunkHeight)
+			c0, c1 := maxInt(col, cx*f.chunkWidth), minInt(col+cols, (cx+1)*f.chunkWidth)
+			for r := r0; r < r1; r++ {
+				base := ((r-cy*f.chunkHeight)*f.chunkWidth - cx*f.chunkWidth) * samples
+				for c := c0; c < c1; c++ {
+					pos := (base + c*samples + sample) * bytesPerSample
+					if pos+bytesPerSample > len(data) {
+						out[(r-row)*cols+c-col] = math.NaN()
+						continue
+					}
+					out[(r-row)*cols+c-col] = f.sample(data[pos:])
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// sample 解码一个采样值
+func (f *File) sample(b []byte) float64 {
+	switch f.SampleFormat {
+	case SampleFloat:
+		if f.BitsPerSample == 32 {
+			return float64(math.Float32frombits(f.order.Uint32(b)))
+		}
+		return math.Float64frombits(f.order.Uint64(b))
+	case SampleInt:
+		switch f.BitsPerSample {
+		case 8:
+			return float64(int8(b[0]))
+		case 16:
+			return float64(int16(f.order.Uint16(b)))
+		case 32:
+			return float64(int32(f.order.Uint32(b)))
+		default:
+			return float64(int64(f.order.Uint64(b)))
+		}
+	default:
+		switch f.BitsPerSample {
+		case 8:
+			return float64(b[0])
+		case 16:
+			return float64(f.order.Uint16(b))
+		case 32:
+			return float64(f.order.Uint32(b))
+		default:
+			return float64(f.order.Uint64(b))
+		}
+	}
+}
+
+// TypeName 采样类型名称，与NetCDF的CDL类型名一致
+func (f *File) TypeName() string {
+	switch f.SampleFormat {
+	case SampleFloat:
+		if f.BitsPerSample == 32 {
+			return "float"
+		}
+		return "double"
+	case SampleInt:
+		return map[int]string{8: "byte", 16: "short", 32: "int", 64: "int64"}[f.BitsPerSample]
+	}
+	return map[int]string{8: "ubyte", 16: "ushort", 32: "uint", 64: "uint64"}[f.BitsPerSample]
+}
+
+// chunksAcross 每行的数据块数
+func (f *File) chunksAcross() int {
+	return (f.Width + f.chunkWidth - 1) / f.chunkWidth
+}
+
+// chunksDown 每列的数据块数
+func (f *File) chunksDown() int {
+	return (f.Height + f.chunkHeight - 1) / f.chunkHeight
+}
+
+// minInt 较小值
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt 较大值
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,131 @@
+package geotiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bigTIFF 构造2×2的单条带BigTIFF(8位无符号，无压缩)，条带的偏移和字节数由参数指定，像元数据紧跟在IFD之后
+func bigTIFF(offset, count uint64) []byte {
+	le := binary.LittleEndian
+	buf := make([]byte, 16)
+	copy(buf, "II")
+	le.PutUint16(buf[2:], 43)
+	le.PutUint16(buf[4:], 8)
+	le.PutUint64(buf[8:], 16)
+
+	tags := []struct {
+		tag   uint16
+		typ   uint16
+		value uint64
+	}{
+		{tagImageWidth, typeShort, 2},
+		{tagImageLength, typeShort, 2},
+		{tagBitsPerSample, typeShort, 8},
+		{tagStripOffsets, typeLong8, offset},
+		{tagRowsPerStrip, typeShort, 2},
+		{tagStripByteCounts, typeLong8, count},
+	}
+	ifd := make([]byte, 8+len(tags)*20+8)
+	le.PutUint64(ifd, uint64(len(tags)))
+	for i, t := range tags {
+		e := ifd[8+i*20:]
+		le.PutUint16(e, t.tag)
+		le.PutUint16(e[2:], t.typ)
+		le.PutUint64(e[4:], 1)
+		le.PutUint64(e[12:], t.value)
+	}
+	buf = append(buf, ifd...)
+	return append(buf, 1, 2, 3, 4)
+}
+
+// sizeless 不提供文件大小的ReaderAt
+type sizeless struct {
+	r *bytes.Reader
+}
+
+func (s sizeless) ReadAt(p []byte, off int64) (int, error) {
+	return s.r.ReadAt(p, off)
+}
+
+func TestNewReaderBlocks(t *testing.T) {
+	data := bigTIFF(0, 0)
+	pixels := uint64(len(data) - 4)
+	data = bigTIFF(pixels, 4)
+
+	f, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	values, err := f.ReadBand(0, 0, 0, 2, 2)
+	if err != nil {
+		t.Fatalf("ReadBand: %v", err)
+	}
+	for i, want := range []float64{1, 2, 3, 4} {
+		if values[i] != want {
+			t.Errorf("value %d = %g, want %g", i, values[i], want)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		offset uint64
+		count  uint64
+		sized  bool
+		want   string
+	}{
+		{"huge byte count", pixels, 1 << 62, true, "invalid byte count"},
+		{"huge byte count without size", pixels, 1 << 62, false, "invalid byte count"},
+		{"block past end of file", pixels, 8, true, "exceeds file size"},
+		{"offset past end of file", 1 << 40, 4, true, "exceeds file size"},
+		{"offset overflow", math.MaxUint64 - 1, 4, true, "exceeds file size"},
+	}
+	for _, tt := range tests {
+		var r io.ReaderAt = bytes.NewReader(bigTIFF(tt.offset, tt.count))
+		if !tt.sized {
+			r = sizeless{bytes.NewReader(bigTIFF(tt.offset, tt.count))}
+		}
+		_, err := NewReader(r)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	// 随机性强的数据压缩后大于原始大小，检查不会被数据块字节数检查误拒
+	width, height := 300, 200
+	band := make([]float64, width*height)
+	x := uint32(1)
+	for i := range band {
+		x = x*1664525 + 1013904223
+		band[i] = float64(math.Float32frombits(x>>9 | 0x3f800000))
+	}
+	band[5] = math.NaN()
+
+	path := filepath.Join(t.TempDir(), "roundtrip.tif")
+	err := Write(path, &Raster{Width: width, Height: height, Bands: [][]float64{band},
+		Transform: [6]float64{100, 0.1, 0, 40, 0, -0.1}, NoData: math.NaN()})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	values, err := f.ReadBand(0, 0, 0, height, width)
+	if err != nil {
+		t.Fatalf("ReadBand: %v", err)
+	}
+	for i, want := range band {
+		if values[i] != want && !(math.IsNaN(want) && math.IsNaN(values[i])) {
+			t.Fatalf("value %d = %g, want %g", i, values[i], want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// stripBytes 写入时每个条带的目标字节数
+const stripBytes = 64 << 10
+
+// Raster 待写入的栅格，各波段写为32位浮点
+type Raster struct {
+	Width  int
+	Height int
+	// Bands 各波段数据，按行存放，第一行对应Transform原点所在行(通常为最北)
+	Bands [][]float64
+	// Transform 仿射变换，约定同File.Transform
+	Transform [6]float64
+	// EPSG 坐标参考系，0表示EPSG:4326
+	EPSG int
+	// NoData 无效值，NaN写为该值；为NaN时保留NaN
+	NoData float64
+	// Descriptions、Units 各波段的描述和单位，可为空
+	Descriptions []string
+	Units        []string
+	// Metadata 数据集级元数据
+	Metadata map[string]string
+}
+
+// tiffField 待写入的IFD条目
+type tiffField struct {
+	tag   uint16
+	typ   uint16
+	count int
+	data  []byte
+}
+
+// Write 将栅格写为GeoTIFF文件(小端经典TIFF，Deflate压缩，多波段按平面存放)
+func Write(path string, r *Raster) error {
+	if r.Width <= 0 || r.Height <= 0 || len(r.Bands) == 0 {
+		return fmt.Errorf("geotiff: empty raster")
+	}
+	for i, band := range r.Bands {
+		if len(band) != r.Width*r.Height {
+			return fmt.Errorf("geotiff: band %d has %d values, expected %d", i, len(band), r.Width*r.Height)
+		}
+	}
+	epsg := r.EPSG
+	if epsg == 0 {
+		epsg = 4326
+	}
+
+	order := binary.LittleEndian
+	rowsPerStrip := stripBytes / (r.Width * 4)
+	if rowsPerStrip < 1 {
+		rowsPerStrip = 1
+	}
+	if rowsPerStrip > r.Height {
+		rowsPerStrip = r.Height
+	}
+
+	// 文件头后依次为条带数据、条目的外部数据和IFD
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'I', 42, 0, 0, 0, 0, 0})
+
+	var offsets, counts []uint32
+	row := make([]byte, r.Width*4)
+	for _, band := range r.Bands {
+		for y := 0; y < r.Height; y += rowsPerStrip {
+			var strip bytes.Buffer
+			zw := zlib.NewWriter(&strip)
+			for yy := y; yy < y+rowsPerStrip && yy < r.Height; yy++ {
+				for x := 0; x < r.Width; x++ {
+					v := band[yy*r.Width+x]
+					if math.IsNaN(v) && !math.IsNaN(r.NoData) {
+						v = r.NoData
+					}
+					order.PutUint32(row[x*4:], math.Float32bits(float32(v)))
+				}
+				zw.Write(row)
+			}
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			offsets = append(offsets, uint32(buf.Len()))
+			counts = append(counts, uint32(strip.Len()))
+			buf.Write(strip.Bytes())
+			if buf.Len()%2 == 1 {
+				buf.WriteByte(0)
+			}
+		}
+	}
+	if buf.Len() > math.MaxUint32/2 {
+		return fmt.Errorf("geotiff: raster is too large for classic TIFF")
+	}
+
+	bands := len(r.Bands)
+	shorts := func(values ...int) []byte {
+		b := make([]byte, len(values)*2)
+		for i, v := range values {
+			order.PutUint16(b[i*2:], uint16(v))
+		}
+		return b
+	}
+	repeat := func(v, n int) []int {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = v
+		}
+		return out
+	}
+	longs := func(values []uint32) []byte {
+		b := make([]byte, len(values)*4)
+		for i, v := range values {
+			order.PutUint32(b[i*4:], v)
+		}
+		return b
+	}
+	doubles := func(values ...float64) []byte {
+		b := make([]byte, len(values)*8)
+		for i, v := range values {
+			order.PutUint64(b[i*8:], math.Float64bits(v))
+		}
+		return b
+	}
+	asciiz := func(s string) []byte {
+		return append([]byte(s), 0)
+	}
+
+	planar := 1
+	if bands > 1 {
+		planar = 2
+	}
+	fields := []tiffField{
+		{tagImageWidth, typeLong, 1, longs([]uint32{uint32(r.Width)})},
+		{tagImageLength, typeLong, 1, longs([]uint32{uint32(r.Height)})},
+		{tagBitsPerSample, typeShort, bands, shorts(repeat(32, bands)...)},
+		{tagCompression, typeShort, 1, shorts(compressionDeflate)},
+		{tagPhotometric, typeShort, 1, shorts(1)},
+		{tagStripOffsets, typeLong, len(offsets), longs(offsets)},
+		{tagSamplesPerPixel, typeShort, 1, shorts(bands)},
+		{tagRowsPerStrip, typeLong, 1, longs([]uint32{uint32(rowsPerStrip)})},
+		{tagStripByteCounts, typeLong, len(counts), longs(counts)},
+		{tagPlanarConfig, typeShort, 1, shorts(planar)},
+		{tagSampleFormat, typeShort, bands, shorts(repeat(SampleFloat, bands)...)},
+	}
+	if bands > 1 {
+		fields = append(fields, tiffField{tagExtraSamples, typeShort, bands - 1, shorts(repeat(0, bands-1)...)})
+	}
+
+	t := r.Transform
+	if t[2] == 0 && t[4] == 0 {
+		fields = append(fields,
+			tiffField{tagModelPixelScale, typeDouble, 3, doubles(t[1], -t[5], 0)},
+			tiffField{tagModelTiepoint, typeDouble, 6, doubles(0, 0, 0, t[0], t[3], 0)})
+	} else {
+		fields = append(fields, tiffField{tagModelTransform, typeDouble, 16, doubles(
+			t[1], t[2], 0, t[0],
+			t[4], t[5], 0, t[3],
+			0, 0, 0, 0,
+			0, 0, 0, 1)})
+	}
+
+	keys := []int{1, 1, 0, 0}
+	if epsg == 4326 || epsg == 4490 || epsg == 4269 || epsg == 4258 {
+		keys = append(keys, keyModelType, 0, 1, ModelGeographic, keyRasterType, 0, 1, 1,
+			keyGeographicType, 0, 1, epsg, keyAngularUnits, 0, 1, 9102)
+	} else {
+		keys = append(keys, keyModelType, 0, 1, ModelProjected, keyRasterType, 0, 1, 1,
+			keyProjectedType, 0, 1, epsg)
+	}
+	keys[3] = (len(keys) - 4) / 4
+	fields = append(fields, tiffField{tagGeoKeyDirectory, typeShort, len(keys), shorts(keys...)})
+
+	if md := gdalMetadataXML(r); md != "" {
+		fields = append(fields, tiffField{tagGDALMetadata, typeASCII, len(md) + 1, asciiz(md)})
+	}
+	noData := "nan"
+	if !math.IsNaN(r.NoData) {
+		noData = strconv.FormatFloat(r.NoData, 'g', -1, 64)
+	}
+	fields = append(fields, tiffField{tagGDALNoData, typeASCII, len(noData) + 1, asciiz(noData)})
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].tag < fields[j].tag })
+
+	// 超过4字节的值写在IFD之前
+	valueOffsets := make([]uint32, len(fields))
+	for i, f := range fields {
+		if len(f.data) > 4 {
+			valueOffsets[i] = uint32(buf.Len())
+			buf.Write(f.data)
+			if buf.Len()%2 == 1 {
+				buf.WriteByte(0)
+			}
+		}
+	}
+
+	ifdOffset := uint32(buf.Len())
+	ifd := make([]byte, 2+len(fields)*12+4)
+	order.PutUint16(ifd, uint16(len(fields)))
+	for i, f := range fields {
+		e := ifd[2+i*12:]
+		order.PutUint16(e, f.tag)
+		order.PutUint16(e[2:], f.typ)
+		order.PutUint32(e[4:], uint32(f.count))
+		if len(f.data) > 4 {
+			order.PutUint32(e[8:], valueOffsets[i])
+		} else {
+			copy(e[8:12], f.data)
+		}
+	}
+	buf.Write(ifd)
+
+	out := buf.Bytes()
+	order.PutUint32(out[4:], ifdOffset)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// gdalMetadataXML 生成GDAL_METADATA标签内容，无元数据时返回空
+func gdalMetadataXML(r *Raster) string {
+	type item struct {
+		XMLName xml.Name `xml:"Item"`
+		Name    string   `xml:"name,attr"`
+		Sample  string   `xml:"sample,attr,omitempty"`
+		Role    string   `xml:"role,attr,omitempty"`
+		Value   string   `xml:",chardata"`
+	}
+	var items []item
+
+	keys := make([]string, 0, len(r.Metadata))
+	for k := range r.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		items = append(items, item{Name: k, Value: r.Metadata[k]})
+	}
+	for i := range r.Bands {
+		sample := strconv.Itoa(i)
+		if i < len(r.Descriptions) && r.Descriptions[i] != "" {
+			items = append(items, item{Name: "DESCRIPTION", Sample: sample, Role: "description", Value: r.Descriptions[i]})
+		}
+		if i < len(r.Units) && r.Units[i] != "" {
+			items = append(items, item{Name: "UNITTYPE", Sample: sample, Role: "unittype", Value: r.Units[i]})
+		}
+	}
+	if len(items) == 0 {
+		return ""
+	}
+
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"GDALMetadata"`
+		Items   []item
+	}{Items: items})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
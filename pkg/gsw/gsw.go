@@ -0,0 +1,271 @@
+// Package gsw 实现TEOS-10海水热力学方程(Gibbs SeaWater工具箱)的核心函数:
+// 绝对盐度、保守温度、位温、密度(75项多项式)、热膨胀/盐收缩系数、声速、浮力频率及深度-压力换算。
+// 函数命名与参数顺序与GSW工具箱一致: SA为绝对盐度(g/kg)，SP为实用盐度，CT为保守温度(°C)，
+// t为现场温度(ITS-90, °C)，pt为位温(°C)，p为海压(dbar，海面为0)，lat为纬度(度)。
+// 计算结果与GSW 3.05工具箱文档中的检验数据一致(绝对盐度不含δSA，见SAFromSP)
+package gsw
+
+import "math"
+
+// TEOS-10常数
+const (
+	// SSO 标准海水的绝对盐度(g/kg)
+	SSO = 35.16504
+	// UPS 实用盐度到参考盐度的换算系数(g/kg)
+	UPS = SSO / 35
+	// CP0 位焓与保守温度之间的比热(J/(kg K))
+	CP0 = 3991.86795711963
+	// T0 摄氏零度对应的开尔文温度
+	T0 = 273.15
+	// DB2Pa dbar换算为Pa
+	DB2Pa = 1e4
+
+	sfac   = 0.0248826675584615 // 1/(40*UPS)
+	offset = 5.971840214030754e-1
+	gamma  = 2.26e-7 // 重力随高度的变化率(1/m)
+)
+
+// SRFromSP 由实用盐度计算参考盐度(g/kg)
+func SRFromSP(sp float64) float64 {
+	return sp * UPS
+}
+
+// SAFromSP 由实用盐度计算绝对盐度(g/kg)。未内置绝对盐度异常δSA的全球查找表，
+// 按δSA = 0取参考盐度，开阔大洋的误差一般小于0.02 g/kg；p、lon、lat保留以与GSW接口一致
+func SAFromSP(sp, p, lon, lat float64) float64 {
+	if math.IsNaN(sp) {
+		return math.NaN()
+	}
+	return SRFromSP(math.Max(sp, 0))
+}
+
+// SPFromSA SAFromSP的逆运算
+func SPFromSA(sa, p, lon, lat float64) float64 {
+	return sa / UPS
+}
+
+// CTFromPt 由位温(参考压力0 dbar)计算保守温度
+func CTFromPt(sa, pt float64) float64 {
+	sa = math.Max(sa, 0)
+	x2 := sfac * sa
+	x := math.Sqrt(x2)
+	y := pt * 0.025
+
+	potEnthalpy := 61.01362420681071 + y*(168776.46138048015+
+		y*(-2735.2785605119625+y*(2574.2164453821433+
+			y*(-1536.6644434977543+y*(545.7340497931629+
+				(-50.91091728474331-18.30489878927802*y)*y))))) +
+		x2*(268.5520265845071+y*(-12019.028203559312+
+			y*(3734.858026725145+y*(-2046.7671145057618+
+				y*(465.28655623826234+(-0.6370820302376359-
+					10.650848542359153*y)*y))))+
+			x*(937.2099110620707+y*(588.1802812170108+
+				y*(248.39476522971285+(-3.871557904936333-
+					2.6268019854268356*y)*y))+
+				x*(-1687.914374187449+x*(246.9598888781377+
+					x*(123.59576582457964-48.5891069025409*x))+
+					y*(936.3206544460336+
+						y*(-942.7827304544439+y*(369.4389437509002+
+							(-33.83664947895248-9.987880382780322*y)*y))))))
+	return potEnthalpy / CP0
+}
+
+// PtFromCT 由保守温度计算位温(参考压力0 dbar)，牛顿迭代求CTFromPt的逆
+func PtFromCT(sa, ct float64) float64 {
+	if math.IsNaN(sa) || math.IsNaN(ct) {
+		return math.NaN()
+	}
+	pt := ct
+	for i := 0; i < 4; i++ {
+		const h = 1e-3
+		f := CTFromPt(sa, pt) - ct
+		df := (CTFromPt(sa, pt+h) - CTFromPt(sa, pt-h)) / (2 * h)
+		pt -= f / df
+	}
+	return pt
+}
+
+// Pt0FromT 由现场温度计算参考压力为0 dbar的位温，McDougall等(2003)初值加两次改进牛顿迭代
+func Pt0FromT(sa, t, p float64) float64 {
+	if math.IsNaN(sa) || math.IsNaN(t) || math.IsNaN(p) {
+		return math.NaN()
+	}
+	sa = math.Max(sa, 0)
+	s1 := sa / UPS
+
+	pt0 := t + p*(8.65483913395442e-6-
+		s1*1.41636299744881e-6-
+		p*7.38286467135737e-9+
+		t*(-8.38241357039698e-6+
+			s1*2.83933368585534e-8+
+			t*1.77803965218656e-8+
+			p*1.71155619208233e-10))
+
+	dentropyDt := CP0 / ((T0 + pt0) * (1 - 0.05*(1-sa/SSO)))
+	trueEntropyPart := entropyPart(sa, t, p)
+	for i := 0; i < 2; i++ {
+		old := pt0
+		dentropy := entropyPartZeroP(sa, old) - trueEntropyPart
+		pt0 = old - dentropy/dentropyDt
+		ptm := 0.5 * (pt0 + old)
+		dentropyDt = -gibbsPt0Pt0(sa, ptm)
+		pt0 = old - dentropy/dentropyDt
+	}
+	return pt0
+}
+
+// CTFromT 由现场温度计算保守温度
+func CTFromT(sa, t, p float64) float64 {
+	return CTFromPt(sa, Pt0FromT(sa, t, p))
+}
+
+// TFromCT 由保守温度计算现场温度，牛顿迭代求Pt0FromT的逆
+func TFromCT(sa, ct, p float64) float64 {
+	pt0 := PtFromCT(sa, ct)
+	if math.IsNaN(pt0) || math.IsNaN(p) {
+		return math.NaN()
+	}
+	t := pt0
+	for i := 0; i < 4; i++ {
+		const h = 1e-3
+		f := Pt0FromT(sa, t, p) - pt0
+		df := (Pt0FromT(sa, t+h, p) - Pt0FromT(sa, t-h, p)) / (2 * h)
+		t -= f / df
+	}
+	return t
+}
+
+// entropyPart 比熵中除盐度线性项以外的部分，由Gibbs函数对温度求导得到
+func entropyPart(sa, t, p float64) float64 {
+	x2 := sfac * sa
+	x := math.Sqrt(x2)
+	y := t * 0.025
+	z := p * 1e-4
+
+	g03 := z*(-270.983805184062+
+		z*(776.153611613101+z*(-196.51255088122+(28.9796526294175-2.13290083518327*z)*z))) +
+		y*(-24715.571866078+z*(2910.0729080936+
+			z*(-1513.116771538718+z*(546.959324647056+z*(-111.1208127634436+8.68841343834394*z))))+
+			y*(2210.2236124548363+z*(-2017.52334943521+
+				z*(1498.081172457456+z*(-718.6359919632359+(146.4037555781616-4.9892131862671505*z)*z)))+
+				y*(-592.743745734632+z*(1591.873781627888+
+					z*(-1207.261522487504+(608.785486935364-105.4993508931208*z)*z))+
+					y*(290.12956292128547+z*(-973.091553087975+
+						z*(602.603274510125+z*(-276.361526170076+32.40953340386105*z)))+
+						y*(-113.90630790850321+y*(21.35571525415769-67.41756835751434*z)+
+							z*(381.06836198507096+z*(-133.7383902842754+49.023632509086724*z)))))))
+
+	g08 := x2 * (z*(729.116529735046+
+		z*(-343.956902961561+z*(124.687671116248+z*(-31.656964386073+7.04658803315449*z)))) +
+		x*(x*(y*(-137.1145018408982+y*(148.10030845687618+
+			y*(-68.5590309679152+12.4848504784754*y)))-
+			22.6683558512829*z)+z*(-175.292041186547+(83.1923927801819-29.483064349429*z)*z)+
+			y*(-86.1329351956084+z*(766.116132004952+z*(-108.3834525034224+51.2796974779828*z))+
+				y*(-30.0682112585625-1380.9597954037708*z+
+					y*(3.50240264723578+938.26075044542*z)))) +
+		y*(1760.062705994408+y*(-675.802947790203+
+			y*(365.7041791005036+y*(-108.30162043765552+12.78101825083098*y)+
+				z*(-1190.914967948748+(298.904564555024-145.9491676006352*z)*z))+
+			z*(2082.7344423998043+z*(-614.668925894709+(340.685093521782-33.3848202979239*z)*z)))+
+			z*(-1721.528607567954+z*(674.819060538734+
+				z*(-356.629112415276+(88.4080716616-15.84003094423364*z)*z)))))
+
+	return -(g03 + g08) * 0.025
+}
+
+// entropyPartZeroP p = 0时的entropyPart
+func entropyPartZeroP(sa, pt0 float64) float64 {
+	x2 := sfac * sa
+	x := math.Sqrt(x2)
+	y := pt0 * 0.025
+
+	g03 := y * (-24715.571866078 + y*(2210.2236124548363+y*(-592.743745734632+y*(290.12956292128547+
+		y*(-113.90630790850321+y*21.35571525415769)))))
+	g08 := x2 * (x*(x*(y*(-137.1145018408982+y*(148.10030845687618+
+		y*(-68.5590309679152+12.4848504784754*y))))+
+		y*(-86.1329351956084+y*(-30.0682112585625+y*3.50240264723578))) +
+		y*(1760.062705994408+y*(-675.802947790203+
+			y*(365.7041791005036+y*(-108.30162043765552+12.78101825083098*y)))))
+	return -(g03 + g08) * 0.025
+}
+
+// gibbsPt0Pt0 p = 0时Gibbs函数对温度的二阶导数
+func gibbsPt0Pt0(sa, pt0 float64) float64 {
+	x2 := sfac * sa
+	x := math.Sqrt(x2)
+	y := pt0 * 0.025
+
+	g03 := -24715.571866078 + y*(4420.4472249096725+y*(-1778.231237203896+
+		y*(1160.5182516851419+y*(-569.531539542516+y*128.13429152494615))))
+	g08 := x2 * (1760.062705994408 + x*(-86.1329351956084+
+		x*(-137.1145018408982+y*(296.20061691375236+
+			y*(-205.67709290374563+49.9394019139016*y)))+
+		y*(-60.136422517125+y*10.50720794170734)) +
+		y*(-1351.605895580406+y*(1097.1125373015109+
+			y*(-433.20648175062206+63.905091254154904*y))))
+	return (g03 + g08) * 0.000625
+}
+
+// Grav 重力加速度(m/s2)
+func Grav(lat, p float64) float64 {
+	x := math.Sin(lat * math.Pi / 180)
+	sin2 := x * x
+	gs := 9.780327 * (1 + (5.2792e-3+2.32e-5*sin2)*sin2)
+	return gs * (1 - gamma*ZFromP(p, lat))
+}
+
+// ZFromP 由海压计算高度(m，海面以下为负)，假定为SA = 35.16504 g/kg、CT = 0°C的标准海洋
+func ZFromP(p, lat float64) float64 {
+	x := math.Sin(lat * math.Pi / 180)
+	sin2 := x * x
+	b := 9.780327 * (1 + (5.2792e-3+2.32e-5*sin2)*sin2)
+	a := -0.5 * gamma * b
+	c := enthalpySSO0(p)
+	return -2 * c / (b + math.Sqrt(b*b-4*a*c))
+}
+
+// PFromZ ZFromP的逆运算，z为高度(m，海面以下为负)
+func PFromZ(z, lat float64) float64 {
+	p := -z * 1.01
+	for i := 0; i < 4; i++ {
+		const h = 0.01
+		f := ZFromP(p, lat) - z
+		df := (ZFromP(p+h, lat) - ZFromP(p-h, lat)) / (2 * h)
+		p -= f / df
+	}
+	return p
+}
+
+// enthalpySSO0 SA = SSO、CT = 0°C时比焓随压力的变化(J/kg)
+func enthalpySSO0(p float64) float64 {
+	z := p * 1e-4
+	dynamic := z * (9.726613854843870e-04 + z*(-2.252956605630465e-5+
+		z*(2.376909655387404e-6+z*(-1.664294869986011e-7+
+			z*(-5.988108894465758e-9+z*(6.056990592034826e-9-2.296633232150567e-10*z))))))
+	return dynamic * DB2Pa * 1e4
+}
+
+// Nsquared 相邻层之间的浮力频率平方N²(1/s2)，返回值及对应的中间压力；
+// 输入按压力递增排列，任一层缺测时对应的N²为NaN
+func Nsquared(sa, ct, p []float64, lat float64) (n2, pMid []float64) {
+	n := len(p)
+	if n < 2 || len(sa) < n || len(ct) < n {
+		return nil, nil
+	}
+	n2 = make([]float64, n-1)
+	pMid = make([]float64, n-1)
+	for i := 0; i < n-1; i++ {
+		pm := 0.5 * (p[i] + p[i+1])
+		dp := p[i+1] - p[i]
+		pMid[i] = pm
+		if dp == 0 {
+			n2[i] = math.NaN()
+			continue
+		}
+		sam, ctm := 0.5*(sa[i]+sa[i+1]), 0.5*(ct[i]+ct[i+1])
+		v, alpha, beta := SpecvolAlphaBeta(sam, ctm, pm)
+		g := Grav(lat, pm)
+		n2[i] = g * g / v * (beta*(sa[i+1]-sa[i]) - alpha*(ct[i+1]-ct[i])) / (dp * DB2Pa)
+	}
+	return n2, pMid
+}
@@ -0,0 +1,118 @@
+package gsw
+
+import (
+	"math"
+	"testing"
+)
+
+// GSW 3.05工具箱文档中的示例输入(经度188°、纬度4°的一个剖面)
+var (
+	checkSP = []float64{34.5487, 34.7275, 34.8605, 34.6810, 34.5680, 34.5600}
+	checkSA = []float64{34.7118, 34.8915, 35.0256, 34.8472, 34.7366, 34.7324}
+	checkCT = []float64{28.8099, 28.4392, 22.7862, 10.2262, 6.8272, 4.3236}
+	checkT  = []float64{28.7856, 28.4329, 22.8103, 10.2600, 6.8863, 4.4036}
+	checkP  = []float64{10, 50, 125, 250, 600, 1000}
+)
+
+// assertClose 逐个比较计算值与检验值
+func assertClose(t *testing.T, name string, got, want []float64, tol float64) {
+	t.Helper()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tol {
+			t.Errorf("%s[%d] = %.15g, want %.15g (tolerance %g)", name, i, got[i], want[i], tol)
+		}
+	}
+}
+
+func TestSAFromSP(t *testing.T) {
+	got := make([]float64, len(checkSP))
+	for i := range checkSP {
+		got[i] = SAFromSP(checkSP[i], checkP[i], 188, 4)
+	}
+	// 检验值含该位置的绝对盐度异常δSA(随深度增大，1000 dbar处约0.009 g/kg)，本包按δSA = 0计算
+	want := []float64{34.711778344814114, 34.891522618230098, 35.025544862476920, 34.847229026189588, 34.736628474576051, 34.732363065590846}
+	assertClose(t, "SA_from_SP", got, want, 0.01)
+
+	// δSA = 0时即为参考盐度
+	for i := range checkSP {
+		if sr := checkSP[i] * 35.16504 / 35; math.Abs(got[i]-sr) > 1e-12 {
+			t.Errorf("SA_from_SP[%d] = %.15g, want SR %.15g", i, got[i], sr)
+		}
+	}
+	if !math.IsNaN(SAFromSP(math.NaN(), 0, 0, 0)) {
+		t.Error("SA_from_SP(NaN) should be NaN")
+	}
+}
+
+func TestCTFromT(t *testing.T) {
+	got := make([]float64, len(checkSA))
+	for i := range checkSA {
+		got[i] = CTFromT(checkSA[i], checkT[i], checkP[i])
+	}
+	want := []float64{28.809919826700281, 28.439227816091140, 22.786176893475052, 10.226189266166565, 6.827213633309444, 4.323575748537977}
+	assertClose(t, "CT_from_t", got, want, 1e-9)
+
+	// 逆运算
+	for i := range checkSA {
+		if back := TFromCT(checkSA[i], got[i], checkP[i]); math.Abs(back-checkT[i]) > 1e-9 {
+			t.Errorf("t_from_CT[%d] = %.15g, want %.15g", i, back, checkT[i])
+		}
+	}
+}
+
+func TestRho(t *testing.T) {
+	got := make([]float64, len(checkSA))
+	for i := range checkSA {
+		got[i] = Rho(checkSA[i], checkCT[i], checkP[i])
+	}
+	want := []float64{1021.839935738108, 1022.262457966867, 1024.427195413316, 1027.790152759127, 1029.837779000189, 1032.002453224572}
+	assertClose(t, "rho", got, want, 1e-9)
+}
+
+func TestSigma0(t *testing.T) {
+	got := make([]float64, len(checkSA))
+	for i := range checkSA {
+		got[i] = Sigma0(checkSA[i], checkCT[i])
+	}
+	want := []float64{21.797900819337656, 22.052215404397316, 23.892985307893923, 26.667608665972011, 27.107380455119710, 27.409748977090885}
+	assertClose(t, "sigma0", got, want, 1e-10)
+}
+
+func TestSoundSpeed(t *testing.T) {
+	for i := range checkSA {
+		c := SoundSpeed(checkSA[i], checkCT[i], checkP[i])
+		// 保守温度不变即熵不变，声速满足 c² = ∂P/∂ρ (SA、CT不变)，按密度的中心差分检验
+		const h = 1.0
+		drho := Rho(checkSA[i], checkCT[i], checkP[i]+h) - Rho(checkSA[i], checkCT[i], checkP[i]-h)
+		want := math.Sqrt(2 * h * DB2Pa / drho)
+		if math.Abs(c-want) > 1e-6 {
+			t.Errorf("sound_speed[%d] = %.15g, want %.15g", i, c, want)
+		}
+		if c < 1400 || c > 1600 {
+			t.Errorf("sound_speed[%d] = %g is out of the oceanic range", i, c)
+		}
+	}
+}
+
+func TestNsquared(t *testing.T) {
+	n2, pMid := Nsquared(checkSA, checkCT, checkP, 4)
+	want := []float64{0.060843209693499e-3, 0.235723066151305e-3, 0.216599928330380e-3, 0.012941204313372e-3, 0.008434782795209e-3}
+	assertClose(t, "N2", n2, want, 1e-12)
+	assertClose(t, "p_mid", pMid, []float64{30, 87.5, 187.5, 425, 800}, 0)
+
+	if n2, pMid := Nsquared(checkSA[:1], checkCT[:1], checkP[:1], 4); n2 != nil || pMid != nil {
+		t.Error("Nsquared of a single level should be nil")
+	}
+}
+
+func TestZFromP(t *testing.T) {
+	got := make([]float64, len(checkP))
+	for i, p := range checkP {
+		got[i] = ZFromP(p, 4)
+		if back := PFromZ(got[i], 4); math.Abs(back-p) > 1e-8 {
+			t.Errorf("p_from_z[%d] = %.15g, want %g", i, back, p)
+		}
+	}
+	want := []float64{-9.9445834469453, -49.7180897012550, -124.2726219409978, -248.4700576548589, -595.8253480356214, -992.0919060719987}
+	assertClose(t, "z_from_p", got, want, 1e-6)
+}
@@ -0,0 +1,130 @@
+package gsw
+
+import "math"
+
+// specvolTerm 比容75项多项式的一项: c·ys^i·xs^j·z^k，
+// 其中xs = sqrt(sfac·SA + offset)，ys = 0.025·CT，z = 1e-4·p
+type specvolTerm struct {
+	i, j, k int
+	c       float64
+}
+
+// specvolTerms Roquet等(2015)比容多项式的系数
+var specvolTerms = []specvolTerm{
+	{0, 0, 0, 1.0769995862e-3}, {0, 0, 1, -6.0799143809e-5}, {0, 0, 2, 9.9856169219e-6},
+	{0, 0, 3, -1.1309361437e-6}, {0, 0, 4, 1.0531153080e-7}, {0, 0, 5, -1.2647261286e-8},
+	{0, 0, 6, 1.9613503930e-9},
+	{0, 1, 0, -3.1038981976e-4}, {0, 1, 1, 2.4262468747e-5}, {0, 1, 2, -5.8484432984e-7},
+	{0, 1, 3, 3.6310188515e-7}, {0, 1, 4, -1.1147125423e-7},
+	{0, 2, 0, 6.6928067038e-4}, {0, 2, 1, -3.4792460974e-5}, {0, 2, 2, -4.8122251597e-6},
+	{0, 2, 3, 1.6746303780e-8},
+	{0, 3, 0, -8.5047933937e-4}, {0, 3, 1, 3.7470777305e-5}, {0, 3, 2, 4.9263106998e-6},
+	{0, 4, 0, 5.8086069943e-4}, {0, 4, 1, -1.7322218612e-5}, {0, 4, 2, -1.7811974727e-6},
+	{0, 5, 0, -2.1092370507e-4}, {0, 5, 1, 3.0927427253e-6},
+	{0, 6, 0, 3.1932457305e-5},
+	{1, 0, 0, -1.5649734675e-5}, {1, 0, 1, 1.8505765429e-5}, {1, 0, 2, -1.1736386731e-6},
+	{1, 0, 3, -3.6527006553e-7}, {1, 0, 4, 3.1454099902e-7},
+	{1, 1, 0, 3.5009599764e-5}, {1, 1, 1, -9.5677088156e-6}, {1, 1, 2, -5.5699154557e-6},
+	{1, 1, 3, -2.7295696237e-7},
+	{1, 2, 0, -4.3592678561e-5}, {1, 2, 1, 1.1100834765e-5}, {1, 2, 2, 5.4620748834e-6},
+	{1, 3, 0, 3.4532461828e-5}, {1, 3, 1, -9.8447117844e-6}, {1, 3, 2, -1.3544185627e-6},
+	{1, 4, 0, -1.1959409788e-5}, {1, 4, 1, 2.5909225260e-6},
+	{1, 5, 0, 1.3864594581e-6},
+	{2, 0, 0, 2.7762106484e-5}, {2, 0, 1, -1.1716606853e-5}, {2, 0, 2, 2.1305028740e-6},
+	{2, 0, 3, 2.8695905159e-7},
+	{2, 1, 0, -3.7435842344e-5}, {2, 1, 1, -2.3678308361e-7}, {2, 1, 2, 3.9137387080e-7},
+	{2, 2, 0, 3.5907822760e-5}, {2, 2, 1, 2.9283346295e-6}, {2, 2, 2, -6.5731104067e-7},
+	{2, 3, 0, -1.8698584187e-5}, {2, 3, 1, -4.8826139200e-7},
+	{2, 4, 0, 3.8595339244e-6},
+	{3, 0, 0, -1.6521159259e-5}, {3, 0, 1, 7.9279656173e-6}, {3, 0, 2, -4.6132540037e-7},
+	{3, 1, 0, 2.4141479483e-5}, {3, 1, 1, -3.4558773655e-6}, {3, 1, 2, 7.7618888092e-9},
+	{3, 2, 0, -1.4353633048e-5}, {3, 2, 1, 3.1655306078e-7},
+	{3, 3, 0, 2.2863324556e-6},
+	{4, 0, 0, 6.9111322702e-6}, {4, 0, 1, -3.4102187482e-6}, {4, 0, 2, -6.3352916514e-8},
+	{4, 1, 0, -8.7595873154e-6}, {4, 1, 1, 1.2956717783e-6},
+	{4, 2, 0, 4.3703680598e-6},
+	{5, 0, 0, -8.0539615540e-7}, {5, 0, 1, 5.0736766814e-7},
+	{5, 1, 0, -3.3052758900e-7},
+	{6, 0, 0, 2.0543094268e-7},
+}
+
+// specvolPoly 比容及其对ys、xs、z的偏导数
+func specvolPoly(sa, ct, p float64) (v, dys, dxs, dz, xs float64) {
+	xs = math.Sqrt(sfac*math.Max(sa, 0) + offset)
+	ys := ct * 0.025
+	z := p * 1e-4
+
+	var pw [3][8]float64 // ys、xs、z的0~7次幂
+	for a, x := range [3]float64{ys, xs, z} {
+		pw[a][0] = 1
+		for n := 1; n < len(pw[a]); n++ {
+			pw[a][n] = pw[a][n-1] * x
+		}
+	}
+	for _, t := range specvolTerms {
+		y, x, w := pw[0][t.i], pw[1][t.j], pw[2][t.k]
+		v += t.c * y * x * w
+		if t.i > 0 {
+			dys += t.c * float64(t.i) * pw[0][t.i-1] * x * w
+		}
+		if t.j > 0 {
+			dxs += t.c * float64(t.j) * y * pw[1][t.j-1] * w
+		}
+		if t.k > 0 {
+			dz += t.c * float64(t.k) * y * x * pw[2][t.k-1]
+		}
+	}
+	return v, dys, dxs, dz, xs
+}
+
+// Specvol 比容(m3/kg)
+func Specvol(sa, ct, p float64) float64 {
+	v, _, _, _, _ := specvolPoly(sa, ct, p)
+	return v
+}
+
+// Rho 现场密度(kg/m3)
+func Rho(sa, ct, p float64) float64 {
+	return 1 / Specvol(sa, ct, p)
+}
+
+// SpecvolAlphaBeta 比容、热膨胀系数α(1/K)和盐收缩系数β(kg/g)
+func SpecvolAlphaBeta(sa, ct, p float64) (v, alpha, beta float64) {
+	v, dys, dxs, _, xs := specvolPoly(sa, ct, p)
+	alpha = 0.025 * dys / v
+	beta = -0.5 * sfac * dxs / xs / v
+	return v, alpha, beta
+}
+
+// Alpha 热膨胀系数(1/K)
+func Alpha(sa, ct, p float64) float64 {
+	_, alpha, _ := SpecvolAlphaBeta(sa, ct, p)
+	return alpha
+}
+
+// Beta 盐收缩系数(kg/g)
+func Beta(sa, ct, p float64) float64 {
+	_, _, beta := SpecvolAlphaBeta(sa, ct, p)
+	return beta
+}
+
+// Sigma0 参考压力为0 dbar的位密(kg/m3)
+func Sigma0(sa, ct float64) float64 {
+	return Rho(sa, ct, 0) - 1000
+}
+
+// Sigma1 参考压力为1000 dbar的位密(kg/m3)
+func Sigma1(sa, ct float64) float64 {
+	return Rho(sa, ct, 1000) - 1000
+}
+
+// Sigma2 参考压力为2000 dbar的位密(kg/m3)
+func Sigma2(sa, ct float64) float64 {
+	return Rho(sa, ct, 2000) - 1000
+}
+
+// SoundSpeed 声速(m/s)，由比容对压力的偏导数计算
+func SoundSpeed(sa, ct, p float64) float64 {
+	v, _, _, dz, _ := specvolPoly(sa, ct, p)
+	return 1e4 * math.Sqrt(-v*v/dz)
+}
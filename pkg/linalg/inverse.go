@@ -0,0 +1,59 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrSingular 矩阵奇异或接近奇异
+var ErrSingular = errors.New("linalg: matrix is singular")
+
+// Inverse 用列主元Gauss-Jordan消元求n×n矩阵的逆矩阵，a不被改写；主元相对于最大元素过小时返回ErrSingular
+func Inverse(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	m := make([][]float64, n)
+	inv := make([][]float64, n)
+	scale := 0.0
+	for i := range a {
+		m[i] = append([]float64(nil), a[i]...)
+		inv[i] = make([]float64, n)
+		inv[i][i] = 1
+		for _, x := range a[i] {
+			scale = math.Max(scale, math.Abs(x))
+		}
+	}
+	if scale == 0 {
+		return nil, ErrSingular
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(m[i][col]) > math.Abs(m[pivot][col]) {
+				pivot = i
+			}
+		}
+		if math.Abs(m[pivot][col]) <= 1e-13*scale {
+			return nil, ErrSingular
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		d := m[col][col]
+		for j := 0; j < n; j++ {
+			m[col][j] /= d
+			inv[col][j] /= d
+		}
+		for i := 0; i < n; i++ {
+			if i == col || m[i][col] == 0 {
+				continue
+			}
+			factor := m[i][col]
+			for j := 0; j < n; j++ {
+				m[i][j] -= factor * m[col][j]
+				inv[i][j] -= factor * inv[col][j]
+			}
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,60 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestInverse(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		want [][]float64
+	}{
+		{"2x2", [][]float64{{4, 7}, {2, 6}}, [][]float64{{0.6, -0.7}, {-0.2, 0.4}}},
+		// 需要行交换
+		{"permutation", [][]float64{{0, 1}, {1, 0}}, [][]float64{{0, 1}, {1, 0}}},
+		{"tridiagonal", [][]float64{{2, -1, 0}, {-1, 2, -1}, {0, -1, 2}},
+			[][]float64{{0.75, 0.5, 0.25}, {0.5, 1, 0.5}, {0.25, 0.5, 0.75}}},
+		// 4阶Hilbert矩阵的逆为整数矩阵
+		{"hilbert", [][]float64{{1, 1.0 / 2, 1.0 / 3, 1.0 / 4}, {1.0 / 2, 1.0 / 3, 1.0 / 4, 1.0 / 5}, {1.0 / 3, 1.0 / 4, 1.0 / 5, 1.0 / 6}, {1.0 / 4, 1.0 / 5, 1.0 / 6, 1.0 / 7}},
+			[][]float64{{16, -120, 240, -140}, {-120, 1200, -2700, 1680}, {240, -2700, 6480, -4200}, {-140, 1680, -4200, 2800}}},
+	}
+	for _, tt := range tests {
+		orig := clone(tt.a)
+		got, err := Inverse(tt.a)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for i := range got {
+			for j := range got[i] {
+				if math.Abs(got[i][j]-tt.want[i][j]) > 1e-9*math.Max(1, math.Abs(tt.want[i][j])) {
+					t.Errorf("%s: inverse[%d][%d] = %.12g, want %g", tt.name, i, j, got[i][j], tt.want[i][j])
+				}
+				if tt.a[i][j] != orig[i][j] {
+					t.Fatalf("%s: input modified", tt.name)
+				}
+			}
+		}
+	}
+
+	for _, a := range [][][]float64{
+		{{1, 2}, {2, 4}},
+		{{0, 0}, {0, 0}},
+		{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+	} {
+		if _, err := Inverse(a); !errors.Is(err, ErrSingular) {
+			t.Errorf("Inverse(%v) error = %v, want ErrSingular", a, err)
+		}
+	}
+}
+
+func clone(a [][]float64) [][]float64 {
+	out := make([][]float64, len(a))
+	for i := range a {
+		out[i] = append([]float64(nil), a[i]...)
+	}
+	return out
+}
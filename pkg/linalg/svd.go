@@ -0,0 +1,116 @@
+// Package linalg 提供分析计算所需的稠密矩阵线性代数运算
+package linalg
+
+import (
+	"math"
+	"sort"
+)
+
+// svdMaxSweeps 单边Jacobi迭代的最大轮数
+const svdMaxSweeps = 60
+
+// svdTolerance 列向量正交的相对容差
+const svdTolerance = 1e-12
+
+// SVD 对m×n矩阵(按行存储)做瘦奇异值分解 A = U·diag(s)·Vᵀ，使用单边Jacobi(Hestenes)方法，
+// 对较小的维度做列正交化。返回k=min(m, n)个奇异值(降序)，u[i]为第i个左奇异向量(长度m)，v[i]为第i个右奇异向量(长度n)；
+// 零奇异值对应的左奇异向量为零向量
+func SVD(rows [][]float64) (u [][]float64, s []float64, v [][]float64) {
+	m := len(rows)
+	if m == 0 || len(rows[0]) == 0 {
+		return nil, nil, nil
+	}
+	n := len(rows[0])
+
+	// 列数不多于行数时对A的列正交化，否则对Aᵀ的列(即A的行)正交化后交换U和V
+	if n <= m {
+		cols := make([][]float64, n)
+		for j := range cols {
+			cols[j] = make([]float64, m)
+			for i := range rows {
+				cols[j][i] = rows[i][j]
+			}
+		}
+		return jacobi(cols)
+	}
+	cols := make([][]float64, m)
+	for i := range cols {
+		cols[i] = append([]float64(nil), rows[i]...)
+	}
+	vt, s, ut := jacobi(cols)
+	return ut, s, vt
+}
+
+// jacobi 对列向量组a(k列，每列长度m，k不大于m)做单边Jacobi正交化，a被改写；
+// 返回左奇异向量(单位化后的列)、奇异值和右奇异向量(累积的旋转)
+func jacobi(a [][]float64) (u [][]float64, s []float64, v [][]float64) {
+	k := len(a)
+	v = make([][]float64, k)
+	for i := range v {
+		v[i] = make([]float64, k)
+		v[i][i] = 1
+	}
+
+	for sweep := 0; sweep < svdMaxSweeps; sweep++ {
+		rotated := false
+		for p := 0; p < k-1; p++ {
+			for q := p + 1; q < k; q++ {
+				alpha, beta, gamma := dot(a[p], a[p]), dot(a[q], a[q]), dot(a[p], a[q])
+				if gamma == 0 || math.Abs(gamma) <= svdTolerance*math.Sqrt(alpha*beta) {
+					continue
+				}
+				rotated = true
+				zeta := (beta - alpha) / (2 * gamma)
+				t := math.Copysign(1, zeta) / (math.Abs(zeta) + math.Sqrt(1+zeta*zeta))
+				c := 1 / math.Sqrt(1+t*t)
+				sn := c * t
+				rotate(a[p], a[q], c, sn)
+				rotate(v[p], v[q], c, sn)
+			}
+		}
+		if !rotated {
+			break
+		}
+	}
+
+	s = make([]float64, k)
+	for j := range a {
+		s[j] = math.Sqrt(dot(a[j], a[j]))
+		if s[j] > 0 {
+			for i := range a[j] {
+				a[j][i] /= s[j]
+			}
+		}
+	}
+
+	order := make([]int, k)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return s[order[i]] > s[order[j]] })
+	u = make([][]float64, k)
+	sorted := make([]float64, k)
+	vs := make([][]float64, k)
+	for i, j := range order {
+		u[i], sorted[i], vs[i] = a[j], s[j], v[j]
+	}
+	return u, sorted, vs
+}
+
+// rotate 对两个向量做平面旋转 x' = c·x - s·y，y' = s·x + c·y
+func rotate(x, y []float64, c, s float64) {
+	for i := range x {
+		xi, yi := x[i], y[i]
+		x[i] = c*xi - s*yi
+		y[i] = s*xi + c*yi
+	}
+}
+
+// dot 向量内积
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
@@ -0,0 +1,86 @@
+package linalg
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSVD(t *testing.T) {
+	r2, r18 := 1/math.Sqrt2, 1/math.Sqrt(18)
+	tests := []struct {
+		name string
+		a    [][]float64
+		s    []float64
+		u, v [][]float64 // 参考奇异向量，符号不定
+	}{
+		{"wide", [][]float64{{3, 2, 2}, {2, 3, -2}},
+			[]float64{5, 3},
+			[][]float64{{r2, r2}, {r2, -r2}},
+			[][]float64{{r2, r2, 0}, {r18, -r18, 4 * r18}}},
+		{"tall", [][]float64{{3, 2}, {2, 3}, {2, -2}},
+			[]float64{5, 3},
+			[][]float64{{r2, r2, 0}, {r18, -r18, 4 * r18}},
+			[][]float64{{r2, r2}, {r2, -r2}}},
+		{"diagonal", [][]float64{{0, 0, 0}, {0, 3, 0}, {0, 0, -7}},
+			[]float64{7, 3, 0},
+			[][]float64{{0, 0, 1}, {0, 1, 0}, {0, 0, 0}},
+			nil},
+		// 秩1: 奇异值为sqrt(1+4)·sqrt(1+4+9)
+		{"rank one", [][]float64{{1, 2}, {2, 4}, {3, 6}},
+			[]float64{math.Sqrt(70), 0},
+			[][]float64{{1 / math.Sqrt(14), 2 / math.Sqrt(14), 3 / math.Sqrt(14)}, {0, 0, 0}},
+			[][]float64{{1 / math.Sqrt(5), 2 / math.Sqrt(5)}}},
+	}
+	for _, tt := range tests {
+		u, s, v := SVD(tt.a)
+		if len(s) != len(tt.s) || len(u) != len(s) || len(v) != len(s) {
+			t.Errorf("%s: %d singular values, want %d", tt.name, len(s), len(tt.s))
+			continue
+		}
+		for i := range s {
+			if math.Abs(s[i]-tt.s[i]) > 1e-10 {
+				t.Errorf("%s: s[%d] = %.12g, want %g", tt.name, i, s[i], tt.s[i])
+			}
+		}
+		for i, want := range tt.u {
+			if !sameDirection(u[i], want) {
+				t.Errorf("%s: u[%d] = %v, want ±%v", tt.name, i, u[i], want)
+			}
+		}
+		for i, want := range tt.v {
+			if !sameDirection(v[i], want) {
+				t.Errorf("%s: v[%d] = %v, want ±%v", tt.name, i, v[i], want)
+			}
+		}
+
+		// A = U·diag(s)·Vᵀ
+		for r := range tt.a {
+			for c := range tt.a[r] {
+				sum := 0.0
+				for k := range s {
+					sum += u[k][r] * s[k] * v[k][c]
+				}
+				if math.Abs(sum-tt.a[r][c]) > 1e-10 {
+					t.Errorf("%s: reconstructed A[%d][%d] = %g, want %g", tt.name, r, c, sum, tt.a[r][c])
+				}
+			}
+		}
+	}
+
+	if u, s, v := SVD(nil); u != nil || s != nil || v != nil {
+		t.Error("SVD(nil) should return nil")
+	}
+}
+
+// sameDirection 两个向量相等或相反(均为零向量时视为相同)
+func sameDirection(x, want []float64) bool {
+	if len(x) != len(want) {
+		return false
+	}
+	plus, minus := true, true
+	for i := range x {
+		plus = plus && math.Abs(x[i]-want[i]) < 1e-9
+		minus = minus && math.Abs(x[i]+want[i]) < 1e-9
+	}
+	return plus || minus
+}
@@ -0,0 +1,340 @@
+// Package mhw 按 Hobday 等(2016)的定义识别海洋热浪: 日平均海温连续超过逐日气候态阈值(基准期内
+// 该日前后窗口的高分位数)达到最短持续天数，间隔不超过最大间隔天数的相邻事件合并为一次事件
+package mhw
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sinker/ssop/pkg/stats"
+)
+
+// 热浪强度类别(Hobday 等, 2018)，按峰值日距平与阈值距平之比划分
+const (
+	Moderate = 1 // 中等(I)
+	Strong   = 2 // 强(II)
+	Severe   = 3 // 严重(III)
+	Extreme  = 4 // 极端(IV)
+)
+
+// categoryNames 各类别的名称，下标为类别
+var categoryNames = [5]string{"", "moderate", "strong", "severe", "extreme"}
+
+// CategoryName 类别的名称，类别无效时为空字符串
+func CategoryName(category int) string {
+	if category < Moderate || category > Extreme {
+		return ""
+	}
+	return categoryNames[category]
+}
+
+// ParseCategory 解析类别名称(moderate、strong、severe、extreme)或数字(1~4)，无效时返回0
+func ParseCategory(s string) int {
+	for c := Moderate; c <= Extreme; c++ {
+		if s == categoryNames[c] || s == string(rune('0'+c)) {
+			return c
+		}
+	}
+	return 0
+}
+
+// daysPerYear 气候态的日序数(闰年2月29日并入2月28日)
+const daysPerYear = 365
+
+// day 一天的时长
+const day = 24 * time.Hour
+
+// Options 热浪识别参数
+type Options struct {
+	Percentile      float64 // 阈值分位数(0~100)
+	WindowHalfWidth int     // 计算气候态时各日前后取的天数
+	SmoothWidth     int     // 气候态和阈值的循环滑动平均宽度(天)，不大于1时不平滑
+	MinDuration     int     // 事件的最短持续天数
+	MaxGap          int     // 合并相邻事件的最大间隔天数
+	BaselineStart   int     // 基准期起始年，0为序列的第一年
+	BaselineEnd     int     // 基准期结束年(含)，0为序列的最后一年
+}
+
+// DefaultOptions Hobday 等(2016)推荐的参数: 90分位数、11天窗口、31天平滑、至少5天、间隔不超过2天
+func DefaultOptions() Options {
+	return Options{Percentile: 90, WindowHalfWidth: 5, SmoothWidth: 31, MinDuration: 5, MaxGap: 2}
+}
+
+// Series 逐日序列: Values[i]为Start之后第i天(UTC)的日平均值，NaN为缺测
+type Series struct {
+	Start  time.Time
+	Values []float64
+}
+
+// Daily 将时间序列按UTC日求平均，得到从第一天到最后一天连续的逐日序列；times为零值的时次和NaN忽略
+func Daily(times []time.Time, values []float64) Series {
+	var first, last time.Time
+	for _, t := range times {
+		if t.IsZero() {
+			continue
+		}
+		d := t.UTC().Truncate(day)
+		if first.IsZero() || d.Before(first) {
+			first = d
+		}
+		if last.IsZero() || d.After(last) {
+			last = d
+		}
+	}
+	if first.IsZero() {
+		return Series{}
+	}
+	n := int(last.Sub(first)/day) + 1
+	sum := make([]float64, n)
+	count := make([]int, n)
+	for i, t := range times {
+		if t.IsZero() || math.IsNaN(values[i]) {
+			continue
+		}
+		k := int(t.UTC().Truncate(day).Sub(first) / day)
+		sum[k] += values[i]
+		count[k]++
+	}
+	for k := range sum {
+		if count[k] > 0 {
+			sum[k] /= float64(count[k])
+		} else {
+			sum[k] = math.NaN()
+		}
+	}
+	return Series{Start: first, Values: sum}
+}
+
+// Day 第i天的日期
+func (s Series) Day(i int) time.Time {
+	return s.Start.AddDate(0, 0, i)
+}
+
+// Slice [from, to]内的部分序列(按日期)，from、to为零值时不限制
+func (s Series) Slice(from, to time.Time) Series {
+	lo, hi := 0, len(s.Values)
+	if !from.IsZero() {
+		if k := int(math.Ceil(float64(from.Sub(s.Start)) / float64(day))); k > lo {
+			lo = k
+		}
+	}
+	if !to.IsZero() {
+		if k := int(math.Floor(float64(to.Sub(s.Start))/float64(day))) + 1; k < hi {
+			hi = k
+		}
+	}
+	if lo >= hi {
+		return Series{Start: s.Day(lo)}
+	}
+	return Series{Start: s.Day(lo), Values: s.Values[lo:hi]}
+}
+
+// dayOfYear 日期的气候态日序数(从0开始)，闰年2月29日并入2月28日
+func dayOfYear(t time.Time) int {
+	d := t.YearDay() - 1
+	if year := t.Year(); year%4 == 0 && (year%100 != 0 || year%400 == 0) && d >= 59 {
+		d--
+	}
+	return d
+}
+
+// Climatology 逐日气候态(各日序数的平均值)和热浪阈值
+type Climatology struct {
+	Mean      [daysPerYear]float64
+	Threshold [daysPerYear]float64
+}
+
+// At 日期t的气候态平均值和阈值
+func (c *Climatology) At(t time.Time) (mean, threshold float64) {
+	d := dayOfYear(t)
+	return c.Mean[d], c.Threshold[d]
+}
+
+// ComputeClimatology 由基准期内的数据计算逐日气候态: 各日序数取前后WindowHalfWidth天(跨年循环)内所有年份的值，
+// 平均值为气候态、Percentile分位数为阈值，再分别做SmoothWidth天的循环滑动平均；没有数据的日序数为NaN
+func ComputeClimatology(s Series, opts Options) *Climatology {
+	buckets := make([][]float64, daysPerYear)
+	for i, x := range s.Values {
+		if math.IsNaN(x) {
+			continue
+		}
+		t := s.Day(i)
+		if (opts.BaselineStart != 0 && t.Year() < opts.BaselineStart) || (opts.BaselineEnd != 0 && t.Year() > opts.BaselineEnd) {
+			continue
+		}
+		d := dayOfYear(t)
+		for k := -opts.WindowHalfWidth; k <= opts.WindowHalfWidth; k++ {
+			slot := ((d+k)%daysPerYear + daysPerYear) % daysPerYear
+			buckets[slot] = append(buckets[slot], x)
+		}
+	}
+
+	c := &Climatology{}
+	for d, bucket := range buckets {
+		c.Mean[d], c.Threshold[d] = math.NaN(), math.NaN()
+		if len(bucket) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, x := range bucket {
+			sum += x
+		}
+		c.Mean[d] = sum / float64(len(bucket))
+		sort.Float64s(bucket)
+		c.Threshold[d] = stats.Percentile(bucket, opts.Percentile)
+	}
+	c.Mean = smooth(c.Mean, opts.SmoothWidth)
+	c.Threshold = smooth(c.Threshold, opts.SmoothWidth)
+	return c
+}
+
+// smooth 循环滑动平均，窗口内的NaN不参与平均
+func smooth(values [daysPerYear]float64, width int) [daysPerYear]float64 {
+	if width <= 1 {
+		return values
+	}
+	half := width / 2
+	var out [daysPerYear]float64
+	for d := range values {
+		sum, n := 0.0, 0
+		for k := -half; k <= half; k++ {
+			if x := values[((d+k)%daysPerYear+daysPerYear)%daysPerYear]; !math.IsNaN(x) {
+				sum += x
+				n++
+			}
+		}
+		out[d] = math.NaN()
+		if n > 0 {
+			out[d] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+// Event 一次海洋热浪事件，强度为相对气候态平均值的距平
+type Event struct {
+	Start               time.Time
+	End                 time.Time // 最后一天
+	Peak                time.Time // 强度最大的一天
+	Duration            int       // 持续天数(含合并的间隔)
+	IntensityMax        float64
+	IntensityMean       float64
+	IntensityCumulative float64 // 逐日强度之和(度·天)
+	Category            int     // 峰值日的强度类别
+	Ongoing             bool    // 持续到序列的最后一天
+}
+
+// Detect 识别逐日序列中的热浪事件: 超过阈值(缺测视为未超过)的连续天数不少于MinDuration的时段为候选事件，
+// 间隔不超过MaxGap天的相邻事件合并；事件内缺测的日期不参与强度统计
+func Detect(s Series, c *Climatology, opts Options) []Event {
+	type run struct{ start, end int }
+	var runs []run
+	start := -1
+	for i, x := range s.Values {
+		_, threshold := c.At(s.Day(i))
+		if !math.IsNaN(x) && x > threshold {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= opts.MinDuration {
+			runs = append(runs, run{start, i - 1})
+		}
+		start = -1
+	}
+	if start >= 0 && len(s.Values)-start >= opts.MinDuration {
+		runs = append(runs, run{start, len(s.Values) - 1})
+	}
+
+	var merged []run
+	for _, r := range runs {
+		if n := len(merged); n > 0 && r.start-merged[n-1].end-1 <= opts.MaxGap {
+			merged[n-1].end = r.end
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	events := make([]Event, 0, len(merged))
+	for _, r := range merged {
+		e := Event{Start: s.Day(r.start), End: s.Day(r.end), Duration: r.end - r.start + 1, IntensityMax: math.Inf(-1),
+			Ongoing: r.end == len(s.Values)-1}
+		n := 0
+		ratio := 0.0
+		for i := r.start; i <= r.end; i++ {
+			x := s.Values[i]
+			mean, threshold := c.At(s.Day(i))
+			if math.IsNaN(x) || math.IsNaN(mean) {
+				continue
+			}
+			intensity := x - mean
+			e.IntensityCumulative += intensity
+			n++
+			if intensity > e.IntensityMax {
+				e.IntensityMax, e.Peak = intensity, s.Day(i)
+				ratio = math.Inf(1)
+				if threshold > mean {
+					ratio = intensity / (threshold - mean)
+				}
+			}
+		}
+		e.IntensityMean = e.IntensityCumulative / float64(n)
+		e.Category = int(math.Max(math.Min(math.Floor(ratio), Extreme), Moderate))
+		events = append(events, e)
+	}
+	return events
+}
+
+// YearSummary 一年的热浪统计，事件按开始日期计入年份，热浪天数按日期计入
+type YearSummary struct {
+	Year                int
+	ValidDays           int     // 有数据的天数
+	Count               int     // 事件次数
+	Days                int     // 热浪天数
+	IntensityMax        float64 // 各事件最大强度的最大值，没有事件时为NaN
+	IntensityMean       float64 // 各事件平均强度的平均值，没有事件时为NaN
+	IntensityCumulative float64 // 各事件累积强度之和
+	Category            int     // 最高类别，没有事件时为0
+}
+
+// Annual 序列覆盖的各年的热浪统计
+func Annual(s Series, events []Event) []YearSummary {
+	if len(s.Values) == 0 {
+		return nil
+	}
+	first := s.Start.Year()
+	years := make([]YearSummary, s.Day(len(s.Values)-1).Year()-first+1)
+	for i := range years {
+		years[i] = YearSummary{Year: first + i, IntensityMax: math.NaN(), IntensityMean: math.NaN()}
+	}
+	for i, x := range s.Values {
+		if !math.IsNaN(x) {
+			years[s.Day(i).Year()-first].ValidDays++
+		}
+	}
+
+	sums := make([]float64, len(years))
+	for _, e := range events {
+		for t := e.Start; !t.After(e.End); t = t.AddDate(0, 0, 1) {
+			years[t.Year()-first].Days++
+		}
+		y := &years[e.Start.Year()-first]
+		y.Count++
+		y.IntensityCumulative += e.IntensityCumulative
+		if math.IsNaN(y.IntensityMax) || e.IntensityMax > y.IntensityMax {
+			y.IntensityMax = e.IntensityMax
+		}
+		sums[e.Start.Year()-first] += e.IntensityMean
+		if e.Category > y.Category {
+			y.Category = e.Category
+		}
+	}
+	for i := range years {
+		if years[i].Count > 0 {
+			years[i].IntensityMean = sums[i] / float64(years[i].Count)
+		}
+	}
+	return years
+}
@@ -0,0 +1,165 @@
+package mhw
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9
+}
+
+// testSeries 1993~1995年为基准期，各年分别恒为20、21、22，气候态平均值为21、90分位数阈值为22；
+// 1996年(闰年)前100天为21，其中含三次热浪和一次过短的超阈值时段
+func testSeries() Series {
+	var values []float64
+	for year := 1993; year <= 1995; year++ {
+		for i := 0; i < 365; i++ {
+			values = append(values, float64(20+year-1993))
+		}
+	}
+	y := make([]float64, 100)
+	for i := range y {
+		y[i] = 21
+	}
+	copy(y[10:], []float64{22.5, 23, 23.5, 22.8, 22.2})
+	copy(y[30:], []float64{23, 23, 23})
+	copy(y[50:], []float64{23, 23, 23, 23, 23, math.NaN(), 21, 24, 24, 24, 24, 24})
+	copy(y[94:], []float64{22.1, 22.1, 22.1, 22.1, 22.1, 22.1})
+	return Series{Start: date(1993, 1, 1), Values: append(values, y...)}
+}
+
+func TestClimatology(t *testing.T) {
+	opts := DefaultOptions()
+	opts.BaselineStart, opts.BaselineEnd = 1993, 1995
+	c := ComputeClimatology(testSeries(), opts)
+	for _, d := range []time.Time{date(1996, 1, 1), date(1996, 2, 29), date(1996, 7, 1), date(1999, 12, 31)} {
+		if mean, threshold := c.At(d); mean != 21 || threshold != 22 {
+			t.Errorf("At(%s) = %g, %g, want 21, 22", d.Format("2006-01-02"), mean, threshold)
+		}
+	}
+
+	// 正弦季节循环: 不平滑时阈值为窗口内各年值的分位数
+	s := Series{Start: date(2001, 1, 1)}
+	for i := 0; i < 3*365; i++ {
+		s.Values = append(s.Values, 10*math.Sin(2*math.Pi*float64(i%365)/365)+float64(i/365))
+	}
+	c = ComputeClimatology(s, Options{Percentile: 50, WindowHalfWidth: 0, SmoothWidth: 1})
+	if mean, threshold := c.At(date(2005, 4, 2)); !closeTo(mean, 10*math.Sin(2*math.Pi*91/365)+1) || !closeTo(threshold, mean) {
+		t.Errorf("sine climatology = %g, %g", mean, threshold)
+	}
+	if mean, _ := ComputeClimatology(Series{}, opts).At(date(2000, 1, 1)); !math.IsNaN(mean) {
+		t.Errorf("empty climatology = %g, want NaN", mean)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	s := testSeries()
+	opts := DefaultOptions()
+	opts.BaselineStart, opts.BaselineEnd = 1993, 1995
+	events := Detect(s, ComputeClimatology(s, opts), opts)
+
+	want := []struct {
+		start, end, peak      time.Time
+		duration              int
+		max, mean, cumulative float64
+		category              int
+		ongoing               bool
+	}{
+		{date(1996, 1, 11), date(1996, 1, 15), date(1996, 1, 13), 5, 2.5, 1.8, 9, Strong, false},
+		// 两段间隔2天(含1天缺测)合并，缺测日不参与强度统计
+		{date(1996, 2, 20), date(1996, 3, 2), date(1996, 2, 27), 12, 3, 25.0 / 11, 25, Severe, false},
+		{date(1996, 4, 4), date(1996, 4, 9), date(1996, 4, 4), 6, 1.1, 1.1, 6.6, Moderate, true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if !e.Start.Equal(w.start) || !e.End.Equal(w.end) || !e.Peak.Equal(w.peak) || e.Duration != w.duration ||
+			e.Category != w.category || e.Ongoing != w.ongoing {
+			t.Errorf("event %d = %+v", i, e)
+		}
+		if !closeTo(e.IntensityMax, w.max) || !closeTo(e.IntensityMean, w.mean) || !closeTo(e.IntensityCumulative, w.cumulative) {
+			t.Errorf("event %d intensity = %g, %g, %g, want %g, %g, %g",
+				i, e.IntensityMax, e.IntensityMean, e.IntensityCumulative, w.max, w.mean, w.cumulative)
+		}
+	}
+
+	annual := Annual(s, events)
+	if len(annual) != 4 {
+		t.Fatalf("%d years, want 4", len(annual))
+	}
+	y := annual[3]
+	if y.Year != 1996 || y.ValidDays != 99 || y.Count != 3 || y.Days != 23 || y.Category != Severe ||
+		!closeTo(y.IntensityMax, 3) || !closeTo(y.IntensityMean, (1.8+25.0/11+1.1)/3) || !closeTo(y.IntensityCumulative, 40.6) {
+		t.Errorf("1996 = %+v", y)
+	}
+	if y := annual[0]; y.Year != 1993 || y.ValidDays != 365 || y.Count != 0 || !math.IsNaN(y.IntensityMax) || y.Category != 0 {
+		t.Errorf("1993 = %+v", y)
+	}
+}
+
+func TestDaily(t *testing.T) {
+	times := []time.Time{
+		time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC),
+		time.Date(2020, 1, 3, 23, 0, 0, 0, time.FixedZone("CST", 8*3600)), // UTC 1月3日15时
+		{},
+		time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+	}
+	s := Daily(times, []float64{10, 12, 7, 99, math.NaN()})
+	want := []float64{11, math.NaN(), 7, math.NaN()}
+	if !s.Start.Equal(date(2020, 1, 1)) || len(s.Values) != len(want) {
+		t.Fatalf("Daily = %+v", s)
+	}
+	for i := range want {
+		if s.Values[i] != want[i] && !(math.IsNaN(s.Values[i]) && math.IsNaN(want[i])) {
+			t.Errorf("day %d = %g, want %g", i, s.Values[i], want[i])
+		}
+	}
+
+	sub := s.Slice(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), date(2020, 1, 3))
+	if !sub.Start.Equal(date(2020, 1, 2)) || len(sub.Values) != 2 {
+		t.Errorf("Slice = %+v", sub)
+	}
+	if sub := s.Slice(date(2021, 1, 1), time.Time{}); len(sub.Values) != 0 {
+		t.Errorf("Slice beyond end = %+v", sub)
+	}
+}
+
+func TestDayOfYear(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want int
+	}{
+		{date(1995, 1, 1), 0},
+		{date(1995, 3, 1), 59},
+		{date(1996, 2, 29), 58},
+		{date(1996, 3, 1), 59},
+		{date(1996, 12, 31), 364},
+		{date(1900, 3, 1), 59},
+		{date(2000, 3, 1), 59},
+	}
+	for _, tt := range tests {
+		if got := dayOfYear(tt.t); got != tt.want {
+			t.Errorf("dayOfYear(%s) = %d, want %d", tt.t.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestCategory(t *testing.T) {
+	for c := Moderate; c <= Extreme; c++ {
+		if got := ParseCategory(CategoryName(c)); got != c {
+			t.Errorf("ParseCategory(%q) = %d, want %d", CategoryName(c), got, c)
+		}
+	}
+	if ParseCategory("3") != Severe || ParseCategory("5") != 0 || ParseCategory("") != 0 || CategoryName(0) != "" {
+		t.Error("invalid category handling")
+	}
+}
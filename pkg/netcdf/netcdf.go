@@ -0,0 +1,166 @@
+// Package netcdf 纯Go实现的NetCDF经典格式读写，读取支持CDF-1、CDF-2(64位偏移)和CDF-5(64位数据)，写入支持CDF-1和CDF-2
+package netcdf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 格式版本
+const (
+	VersionClassic = 1 // CDF-1
+	Version64Bit   = 2 // CDF-2，64位偏移
+	VersionCDF5    = 5 // CDF-5，64位数据
+)
+
+// Type 数据类型
+type Type int
+
+// NetCDF数据类型
+const (
+	Byte   Type = 1
+	Char   Type = 2
+	Short  Type = 3
+	Int    Type = 4
+	Float  Type = 5
+	Double Type = 6
+	UByte  Type = 7
+	UShort Type = 8
+	UInt   Type = 9
+	Int64  Type = 10
+	UInt64 Type = 11
+)
+
+// header中的标记
+const (
+	tagDimension = 0x0A
+	tagVariable  = 0x0B
+	tagAttribute = 0x0C
+)
+
+// streamingRecords numrecs为该值时记录数需根据文件大小计算
+const streamingRecords = 0xFFFFFFFF
+
+var (
+	// ErrNotNetCDF 不是NetCDF文件
+	ErrNotNetCDF = errors.New("netcdf: not a netCDF file")
+	// ErrNetCDF4 NetCDF-4(HDF5)格式暂不支持
+	ErrNetCDF4 = errors.New("netcdf: netCDF-4/HDF5 files are not supported")
+	// ErrInvalidSlice 读取范围无效
+	ErrInvalidSlice = errors.New("netcdf: invalid start/count")
+)
+
+// Size 类型的字节大小
+func (t Type) Size() int {
+	switch t {
+	case Byte, Char, UByte:
+		return 1
+	case Short, UShort:
+		return 2
+	case Int, Float, UInt:
+		return 4
+	case Double, Int64, UInt64:
+		return 8
+	}
+	return 0
+}
+
+// String 类型名称，与CDL一致
+func (t Type) String() string {
+	switch t {
+	case Byte:
+		return "byte"
+	case Char:
+		return "char"
+	case Short:
+		return "short"
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Double:
+		return "double"
+	case UByte:
+		return "ubyte"
+	case UShort:
+		return "ushort"
+	case UInt:
+		return "uint"
+	case Int64:
+		return "int64"
+	case UInt64:
+		return "uint64"
+	}
+	return fmt.Sprintf("type(%d)", int(t))
+}
+
+// Dimension 维度
+type Dimension struct {
+	Name      string
+	Len       int  // 无限维度为当前记录数
+	Unlimited bool // 是否为记录(无限)维度
+}
+
+// Attribute 属性，字符类型的值为string，数值类型的值为[]float64
+type Attribute struct {
+	Name  string
+	Type  Type
+	Value interface{}
+}
+
+// String 字符属性的值
+func (a Attribute) String() string {
+	if s, ok := a.Value.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// Floats 数值属性的值
+func (a Attribute) Floats() []float64 {
+	if v, ok := a.Value.([]float64); ok {
+		return v
+	}
+	return nil
+}
+
+// Variable 变量
+type Variable struct {
+	Name   string
+	Type   Type
+	Dims   []*Dimension
+	Attrs  []Attribute
+	vsize  int64 // 单个记录(或整个非记录变量)的字节数
+	begin  int64 // 数据起始偏移
+	record bool  // 是否为记录变量
+	file   *File
+}
+
+// Shape 变量形状
+func (v *Variable) Shape() []int {
+	shape := make([]int, len(v.Dims))
+	for i, d := range v.Dims {
+		shape[i] = d.Len
+	}
+	return shape
+}
+
+// IsRecord 是否为记录变量
+func (v *Variable) IsRecord() bool {
+	return v.record
+}
+
+// Attr 按名称查找变量属性
+func (v *Variable) Attr(name string) (Attribute, bool) {
+	return findAttr(v.Attrs, name)
+}
+
+// findAttr 在属性列表中按名称查找
+func findAttr(attrs []Attribute, name string) (Attribute, bool) {
+	for _, a := range attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attribute{}, false
+}
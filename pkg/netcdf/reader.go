@@ -0,0 +1,521 @@
+package netcdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// File 已打开的NetCDF文件
+type File struct {
+	Version int
+	NumRecs int
+	Dims    []*Dimension
+	Attrs   []Attribute
+	Vars    []*Variable
+
+	recSize int64 // 每条记录的字节数
+	r       io.ReaderAt
+	closer  io.Closer
+}
+
+// Open 打开NetCDF文件
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	nc, err := NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	nc.closer = f
+	return nc, nil
+}
+
+// NewReader 从ReaderAt解析NetCDF文件，size为文件总大小
+func NewReader(r io.ReaderAt, size int64) (*File, error) {
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(r, 0, size)), limit: size}
+	nc := &File{r: r}
+
+	magic := h.bytes(4)
+	if h.err != nil {
+		return nil, ErrNotNetCDF
+	}
+	if string(magic[:4]) == "\x89HDF" {
+		return nil, ErrNetCDF4
+	}
+	if string(magic[:3]) != "CDF" {
+		return nil, ErrNotNetCDF
+	}
+	nc.Version = int(magic[3])
+	switch nc.Version {
+	case VersionClassic, Version64Bit, VersionCDF5:
+	default:
+		return nil, fmt.Errorf("netcdf: unsupported version %d", nc.Version)
+	}
+	h.version = nc.Version
+
+	numRecs := h.uint32()
+	if nc.Version == VersionCDF5 {
+		numRecs = uint64(numRecs)<<32 | h.uint32()
+	}
+
+	// 维度
+	if err := h.list(tagDimension, func() {
+		name := h.name()
+		length := h.size()
+		if length > math.MaxInt32 && nc.Version != VersionCDF5 || length > math.MaxInt64 {
+			h.err = fmt.Errorf("netcdf: dimension %s too large (%d)", name, length)
+			return
+		}
+		nc.Dims = append(nc.Dims, &Dimension{Name: name, Len: int(length), Unlimited: length == 0})
+	}); err != nil {
+		return nil, err
+	}
+
+	// 全局属性
+	attrs, err := h.attrs()
+	if err != nil {
+		return nil, err
+	}
+	nc.Attrs = attrs
+
+	// 变量
+	if err := h.list(tagVariable, func() {
+		v := &Variable{file: nc}
+		v.Name = h.name()
+		n := h.size()
+		for i := uint64(0); i < n && h.err == nil; i++ {
+			id := h.size()
+			if id >= uint64(len(nc.Dims)) {
+				h.err = fmt.Errorf("netcdf: variable %s references unknown dimension %d", v.Name, id)
+				return
+			}
+			v.Dims = append(v.Dims, nc.Dims[id])
+		}
+		v.Attrs, h.err = h.attrs()
+		v.Type = Type(h.uint32())
+		v.vsize = int64(h.size())
+		if nc.Version == VersionClassic {
+			v.begin = int64(h.uint32())
+		} else {
+			v.begin = int64(h.uint64())
+		}
+		v.record = len(v.Dims) > 0 && v.Dims[0].Unlimited
+		if v.Type.Size() == 0 && h.err == nil {
+			h.err = fmt.Errorf("netcdf: variable %s has unknown type %d", v.Name, int(v.Type))
+		}
+		nc.Vars = append(nc.Vars, v)
+	}); err != nil {
+		return nil, err
+	}
+
+	// 记录大小: 所有记录变量vsize之和；仅有一个记录变量时不做对齐填充
+	var recordVars []*Variable
+	for _, v := range nc.Vars {
+		if v.record {
+			recordVars = append(recordVars, v)
+			if v.vsize < 0 || nc.recSize > math.MaxInt64-v.vsize {
+				return nil, fmt.Errorf("netcdf: record size of %s overflows", v.Name)
+			}
+			nc.recSize += v.vsize
+		}
+	}
+	if len(recordVars) == 1 {
+		nc.recSize = int64(recordVars[0].Type.Size())
+		for _, d := range recordVars[0].Dims[1:] {
+			nc.recSize *= int64(d.Len)
+		}
+	}
+
+	// 记录数
+	if numRecs == streamingRecords && nc.recSize > 0 {
+		first := int64(-1)
+		for _, v := range recordVars {
+			if first < 0 || v.begin < first {
+				first = v.begin
+			}
+		}
+		numRecs = 0
+		if first >= 0 && first < size {
+			numRecs = uint64((size - first) / nc.recSize)
+		}
+	} else if numRecs == streamingRecords {
+		numRecs = 0
+	}
+	if numRecs > math.MaxInt32 && nc.Version != VersionCDF5 || numRecs > math.MaxInt64 {
+		return nil, fmt.Errorf("netcdf: too many records (%d)", numRecs)
+	}
+	nc.NumRecs = int(numRecs)
+	for _, d := range nc.Dims {
+		if d.Unlimited {
+			d.Len = nc.NumRecs
+		}
+	}
+
+	// 变量数据须位于文件内
+	for _, v := range nc.Vars {
+		if err := nc.checkExtent(v, size); err != nil {
+			return nil, err
+		}
+	}
+
+	return nc, nil
+}
+
+// checkExtent 检查变量的元素数不溢出、数据范围不超出文件大小
+func (f *File) checkExtent(v *Variable, size int64) error {
+	dims := v.Dims
+	if v.record {
+		dims = dims[1:]
+	}
+	n := int64(v.Type.Size())
+	for _, d := range dims {
+		if d.Len == 0 {
+			n = 0
+			break
+		}
+		if n > math.MaxInt64/int64(d.Len) {
+			return fmt.Errorf("netcdf: variable %s is too large", v.Name)
+		}
+		n *= int64(d.Len)
+	}
+	if n == 0 || v.record && f.NumRecs == 0 {
+		return nil
+	}
+	if v.begin < 0 || v.begin > size {
+		return fmt.Errorf("netcdf: variable %s exceeds file size (offset %d, file %d bytes)", v.Name, v.begin, size)
+	}
+
+	// 记录变量的数据结束于最后一条记录
+	end := v.begin
+	if v.record {
+		if f.recSize < n {
+			return fmt.Errorf("netcdf: variable %s is larger than the record size %d", v.Name, f.recSize)
+		}
+		if int64(f.NumRecs-1) > (size-v.begin)/f.recSize {
+			return fmt.Errorf("netcdf: variable %s exceeds file size (%d records of %d bytes)", v.Name, f.NumRecs, f.recSize)
+		}
+		end += int64(f.NumRecs-1) * f.recSize
+	}
+	if n > size-end {
+		return fmt.Errorf("netcdf: variable %s exceeds file size (offset %d, %d bytes, file %d bytes)", v.Name, end, n, size)
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (f *File) Close() error {
+	if f.closer != nil {
+		return f.closer.Close()
+	}
+	return nil
+}
+
+// Var 按名称查找变量
+func (f *File) Var(name string) *Variable {
+	for _, v := range f.Vars {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Attr 按名称查找全局属性
+func (f *File) Attr(name string) (Attribute, bool) {
+	return findAttr(f.Attrs, name)
+}
+
+// ReadFloat64 读取变量的超立方体切片并转换为float64，start/count为nil时读取全部
+func (v *Variable) ReadFloat64(start, count []int) ([]float64, error) {
+	if v.Type == Char {
+		return nil, fmt.Errorf("netcdf: variable %s is a char variable", v.Name)
+	}
+
+	raw, n, err := v.readRaw(start, count)
+	if err != nil {
+		return nil, err
+	}
+	return decode(v.Type, raw, n), nil
+}
+
+// ReadBytes 读取字符(或字节)变量的原始数据
+func (v *Variable) ReadBytes(start, count []int) ([]byte, error) {
+	if v.Type.Size() != 1 {
+		return nil, fmt.Errorf("netcdf: variable %s is not a char or byte variable", v.Name)
+	}
+	raw, _, err := v.readRaw(start, count)
+	return raw, err
+}
+
+// readRaw 读取切片的原始字节，返回数据和元素个数
+func (v *Variable) readRaw(start, count []int) ([]byte, int, error) {
+	shape := v.Shape()
+	if start == nil {
+		start = make([]int, len(shape))
+	}
+	if count == nil {
+		count = make([]int, len(shape))
+		for i := range shape {
+			count[i] = shape[i] - start[i]
+		}
+	}
+	if len(start) != len(shape) || len(count) != len(shape) {
+		return nil, 0, ErrInvalidSlice
+	}
+
+	n := 1
+	for i := range shape {
+		if start[i] < 0 || count[i] < 0 || start[i]+count[i] > shape[i] {
+			return nil, 0, fmt.Errorf("%w: %s dimension %d", ErrInvalidSlice, v.Name, i)
+		}
+		n *= count[i]
+	}
+
+	elem := int64(v.Type.Size())
+	out := make([]byte, int64(n)*elem)
+	if n == 0 {
+		return out, 0, nil
+	}
+
+	// 记录变量第0维按记录寻址，其余维度在记录内连续
+	inner := shape
+	innerStart, innerCount := start, count
+	if v.record {
+		inner, innerStart, innerCount = shape[1:], start[1:], count[1:]
+	}
+
+	// 末尾完整覆盖的维度可合并为一次连续读取
+	run := int64(1)
+	k := len(inner)
+	for k > 0 && innerStart[k-1] == 0 && innerCount[k-1] == inner[k-1] {
+		k--
+		run *= int64(inner[k])
+	}
+	if k > 0 {
+		run *= int64(innerCount[k-1])
+		k--
+	}
+
+	// strides 内部维度的元素步长
+	strides := make([]int64, len(inner))
+	stride := int64(1)
+	for i := len(inner) - 1; i >= 0; i-- {
+		strides[i] = stride
+		stride *= int64(inner[i])
+	}
+
+	records, recStart := 1, 0
+	if v.record {
+		records, recStart = count[0], start[0]
+	}
+
+	idx := make([]int, k) // 外层维度的当前偏移
+	pos := int64(0)
+	for rec := 0; rec < records; rec++ {
+		base := v.begin
+		if v.record {
+			base += int64(recStart+rec) * v.file.recSize
+		}
+		for i := range idx {
+			idx[i] = 0
+		}
+		for {
+			offset := int64(0)
+			for i := 0; i < len(inner); i++ {
+				o := int64(innerStart[i])
+				if i < k {
+					o += int64(idx[i])
+				}
+				offset += o * strides[i]
+			}
+
+			size := run * elem
+			if _, err := v.file.r.ReadAt(out[pos:pos+size], base+offset*elem); err != nil {
+				return nil, 0, fmt.Errorf("netcdf: read %s: %w", v.Name, err)
+			}
+			pos += size
+
+			// 递增外层索引
+			i := k - 1
+			for ; i >= 0; i-- {
+				idx[i]++
+				if idx[i] < innerCount[i] {
+					break
+				}
+				idx[i] = 0
+			}
+			if i < 0 {
+				break
+			}
+		}
+	}
+
+	return out, n, nil
+}
+
+// decode 将大端字节转换为float64
+func decode(t Type, raw []byte, n int) []float64 {
+	out := make([]float64, n)
+	switch t {
+	case Byte:
+		for i := range out {
+			out[i] = float64(int8(raw[i]))
+		}
+	case UByte:
+		for i := range out {
+			out[i] = float64(raw[i])
+		}
+	case Short:
+		for i := range out {
+			out[i] = float64(int16(binary.BigEndian.Uint16(raw[i*2:])))
+		}
+	case UShort:
+		for i := range out {
+			out[i] = float64(binary.BigEndian.Uint16(raw[i*2:]))
+		}
+	case Int:
+		for i := range out {
+			out[i] = float64(int32(binary.BigEndian.Uint32(raw[i*4:])))
+		}
+	case UInt:
+		for i := range out {
+			out[i] = float64(binary.BigEndian.Uint32(raw[i*4:]))
+		}
+	case Float:
+		for i := range out {
+			out[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(raw[i*4:])))
+		}
+	case Double:
+		for i := range out {
+			out[i] = math.Float64frombits(binary.BigEndian.Uint64(raw[i*8:]))
+		}
+	case Int64:
+		for i := range out {
+			out[i] = float64(int64(binary.BigEndian.Uint64(raw[i*8:])))
+		}
+	case UInt64:
+		for i := range out {
+			out[i] = float64(binary.BigEndian.Uint64(raw[i*8:]))
+		}
+	}
+	return out
+}
+
+// headerReader 顺序解析文件头，出错后后续读取均为空操作
+type headerReader struct {
+	r       *bufio.Reader
+	version int
+	limit   int64 // 文件大小，单个属性不超过该值
+	err     error
+}
+
+func (h *headerReader) bytes(n int) []byte {
+	buf := make([]byte, n)
+	if h.err != nil {
+		return buf
+	}
+	if _, err := io.ReadFull(h.r, buf); err != nil {
+		h.err = fmt.Errorf("netcdf: truncated header: %w", err)
+	}
+	return buf
+}
+
+func (h *headerReader) uint32() uint64 {
+	return uint64(binary.BigEndian.Uint32(h.bytes(4)))
+}
+
+func (h *headerReader) uint64() uint64 {
+	return binary.BigEndian.Uint64(h.bytes(8))
+}
+
+// size 读取NON_NEG，CDF-5为8字节
+func (h *headerReader) size() uint64 {
+	if h.version == VersionCDF5 {
+		return h.uint64()
+	}
+	return h.uint32()
+}
+
+// padded 读取n字节并跳过4字节对齐填充
+func (h *headerReader) padded(n int) []byte {
+	buf := h.bytes(n)
+	if pad := (4 - n%4) % 4; pad > 0 {
+		h.bytes(pad)
+	}
+	return buf
+}
+
+func (h *headerReader) name() string {
+	n := h.size()
+	if n > 1<<16 {
+		h.err = fmt.Errorf("netcdf: name too long (%d)", n)
+		return ""
+	}
+	return string(h.padded(int(n)))
+}
+
+// list 读取带标记的列表，标记与数量均为0表示空列表
+func (h *headerReader) list(tag uint64, item func()) error {
+	t := h.uint32()
+	n := h.size()
+	if h.err != nil {
+		return h.err
+	}
+	if t == 0 && n == 0 {
+		return nil
+	}
+	if t != tag {
+		return fmt.Errorf("netcdf: unexpected header tag 0x%x", t)
+	}
+	for i := uint64(0); i < n && h.err == nil; i++ {
+		item()
+	}
+	return h.err
+}
+
+func (h *headerReader) attrs() ([]Attribute, error) {
+	var attrs []Attribute
+	err := h.list(tagAttribute, func() {
+		a := Attribute{Name: h.name(), Type: Type(h.uint32())}
+		n := h.size()
+		size := a.Type.Size()
+		if size == 0 {
+			h.err = fmt.Errorf("netcdf: attribute %s has unknown type %d", a.Name, int(a.Type))
+			return
+		}
+		if n > 1<<28 || int64(n)*int64(size) > h.limit {
+			h.err = fmt.Errorf("netcdf: attribute %s too large", a.Name)
+			return
+		}
+		raw := h.padded(int(n) * size)
+		if a.Type == Char {
+			a.Value = trimNull(string(raw))
+		} else {
+			a.Value = decode(a.Type, raw, int(n))
+		}
+		attrs = append(attrs, a)
+	})
+	return attrs, err
+}
+
+// trimNull 去除字符串末尾的空字符
+func trimNull(s string) string {
+	for len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	return s
+}
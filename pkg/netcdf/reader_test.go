@@ -0,0 +1,223 @@
+package netcdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDim 构造文件头用的维度，长度为0表示记录维度
+type testDim struct {
+	name   string
+	length uint32
+}
+
+// testVar 构造文件头用的变量，offset为相对数据区起点的偏移
+type testVar struct {
+	name   string
+	dims   []uint32
+	typ    Type
+	vsize  uint32
+	offset uint32
+}
+
+// cdf1 构造CDF-1文件: 文件头之后紧跟data
+func cdf1(numRecs uint32, dims []testDim, vars []testVar, data []byte) []byte {
+	header := func(base uint32) []byte {
+		var buf bytes.Buffer
+		u32 := func(x uint32) { binary.Write(&buf, binary.BigEndian, x) }
+		name := func(s string) {
+			u32(uint32(len(s)))
+			buf.WriteString(s)
+			buf.Write(make([]byte, (4-len(s)%4)%4))
+		}
+		buf.WriteString("CDF\x01")
+		u32(numRecs)
+		u32(tagDimension)
+		u32(uint32(len(dims)))
+		for _, d := range dims {
+			name(d.name)
+			u32(d.length)
+		}
+		u32(0)
+		u32(0)
+		u32(tagVariable)
+		u32(uint32(len(vars)))
+		for _, v := range vars {
+			name(v.name)
+			u32(uint32(len(v.dims)))
+			for _, id := range v.dims {
+				u32(id)
+			}
+			u32(0)
+			u32(0)
+			u32(uint32(v.typ))
+			u32(v.vsize)
+			u32(base + v.offset)
+		}
+		return buf.Bytes()
+	}
+	h := header(uint32(len(header(0))))
+	return append(h, data...)
+}
+
+// be 编码大端int16序列
+func be(values ...int16) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, values)
+	return buf.Bytes()
+}
+
+func open(t *testing.T, data []byte) *File {
+	t.Helper()
+	f, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return f
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	w := NewWriter()
+	w.AddDim("y", 2)
+	w.AddDim("x", 3)
+	w.AddAttr("title", Char, "test")
+	w.AddVar("t", Short, []string{"y", "x"})
+	w.AddVarAttr("t", "scale_factor", Double, []float64{0.5})
+	w.SetData("t", []float64{1, 2, 3, 4, 5, 6})
+	w.AddVar("name", Char, []string{"x"})
+	w.SetData("name", []byte("abc"))
+
+	path := filepath.Join(t.TempDir(), "test.nc")
+	if err := w.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+
+	if a, ok := f.Attr("title"); !ok || a.String() != "test" {
+		t.Errorf("title = %v", a.Value)
+	}
+	v := f.Var("t")
+	if a, ok := v.Attr("scale_factor"); !ok || a.Floats()[0] != 0.5 {
+		t.Errorf("scale_factor = %v", a.Value)
+	}
+	got, err := v.ReadFloat64([]int{1, 1}, []int{1, 2})
+	if err != nil {
+		t.Fatalf("ReadFloat64: %v", err)
+	}
+	if len(got) != 2 || got[0] != 5 || got[1] != 6 {
+		t.Errorf("t[1,1:3] = %v, want [5 6]", got)
+	}
+	if b, err := f.Var("name").ReadBytes(nil, nil); err != nil || string(b) != "abc" {
+		t.Errorf("name = %q, %v", b, err)
+	}
+	if _, err := v.ReadFloat64([]int{1, 2}, []int{1, 2}); err == nil {
+		t.Error("out of range slice should fail")
+	}
+}
+
+func TestNewReaderRecords(t *testing.T) {
+	// 两个记录变量a(time)、b(time, x)，每条记录 a 2字节+填充2字节、b 4字节
+	dims := []testDim{{"time", 0}, {"x", 2}}
+	vars := []testVar{
+		{"a", []uint32{0}, Short, 4, 0},
+		{"b", []uint32{0, 1}, Short, 4, 4},
+	}
+	data := bytes.Join([][]byte{be(1, 0, 10, 11), be(2, 0, 20, 21), be(3, 0, 30, 31)}, nil)
+	f := open(t, cdf1(3, dims, vars, data))
+	if f.NumRecs != 3 || f.Dims[0].Len != 3 {
+		t.Fatalf("NumRecs = %d, time = %d, want 3", f.NumRecs, f.Dims[0].Len)
+	}
+	a, err := f.Var("a").ReadFloat64(nil, nil)
+	if err != nil || len(a) != 3 || a[0] != 1 || a[2] != 3 {
+		t.Errorf("a = %v, %v", a, err)
+	}
+	b, err := f.Var("b").ReadFloat64([]int{1, 1}, []int{2, 1})
+	if err != nil || len(b) != 2 || b[0] != 21 || b[1] != 31 {
+		t.Errorf("b[1:3,1] = %v, %v", b, err)
+	}
+
+	// 记录数未知时根据文件大小计算
+	f = open(t, cdf1(streamingRecords, dims, vars, data[:len(data)-2]))
+	if f.NumRecs != 2 {
+		t.Errorf("streaming NumRecs = %d, want 2", f.NumRecs)
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"dimension too large",
+			cdf1(0, []testDim{{"x", math.MaxUint32}, {"y", math.MaxUint32}}, []testVar{{"v", []uint32{0, 1}, Double, 8, 0}}, nil),
+			"dimension x too large"},
+		{"element count overflow",
+			cdf1(0, []testDim{{"x", math.MaxInt32}, {"y", math.MaxInt32}, {"z", math.MaxInt32}}, []testVar{{"v", []uint32{0, 1, 2}, Double, 8, 0}}, nil),
+			"too large"},
+		{"data past end of file",
+			cdf1(0, []testDim{{"x", 4}}, []testVar{{"v", []uint32{0}, Short, 8, 0}}, be(1, 2, 3)),
+			"exceeds file size"},
+		{"offset past end of file",
+			cdf1(0, []testDim{{"x", 2}}, []testVar{{"v", []uint32{0}, Short, 4, 1 << 20}}, be(1, 2)),
+			"exceeds file size"},
+		{"records past end of file",
+			cdf1(5, []testDim{{"time", 0}}, []testVar{{"v", []uint32{0}, Short, 4, 0}}, be(1, 2, 3)),
+			"exceeds file size"},
+		{"record variable larger than record",
+			cdf1(1, []testDim{{"time", 0}, {"x", 4}}, []testVar{{"a", []uint32{0}, Short, 4, 0}, {"b", []uint32{0, 1}, Short, 0, 4}}, be(1, 0, 1, 2, 3, 4)),
+			"larger than the record size"},
+	}
+	for _, tt := range tests {
+		_, err := NewReader(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func FuzzNewReader(f *testing.F) {
+	f.Add(cdf1(2, []testDim{{"time", 0}, {"x", 2}},
+		[]testVar{{"a", []uint32{0}, Short, 4, 0}, {"b", []uint32{0, 1}, Short, 4, 4}},
+		be(1, 0, 10, 11, 2, 0, 20, 21)))
+	f.Add(cdf1(0, []testDim{{"x", 3}}, []testVar{{"v", []uint32{0}, Int, 12, 0}}, be(0, 1, 0, 2, 0, 3)))
+
+	path := filepath.Join(f.TempDir(), "seed.nc")
+	w := NewWriter()
+	w.AddDim("x", 3)
+	w.AddAttr("title", Char, "seed")
+	w.AddVar("v", Double, []string{"x"})
+	w.SetData("v", []float64{1, 2, 3})
+	if err := w.WriteFile(path); err != nil {
+		f.Fatal(err)
+	}
+	seed, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(seed)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		nc, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		// 通过检查的文件读取全部变量不应panic，数据量不超过文件大小
+		for _, v := range nc.Vars {
+			if v.Type == Char {
+				v.ReadBytes(nil, nil)
+			} else {
+				v.ReadFloat64(nil, nil)
+			}
+		}
+	})
+}
@@ -0,0 +1,356 @@
+package netcdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Writer 在内存中构建NetCDF经典格式文件，数据超过2GB时自动使用CDF-2
+type Writer struct {
+	dims  []*Dimension
+	attrs []Attribute
+	vars  []*writerVar
+}
+
+// writerVar 待写入的变量
+type writerVar struct {
+	Variable
+	data interface{} // []float64 或 []byte
+}
+
+// NewWriter 创建写入器
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// AddDim 添加维度
+func (w *Writer) AddDim(name string, length int) error {
+	if length <= 0 {
+		// 经典格式中长度为0的维度表示记录维度
+		return fmt.Errorf("netcdf: dimension %s must have positive length", name)
+	}
+	for _, d := range w.dims {
+		if d.Name == name {
+			return fmt.Errorf("netcdf: duplicate dimension %s", name)
+		}
+	}
+	w.dims = append(w.dims, &Dimension{Name: name, Len: length})
+	return nil
+}
+
+// AddAttr 添加全局属性，value为string或[]float64
+func (w *Writer) AddAttr(name string, typ Type, value interface{}) {
+	w.attrs = append(w.attrs, Attribute{Name: name, Type: typ, Value: value})
+}
+
+// AddVar 添加变量
+func (w *Writer) AddVar(name string, typ Type, dims []string) error {
+	if w.findVar(name) != nil {
+		return fmt.Errorf("netcdf: duplicate variable %s", name)
+	}
+	if typ < Byte || typ > Double {
+		// 无符号及64位整数类型仅CDF-5支持
+		return fmt.Errorf("netcdf: type %s is not supported by the classic format", typ)
+	}
+
+	v := &writerVar{Variable: Variable{Name: name, Type: typ}}
+	for _, dn := range dims {
+		var dim *Dimension
+		for _, d := range w.dims {
+			if d.Name == dn {
+				dim = d
+			}
+		}
+		if dim == nil {
+			return fmt.Errorf("netcdf: variable %s references unknown dimension %s", name, dn)
+		}
+		v.Dims = append(v.Dims, dim)
+	}
+	w.vars = append(w.vars, v)
+	return nil
+}
+
+// AddVarAttr 添加变量属性，value为string或[]float64
+func (w *Writer) AddVarAttr(varName, name string, typ Type, value interface{}) error {
+	v := w.findVar(varName)
+	if v == nil {
+		return fmt.Errorf("netcdf: unknown variable %s", varName)
+	}
+	v.Attrs = append(v.Attrs, Attribute{Name: name, Type: typ, Value: value})
+	return nil
+}
+
+// SetData 设置变量数据，数值变量为[]float64，字符变量为[]byte
+func (w *Writer) SetData(varName string, data interface{}) error {
+	v := w.findVar(varName)
+	if v == nil {
+		return fmt.Errorf("netcdf: unknown variable %s", varName)
+	}
+
+	n := 1
+	for _, d := range v.Dims {
+		n *= d.Len
+	}
+
+	switch d := data.(type) {
+	case []float64:
+		if v.Type == Char {
+			return fmt.Errorf("netcdf: char variable %s requires []byte data", varName)
+		}
+		if len(d) != n {
+			return fmt.Errorf("netcdf: variable %s expects %d values, got %d", varName, n, len(d))
+		}
+	case []byte:
+		if v.Type.Size() != 1 {
+			return fmt.Errorf("netcdf: variable %s requires []float64 data", varName)
+		}
+		if len(d) != n {
+			return fmt.Errorf("netcdf: variable %s expects %d values, got %d", varName, n, len(d))
+		}
+	default:
+		return fmt.Errorf("netcdf: unsupported data type %T for %s", data, varName)
+	}
+
+	v.data = data
+	return nil
+}
+
+func (w *Writer) findVar(name string) *writerVar {
+	for _, v := range w.vars {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// WriteFile 写入文件
+func (w *Writer) WriteFile(path string) error {
+	// 计算各变量大小
+	var total int64
+	for _, v := range w.vars {
+		v.vsize = int64(v.Type.Size())
+		for _, d := range v.Dims {
+			v.vsize *= int64(d.Len)
+		}
+		v.vsize = pad4(v.vsize)
+		total += v.vsize
+	}
+
+	version := VersionClassic
+	if total > math.MaxInt32 {
+		version = Version64Bit
+	}
+
+	// 先以零偏移计算文件头长度，再确定各变量的起始位置
+	headerLen := int64(len(w.header(version)))
+	offset := headerLen
+	for _, v := range w.vars {
+		v.begin = offset
+		offset += v.vsize
+	}
+	header := w.header(version)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+
+	if _, err := bw.Write(header); err != nil {
+		f.Close()
+		return err
+	}
+	for _, v := range w.vars {
+		if err := writeData(bw, v); err != nil {
+			f.Close()
+			return fmt.Errorf("netcdf: write %s: %w", v.Name, err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// header 编码文件头
+func (w *Writer) header(version int) []byte {
+	var b []byte
+	u32 := func(x uint32) { b = binary.BigEndian.AppendUint32(b, x) }
+	name := func(s string) {
+		u32(uint32(len(s)))
+		b = append(b, s...)
+		b = append(b, make([]byte, pad4(int64(len(s)))-int64(len(s)))...)
+	}
+	attrs := func(list []Attribute) {
+		if len(list) == 0 {
+			u32(0)
+			u32(0)
+			return
+		}
+		u32(tagAttribute)
+		u32(uint32(len(list)))
+		for _, a := range list {
+			name(a.Name)
+			u32(uint32(a.Type))
+			raw := encodeAttr(a)
+			u32(uint32(len(raw) / a.Type.Size()))
+			b = append(b, raw...)
+			b = append(b, make([]byte, pad4(int64(len(raw)))-int64(len(raw)))...)
+		}
+	}
+
+	b = append(b, 'C', 'D', 'F', byte(version))
+	u32(0) // numrecs
+
+	if len(w.dims) == 0 {
+		u32(0)
+		u32(0)
+	} else {
+		u32(tagDimension)
+		u32(uint32(len(w.dims)))
+		for _, d := range w.dims {
+			name(d.Name)
+			u32(uint32(d.Len))
+		}
+	}
+
+	attrs(w.attrs)
+
+	if len(w.vars) == 0 {
+		u32(0)
+		u32(0)
+		return b
+	}
+	u32(tagVariable)
+	u32(uint32(len(w.vars)))
+	for _, v := range w.vars {
+		name(v.Name)
+		u32(uint32(len(v.Dims)))
+		for _, d := range v.Dims {
+			for i, wd := range w.dims {
+				if wd == d {
+					u32(uint32(i))
+				}
+			}
+		}
+		attrs(v.Attrs)
+		u32(uint32(v.Type))
+		vsize := v.vsize
+		if vsize > math.MaxUint32 {
+			vsize = math.MaxUint32
+		}
+		u32(uint32(vsize))
+		if version == VersionClassic {
+			u32(uint32(v.begin))
+		} else {
+			b = binary.BigEndian.AppendUint64(b, uint64(v.begin))
+		}
+	}
+	return b
+}
+
+// encodeAttr 编码属性值
+func encodeAttr(a Attribute) []byte {
+	switch v := a.Value.(type) {
+	case string:
+		return []byte(v)
+	case []float64:
+		return encode(a.Type, v)
+	}
+	return nil
+}
+
+// writeData 写入变量数据，未设置数据时写入填充值
+func writeData(w *bufio.Writer, v *writerVar) error {
+	var raw []byte
+	switch d := v.data.(type) {
+	case []byte:
+		raw = d
+	case []float64:
+		raw = encode(v.Type, d)
+	default:
+		n := v.vsize / int64(v.Type.Size())
+		fill := make([]float64, n)
+		for i := range fill {
+			fill[i] = fillValue(v.Type)
+		}
+		raw = encode(v.Type, fill)
+	}
+
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	_, err := w.Write(make([]byte, v.vsize-int64(len(raw))))
+	return err
+}
+
+// encode 将float64编码为大端字节，整数类型四舍五入，NaN写为该类型的默认填充值
+func encode(t Type, values []float64) []byte {
+	out := make([]byte, len(values)*t.Size())
+	for i, x := range values {
+		if math.IsNaN(x) {
+			x = fillValue(t)
+		}
+		switch t {
+		case Byte:
+			out[i] = byte(int8(math.Round(x)))
+		case Char, UByte:
+			out[i] = byte(math.Round(x))
+		case Short:
+			binary.BigEndian.PutUint16(out[i*2:], uint16(int16(math.Round(x))))
+		case UShort:
+			binary.BigEndian.PutUint16(out[i*2:], uint16(math.Round(x)))
+		case Int:
+			binary.BigEndian.PutUint32(out[i*4:], uint32(int32(math.Round(x))))
+		case UInt:
+			binary.BigEndian.PutUint32(out[i*4:], uint32(math.Round(x)))
+		case Float:
+			binary.BigEndian.PutUint32(out[i*4:], math.Float32bits(float32(x)))
+		case Double:
+			binary.BigEndian.PutUint64(out[i*8:], math.Float64bits(x))
+		case Int64:
+			binary.BigEndian.PutUint64(out[i*8:], uint64(int64(math.Round(x))))
+		case UInt64:
+			binary.BigEndian.PutUint64(out[i*8:], uint64(math.Round(x)))
+		}
+	}
+	return out
+}
+
+// fillValue 各类型的默认填充值
+func fillValue(t Type) float64 {
+	switch t {
+	case Byte:
+		return -127
+	case Char:
+		return 0
+	case Short:
+		return -32767
+	case Int:
+		return -2147483647
+	case Float, Double:
+		return 9.969209968386869e36
+	case UByte:
+		return 255
+	case UShort:
+		return 65535
+	case UInt:
+		return 4294967295
+	case Int64:
+		return -9223372036854775806
+	case UInt64:
+		return 18446744073709551614
+	}
+	return 0
+}
+
+// pad4 对齐到4字节
+func pad4(n int64) int64 {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,285 @@
+// Package particle 拉格朗日粒子追踪: 在随时间变化的流场中用四阶Runge-Kutta方法平流粒子，
+// 可叠加风致漂移和随机游走扩散，粒子到达陆地(流场缺测)时搁浅、离开流场范围时出界
+package particle
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// earthRadius 地球平均半径(m)
+const earthRadius = 6371000.0
+
+// metersPerDegree 纬度每度对应的距离(m)
+const metersPerDegree = earthRadius * math.Pi / 180
+
+// Field 速度场
+type Field interface {
+	// Velocity 时刻t位置(lat, lng)处的东向、北向速度(m/s)，ok为false表示该处缺测(陆地)或超出范围
+	Velocity(t time.Time, lat, lng float64) (u, v float64, ok bool)
+	// Contains 位置是否在速度场的范围内
+	Contains(lat, lng float64) bool
+}
+
+// GridField 规则经纬度网格上的速度场快照序列: 空间上双线性插值(任一角点缺测视为陆地)，时间上线性插值，
+// 超出快照时间范围时取最近的快照；只有一个快照时为定常场
+type GridField struct {
+	MinLat, MinLng float64
+	Step           float64 // 格距(度)
+	Rows, Cols     int
+	Times          []time.Time // 快照时间，升序
+	U, V           [][]float32 // 各快照按纬度升序逐行展开的分量，缺测为NaN
+}
+
+// Contains 位置是否在网格范围内
+func (f *GridField) Contains(lat, lng float64) bool {
+	if f.Rows < 2 || f.Cols < 2 {
+		return false
+	}
+	y, x := (lat-f.MinLat)/f.Step, (lng-f.MinLng)/f.Step
+	return y >= 0 && x >= 0 && y <= float64(f.Rows-1) && x <= float64(f.Cols-1)
+}
+
+// Velocity 时刻t位置(lat, lng)处的速度
+func (f *GridField) Velocity(t time.Time, lat, lng float64) (u, v float64, ok bool) {
+	if len(f.Times) == 0 || !f.Contains(lat, lng) {
+		return math.NaN(), math.NaN(), false
+	}
+	k := sort.Search(len(f.Times), func(i int) bool { return !f.Times[i].Before(t) })
+	if k == 0 || k == len(f.Times) {
+		if k == len(f.Times) {
+			k--
+		}
+		return f.spatial(k, lat, lng)
+	}
+	u0, v0, ok0 := f.spatial(k-1, lat, lng)
+	u1, v1, ok1 := f.spatial(k, lat, lng)
+	if !ok0 || !ok1 {
+		return math.NaN(), math.NaN(), false
+	}
+	w := float64(t.Sub(f.Times[k-1])) / float64(f.Times[k].Sub(f.Times[k-1]))
+	return u0 + w*(u1-u0), v0 + w*(v1-v0), true
+}
+
+// spatial 第k个快照在(lat, lng)处的双线性插值
+func (f *GridField) spatial(k int, lat, lng float64) (u, v float64, ok bool) {
+	y, x := (lat-f.MinLat)/f.Step, (lng-f.MinLng)/f.Step
+	i, j := int(math.Floor(y)), int(math.Floor(x))
+	if i >= f.Rows-1 {
+		i = f.Rows - 2
+	}
+	if j >= f.Cols-1 {
+		j = f.Cols - 2
+	}
+	if i < 0 {
+		i = 0
+	}
+	if j < 0 {
+		j = 0
+	}
+	wy, wx := y-float64(i), x-float64(j)
+	weights := [4]float64{(1 - wy) * (1 - wx), (1 - wy) * wx, wy * (1 - wx), wy * wx}
+	cells := [4]int{i*f.Cols + j, i*f.Cols + j + 1, (i+1)*f.Cols + j, (i+1)*f.Cols + j + 1}
+	for c, cell := range cells {
+		cu, cv := float64(f.U[k][cell]), float64(f.V[k][cell])
+		if math.IsNaN(cu) || math.IsNaN(cv) {
+			return math.NaN(), math.NaN(), false
+		}
+		u += weights[c] * cu
+		v += weights[c] * cv
+	}
+	return u, v, true
+}
+
+// 粒子状态
+const (
+	Active   = "active"   // 追踪结束时仍在海上
+	Stranded = "stranded" // 到达陆地(流场缺测处)
+	Outside  = "outside"  // 离开流场范围
+)
+
+// Particle 释放的粒子
+type Particle struct {
+	Release int // 所属释放源的序号
+	Lat     float64
+	Lng     float64
+	Time    time.Time // 释放时间
+}
+
+// Trajectory 粒子轨迹，第一个点为释放位置
+type Trajectory struct {
+	Particle Particle
+	Times    []time.Time
+	Lats     []float64
+	Lngs     []float64
+	Status   string
+	StopTime time.Time // 搁浅或出界的时间，仍在海上时为零值
+}
+
+// Position 某一时刻的粒子位置
+type Position struct {
+	Lat, Lng float64
+	Status   string
+	Released bool
+}
+
+// Options 追踪参数
+type Options struct {
+	Start       time.Time
+	End         time.Time
+	Step        time.Duration // 积分步长
+	Output      time.Duration // 轨迹输出间隔，须为Step的整数倍
+	Wind        Field         // 风场，nil时不计风致漂移
+	Windage     float64       // 风致漂移系数(风速的比例)
+	Diffusivity float64       // 水平扩散系数(m²/s)，0时不做随机游走
+	Seed        int64         // 随机游走的随机数种子
+	Snapshots   []time.Time   // 需要粒子位置的时刻(升序)
+}
+
+// Track 从Start积分到End: 每步用RK4求平流位移(任一阶段位于陆地时退化为前向Euler)，再叠加标准差为√(2KΔt)的随机位移
+// (落到陆地时放弃该步的随机位移)；返回各粒子的轨迹及Snapshots各时刻(取不早于该时刻的第一个积分时刻)的位置
+func Track(particles []Particle, current Field, opts Options) ([]Trajectory, [][]Position) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	trajectories := make([]Trajectory, len(particles))
+	released := make([]bool, len(particles))
+	lats := make([]float64, len(particles))
+	lngs := make([]float64, len(particles))
+	for i, p := range particles {
+		trajectories[i] = Trajectory{Particle: p, Status: Active}
+		lats[i], lngs[i] = p.Lat, p.Lng
+	}
+	snapshots := make([][]Position, len(opts.Snapshots))
+	next := 0
+
+	outputEvery := int(opts.Output / opts.Step)
+	if outputEvery < 1 {
+		outputEvery = 1
+	}
+	t := opts.Start
+	for n := 0; ; n++ {
+		for i := range particles {
+			tr := &trajectories[i]
+			if !released[i] {
+				if particles[i].Time.After(t) {
+					continue
+				}
+				released[i] = true
+				tr.add(particles[i].Time, lats[i], lngs[i])
+				if !current.Contains(lats[i], lngs[i]) {
+					tr.Status, tr.StopTime = Outside, particles[i].Time
+				} else if _, _, ok := current.Velocity(particles[i].Time, lats[i], lngs[i]); !ok {
+					tr.Status, tr.StopTime = Stranded, particles[i].Time
+				}
+			} else if n%outputEvery == 0 && tr.Status == Active {
+				tr.add(t, lats[i], lngs[i])
+			}
+		}
+		for next < len(opts.Snapshots) && !opts.Snapshots[next].After(t) {
+			snapshots[next] = positions(trajectories, released, lats, lngs)
+			next++
+		}
+		if !t.Before(opts.End) {
+			break
+		}
+
+		// 最后一步截止到End
+		dt := opts.Step
+		if t.Add(dt).After(opts.End) {
+			dt = opts.End.Sub(t)
+		}
+		h := dt.Seconds()
+		end := t.Add(dt)
+		for i := range particles {
+			tr := &trajectories[i]
+			if !released[i] || tr.Status != Active {
+				continue
+			}
+			lat, lng, ok := advect(current, opts, t, h, lats[i], lngs[i])
+			if !ok {
+				// 停在最后一个有效位置
+				tr.Status, tr.StopTime = Stranded, end
+				if !current.Contains(lat, lng) {
+					tr.Status = Outside
+				}
+				tr.add(end, lats[i], lngs[i])
+				continue
+			}
+			if opts.Diffusivity > 0 {
+				sigma := math.Sqrt(2 * opts.Diffusivity * h)
+				dlat := sigma * rng.NormFloat64() / metersPerDegree
+				dlng := sigma * rng.NormFloat64() / (metersPerDegree * math.Cos(lat*math.Pi/180))
+				if _, _, ok := current.Velocity(end, lat+dlat, lng+dlng); ok {
+					lat, lng = lat+dlat, lng+dlng
+				}
+			}
+			lats[i], lngs[i] = lat, lng
+		}
+		t = end
+	}
+	for i := range trajectories {
+		tr := &trajectories[i]
+		if released[i] && tr.Status == Active && !tr.Times[len(tr.Times)-1].Equal(opts.End) {
+			tr.add(opts.End, lats[i], lngs[i])
+		}
+	}
+	for ; next < len(opts.Snapshots); next++ {
+		snapshots[next] = positions(trajectories, released, lats, lngs)
+	}
+	return trajectories, snapshots
+}
+
+// add 追加轨迹点
+func (tr *Trajectory) add(t time.Time, lat, lng float64) {
+	tr.Times = append(tr.Times, t)
+	tr.Lats = append(tr.Lats, lat)
+	tr.Lngs = append(tr.Lngs, lng)
+}
+
+// positions 当前各粒子的位置
+func positions(trajectories []Trajectory, released []bool, lats, lngs []float64) []Position {
+	out := make([]Position, len(trajectories))
+	for i := range trajectories {
+		out[i] = Position{Lat: lats[i], Lng: lngs[i], Status: trajectories[i].Status, Released: released[i]}
+	}
+	return out
+}
+
+// advect 一个积分步的平流: RK4，任一阶段无速度时退化为前向Euler；终点无速度时ok为false，返回终点位置
+func advect(current Field, opts Options, t time.Time, h, lat, lng float64) (float64, float64, bool) {
+	rate := func(t time.Time, lat, lng float64) (dlat, dlng float64, ok bool) {
+		u, v, ok := current.Velocity(t, lat, lng)
+		if !ok {
+			return 0, 0, false
+		}
+		if opts.Wind != nil && opts.Windage != 0 {
+			if wu, wv, ok := opts.Wind.Velocity(t, lat, lng); ok {
+				u += opts.Windage * wu
+				v += opts.Windage * wv
+			}
+		}
+		return v / metersPerDegree, u / (metersPerDegree * math.Cos(lat*math.Pi/180)), true
+	}
+	half := t.Add(time.Duration(h / 2 * float64(time.Second)))
+	end := t.Add(time.Duration(h * float64(time.Second)))
+
+	y1, x1, ok := rate(t, lat, lng)
+	if !ok {
+		return lat, lng, false
+	}
+	if y2, x2, ok2 := rate(half, lat+h/2*y1, lng+h/2*x1); ok2 {
+		if y3, x3, ok3 := rate(half, lat+h/2*y2, lng+h/2*x2); ok3 {
+			if y4, x4, ok4 := rate(end, lat+h*y3, lng+h*x3); ok4 {
+				nlat := lat + h/6*(y1+2*y2+2*y3+y4)
+				nlng := lng + h/6*(x1+2*x2+2*x3+x4)
+				if _, _, ok := current.Velocity(end, nlat, nlng); ok {
+					return nlat, nlng, true
+				}
+			}
+		}
+	}
+	nlat, nlng := lat+h*y1, lng+h*x1
+	_, _, ok = current.Velocity(end, nlat, nlng)
+	return nlat, nlng, ok
+}
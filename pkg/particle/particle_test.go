@@ -0,0 +1,180 @@
+package particle
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+
+// testField 纬度-2~2°、经度100~104°、格距0.5°的网格，各快照由value(快照序号, 行, 列)给出
+func testField(times []time.Time, value func(k, i, j int) (u, v float32)) *GridField {
+	f := &GridField{MinLat: -2, MinLng: 100, Step: 0.5, Rows: 9, Cols: 9, Times: times}
+	for k := range times {
+		u := make([]float32, f.Rows*f.Cols)
+		v := make([]float32, f.Rows*f.Cols)
+		for i := 0; i < f.Rows; i++ {
+			for j := 0; j < f.Cols; j++ {
+				u[i*f.Cols+j], v[i*f.Cols+j] = value(k, i, j)
+			}
+		}
+		f.U = append(f.U, u)
+		f.V = append(f.V, v)
+	}
+	return f
+}
+
+func uniform(u, v float32) *GridField {
+	return testField([]time.Time{t0}, func(int, int, int) (float32, float32) { return u, v })
+}
+
+func closeTo(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol
+}
+
+func TestGridField(t *testing.T) {
+	// 线性场的双线性插值是精确的; 第二个快照整体加1
+	f := testField([]time.Time{t0, t0.Add(2 * time.Hour)}, func(k, i, j int) (float32, float32) {
+		return float32(i + 2*j + k), float32(3*i - j)
+	})
+	f.U[0][8*f.Cols+8] = float32(math.NaN())
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		lat, lng float64
+		u, v     float64
+		ok       bool
+	}{
+		{"grid point", t0, -1, 101, 2 + 4, 6 - 2, true},
+		{"cell center", t0, -1.25, 101.75, 1.5 + 7, 4.5 - 3.5, true},
+		{"before first snapshot", t0.Add(-time.Hour), -2, 100, 0, 0, true},
+		{"between snapshots", t0.Add(30 * time.Minute), -2, 100, 0.25, 0, true},
+		{"after last snapshot", t0.Add(5 * time.Hour), 0, 102, 4 + 8 + 1, 12 - 4, true},
+		{"east edge", t0.Add(2 * time.Hour), -2, 104, 16 + 1, -8, true},
+		{"land corner", t0, 1.9, 103.9, math.NaN(), math.NaN(), false},
+		{"land in one snapshot", t0.Add(time.Hour), 1.9, 103.9, math.NaN(), math.NaN(), false},
+		{"outside", t0, 2.1, 101, math.NaN(), math.NaN(), false},
+	}
+	for _, tt := range tests {
+		u, v, ok := f.Velocity(tt.t, tt.lat, tt.lng)
+		if ok != tt.ok || ok && (!closeTo(u, tt.u, 1e-9) || !closeTo(v, tt.v, 1e-9)) {
+			t.Errorf("%s: %g, %g, %v, want %g, %g, %v", tt.name, u, v, ok, tt.u, tt.v, tt.ok)
+		}
+	}
+	if !f.Contains(2, 104) || f.Contains(-2.01, 101) || (&GridField{Rows: 1, Cols: 5, Step: 1}).Contains(0, 0) {
+		t.Error("Contains edge handling")
+	}
+}
+
+func TestTrackAdvection(t *testing.T) {
+	day := 24 * time.Hour
+	ramp := testField([]time.Time{t0, t0.Add(day)}, func(k, _, _ int) (float32, float32) { return 0, float32(k) })
+	tests := []struct {
+		name          string
+		field         Field
+		wind          Field
+		windage       float64
+		start         Particle
+		lat, lng      float64
+		status        string
+		stopAfterTime time.Duration
+	}{
+		// 赤道上1 m/s东向流一天移动86.4 km
+		{"eastward", uniform(1, 0), nil, 0, Particle{Lat: 0, Lng: 101}, 0, 101 + 86400/metersPerDegree, Active, 0},
+		{"eastward at 1.5N", uniform(1, 0), nil, 0, Particle{Lat: 1.5, Lng: 101}, 1.5, 101 + 86400/metersPerDegree/math.Cos(1.5*math.Pi/180), Active, 0},
+		// 北向流随时间由0线性增至1 m/s，位移为平均流速0.5 m/s一天的距离
+		{"linear in time", ramp, nil, 0, Particle{Lat: -1, Lng: 102}, -1 + 43200/metersPerDegree, 102, Active, 0},
+		// 3%风致漂移: 10 m/s西风叠加0.3 m/s
+		{"windage", uniform(0, 0), uniform(10, 0), 0.03, Particle{Lat: 0, Lng: 101}, 0, 101 + 0.3*86400/metersPerDegree, Active, 0},
+		// 2 m/s南向流约6.2小时离开网格南界，停在第6小时的位置
+		{"outside", uniform(0, -2), nil, 0, Particle{Lat: -1.6, Lng: 102}, -1.6 - 2*6*3600/metersPerDegree, 102, Outside, 7 * time.Hour},
+	}
+	for _, tt := range tests {
+		tt.start.Time = t0
+		trs, _ := Track([]Particle{tt.start}, tt.field, Options{Start: t0, End: t0.Add(day), Step: time.Hour, Output: 6 * time.Hour,
+			Wind: tt.wind, Windage: tt.windage})
+		tr := trs[0]
+		n := len(tr.Lats) - 1
+		if tr.Status != tt.status || !closeTo(tr.Lats[n], tt.lat, 1e-9) || !closeTo(tr.Lngs[n], tt.lng, 1e-9) {
+			t.Errorf("%s: %s at %.9f, %.9f, want %s at %.9f, %.9f", tt.name, tr.Status, tr.Lats[n], tr.Lngs[n], tt.status, tt.lat, tt.lng)
+		}
+		if tt.status == Active && (len(tr.Times) != 5 || !tr.Times[4].Equal(t0.Add(day)) || !tr.StopTime.IsZero()) {
+			t.Errorf("%s: output times %v", tt.name, tr.Times)
+		}
+		if tt.status != Active && !tr.StopTime.Equal(t0.Add(tt.stopAfterTime)) {
+			t.Errorf("%s: stopped at %s", tt.name, tr.StopTime)
+		}
+	}
+}
+
+func TestTrackStranding(t *testing.T) {
+	// 经度103°以东为陆地
+	f := testField([]time.Time{t0}, func(_, _, j int) (float32, float32) {
+		if j >= 6 {
+			return float32(math.NaN()), float32(math.NaN())
+		}
+		return 1, 0
+	})
+	particles := []Particle{
+		{Release: 0, Lat: 0, Lng: 101, Time: t0},
+		{Release: 1, Lat: 0, Lng: 103.5, Time: t0},                   // 释放在陆地上
+		{Release: 2, Lat: 3, Lng: 101, Time: t0},                     // 释放在网格外
+		{Release: 3, Lat: 1, Lng: 100, Time: t0.Add(12 * time.Hour)}, // 延迟释放
+	}
+	snapshots := []time.Time{t0.Add(6 * time.Hour), t0.Add(30 * time.Minute * 37)}
+	trs, pos := Track(particles, f, Options{Start: t0, End: t0.Add(48 * time.Hour), Step: time.Hour, Output: time.Hour, Snapshots: snapshots})
+
+	// 1 m/s东向流到达102.5°(最后一个四角均为海的格子边缘)约需46.3小时，48小时内第0个粒子已搁浅
+	tr := trs[0]
+	last := len(tr.Lngs) - 1
+	if tr.Status != Stranded || tr.Lngs[last] > 102.5 || tr.Lngs[last] < 102.5-3600/metersPerDegree || !tr.StopTime.Equal(tr.Times[last]) {
+		t.Errorf("particle 0: %s at %g (%s)", tr.Status, tr.Lngs[last], tr.StopTime)
+	}
+	if trs[1].Status != Stranded || !trs[1].StopTime.Equal(t0) || len(trs[1].Times) != 1 {
+		t.Errorf("particle 1 = %+v", trs[1])
+	}
+	if trs[2].Status != Outside || len(trs[2].Times) != 1 {
+		t.Errorf("particle 2 = %+v", trs[2])
+	}
+	if tr := trs[3]; tr.Status != Active || !tr.Times[0].Equal(t0.Add(12*time.Hour)) || len(tr.Times) != 37 ||
+		!closeTo(tr.Lngs[36], 100+36*3600/metersPerDegree/math.Cos(math.Pi/180), 1e-9) {
+		t.Errorf("particle 3: %s, %d points from %s", tr.Status, len(tr.Times), tr.Times[0])
+	}
+
+	if len(pos) != 2 || pos[0][3].Released || !pos[1][3].Released || pos[0][1].Status != Stranded ||
+		!closeTo(pos[0][0].Lng, 101+6*3600/metersPerDegree, 1e-9) {
+		t.Errorf("snapshots = %+v", pos)
+	}
+}
+
+func TestTrackDiffusion(t *testing.T) {
+	// 静止水体中随机游走: 一天后各方向位移方差为2Kt
+	const k = 10.0
+	particles := make([]Particle, 2000)
+	for i := range particles {
+		particles[i] = Particle{Lat: 0, Lng: 102, Time: t0}
+	}
+	opts := Options{Start: t0, End: t0.Add(24 * time.Hour), Step: time.Hour, Output: 24 * time.Hour, Diffusivity: k, Seed: 7}
+	trs, _ := Track(particles, uniform(0, 0), opts)
+	var sx, sy float64
+	for _, tr := range trs {
+		n := len(tr.Lats) - 1
+		dy := tr.Lats[n] * metersPerDegree
+		dx := (tr.Lngs[n] - 102) * metersPerDegree
+		sx += dx * dx
+		sy += dy * dy
+	}
+	want := 2 * k * 86400
+	for _, got := range []float64{sx / 2000, sy / 2000} {
+		if !closeTo(got, want, 0.1*want) {
+			t.Errorf("displacement variance = %g, want %g", got, want)
+		}
+	}
+
+	again, _ := Track(particles, uniform(0, 0), opts)
+	if again[1999].Lats[1] != trs[1999].Lats[1] || again[1999].Lngs[1] != trs[1999].Lngs[1] {
+		t.Error("same seed should reproduce the same walk")
+	}
+}
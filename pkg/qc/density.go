@@ -0,0 +1,10 @@
+package qc
+
+import "github.com/sinker/ssop/pkg/gsw"
+
+// Density0 按TEOS-10(75项多项式)计算海面压力下的海水密度(kg/m3)，与分析中的σ0一致；
+// s为实用盐度(绝对盐度不含δSA)，t为温度(°C)。用于相邻层次间的密度逆转判断，以现场温度近似位温
+func Density0(s, t float64) float64 {
+	sa := gsw.SAFromSP(s, 0, 0, 0)
+	return gsw.Rho(sa, gsw.CTFromPt(sa, t), 0)
+}
@@ -0,0 +1,257 @@
+// Package qc 实现海洋观测数据的自动质量控制检验，标志约定参照IOOS QARTOD
+package qc
+
+import (
+	"math"
+)
+
+// 质量标志(QARTOD)
+const (
+	Pass         byte = 1 // 通过
+	NotEvaluated byte = 2 // 未检验
+	Suspect      byte = 3 // 可疑
+	Fail         byte = 4 // 错误
+	Missing      byte = 9 // 缺测
+)
+
+// FlagMeanings 标志含义，写入flag_meanings属性
+const FlagMeanings = "pass not_evaluated suspect fail missing_data"
+
+// FlagValues 全部标志值，与FlagMeanings对应
+var FlagValues = []byte{Pass, NotEvaluated, Suspect, Fail, Missing}
+
+// severity 标志的严重程度，用于合并多个检验的结果
+func severity(f byte) int {
+	switch f {
+	case Missing:
+		return 4
+	case Fail:
+		return 3
+	case Suspect:
+		return 2
+	case Pass:
+		return 1
+	}
+	return 0
+}
+
+// NewFlags 初始化标志，缺测值为Missing，其余为NotEvaluated
+func NewFlags(values []float64) []byte {
+	flags := make([]byte, len(values))
+	for i, x := range values {
+		if math.IsNaN(x) {
+			flags[i] = Missing
+		} else {
+			flags[i] = NotEvaluated
+		}
+	}
+	return flags
+}
+
+// Merge 将检验结果合并到汇总标志中，取更严重的标志；检验结果中的缺测(因其他输入缺测而未检验)不合并
+func Merge(dst, src []byte) {
+	for i := range dst {
+		if i < len(src) && src[i] != Missing && severity(src[i]) > severity(dst[i]) {
+			dst[i] = src[i]
+		}
+	}
+}
+
+// Counts 统计各标志的数量
+func Counts(flags []byte) map[byte]int64 {
+	counts := make(map[byte]int64, len(FlagValues))
+	for _, f := range flags {
+		counts[f]++
+	}
+	return counts
+}
+
+// Range 范围检验参数，超出Fail范围为错误，超出Suspect范围为可疑；上下限均为0时不检验对应级别
+type Range struct {
+	FailMin    float64 `json:"failMin"`
+	FailMax    float64 `json:"failMax"`
+	SuspectMin float64 `json:"suspectMin"`
+	SuspectMax float64 `json:"suspectMax"`
+}
+
+// RangeTest 范围检验，apply为nil时检验全部值，否则仅检验apply返回true的值
+func RangeTest(values []float64, r Range, apply func(i int) bool) []byte {
+	hasFail := r.FailMin != 0 || r.FailMax != 0
+	hasSuspect := r.SuspectMin != 0 || r.SuspectMax != 0
+
+	flags := NewFlags(values)
+	for i, x := range values {
+		if flags[i] == Missing || (apply != nil && !apply(i)) {
+			continue
+		}
+		switch {
+		case hasFail && (x < r.FailMin || x > r.FailMax):
+			flags[i] = Fail
+		case hasSuspect && (x < r.SuspectMin || x > r.SuspectMax):
+			flags[i] = Suspect
+		default:
+			flags[i] = Pass
+		}
+	}
+	return flags
+}
+
+// Threshold 阈值检验参数，超过Fail为错误，超过Suspect为可疑，为0时不检验对应级别
+type Threshold struct {
+	Suspect float64 `json:"suspect"`
+	Fail    float64 `json:"fail"`
+}
+
+// classify 按阈值判定
+func (t Threshold) classify(x float64) byte {
+	switch {
+	case t.Fail > 0 && x > t.Fail:
+		return Fail
+	case t.Suspect > 0 && x > t.Suspect:
+		return Suspect
+	}
+	return Pass
+}
+
+// SpikeTest 尖峰检验(Argo): |V2-(V3+V1)/2| - |(V3-V1)/2|，扣除相邻两点自身的变化以避免尖峰两侧的点被误判，
+// 序列首尾及相邻缺测的点不检验
+func SpikeTest(series []float64, t Threshold) []byte {
+	flags := NewFlags(series)
+	for i := 1; i+1 < len(series); i++ {
+		prev, cur, next := series[i-1], series[i], series[i+1]
+		if math.IsNaN(prev) || math.IsNaN(cur) || math.IsNaN(next) {
+			continue
+		}
+		flags[i] = t.classify(math.Abs(cur-(prev+next)/2) - math.Abs(next-prev)/2)
+	}
+	return flags
+}
+
+// RateOfChangeTest 变化率检验: 相邻两点的变化率 |v[i]-v[i-1]| / |x[i]-x[i-1]|，x为nil时间隔按1计算；
+// 与QARTOD的梯度检验(同尖峰检验，比较与前后两点平均值的偏差)不同，只比较相邻两点
+func RateOfChangeTest(series, x []float64, t Threshold) []byte {
+	flags := NewFlags(series)
+	for i := 1; i < len(series); i++ {
+		if math.IsNaN(series[i]) || math.IsNaN(series[i-1]) {
+			continue
+		}
+		dx := 1.0
+		if x != nil {
+			dx = math.Abs(x[i] - x[i-1])
+			if dx == 0 || math.IsNaN(dx) {
+				continue
+			}
+		}
+		f := t.classify(math.Abs(series[i]-series[i-1]) / dx)
+		if severity(f) > severity(flags[i]) {
+			flags[i] = f
+		}
+	}
+	return flags
+}
+
+// FlatLine 平直检验参数，连续SuspectCount个值的变化不超过Tolerance为可疑，连续FailCount个为错误
+type FlatLine struct {
+	Tolerance    float64 `json:"tolerance"`
+	SuspectCount int     `json:"suspectCount"`
+	FailCount    int     `json:"failCount"`
+}
+
+// FlatLineTest 平直(传感器卡滞)检验
+func FlatLineTest(series []float64, p FlatLine) []byte {
+	flags := NewFlags(series)
+	run := 1 // 以当前点结尾、与当前值相差不超过容差的连续点数
+	for i := range series {
+		if math.IsNaN(series[i]) {
+			run = 1
+			continue
+		}
+		if i > 0 && !math.IsNaN(series[i-1]) && math.Abs(series[i]-series[i-1]) <= p.Tolerance {
+			run++
+		} else {
+			run = 1
+		}
+
+		switch {
+		case p.FailCount > 1 && run >= p.FailCount:
+			flags[i] = Fail
+		case p.SuspectCount > 1 && run >= p.SuspectCount:
+			flags[i] = Suspect
+		default:
+			flags[i] = Pass
+		}
+	}
+	return flags
+}
+
+// DensityInversionTest 密度逆转检验: 沿压力增加方向密度减小超过threshold(kg/m3)时，标记逆转两侧的点为错误
+func DensityInversionTest(density, pressure []float64, threshold float64) []byte {
+	flags := NewFlags(density)
+	prev := -1
+	for i := range density {
+		if math.IsNaN(density[i]) || math.IsNaN(pressure[i]) {
+			continue
+		}
+		flags[i] = Pass
+		if prev >= 0 {
+			d := density[i] - density[prev]
+			if pressure[i] < pressure[prev] {
+				d = -d
+			}
+			if d < -threshold {
+				flags[i] = Fail
+				flags[prev] = Fail
+			}
+		}
+		prev = i
+	}
+	return flags
+}
+
+// ForEachSeries 沿axis维遍历多维数组中的每条一维序列，fn接收序列中各元素在展开数组中的下标
+func ForEachSeries(shape []int, axis int, fn func(indices []int)) {
+	if len(shape) == 0 {
+		fn([]int{0})
+		return
+	}
+
+	stride := 1
+	for _, s := range shape[axis+1:] {
+		stride *= s
+	}
+	n := 1
+	for _, s := range shape {
+		n *= s
+	}
+	length := shape[axis]
+	if length == 0 {
+		return
+	}
+
+	indices := make([]int, length)
+	block := stride * length
+	for outer := 0; outer < n; outer += block {
+		for inner := 0; inner < stride; inner++ {
+			for k := 0; k < length; k++ {
+				indices[k] = outer + inner + k*stride
+			}
+			fn(indices)
+		}
+	}
+}
+
+// Gather 按下标取值
+func Gather(values []float64, indices []int) []float64 {
+	out := make([]float64, len(indices))
+	for i, idx := range indices {
+		out[i] = values[idx]
+	}
+	return out
+}
+
+// Scatter 将序列的检验结果写回展开数组
+func Scatter(dst []byte, indices []int, flags []byte) {
+	for i, idx := range indices {
+		dst[idx] = flags[i]
+	}
+}
@@ -0,0 +1,173 @@
+package qc
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+func TestRangeTest(t *testing.T) {
+	values := []float64{-3, -2.5, 0, 30, 35, 41, nan}
+	tests := []struct {
+		name  string
+		r     Range
+		apply func(int) bool
+		want  []byte
+	}{
+		{"fail and suspect", Range{FailMin: -2.5, FailMax: 40, SuspectMin: -2, SuspectMax: 32},
+			nil, []byte{Fail, Suspect, Pass, Pass, Suspect, Fail, Missing}},
+		{"fail only", Range{FailMin: -2.5, FailMax: 40},
+			nil, []byte{Fail, Pass, Pass, Pass, Pass, Fail, Missing}},
+		{"no limits", Range{},
+			nil, []byte{Pass, Pass, Pass, Pass, Pass, Pass, Missing}},
+		{"partial", Range{FailMin: 0, FailMax: 1},
+			func(i int) bool { return i >= 3 }, []byte{NotEvaluated, NotEvaluated, NotEvaluated, Fail, Fail, Fail, Missing}},
+	}
+	for _, tt := range tests {
+		if got := RangeTest(values, tt.r, tt.apply); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSpikeTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		want   []byte
+	}{
+		// 尖峰值: |12-(10+10)/2| - |10-10|/2 = 2
+		{"spike", []float64{10, 10, 12, 10, 10}, []byte{NotEvaluated, Pass, Suspect, Pass, NotEvaluated}},
+		{"large spike", []float64{10, 10, 16, 10, 10}, []byte{NotEvaluated, Pass, Fail, Pass, NotEvaluated}},
+		// 线性变化不是尖峰
+		{"gradient", []float64{0, 5, 10, 15}, []byte{NotEvaluated, Pass, Pass, NotEvaluated}},
+		// 台阶: |10-(0+10)/2| - |10-0|/2 = 0
+		{"step", []float64{0, 0, 10, 10}, []byte{NotEvaluated, Pass, Pass, NotEvaluated}},
+		{"missing", []float64{10, nan, 12, 10, 10}, []byte{NotEvaluated, Missing, NotEvaluated, Pass, NotEvaluated}},
+	}
+	for _, tt := range tests {
+		if got := SpikeTest(tt.series, Threshold{Suspect: 1, Fail: 5}); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRateOfChangeTest(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []float64
+		x      []float64
+		want   []byte
+	}{
+		{"unit spacing", []float64{0, 0.5, 2, 6, nan, 7},
+			nil, []byte{NotEvaluated, Pass, Suspect, Fail, Missing, NotEvaluated}},
+		// 按坐标间隔计算变化率: 2/4=0.5, 4/2=2, 3/0.5=6
+		{"spacing", []float64{0, 2, 6, 9},
+			[]float64{0, 4, 6, 6.5}, []byte{NotEvaluated, Pass, Suspect, Fail}},
+		{"zero spacing", []float64{0, 100},
+			[]float64{1, 1}, []byte{NotEvaluated, NotEvaluated}},
+	}
+	for _, tt := range tests {
+		if got := RateOfChangeTest(tt.series, tt.x, Threshold{Suspect: 1, Fail: 3}); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFlatLineTest(t *testing.T) {
+	series := []float64{1, 1, 1.05, 1, 1, 2, 2, nan, 2, 2}
+	want := []byte{Pass, Pass, Suspect, Suspect, Fail, Pass, Pass, Missing, Pass, Pass}
+	if got := FlatLineTest(series, FlatLine{Tolerance: 0.1, SuspectCount: 3, FailCount: 5}); !bytes.Equal(got, want) {
+		t.Errorf("FlatLineTest = %v, want %v", got, want)
+	}
+}
+
+func TestDensityInversionTest(t *testing.T) {
+	tests := []struct {
+		name              string
+		density, pressure []float64
+		want              []byte
+	}{
+		{"stable", []float64{1025, 1025.5, 1026}, []float64{0, 10, 20}, []byte{Pass, Pass, Pass}},
+		{"inversion", []float64{1025, 1025.5, 1025.2, 1026}, []float64{0, 10, 20, 30}, []byte{Pass, Fail, Fail, Pass}},
+		{"below threshold", []float64{1025, 1025.5, 1025.47}, []float64{0, 10, 20}, []byte{Pass, Pass, Pass}},
+		// 上升剖面按压力方向判断
+		{"ascending", []float64{1026, 1025.5, 1025.8}, []float64{20, 10, 0}, []byte{Pass, Fail, Fail}},
+		// 缺测点跳过，与上一个有效点比较
+		{"missing", []float64{1025.5, nan, 1025}, []float64{0, 10, 20}, []byte{Fail, Missing, Fail}},
+	}
+	for _, tt := range tests {
+		if got := DensityInversionTest(tt.density, tt.pressure, 0.03); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDensity0(t *testing.T) {
+	// UNESCO(1981)国际海水状态方程的检验值
+	tests := []struct{ s, t, want float64 }{
+		{0, 5, 999.96675},
+		{35, 5, 1027.67547},
+		{35, 25, 1023.34306},
+	}
+	for _, tt := range tests {
+		if got := Density0(tt.s, tt.t); math.Abs(got-tt.want) > 0.003 {
+			t.Errorf("Density0(%g, %g) = %.5f, want %.5f", tt.s, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := []byte{NotEvaluated, Pass, Suspect, Pass, Missing}
+	Merge(dst, []byte{Pass, Suspect, Pass, Missing, Pass})
+	want := []byte{Pass, Suspect, Suspect, Pass, Missing}
+	if !bytes.Equal(dst, want) {
+		t.Errorf("Merge = %v, want %v", dst, want)
+	}
+	if c := Counts(dst); c[Pass] != 2 || c[Suspect] != 2 || c[Missing] != 1 || c[Fail] != 0 {
+		t.Errorf("Counts = %v", c)
+	}
+}
+
+func TestForEachSeries(t *testing.T) {
+	tests := []struct {
+		shape []int
+		axis  int
+		want  [][]int
+	}{
+		{[]int{2, 3}, 0, [][]int{{0, 3}, {1, 4}, {2, 5}}},
+		{[]int{2, 3}, 1, [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{[]int{2, 2, 2}, 1, [][]int{{0, 2}, {1, 3}, {4, 6}, {5, 7}}},
+		{[]int{3, 0}, 0, nil},
+		{nil, 0, [][]int{{0}}},
+	}
+	for _, tt := range tests {
+		var got [][]int
+		ForEachSeries(tt.shape, tt.axis, func(indices []int) {
+			got = append(got, append([]int(nil), indices...))
+		})
+		if len(got) != len(tt.want) {
+			t.Errorf("ForEachSeries(%v, %d) = %v, want %v", tt.shape, tt.axis, got, tt.want)
+			continue
+		}
+		for i := range got {
+			for j := range got[i] {
+				if got[i][j] != tt.want[i][j] {
+					t.Errorf("ForEachSeries(%v, %d) = %v, want %v", tt.shape, tt.axis, got, tt.want)
+				}
+			}
+		}
+	}
+
+	values := []float64{0, 1, 2, 3, 4, 5}
+	flags := make([]byte, 6)
+	ForEachSeries([]int{2, 3}, 0, func(indices []int) {
+		series := Gather(values, indices)
+		Scatter(flags, indices, []byte{byte(series[0]), byte(series[1])})
+	})
+	if !bytes.Equal(flags, []byte{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("Gather/Scatter = %v", flags)
+	}
+}
@@ -0,0 +1,368 @@
+// Package resample 按日历周期(时、日、周、月、季节、年及其倍数)对时间序列重采样和聚合
+package resample
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+	"github.com/sinker/ssop/pkg/stats"
+)
+
+// 周期单位
+const (
+	Hour   = "hour"
+	Day    = "day"
+	Week   = "week"   // 以周一为一周开始
+	Month  = "month"  // 日历月
+	Season = "season" // 气象季节: DJF、MAM、JJA、SON，12月计入次年的冬季
+	Year   = "year"
+)
+
+// ErrInvalidPeriod 周期格式无效
+var ErrInvalidPeriod = errors.New("resample: invalid period")
+
+// ErrInvalidReducer 聚合方法无效
+var ErrInvalidReducer = errors.New("resample: invalid reducer")
+
+// unitSuffixes 自定义周期(如 "10D"、"3M")的单位后缀
+var unitSuffixes = map[string]string{"H": Hour, "D": Day, "W": Week, "M": Month, "Y": Year}
+
+// seasonNames 各季节的名称，下标为季节起始月份(12、3、6、9)除以3后对4取模
+var seasonNames = [4]string{"DJF", "MAM", "JJA", "SON"}
+
+// Period 重采样周期: N个Unit，按小时、天、周计的周期从Origin起算，按月、年计的周期从公元0年1月起算
+// (如 "3M" 为各季度，"6M" 为上下半年)；Unit为空时不重采样
+type Period struct {
+	Unit   string
+	N      int
+	Origin time.Time
+}
+
+// ParsePeriod 解析周期: hour、day、week、month、season、year，或数字加单位后缀H、D、W、M、Y(如 "6H"、"10D"、"3M"、"5Y")；
+// 空字符串表示不重采样。origin为按小时、天、周计的多倍周期的起点，零值时使用默认起点；单倍周期按日历边界划分
+func ParsePeriod(s string, origin time.Time) (Period, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "":
+		return Period{}, nil
+	case Hour, Day, Week, Month, Season, Year:
+		unit := strings.ToLower(s)
+		return Period{Unit: unit, N: 1, Origin: defaultOrigin(unit)}, nil
+	}
+
+	unit, ok := unitSuffixes[strings.ToUpper(s[len(s)-1:])]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if !ok || err != nil || n < 1 {
+		return Period{}, fmt.Errorf("%w %q", ErrInvalidPeriod, s)
+	}
+	if origin.IsZero() {
+		origin = defaultOrigin(unit)
+	}
+	return Period{Unit: unit, N: n, Origin: origin.UTC()}, nil
+}
+
+// defaultOrigin 按小时、天、周计的周期的默认起点: 1970-01-01，周为1970-01-05(周一)
+func defaultOrigin(unit string) time.Time {
+	if unit == Week {
+		return time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+// IsZero 是否不重采样
+func (p Period) IsZero() bool {
+	return p.Unit == ""
+}
+
+// String 周期的文本形式，与ParsePeriod的输入格式一致
+func (p Period) String() string {
+	if p.Unit == "" || p.N == 1 {
+		return p.Unit
+	}
+	for suffix, unit := range unitSuffixes {
+		if unit == p.Unit {
+			return strconv.Itoa(p.N) + suffix
+		}
+	}
+	return p.Unit
+}
+
+// Start 时间t所在周期的起始时间
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	switch p.Unit {
+	case Hour, Day, Week:
+		step := map[string]time.Duration{Hour: time.Hour, Day: 24 * time.Hour, Week: 7 * 24 * time.Hour}[p.Unit] * time.Duration(p.N)
+		origin := p.Origin.UTC()
+		k := t.Sub(origin) / step
+		if t.Before(origin.Add(k * step)) {
+			k--
+		}
+		return origin.Add(k * step)
+	case Month, Year:
+		months := p.N
+		if p.Unit == Year {
+			months *= 12
+		}
+		index := floorDiv(t.Year()*12+int(t.Month())-1, months) * months
+		return time.Date(floorDiv(index, 12), time.Month(index-floorDiv(index, 12)*12+1), 1, 0, 0, 0, 0, time.UTC)
+	case Season:
+		year, month := t.Year(), int(t.Month())
+		if month == 12 {
+			return time.Date(year, 12, 1, 0, 0, 0, 0, time.UTC)
+		}
+		start := month - month%3 // 1、2月为上一年12月，返回0后由time.Date规范化
+		return time.Date(year, time.Month(start), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// End 以start为起点的周期的结束时间(下一个周期的起点)
+func (p Period) End(start time.Time) time.Time {
+	switch p.Unit {
+	case Hour:
+		return start.Add(time.Duration(p.N) * time.Hour)
+	case Day:
+		return start.AddDate(0, 0, p.N)
+	case Week:
+		return start.AddDate(0, 0, 7*p.N)
+	case Month:
+		return start.AddDate(0, p.N, 0)
+	case Season:
+		return start.AddDate(0, 3, 0)
+	case Year:
+		return start.AddDate(p.N, 0, 0)
+	}
+	return start
+}
+
+// Label 周期的标签: 季节为 "1991-DJF"(按1月所在年份)，月为 "1991-01"，年为 "1991"，其余为起始时间
+func (p Period) Label(start time.Time) string {
+	switch {
+	case p.Unit == Season:
+		year := start.Year()
+		if start.Month() == 12 {
+			year++
+		}
+		return fmt.Sprintf("%d-%s", year, seasonNames[int(start.Month())/3%4])
+	case p.Unit == Month && p.N == 1:
+		return start.Format("2006-01")
+	case p.Unit == Year && p.N == 1:
+		return start.Format("2006")
+	}
+	return start.Format(time.RFC3339)
+}
+
+// floorDiv 向下取整的整数除法
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// Reducer 聚合方法: mean、min、max、sum、count、median 或 percentile(Percentile为0~100)
+type Reducer struct {
+	Name       string
+	Percentile float64
+}
+
+// ParseReducer 解析聚合方法，percentile也可写作 "p90"；空字符串为mean
+func ParseReducer(name string, percentile float64) (Reducer, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch name {
+	case "":
+		return Reducer{Name: "mean"}, nil
+	case "mean", "min", "max", "sum", "count", "median":
+		return Reducer{Name: name}, nil
+	case "percentile":
+	default:
+		p, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64)
+		if !strings.HasPrefix(name, "p") || err != nil {
+			return Reducer{}, fmt.Errorf("%w %q", ErrInvalidReducer, name)
+		}
+		percentile = p
+	}
+	if percentile < 0 || percentile > 100 || math.IsNaN(percentile) {
+		return Reducer{}, fmt.Errorf("%w: percentile must be between 0 and 100", ErrInvalidReducer)
+	}
+	return Reducer{Name: "percentile", Percentile: percentile}, nil
+}
+
+// String 聚合方法的文本形式
+func (r Reducer) String() string {
+	if r.Name == "percentile" {
+		return "p" + strconv.FormatFloat(r.Percentile, 'f', -1, 64)
+	}
+	return r.Name
+}
+
+// Reduce 聚合有效值，values为空时count为0、其余为NaN；会重排values
+func (r Reducer) Reduce(values []float64) float64 {
+	if r.Name == "count" {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	switch r.Name {
+	case "min", "max":
+		x := values[0]
+		for _, v := range values[1:] {
+			if (r.Name == "min" && v < x) || (r.Name == "max" && v > x) {
+				x = v
+			}
+		}
+		return x
+	case "sum", "mean":
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		if r.Name == "sum" {
+			return sum
+		}
+		return sum / float64(len(values))
+	case "median":
+		return stats.Median(values)
+	case "percentile":
+		sort.Float64s(values)
+		return stats.Percentile(values, r.Percentile)
+	}
+	return math.NaN()
+}
+
+// Options 重采样参数
+type Options struct {
+	Period      Period
+	Reducer     Reducer
+	MinCount    int     // 周期内的最少有效值个数，默认1
+	MinCoverage float64 // 周期内有效值占应有时次数的最低比例(0~1)
+	// Calendar 源数据的模式日历(noleap、360_day等)，应有时次数按该日历的月长和年长计算；nil为公历
+	Calendar *dataio.Calendar
+}
+
+// Bin 一个周期的聚合结果
+type Bin struct {
+	Start    time.Time
+	End      time.Time
+	Value    float64 // 不满足最少个数或覆盖率要求时为NaN(count不受限制)
+	Count    int     // 有效值个数
+	Expected int     // 应有时次数: 周期长度除以序列的典型时间间隔(时间差的中位数)
+}
+
+// Coverage 有效值覆盖率，不超过1
+func (b Bin) Coverage() float64 {
+	if b.Expected <= 0 {
+		return 1
+	}
+	return math.Min(float64(b.Count)/float64(b.Expected), 1)
+}
+
+// Resample 按周期聚合时间序列，times为零值的时次忽略，values中的NaN为缺测；
+// 结果按时间升序，包含所有有时次(即使全为缺测)的周期。不重采样时每个时次为一个周期
+func Resample(times []time.Time, values []float64, opts Options) []Bin {
+	step := TypicalStep(times)
+
+	groups := map[time.Time][]float64{}
+	var starts []time.Time
+	for i, t := range times {
+		if t.IsZero() {
+			continue
+		}
+		start := t
+		if !opts.Period.IsZero() {
+			start = opts.Period.Start(t)
+		}
+		group, ok := groups[start]
+		if !ok {
+			starts = append(starts, start)
+		}
+		if !math.IsNaN(values[i]) {
+			group = append(group, values[i])
+		}
+		groups[start] = group
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	bins := make([]Bin, len(starts))
+	for i, start := range starts {
+		group := groups[start]
+		b := Bin{Start: start, End: start, Count: len(group)}
+		if !opts.Period.IsZero() {
+			b.End = opts.Period.End(start)
+			b.Expected = opts.Period.Expected(start, step, opts.Calendar)
+		}
+		b.Value = opts.Reducer.Reduce(group)
+		if opts.Reducer.Name != "count" && !opts.Accept(b.Count, b.Expected) {
+			b.Value = math.NaN()
+		}
+		bins[i] = b
+	}
+	return bins
+}
+
+// Expected 以start为起点的周期内按时间间隔step应有的时次数，step不大于0时为0；
+// 按月、季节、年计的周期长度取日历cal中的天数(nil为公历)
+func (p Period) Expected(start time.Time, step time.Duration, cal *dataio.Calendar) int {
+	if step <= 0 || p.IsZero() {
+		return 0
+	}
+	length := float64(p.End(start).Sub(start))
+	if cal != nil {
+		months := 0
+		switch p.Unit {
+		case Month:
+			months = p.N
+		case Season:
+			months = 3
+		case Year:
+			months = 12 * p.N
+		}
+		if months > 0 {
+			days := 0
+			for i := 0; i < months; i++ {
+				days += cal.MonthDays[(int(start.Month())-1+i)%12]
+			}
+			length = float64(days) * float64(24*time.Hour)
+		}
+	}
+	return int(math.Max(math.Round(length/float64(step)), 1))
+}
+
+// Accept 周期内的有效值个数count是否满足最少个数和覆盖率要求，expected为应有时次数(0为不限制覆盖率)
+func (o Options) Accept(count, expected int) bool {
+	minCount := o.MinCount
+	if minCount < 1 {
+		minCount = 1
+	}
+	return count >= minCount && (Bin{Count: count, Expected: expected}).Coverage() >= o.MinCoverage
+}
+
+// TypicalStep 相邻时次时间差的中位数，少于两个时次时为0
+func TypicalStep(times []time.Time) time.Duration {
+	var valid []time.Time
+	for _, t := range times {
+		if !t.IsZero() {
+			valid = append(valid, t)
+		}
+	}
+	sort.Slice(valid, func(i, j int) bool { return valid[i].Before(valid[j]) })
+	var diffs []float64
+	for i := 1; i < len(valid); i++ {
+		if d := valid[i].Sub(valid[i-1]); d > 0 {
+			diffs = append(diffs, float64(d))
+		}
+	}
+	if len(diffs) == 0 {
+		return 0
+	}
+	return time.Duration(stats.Median(diffs))
+}
@@ -0,0 +1,160 @@
+package resample
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sinker/ssop/pkg/dataio"
+)
+
+// dailySeries 按日历calendar从2000-01-01起逐日的完整序列，值为日序号
+func dailySeries(t *testing.T, calendar string, days int) ([]time.Time, []float64, *dataio.Calendar) {
+	t.Helper()
+	u, err := dataio.ParseTimeUnits("days since 2000-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Calendar, err = dataio.ParseCalendar(calendar); err != nil {
+		t.Fatal(err)
+	}
+	times := make([]time.Time, days)
+	values := make([]float64, days)
+	for i := range times {
+		times[i] = u.Time(float64(i))
+		values[i] = float64(i)
+	}
+	return times, values, u.Calendar
+}
+
+func TestResampleCalendar(t *testing.T) {
+	tests := []struct {
+		calendar string
+		period   string
+		days     int
+		expected []int
+	}{
+		{"", "month", 366, []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+		{"noleap", "month", 365, []int{31, 28, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+		{"all_leap", "month", 366, []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}},
+		{"360_day", "month", 360, []int{30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30}},
+		{"noleap", "year", 730, []int{365, 365}},
+		{"360_day", "year", 720, []int{360, 360}},
+		{"360_day", "season", 360, []int{90, 90, 90, 90, 90}},
+		{"noleap", "3M", 365, []int{90, 91, 92, 92}},
+	}
+	for _, tt := range tests {
+		times, values, cal := dailySeries(t, tt.calendar, tt.days)
+		period, err := ParsePeriod(tt.period, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reducer, _ := ParseReducer("mean", 0)
+		bins := Resample(times, values, Options{Period: period, Reducer: reducer, MinCoverage: 1, Calendar: cal})
+		if len(bins) != len(tt.expected) {
+			t.Errorf("%s %s: %d bins, want %d", tt.calendar, tt.period, len(bins), len(tt.expected))
+			continue
+		}
+		for i, b := range bins {
+			if b.Expected != tt.expected[i] {
+				t.Errorf("%s %s %s: expected = %d, want %d", tt.calendar, tt.period, period.Label(b.Start), b.Expected, tt.expected[i])
+			}
+			// 季节的首尾周期(上一年12月起、当年12月)数据不完整
+			partial := period.Unit == Season && (i == 0 || i == len(bins)-1)
+			if !partial && (b.Count != b.Expected || math.IsNaN(b.Value)) {
+				t.Errorf("%s %s %s: count = %d/%d, value = %g, complete period rejected",
+					tt.calendar, tt.period, period.Label(b.Start), b.Count, b.Expected, b.Value)
+			}
+		}
+	}
+}
+
+func TestResampleCoverage(t *testing.T) {
+	times, values, _ := dailySeries(t, "", 31)
+	values[3], values[4] = math.NaN(), math.NaN()
+	period, _ := ParsePeriod("month", time.Time{})
+	bins := Resample(times, values, Options{Period: period, Reducer: Reducer{Name: "mean"}, MinCoverage: 0.95})
+	if len(bins) != 1 || bins[0].Count != 29 || bins[0].Expected != 31 {
+		t.Fatalf("bins = %+v", bins)
+	}
+	if got := bins[0].Coverage(); math.Abs(got-29.0/31) > 1e-12 {
+		t.Errorf("coverage = %g, want %g", got, 29.0/31)
+	}
+	if !math.IsNaN(bins[0].Value) {
+		t.Errorf("value = %g, want NaN below coverage 0.95", bins[0].Value)
+	}
+	bins = Resample(times, values, Options{Period: period, Reducer: Reducer{Name: "count"}, MinCoverage: 0.99})
+	if bins[0].Value != 29 {
+		t.Errorf("count = %g, want 29", bins[0].Value)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	at := time.Date(1991, 1, 15, 13, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period     string
+		start, end time.Time
+		label      string
+	}{
+		{"hour", time.Date(1991, 1, 15, 13, 0, 0, 0, time.UTC), time.Date(1991, 1, 15, 14, 0, 0, 0, time.UTC), "1991-01-15T13:00:00Z"},
+		{"6H", time.Date(1991, 1, 15, 12, 0, 0, 0, time.UTC), time.Date(1991, 1, 15, 18, 0, 0, 0, time.UTC), "1991-01-15T12:00:00Z"},
+		{"day", time.Date(1991, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(1991, 1, 16, 0, 0, 0, 0, time.UTC), "1991-01-15T00:00:00Z"},
+		{"week", time.Date(1991, 1, 14, 0, 0, 0, 0, time.UTC), time.Date(1991, 1, 21, 0, 0, 0, 0, time.UTC), "1991-01-14T00:00:00Z"},
+		{"month", time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1991, 2, 1, 0, 0, 0, 0, time.UTC), "1991-01"},
+		{"season", time.Date(1990, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(1991, 3, 1, 0, 0, 0, 0, time.UTC), "1991-DJF"},
+		{"6M", time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1991, 7, 1, 0, 0, 0, 0, time.UTC), "1991-01-01T00:00:00Z"},
+		{"year", time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(1992, 1, 1, 0, 0, 0, 0, time.UTC), "1991"},
+		{"10Y", time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), "1990-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		p, err := ParsePeriod(tt.period, time.Time{})
+		if err != nil {
+			t.Fatalf("ParsePeriod(%q): %v", tt.period, err)
+		}
+		start := p.Start(at)
+		if !start.Equal(tt.start) || !p.End(start).Equal(tt.end) {
+			t.Errorf("%s: [%v, %v), want [%v, %v)", tt.period, start, p.End(start), tt.start, tt.end)
+		}
+		if got := p.Label(start); got != tt.label {
+			t.Errorf("%s: label = %q, want %q", tt.period, got, tt.label)
+		}
+		if got := p.String(); got != tt.period {
+			t.Errorf("%s: String() = %q", tt.period, got)
+		}
+	}
+	for _, s := range []string{"0D", "-1M", "fortnight", "3X", "M"} {
+		if _, err := ParsePeriod(s, time.Time{}); err == nil {
+			t.Errorf("ParsePeriod(%q) should fail", s)
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	values := []float64{4, 1, 3, 2, 10}
+	tests := []struct {
+		name       string
+		percentile float64
+		want       float64
+	}{
+		{"mean", 0, 4},
+		{"sum", 0, 20},
+		{"min", 0, 1},
+		{"max", 0, 10},
+		{"median", 0, 3},
+		{"count", 0, 5},
+		{"percentile", 50, 3},
+		{"percentile", 100, 10},
+	}
+	for _, tt := range tests {
+		r, err := ParseReducer(tt.name, tt.percentile)
+		if err != nil {
+			t.Fatalf("ParseReducer(%q): %v", tt.name, err)
+		}
+		if got := r.Reduce(append([]float64(nil), values...)); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s: Reduce = %g, want %g", r, got, tt.want)
+		}
+	}
+	if got := (Reducer{Name: "mean"}).Reduce(nil); !math.IsNaN(got) {
+		t.Errorf("mean of nothing = %g, want NaN", got)
+	}
+}
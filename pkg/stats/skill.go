@@ -0,0 +1,87 @@
+package stats
+
+import "math"
+
+// Skill 模式与观测的对比指标，差值为 模式 - 观测
+type Skill struct {
+	Count       int
+	ObsMean     float64
+	ModelMean   float64
+	Bias        float64 // 平均偏差
+	RMSE        float64 // 均方根误差
+	MAE         float64 // 平均绝对误差
+	Correlation float64 // Pearson相关系数
+	PValue      float64 // 相关系数的双侧t检验p值
+	Willmott    float64 // Willmott(1981)一致性指数 d，0~1
+	ObsStd      float64 // 观测的标准差(总体)
+	ModelStd    float64 // 模式的标准差(总体)
+	CRMSE       float64 // 中心化均方根误差(去掉平均偏差后的RMSE)
+}
+
+// Taylor Taylor(2001)图的坐标: 以观测标准差归一化的模式标准差和中心化RMSE，观测位于(1, 1, 0)
+func (s Skill) Taylor() (normalizedStd, correlation, normalizedCRMSE float64) {
+	if !(s.ObsStd > 0) {
+		return math.NaN(), s.Correlation, math.NaN()
+	}
+	return s.ModelStd / s.ObsStd, s.Correlation, s.CRMSE / s.ObsStd
+}
+
+// NewSkill 计算配对样本model、obs的对比指标，跳过任一方为NaN的样本；无有效样本时各指标为NaN，
+// 样本数少于3或任一方方差为0时相关系数为NaN
+func NewSkill(model, obs []float64) Skill {
+	s := Skill{ObsMean: math.NaN(), ModelMean: math.NaN(), Bias: math.NaN(), RMSE: math.NaN(), MAE: math.NaN(),
+		Correlation: math.NaN(), PValue: math.NaN(), Willmott: math.NaN(), ObsStd: math.NaN(), ModelStd: math.NaN(), CRMSE: math.NaN()}
+	var sumM, sumO float64
+	for i := range obs {
+		if math.IsNaN(model[i]) || math.IsNaN(obs[i]) {
+			continue
+		}
+		s.Count++
+		sumM += model[i]
+		sumO += obs[i]
+	}
+	if s.Count == 0 {
+		return s
+	}
+	n := float64(s.Count)
+	s.ModelMean, s.ObsMean = sumM/n, sumO/n
+
+	// 第二遍按偏离均值累加，避免大均值时的精度损失
+	var sse, sae, smm, soo, smo, potential float64
+	for i := range obs {
+		if math.IsNaN(model[i]) || math.IsNaN(obs[i]) {
+			continue
+		}
+		d := model[i] - obs[i]
+		sse += d * d
+		sae += math.Abs(d)
+		dm, do := model[i]-s.ModelMean, obs[i]-s.ObsMean
+		smm += dm * dm
+		soo += do * do
+		smo += dm * do
+		// d的分母 Σ(|P-Ō|+|O-Ō|)² 两项均相对观测均值
+		p := math.Abs(model[i]-s.ObsMean) + math.Abs(do)
+		potential += p * p
+	}
+	s.Bias = s.ModelMean - s.ObsMean
+	s.RMSE = math.Sqrt(sse / n)
+	s.MAE = sae / n
+	s.ModelStd, s.ObsStd = math.Sqrt(smm/n), math.Sqrt(soo/n)
+	s.CRMSE = math.Sqrt(math.Max(sse/n-s.Bias*s.Bias, 0))
+	if potential > 0 {
+		s.Willmott = 1 - sse/potential
+	} else if sse == 0 {
+		s.Willmott = 1
+	}
+	if s.Count >= 3 && smm > 0 && soo > 0 {
+		r := smo / math.Sqrt(smm*soo)
+		s.Correlation = math.Max(-1, math.Min(1, r))
+		df := n - 2
+		if math.Abs(s.Correlation) == 1 {
+			s.PValue = 0
+		} else {
+			s.PValue = StudentTTest(s.Correlation*math.Sqrt(df/(1-s.Correlation*s.Correlation)), df)
+		}
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// closeTo 比较浮点数，两者均为NaN时视为相等
+func closeTo(got, want, tol float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) <= tol
+}
+
+func TestNewSkill(t *testing.T) {
+	nan := math.NaN()
+	obs := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		name  string
+		model []float64
+		obs   []float64
+		want  Skill
+	}{
+		{"perfect fit", []float64{1, 2, 3, 4, 5}, obs,
+			Skill{Count: 5, ObsMean: 3, ModelMean: 3, Bias: 0, RMSE: 0, MAE: 0, Correlation: 1, PValue: 0,
+				Willmott: 1, ObsStd: math.Sqrt2, ModelStd: math.Sqrt2, CRMSE: 0}},
+		// d = 1 - 500/640，分母两项均相对观测均值
+		{"constant bias", []float64{11, 12, 13, 14, 15}, obs,
+			Skill{Count: 5, ObsMean: 3, ModelMean: 13, Bias: 10, RMSE: 10, MAE: 10, Correlation: 1, PValue: 0,
+				Willmott: 0.21875, ObsStd: math.Sqrt2, ModelStd: math.Sqrt2, CRMSE: 0}},
+		// r = √0.6，t = 2.1213，df = 3
+		{"partial fit", []float64{2, 4, 5, 4, 5}, obs,
+			Skill{Count: 5, ObsMean: 3, ModelMean: 4, Bias: 1, RMSE: math.Sqrt(1.8), MAE: 1, Correlation: math.Sqrt(0.6),
+				PValue: 0.12402706265755459, Willmott: 1 - 9.0/37, ObsStd: math.Sqrt2, ModelStd: math.Sqrt(1.2), CRMSE: math.Sqrt(0.8)}},
+		{"missing samples skipped", []float64{1, nan, 3, 4, 5, 9}, []float64{1, 2, 3, 4, 5, nan},
+			Skill{Count: 4, ObsMean: 3.25, ModelMean: 3.25, Bias: 0, RMSE: 0, MAE: 0, Correlation: 1, PValue: 0,
+				Willmott: 1, ObsStd: math.Sqrt(2.1875), ModelStd: math.Sqrt(2.1875), CRMSE: 0}},
+		{"constant observations", []float64{1, 2, 3}, []float64{2, 2, 2},
+			Skill{Count: 3, ObsMean: 2, ModelMean: 2, Bias: 0, RMSE: math.Sqrt(2.0 / 3), MAE: 2.0 / 3, Correlation: nan, PValue: nan,
+				Willmott: 0, ObsStd: 0, ModelStd: math.Sqrt(2.0 / 3), CRMSE: math.Sqrt(2.0 / 3)}},
+		{"no samples", []float64{nan}, []float64{1},
+			Skill{ObsMean: nan, ModelMean: nan, Bias: nan, RMSE: nan, MAE: nan, Correlation: nan, PValue: nan,
+				Willmott: nan, ObsStd: nan, ModelStd: nan, CRMSE: nan}},
+	}
+	for _, tt := range tests {
+		got := NewSkill(tt.model, tt.obs)
+		if got.Count != tt.want.Count {
+			t.Errorf("%s: Count = %d, want %d", tt.name, got.Count, tt.want.Count)
+		}
+		fields := []struct {
+			name      string
+			got, want float64
+		}{
+			{"ObsMean", got.ObsMean, tt.want.ObsMean},
+			{"ModelMean", got.ModelMean, tt.want.ModelMean},
+			{"Bias", got.Bias, tt.want.Bias},
+			{"RMSE", got.RMSE, tt.want.RMSE},
+			{"MAE", got.MAE, tt.want.MAE},
+			{"Correlation", got.Correlation, tt.want.Correlation},
+			{"PValue", got.PValue, tt.want.PValue},
+			{"Willmott", got.Willmott, tt.want.Willmott},
+			{"ObsStd", got.ObsStd, tt.want.ObsStd},
+			{"ModelStd", got.ModelStd, tt.want.ModelStd},
+			{"CRMSE", got.CRMSE, tt.want.CRMSE},
+		}
+		for _, f := range fields {
+			if !closeTo(f.got, f.want, 1e-9) {
+				t.Errorf("%s: %s = %.15g, want %.15g", tt.name, f.name, f.got, f.want)
+			}
+		}
+		if !math.IsNaN(got.Willmott) && (got.Willmott < 0 || got.Willmott > 1) {
+			t.Errorf("%s: Willmott = %g is outside [0, 1]", tt.name, got.Willmott)
+		}
+	}
+}
+
+func TestSkillTaylor(t *testing.T) {
+	std, r, crmse := NewSkill([]float64{2, 4, 5, 4, 5}, []float64{1, 2, 3, 4, 5}).Taylor()
+	if !closeTo(std, math.Sqrt(0.6), 1e-12) || !closeTo(r, math.Sqrt(0.6), 1e-12) || !closeTo(crmse, math.Sqrt(0.4), 1e-12) {
+		t.Errorf("Taylor = (%g, %g, %g), want (%g, %g, %g)", std, r, crmse, math.Sqrt(0.6), math.Sqrt(0.6), math.Sqrt(0.4))
+	}
+}
@@ -0,0 +1,164 @@
+// Package stats 提供流式统计量、分位数和直方图计算
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// DefaultSampleSize 估算分位数时保留的样本数
+const DefaultSampleSize = 100000
+
+// Summary 描述性统计结果
+type Summary struct {
+	Count   int64   `json:"count"`   // 有效值个数
+	Missing int64   `json:"missing"` // 缺测值个数(NaN或Inf)
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Mean    float64 `json:"mean"`
+	Std     float64 `json:"std"` // 样本标准差
+}
+
+// Accumulator 流式统计累加器，使用Welford算法计算均值方差，并以蓄水池抽样保留样本用于分位数估算
+type Accumulator struct {
+	summary    Summary
+	m2         float64
+	sample     []float64
+	sampleSize int
+	rng        *rand.Rand
+}
+
+// NewAccumulator 创建累加器，sampleSize<=0时使用DefaultSampleSize
+func NewAccumulator(sampleSize int) *Accumulator {
+	if sampleSize <= 0 {
+		sampleSize = DefaultSampleSize
+	}
+	return &Accumulator{
+		summary:    Summary{Min: math.Inf(1), Max: math.Inf(-1)},
+		sampleSize: sampleSize,
+		rng:        rand.New(rand.NewSource(1)),
+	}
+}
+
+// Add 累加数据，NaN与Inf计为缺测
+func (a *Accumulator) Add(values ...float64) {
+	s := &a.summary
+	for _, x := range values {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			s.Missing++
+			continue
+		}
+
+		s.Count++
+		delta := x - s.Mean
+		s.Mean += delta / float64(s.Count)
+		a.m2 += delta * (x - s.Mean)
+		if x < s.Min {
+			s.Min = x
+		}
+		if x > s.Max {
+			s.Max = x
+		}
+
+		if len(a.sample) < a.sampleSize {
+			a.sample = append(a.sample, x)
+		} else if j := a.rng.Int63n(s.Count); j < int64(a.sampleSize) {
+			a.sample[j] = x
+		}
+	}
+}
+
+// Summary 当前统计结果，无有效值时Min/Max/Mean/Std为NaN
+func (a *Accumulator) Summary() Summary {
+	s := a.summary
+	if s.Count == 0 {
+		s.Min, s.Max, s.Mean, s.Std = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+		return s
+	}
+	if s.Count > 1 {
+		s.Std = math.Sqrt(a.m2 / float64(s.Count-1))
+	}
+	return s
+}
+
+// Exact 分位数是否为精确值(样本包含全部有效值)
+func (a *Accumulator) Exact() bool {
+	return a.summary.Count <= int64(a.sampleSize)
+}
+
+// Percentiles 计算分位数，ps取值0-100
+func (a *Accumulator) Percentiles(ps ...float64) []float64 {
+	sorted := append([]float64(nil), a.sample...)
+	sort.Float64s(sorted)
+
+	out := make([]float64, len(ps))
+	for i, p := range ps {
+		out[i] = Percentile(sorted, p)
+	}
+	return out
+}
+
+// Percentile 计算已排序数据的分位数(线性插值)，p取值0-100
+func Percentile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return math.NaN()
+	}
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[n-1]
+	}
+
+	pos := p / 100 * float64(n-1)
+	lo := int(math.Floor(pos))
+	hi := lo + 1
+	if hi >= n {
+		return sorted[n-1]
+	}
+	frac := pos - float64(lo)
+	return sorted[lo] + frac*(sorted[hi]-sorted[lo])
+}
+
+// Histogram 等宽直方图，最后一个区间包含右端点
+type Histogram struct {
+	Edges  []float64 `json:"edges"`  // 区间边界，长度为bins+1
+	Counts []int64   `json:"counts"` // 各区间计数
+}
+
+// NewHistogram 创建[min, max]范围内的等宽直方图
+func NewHistogram(min, max float64, bins int) *Histogram {
+	if bins <= 0 {
+		bins = 1
+	}
+	if max <= min {
+		max = min + 1
+	}
+
+	h := &Histogram{Edges: make([]float64, bins+1), Counts: make([]int64, bins)}
+	width := (max - min) / float64(bins)
+	for i := range h.Edges {
+		h.Edges[i] = min + float64(i)*width
+	}
+	h.Edges[bins] = max
+	return h
+}
+
+// Add 累加数据，范围外的值及NaN忽略
+func (h *Histogram) Add(values ...float64) {
+	bins := len(h.Counts)
+	min, max := h.Edges[0], h.Edges[bins]
+	width := (max - min) / float64(bins)
+	for _, x := range values {
+		if math.IsNaN(x) || x < min || x > max {
+			continue
+		}
+		i := int((x - min) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		h.Counts[i]++
+	}
+}
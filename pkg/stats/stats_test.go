@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAccumulator(t *testing.T) {
+	a := NewAccumulator(0)
+	a.Add(2, 4, 4, 4, math.NaN(), 5, 5, 7, math.Inf(1), 9)
+	s := a.Summary()
+	if s.Count != 8 || s.Missing != 2 || s.Min != 2 || s.Max != 9 {
+		t.Errorf("summary = %+v", s)
+	}
+	if !closeTo(s.Mean, 5, 1e-12) || !closeTo(s.Std, math.Sqrt(32.0/7), 1e-12) {
+		t.Errorf("mean, std = %g, %g, want 5, %g", s.Mean, s.Std, math.Sqrt(32.0/7))
+	}
+	if !a.Exact() {
+		t.Error("Exact() = false with all values sampled")
+	}
+	want := []float64{2, 4, 4.5, 7.6, 9}
+	for i, got := range a.Percentiles(0, 25, 50, 90, 100) {
+		if !closeTo(got, want[i], 1e-12) {
+			t.Errorf("percentiles[%d] = %g, want %g", i, got, want[i])
+		}
+	}
+
+	empty := NewAccumulator(0).Summary()
+	if empty.Count != 0 || !math.IsNaN(empty.Mean) || !math.IsNaN(empty.Min) || !math.IsNaN(empty.Std) {
+		t.Errorf("empty summary = %+v", empty)
+	}
+	if one := func() Summary { a := NewAccumulator(0); a.Add(3); return a.Summary() }(); one.Std != 0 || one.Mean != 3 {
+		t.Errorf("single value summary = %+v", one)
+	}
+}
+
+func TestAccumulatorSampling(t *testing.T) {
+	a := NewAccumulator(100)
+	for i := 0; i < 10000; i++ {
+		a.Add(float64(i))
+	}
+	if a.Exact() {
+		t.Error("Exact() = true with 10000 values and a sample of 100")
+	}
+	if len(a.sample) != 100 {
+		t.Fatalf("sample size = %d, want 100", len(a.sample))
+	}
+	// 均值和方差不受抽样影响
+	s := a.Summary()
+	if !closeTo(s.Mean, 4999.5, 1e-9) || !closeTo(s.Std, math.Sqrt(10000*10001/12.0), 1e-6) {
+		t.Errorf("mean, std = %g, %g", s.Mean, s.Std)
+	}
+	// 均匀分布的中位数估计误差在抽样误差范围内
+	if median := a.Percentiles(50)[0]; math.Abs(median-5000) > 1500 {
+		t.Errorf("sampled median = %g, want about 5000", median)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4}
+	tests := []struct{ p, want float64 }{
+		{-5, 1}, {0, 1}, {25, 1.75}, {50, 2.5}, {100.0 / 3, 2}, {99, 3.97}, {100, 4}, {150, 4},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); !closeTo(got, tt.want, 1e-12) {
+			t.Errorf("Percentile(%g) = %g, want %g", tt.p, got, tt.want)
+		}
+	}
+	if got := Percentile(nil, 50); !math.IsNaN(got) {
+		t.Errorf("Percentile(nil) = %g, want NaN", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(0, 10, 4)
+	h.Add(0, 2.4, 2.5, 5, 7.49, 7.5, 10, -0.1, 10.1, math.NaN())
+	wantEdges := []float64{0, 2.5, 5, 7.5, 10}
+	wantCounts := []int64{2, 1, 2, 2}
+	for i := range wantEdges {
+		if h.Edges[i] != wantEdges[i] {
+			t.Errorf("edges = %v, want %v", h.Edges, wantEdges)
+			break
+		}
+	}
+	for i := range wantCounts {
+		if h.Counts[i] != wantCounts[i] {
+			t.Errorf("counts = %v, want %v", h.Counts, wantCounts)
+			break
+		}
+	}
+
+	// 常数数据和非法区间数
+	h = NewHistogram(3, 3, 0)
+	h.Add(3, 3, 4)
+	if len(h.Counts) != 1 || h.Counts[0] != 3 || h.Edges[1] != 4 {
+		t.Errorf("degenerate histogram = %+v", h)
+	}
+}
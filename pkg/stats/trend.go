@@ -0,0 +1,283 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// LinearFit 最小二乘线性拟合 y = Intercept + Slope*x
+type LinearFit struct {
+	N           int     // 参与拟合的点数
+	Slope       float64 // 斜率
+	Intercept   float64 // 截距(x=0处的拟合值)
+	SlopeStdErr float64 // 斜率的标准误
+	RSquared    float64 // 决定系数
+	PValue      float64 // 斜率为零的双侧t检验p值
+}
+
+// LinearRegression 对(x, y)做最小二乘线性拟合，跳过含NaN的点；点数少于3或x全相同时结果为NaN
+func LinearRegression(x, y []float64) LinearFit {
+	fit := LinearFit{Slope: math.NaN(), Intercept: math.NaN(), SlopeStdErr: math.NaN(), RSquared: math.NaN(), PValue: math.NaN()}
+	var sx, sy float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		sx += x[i]
+		sy += y[i]
+		fit.N++
+	}
+	if fit.N < 3 {
+		return fit
+	}
+	n := float64(fit.N)
+	mx, my := sx/n, sy/n
+
+	var sxx, sxy, syy float64
+	for i := range x {
+		if math.IsNaN(x[i]) || math.IsNaN(y[i]) {
+			continue
+		}
+		dx, dy := x[i]-mx, y[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return fit
+	}
+
+	fit.Slope = sxy / sxx
+	fit.Intercept = my - fit.Slope*mx
+	sse := math.Max(syy-fit.Slope*sxy, 0)
+	if syy > 0 {
+		fit.RSquared = 1 - sse/syy
+	}
+	df := n - 2
+	fit.SlopeStdErr = math.Sqrt(sse / df / sxx)
+	switch {
+	case fit.SlopeStdErr > 0:
+		fit.PValue = StudentTTest(fit.Slope/fit.SlopeStdErr, df)
+	case fit.Slope == 0:
+		fit.PValue = 1
+	default:
+		fit.PValue = 0 // 完全线性
+	}
+	return fit
+}
+
+// SensSlope Theil-Sen斜率: 所有点对斜率的中位数，截距为 y - slope*x 的中位数；跳过含NaN的点，点数少于2时为NaN
+func SensSlope(x, y []float64) (slope, intercept float64) {
+	var xs, ys []float64
+	for i := range x {
+		if !math.IsNaN(x[i]) && !math.IsNaN(y[i]) {
+			xs = append(xs, x[i])
+			ys = append(ys, y[i])
+		}
+	}
+	n := len(xs)
+	slopes := make([]float64, 0, n*(n-1)/2)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if dx := xs[j] - xs[i]; dx != 0 {
+				slopes = append(slopes, (ys[j]-ys[i])/dx)
+			}
+		}
+	}
+	if len(slopes) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	slope = Median(slopes)
+
+	residuals := make([]float64, n)
+	for i := range xs {
+		residuals[i] = ys[i] - slope*xs[i]
+	}
+	return slope, Median(residuals)
+}
+
+// MannKendallResult Mann-Kendall趋势检验结果
+type MannKendallResult struct {
+	N      int     // 样本数
+	S      float64 // 统计量S
+	Z      float64 // 经连续性校正的标准化统计量
+	Tau    float64 // Kendall τ
+	PValue float64 // 无趋势假设的双侧p值
+}
+
+// MannKendall 对按时间排列的序列做Mann-Kendall检验(正态近似，方差含结校正)，跳过NaN；样本数少于3时为NaN
+func MannKendall(y []float64) MannKendallResult {
+	var values []float64
+	for _, v := range y {
+		if !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	n := len(values)
+	res := MannKendallResult{N: n, S: math.NaN(), Z: math.NaN(), Tau: math.NaN(), PValue: math.NaN()}
+	if n < 3 {
+		return res
+	}
+
+	var s float64
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			switch d := values[j] - values[i]; {
+			case d > 0:
+				s++
+			case d < 0:
+				s--
+			}
+		}
+	}
+
+	// 相同值(结)的方差校正
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	fn := float64(n)
+	variance := fn * (fn - 1) * (2*fn + 5)
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n && sorted[j] == sorted[i] {
+			j++
+		}
+		if t := float64(j - i); t > 1 {
+			variance -= t * (t - 1) * (2*t + 5)
+		}
+		i = j
+	}
+	variance /= 18
+
+	res.S = s
+	res.Tau = s / (fn * (fn - 1) / 2)
+	switch {
+	case variance <= 0:
+		res.Z, res.PValue = 0, 1
+		return res
+	case s > 0:
+		res.Z = (s - 1) / math.Sqrt(variance)
+	case s < 0:
+		res.Z = (s + 1) / math.Sqrt(variance)
+	default:
+		res.Z = 0
+	}
+	res.PValue = math.Erfc(math.Abs(res.Z) / math.Sqrt2)
+	return res
+}
+
+// Median 中位数(会重排values)，values为空时为NaN
+func Median(values []float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return math.NaN()
+	}
+	hi := selectKth(values, n/2)
+	if n%2 == 1 {
+		return hi
+	}
+	// 第n/2小的值之前的元素均不大于它，其中的最大值即第n/2-1小的值
+	lo := values[0]
+	for _, v := range values[1 : n/2] {
+		if v > lo {
+			lo = v
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// selectKth 快速选择第k小(从0开始)的值，返回后values[:k]均不大于values[k]
+func selectKth(values []float64, k int) float64 {
+	lo, hi := 0, len(values)-1
+	for lo < hi {
+		pivot := values[lo+(hi-lo)/2]
+		i, j := lo, hi
+		for i <= j {
+			for values[i] < pivot {
+				i++
+			}
+			for values[j] > pivot {
+				j--
+			}
+			if i <= j {
+				values[i], values[j] = values[j], values[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			return values[k]
+		}
+	}
+	return values[k]
+}
+
+// StudentTTest 自由度为df的t统计量的双侧p值
+func StudentTTest(t, df float64) float64 {
+	if math.IsNaN(t) || df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(t, 0) {
+		return 0
+	}
+	return RegularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// RegularizedBeta 正则化不完全Beta函数 I_x(a, b)，按连分式展开计算
+func RegularizedBeta(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// betaFraction 不完全Beta函数的连分式(修正Lentz算法)
+func betaFraction(x, a, b float64) float64 {
+	const (
+		maxIter = 300
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestLinearRegression(t *testing.T) {
+	nan := math.NaN()
+	fit := LinearRegression([]float64{1, 2, nan, 3, 4, 5}, []float64{2, 4, 100, 5, 4, 5})
+	// 参考值: 斜率0.6，截距2.2，R²=0.6，斜率标准误sqrt(0.08)，自由度3的t检验
+	if fit.N != 5 || !closeTo(fit.Slope, 0.6, 1e-12) || !closeTo(fit.Intercept, 2.2, 1e-12) || !closeTo(fit.RSquared, 0.6, 1e-12) {
+		t.Errorf("fit = %+v", fit)
+	}
+	if !closeTo(fit.SlopeStdErr, math.Sqrt(0.08), 1e-12) || !closeTo(fit.PValue, 0.1240270626575547, 1e-9) {
+		t.Errorf("stderr, p = %g, %g", fit.SlopeStdErr, fit.PValue)
+	}
+
+	exact := LinearRegression([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7})
+	if exact.Slope != 2 || exact.Intercept != 1 || exact.RSquared != 1 || exact.PValue != 0 {
+		t.Errorf("exact fit = %+v", exact)
+	}
+	flat := LinearRegression([]float64{0, 1, 2}, []float64{4, 4, 4})
+	if flat.Slope != 0 || flat.PValue != 1 || !math.IsNaN(flat.RSquared) {
+		t.Errorf("flat fit = %+v", flat)
+	}
+	for _, x := range [][]float64{{1, 2}, {3, 3, 3}} {
+		if fit := LinearRegression(x, []float64{1, 2, 3}[:len(x)]); !math.IsNaN(fit.Slope) || !math.IsNaN(fit.PValue) {
+			t.Errorf("LinearRegression(%v) = %+v, want NaN", x, fit)
+		}
+	}
+}
+
+func TestSensSlope(t *testing.T) {
+	slope, intercept := SensSlope([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5})
+	if !closeTo(slope, 0.7083333333333333, 1e-12) || !closeTo(intercept, 1.458333333333334, 1e-12) {
+		t.Errorf("SensSlope = %g, %g, want 0.708333, 1.458333", slope, intercept)
+	}
+	// 离群值不影响Theil-Sen斜率
+	slope, intercept = SensSlope([]float64{0, 1, 2, 3, 4, 5, 6}, []float64{0, 2, 4, 600, 8, 10, 12})
+	if slope != 2 || intercept != 0 {
+		t.Errorf("SensSlope with outlier = %g, %g, want 2, 0", slope, intercept)
+	}
+	if slope, _ := SensSlope([]float64{1, 1}, []float64{1, 2}); !math.IsNaN(slope) {
+		t.Errorf("SensSlope with equal x = %g, want NaN", slope)
+	}
+}
+
+func TestMannKendall(t *testing.T) {
+	tests := []struct {
+		name   string
+		y      []float64
+		s, tau float64
+		z, p   float64
+	}{
+		{"increasing", []float64{1, 2, 3, 4, 5}, 10, 1, 2.2045407685048604, 0.027486336111510353},
+		{"decreasing", []float64{5, 4, math.NaN(), 3, 2, 1}, -10, -1, -2.2045407685048604, 0.027486336111510353},
+		{"ties", []float64{1, 2, 2, 3}, 5, 5.0 / 6, 1.4446302370292303, 0.1485617748918687},
+		{"constant", []float64{3, 3, 3}, 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		r := MannKendall(tt.y)
+		if r.S != tt.s || !closeTo(r.Tau, tt.tau, 1e-12) || !closeTo(r.Z, tt.z, 1e-12) || !closeTo(r.PValue, tt.p, 1e-12) {
+			t.Errorf("%s: %+v, want S=%g tau=%g Z=%g p=%g", tt.name, r, tt.s, tt.tau, tt.z, tt.p)
+		}
+	}
+	if r := MannKendall([]float64{1, math.NaN(), 2}); r.N != 2 || !math.IsNaN(r.PValue) {
+		t.Errorf("short series = %+v", r)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{3}, 3},
+		{[]float64{3, 1}, 2},
+		{[]float64{5, 1, 4, 2, 3}, 3},
+		{[]float64{6, 1, 5, 2, 4, 3}, 3.5},
+		{[]float64{2, 2, 2, 1, 2, 2}, 2},
+		{nil, math.NaN()},
+	}
+	for _, tt := range tests {
+		if got := Median(append([]float64(nil), tt.values...)); !closeTo(got, tt.want, 0) {
+			t.Errorf("Median(%v) = %g, want %g", tt.values, got, tt.want)
+		}
+	}
+
+	// 与排序结果比较
+	values := make([]float64, 101)
+	for i := range values {
+		values[i] = math.Mod(float64(i)*37, 101)
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if got := Median(values); got != sorted[50] {
+		t.Errorf("Median = %g, want %g", got, sorted[50])
+	}
+}
+
+func TestStudentTTest(t *testing.T) {
+	// 自由度1、2、3的t分布有闭式解
+	tests := []struct{ t, df, want float64 }{
+		{1, 1, 0.5},
+		{-1, 1, 0.5},
+		{3, 2, 1 - 3/math.Sqrt(11)},
+		{2.1213203435596424, 3, 0.1240270626575547},
+		{0, 10, 1},
+		{math.Inf(1), 5, 0},
+		{1.959963984540054, 1e7, 0.05},
+	}
+	for _, tt := range tests {
+		if got := StudentTTest(tt.t, tt.df); !closeTo(got, tt.want, 1e-6) {
+			t.Errorf("StudentTTest(%g, %g) = %.10g, want %.10g", tt.t, tt.df, got, tt.want)
+		}
+	}
+	if got := StudentTTest(1, 0); !math.IsNaN(got) {
+		t.Errorf("StudentTTest(df=0) = %g, want NaN", got)
+	}
+}
+
+func TestRegularizedBeta(t *testing.T) {
+	// 整数参数时 I_x(a, b) 等于二项分布的尾部概率
+	tests := []struct{ x, a, b, want float64 }{
+		{0.5, 2, 3, 11.0 / 16},
+		{0.3, 1, 1, 0.3},
+		{0.2, 1, 4, 1 - math.Pow(0.8, 4)},
+		{0.9, 5, 1, math.Pow(0.9, 5)},
+		{0, 2, 2, 0},
+		{1, 2, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := RegularizedBeta(tt.x, tt.a, tt.b); !closeTo(got, tt.want, 1e-12) {
+			t.Errorf("RegularizedBeta(%g, %g, %g) = %.15g, want %.15g", tt.x, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+// Package tide 潮汐调和分析: 按各分潮的天文相角和交点订正(f、u)对水位序列做最小二乘拟合，
+// 得到振幅、格林尼治迟角及其置信区间，并据此预报潮位
+package tide
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sinker/ssop/pkg/linalg"
+)
+
+// ErrInsufficientData 有效数据不足以拟合所选分潮
+var ErrInsufficientData = errors.New("tide: insufficient data")
+
+// ErrUnknownConstituent 未知的分潮名
+var ErrUnknownConstituent = errors.New("tide: unknown constituent")
+
+// j2000 天文参数的历元(2000-01-01 12:00 UTC)
+var j2000 = time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// 天文参数(τ、s、h、p、N'、p')的历元值和变化率(度/天)，N'为月球升交点经度的相反数
+var (
+	astroEpoch = [6]float64{0, 218.3164477, 280.4664567, 83.3532465, -125.0445479, 282.9373}
+	astroRates = [6]float64{0, 13.17639648, 0.98564736, 0.11140353, 0.05295377, 0.0000470684}
+)
+
+// astronomical 时刻t的天文参数(度): τ为平太阴时角，τ = T + h - s，T = 180° + 15°×UTC小时数为平太阳时角
+func astronomical(t time.Time) (args [6]float64) {
+	t = t.UTC()
+	days := t.Sub(j2000).Hours() / 24
+	for i := 1; i < 6; i++ {
+		args[i] = astroEpoch[i] + astroRates[i]*days
+	}
+	hours := float64(t.Hour()) + float64(t.Minute())/60 + (float64(t.Second())+float64(t.Nanosecond())/1e9)/3600
+	args[0] = 180 + 15*hours + args[2] - args[1]
+	return args
+}
+
+// nodal 交点因子f和交点订正角u(度)，n为月球升交点经度(度)
+type nodal func(n float64) (f, u float64)
+
+// 各类分潮的交点订正(Schureman, 1958)
+var (
+	nodalNone nodal = func(float64) (float64, float64) { return 1, 0 }
+	nodalM2   nodal = func(n float64) (float64, float64) {
+		return 1.0004 - 0.0373*cosd(n) + 0.0002*cosd(2*n), -2.14 * sind(n)
+	}
+	nodalK1 nodal = func(n float64) (float64, float64) {
+		return 1.0060 + 0.1150*cosd(n) - 0.0088*cosd(2*n) + 0.0006*cosd(3*n),
+			-8.86*sind(n) + 0.68*sind(2*n) - 0.07*sind(3*n)
+	}
+	nodalO1 nodal = func(n float64) (float64, float64) {
+		return 1.0089 + 0.1871*cosd(n) - 0.0147*cosd(2*n) + 0.0014*cosd(3*n),
+			10.80*sind(n) - 1.34*sind(2*n) + 0.19*sind(3*n)
+	}
+	nodalK2 nodal = func(n float64) (float64, float64) {
+		return 1.0241 + 0.2863*cosd(n) + 0.0083*cosd(2*n) - 0.0015*cosd(3*n),
+			-17.74*sind(n) + 0.68*sind(2*n) - 0.04*sind(3*n)
+	}
+	nodalJ1 nodal = func(n float64) (float64, float64) {
+		return 1.1029 + 0.1676*cosd(n) - 0.0170*cosd(2*n) + 0.0016*cosd(3*n),
+			-12.94*sind(n) + 1.34*sind(2*n) - 0.19*sind(3*n)
+	}
+	nodalOO1 nodal = func(n float64) (float64, float64) {
+		return 1.1027 + 0.6504*cosd(n) + 0.0317*cosd(2*n) - 0.0014*cosd(3*n),
+			-36.68*sind(n) + 4.02*sind(2*n) - 0.57*sind(3*n)
+	}
+	nodalMm nodal = func(n float64) (float64, float64) {
+		return 1.0 - 0.1300*cosd(n) + 0.0013*cosd(2*n), 0
+	}
+	nodalMf nodal = func(n float64) (float64, float64) {
+		return 1.0429 + 0.4135*cosd(n) - 0.004*cosd(2*n),
+			-23.74*sind(n) + 2.68*sind(2*n) - 0.38*sind(3*n)
+	}
+)
+
+// power 浅水分潮和复合分潮的交点订正: f为基本分潮f的|k|次方，u为基本分潮u的k倍
+func power(base nodal, k float64) nodal {
+	return func(n float64) (float64, float64) {
+		f, u := base(n)
+		return math.Pow(f, math.Abs(k)), k * u
+	}
+}
+
+// product 两个分潮合成的复合分潮的交点订正: f相乘，u相加
+func product(a, b nodal) nodal {
+	return func(n float64) (float64, float64) {
+		fa, ua := a(n)
+		fb, ub := b(n)
+		return fa * fb, ua + ub
+	}
+}
+
+// Constituent 分潮: Doodson数(τ、s、h、p、N'、p'的系数)、附加相角和交点订正
+type Constituent struct {
+	Name    string
+	Doodson [6]int
+	Phase   float64 // 附加相角(度)
+	nodal   nodal
+}
+
+// Frequency 分潮频率(周/小时)
+func (c *Constituent) Frequency() float64 {
+	// τ的变化率为 15°/小时 + (h - s)的变化率
+	rates := astroRates
+	rates[0] = 15*24 + astroRates[2] - astroRates[1]
+	sum := 0.0
+	for i, d := range c.Doodson {
+		sum += float64(d) * rates[i]
+	}
+	return sum / 24 / 360
+}
+
+// argument 时刻t的交点因子f和相角V+u(度)
+func (c *Constituent) argument(t time.Time) (f, vu float64) {
+	args := astronomical(t)
+	v := c.Phase
+	for i, d := range c.Doodson {
+		v += float64(d) * args[i]
+	}
+	f, u := c.nodal(-args[4])
+	return f, v + u
+}
+
+// constituents 支持的分潮，按自动选取时的优先级排列
+var constituents = []*Constituent{
+	{Name: "M2", Doodson: [6]int{2, 0, 0, 0, 0, 0}, nodal: nodalM2},
+	{Name: "S2", Doodson: [6]int{2, 2, -2, 0, 0, 0}, nodal: nodalNone},
+	{Name: "K1", Doodson: [6]int{1, 1, 0, 0, 0, 0}, Phase: -90, nodal: nodalK1},
+	{Name: "O1", Doodson: [6]int{1, -1, 0, 0, 0, 0}, Phase: 90, nodal: nodalO1},
+	{Name: "N2", Doodson: [6]int{2, -1, 0, 1, 0, 0}, nodal: nodalM2},
+	{Name: "K2", Doodson: [6]int{2, 2, 0, 0, 0, 0}, nodal: nodalK2},
+	{Name: "P1", Doodson: [6]int{1, 1, -2, 0, 0, 0}, Phase: 90, nodal: nodalNone},
+	{Name: "Q1", Doodson: [6]int{1, -2, 0, 1, 0, 0}, Phase: 90, nodal: nodalO1},
+	{Name: "M4", Doodson: [6]int{4, 0, 0, 0, 0, 0}, nodal: power(nodalM2, 2)},
+	{Name: "MS4", Doodson: [6]int{4, 2, -2, 0, 0, 0}, nodal: nodalM2},
+	{Name: "MN4", Doodson: [6]int{4, -1, 0, 1, 0, 0}, nodal: power(nodalM2, 2)},
+	{Name: "M6", Doodson: [6]int{6, 0, 0, 0, 0, 0}, nodal: power(nodalM2, 3)},
+	{Name: "2N2", Doodson: [6]int{2, -2, 0, 2, 0, 0}, nodal: nodalM2},
+	{Name: "MU2", Doodson: [6]int{2, -2, 2, 0, 0, 0}, nodal: nodalM2},
+	{Name: "NU2", Doodson: [6]int{2, -1, 2, -1, 0, 0}, nodal: nodalM2},
+	{Name: "L2", Doodson: [6]int{2, 1, 0, -1, 0, 0}, Phase: 180, nodal: nodalM2}, // 近似采用M2的交点订正
+	{Name: "T2", Doodson: [6]int{2, 2, -3, 0, 0, 1}, nodal: nodalNone},
+	{Name: "J1", Doodson: [6]int{1, 2, 0, -1, 0, 0}, Phase: -90, nodal: nodalJ1},
+	{Name: "OO1", Doodson: [6]int{1, 3, 0, 0, 0, 0}, Phase: -90, nodal: nodalOO1},
+	{Name: "2Q1", Doodson: [6]int{1, -3, 0, 2, 0, 0}, Phase: 90, nodal: nodalO1},
+	{Name: "RHO1", Doodson: [6]int{1, -2, 2, -1, 0, 0}, Phase: 90, nodal: nodalO1},
+	{Name: "M3", Doodson: [6]int{3, 0, 0, 0, 0, 0}, Phase: 180, nodal: power(nodalM2, 1.5)},
+	{Name: "MK3", Doodson: [6]int{3, 1, 0, 0, 0, 0}, Phase: -90, nodal: product(nodalM2, nodalK1)},
+	{Name: "S4", Doodson: [6]int{4, 4, -4, 0, 0, 0}, nodal: nodalNone},
+	{Name: "M8", Doodson: [6]int{8, 0, 0, 0, 0, 0}, nodal: power(nodalM2, 4)},
+	{Name: "2SM2", Doodson: [6]int{2, 4, -4, 0, 0, 0}, nodal: power(nodalM2, -1)},
+	{Name: "Mf", Doodson: [6]int{0, 2, 0, 0, 0, 0}, nodal: nodalMf},
+	{Name: "Mm", Doodson: [6]int{0, 1, 0, -1, 0, 0}, nodal: nodalMm},
+	{Name: "MSf", Doodson: [6]int{0, 2, -2, 0, 0, 0}, nodal: power(nodalM2, -1)},
+	{Name: "Ssa", Doodson: [6]int{0, 0, 2, 0, 0, 0}, nodal: nodalNone},
+	{Name: "Sa", Doodson: [6]int{0, 0, 1, 0, 0, -1}, nodal: nodalNone},
+}
+
+// Lookup 按名称查找分潮(不区分大小写)
+func Lookup(name string) (*Constituent, bool) {
+	for _, c := range constituents {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Names 支持的分潮名，按自动选取时的优先级排列
+func Names() []string {
+	names := make([]string, len(constituents))
+	for i, c := range constituents {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Exclusion 未参与拟合的分潮及原因
+type Exclusion struct {
+	Name   string
+	Reason string
+}
+
+// Select 按Rayleigh判据选取分潮: 依次(names为空时按优先级遍历全部分潮)接受与已接受的分潮及平均值(频率0)的频率差
+// 不小于 rayleigh/记录长度、且不超过Nyquist频率(step为采样间隔，0为不限制)的分潮；names中的未知分潮名返回错误
+func Select(names []string, duration, step time.Duration, rayleigh float64) ([]*Constituent, []Exclusion, error) {
+	candidates := constituents
+	if len(names) > 0 {
+		candidates = make([]*Constituent, 0, len(names))
+		for _, name := range names {
+			c, ok := Lookup(strings.TrimSpace(name))
+			if !ok {
+				return nil, nil, fmt.Errorf("%w %q", ErrUnknownConstituent, name)
+			}
+			candidates = append(candidates, c)
+		}
+	}
+
+	hours := duration.Hours()
+	var selected []*Constituent
+	var excluded []Exclusion
+	accepted := map[*Constituent]bool{}
+	for _, c := range candidates {
+		if accepted[c] {
+			continue
+		}
+		freq := c.Frequency()
+		if step > 0 && freq > 0.5/step.Hours() {
+			excluded = append(excluded, Exclusion{c.Name, "frequency above the Nyquist frequency of the sampling interval"})
+			continue
+		}
+		conflict := ""
+		if hours <= 0 || math.Abs(freq)*hours < rayleigh {
+			conflict = "Z0"
+		}
+		for _, s := range selected {
+			if conflict == "" && math.Abs(freq-s.Frequency())*hours < rayleigh {
+				conflict = s.Name
+			}
+		}
+		if conflict != "" {
+			excluded = append(excluded, Exclusion{c.Name, "not resolved from " + conflict + " by the record length (Rayleigh criterion)"})
+			continue
+		}
+		selected = append(selected, c)
+		accepted[c] = true
+	}
+	sort.SliceStable(selected, func(i, j int) bool { return selected[i].Frequency() < selected[j].Frequency() })
+	return selected, excluded, nil
+}
+
+// Result 一个分潮的拟合结果
+type Result struct {
+	Constituent *Constituent
+	Amplitude   float64
+	Phase       float64 // 格林尼治迟角(度，UTC)，0~360
+	AmplitudeCI float64 // 振幅置信区间的半宽
+	PhaseCI     float64 // 迟角置信区间的半宽(度)
+	SNR         float64 // 信噪比: (振幅/振幅置信区间半宽)²
+	cos, sin    float64 // 拟合系数 A·cos(g)、A·sin(g)
+}
+
+// Fit 调和分析结果
+type Fit struct {
+	Epoch        time.Time // 趋势项的时间原点(记录的中点)
+	Mean         float64   // Epoch处的平均水位
+	Trend        float64   // 线性趋势(单位/年)，未拟合趋势时为0
+	Results      []Result  // 按频率升序
+	Samples      int       // 有效样本数
+	Residual     float64   // 残差标准差
+	Explained    float64   // 潮汐(不含平均值和趋势)解释的方差比例
+	HasTrend     bool
+	Confidence   float64
+	meanSE       float64
+	trendSE      float64
+	hoursPerYear float64
+}
+
+// Options 调和分析参数
+type Options struct {
+	Constituents []*Constituent
+	Trend        bool    // 是否同时拟合线性趋势
+	Confidence   float64 // 置信水平(0~1)
+}
+
+// Analyze 对水位序列做最小二乘调和拟合: x(t) = 平均值 + 趋势 + Σ f·A·cos(V + u - g)，交点因子和相角逐时刻计算；
+// times为零值和values为NaN的样本忽略。置信区间按残差为白噪声估计
+func Analyze(times []time.Time, values []float64, opts Options) (*Fit, error) {
+	var valid []int
+	var first, last time.Time
+	for i, t := range times {
+		if t.IsZero() || math.IsNaN(values[i]) {
+			continue
+		}
+		valid = append(valid, i)
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	fit := &Fit{Epoch: first.Add(last.Sub(first) / 2).UTC(), HasTrend: opts.Trend, Confidence: opts.Confidence, hoursPerYear: 365.2425 * 24}
+	m := 1 + 2*len(opts.Constituents)
+	if opts.Trend {
+		m++
+	}
+	if len(valid) < 2*m {
+		return nil, fmt.Errorf("%w: %d valid samples for %d parameters", ErrInsufficientData, len(valid), m)
+	}
+
+	// 累加法方程 XᵀX·β = Xᵀy
+	normal := make([][]float64, m)
+	for i := range normal {
+		normal[i] = make([]float64, m)
+	}
+	rhs := make([]float64, m)
+	row := make([]float64, m)
+	for _, i := range valid {
+		fit.row(times[i], opts.Constituents, row)
+		for a := 0; a < m; a++ {
+			rhs[a] += row[a] * values[i]
+			for b := a; b < m; b++ {
+				normal[a][b] += row[a] * row[b]
+			}
+		}
+	}
+	for a := 0; a < m; a++ {
+		for b := 0; b < a; b++ {
+			normal[a][b] = normal[b][a]
+		}
+	}
+	inv, err := linalg.Inverse(normal)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInsufficientData, err)
+	}
+	beta := make([]float64, m)
+	for a := range beta {
+		for b := range rhs {
+			beta[a] += inv[a][b] * rhs[b]
+		}
+	}
+
+	// 残差方差和潮汐解释的方差
+	var resSq, detrendSq float64
+	for _, i := range valid {
+		fit.row(times[i], opts.Constituents, row)
+		predicted := 0.0
+		for a := range row {
+			predicted += row[a] * beta[a]
+		}
+		background := beta[0]
+		if opts.Trend {
+			background += row[1] * beta[1]
+		}
+		r := values[i] - predicted
+		resSq += r * r
+		d := values[i] - background
+		detrendSq += d * d
+	}
+	n := float64(len(valid))
+	variance := resSq / (n - float64(m))
+	fit.Samples = len(valid)
+	fit.Residual = math.Sqrt(resSq / n)
+	if detrendSq > 0 {
+		fit.Explained = math.Max(1-resSq/detrendSq, 0)
+	}
+
+	z := math.Sqrt2 * math.Erfinv(opts.Confidence)
+	fit.Mean = beta[0]
+	fit.meanSE = math.Sqrt(variance * inv[0][0])
+	offset := 1
+	if opts.Trend {
+		fit.Trend = beta[1] * fit.hoursPerYear
+		fit.trendSE = math.Sqrt(variance*inv[1][1]) * fit.hoursPerYear
+		offset = 2
+	}
+	for k, c := range opts.Constituents {
+		a, b := offset+2*k, offset+2*k+1
+		x, y := beta[a], beta[b]
+		vx, vy, cxy := variance*inv[a][a], variance*inv[b][b], variance*inv[a][b]
+		r := Result{Constituent: c, cos: x, sin: y, Amplitude: math.Hypot(x, y)}
+		r.Phase = math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+		if r.Amplitude > 0 {
+			a2 := r.Amplitude * r.Amplitude
+			r.AmplitudeCI = z * math.Sqrt(math.Max(x*x*vx+y*y*vy+2*x*y*cxy, 0)/a2)
+			r.PhaseCI = z * math.Sqrt(math.Max(y*y*vx+x*x*vy-2*x*y*cxy, 0)/(a2*a2)) * 180 / math.Pi
+			r.PhaseCI = math.Min(r.PhaseCI, 180)
+		}
+		r.SNR = math.Inf(1)
+		if r.AmplitudeCI > 0 {
+			r.SNR = (r.Amplitude / r.AmplitudeCI) * (r.Amplitude / r.AmplitudeCI)
+		}
+		fit.Results = append(fit.Results, r)
+	}
+	return fit, nil
+}
+
+// row 设计矩阵的一行: 1、趋势项(距Epoch的小时数)、各分潮的 f·cos(V+u)、f·sin(V+u)
+func (fit *Fit) row(t time.Time, list []*Constituent, row []float64) {
+	row[0] = 1
+	k := 1
+	if fit.HasTrend {
+		row[1] = t.Sub(fit.Epoch).Hours()
+		k = 2
+	}
+	for _, c := range list {
+		f, vu := c.argument(t)
+		row[k] = f * cosd(vu)
+		row[k+1] = f * sind(vu)
+		k += 2
+	}
+}
+
+// MeanCI 平均水位置信区间的半宽
+func (fit *Fit) MeanCI() float64 {
+	return math.Sqrt2 * math.Erfinv(fit.Confidence) * fit.meanSE
+}
+
+// TrendCI 趋势置信区间的半宽(单位/年)
+func (fit *Fit) TrendCI() float64 {
+	return math.Sqrt2 * math.Erfinv(fit.Confidence) * fit.trendSE
+}
+
+// Predict 时刻t的潮位: 平均值、趋势与信噪比不小于minSNR的分潮之和；withBackground为false时不含平均值和趋势
+func (fit *Fit) Predict(t time.Time, minSNR float64, withBackground bool) float64 {
+	x := 0.0
+	if withBackground {
+		x = fit.Mean + fit.Trend*t.Sub(fit.Epoch).Hours()/fit.hoursPerYear
+	}
+	for _, r := range fit.Results {
+		if r.SNR < minSNR {
+			continue
+		}
+		f, vu := r.Constituent.argument(t)
+		x += f * (r.cos*cosd(vu) + r.sin*sind(vu))
+	}
+	return x
+}
+
+// cosd 角度的余弦
+func cosd(deg float64) float64 {
+	return math.Cos(deg * math.Pi / 180)
+}
+
+// sind 角度的正弦
+func sind(deg float64) float64 {
+	return math.Sin(deg * math.Pi / 180)
+}
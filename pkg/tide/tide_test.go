@@ -0,0 +1,207 @@
+package tide
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func lookup(t *testing.T, name string) *Constituent {
+	t.Helper()
+	c, ok := Lookup(name)
+	if !ok {
+		t.Fatalf("Lookup(%s) failed", name)
+	}
+	return c
+}
+
+func TestFrequency(t *testing.T) {
+	// 参考值取自T_TIDE的分潮频率表(周/小时)
+	tests := []struct {
+		name string
+		want float64
+	}{
+		{"M2", 0.0805114007},
+		{"S2", 0.0833333333},
+		{"N2", 0.0789992487},
+		{"K2", 0.0835614924},
+		{"K1", 0.0417807462},
+		{"O1", 0.0387306544},
+		{"P1", 0.0415525871},
+		{"Q1", 0.0372185026},
+		{"M4", 0.1610228013},
+		{"MK3", 0.1222921470},
+		{"Mf", 0.0030500918},
+		{"Mm", 0.0015121518},
+		{"Sa", 0.0001140741},
+	}
+	for _, tt := range tests {
+		if got := lookup(t, tt.name).Frequency(); math.Abs(got-tt.want) > 2e-10 {
+			t.Errorf("%s frequency = %.10f, want %.10f", tt.name, got, tt.want)
+		}
+	}
+	if c, ok := Lookup("m2"); !ok || c.Name != "M2" {
+		t.Error("Lookup should ignore case")
+	}
+	if _, ok := Lookup("X9"); ok {
+		t.Error("Lookup(X9) should fail")
+	}
+}
+
+func TestNodal(t *testing.T) {
+	// Schureman(1958)表中交点因子f的范围: 升交点经度N为0°时取一端，180°时取另一端
+	tests := []struct {
+		name     string
+		n0, n180 float64
+		nodal    nodal
+	}{
+		{"M2", 0.963, 1.038, nodalM2},
+		{"K1", 1.113, 0.882, nodalK1},
+		{"O1", 1.183, 0.806, nodalO1},
+		{"K2", 1.317, 0.748, nodalK2},
+	}
+	for _, tt := range tests {
+		f0, u0 := tt.nodal(0)
+		f180, u180 := tt.nodal(180)
+		if math.Abs(f0-tt.n0) > 0.001 || math.Abs(f180-tt.n180) > 0.001 {
+			t.Errorf("%s: f = %.4f, %.4f, want %.3f, %.3f", tt.name, f0, f180, tt.n0, tt.n180)
+		}
+		if math.Abs(u0) > 1e-12 || math.Abs(u180) > 1e-9 {
+			t.Errorf("%s: u = %g, %g, want 0 at N = 0 and 180", tt.name, u0, u180)
+		}
+	}
+	// 复合分潮: M4的f为M2的平方，u为两倍
+	fm2, um2 := nodalM2(60)
+	if f, u := lookup(t, "M4").nodal(60); math.Abs(f-fm2*fm2) > 1e-12 || math.Abs(u-2*um2) > 1e-12 {
+		t.Errorf("M4 nodal = %g, %g", f, u)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	selected, excluded, err := Select([]string{"M2", "S2", "K1", "P1", "M6", "Mf", "Mm", "m2"}, 15*24*time.Hour, 3*time.Hour, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range selected {
+		names = append(names, c.Name)
+	}
+	// 15天可分辨M2与S2(相差1.016周)，不能分辨K1与P1；3小时采样的Nyquist频率为1/6周/小时
+	want := []string{"Mf", "K1", "M2", "S2"}
+	if len(names) != len(want) {
+		t.Fatalf("selected %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("selected %v, want %v", names, want)
+		}
+	}
+	reasons := map[string]string{}
+	for _, e := range excluded {
+		reasons[e.Name] = e.Reason
+	}
+	if len(excluded) != 3 || reasons["P1"] == "" || reasons["M6"] == "" || reasons["Mm"] == "" {
+		t.Errorf("excluded = %+v", excluded)
+	}
+
+	if _, _, err := Select([]string{"M2", "XX"}, 24*time.Hour, 0, 1); !errors.Is(err, ErrUnknownConstituent) {
+		t.Errorf("unknown constituent error = %v", err)
+	}
+	all, _, err := Select(nil, 366*24*time.Hour, time.Hour, 1)
+	if err != nil || len(all) != len(Names()) {
+		t.Errorf("one year of hourly data selects %d of %d constituents (%v)", len(all), len(Names()), err)
+	}
+}
+
+func TestAnalyzeS2(t *testing.T) {
+	// S2的天文相角V = 2τ + 2s - 2h = 2T，即UTC小时数×30°，与天文参数无关
+	var times []time.Time
+	var values []float64
+	start := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 6*72; i++ {
+		tm := start.Add(time.Duration(i) * 10 * time.Minute)
+		hours := float64(i) / 6
+		times = append(times, tm)
+		values = append(values, 0.8+0.5*cosd(30*hours-75))
+	}
+	fit, err := Analyze(times, values, Options{Constituents: []*Constituent{lookup(t, "S2")}, Confidence: 0.95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := fit.Results[0]
+	if math.Abs(fit.Mean-0.8) > 1e-9 || math.Abs(r.Amplitude-0.5) > 1e-9 || math.Abs(r.Phase-75) > 1e-6 {
+		t.Errorf("S2 fit: mean %g, amplitude %g, phase %g, want 0.8, 0.5, 75", fit.Mean, r.Amplitude, r.Phase)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	type wave struct {
+		name       string
+		amp, phase float64
+	}
+	waves := []wave{{"M2", 1.2, 120}, {"S2", 0.4, 150}, {"K1", 0.3, 300}, {"O1", 0.25, 280}}
+	signal := func(tm time.Time, epoch time.Time) float64 {
+		x := 1.5 + 0.05*tm.Sub(epoch).Hours()/(365.2425*24)
+		for _, w := range waves {
+			f, vu := lookup(t, w.name).argument(tm)
+			x += f * w.amp * cosd(vu-w.phase)
+		}
+		return x
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := 30 * 24
+	epoch := start.Add(time.Duration(n-1) * time.Hour / 2)
+	times := make([]time.Time, n)
+	values := make([]float64, n)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Hour)
+		// 确定性的小幅"噪声"
+		values[i] = signal(times[i], epoch) + 0.01*math.Sin(float64(i)*1.7)
+	}
+	values[100], times[200] = math.NaN(), time.Time{}
+
+	var list []*Constituent
+	for _, w := range waves {
+		list = append(list, lookup(t, w.name))
+	}
+	fit, err := Analyze(times, values, Options{Constituents: list, Trend: true, Confidence: 0.95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fit.Samples != n-2 || math.Abs(fit.Mean-1.5) > 2e-3 || math.Abs(fit.Trend-0.05) > 0.05 || fit.Explained < 0.99 {
+		t.Errorf("fit = %+v", fit)
+	}
+	if fit.Residual > 0.01 || fit.MeanCI() <= 0 || fit.TrendCI() <= 0 {
+		t.Errorf("residual %g, mean CI %g, trend CI %g", fit.Residual, fit.MeanCI(), fit.TrendCI())
+	}
+	found := 0
+	for _, r := range fit.Results {
+		for _, w := range waves {
+			if r.Constituent.Name != w.name {
+				continue
+			}
+			found++
+			if math.Abs(r.Amplitude-w.amp) > 3e-3 || math.Abs(r.Phase-w.phase) > 1 {
+				t.Errorf("%s: amplitude %g, phase %g, want %g, %g", w.name, r.Amplitude, r.Phase, w.amp, w.phase)
+			}
+			if r.AmplitudeCI <= 0 || r.AmplitudeCI > 0.01 || r.SNR < 100 {
+				t.Errorf("%s: amplitude CI %g, SNR %g", w.name, r.AmplitudeCI, r.SNR)
+			}
+		}
+	}
+	if found != len(waves) {
+		t.Errorf("%d results, want %d", found, len(waves))
+	}
+
+	// 预报记录之外的时刻
+	at := start.AddDate(0, 2, 0)
+	if got, want := fit.Predict(at, 2, true), signal(at, epoch); math.Abs(got-want) > 0.02 {
+		t.Errorf("Predict = %g, want %g", got, want)
+	}
+
+	if _, err := Analyze(times[:5], values[:5], Options{Constituents: list}); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("insufficient data error = %v", err)
+	}
+}
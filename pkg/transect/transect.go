@@ -0,0 +1,90 @@
+// Package transect 沿折线(如调查航线)按大圆路径等间距布设断面站位
+package transect
+
+import (
+	"errors"
+	"math"
+)
+
+// earthRadius 地球平均半径(km)
+const earthRadius = 6371.0
+
+// Station 断面站位
+type Station struct {
+	Lat      float64
+	Lng      float64
+	Distance float64 // 沿折线距起点的距离(km)
+}
+
+// Distance 两点间的大圆距离(km)
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	p1, p2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dp := p2 - p1
+	dl := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dp/2)*math.Sin(dp/2) + math.Cos(p1)*math.Cos(p2)*math.Sin(dl/2)*math.Sin(dl/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// intermediate 大圆弧上从(lat1, lng1)到(lat2, lng2)比例为f的点，d为两点的角距离(弧度)
+func intermediate(lat1, lng1, lat2, lng2, d, f float64) (lat, lng float64) {
+	if d == 0 {
+		return lat1, lng1
+	}
+	p1, l1 := lat1*math.Pi/180, lng1*math.Pi/180
+	p2, l2 := lat2*math.Pi/180, lng2*math.Pi/180
+	a := math.Sin((1-f)*d) / math.Sin(d)
+	b := math.Sin(f*d) / math.Sin(d)
+	x := a*math.Cos(p1)*math.Cos(l1) + b*math.Cos(p2)*math.Cos(l2)
+	y := a*math.Cos(p1)*math.Sin(l1) + b*math.Cos(p2)*math.Sin(l2)
+	z := a*math.Sin(p1) + b*math.Sin(p2)
+	lat = math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+	lng = math.Atan2(y, x) * 180 / math.Pi
+	// 与起点经度保持连续，兼容0~360的经度
+	return lat, lng1 + math.Remainder(lng-lng1, 360)
+}
+
+// Stations 沿折线path(各顶点为[lat, lng])从起点起每隔spacing(km)布设一个站位，终点也作为一个站位；
+// 同时返回各顶点距起点的距离(km)
+func Stations(path [][2]float64, spacing float64) ([]Station, []float64, error) {
+	if len(path) < 2 {
+		return nil, nil, errors.New("path must have at least 2 points")
+	}
+	if !(spacing > 0) {
+		return nil, nil, errors.New("spacing must be positive")
+	}
+	vertices := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		vertices[i] = vertices[i-1] + Distance(path[i-1][0], path[i-1][1], path[i][0], path[i][1])
+	}
+	total := vertices[len(vertices)-1]
+	if total == 0 {
+		return nil, nil, errors.New("path has zero length")
+	}
+
+	n := int(math.Floor(total/spacing + 1e-9))
+	stations := make([]Station, 0, n+2)
+	segment := 1
+	for k := 0; k <= n; k++ {
+		stations = append(stations, locate(path, vertices, &segment, float64(k)*spacing))
+	}
+	if total-stations[len(stations)-1].Distance > 1e-6*spacing {
+		last := path[len(path)-1]
+		stations = append(stations, Station{Lat: last[0], Lng: last[1], Distance: total})
+	}
+	return stations, vertices, nil
+}
+
+// locate 折线上距起点distance处的站位，segment为当前所在线段(终点顶点的下标)，随distance递增向后推进
+func locate(path [][2]float64, vertices []float64, segment *int, distance float64) Station {
+	for *segment < len(path)-1 && vertices[*segment] < distance {
+		*segment++
+	}
+	i := *segment
+	length := vertices[i] - vertices[i-1]
+	f := 0.0
+	if length > 0 {
+		f = math.Max(0, math.Min(1, (distance-vertices[i-1])/length))
+	}
+	lat, lng := intermediate(path[i-1][0], path[i-1][1], path[i][0], path[i][1], length/earthRadius, f)
+	return Station{Lat: lat, Lng: lng, Distance: distance}
+}
@@ -0,0 +1,470 @@
+// Package units 实现UDUNITS风格的物理单位解析与换算
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 基本量纲: 长度、质量、时间、温度、电流、物质的量、发光强度，以及实用盐度
+// (实用盐度由电导率比定义，与g/kg的绝对盐度不能直接换算，单独作为一个量纲)
+const (
+	dimLength = iota
+	dimMass
+	dimTime
+	dimTemperature
+	dimCurrent
+	dimAmount
+	dimLuminosity
+	dimSalinity
+	numDims
+)
+
+var (
+	// ErrUnknownUnit 无法识别的单位
+	ErrUnknownUnit = errors.New("units: unknown unit")
+	// ErrIncompatible 单位量纲不一致，无法换算
+	ErrIncompatible = errors.New("units: incompatible units")
+)
+
+// Unit 解析后的单位: 数值 x 对应的国际单位制值为 x*Scale + Offset
+type Unit struct {
+	Scale  float64
+	Offset float64
+	dims   [numDims]int
+}
+
+// unit 构造单位
+func unit(scale float64, dims ...int) Unit {
+	u := Unit{Scale: scale}
+	for i := 0; i+1 < len(dims); i += 2 {
+		u.dims[dims[i]] = dims[i+1]
+	}
+	return u
+}
+
+// Dimensionless 是否为无量纲单位
+func (u Unit) Dimensionless() bool {
+	return u.dims == [numDims]int{}
+}
+
+// Compatible 是否可与另一单位相互换算
+func (u Unit) Compatible(other Unit) bool {
+	return u.dims == other.dims
+}
+
+// mul 单位相乘
+func (u Unit) mul(other Unit) Unit {
+	out := Unit{Scale: u.Scale * other.Scale}
+	for i := range u.dims {
+		out.dims[i] = u.dims[i] + other.dims[i]
+	}
+	return out
+}
+
+// pow 单位乘方
+func (u Unit) pow(n int) Unit {
+	out := Unit{Scale: math.Pow(u.Scale, float64(n))}
+	for i := range u.dims {
+		out.dims[i] = u.dims[i] * n
+	}
+	return out
+}
+
+// 常用单位
+var (
+	meter    = unit(1, dimLength, 1)
+	kilogram = unit(1, dimMass, 1)
+	second   = unit(1, dimTime, 1)
+	kelvin   = unit(1, dimTemperature, 1)
+	one      = unit(1)
+	pascal   = unit(1, dimMass, 1, dimLength, -1, dimTime, -2)
+	radian   = one
+)
+
+var (
+	degree     = unit(math.Pi / 180)
+	dbar       = unit(1e4, dimMass, 1, dimLength, -1, dimTime, -2)
+	knot       = unit(1852.0/3600, dimLength, 1, dimTime, -1)
+	year       = unit(31556925.9747, dimTime, 1)
+	siemens    = unit(1, dimMass, -1, dimLength, -2, dimTime, 3, dimCurrent, 2)
+	celsius    = Unit{Scale: 1, Offset: 273.15}
+	fahrenheit = Unit{Scale: 5.0 / 9, Offset: 273.15 - 32*5.0/9}
+)
+
+// unitTable 单位及其名称(区分大小写)
+var unitTable = []struct {
+	names []string
+	unit  Unit
+}{
+	// 无量纲
+	{[]string{"1", "count", "counts", "level", "sigma", "ratio"}, one},
+	{[]string{"percent", "%"}, unit(0.01)},
+	{[]string{"ppt", "ppth", "‰", "permil"}, unit(1e-3)},
+	{[]string{"ppm"}, unit(1e-6)},
+	{[]string{"ppb"}, unit(1e-9)},
+	// 实用盐度，换算为绝对盐度(g/kg)需按TEOS-10结合压力和位置计算
+	{[]string{"psu", "PSU", "pss", "PSS", "pss-78", "PSS-78", "pss78", "PSS78", "practical_salinity_unit"}, unit(1, dimSalinity, 1)},
+
+	// 角度
+	{[]string{"rad", "radian", "radians"}, radian},
+	{[]string{"degree", "degrees", "deg", "°", "arc_degree", "degrees_true", "degree_true",
+		"degrees_north", "degree_north", "degrees_N", "degree_N", "degreesN", "degreeN",
+		"degrees_east", "degree_east", "degrees_E", "degree_E", "degreesE", "degreeE"}, degree},
+
+	// 长度
+	{[]string{"m", "meter", "meters", "metre", "metres"}, meter},
+	{[]string{"ft", "foot", "feet"}, unit(0.3048, dimLength, 1)},
+	{[]string{"in", "inch", "inches"}, unit(0.0254, dimLength, 1)},
+	{[]string{"mile", "miles"}, unit(1609.344, dimLength, 1)},
+	{[]string{"nmi", "nautical_mile", "nautical_miles"}, unit(1852, dimLength, 1)},
+	{[]string{"fathom", "fathoms"}, unit(1.8288, dimLength, 1)},
+
+	// 质量
+	{[]string{"g", "gram", "grams"}, unit(1e-3, dimMass, 1)},
+	{[]string{"kg", "kilogram", "kilograms"}, kilogram},
+	{[]string{"t", "tonne", "tonnes"}, unit(1000, dimMass, 1)},
+
+	// 时间
+	{[]string{"s", "sec", "secs", "second", "seconds"}, second},
+	{[]string{"min", "mins", "minute", "minutes"}, unit(60, dimTime, 1)},
+	{[]string{"h", "hr", "hrs", "hour", "hours"}, unit(3600, dimTime, 1)},
+	{[]string{"d", "day", "days"}, unit(86400, dimTime, 1)},
+	{[]string{"week", "weeks"}, unit(604800, dimTime, 1)},
+	{[]string{"year", "years", "yr", "a"}, year},
+	{[]string{"Hz", "hertz"}, unit(1, dimTime, -1)},
+
+	// 温度
+	{[]string{"K", "kelvin", "degK", "deg_K", "degree_K", "degrees_K", "degreeK", "degree_kelvin", "degrees_kelvin"}, kelvin},
+
+	// 物质的量、电流、发光强度
+	{[]string{"mol", "mole", "moles"}, unit(1, dimAmount, 1)},
+	{[]string{"A", "ampere", "amp"}, unit(1, dimCurrent, 1)},
+	{[]string{"cd", "candela"}, unit(1, dimLuminosity, 1)},
+
+	// 导出单位
+	{[]string{"N", "newton"}, unit(1, dimMass, 1, dimLength, 1, dimTime, -2)},
+	{[]string{"Pa", "pascal"}, pascal},
+	{[]string{"bar", "bars"}, unit(1e5, dimMass, 1, dimLength, -1, dimTime, -2)},
+	{[]string{"dbar", "decibar", "decibars"}, dbar},
+	{[]string{"atm"}, unit(101325, dimMass, 1, dimLength, -1, dimTime, -2)},
+	{[]string{"psi"}, unit(6894.757, dimMass, 1, dimLength, -1, dimTime, -2)},
+	{[]string{"J", "joule"}, unit(1, dimMass, 1, dimLength, 2, dimTime, -2)},
+	{[]string{"W", "watt"}, unit(1, dimMass, 1, dimLength, 2, dimTime, -3)},
+	{[]string{"V", "volt"}, unit(1, dimMass, 1, dimLength, 2, dimTime, -3, dimCurrent, -1)},
+	{[]string{"S", "siemens", "mho"}, siemens},
+	{[]string{"L", "l", "liter", "litre", "liters", "litres"}, unit(1e-3, dimLength, 3)},
+	{[]string{"knot", "knots", "kt", "kn"}, knot},
+	{[]string{"Sv", "sverdrup"}, unit(1e6, dimLength, 3, dimTime, -1)},
+}
+
+// offsetTable 带零点偏移的温度单位，只能单独使用
+var offsetTable = []struct {
+	names []string
+	unit  Unit
+}{
+	{[]string{"degC", "deg_C", "degree_C", "degrees_C", "degreeC", "degreesC", "celsius", "Celsius", "°C", "℃", "C",
+		"deg_Celsius", "degree_Celsius", "degrees_Celsius", "degree_celsius", "degrees_celsius"}, celsius},
+	{[]string{"degF", "deg_F", "degree_F", "degrees_F", "fahrenheit", "Fahrenheit", "°F", "℉",
+		"deg_Fahrenheit", "degree_Fahrenheit", "degrees_Fahrenheit", "degree_fahrenheit", "degrees_fahrenheit"}, fahrenheit},
+}
+
+// names、offsetUnits 按名称索引的单位表
+var (
+	names       = map[string]Unit{}
+	offsetUnits = map[string]Unit{}
+)
+
+func init() {
+	for _, entry := range unitTable {
+		for _, name := range entry.names {
+			names[name] = entry.unit
+		}
+	}
+	for _, entry := range offsetTable {
+		u := entry.unit
+		u.dims[dimTemperature] = 1
+		for _, name := range entry.names {
+			offsetUnits[name] = u
+		}
+	}
+}
+
+// prefixes SI词头，较长的词头先匹配
+var prefixes = []struct {
+	name  string
+	scale float64
+}{
+	{"yotta", 1e24}, {"zetta", 1e21}, {"exa", 1e18}, {"peta", 1e15}, {"tera", 1e12}, {"giga", 1e9},
+	{"mega", 1e6}, {"kilo", 1e3}, {"hecto", 1e2}, {"deka", 1e1}, {"deca", 1e1}, {"deci", 1e-1},
+	{"centi", 1e-2}, {"milli", 1e-3}, {"micro", 1e-6}, {"nano", 1e-9}, {"pico", 1e-12}, {"femto", 1e-15},
+	{"atto", 1e-18}, {"da", 1e1}, {"Y", 1e24}, {"Z", 1e21}, {"E", 1e18}, {"P", 1e15}, {"T", 1e12},
+	{"G", 1e9}, {"M", 1e6}, {"k", 1e3}, {"h", 1e2}, {"d", 1e-1}, {"c", 1e-2}, {"m", 1e-3},
+	{"u", 1e-6}, {"µ", 1e-6}, {"μ", 1e-6}, {"n", 1e-9}, {"p", 1e-12}, {"f", 1e-15}, {"a", 1e-18},
+}
+
+// Parse 解析单位字符串，支持词头、乘除(空格、*、.、/)和乘方(m2、m^2、m**2、s-1)，
+// 带偏移的温度单位(degC、degF，含"degrees Celsius"、"deg C"等多词写法)单独使用时保留偏移，
+// 在变化率(如degC/m)中按温差处理，不能与其他单位相乘；空字符串按无量纲处理
+func Parse(s string) (Unit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return one, nil
+	}
+	if strings.Contains(s, " since ") {
+		return Unit{}, fmt.Errorf("%w: %q is a time reference", ErrUnknownUnit, s)
+	}
+	if u, ok := offsetUnits[s]; ok {
+		return u, nil
+	}
+	// 多词写法作为一个整体单位: "degrees Celsius" -> degrees_Celsius，"deg C" -> deg_C
+	if joined := strings.Join(strings.Fields(s), "_"); joined != s {
+		if u, ok := offsetUnits[joined]; ok {
+			return u, nil
+		}
+		if u, ok := names[joined]; ok {
+			return u, nil
+		}
+	}
+
+	tokens, err := tokenize(s)
+	if err != nil {
+		return Unit{}, err
+	}
+
+	result := one
+	divide := false
+	offset, multiplied := false, false
+	for _, tok := range tokens {
+		switch tok {
+		case "/":
+			divide = true
+			continue
+		case "*", ".":
+			continue
+		}
+
+		u, isOffset, err := parseFactor(tok)
+		if err != nil {
+			return Unit{}, fmt.Errorf("%w: %q in %q", ErrUnknownUnit, tok, s)
+		}
+		if divide {
+			u = u.pow(-1)
+			divide = false
+		}
+		// 分子中的温度单位与分子中的其他单位相乘(如 degree Celsius)没有意义
+		switch {
+		case isOffset && u.dims[dimTemperature] > 0:
+			if offset || multiplied {
+				return Unit{}, fmt.Errorf("%w: %q multiplies a temperature with an offset by another unit", ErrUnknownUnit, s)
+			}
+			offset = true
+		case !isDivisor(u) && !isNumber(tok):
+			if offset {
+				return Unit{}, fmt.Errorf("%w: %q multiplies a temperature with an offset by another unit", ErrUnknownUnit, s)
+			}
+			multiplied = true
+		}
+		result = result.mul(u)
+	}
+	return result, nil
+}
+
+// isDivisor 因子是否位于分母(各量纲的指数均不为正且不全为0)
+func isDivisor(u Unit) bool {
+	negative := false
+	for _, d := range u.dims {
+		if d > 0 {
+			return false
+		}
+		if d < 0 {
+			negative = true
+		}
+	}
+	return negative
+}
+
+// isNumber 因子是否为数值
+func isNumber(tok string) bool {
+	_, err := strconv.ParseFloat(tok, 64)
+	return err == nil
+}
+
+// tokenize 将单位字符串切分为因子和运算符
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case r == '/':
+			flush()
+			tokens = append(tokens, "/")
+		case r == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			cur.WriteString("^")
+			i++
+		case r == '*':
+			flush()
+			tokens = append(tokens, "*")
+		case r == '.' && (cur.Len() == 0 || !isDigitString(cur.String())):
+			// 小数点之外的"."表示乘号
+			flush()
+			tokens = append(tokens, "*")
+		case r == '(' || r == ')':
+			return nil, fmt.Errorf("%w: parentheses are not supported in %q", ErrUnknownUnit, s)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+// isDigitString 是否为数字(用于区分小数点与乘号)
+func isDigitString(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) && r != '-' && r != '+' && r != 'e' && r != 'E' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFactor 解析单个因子: 数值、带乘方的单位名，isOffset表示单位名为带偏移的温度单位
+func parseFactor(tok string) (u Unit, isOffset bool, err error) {
+	if x, err := strconv.ParseFloat(tok, 64); err == nil {
+		return unit(x), false, nil
+	}
+	if u, ok := lookup(tok); ok {
+		return u, isOffsetName(tok), nil
+	}
+
+	// 拆分乘方: m^2、m2、s-1
+	name, exp := tok, ""
+	if i := strings.Index(tok, "^"); i > 0 {
+		name, exp = tok[:i], tok[i+1:]
+	} else {
+		i := len(tok)
+		for i > 0 && (unicode.IsDigit(rune(tok[i-1])) || tok[i-1] == '-' || tok[i-1] == '+') {
+			i--
+		}
+		name, exp = tok[:i], tok[i:]
+	}
+	if name == "" || exp == "" {
+		return Unit{}, false, ErrUnknownUnit
+	}
+
+	n, err := strconv.Atoi(exp)
+	if err != nil {
+		return Unit{}, false, ErrUnknownUnit
+	}
+	u, ok := lookup(name)
+	if !ok {
+		return Unit{}, false, ErrUnknownUnit
+	}
+	return u.pow(n), isOffsetName(name), nil
+}
+
+// isOffsetName 是否为带偏移的温度单位名
+func isOffsetName(name string) bool {
+	if _, ok := names[name]; ok {
+		return false
+	}
+	_, ok := offsetUnits[name]
+	return ok
+}
+
+// lookup 按名称查找单位，依次尝试完整名称和带词头的名称；温度单位出现在乘除式中时按温差处理
+func lookup(name string) (Unit, bool) {
+	if u, ok := names[name]; ok {
+		return u, true
+	}
+	if u, ok := offsetUnits[name]; ok {
+		u.Offset = 0
+		return u, true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p.name) && len(name) > len(p.name) {
+			if u, ok := names[name[len(p.name):]]; ok {
+				u.Scale *= p.scale
+				return u, true
+			}
+		}
+	}
+	return Unit{}, false
+}
+
+// Converter 单位换算器: to = from*Scale + Offset
+type Converter struct {
+	Scale  float64
+	Offset float64
+}
+
+// Identity 是否为恒等换算
+func (c Converter) Identity() bool {
+	return c.Scale == 1 && c.Offset == 0
+}
+
+// Convert 换算单个值
+func (c Converter) Convert(x float64) float64 {
+	return x*c.Scale + c.Offset
+}
+
+// ConvertAll 原地换算数组
+func (c Converter) ConvertAll(values []float64) {
+	if c.Identity() {
+		return
+	}
+	for i, x := range values {
+		values[i] = x*c.Scale + c.Offset
+	}
+}
+
+// NewConverter 创建从from到to的换算器
+func NewConverter(from, to string) (Converter, error) {
+	fu, err := Parse(from)
+	if err != nil {
+		return Converter{}, err
+	}
+	tu, err := Parse(to)
+	if err != nil {
+		return Converter{}, err
+	}
+	if !fu.Compatible(tu) {
+		return Converter{}, fmt.Errorf("%w: %q and %q", ErrIncompatible, from, to)
+	}
+
+	// x*fs + fo = y*ts + to  =>  y = x*fs/ts + (fo-to)/ts
+	scale := fu.Scale / tu.Scale
+	offset := (fu.Offset - tu.Offset) / tu.Scale
+	if math.Abs(scale-1) < 1e-12 {
+		scale = 1
+	}
+	if math.Abs(offset) < 1e-9 {
+		offset = 0
+	}
+	return Converter{Scale: scale, Offset: offset}, nil
+}
+
+// Convert 将单个值从from单位换算到to单位
+func Convert(x float64, from, to string) (float64, error) {
+	c, err := NewConverter(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return c.Convert(x), nil
+}
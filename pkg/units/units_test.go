@@ -0,0 +1,96 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		from, to string
+		x, want  float64
+	}{
+		{"degC", "K", 20, 293.15},
+		{"K", "degC", 300, 26.85},
+		{"degF", "degC", 68, 20},
+		{"degrees Celsius", "degC", 20, 20},
+		{"degree Celsius", "K", 20, 293.15},
+		{"deg C", "degC", 20, 20},
+		{"degrees C", "degC", 20, 20},
+		{"degrees  celsius", "degC", 20, 20},
+		{"degree_Celsius", "degC", 20, 20},
+		{"Celsius", "degC", 20, 20},
+		{"℃", "degC", 20, 20},
+		{"degrees Fahrenheit", "degC", 68, 20},
+		{"degree F", "degC", 212, 100},
+		{"degrees K", "degC", 273.15, 0},
+		// 变化率中的温度单位按温差处理
+		{"degC/m", "K/m", 0.5, 0.5},
+		{"degC m-1", "K km-1", 0.5, 500},
+		{"degC/day", "K s-1", 86400, 1},
+		{"m/degC", "m/K", 2, 2},
+		{"m s-1", "knots", 1, 3600.0 / 1852},
+		{"cm/s", "m s-1", 25, 0.25},
+		{"m.s-1", "km/h", 1, 3.6},
+		{"m^2", "cm2", 1, 1e4},
+		{"dbar", "Pa", 1, 1e4},
+		{"kg m-3", "g/L", 1025, 1025},
+		{"W.m-2", "mW m**-2", 1, 1000},
+		{"degrees_north", "radians", 180, math.Pi},
+		{"Sv", "m3 s-1", 1, 1e6},
+		{"percent", "1", 50, 0.5},
+		{"psu", "PSS-78", 35, 35},
+		{"g/kg", "g kg-1", 35, 35},
+		{"", "1", 3, 3},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.x, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Convert(%g, %q, %q): %v", tt.x, tt.from, tt.to, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("Convert(%g, %q, %q) = %.12g, want %.12g", tt.x, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     error
+	}{
+		// 实用盐度不能直接换算为绝对盐度
+		{"psu", "g/kg", ErrIncompatible},
+		{"1e-3", "psu", ErrIncompatible},
+		{"degC", "m", ErrIncompatible},
+		{"arc_degree Celsius", "degC", ErrUnknownUnit},
+		{"degC m", "K m", ErrUnknownUnit},
+		{"m degC", "K m", ErrUnknownUnit},
+		{"degC degF", "K2", ErrUnknownUnit},
+		{"days since 1970-01-01", "s", ErrUnknownUnit},
+		{"furlong", "m", ErrUnknownUnit},
+		{"(m)", "m", ErrUnknownUnit},
+	}
+	for _, tt := range tests {
+		if _, err := NewConverter(tt.from, tt.to); !errors.Is(err, tt.want) {
+			t.Errorf("NewConverter(%q, %q) error = %v, want %v", tt.from, tt.to, err, tt.want)
+		}
+	}
+}
+
+func TestConverterIdentity(t *testing.T) {
+	c, err := NewConverter("degrees Celsius", "degC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Identity() {
+		t.Errorf("degrees Celsius -> degC = %+v, want identity", c)
+	}
+	values := []float64{1, 2}
+	c.ConvertAll(values)
+	if values[0] != 1 || values[1] != 2 {
+		t.Errorf("identity ConvertAll changed values: %v", values)
+	}
+}
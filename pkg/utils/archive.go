@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrArchiveTooLarge 解压后的内容超过限制
+var ErrArchiveTooLarge = errors.New("archive expands beyond the allowed size")
+
+// archiveExts 支持的归档扩展名
+var archiveExts = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// ArchiveExt 返回文件名的归档扩展名，不是支持的归档时返回空字符串
+func ArchiveExt(filename string) string {
+	name := strings.ToLower(filename)
+	ext := ""
+	for _, e := range archiveExts {
+		if strings.HasSuffix(name, e) && len(e) > len(ext) {
+			ext = e
+		}
+	}
+	return ext
+}
+
+// archiveEntry 归档中的一个条目
+type archiveEntry struct {
+	name string
+	dir  bool
+	open func() (io.ReadCloser, error) // 普通文件的内容，目录和其他类型为nil
+}
+
+// walkArchive 依次访问zip或tar(.gz)归档中的条目
+func walkArchive(src string, fn func(e archiveEntry) error) error {
+	if ArchiveExt(src) == ".zip" {
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			e := archiveEntry{name: f.Name, dir: f.FileInfo().IsDir()}
+			if f.Mode().IsRegular() {
+				e.open = f.Open
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if ext := ArchiveExt(src); ext == ".tar.gz" || ext == ".tgz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := archiveEntry{name: hdr.Name, dir: hdr.Typeflag == tar.TypeDir}
+		if hdr.Typeflag == tar.TypeReg {
+			e.open = func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// ListArchive 列出归档中的文件和目录名(以/分隔)
+func ListArchive(src string) ([]string, error) {
+	var names []string
+	err := walkArchive(src, func(e archiveEntry) error {
+		names = append(names, strings.TrimSuffix(e.name, "/"))
+		return nil
+	})
+	return names, err
+}
+
+// ExtractArchive 将归档解压到dst目录，返回解压的总字节数；
+// 跳过符号链接等特殊条目，拒绝指向目录外的路径，总大小超过maxBytes时返回ErrArchiveTooLarge
+func ExtractArchive(src, dst string, maxBytes int64) (int64, error) {
+	if err := EnsureDir(dst); err != nil {
+		return 0, err
+	}
+	var total int64
+	err := walkArchive(src, func(e archiveEntry) error {
+		name := path.Clean("/" + strings.ReplaceAll(e.name, "\\", "/"))
+		if name == "/" {
+			return nil
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dst)+string(os.PathSeparator)) {
+			return fmt.Errorf("illegal path in archive: %s", e.name)
+		}
+		if e.dir {
+			return EnsureDir(target)
+		}
+		if e.open == nil {
+			return nil
+		}
+		if err := EnsureDir(filepath.Dir(target)); err != nil {
+			return err
+		}
+
+		rc, err := e.open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		n, err := io.Copy(out, io.LimitReader(rc, maxBytes-total+1))
+		total += n
+		if err != nil {
+			return err
+		}
+		if total > maxBytes {
+			return ErrArchiveTooLarge
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ZipDir 将目录以zip格式写入w，条目路径以目录名开头
+func ZipDir(w io.Writer, dir string) error {
+	// 目录本身可能是符号链接
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(filepath.Base(dir), filepath.ToSlash(rel))
+		hdr.Method = zip.Deflate
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DirSize 目录下普通文件的总大小，目录本身可以是符号链接
+func DirSize(dir string) (int64, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
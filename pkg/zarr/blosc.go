@@ -0,0 +1,323 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Blosc1帧格式解压，支持blosclz、lz4/lz4hc、zlib和zstd内部压缩器及字节/位重排
+
+var errBloscCorrupt = errors.New("blosc: corrupt input")
+
+// Blosc头部标志位
+const (
+	bloscDoShuffle    = 0x01
+	bloscMemcpyed     = 0x02
+	bloscDoBitShuffle = 0x04
+	bloscDontSplit    = 0x10
+	bloscHeaderSize   = 16
+	bloscMaxSplits    = 16
+	bloscMinBuffer    = 128
+)
+
+// Blosc内部压缩器编号(头部标志的高3位)
+const (
+	bloscBloscLZ = 0
+	bloscLZ4     = 1
+	bloscSnappy  = 2
+	bloscZlib    = 3
+	bloscZstd    = 4
+)
+
+// bloscDecompress 解压Blosc1格式的数据，解压大小超过limit时出错
+func bloscDecompress(src []byte, limit int) ([]byte, error) {
+	if len(src) < bloscHeaderSize {
+		return nil, errBloscCorrupt
+	}
+	flags := src[2]
+	typesize := int(src[3])
+	nbytes := int(binary.LittleEndian.Uint32(src[4:]))
+	blocksize := int(binary.LittleEndian.Uint32(src[8:]))
+	cbytes := int(binary.LittleEndian.Uint32(src[12:]))
+	if cbytes > len(src) || nbytes < 0 || typesize == 0 {
+		return nil, errBloscCorrupt
+	}
+	if nbytes > limit {
+		return nil, fmt.Errorf("blosc: decoded size %d exceeds expected %d", nbytes, limit)
+	}
+	src = src[:cbytes]
+
+	out := make([]byte, nbytes)
+	if flags&bloscMemcpyed != 0 {
+		if bloscHeaderSize+nbytes > len(src) {
+			return nil, errBloscCorrupt
+		}
+		copy(out, src[bloscHeaderSize:])
+		return out, nil
+	}
+	if nbytes == 0 {
+		return out, nil
+	}
+	if blocksize <= 0 || blocksize > nbytes {
+		return nil, errBloscCorrupt
+	}
+
+	compressor := int(flags >> 5)
+	nblocks := (nbytes + blocksize - 1) / blocksize
+	if bloscHeaderSize+nblocks*4 > len(src) {
+		return nil, errBloscCorrupt
+	}
+	tmp := make([]byte, blocksize)
+	for j := 0; j < nblocks; j++ {
+		start := int(binary.LittleEndian.Uint32(src[bloscHeaderSize+j*4:]))
+		bsize := blocksize
+		leftover := j == nblocks-1 && nbytes%blocksize != 0
+		if leftover {
+			bsize = nbytes % blocksize
+		}
+
+		nsplits := 1
+		if flags&bloscDontSplit == 0 && typesize <= bloscMaxSplits && blocksize/typesize >= bloscMinBuffer && !leftover {
+			nsplits = typesize
+		}
+		neblock := bsize / nsplits
+
+		final := out[j*blocksize : j*blocksize+bsize]
+		bitShuffled := flags&bloscDoBitShuffle != 0
+		byteShuffled := flags&bloscDoShuffle != 0 && typesize > 1
+		dst := final
+		if bitShuffled || byteShuffled {
+			dst = tmp[:bsize]
+		}
+
+		pos := start
+		for k := 0; k < nsplits; k++ {
+			if pos+4 > len(src) {
+				return nil, errBloscCorrupt
+			}
+			size := int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+			if pos+size > len(src) {
+				return nil, errBloscCorrupt
+			}
+			part := dst[k*neblock : (k+1)*neblock]
+			if size == neblock {
+				copy(part, src[pos:pos+size])
+			} else if err := bloscBlock(compressor, src[pos:pos+size], part); err != nil {
+				return nil, err
+			}
+			pos += size
+		}
+
+		switch {
+		case bitShuffled:
+			bitUnshuffle(dst, final, typesize)
+		case byteShuffled:
+			byteUnshuffle(dst, final, typesize)
+		}
+	}
+	return out, nil
+}
+
+// bloscBlock 用内部压缩器解压一个分块，结果须恰好填满dst
+func bloscBlock(compressor int, src, dst []byte) error {
+	var n int
+	var err error
+	switch compressor {
+	case bloscBloscLZ:
+		n, err = blosclzDecompress(src, dst)
+	case bloscLZ4:
+		n, err = lz4Block(src, dst)
+	case bloscZlib:
+		var zr io.ReadCloser
+		zr, err = zlib.NewReader(bytes.NewReader(src))
+		if err == nil {
+			n, err = io.ReadFull(zr, dst)
+			zr.Close()
+		}
+	case bloscZstd:
+		var data []byte
+		data, err = zstdDecompress(src, len(dst))
+		n = copy(dst, data)
+		if len(data) != len(dst) {
+			err = errBloscCorrupt
+		}
+	default:
+		return fmt.Errorf("%w: blosc compressor %d", ErrUnsupported, compressor)
+	}
+	if err != nil {
+		return err
+	}
+	if n != len(dst) {
+		return errBloscCorrupt
+	}
+	return nil
+}
+
+// blosclzDecompress BloscLZ(FastLZ变体)解压，返回写入的字节数
+func blosclzDecompress(src, dst []byte) (int, error) {
+	const maxDistance = 8191
+	if len(src) == 0 {
+		return 0, nil
+	}
+	ip, op := 0, 0
+	ctrl := int(src[ip] & 31)
+	ip++
+	for {
+		if ctrl >= 32 {
+			// 匹配
+			length := ctrl>>5 - 1
+			ofs := (ctrl & 31) << 8
+			if length == 6 {
+				for {
+					if ip+1 >= len(src) {
+						return 0, errBloscCorrupt
+					}
+					code := int(src[ip])
+					ip++
+					length += code
+					if code != 255 {
+						break
+					}
+				}
+			} else if ip+1 >= len(src) {
+				return 0, errBloscCorrupt
+			}
+			code := int(src[ip])
+			ip++
+			length += 3
+			ref := op - ofs - code
+			if code == 255 && ofs == 31<<8 {
+				if ip+1 >= len(src) {
+					return 0, errBloscCorrupt
+				}
+				ofs = int(src[ip])<<8 + int(src[ip+1])
+				ip += 2
+				ref = op - ofs - maxDistance
+			}
+			ref--
+			if ref < 0 || op+length > len(dst) {
+				return 0, errBloscCorrupt
+			}
+			for k := 0; k < length; k++ {
+				dst[op+k] = dst[ref+k]
+			}
+			op += length
+		} else {
+			// 字面量
+			ctrl++
+			if op+ctrl > len(dst) || ip+ctrl > len(src) {
+				return 0, errBloscCorrupt
+			}
+			copy(dst[op:], src[ip:ip+ctrl])
+			op += ctrl
+			ip += ctrl
+		}
+		if ip >= len(src) {
+			break
+		}
+		ctrl = int(src[ip])
+		ip++
+	}
+	return op, nil
+}
+
+// byteUnshuffle 还原字节重排: 各元素的第k字节连续存放
+func byteUnshuffle(src, dst []byte, typesize int) {
+	n := len(src) / typesize
+	for i := 0; i < n; i++ {
+		for k := 0; k < typesize; k++ {
+			dst[i*typesize+k] = src[k*n+i]
+		}
+	}
+	copy(dst[n*typesize:], src[n*typesize:])
+}
+
+// bitUnshuffle 还原位重排(bitshuffle): 以8个元素为单位，各元素的同一位连续存放
+func bitUnshuffle(src, dst []byte, typesize int) {
+	n := len(src) / typesize
+	n -= n % 8
+	for i := range dst[:n*typesize] {
+		dst[i] = 0
+	}
+	rowBytes := n / 8
+	for b := 0; b < typesize*8; b++ {
+		row := src[b*rowBytes : (b+1)*rowBytes]
+		byteIdx, bit := b/8, uint(b%8)
+		for i := 0; i < n; i++ {
+			if row[i/8]>>(uint(i)%8)&1 != 0 {
+				dst[i*typesize+byteIdx] |= 1 << bit
+			}
+		}
+	}
+	copy(dst[n*typesize:], src[n*typesize:])
+}
+
+// lz4Block LZ4块格式解压，返回写入的字节数
+func lz4Block(src, dst []byte) (int, error) {
+	ip, op := 0, 0
+	for ip < len(src) {
+		token := int(src[ip])
+		ip++
+
+		litLen := token >> 4
+		if litLen == 15 {
+			for {
+				if ip >= len(src) {
+					return 0, errLZ4Corrupt
+				}
+				b := int(src[ip])
+				ip++
+				litLen += b
+				if b != 255 {
+					break
+				}
+			}
+		}
+		if ip+litLen > len(src) || op+litLen > len(dst) {
+			return 0, errLZ4Corrupt
+		}
+		copy(dst[op:], src[ip:ip+litLen])
+		ip += litLen
+		op += litLen
+		if ip >= len(src) {
+			break // 最后一个序列只有字面量
+		}
+
+		if ip+2 > len(src) {
+			return 0, errLZ4Corrupt
+		}
+		offset := int(src[ip]) | int(src[ip+1])<<8
+		ip += 2
+		matchLen := token & 15
+		if matchLen == 15 {
+			for {
+				if ip >= len(src) {
+					return 0, errLZ4Corrupt
+				}
+				b := int(src[ip])
+				ip++
+				matchLen += b
+				if b != 255 {
+					break
+				}
+			}
+		}
+		matchLen += 4
+		if offset == 0 || offset > op || op+matchLen > len(dst) {
+			return 0, errLZ4Corrupt
+		}
+		from := op - offset
+		for k := 0; k < matchLen; k++ {
+			dst[op+k] = dst[from+k]
+		}
+		op += matchLen
+	}
+	return op, nil
+}
+
+var errLZ4Corrupt = errors.New("lz4: corrupt input")
@@ -0,0 +1,241 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// bloscFrame 按Blosc1格式编码: 各块按flags重排后分段，compress返回nil或结果不短于原文时按原样存放
+func bloscFrame(data []byte, typesize, blocksize int, flags byte, compress func([]byte) []byte) []byte {
+	nbytes := len(data)
+	nblocks := (nbytes + blocksize - 1) / blocksize
+	frame := make([]byte, bloscHeaderSize+4*nblocks)
+	frame[0], frame[1], frame[2], frame[3] = 2, 1, flags, byte(typesize)
+	binary.LittleEndian.PutUint32(frame[4:], uint32(nbytes))
+	binary.LittleEndian.PutUint32(frame[8:], uint32(blocksize))
+	if flags&bloscMemcpyed != 0 {
+		frame = append(frame[:bloscHeaderSize], data...)
+		binary.LittleEndian.PutUint32(frame[12:], uint32(len(frame)))
+		return frame
+	}
+
+	for j := 0; j < nblocks; j++ {
+		end := (j + 1) * blocksize
+		if end > nbytes {
+			end = nbytes
+		}
+		block := data[j*blocksize : end]
+		switch {
+		case flags&bloscDoBitShuffle != 0:
+			block = bitShuffle(block, typesize)
+		case flags&bloscDoShuffle != 0 && typesize > 1:
+			block = byteShuffle(block, typesize)
+		}
+		nsplits := 1
+		if flags&bloscDontSplit == 0 && typesize <= bloscMaxSplits && blocksize/typesize >= bloscMinBuffer && len(block) == blocksize {
+			nsplits = typesize
+		}
+
+		binary.LittleEndian.PutUint32(frame[bloscHeaderSize+4*j:], uint32(len(frame)))
+		neblock := len(block) / nsplits
+		for k := 0; k < nsplits; k++ {
+			part := block[k*neblock : (k+1)*neblock]
+			c := compress(part)
+			if c == nil || len(c) >= len(part) {
+				c = part
+			}
+			frame = binary.LittleEndian.AppendUint32(frame, uint32(len(c)))
+			frame = append(frame, c...)
+		}
+	}
+	binary.LittleEndian.PutUint32(frame[12:], uint32(len(frame)))
+	return frame
+}
+
+// byteShuffle 字节重排: 各元素的第k字节连续存放，不足一个元素的尾部原样保留
+func byteShuffle(src []byte, typesize int) []byte {
+	n := len(src) / typesize
+	out := append([]byte(nil), src...)
+	for i := 0; i < n; i++ {
+		for k := 0; k < typesize; k++ {
+			out[k*n+i] = src[i*typesize+k]
+		}
+	}
+	return out
+}
+
+// bitShuffle 位重排: 第b行(第b/8字节的第b%8位)依次存放各元素的该位，元素数按8取整，其余原样保留
+func bitShuffle(src []byte, typesize int) []byte {
+	n := len(src) / typesize
+	n -= n % 8
+	out := append([]byte(nil), src...)
+	for i := range out[:n*typesize] {
+		out[i] = 0
+	}
+	for b := 0; b < typesize*8; b++ {
+		for i := 0; i < n; i++ {
+			if src[i*typesize+b/8]>>(uint(b)%8)&1 != 0 {
+				out[b*(n/8)+i/8] |= 1 << (uint(i) % 8)
+			}
+		}
+	}
+	return out
+}
+
+func zlibCompress(data []byte) []byte {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+// float32Bytes 平滑变化的float32序列(小端)，重排后高字节可压缩
+func float32Bytes(n int) []byte {
+	out := make([]byte, 4*n)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(float32(15+10*math.Sin(float64(i)/50))))
+	}
+	return out
+}
+
+func TestBloscDecompress(t *testing.T) {
+	text := testText(200)
+	floats := float32Bytes(1000)
+	raw := func([]byte) []byte { return nil }
+	fixed := func(c []byte) func([]byte) []byte { return func([]byte) []byte { return c } }
+
+	tests := []struct {
+		name      string
+		data      []byte
+		typesize  int
+		blocksize int
+		flags     byte
+		compress  func([]byte) []byte
+	}{
+		{"memcpy", text, 1, 4096, bloscMemcpyed, nil},
+		{"stored blocks", text[:1000], 1, 256, 0, raw},
+		{"zlib shuffle split", floats, 4, 1024, bloscZlib<<5 | bloscDoShuffle, zlibCompress},
+		{"zlib shuffle leftover", floats[:1022], 4, 512, bloscZlib<<5 | bloscDoShuffle, zlibCompress},
+		{"zlib bitshuffle", floats, 4, 2048, bloscZlib<<5 | bloscDoBitShuffle | bloscDontSplit, zlibCompress},
+		{"lz4 reference block", text, 1, len(text), bloscLZ4<<5 | bloscDontSplit, fixed(readTestdata(t, "text200.lz4"))},
+		{"zstd reference frame", text, 1, len(text), bloscZstd<<5 | bloscDontSplit, fixed(readTestdata(t, "text200-19.zst"))},
+	}
+	for _, tt := range tests {
+		frame := bloscFrame(tt.data, tt.typesize, tt.blocksize, tt.flags, tt.compress)
+		got, err := bloscDecompress(frame, len(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: decoded data differs from input", tt.name)
+		}
+	}
+
+	frame := bloscFrame(floats, 4, 1024, bloscZlib<<5|bloscDoShuffle, zlibCompress)
+	if _, err := bloscDecompress(frame, len(floats)-1); err == nil {
+		t.Error("decoded size beyond the limit should fail")
+	}
+	for _, n := range []int{0, 15, 40, len(frame) - 1} {
+		if _, err := bloscDecompress(frame[:n], len(floats)); err == nil {
+			t.Errorf("frame truncated to %d bytes should fail", n)
+		}
+	}
+	if _, err := bloscDecompress(bloscFrame(text, 1, len(text), bloscSnappy<<5|bloscDontSplit, zlibCompress), len(text)); err == nil {
+		t.Error("snappy should be unsupported")
+	}
+}
+
+func TestBlosclzDecompress(t *testing.T) {
+	// 参照FastLZ level 1格式手工构造: 控制字节<32为字面量，否则高3位为匹配长度、低5位与下一字节为距离
+	tests := []struct {
+		name string
+		src  []byte
+		want string
+	}{
+		{"literals", []byte{0x04, 'h', 'e', 'l', 'l', 'o'}, "hello"},
+		{"short match", []byte{0x02, 'a', 'b', 'c', 0x80, 0x02, 0x00, '!'}, "abcabcabc!"},
+		{"long match", []byte{0x00, 'a', 0xE0, 11, 0x00, 0x00, 'b'}, "aaaaaaaaaaaaaaaaaaaaab"},
+	}
+	for _, tt := range tests {
+		dst := make([]byte, len(tt.want))
+		n, err := blosclzDecompress(tt.src, dst)
+		if err != nil || string(dst[:n]) != tt.want {
+			t.Errorf("%s: %q (%v), want %q", tt.name, dst[:n], err, tt.want)
+		}
+	}
+	for _, src := range [][]byte{
+		{0x02, 'a', 'b'},                   // 字面量不完整
+		{0x00, 'a', 0x80, 0x05, 0x00, 'b'}, // 距离超出已解压数据
+	} {
+		if _, err := blosclzDecompress(src, make([]byte, 64)); err == nil {
+			t.Errorf("blosclzDecompress(%v) should fail", src)
+		}
+	}
+}
+
+func TestLZ4Block(t *testing.T) {
+	text := testText(200)
+	tests := []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{"reference block", readTestdata(t, "text200.lz4"), text},
+		// 字面量"abc"后接距离3、长度9的重叠匹配，最后5字节为字面量
+		{"overlapping match", []byte{0x35, 'a', 'b', 'c', 3, 0, 0x50, 'X', 'Y', 'Z', 'W', 'V'}, []byte("abcabcabcabcXYZWV")},
+		// 字面量长度15+3
+		{"long literals", append([]byte{0xF0, 3}, "abcdefghijklmnopqr"...), []byte("abcdefghijklmnopqr")},
+	}
+	for _, tt := range tests {
+		dst := make([]byte, len(tt.want))
+		n, err := lz4Block(tt.src, dst)
+		if err != nil || !bytes.Equal(dst[:n], tt.want) {
+			t.Errorf("%s: %q (%v)", tt.name, dst[:n], err)
+		}
+	}
+
+	// numcodecs LZ4: 4字节小端原始长度后接LZ4块
+	chunk := binary.LittleEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, readTestdata(t, "text200.lz4")...)
+	got, err := bytesCodec{id: "lz4"}.decode(chunk, len(text))
+	if err != nil || !bytes.Equal(got, text) {
+		t.Errorf("lz4 codec: %d bytes (%v)", len(got), err)
+	}
+	if _, err := (bytesCodec{id: "lz4"}).decode(chunk, len(text)-1); err == nil {
+		t.Error("lz4 codec: size beyond the limit should fail")
+	}
+	for _, src := range [][]byte{{0x10}, {0x04, 0, 0}, {0x04, 1, 0}} {
+		if _, err := lz4Block(src, make([]byte, 64)); err == nil {
+			t.Errorf("lz4Block(%v) should fail", src)
+		}
+	}
+}
+
+func FuzzBloscDecompress(f *testing.F) {
+	f.Add(bloscFrame(float32Bytes(256), 4, 1024, bloscZlib<<5|bloscDoShuffle, zlibCompress))
+	f.Add(bloscFrame(float32Bytes(64), 4, 256, bloscDoBitShuffle, func([]byte) []byte { return nil }))
+	f.Add(bloscFrame(testText(200), 1, 6800, bloscLZ4<<5|bloscDontSplit, func([]byte) []byte { return readTestdata(f, "text200.lz4") }))
+	f.Add(bloscFrame([]byte("abcabcabc!"), 1, 10, bloscDontSplit, func([]byte) []byte { return []byte{0x02, 'a', 'b', 'c', 0x80, 0x02, 0x00, '!'} }))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := bloscDecompress(data, 1<<16)
+		if err == nil && len(out) > 1<<16 {
+			t.Fatalf("decoded %d bytes beyond the limit", len(out))
+		}
+	})
+}
+
+func FuzzLZ4Block(f *testing.F) {
+	f.Add(readTestdata(f, "text200.lz4"))
+	f.Add([]byte{0x35, 'a', 'b', 'c', 3, 0, 0x50, 'X', 'Y', 'Z', 'W', 'V'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dst := make([]byte, 4096)
+		if n, err := lz4Block(data, dst); err == nil && n > len(dst) {
+			t.Fatalf("wrote %d bytes into %d", n, len(dst))
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// bytesCodec 字节到字节的编解码器(压缩、字节重排、校验)
+type bytesCodec struct {
+	id          string
+	elementSize int // shuffle过滤器的元素字节数
+}
+
+// decode 解码，size为解码后的预期字节数，也是解压结果的上限，避免按文件中的长度字段分配过多内存
+func (c bytesCodec) decode(data []byte, size int) ([]byte, error) {
+	switch c.id {
+	case "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return readAllSized(zr, size)
+	case "zlib":
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return readAllSized(zr, size)
+	case "bz2":
+		return readAllSized(bzip2.NewReader(bytes.NewReader(data)), size)
+	case "zstd":
+		return zstdDecompress(data, size)
+	case "blosc":
+		return bloscDecompress(data, size)
+	case "lz4":
+		// numcodecs LZ4: 4字节小端原始长度后接LZ4块
+		if len(data) < 4 {
+			return nil, errLZ4Corrupt
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(size) {
+			return nil, fmt.Errorf("lz4: decoded size %d exceeds expected %d", n, size)
+		}
+		out := make([]byte, n)
+		m, err := lz4Block(data[4:], out)
+		if err != nil {
+			return nil, err
+		}
+		return out[:m], nil
+	case "crc32c":
+		if len(data) < 4 {
+			return nil, errors.New("crc32c: truncated chunk")
+		}
+		body := data[:len(data)-4]
+		if crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)) != binary.LittleEndian.Uint32(data[len(data)-4:]) {
+			return nil, errors.New("crc32c: checksum mismatch")
+		}
+		return body, nil
+	case "shuffle":
+		out := make([]byte, len(data))
+		byteUnshuffle(data, out, c.elementSize)
+		return out, nil
+	}
+	return nil, fmt.Errorf("%w: codec %s", ErrUnsupported, c.id)
+}
+
+// readAllSized 读取全部数据，超过size字节时出错
+func readAllSized(r io.Reader, size int) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if _, err := io.Copy(buf, io.LimitReader(r, int64(size)+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > size {
+		return nil, fmt.Errorf("decoded size exceeds expected %d", size)
+	}
+	return buf.Bytes(), nil
+}
+
+// dataType 元素类型
+type dataType struct {
+	kind  byte // 'f'浮点、'i'有符号整数、'u'无符号整数、'b'布尔
+	size  int
+	order binary.ByteOrder
+}
+
+// parseV2DType 解析v2的NumPy类型字符串，如"<f4"、"|u1"、"<M8[ns]"
+func parseV2DType(raw json.RawMessage) (dataType, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil || len(s) < 3 {
+		return dataType{}, fmt.Errorf("%w: dtype %s", ErrUnsupported, string(raw))
+	}
+	dt := dataType{order: binary.LittleEndian}
+	if s[0] == '>' {
+		dt.order = binary.BigEndian
+	}
+	kind := s[1]
+	var size int
+	if _, err := fmt.Sscanf(s[2:], "%d", &size); err != nil {
+		return dataType{}, fmt.Errorf("%w: dtype %s", ErrUnsupported, s)
+	}
+	switch kind {
+	case 'f', 'i', 'u', 'b':
+		dt.kind = kind
+	case 'M', 'm':
+		// datetime64/timedelta64按int64读取
+		dt.kind = 'i'
+	default:
+		return dataType{}, fmt.Errorf("%w: dtype %s", ErrUnsupported, s)
+	}
+	dt.size = size
+	if !dt.valid() {
+		return dataType{}, fmt.Errorf("%w: dtype %s", ErrUnsupported, s)
+	}
+	return dt, nil
+}
+
+// parseV3DType 解析v3的data_type名称
+func parseV3DType(raw json.RawMessage) (dataType, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return dataType{}, fmt.Errorf("%w: data_type %s", ErrUnsupported, string(raw))
+	}
+	dt := dataType{order: binary.LittleEndian}
+	switch s {
+	case "bool":
+		dt.kind, dt.size = 'b', 1
+	case "int8", "int16", "int32", "int64":
+		dt.kind = 'i'
+		fmt.Sscanf(s[3:], "%d", &dt.size)
+		dt.size /= 8
+	case "uint8", "uint16", "uint32", "uint64":
+		dt.kind = 'u'
+		fmt.Sscanf(s[4:], "%d", &dt.size)
+		dt.size /= 8
+	case "float16", "float32", "float64":
+		dt.kind = 'f'
+		fmt.Sscanf(s[5:], "%d", &dt.size)
+		dt.size /= 8
+	default:
+		return dataType{}, fmt.Errorf("%w: data_type %s", ErrUnsupported, s)
+	}
+	return dt, nil
+}
+
+func (dt dataType) valid() bool {
+	switch dt.kind {
+	case 'f':
+		return dt.size == 2 || dt.size == 4 || dt.size == 8
+	case 'i', 'u':
+		return dt.size == 1 || dt.size == 2 || dt.size == 4 || dt.size == 8
+	case 'b':
+		return dt.size == 1
+	}
+	return false
+}
+
+// typeName 对应的CDL类型名
+func (dt dataType) typeName() string {
+	switch dt.kind {
+	case 'f':
+		if dt.size == 8 {
+			return "double"
+		}
+		return "float"
+	case 'i':
+		return [...]string{1: "byte", 2: "short", 4: "int", 8: "int64"}[dt.size]
+	case 'u':
+		return [...]string{1: "ubyte", 2: "ushort", 4: "uint", 8: "uint64"}[dt.size]
+	}
+	return "byte"
+}
+
+// decodeValues 将字节按元素类型转换为float64
+func (dt dataType) decodeValues(data []byte, n int) ([]float64, error) {
+	if len(data) < n*dt.size {
+		return nil, fmt.Errorf("zarr: chunk has %d bytes, expected %d", len(data), n*dt.size)
+	}
+	out := make([]float64, n)
+	o := dt.order
+	for i := range out {
+		b := data[i*dt.size:]
+		switch dt.kind {
+		case 'f':
+			switch dt.size {
+			case 2:
+				out[i] = float16(o.Uint16(b))
+			case 4:
+				out[i] = float64(math.Float32frombits(o.Uint32(b)))
+			default:
+				out[i] = math.Float64frombits(o.Uint64(b))
+			}
+		case 'i':
+			switch dt.size {
+			case 1:
+				out[i] = float64(int8(b[0]))
+			case 2:
+				out[i] = float64(int16(o.Uint16(b)))
+			case 4:
+				out[i] = float64(int32(o.Uint32(b)))
+			default:
+				out[i] = float64(int64(o.Uint64(b)))
+			}
+		default:
+			switch dt.size {
+			case 1:
+				out[i] = float64(b[0])
+			case 2:
+				out[i] = float64(o.Uint16(b))
+			case 4:
+				out[i] = float64(o.Uint32(b))
+			default:
+				out[i] = float64(o.Uint64(b))
+			}
+		}
+	}
+	return out, nil
+}
+
+// float16 半精度浮点转换
+func float16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1F
+	frac := float64(h & 0x3FF)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(1+frac/1024, exp-15)
+}
+
+// parseFillValue 解析fill_value: 数值、布尔、"NaN"、"Infinity"、"-Infinity"或v3的十六进制位模式
+func parseFillValue(raw json.RawMessage, dt dataType) (float64, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, false
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, false
+	}
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		switch x {
+		case "NaN":
+			return math.NaN(), true
+		case "Infinity":
+			return math.Inf(1), true
+		case "-Infinity":
+			return math.Inf(-1), true
+		}
+		var bits uint64
+		if _, err := fmt.Sscanf(x, "0x%x", &bits); err == nil {
+			switch dt.size {
+			case 2:
+				return float16(uint16(bits)), true
+			case 4:
+				return float64(math.Float32frombits(uint32(bits))), true
+			default:
+				return math.Float64frombits(bits), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// transposeToC 将按perm转置存放的块还原为C序，perm[i]为编码后第i维对应的原始维度
+func transposeToC(values []float64, shape, perm []int) []float64 {
+	n := len(shape)
+	encShape := make([]int, n)
+	for i, p := range perm {
+		encShape[i] = shape[p]
+	}
+	// 原始数组各维的步长
+	strides := make([]int, n)
+	s := 1
+	for d := n - 1; d >= 0; d-- {
+		strides[d] = s
+		s *= shape[d]
+	}
+
+	out := make([]float64, len(values))
+	idx := make([]int, n)
+	for e := range values {
+		dst := 0
+		for i := 0; i < n; i++ {
+			dst += idx[i] * strides[perm[i]]
+		}
+		out[dst] = values[e]
+		for i := n - 1; i >= 0; i-- {
+			idx[i]++
+			if idx[i] < encShape[i] {
+				break
+			}
+			idx[i] = 0
+		}
+	}
+	return out
+}
@@ -0,0 +1,876 @@
+// Package zarr 纯Go实现的Zarr v2/v3目录存储读取: 支持合并元数据、分块与分片(sharding)存储、
+// 按块读取超立方体切片，以及gzip、zlib、bz2、zstd、lz4、blosc压缩和crc32c校验
+package zarr
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNotZarr 路径不是Zarr存储
+	ErrNotZarr = errors.New("zarr: not a zarr store")
+	// ErrUnsupported 不支持的Zarr特性(数据类型、编解码器等)
+	ErrUnsupported = errors.New("zarr: unsupported feature")
+)
+
+// maxCacheBytes 每个数组解码后数据块的缓存上限
+const maxCacheBytes = 64 << 20
+
+// Store 已打开的Zarr存储，只读取根组下的数组
+type Store struct {
+	Path    string
+	Version int // 2或3
+	Attrs   map[string]interface{}
+	Arrays  []*Array
+	// Skipped 因数据类型或编解码器不受支持而跳过的数组
+	Skipped []string
+}
+
+// Array Zarr数组
+type Array struct {
+	Name  string
+	Shape []int
+	// Chunks 最小读取单元的形状，分片存储时为分片内的块
+	Chunks []int
+	// Dims 维度名(v3的dimension_names或xarray的_ARRAY_DIMENSIONS)，未提供时为空
+	Dims      []string
+	Attrs     map[string]interface{}
+	FillValue float64
+	HasFill   bool
+	Version   int
+
+	dir       string
+	dtype     dataType
+	codecs    []bytesCodec // 按编码顺序
+	perm      []int        // 块内转置，nil表示C序
+	keyPrefix string
+	keySep    string
+	shard     *shardLayout
+
+	mu         sync.Mutex
+	cache      map[string][]float64
+	cacheOrder []string
+	cacheBytes int
+}
+
+// shardLayout 分片存储的布局
+type shardLayout struct {
+	shape        []int // 分片形状
+	perShard     []int // 每个分片各维的块数
+	indexCodecs  []bytesCodec
+	indexOrder   binary.ByteOrder
+	indexAtStart bool
+
+	mu    sync.Mutex
+	index map[string][]uint64
+}
+
+// Open 打开Zarr目录存储，自动识别v2(含.zmetadata合并元数据)和v3
+func Open(path string) (*Store, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, ErrNotZarr
+	}
+	s := &Store{Path: path, Attrs: map[string]interface{}{}}
+	if fileExists(filepath.Join(path, "zarr.json")) {
+		s.Version = 3
+		err = s.openV3()
+	} else if fileExists(filepath.Join(path, ".zmetadata")) || fileExists(filepath.Join(path, ".zgroup")) || fileExists(filepath.Join(path, ".zarray")) {
+		s.Version = 2
+		err = s.openV2()
+	} else {
+		return nil, ErrNotZarr
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(s.Arrays, func(i, j int) bool { return s.Arrays[i].Name < s.Arrays[j].Name })
+	return s, nil
+}
+
+// Array 按名称查找数组
+func (s *Store) Array(name string) *Array {
+	for _, a := range s.Arrays {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Close 实现io.Closer，目录存储无需释放资源
+func (s *Store) Close() error {
+	return nil
+}
+
+// rootName 根节点本身是数组时使用的名称
+func (s *Store) rootName() string {
+	base := filepath.Base(s.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// v2数组元数据(.zarray)
+type v2Meta struct {
+	Shape              []int                    `json:"shape"`
+	Chunks             []int                    `json:"chunks"`
+	DType              json.RawMessage          `json:"dtype"`
+	Compressor         map[string]interface{}   `json:"compressor"`
+	FillValue          json.RawMessage          `json:"fill_value"`
+	Filters            []map[string]interface{} `json:"filters"`
+	Order              string                   `json:"order"`
+	DimensionSeparator string                   `json:"dimension_separator"`
+}
+
+// openV2 读取v2元数据，优先使用.zmetadata
+func (s *Store) openV2() error {
+	var consolidated struct {
+		Metadata map[string]json.RawMessage `json:"metadata"`
+	}
+	if data, err := os.ReadFile(filepath.Join(s.Path, ".zmetadata")); err == nil {
+		if err := json.Unmarshal(data, &consolidated); err != nil {
+			return fmt.Errorf("zarr: invalid .zmetadata: %w", err)
+		}
+	}
+
+	// 读取元数据文件，有合并元数据时直接取用
+	load := func(key string) ([]byte, bool) {
+		if consolidated.Metadata != nil {
+			raw, ok := consolidated.Metadata[key]
+			return raw, ok
+		}
+		data, err := os.ReadFile(filepath.Join(s.Path, filepath.FromSlash(key)))
+		return data, err == nil
+	}
+	attrsOf := func(prefix string) (map[string]interface{}, error) {
+		attrs := map[string]interface{}{}
+		if raw, ok := load(prefix + ".zattrs"); ok {
+			if err := json.Unmarshal(raw, &attrs); err != nil {
+				return nil, fmt.Errorf("zarr: invalid %s.zattrs: %w", prefix, err)
+			}
+		}
+		return attrs, nil
+	}
+	addArray := func(name, prefix string) error {
+		raw, _ := load(prefix + ".zarray")
+		var meta v2Meta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return fmt.Errorf("zarr: invalid %s.zarray: %w", prefix, err)
+		}
+		attrs, err := attrsOf(prefix)
+		if err != nil {
+			return err
+		}
+		a, err := newV2Array(name, filepath.Join(s.Path, filepath.FromSlash(prefix)), &meta, attrs)
+		if errors.Is(err, ErrUnsupported) {
+			s.Skipped = append(s.Skipped, name)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		s.Arrays = append(s.Arrays, a)
+		return nil
+	}
+
+	if _, ok := load(".zarray"); ok {
+		return addArray(s.rootName(), "")
+	}
+	attrs, err := attrsOf("")
+	if err != nil {
+		return err
+	}
+	s.Attrs = attrs
+
+	var names []string
+	if consolidated.Metadata != nil {
+		for key := range consolidated.Metadata {
+			if name := strings.TrimSuffix(key, "/.zarray"); name != key && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(s.Path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() && fileExists(filepath.Join(s.Path, e.Name(), ".zarray")) {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	for _, name := range names {
+		if err := addArray(name, name+"/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newV2Array 由.zarray和.zattrs创建数组
+func newV2Array(name, dir string, meta *v2Meta, attrs map[string]interface{}) (*Array, error) {
+	dt, err := parseV2DType(meta.DType)
+	if err != nil {
+		return nil, err
+	}
+	a := &Array{
+		Name: name, Shape: meta.Shape, Chunks: meta.Chunks, Attrs: attrs, Version: 2,
+		dir: dir, dtype: dt, keySep: meta.DimensionSeparator,
+	}
+	if a.keySep == "" {
+		a.keySep = "."
+	}
+	a.FillValue, a.HasFill = parseFillValue(meta.FillValue, dt)
+	a.Dims = dimensionNames(attrs["_ARRAY_DIMENSIONS"], len(a.Shape))
+	delete(attrs, "_ARRAY_DIMENSIONS")
+
+	for _, f := range meta.Filters {
+		id, _ := f["id"].(string)
+		if id != "shuffle" {
+			return nil, fmt.Errorf("%w: filter %s", ErrUnsupported, id)
+		}
+		size := dt.size
+		if v, ok := f["elementsize"].(float64); ok && v > 0 {
+			size = int(v)
+		}
+		a.codecs = append(a.codecs, bytesCodec{id: "shuffle", elementSize: size})
+	}
+	if meta.Compressor != nil {
+		id, _ := meta.Compressor["id"].(string)
+		switch id {
+		case "blosc", "zlib", "gzip", "bz2", "zstd", "lz4":
+			a.codecs = append(a.codecs, bytesCodec{id: id})
+		default:
+			return nil, fmt.Errorf("%w: compressor %s", ErrUnsupported, id)
+		}
+	}
+	if meta.Order == "F" && len(a.Shape) > 1 {
+		a.perm = make([]int, len(a.Shape))
+		for i := range a.perm {
+			a.perm[i] = len(a.Shape) - 1 - i
+		}
+	}
+	return a, a.validate()
+}
+
+// v3节点元数据(zarr.json)
+type v3Meta struct {
+	NodeType  string          `json:"node_type"`
+	Shape     []int           `json:"shape"`
+	DataType  json.RawMessage `json:"data_type"`
+	ChunkGrid struct {
+		Name          string `json:"name"`
+		Configuration struct {
+			ChunkShape []int `json:"chunk_shape"`
+		} `json:"configuration"`
+	} `json:"chunk_grid"`
+	ChunkKeyEncoding struct {
+		Name          string `json:"name"`
+		Configuration struct {
+			Separator string `json:"separator"`
+		} `json:"configuration"`
+	} `json:"chunk_key_encoding"`
+	FillValue            json.RawMessage        `json:"fill_value"`
+	Codecs               []v3Codec              `json:"codecs"`
+	Attributes           map[string]interface{} `json:"attributes"`
+	DimensionNames       []*string              `json:"dimension_names"`
+	ConsolidatedMetadata *struct {
+		Metadata map[string]json.RawMessage `json:"metadata"`
+	} `json:"consolidated_metadata"`
+}
+
+// v3Codec v3编解码器配置
+type v3Codec struct {
+	Name          string          `json:"name"`
+	Configuration json.RawMessage `json:"configuration"`
+}
+
+// openV3 读取v3元数据，根组含consolidated_metadata时直接取用
+func (s *Store) openV3() error {
+	root, err := readV3Meta(filepath.Join(s.Path, "zarr.json"))
+	if err != nil {
+		return err
+	}
+	if root.NodeType == "array" {
+		a, err := newV3Array(s.rootName(), s.Path, root)
+		if err != nil {
+			return err
+		}
+		s.Arrays = append(s.Arrays, a)
+		return nil
+	}
+	if root.Attributes != nil {
+		s.Attrs = root.Attributes
+	}
+
+	children := map[string]*v3Meta{}
+	if root.ConsolidatedMetadata != nil && root.ConsolidatedMetadata.Metadata != nil {
+		for name, raw := range root.ConsolidatedMetadata.Metadata {
+			if strings.Contains(name, "/") {
+				continue
+			}
+			var meta v3Meta
+			if err := json.Unmarshal(raw, &meta); err != nil {
+				return fmt.Errorf("zarr: invalid metadata of %s: %w", name, err)
+			}
+			children[name] = &meta
+		}
+	} else {
+		entries, err := os.ReadDir(s.Path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			p := filepath.Join(s.Path, e.Name(), "zarr.json")
+			if !e.IsDir() || !fileExists(p) {
+				continue
+			}
+			meta, err := readV3Meta(p)
+			if err != nil {
+				return err
+			}
+			children[e.Name()] = meta
+		}
+	}
+
+	for name, meta := range children {
+		if meta.NodeType != "array" {
+			continue
+		}
+		a, err := newV3Array(name, filepath.Join(s.Path, name), meta)
+		if errors.Is(err, ErrUnsupported) {
+			s.Skipped = append(s.Skipped, name)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		s.Arrays = append(s.Arrays, a)
+	}
+	return nil
+}
+
+func readV3Meta(path string) (*v3Meta, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var meta v3Meta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("zarr: invalid %s: %w", filepath.Base(path), err)
+	}
+	return &meta, nil
+}
+
+// newV3Array 由zarr.json创建数组
+func newV3Array(name, dir string, meta *v3Meta) (*Array, error) {
+	dt, err := parseV3DType(meta.DataType)
+	if err != nil {
+		return nil, err
+	}
+	if meta.ChunkGrid.Name != "regular" {
+		return nil, fmt.Errorf("%w: chunk grid %s", ErrUnsupported, meta.ChunkGrid.Name)
+	}
+	attrs := meta.Attributes
+	if attrs == nil {
+		attrs = map[string]interface{}{}
+	}
+	a := &Array{
+		Name: name, Shape: meta.Shape, Chunks: meta.ChunkGrid.Configuration.ChunkShape, Attrs: attrs, Version: 3,
+		dir: dir, dtype: dt,
+	}
+	a.FillValue, a.HasFill = parseFillValue(meta.FillValue, dt)
+
+	if len(meta.DimensionNames) == len(a.Shape) {
+		for _, d := range meta.DimensionNames {
+			if d == nil {
+				a.Dims = nil
+				break
+			}
+			a.Dims = append(a.Dims, *d)
+		}
+	}
+	if a.Dims == nil {
+		a.Dims = dimensionNames(attrs["_ARRAY_DIMENSIONS"], len(a.Shape))
+	}
+	delete(attrs, "_ARRAY_DIMENSIONS")
+
+	switch meta.ChunkKeyEncoding.Name {
+	case "", "default":
+		a.keyPrefix, a.keySep = "c", "/"
+	case "v2":
+		a.keySep = "."
+	default:
+		return nil, fmt.Errorf("%w: chunk key encoding %s", ErrUnsupported, meta.ChunkKeyEncoding.Name)
+	}
+	if sep := meta.ChunkKeyEncoding.Configuration.Separator; sep != "" {
+		a.keySep = sep
+	}
+
+	// 分片编解码器作为array->bytes时，块的编解码链取自分片配置
+	codecs := meta.Codecs
+	for i, c := range codecs {
+		if c.Name != "sharding_indexed" {
+			continue
+		}
+		if i != len(codecs)-1 || i != 0 {
+			return nil, fmt.Errorf("%w: codecs around sharding_indexed", ErrUnsupported)
+		}
+		var cfg struct {
+			ChunkShape    []int     `json:"chunk_shape"`
+			Codecs        []v3Codec `json:"codecs"`
+			IndexCodecs   []v3Codec `json:"index_codecs"`
+			IndexLocation string    `json:"index_location"`
+		}
+		if err := json.Unmarshal(c.Configuration, &cfg); err != nil {
+			return nil, fmt.Errorf("zarr: invalid sharding configuration: %w", err)
+		}
+		if len(cfg.ChunkShape) != len(a.Shape) {
+			return nil, fmt.Errorf("zarr: invalid sharding chunk shape")
+		}
+		sh := &shardLayout{shape: a.Chunks, indexOrder: binary.LittleEndian, indexAtStart: cfg.IndexLocation == "start", index: map[string][]uint64{}}
+		for d, n := range cfg.ChunkShape {
+			if n <= 0 || sh.shape[d]%n != 0 {
+				return nil, fmt.Errorf("zarr: shard shape is not a multiple of chunk shape")
+			}
+			sh.perShard = append(sh.perShard, sh.shape[d]/n)
+		}
+		idx := &Array{dtype: dataType{kind: 'u', size: 8, order: binary.LittleEndian}}
+		if err := idx.parseV3Codecs(cfg.IndexCodecs); err != nil {
+			return nil, err
+		}
+		sh.indexCodecs, sh.indexOrder = idx.codecs, idx.dtype.order
+		a.shard = sh
+		a.Chunks = cfg.ChunkShape
+		codecs = cfg.Codecs
+	}
+	if err := a.parseV3Codecs(codecs); err != nil {
+		return nil, err
+	}
+	return a, a.validate()
+}
+
+// parseV3Codecs 解析transpose、bytes及字节编解码器
+func (a *Array) parseV3Codecs(codecs []v3Codec) error {
+	seenBytes := false
+	for _, c := range codecs {
+		switch c.Name {
+		case "transpose":
+			if seenBytes {
+				return fmt.Errorf("%w: transpose after bytes codec", ErrUnsupported)
+			}
+			var cfg struct {
+				Order json.RawMessage `json:"order"`
+			}
+			json.Unmarshal(c.Configuration, &cfg)
+			var order []int
+			if err := json.Unmarshal(cfg.Order, &order); err != nil {
+				var s string
+				if json.Unmarshal(cfg.Order, &s) != nil || (s != "C" && s != "F") {
+					return fmt.Errorf("zarr: invalid transpose order")
+				}
+				order = make([]int, len(a.Shape))
+				for i := range order {
+					order[i] = i
+					if s == "F" {
+						order[i] = len(order) - 1 - i
+					}
+				}
+			}
+			if a.perm == nil {
+				a.perm = order
+			} else {
+				// 多个转置依次作用
+				combined := make([]int, len(order))
+				for i, o := range order {
+					combined[i] = a.perm[o]
+				}
+				a.perm = combined
+			}
+		case "bytes", "endian":
+			var cfg struct {
+				Endian string `json:"endian"`
+			}
+			json.Unmarshal(c.Configuration, &cfg)
+			if cfg.Endian == "big" {
+				a.dtype.order = binary.BigEndian
+			}
+			seenBytes = true
+		case "gzip", "zstd", "blosc", "crc32c":
+			a.codecs = append(a.codecs, bytesCodec{id: c.Name})
+		case "sharding_indexed":
+			return fmt.Errorf("%w: nested sharding", ErrUnsupported)
+		default:
+			return fmt.Errorf("%w: codec %s", ErrUnsupported, c.Name)
+		}
+	}
+	return nil
+}
+
+// validate 检查形状与块形状
+func (a *Array) validate() error {
+	if len(a.Chunks) != len(a.Shape) {
+		return fmt.Errorf("zarr: chunk shape %v does not match shape %v", a.Chunks, a.Shape)
+	}
+	for d := range a.Shape {
+		if a.Chunks[d] <= 0 || a.Shape[d] < 0 {
+			return fmt.Errorf("zarr: invalid chunk shape %v", a.Chunks)
+		}
+	}
+	if a.perm != nil {
+		seen := make([]bool, len(a.Shape))
+		if len(a.perm) != len(a.Shape) {
+			return fmt.Errorf("zarr: invalid transpose order %v", a.perm)
+		}
+		for _, p := range a.perm {
+			if p < 0 || p >= len(seen) || seen[p] {
+				return fmt.Errorf("zarr: invalid transpose order %v", a.perm)
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
+
+// TypeName 元素类型对应的CDL类型名
+func (a *Array) TypeName() string {
+	return a.dtype.typeName()
+}
+
+// Read 读取超立方体切片(原始值，缺失的块取fill_value)，只解码与切片相交的块
+func (a *Array) Read(start, count []int) ([]float64, error) {
+	n := len(a.Shape)
+	if len(start) != n || len(count) != n {
+		return nil, fmt.Errorf("zarr: %s: slice rank does not match", a.Name)
+	}
+	total := 1
+	for d := 0; d < n; d++ {
+		if start[d] < 0 || count[d] < 0 || start[d]+count[d] > a.Shape[d] {
+			return nil, fmt.Errorf("zarr: %s: slice out of range", a.Name)
+		}
+		total *= count[d]
+	}
+	out := make([]float64, total)
+	if total == 0 {
+		return out, nil
+	}
+
+	// 输出数组各维的步长
+	outStrides := strides(count)
+	chunkStrides := strides(a.Chunks)
+	lo, hi := make([]int, n), make([]int, n)
+	for d := 0; d < n; d++ {
+		lo[d] = start[d] / a.Chunks[d]
+		hi[d] = (start[d] + count[d] - 1) / a.Chunks[d]
+	}
+
+	ci := append([]int(nil), lo...)
+	for {
+		values, err := a.chunk(ci)
+		if err != nil {
+			return nil, err
+		}
+
+		// 块与切片的交集
+		from, to := make([]int, n), make([]int, n)
+		for d := 0; d < n; d++ {
+			origin := ci[d] * a.Chunks[d]
+			from[d] = maxInt(start[d], origin)
+			to[d] = minInt(start[d]+count[d], origin+a.Chunks[d])
+		}
+		a.copyRegion(values, out, ci, start, from, to, chunkStrides, outStrides)
+
+		if !nextIndex(ci, lo, hi) {
+			break
+		}
+	}
+	return out, nil
+}
+
+// copyRegion 将块内[from, to)区域复制到输出，最内维整段复制
+func (a *Array) copyRegion(values, out []float64, ci, start, from, to, chunkStrides, outStrides []int) {
+	n := len(a.Shape)
+	if n == 0 {
+		out[0] = values[0]
+		return
+	}
+	idx := append([]int(nil), from...)
+	last := n - 1
+	for {
+		src, dst := 0, 0
+		for d := 0; d < n; d++ {
+			src += (idx[d] - ci[d]*a.Chunks[d]) * chunkStrides[d]
+			dst += (idx[d] - start[d]) * outStrides[d]
+		}
+		copy(out[dst:dst+to[last]-from[last]], values[src:])
+
+		d := last - 1
+		for ; d >= 0; d-- {
+			idx[d]++
+			if idx[d] < to[d] {
+				break
+			}
+			idx[d] = from[d]
+		}
+		if d < 0 {
+			return
+		}
+	}
+}
+
+// chunk 读取并解码一个块，结果为C序的完整块
+func (a *Array) chunk(ci []int) ([]float64, error) {
+	key := a.chunkKey(ci)
+	a.mu.Lock()
+	if values, ok := a.cache[key]; ok {
+		a.mu.Unlock()
+		return values, nil
+	}
+	a.mu.Unlock()
+
+	size := 1
+	for _, c := range a.Chunks {
+		size *= c
+	}
+
+	raw, err := a.chunkBytes(ci, key)
+	if err != nil {
+		return nil, err
+	}
+	var values []float64
+	if raw == nil {
+		values = make([]float64, size)
+		if a.HasFill && a.FillValue != 0 {
+			for i := range values {
+				values[i] = a.FillValue
+			}
+		}
+	} else {
+		data := raw
+		for i := len(a.codecs) - 1; i >= 0; i-- {
+			if data, err = a.codecs[i].decode(data, size*a.dtype.size); err != nil {
+				return nil, fmt.Errorf("zarr: %s: chunk %s: %w", a.Name, key, err)
+			}
+		}
+		if values, err = a.dtype.decodeValues(data, size); err != nil {
+			return nil, fmt.Errorf("zarr: %s: chunk %s: %w", a.Name, key, err)
+		}
+		if a.perm != nil {
+			values = transposeToC(values, a.Chunks, a.perm)
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cache == nil {
+		a.cache = map[string][]float64{}
+	}
+	bytes := len(values) * 8
+	for a.cacheBytes+bytes > maxCacheBytes && len(a.cacheOrder) > 0 {
+		oldest := a.cacheOrder[0]
+		a.cacheOrder = a.cacheOrder[1:]
+		a.cacheBytes -= len(a.cache[oldest]) * 8
+		delete(a.cache, oldest)
+	}
+	a.cache[key] = values
+	a.cacheOrder = append(a.cacheOrder, key)
+	a.cacheBytes += bytes
+	return values, nil
+}
+
+// chunkBytes 读取块的编码数据，块不存在时返回nil
+func (a *Array) chunkBytes(ci []int, key string) ([]byte, error) {
+	if a.shard == nil {
+		data, err := os.ReadFile(filepath.Join(a.dir, filepath.FromSlash(key)))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	sh := a.shard
+	si, li := make([]int, len(ci)), make([]int, len(ci))
+	for d := range ci {
+		si[d], li[d] = ci[d]/sh.perShard[d], ci[d]%sh.perShard[d]
+	}
+	shardKey := a.chunkKey(si)
+	path := filepath.Join(a.dir, filepath.FromSlash(shardKey))
+	index, err := a.shardIndex(path, shardKey)
+	if err != nil || index == nil {
+		return nil, err
+	}
+
+	pos := 0
+	for d, s := range strides(sh.perShard) {
+		pos += li[d] * s
+	}
+	offset, nbytes := index[2*pos], index[2*pos+1]
+	if offset == math.MaxUint64 && nbytes == math.MaxUint64 {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// 偏移和长度来自分片索引，须在分配内存前检查
+	if size := uint64(info.Size()); offset > size || nbytes > size-offset {
+		return nil, fmt.Errorf("zarr: %s: shard %s: chunk at offset %d with %d bytes exceeds shard size %d", a.Name, shardKey, offset, nbytes, size)
+	}
+	data := make([]byte, nbytes)
+	if _, err := f.ReadAt(data, int64(offset)); err != nil {
+		return nil, fmt.Errorf("zarr: %s: read shard %s: %w", a.Name, shardKey, err)
+	}
+	return data, nil
+}
+
+// shardIndex 读取分片索引(每个块一对偏移量和长度)，分片不存在时返回nil
+func (a *Array) shardIndex(path, shardKey string) ([]uint64, error) {
+	sh := a.shard
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if index, ok := sh.index[shardKey]; ok {
+		return index, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := 1
+	for _, n := range sh.perShard {
+		entries *= n
+	}
+	size := int64(entries * 16)
+	for _, c := range sh.indexCodecs {
+		if c.id == "crc32c" {
+			size += 4
+		} else {
+			return nil, fmt.Errorf("%w: shard index codec %s", ErrUnsupported, c.id)
+		}
+	}
+	offset := info.Size() - size
+	if sh.indexAtStart {
+		offset = 0
+	}
+	if offset < 0 || size > info.Size() {
+		return nil, fmt.Errorf("zarr: %s: shard %s is truncated", a.Name, shardKey)
+	}
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	for i := len(sh.indexCodecs) - 1; i >= 0; i-- {
+		if data, err = sh.indexCodecs[i].decode(data, entries*16); err != nil {
+			return nil, fmt.Errorf("zarr: %s: shard %s index: %w", a.Name, shardKey, err)
+		}
+	}
+
+	index := make([]uint64, entries*2)
+	for i := range index {
+		index[i] = sh.indexOrder.Uint64(data[i*8:])
+	}
+	sh.index[shardKey] = index
+	return index, nil
+}
+
+// chunkKey 块(或分片)的存储键
+func (a *Array) chunkKey(ci []int) string {
+	parts := make([]string, len(ci))
+	for i, c := range ci {
+		parts[i] = strconv.Itoa(c)
+	}
+	if a.keyPrefix != "" {
+		return strings.Join(append([]string{a.keyPrefix}, parts...), a.keySep)
+	}
+	if len(parts) == 0 {
+		return "0"
+	}
+	return strings.Join(parts, a.keySep)
+}
+
+// dimensionNames 解析xarray的_ARRAY_DIMENSIONS属性
+func dimensionNames(v interface{}, rank int) []string {
+	list, ok := v.([]interface{})
+	if !ok || len(list) != rank {
+		return nil
+	}
+	names := make([]string, rank)
+	for i, x := range list {
+		s, ok := x.(string)
+		if !ok {
+			return nil
+		}
+		names[i] = s
+	}
+	return names
+}
+
+// strides C序数组各维的步长
+func strides(shape []int) []int {
+	s := make([]int, len(shape))
+	n := 1
+	for d := len(shape) - 1; d >= 0; d-- {
+		s[d] = n
+		n *= shape[d]
+	}
+	return s
+}
+
+// nextIndex 多维下标在[lo, hi]范围内递增，越过末尾时返回false
+func nextIndex(idx, lo, hi []int) bool {
+	for d := len(idx) - 1; d >= 0; d-- {
+		idx[d]++
+		if idx[d] <= hi[d] {
+			return true
+		}
+		idx[d] = lo[d]
+	}
+	return false
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,291 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeStore 按相对路径写入存储中的文件
+func writeStore(t testing.TB, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// gridValue 测试数组的值: 第i行第j列为i*10+j
+func gridValue(i, j int) float64 {
+	return float64(i*10 + j)
+}
+
+// encodeChunk 按C序编码块，块超出数组范围的部分补0；transpose为true时按F序
+func encodeChunk(ci, cj, rows, cols, nrows, ncols int, transpose bool, put func([]byte, float64) []byte) []byte {
+	var out []byte
+	value := func(a, b int) float64 {
+		i, j := ci*rows+a, cj*cols+b
+		if i >= nrows || j >= ncols {
+			return 0
+		}
+		return gridValue(i, j)
+	}
+	if transpose {
+		for b := 0; b < cols; b++ {
+			for a := 0; a < rows; a++ {
+				out = put(out, value(a, b))
+			}
+		}
+		return out
+	}
+	for a := 0; a < rows; a++ {
+		for b := 0; b < cols; b++ {
+			out = put(out, value(a, b))
+		}
+	}
+	return out
+}
+
+func putF32(b []byte, x float64) []byte {
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(x)))
+}
+
+func putF64(b []byte, x float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+}
+
+func putBigI16(b []byte, x float64) []byte {
+	return binary.BigEndian.AppendUint16(b, uint16(int16(x)))
+}
+
+func gzipCompress(data []byte) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+// checkRead 读取切片并与gridValue比较，missing中的位置应为NaN
+func checkRead(t *testing.T, a *Array, start, count []int, missing map[[2]int]bool) {
+	t.Helper()
+	got, err := a.Read(start, count)
+	if err != nil {
+		t.Fatalf("%s: Read(%v, %v): %v", a.Name, start, count, err)
+	}
+	for i := 0; i < count[0]; i++ {
+		for j := 0; j < count[1]; j++ {
+			r, c := start[0]+i, start[1]+j
+			want := gridValue(r, c)
+			if missing[[2]int{r, c}] {
+				want = math.NaN()
+			}
+			if x := got[i*count[1]+j]; x != want && !(math.IsNaN(x) && math.IsNaN(want)) {
+				t.Errorf("%s: [%d,%d] = %g, want %g", a.Name, r, c, x, want)
+			}
+		}
+	}
+}
+
+func TestOpenV2(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "obs.zarr")
+	files := map[string][]byte{
+		".zgroup": []byte(`{"zarr_format": 2}`),
+		".zattrs": []byte(`{"title": "test"}`),
+		// 5x4数组按2x3分块，zlib压缩，块(2,1)缺失
+		"temp/.zarray": []byte(`{"zarr_format": 2, "shape": [5, 4], "chunks": [2, 3], "dtype": "<f4",
+			"compressor": {"id": "zlib", "level": 1}, "fill_value": "NaN", "filters": null, "order": "C"}`),
+		"temp/.zattrs": []byte(`{"_ARRAY_DIMENSIONS": ["lat", "lon"], "units": "degC"}`),
+		// 大端int16，F序存放，字节重排，块键以/分隔
+		"flag/.zarray": []byte(`{"zarr_format": 2, "shape": [3, 2], "chunks": [3, 2], "dtype": ">i2",
+			"compressor": null, "fill_value": 0, "filters": [{"id": "shuffle", "elementsize": 2}],
+			"order": "F", "dimension_separator": "/"}`),
+		"flag/0/0":          byteShuffle(encodeChunk(0, 0, 3, 2, 3, 2, true, putBigI16), 2),
+		"skipped/.zarray":   []byte(`{"zarr_format": 2, "shape": [2], "chunks": [2], "dtype": "<U8", "compressor": null, "fill_value": null}`),
+		"notarray/.zattrs":  []byte(`{}`),
+		"temp/unrelated.md": []byte("not a chunk"),
+	}
+	for ci := 0; ci < 3; ci++ {
+		for cj := 0; cj < 2; cj++ {
+			if ci == 2 && cj == 1 {
+				continue
+			}
+			files["temp/"+string(rune('0'+ci))+"."+string(rune('0'+cj))] = zlibCompress(encodeChunk(ci, cj, 2, 3, 5, 4, false, putF32))
+		}
+	}
+	writeStore(t, dir, files)
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 2 || s.Attrs["title"] != "test" || len(s.Arrays) != 2 || len(s.Skipped) != 1 || s.Skipped[0] != "skipped" {
+		t.Fatalf("store = %+v", s)
+	}
+
+	temp := s.Array("temp")
+	if temp.TypeName() != "float" || !temp.HasFill || !math.IsNaN(temp.FillValue) ||
+		len(temp.Dims) != 2 || temp.Dims[0] != "lat" || temp.Attrs["units"] != "degC" || temp.Attrs["_ARRAY_DIMENSIONS"] != nil {
+		t.Errorf("temp = %+v", temp)
+	}
+	missing := map[[2]int]bool{{4, 3}: true}
+	checkRead(t, temp, []int{0, 0}, []int{5, 4}, missing)
+	checkRead(t, temp, []int{1, 2}, []int{3, 2}, missing)
+	checkRead(t, temp, []int{4, 0}, []int{1, 4}, missing)
+	if _, err := temp.Read([]int{4, 0}, []int{2, 1}); err == nil {
+		t.Error("out of range slice should fail")
+	}
+
+	flag := s.Array("flag")
+	if flag.TypeName() != "short" {
+		t.Errorf("flag type = %s", flag.TypeName())
+	}
+	checkRead(t, flag, []int{0, 0}, []int{3, 2}, nil)
+}
+
+func TestOpenV3(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "model.zarr")
+	// 4x4数组为一个分片，分片内2x2的块按转置存放并gzip压缩，索引在末尾并带crc32c校验；块(1,0)缺失
+	meta := `{"zarr_format": 3, "node_type": "array", "shape": [4, 4], "data_type": "float64",
+		"chunk_grid": {"name": "regular", "configuration": {"chunk_shape": [4, 4]}},
+		"chunk_key_encoding": {"name": "default", "configuration": {"separator": "/"}},
+		"fill_value": "NaN", "dimension_names": ["y", "x"], "attributes": {"units": "m"},
+		"codecs": [{"name": "sharding_indexed", "configuration": {
+			"chunk_shape": [2, 2],
+			"codecs": [{"name": "transpose", "configuration": {"order": [1, 0]}},
+				{"name": "bytes", "configuration": {"endian": "little"}}, {"name": "gzip", "configuration": {"level": 1}}],
+			"index_codecs": [{"name": "bytes", "configuration": {"endian": "little"}}, {"name": "crc32c"}],
+			"index_location": "end"}}]}`
+
+	var shard []byte
+	var index []byte
+	for ci := 0; ci < 2; ci++ {
+		for cj := 0; cj < 2; cj++ {
+			if ci == 1 && cj == 0 {
+				index = binary.LittleEndian.AppendUint64(index, math.MaxUint64)
+				index = binary.LittleEndian.AppendUint64(index, math.MaxUint64)
+				continue
+			}
+			chunk := gzipCompress(encodeChunk(ci, cj, 2, 2, 4, 4, true, putF64))
+			index = binary.LittleEndian.AppendUint64(index, uint64(len(shard)))
+			index = binary.LittleEndian.AppendUint64(index, uint64(len(chunk)))
+			shard = append(shard, chunk...)
+		}
+	}
+	index = binary.LittleEndian.AppendUint32(index, crc32.Checksum(index, crc32.MakeTable(crc32.Castagnoli)))
+	shard = append(shard, index...)
+
+	writeStore(t, dir, map[string][]byte{
+		"zarr.json":           []byte(`{"zarr_format": 3, "node_type": "group", "attributes": {"source": "test"}}`),
+		"sst/zarr.json":       []byte(meta),
+		"sst/c/0/0":           shard,
+		"strings/zarr.json":   []byte(`{"zarr_format": 3, "node_type": "array", "shape": [2], "data_type": "string", "chunk_grid": {"name": "regular", "configuration": {"chunk_shape": [2]}}, "codecs": []}`),
+		"subgroup/zarr.json":  []byte(`{"zarr_format": 3, "node_type": "group"}`),
+		"sst/c/0/0.unrelated": []byte("x"),
+	})
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 3 || s.Attrs["source"] != "test" || len(s.Arrays) != 1 || len(s.Skipped) != 1 {
+		t.Fatalf("store = %+v", s)
+	}
+	sst := s.Array("sst")
+	if sst.TypeName() != "double" || len(sst.Chunks) != 2 || sst.Chunks[0] != 2 || len(sst.Dims) != 2 || sst.Dims[1] != "x" {
+		t.Errorf("sst = %+v", sst)
+	}
+	missing := map[[2]int]bool{{2, 0}: true, {2, 1}: true, {3, 0}: true, {3, 1}: true}
+	checkRead(t, sst, []int{0, 0}, []int{4, 4}, missing)
+	checkRead(t, sst, []int{1, 1}, []int{2, 3}, missing)
+
+	// 校验和错误
+	shard[len(shard)-1] ^= 0xFF
+	writeStore(t, dir, map[string][]byte{"sst/c/0/0": shard})
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Array("sst").Read([]int{0, 0}, []int{1, 1}); err == nil {
+		t.Error("corrupt shard index should fail")
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(dir); !errors.Is(err, ErrNotZarr) {
+		t.Errorf("empty directory: %v", err)
+	}
+	writeStore(t, dir, map[string][]byte{".zarray": []byte(`{"shape": [2, 2], "chunks": [2], "dtype": "<f8"}`)})
+	if _, err := Open(dir); err == nil {
+		t.Error("chunk rank mismatch should fail")
+	}
+}
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		bits uint16
+		want float64
+	}{
+		{0x3C00, 1},
+		{0xC000, -2},
+		{0x7BFF, 65504},
+		{0x0001, math.Ldexp(1, -24)},
+		{0x3555, 0.333251953125},
+		{0x7C00, math.Inf(1)},
+		{0xFC00, math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		if got := float16(tt.bits); got != tt.want {
+			t.Errorf("float16(%#04x) = %g, want %g", tt.bits, got, tt.want)
+		}
+	}
+	if got := float16(0x7E00); !math.IsNaN(got) {
+		t.Errorf("float16(0x7e00) = %g, want NaN", got)
+	}
+}
+
+func FuzzOpenV2(f *testing.F) {
+	f.Add([]byte(`{"shape": [5, 4], "chunks": [2, 3], "dtype": "<f4", "compressor": {"id": "zlib"}, "fill_value": "NaN"}`),
+		zlibCompress(encodeChunk(0, 0, 2, 3, 5, 4, false, putF32)))
+	f.Add([]byte(`{"shape": [3, 2], "chunks": [3, 2], "dtype": ">i2", "compressor": null, "filters": [{"id": "shuffle"}], "order": "F"}`),
+		byteShuffle(encodeChunk(0, 0, 3, 2, 3, 2, true, putBigI16), 2))
+	f.Add([]byte(`{"shape": [256], "chunks": [256], "dtype": "<f4", "compressor": {"id": "blosc"}}`),
+		bloscFrame(float32Bytes(256), 4, 1024, bloscZlib<<5|bloscDoShuffle, zlibCompress))
+	f.Fuzz(func(t *testing.T, meta, chunk []byte) {
+		dir := t.TempDir()
+		writeStore(t, dir, map[string][]byte{".zarray": meta, "0.0": chunk, "0": chunk})
+		s, err := Open(dir)
+		if err != nil || len(s.Arrays) == 0 {
+			return
+		}
+		a := s.Arrays[0]
+		// 只读取第一个块，避免按元数据中的形状分配过多内存
+		n := 1
+		start, count := make([]int, len(a.Shape)), make([]int, len(a.Shape))
+		for d := range a.Shape {
+			count[d] = minInt(a.Shape[d], a.Chunks[d])
+			if count[d] > 1<<16 || n*count[d] > 1<<16 || a.Chunks[d] > 1<<16 {
+				return
+			}
+			n *= a.Chunks[d]
+		}
+		if n > 1<<16 {
+			return
+		}
+		if values, err := a.Read(start, count); err == nil && len(values) > n {
+			t.Fatalf("read %d values from a chunk of %d", len(values), n)
+		}
+	})
+}
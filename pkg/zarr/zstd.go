@@ -0,0 +1,857 @@
+package zarr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Zstandard解压(RFC 8878)，仅支持无字典的帧，整帧解压到内存
+
+var errZstdCorrupt = errors.New("zstd: corrupt input")
+
+const (
+	zstdMagic     = 0xFD2FB528
+	zstdMaxBlock  = 128 << 10
+	zstdMaxOutput = 1 << 31
+)
+
+// zstdDecompress 解压一个或多个连续的zstd帧，limit为解压大小的上限(为0时不超过zstdMaxOutput)
+func zstdDecompress(src []byte, limit int) ([]byte, error) {
+	if limit <= 0 || limit > zstdMaxOutput {
+		limit = zstdMaxOutput
+	}
+	out := []byte{}
+	for len(src) > 0 {
+		if len(src) < 4 {
+			return nil, errZstdCorrupt
+		}
+		magic := binary.LittleEndian.Uint32(src)
+		if magic&0xFFFFFFF0 == 0x184D2A50 {
+			// 可跳过帧
+			if len(src) < 8 {
+				return nil, errZstdCorrupt
+			}
+			n := int(binary.LittleEndian.Uint32(src[4:]))
+			if len(src) < 8+n {
+				return nil, errZstdCorrupt
+			}
+			src = src[8+n:]
+			continue
+		}
+		if magic != zstdMagic {
+			return nil, fmt.Errorf("zstd: invalid magic number %#x", magic)
+		}
+		var err error
+		var n int
+		out, n, err = zstdFrame(src[4:], out, limit)
+		if err != nil {
+			return nil, err
+		}
+		src = src[4+n:]
+	}
+	return out, nil
+}
+
+// zstdState 帧内跨块保留的解码状态
+type zstdState struct {
+	huffman  *huffmanTable
+	llTable  *fseTable
+	ofTable  *fseTable
+	mlTable  *fseTable
+	reps     [3]int
+	frameOut int // 本帧输出在out中的起始位置
+}
+
+// zstdFrame 解码一个帧(不含魔数)，返回追加后的输出和消耗的字节数；输出总长度超过limit时出错
+func zstdFrame(src, out []byte, limit int) ([]byte, int, error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	desc := src[0]
+	fcsFlag := desc >> 6
+	singleSegment := desc&0x20 != 0
+	checksum := desc&0x04 != 0
+	dictFlag := desc & 0x03
+	if desc&0x08 != 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	pos := 1
+	if !singleSegment {
+		pos++ // 窗口描述符
+	}
+	dictSize := []int{0, 1, 2, 4}[dictFlag]
+	if pos+dictSize > len(src) {
+		return nil, 0, errZstdCorrupt
+	}
+	var dictID uint32
+	for i := 0; i < dictSize; i++ {
+		dictID |= uint32(src[pos+i]) << (8 * i)
+	}
+	if dictID != 0 {
+		return nil, 0, errors.New("zstd: dictionaries are not supported")
+	}
+	pos += dictSize
+
+	fcsSize := []int{0, 2, 4, 8}[fcsFlag]
+	if fcsFlag == 0 && singleSegment {
+		fcsSize = 1
+	}
+	if pos+fcsSize > len(src) {
+		return nil, 0, errZstdCorrupt
+	}
+	var contentSize uint64
+	for i := 0; i < fcsSize; i++ {
+		contentSize |= uint64(src[pos+i]) << (8 * i)
+	}
+	if fcsSize == 2 {
+		contentSize += 256
+	}
+	pos += fcsSize
+	if fcsSize > 0 && contentSize > uint64(limit-len(out)) {
+		return nil, 0, errors.New("zstd: output too large")
+	}
+	if fcsSize > 0 && cap(out)-len(out) < int(contentSize) {
+		grown := make([]byte, len(out), len(out)+int(contentSize))
+		copy(grown, out)
+		out = grown
+	}
+
+	st := &zstdState{reps: [3]int{1, 4, 8}, frameOut: len(out)}
+	for {
+		if pos+3 > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		header := int(src[pos]) | int(src[pos+1])<<8 | int(src[pos+2])<<16
+		pos += 3
+		last := header&1 != 0
+		size := header >> 3
+		if size > zstdMaxBlock {
+			return nil, 0, errZstdCorrupt
+		}
+		switch (header >> 1) & 3 {
+		case 0: // 原始块
+			if pos+size > len(src) {
+				return nil, 0, errZstdCorrupt
+			}
+			out = append(out, src[pos:pos+size]...)
+			pos += size
+		case 1: // RLE块
+			if pos+1 > len(src) {
+				return nil, 0, errZstdCorrupt
+			}
+			for i := 0; i < size; i++ {
+				out = append(out, src[pos])
+			}
+			pos++
+		case 2:
+			if pos+size > len(src) {
+				return nil, 0, errZstdCorrupt
+			}
+			var err error
+			out, err = st.block(src[pos:pos+size], out)
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += size
+		default:
+			return nil, 0, errZstdCorrupt
+		}
+		if len(out) > limit {
+			return nil, 0, errors.New("zstd: output too large")
+		}
+		if last {
+			break
+		}
+	}
+	if checksum {
+		pos += 4 // 不校验xxhash64
+	}
+	if pos > len(src) {
+		return nil, 0, errZstdCorrupt
+	}
+	if fcsSize > 0 && uint64(len(out)-st.frameOut) != contentSize {
+		return nil, 0, errors.New("zstd: frame content size mismatch")
+	}
+	return out, pos, nil
+}
+
+// block 解码压缩块: 字面量段和序列段，解压后不超过zstdMaxBlock字节
+func (st *zstdState) block(src, out []byte) ([]byte, error) {
+	blockStart := len(out)
+	literals, n, err := st.literals(src)
+	if err != nil {
+		return nil, err
+	}
+	src = src[n:]
+
+	// 序列数
+	if len(src) < 1 {
+		return nil, errZstdCorrupt
+	}
+	var nseq int
+	switch b0 := int(src[0]); {
+	case b0 == 0:
+		return append(out, literals...), nil
+	case b0 < 128:
+		nseq, src = b0, src[1:]
+	case b0 < 255:
+		if len(src) < 2 {
+			return nil, errZstdCorrupt
+		}
+		nseq, src = (b0-128)<<8+int(src[1]), src[2:]
+	default:
+		if len(src) < 3 {
+			return nil, errZstdCorrupt
+		}
+		nseq, src = int(src[1])+int(src[2])<<8+0x7F00, src[3:]
+	}
+
+	if len(src) < 1 {
+		return nil, errZstdCorrupt
+	}
+	modes := src[0]
+	src = src[1:]
+	for _, t := range []struct {
+		mode  byte
+		table **fseTable
+		kind  int
+	}{
+		{modes >> 6, &st.llTable, seqLiteralLength},
+		{(modes >> 4) & 3, &st.ofTable, seqOffset},
+		{(modes >> 2) & 3, &st.mlTable, seqMatchLength},
+	} {
+		n, err := st.seqTable(t.mode, t.table, t.kind, src)
+		if err != nil {
+			return nil, err
+		}
+		src = src[n:]
+	}
+
+	br, err := newBackwardReader(src)
+	if err != nil {
+		return nil, err
+	}
+	ll := fseDecoder{table: st.llTable}
+	of := fseDecoder{table: st.ofTable}
+	ml := fseDecoder{table: st.mlTable}
+	ll.init(br)
+	of.init(br)
+	ml.init(br)
+
+	for i := 0; i < nseq; i++ {
+		ofCode := int(of.symbol())
+		mlCode := int(ml.symbol())
+		llCode := int(ll.symbol())
+		if ofCode > 31 || mlCode >= len(mlBase) || llCode >= len(llBase) {
+			return nil, errZstdCorrupt
+		}
+
+		offsetValue := 1<<uint(ofCode) + int(br.read(uint(ofCode)))
+		matchLen := mlBase[mlCode] + int(br.read(mlBits[mlCode]))
+		litLen := llBase[llCode] + int(br.read(llBits[llCode]))
+
+		var offset int
+		if offsetValue > 3 {
+			offset = offsetValue - 3
+			st.reps[2], st.reps[1], st.reps[0] = st.reps[1], st.reps[0], offset
+		} else {
+			idx := offsetValue - 1
+			if litLen == 0 {
+				idx++
+			}
+			if idx == 0 {
+				offset = st.reps[0]
+			} else {
+				if idx < 3 {
+					offset = st.reps[idx]
+				} else {
+					offset = st.reps[0] - 1
+				}
+				if idx > 1 {
+					st.reps[2] = st.reps[1]
+				}
+				st.reps[1], st.reps[0] = st.reps[0], offset
+			}
+		}
+
+		if i < nseq-1 {
+			ll.update(br)
+			ml.update(br)
+			of.update(br)
+		}
+		if br.overflow() {
+			return nil, errZstdCorrupt
+		}
+
+		if litLen > len(literals) || len(out)-blockStart+litLen+matchLen > zstdMaxBlock {
+			return nil, errZstdCorrupt
+		}
+		out = append(out, literals[:litLen]...)
+		literals = literals[litLen:]
+		if offset <= 0 || offset > len(out)-st.frameOut {
+			return nil, errZstdCorrupt
+		}
+		from := len(out) - offset
+		for k := 0; k < matchLen; k++ {
+			out = append(out, out[from+k])
+		}
+	}
+	return append(out, literals...), nil
+}
+
+// literals 解码字面量段，返回字面量和消耗的字节数
+func (st *zstdState) literals(src []byte) ([]byte, int, error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	typ := src[0] & 3
+	sizeFormat := (src[0] >> 2) & 3
+
+	if typ == 0 || typ == 1 {
+		var size, hdr int
+		switch sizeFormat {
+		case 0, 2:
+			size, hdr = int(src[0]>>3), 1
+		case 1:
+			if len(src) < 2 {
+				return nil, 0, errZstdCorrupt
+			}
+			size, hdr = int(src[0]>>4)+int(src[1])<<4, 2
+		case 3:
+			if len(src) < 3 {
+				return nil, 0, errZstdCorrupt
+			}
+			size, hdr = int(src[0]>>4)+int(src[1])<<4+int(src[2])<<12, 3
+		}
+		if size > zstdMaxBlock {
+			return nil, 0, errZstdCorrupt
+		}
+		if typ == 0 {
+			if hdr+size > len(src) {
+				return nil, 0, errZstdCorrupt
+			}
+			return src[hdr : hdr+size], hdr + size, nil
+		}
+		if hdr+1 > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		lit := make([]byte, size)
+		for i := range lit {
+			lit[i] = src[hdr]
+		}
+		return lit, hdr + 1, nil
+	}
+
+	// Huffman压缩的字面量
+	var hdr, sizeBits, streams int
+	switch sizeFormat {
+	case 0:
+		hdr, sizeBits, streams = 3, 10, 1
+	case 1:
+		hdr, sizeBits, streams = 3, 10, 4
+	case 2:
+		hdr, sizeBits, streams = 4, 14, 4
+	case 3:
+		hdr, sizeBits, streams = 5, 18, 4
+	}
+	if len(src) < hdr {
+		return nil, 0, errZstdCorrupt
+	}
+	var h uint64
+	for i := 0; i < hdr; i++ {
+		h |= uint64(src[i]) << (8 * i)
+	}
+	mask := uint64(1)<<uint(sizeBits) - 1
+	regen := int((h >> 4) & mask)
+	compressed := int((h >> (4 + uint(sizeBits))) & mask)
+	if hdr+compressed > len(src) || regen > zstdMaxBlock {
+		return nil, 0, errZstdCorrupt
+	}
+	data := src[hdr : hdr+compressed]
+
+	if typ == 2 {
+		table, n, err := readHuffmanTable(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		st.huffman = table
+		data = data[n:]
+	} else if st.huffman == nil {
+		return nil, 0, errZstdCorrupt
+	}
+
+	lit := make([]byte, regen)
+	if streams == 1 {
+		if err := st.huffman.decode(data, lit); err != nil {
+			return nil, 0, err
+		}
+		return lit, hdr + compressed, nil
+	}
+
+	if len(data) < 6 {
+		return nil, 0, errZstdCorrupt
+	}
+	sizes := [4]int{
+		int(binary.LittleEndian.Uint16(data)),
+		int(binary.LittleEndian.Uint16(data[2:])),
+		int(binary.LittleEndian.Uint16(data[4:])),
+	}
+	data = data[6:]
+	sizes[3] = len(data) - sizes[0] - sizes[1] - sizes[2]
+	if sizes[3] < 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	seg := (regen + 3) / 4
+	for i := 0; i < 4; i++ {
+		lo, hi := i*seg, (i+1)*seg
+		if i == 3 {
+			hi = regen
+		}
+		if lo > regen {
+			lo = regen
+		}
+		if hi > regen {
+			hi = regen
+		}
+		if err := st.huffman.decode(data[:sizes[i]], lit[lo:hi]); err != nil {
+			return nil, 0, err
+		}
+		data = data[sizes[i]:]
+	}
+	return lit, hdr + compressed, nil
+}
+
+// 序列符号种类
+const (
+	seqLiteralLength = iota
+	seqOffset
+	seqMatchLength
+)
+
+// seqTable 按压缩模式准备序列的FSE解码表，返回消耗的字节数
+func (st *zstdState) seqTable(mode byte, table **fseTable, kind int, src []byte) (int, error) {
+	maxLog, maxSymbol := 9, 35
+	switch kind {
+	case seqOffset:
+		maxLog, maxSymbol = 8, 31
+	case seqMatchLength:
+		maxLog, maxSymbol = 9, 52
+	}
+
+	switch mode {
+	case 0: // 预定义分布
+		switch kind {
+		case seqLiteralLength:
+			*table = predefinedLL
+		case seqOffset:
+			*table = predefinedOF
+		default:
+			*table = predefinedML
+		}
+		return 0, nil
+	case 1: // RLE
+		if len(src) < 1 {
+			return 0, errZstdCorrupt
+		}
+		*table = &fseTable{log: 0, cells: []fseCell{{symbol: src[0]}}}
+		return 1, nil
+	case 2:
+		norm, log, n, err := readFSEDistribution(src, maxSymbol, maxLog)
+		if err != nil {
+			return 0, err
+		}
+		t, err := buildFSETable(norm, log)
+		if err != nil {
+			return 0, err
+		}
+		*table = t
+		return n, nil
+	default: // 沿用上一个块的表
+		if *table == nil {
+			return 0, errZstdCorrupt
+		}
+		return 0, nil
+	}
+}
+
+// 字面量长度和匹配长度码的基值与附加位数
+var (
+	llBase = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536}
+	llBits = []uint{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16}
+	mlBase = []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539}
+	mlBits = []uint{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16}
+)
+
+// 预定义的序列符号分布
+var (
+	predefinedLL = mustFSETable([]int{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1}, 6)
+	predefinedML = mustFSETable([]int{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1}, 6)
+	predefinedOF = mustFSETable([]int{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}, 5)
+)
+
+// fseCell FSE解码表的单元
+type fseCell struct {
+	symbol   byte
+	nbBits   uint8
+	baseline int
+}
+
+// fseTable FSE解码表
+type fseTable struct {
+	log   uint
+	cells []fseCell
+}
+
+func mustFSETable(norm []int, log uint) *fseTable {
+	t, err := buildFSETable(norm, log)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// buildFSETable 由归一化概率构建解码表，概率-1表示"小于1"
+func buildFSETable(norm []int, log uint) (*fseTable, error) {
+	size := 1 << log
+	cells := make([]fseCell, size)
+	next := make([]int, len(norm))
+
+	high := size - 1
+	for s, p := range norm {
+		if p == -1 {
+			if high < 0 {
+				return nil, errZstdCorrupt
+			}
+			cells[high].symbol = byte(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = p
+		}
+	}
+
+	pos, step, mask := 0, size>>1+size>>3+3, size-1
+	for s, p := range norm {
+		for i := 0; i < p; i++ {
+			cells[pos].symbol = byte(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	if pos != 0 {
+		return nil, errZstdCorrupt
+	}
+
+	for u := range cells {
+		s := cells[u].symbol
+		state := next[s]
+		next[s]++
+		if state <= 0 {
+			return nil, errZstdCorrupt
+		}
+		nb := int(log) - (bits.Len(uint(state)) - 1)
+		cells[u].nbBits = uint8(nb)
+		cells[u].baseline = state<<uint(nb) - size
+	}
+	return &fseTable{log: log, cells: cells}, nil
+}
+
+// readFSEDistribution 读取FSE表描述，返回归一化概率、精度和消耗的字节数
+func readFSEDistribution(src []byte, maxSymbol, maxLog int) ([]int, uint, int, error) {
+	fr := forwardReader{data: src}
+	log := int(fr.read(4)) + 5
+	if log > maxLog {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	remaining := 1<<uint(log) + 1
+	threshold := 1 << uint(log)
+	nbBits := uint(log + 1)
+
+	var norm []int
+	for remaining > 1 && len(norm) <= maxSymbol {
+		max := 2*threshold - 1 - remaining
+		v := int(fr.peek(nbBits))
+		var count int
+		if v&(threshold-1) < max {
+			count = v & (threshold - 1)
+			fr.skip(nbBits - 1)
+		} else {
+			count = v & (2*threshold - 1)
+			if count >= threshold {
+				count -= max
+			}
+			fr.skip(nbBits)
+		}
+		count--
+		if count < 0 {
+			remaining--
+		} else {
+			remaining -= count
+		}
+		norm = append(norm, count)
+
+		if count == 0 {
+			for {
+				repeat := int(fr.read(2))
+				for i := 0; i < repeat && len(norm) <= maxSymbol; i++ {
+					norm = append(norm, 0)
+				}
+				if repeat != 3 {
+					break
+				}
+			}
+		}
+		for remaining < threshold && nbBits > 1 {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || len(norm) > maxSymbol+1 || fr.pos > len(src)*8 {
+		return nil, 0, 0, errZstdCorrupt
+	}
+	return norm, uint(log), (fr.pos + 7) / 8, nil
+}
+
+// fseDecoder FSE状态机
+type fseDecoder struct {
+	table *fseTable
+	state int
+}
+
+func (d *fseDecoder) init(br *backwardReader) {
+	d.state = int(br.read(d.table.log))
+}
+
+func (d *fseDecoder) symbol() byte {
+	return d.table.cells[d.state].symbol
+}
+
+func (d *fseDecoder) update(br *backwardReader) {
+	c := d.table.cells[d.state]
+	d.state = c.baseline + int(br.read(uint(c.nbBits)))
+}
+
+// huffmanTable Huffman解码表，按maxBits位前缀查表
+type huffmanTable struct {
+	maxBits uint
+	symbols []byte
+	lengths []uint8
+}
+
+// readHuffmanTable 读取Huffman树描述，返回解码表和消耗的字节数
+func readHuffmanTable(src []byte) (*huffmanTable, int, error) {
+	if len(src) < 1 {
+		return nil, 0, errZstdCorrupt
+	}
+	var weights []int
+	hb := int(src[0])
+	n := 1
+	if hb < 128 {
+		// FSE压缩的权重
+		if 1+hb > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		data := src[1 : 1+hb]
+		norm, log, m, err := readFSEDistribution(data, 255, 6)
+		if err != nil {
+			return nil, 0, err
+		}
+		table, err := buildFSETable(norm, log)
+		if err != nil {
+			return nil, 0, err
+		}
+		br, err := newBackwardReader(data[m:])
+		if err != nil {
+			return nil, 0, err
+		}
+		s1, s2 := fseDecoder{table: table}, fseDecoder{table: table}
+		s1.init(br)
+		s2.init(br)
+		for len(weights) < 255 {
+			weights = append(weights, int(s1.symbol()))
+			s1.update(br)
+			if br.overflow() {
+				weights = append(weights, int(s2.symbol()))
+				break
+			}
+			weights = append(weights, int(s2.symbol()))
+			s2.update(br)
+			if br.overflow() {
+				weights = append(weights, int(s1.symbol()))
+				break
+			}
+		}
+		n += hb
+	} else {
+		count := hb - 127
+		size := (count + 1) / 2
+		if 1+size > len(src) {
+			return nil, 0, errZstdCorrupt
+		}
+		for i := 0; i < count; i++ {
+			b := src[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, int(b>>4))
+			} else {
+				weights = append(weights, int(b&15))
+			}
+		}
+		n += size
+	}
+
+	// 最后一个符号的权重由其余权重推出
+	total := 0
+	for _, w := range weights {
+		if w > 11 {
+			return nil, 0, errZstdCorrupt
+		}
+		if w > 0 {
+			total += 1 << uint(w-1)
+		}
+	}
+	if total == 0 {
+		return nil, 0, errZstdCorrupt
+	}
+	maxBits := uint(bits.Len(uint(total)))
+	rest := 1<<maxBits - total
+	if rest&(rest-1) != 0 || maxBits > 11 {
+		return nil, 0, errZstdCorrupt
+	}
+	weights = append(weights, bits.Len(uint(rest)))
+
+	t := &huffmanTable{maxBits: maxBits, symbols: make([]byte, 1<<maxBits), lengths: make([]uint8, 1<<maxBits)}
+	pos := 0
+	for w := 1; w <= int(maxBits); w++ {
+		for s, sw := range weights {
+			if sw != w {
+				continue
+			}
+			span := 1 << uint(w-1)
+			for i := 0; i < span; i++ {
+				t.symbols[pos+i] = byte(s)
+				t.lengths[pos+i] = uint8(maxBits + 1 - uint(w))
+			}
+			pos += span
+		}
+	}
+	if pos != len(t.symbols) {
+		return nil, 0, errZstdCorrupt
+	}
+	return t, n, nil
+}
+
+// decode 解码一个Huffman流，填满out
+func (t *huffmanTable) decode(src, out []byte) error {
+	if len(out) == 0 {
+		return nil
+	}
+	br, err := newBackwardReader(src)
+	if err != nil {
+		return err
+	}
+	for i := range out {
+		v := br.peek(t.maxBits)
+		out[i] = t.symbols[v]
+		br.skip(uint(t.lengths[v]))
+	}
+	if br.overflow() {
+		return errZstdCorrupt
+	}
+	return nil
+}
+
+// backwardReader 从末尾向前读取的位流，最后一个字节的最高置位为起始标记
+type backwardReader struct {
+	data []byte
+	pos  int // 剩余位数，读越界后为负
+}
+
+func newBackwardReader(data []byte) (*backwardReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, errZstdCorrupt
+	}
+	last := data[len(data)-1]
+	return &backwardReader{data: data, pos: (len(data)-1)*8 + bits.Len8(last) - 1}, nil
+}
+
+// peek 读取接下来的n位(n不超过56，高位在前)而不移动位置，越过流起点的部分补零
+func (r *backwardReader) peek(n uint) uint64 {
+	lo := r.pos - int(n)
+	var shift uint
+	if lo < 0 {
+		shift, lo = uint(-lo), 0
+	}
+	width := r.pos - lo
+	if width <= 0 {
+		return 0
+	}
+	return bitsAt(r.data, lo, uint(width)) << shift
+}
+
+func (r *backwardReader) skip(n uint) {
+	r.pos -= int(n)
+}
+
+func (r *backwardReader) read(n uint) uint64 {
+	v := r.peek(n)
+	r.skip(n)
+	return v
+}
+
+// overflow 是否读到了流起点之前
+func (r *backwardReader) overflow() bool {
+	return r.pos < 0
+}
+
+// forwardReader 从前向后、低位在前读取的位流
+type forwardReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *forwardReader) peek(n uint) uint64 {
+	return bitsAt(r.data, r.pos, n)
+}
+
+func (r *forwardReader) skip(n uint) {
+	r.pos += int(n)
+}
+
+func (r *forwardReader) read(n uint) uint64 {
+	v := r.peek(n)
+	r.skip(n)
+	return v
+}
+
+// bitsAt 取从第lo位开始的width位(低位在前编号)，越过末尾的部分补零
+func bitsAt(data []byte, lo int, width uint) uint64 {
+	if width == 0 {
+		return 0
+	}
+	b, off := lo>>3, uint(lo&7)
+	var w uint64
+	for i := 0; i < 8 && b+i < len(data); i++ {
+		w |= uint64(data[b+i]) << (8 * uint(i))
+	}
+	return (w >> off) & (1<<width - 1)
+}
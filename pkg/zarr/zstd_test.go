@@ -0,0 +1,133 @@
+package zarr
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testText 测试文本，与testdata中压缩文件的原文一致；压缩文件由zstd 1.5.6和lz4 1.9.4命令行工具生成:
+//
+//	zstd -19 --no-check text200.txt -o text200-19.zst
+//	zstd -1 --check text200.txt -o text200-1-check.zst
+//	zstd -19 text8000.txt -o text8000-19.zst
+//	lz4 -12 text200.txt (取帧中的第一个LZ4块)
+func testText(lines int) []byte {
+	var b bytes.Buffer
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "station %03d,2020-01-%02d,%.2f,%.1f\n", i%37, i%28+1, 10+float64(i%53)*0.37, 30+float64(i%11)*0.5)
+	}
+	return b.Bytes()
+}
+
+func readTestdata(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// zstdRawFrame 由单个原始块或RLE块构成的帧(单段模式，1字节内容大小)
+func zstdRawFrame(blockType int, size int, payload []byte) []byte {
+	header := 1 | blockType<<1 | size<<3
+	frame := []byte{0x28, 0xB5, 0x2F, 0xFD, 0x20, byte(size), byte(header), byte(header >> 8), byte(header >> 16)}
+	return append(frame, payload...)
+}
+
+func TestZstdDecompress(t *testing.T) {
+	tests := []struct {
+		file  string
+		lines int
+	}{
+		{"text200-19.zst", 200},
+		{"text200-1-check.zst", 200},
+		// 多个块，跨块沿用Huffman表、FSE表和重复偏移
+		{"text8000-19.zst", 8000},
+	}
+	for _, tt := range tests {
+		want := testText(tt.lines)
+		got, err := zstdDecompress(readTestdata(t, tt.file), len(want))
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: decoded %d bytes, want %d bytes of text", tt.file, len(got), len(want))
+		}
+		if _, err := zstdDecompress(readTestdata(t, tt.file), len(want)-1); err == nil {
+			t.Errorf("%s: limit below content size should fail", tt.file)
+		}
+	}
+}
+
+func TestZstdFrames(t *testing.T) {
+	text := testText(200)
+	frame := readTestdata(t, "text200-19.zst")
+	skippable := []byte{0x5A, 0x2A, 0x4D, 0x18, 3, 0, 0, 0, 'a', 'b', 'c'}
+
+	tests := []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{"raw block", zstdRawFrame(0, 5, []byte("hello")), []byte("hello")},
+		{"rle block", zstdRawFrame(1, 6, []byte("x")), []byte("xxxxxx")},
+		{"empty", zstdRawFrame(0, 0, nil), []byte{}},
+		{"concatenated", append(append(append([]byte(nil), frame...), skippable...), frame...), append(append([]byte(nil), text...), text...)},
+	}
+	for _, tt := range tests {
+		got, err := zstdDecompress(tt.src, 0)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	invalid := map[string][]byte{
+		"magic":        []byte("PK\x03\x04rest"),
+		"truncated":    frame[:len(frame)/2],
+		"content size": zstdRawFrame(0, 5, []byte("hell")),
+		"reserved":     append(zstdRawFrame(0, 0, nil)[:4], 0x28, 0, 1, 0, 0),
+		"dictionary":   {0x28, 0xB5, 0x2F, 0xFD, 0x21, 7, 1, 0, 0},
+	}
+	for name, src := range invalid {
+		if _, err := zstdDecompress(src, 0); err == nil {
+			t.Errorf("%s: should fail", name)
+		}
+	}
+}
+
+func TestZstdBlockLimit(t *testing.T) {
+	// 8字节原始块后接压缩块: 32000个序列，字面量长度0、重复偏移、匹配长度131074(码52，16个附加位全为1)，
+	// 不限制单块解压大小时将展开为约4GB
+	frame := []byte{0x28, 0xB5, 0x2F, 0xFD, 0x00, 0x00, 8 << 3, 0, 0}
+	frame = append(frame, "abcdefgh"...)
+	block := []byte{0x00, 128 + 32000>>8, 32000 & 0xFF, 0x54, 0, 0, 52}
+	block = append(block, bytes.Repeat([]byte{0xFF}, 32000*2)...)
+	block = append(block, 0x01)
+	header := 1 | 2<<1 | len(block)<<3
+	frame = append(frame, byte(header), byte(header>>8), byte(header>>16))
+	frame = append(frame, block...)
+	if _, err := zstdDecompress(frame, 0); err == nil {
+		t.Error("block expanding beyond 128KB should fail")
+	}
+}
+
+func FuzzZstdDecompress(f *testing.F) {
+	f.Add(readTestdata(f, "text200-19.zst"))
+	f.Add(readTestdata(f, "text200-1-check.zst"))
+	f.Add(zstdRawFrame(1, 6, []byte("x")))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out, err := zstdDecompress(data, 1<<20)
+		if err == nil && len(out) > 1<<20 {
+			t.Fatalf("decoded %d bytes beyond the limit", len(out))
+		}
+	})
+}